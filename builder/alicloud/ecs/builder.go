@@ -19,10 +19,11 @@ import (
 const BuilderId = "alibaba.alicloud"
 
 type Config struct {
-	common.PackerConfig  `mapstructure:",squash"`
-	AlicloudAccessConfig `mapstructure:",squash"`
-	AlicloudImageConfig  `mapstructure:",squash"`
-	RunConfig            `mapstructure:",squash"`
+	common.PackerConfig    `mapstructure:",squash"`
+	common.CloudInitConfig `mapstructure:",squash"`
+	AlicloudAccessConfig   `mapstructure:",squash"`
+	AlicloudImageConfig    `mapstructure:",squash"`
+	RunConfig              `mapstructure:",squash"`
 
 	ctx interpolate.Context
 }
@@ -67,6 +68,7 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 	errs = packer.MultiErrorAppend(errs, b.config.AlicloudAccessConfig.Prepare(&b.config.ctx)...)
 	errs = packer.MultiErrorAppend(errs, b.config.AlicloudImageConfig.Prepare(&b.config.ctx)...)
 	errs = packer.MultiErrorAppend(errs, b.config.RunConfig.Prepare(&b.config.ctx)...)
+	errs = packer.MultiErrorAppend(errs, b.config.CloudInitConfig.Prepare()...)
 
 	if errs != nil && len(errs.Errors) > 0 {
 		return nil, errs
@@ -156,12 +158,17 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		&stepRunAlicloudInstance{},
 		&stepMountAlicloudDisk{},
 		&communicator.StepConnect{
-			Config: &b.config.RunConfig.Comm,
+			Config:    &b.config.RunConfig.Comm,
+			BuildName: b.config.PackerBuildName,
 			Host: SSHHost(
 				client,
 				b.config.SSHPrivateIp),
 			SSHConfig: b.config.RunConfig.Comm.SSHConfigFunc(),
 		},
+		&common.StepWaitCloudInit{
+			Config: &b.config.CloudInitConfig,
+			Comm:   &b.config.RunConfig.Comm,
+		},
 		&common.StepProvision{},
 		&common.StepCleanupTempKeys{
 			Comm: &b.config.RunConfig.Comm,
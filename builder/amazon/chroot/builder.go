@@ -12,6 +12,7 @@ import (
 	"github.com/aws/aws-sdk-go/service/ec2"
 	awscommon "github.com/hashicorp/packer/builder/amazon/common"
 	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/common/chroot"
 	"github.com/hashicorp/packer/helper/config"
 	"github.com/hashicorp/packer/helper/multistep"
 	"github.com/hashicorp/packer/packer"
@@ -25,6 +26,7 @@ const BuilderId = "mitchellh.amazon.chroot"
 // settable from the template.
 type Config struct {
 	common.PackerConfig       `mapstructure:",squash"`
+	common.IfExistsConfig     `mapstructure:",squash"`
 	awscommon.AMIBlockDevices `mapstructure:",squash"`
 	awscommon.AMIConfig       `mapstructure:",squash"`
 	awscommon.AccessConfig    `mapstructure:",squash"`
@@ -54,6 +56,11 @@ type wrappedCommandTemplate struct {
 	Command string
 }
 
+// GetContext is the interpolation context of the steps of common/chroot.
+func (c *Config) GetContext() interpolate.Context {
+	return c.ctx
+}
+
 type Builder struct {
 	config Config
 	runner multistep.Runner
@@ -81,30 +88,20 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 		return nil, err
 	}
 
-	if b.config.PackerConfig.PackerForce {
+	if b.config.PackerConfig.PackerForce || b.config.IfExists == common.IfExistsReplace {
 		b.config.AMIForceDeregister = true
 	}
 
 	// Defaults
-	if b.config.ChrootMounts == nil {
-		b.config.ChrootMounts = make([][]string, 0)
-	}
-
 	if len(b.config.ChrootMounts) == 0 {
-		b.config.ChrootMounts = [][]string{
-			{"proc", "proc", "/proc"},
-			{"sysfs", "sysfs", "/sys"},
-			{"bind", "/dev", "/dev"},
-			{"devpts", "devpts", "/dev/pts"},
-			{"binfmt_misc", "binfmt_misc", "/proc/sys/fs/binfmt_misc"},
-		}
+		b.config.ChrootMounts = chroot.DefaultChrootMounts()
 	}
 
 	// set default copy file if we're not giving our own
 	if b.config.CopyFiles == nil {
 		b.config.CopyFiles = make([]string, 0)
 		if !b.config.FromScratch {
-			b.config.CopyFiles = []string{"/etc/resolv.conf"}
+			b.config.CopyFiles = chroot.DefaultCopyFiles
 		}
 	}
 
@@ -125,6 +122,7 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 	var warns []string
 
 	errs = packer.MultiErrorAppend(errs, b.config.AccessConfig.Prepare(&b.config.ctx)...)
+	errs = packer.MultiErrorAppend(errs, b.config.IfExistsConfig.Prepare()...)
 	errs = packer.MultiErrorAppend(errs,
 		b.config.AMIConfig.Prepare(&b.config.AccessConfig, &b.config.ctx)...)
 
@@ -205,13 +203,15 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 	state.Put("awsSession", session)
 	state.Put("hook", hook)
 	state.Put("ui", ui)
-	state.Put("wrappedCommand", CommandWrapper(wrappedCommand))
+	state.Put("wrappedCommand", chroot.CommandWrapper(wrappedCommand))
 
 	// Build the steps
 	steps := []multistep.Step{
 		&awscommon.StepPreValidate{
 			DestAmiName:     b.config.AMIName,
 			ForceDeregister: b.config.AMIForceDeregister,
+			IfExists:        b.config.IfExists,
+			Regions:         b.config.AMIRegions,
 		},
 		&StepInstanceInfo{},
 	}
@@ -230,7 +230,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 	}
 
 	steps = append(steps,
-		&StepFlock{},
+		&chroot.StepFlock{},
 		&StepPrepareDevice{},
 		&StepCreateVolume{
 			RootVolumeType: b.config.RootVolumeType,
@@ -239,21 +239,25 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			Ctx:            b.config.ctx,
 		},
 		&StepAttachVolume{},
-		&StepEarlyUnflock{},
-		&StepPreMountCommands{
+		&chroot.StepEarlyUnflock{},
+		&chroot.StepPreMountCommands{
 			Commands: b.config.PreMountCommands,
 		},
 		&StepMountDevice{
 			MountOptions:   b.config.MountOptions,
 			MountPartition: b.config.MountPartition,
 		},
-		&StepPostMountCommands{
+		&chroot.StepPostMountCommands{
 			Commands: b.config.PostMountCommands,
 		},
-		&StepMountExtra{},
-		&StepCopyFiles{},
-		&StepChrootProvision{},
-		&StepEarlyCleanup{},
+		&chroot.StepMountExtra{
+			ChrootMounts: b.config.ChrootMounts,
+		},
+		&chroot.StepCopyFiles{
+			Files: b.config.CopyFiles,
+		},
+		&chroot.StepChrootProvision{},
+		&chroot.StepEarlyCleanup{},
 		&StepSnapshot{},
 		&awscommon.StepDeregisterAMI{
 			AccessConfig:        &b.config.AccessConfig,
@@ -311,10 +315,12 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 	}
 
 	// Build the artifact and return it
+	_, existing := state.GetOk("amis_existing")
 	artifact := &awscommon.Artifact{
 		Amis:           state.Get("amis").(map[string]string),
 		BuilderIdValue: BuilderId,
 		Session:        session,
+		Existing:       existing,
 	}
 
 	return artifact, nil
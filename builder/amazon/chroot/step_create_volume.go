@@ -9,6 +9,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	awscommon "github.com/hashicorp/packer/builder/amazon/common"
+	"github.com/hashicorp/packer/helper/ledger"
 	"github.com/hashicorp/packer/helper/multistep"
 	"github.com/hashicorp/packer/packer"
 	"github.com/hashicorp/packer/template/interpolate"
@@ -21,6 +22,7 @@ import (
 //   volume_id string - The ID of the created volume
 type StepCreateVolume struct {
 	volumeId       string
+	resource       *ledger.Resource
 	RootVolumeSize int64
 	RootVolumeType string
 	RootVolumeTags awscommon.TagMap
@@ -107,6 +109,7 @@ func (s *StepCreateVolume) Run(ctx context.Context, state multistep.StateBag) mu
 
 	// Set the volume ID so we remember to delete it later
 	s.volumeId = *createVolumeResp.VolumeId
+	s.resource = awscommon.TrackResource(ec2conn, "volume", s.volumeId)
 	log.Printf("Volume ID: %s", s.volumeId)
 
 	// Wait for the volume to become ready
@@ -134,7 +137,9 @@ func (s *StepCreateVolume) Cleanup(state multistep.StateBag) {
 	_, err := ec2conn.DeleteVolume(&ec2.DeleteVolumeInput{VolumeId: &s.volumeId})
 	if err != nil {
 		ui.Error(fmt.Sprintf("Error deleting EBS volume: %s", err))
+		return
 	}
+	ledger.Release(s.resource)
 }
 
 func (s *StepCreateVolume) buildCreateVolumeInput(az string, rootDevice *ec2.BlockDeviceMapping) (*ec2.CreateVolumeInput, error) {
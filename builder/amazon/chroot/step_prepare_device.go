@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 
+	"github.com/hashicorp/packer/common/chroot"
 	"github.com/hashicorp/packer/helper/multistep"
 	"github.com/hashicorp/packer/packer"
 )
@@ -22,7 +23,7 @@ func (s *StepPrepareDevice) Run(_ context.Context, state multistep.StateBag) mul
 	if device == "" {
 		var err error
 		log.Println("Device path not specified, searching for available device...")
-		device, err = AvailableDevice()
+		device, err = chroot.AvailableDevice()
 		if err != nil {
 			err := fmt.Errorf("Error finding available device: %s", err)
 			state.Put("error", err)
@@ -7,6 +7,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	awscommon "github.com/hashicorp/packer/builder/amazon/common"
+	"github.com/hashicorp/packer/helper/ledger"
 	"github.com/hashicorp/packer/helper/multistep"
 	"github.com/hashicorp/packer/packer"
 )
@@ -16,6 +17,7 @@ type StepRegisterAMI struct {
 	RootVolumeSize           int64
 	EnableAMIENASupport      *bool
 	EnableAMISriovNetSupport bool
+	resource                 *ledger.Resource
 }
 
 func (s *StepRegisterAMI) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
@@ -83,10 +85,10 @@ func (s *StepRegisterAMI) Run(ctx context.Context, state multistep.StateBag) mul
 		// As of February 2017, this applies to C3, C4, D2, I2, R3, and M4 (excluding m4.16xlarge)
 		registerOpts.SriovNetSupport = aws.String("simple")
 	}
-	if s.EnableAMIENASupport != nil && *s.EnableAMIENASupport {
-		// Set EnaSupport to true
+	if s.EnableAMIENASupport != nil {
+		// Set EnaSupport, or unset the one of the source AMI
 		// As of February 2017, this applies to C5, I3, P2, R4, X1, and m4.16xlarge
-		registerOpts.EnaSupport = aws.Bool(true)
+		registerOpts.EnaSupport = aws.Bool(*s.EnableAMIENASupport)
 	}
 
 	registerResp, err := ec2conn.RegisterImage(registerOpts)
@@ -97,6 +99,7 @@ func (s *StepRegisterAMI) Run(ctx context.Context, state multistep.StateBag) mul
 	}
 
 	// Set the AMI ID in the state
+	s.resource = awscommon.TrackResource(ec2conn, "image", *registerResp.ImageId)
 	ui.Say(fmt.Sprintf("AMI: %s", *registerResp.ImageId))
 	amis := make(map[string]string)
 	amis[*ec2conn.Config.Region] = *registerResp.ImageId
@@ -113,7 +116,28 @@ func (s *StepRegisterAMI) Run(ctx context.Context, state multistep.StateBag) mul
 	return multistep.ActionContinue
 }
 
-func (s *StepRegisterAMI) Cleanup(state multistep.StateBag) {}
+func (s *StepRegisterAMI) Cleanup(state multistep.StateBag) {
+	if s.resource == nil {
+		return
+	}
+
+	_, cancelled := state.GetOk(multistep.StateCancelled)
+	_, halted := state.GetOk(multistep.StateHalted)
+	if !cancelled && !halted {
+		ledger.Release(s.resource)
+		return
+	}
+
+	ec2conn := state.Get("ec2").(*ec2.EC2)
+	ui := state.Get("ui").(packer.Ui)
+
+	ui.Say("Deregistering the AMI because cancellation or error...")
+	if err := awscommon.DeleteAMI(ec2conn, s.resource.ID); err != nil {
+		ui.Error(fmt.Sprintf("Error deregistering AMI, may still be around: %s", err))
+		return
+	}
+	ledger.Release(s.resource)
+}
 
 func buildRegisterOpts(config *Config, image *ec2.Image, mappings []*ec2.BlockDeviceMapping) *ec2.RegisterImageInput {
 	registerOpts := &ec2.RegisterImageInput{
@@ -122,6 +146,10 @@ func buildRegisterOpts(config *Config, image *ec2.Image, mappings []*ec2.BlockDe
 		RootDeviceName:      image.RootDeviceName,
 		BlockDeviceMappings: mappings,
 		VirtualizationType:  image.VirtualizationType,
+		// The AMI runs the same system as the source AMI, so it supports
+		// enhanced networking like it does
+		EnaSupport:      image.EnaSupport,
+		SriovNetSupport: image.SriovNetSupport,
 	}
 
 	if config.AMIVirtType != "" {
@@ -71,3 +71,22 @@ func TestStepRegisterAmi_buildRegisterOpts_hvm(t *testing.T) {
 		t.Fatalf("Unexpected KernelId value: expected nil got %s\n", *opts.KernelId)
 	}
 }
+
+func TestStepRegisterAmi_buildRegisterOpts_enhancedNetworking(t *testing.T) {
+	config := Config{}
+	config.AMIName = "test_ami_name"
+	config.AMIVirtType = "hvm"
+
+	image := testImage()
+	image.EnaSupport = aws.Bool(true)
+	image.SriovNetSupport = aws.String("simple")
+
+	opts := buildRegisterOpts(&config, &image, []*ec2.BlockDeviceMapping{})
+
+	if opts.EnaSupport == nil || !*opts.EnaSupport {
+		t.Fatalf("The AMI should support ENA like the source AMI")
+	}
+	if opts.SriovNetSupport == nil || *opts.SriovNetSupport != "simple" {
+		t.Fatalf("The AMI should support SR-IOV like the source AMI")
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/aws/aws-sdk-go/service/ec2"
 	awscommon "github.com/hashicorp/packer/builder/amazon/common"
+	"github.com/hashicorp/packer/helper/ledger"
 	"github.com/hashicorp/packer/helper/multistep"
 	"github.com/hashicorp/packer/packer"
 )
@@ -17,6 +18,7 @@ import (
 //   snapshot_id string - ID of the created snapshot
 type StepSnapshot struct {
 	snapshotId string
+	resource   *ledger.Resource
 }
 
 func (s *StepSnapshot) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
@@ -40,6 +42,7 @@ func (s *StepSnapshot) Run(ctx context.Context, state multistep.StateBag) multis
 
 	// Set the snapshot ID so we can delete it later
 	s.snapshotId = *createSnapResp.SnapshotId
+	s.resource = awscommon.TrackResource(ec2conn, "snapshot", s.snapshotId)
 	ui.Message(fmt.Sprintf("Snapshot ID: %s", s.snapshotId))
 
 	// Wait for the snapshot to be ready
@@ -76,6 +79,10 @@ func (s *StepSnapshot) Cleanup(state multistep.StateBag) {
 		_, err := ec2conn.DeleteSnapshot(&ec2.DeleteSnapshotInput{SnapshotId: &s.snapshotId})
 		if err != nil {
 			ui.Error(fmt.Sprintf("Error: %s", err))
+			return
 		}
 	}
+
+	// The snapshot is either gone or part of the AMI now
+	ledger.Release(s.resource)
 }
@@ -22,6 +22,11 @@ type Artifact struct {
 
 	// EC2 connection for performing API stuff.
 	Session *session.Session
+
+	// Existing is true when the AMIs existed before the build, which
+	// skipped building them. They weren't created by Packer, so Destroy
+	// leaves them.
+	Existing bool
 }
 
 func (a *Artifact) BuilderId() string {
@@ -51,6 +56,9 @@ func (a *Artifact) String() string {
 	}
 
 	sort.Strings(amiStrings)
+	if a.Existing {
+		return fmt.Sprintf("AMIs already existed:\n%s\n", strings.Join(amiStrings, "\n"))
+	}
 	return fmt.Sprintf("AMIs were created:\n%s\n", strings.Join(amiStrings, "\n"))
 }
 
@@ -64,6 +72,11 @@ func (a *Artifact) State(name string) interface{} {
 }
 
 func (a *Artifact) Destroy() error {
+	if a.Existing {
+		log.Printf("Keeping the AMIs that existed before the build: %s", a.Id())
+		return nil
+	}
+
 	errors := make([]error, 0)
 
 	for region, imageId := range a.Amis {
@@ -46,6 +46,22 @@ func TestArtifactState_atlasMetadata(t *testing.T) {
 	}
 }
 
+func TestArtifactDestroy_existing(t *testing.T) {
+	// Without a session, deregistering the AMIs would panic
+	a := &Artifact{
+		Amis:     map[string]string{"east": "foo"},
+		Existing: true,
+	}
+	if err := a.Destroy(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := "AMIs already existed:\neast: foo\n"
+	if result := a.String(); result != expected {
+		t.Fatalf("bad: %s", result)
+	}
+}
+
 func TestArtifactString(t *testing.T) {
 	expected := `AMIs were created:
 east: foo
@@ -0,0 +1,114 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/hashicorp/packer/helper/ledger"
+)
+
+// LedgerProvider is the provider of the resources the Amazon builders
+// record in the ledger.
+const LedgerProvider = "amazon"
+
+// TrackResource records a resource the build created in the ledger, so
+// `packer cleanup` can delete it if the build doesn't get to.
+func TrackResource(ec2conn *ec2.EC2, resourceType, id string) *ledger.Resource {
+	r := &ledger.Resource{
+		Provider: LedgerProvider,
+		Type:     resourceType,
+		ID:       id,
+		Region:   aws.StringValue(ec2conn.Config.Region),
+	}
+	ledger.Track(r)
+	return r
+}
+
+// DeleteLedgerResource deletes a resource recorded by TrackResource. The
+// credentials come from the environment and the shared configuration
+// files, like for the builders.
+func DeleteLedgerResource(r *ledger.Resource) error {
+	sess, err := session.NewSessionWithOptions(session.Options{
+		SharedConfigState: session.SharedConfigEnable,
+		Config:            aws.Config{Region: aws.String(r.Region)},
+	})
+	if err != nil {
+		return err
+	}
+	ec2conn := ec2.New(sess)
+
+	switch r.Type {
+	case "instance":
+		_, err = ec2conn.TerminateInstances(&ec2.TerminateInstancesInput{
+			InstanceIds: []*string{aws.String(r.ID)},
+		})
+		if err == nil {
+			// The security group and the key pair can only go once the
+			// instance is gone.
+			err = WaitUntilInstanceTerminated(aws.BackgroundContext(), ec2conn, r.ID)
+		}
+	case "spot_request":
+		_, err = ec2conn.CancelSpotInstanceRequests(&ec2.CancelSpotInstanceRequestsInput{
+			SpotInstanceRequestIds: []*string{aws.String(r.ID)},
+		})
+	case "key_pair":
+		_, err = ec2conn.DeleteKeyPair(&ec2.DeleteKeyPairInput{KeyName: aws.String(r.ID)})
+	case "security_group":
+		_, err = ec2conn.DeleteSecurityGroup(&ec2.DeleteSecurityGroupInput{GroupId: aws.String(r.ID)})
+	case "volume":
+		_, err = ec2conn.DeleteVolume(&ec2.DeleteVolumeInput{VolumeId: aws.String(r.ID)})
+	case "snapshot":
+		_, err = ec2conn.DeleteSnapshot(&ec2.DeleteSnapshotInput{SnapshotId: aws.String(r.ID)})
+	case "image":
+		err = DeleteAMI(ec2conn, r.ID)
+	default:
+		return fmt.Errorf("unknown resource type %q", r.Type)
+	}
+
+	if awsErr, ok := err.(awserr.Error); ok && strings.HasSuffix(awsErr.Code(), ".NotFound") {
+		return nil
+	}
+	return err
+}
+
+// DeleteAMI deregisters the AMI and deletes the snapshots EC2 created for
+// it with CreateImage or CopyImage, which have the ID of the AMI in their
+// description. The snapshots it was registered from are left alone. An AMI
+// that doesn't exist anymore is not an error.
+func DeleteAMI(ec2conn *ec2.EC2, id string) error {
+	_, err := ec2conn.DeregisterImage(&ec2.DeregisterImageInput{ImageId: aws.String(id)})
+	if awsErr, ok := err.(awserr.Error); ok && strings.HasPrefix(awsErr.Code(), "InvalidAMIID.") {
+		err = nil
+	}
+	if err != nil {
+		return err
+	}
+
+	resp, err := ec2conn.DescribeSnapshots(&ec2.DescribeSnapshotsInput{
+		OwnerIds: []*string{aws.String("self")},
+		Filters: []*ec2.Filter{{
+			Name: aws.String("description"),
+			Values: []*string{
+				aws.String(fmt.Sprintf("Created by CreateImage(*) for %s *", id)),
+				aws.String(fmt.Sprintf("Copied for DestinationAmi %s *", id)),
+			},
+		}},
+	})
+	if err != nil {
+		return err
+	}
+	for _, snapshot := range resp.Snapshots {
+		_, err := ec2conn.DeleteSnapshot(&ec2.DeleteSnapshotInput{SnapshotId: snapshot.SnapshotId})
+		if awsErr, ok := err.(awserr.Error); ok && strings.HasSuffix(awsErr.Code(), ".NotFound") {
+			err = nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package common
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// publicIPURL is the service that answers with the public IP address
+// requests come from. It's modified in tests.
+var publicIPURL = "https://checkip.amazonaws.com"
+
+// publicIPCidr is the CIDR block of the public IP address of this host,
+// like "203.0.113.12/32", as the instance sees the connections of Packer.
+func publicIPCidr() (string, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(publicIPURL)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status from %s: %s", publicIPURL, resp.Status)
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 256))
+	if err != nil {
+		return "", err
+	}
+	ip := net.ParseIP(strings.TrimSpace(string(body)))
+	if ip == nil {
+		return "", fmt.Errorf("unexpected answer from %s: %q", publicIPURL, strings.TrimSpace(string(body)))
+	}
+	if ip.To4() != nil {
+		return ip.String() + "/32", nil
+	}
+	return ip.String() + "/128", nil
+}
@@ -0,0 +1,36 @@
+package common
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPublicIPCidr(t *testing.T) {
+	answer := "203.0.113.12\n"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(answer))
+	}))
+	defer server.Close()
+
+	defer func(url string) { publicIPURL = url }(publicIPURL)
+	publicIPURL = server.URL
+
+	cidr, err := publicIPCidr()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if cidr != "203.0.113.12/32" {
+		t.Fatalf("bad: %s", cidr)
+	}
+
+	answer = "2001:db8::1"
+	if cidr, err = publicIPCidr(); err != nil || cidr != "2001:db8::1/128" {
+		t.Fatalf("bad: %s %v", cidr, err)
+	}
+
+	answer = "<html>"
+	if _, err := publicIPCidr(); err == nil {
+		t.Fatal("should error if the answer isn't an IP address")
+	}
+}
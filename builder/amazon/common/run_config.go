@@ -8,12 +8,15 @@ import (
 	"strings"
 	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/hashicorp/packer/common/uuid"
 	"github.com/hashicorp/packer/helper/communicator"
 	"github.com/hashicorp/packer/template/interpolate"
 )
 
 var reShutdownBehavior = regexp.MustCompile("^(stop|terminate)$")
+var reTenancy = regexp.MustCompile("^(default|dedicated|host)$")
 
 type AmiFilterOptions struct {
 	Filters    map[*string]*string
@@ -55,18 +58,65 @@ func (d *SecurityGroupFilterOptions) Empty() bool {
 	return len(d.Filters) == 0
 }
 
+// CPUOptions are the number of CPU cores and of threads per core of the
+// source instance. Both must be set, see
+// https://docs.aws.amazon.com/AWSEC2/latest/UserGuide/instance-optimize-cpu.html
+type CPUOptions struct {
+	CoreCount      int64 `mapstructure:"core_count"`
+	ThreadsPerCore int64 `mapstructure:"threads_per_core"`
+}
+
+func (o *CPUOptions) Empty() bool {
+	return o.CoreCount == 0 && o.ThreadsPerCore == 0
+}
+
+// LaunchTemplateOptions are the launch template the source instance is
+// launched from, by ID or by name. The settings Packer sets override the
+// ones of the template.
+type LaunchTemplateOptions struct {
+	ID      string `mapstructure:"id"`
+	Name    string `mapstructure:"name"`
+	Version string `mapstructure:"version"`
+}
+
+func (o *LaunchTemplateOptions) Empty() bool {
+	return o.ID == "" && o.Name == "" && o.Version == ""
+}
+
+// Specification is the launch template specification to launch instances
+// with, nil if no launch template is set.
+func (o *LaunchTemplateOptions) Specification() *ec2.LaunchTemplateSpecification {
+	if o.Empty() {
+		return nil
+	}
+	spec := &ec2.LaunchTemplateSpecification{}
+	if o.ID != "" {
+		spec.LaunchTemplateId = aws.String(o.ID)
+	}
+	if o.Name != "" {
+		spec.LaunchTemplateName = aws.String(o.Name)
+	}
+	if o.Version != "" {
+		spec.Version = aws.String(o.Version)
+	}
+	return spec
+}
+
 // RunConfig contains configuration for running an instance from a source
 // AMI and details on how to access that launched image.
 type RunConfig struct {
 	AssociatePublicIpAddress          bool                       `mapstructure:"associate_public_ip_address"`
 	AvailabilityZone                  string                     `mapstructure:"availability_zone"`
 	BlockDurationMinutes              int64                      `mapstructure:"block_duration_minutes"`
+	CPUOptions                        CPUOptions                 `mapstructure:"cpu_options"`
 	DisableStopInstance               bool                       `mapstructure:"disable_stop_instance"`
 	EbsOptimized                      bool                       `mapstructure:"ebs_optimized"`
 	EnableT2Unlimited                 bool                       `mapstructure:"enable_t2_unlimited"`
 	IamInstanceProfile                string                     `mapstructure:"iam_instance_profile"`
 	InstanceInitiatedShutdownBehavior string                     `mapstructure:"shutdown_behavior"`
 	InstanceType                      string                     `mapstructure:"instance_type"`
+	LaunchTemplate                    LaunchTemplateOptions      `mapstructure:"launch_template"`
+	PlacementGroup                    string                     `mapstructure:"placement_group"`
 	SecurityGroupFilter               SecurityGroupFilterOptions `mapstructure:"security_group_filter"`
 	RunTags                           map[string]string          `mapstructure:"run_tags"`
 	SecurityGroupId                   string                     `mapstructure:"security_group_id"`
@@ -80,6 +130,9 @@ type RunConfig struct {
 	SubnetId                          string                     `mapstructure:"subnet_id"`
 	TemporaryKeyPairName              string                     `mapstructure:"temporary_key_pair_name"`
 	TemporarySGSourceCidr             string                     `mapstructure:"temporary_security_group_source_cidr"`
+	TemporarySGSourceCidrs            []string                   `mapstructure:"temporary_security_group_source_cidrs"`
+	TemporarySGSourcePublicIp         bool                       `mapstructure:"temporary_security_group_source_public_ip"`
+	Tenancy                           string                     `mapstructure:"tenancy"`
 	UserData                          string                     `mapstructure:"user_data"`
 	UserDataFile                      string                     `mapstructure:"user_data_file"`
 	VpcFilter                         VpcFilterOptions           `mapstructure:"vpc_filter"`
@@ -137,10 +190,26 @@ func (c *RunConfig) Prepare(ctx *interpolate.Context) []error {
 		errs = append(errs, fmt.Errorf("For security reasons, your source AMI filter must declare an owner."))
 	}
 
-	if c.InstanceType == "" {
+	// The instance type can be the one of the launch template
+	if c.InstanceType == "" && (c.LaunchTemplate.Empty() || c.IsSpotInstance()) {
 		errs = append(errs, fmt.Errorf("An instance_type must be specified"))
 	}
 
+	if !c.LaunchTemplate.Empty() {
+		if c.LaunchTemplate.ID != "" && c.LaunchTemplate.Name != "" {
+			errs = append(errs, fmt.Errorf("Only one of launch_template id or name can be specified."))
+		} else if c.LaunchTemplate.ID == "" && c.LaunchTemplate.Name == "" {
+			errs = append(errs, fmt.Errorf("launch_template must specify an id or a name."))
+		}
+		if c.IsSpotInstance() {
+			errs = append(errs, fmt.Errorf("launch_template cannot be used with Spot Instances."))
+		}
+	}
+
+	if c.Tenancy != "" && !reTenancy.MatchString(c.Tenancy) {
+		errs = append(errs, fmt.Errorf("tenancy only accepts 'default', 'dedicated' or 'host' values."))
+	}
+
 	if c.BlockDurationMinutes%60 != 0 {
 		errs = append(errs, fmt.Errorf(
 			"block_duration_minutes must be multiple of 60"))
@@ -184,11 +253,25 @@ func (c *RunConfig) Prepare(ctx *interpolate.Context) []error {
 		}
 	}
 
-	if c.TemporarySGSourceCidr == "" {
-		c.TemporarySGSourceCidr = "0.0.0.0/0"
-	} else {
-		if _, _, err := net.ParseCIDR(c.TemporarySGSourceCidr); err != nil {
-			errs = append(errs, fmt.Errorf("Error parsing temporary_security_group_source_cidr: %s", err.Error()))
+	if c.TemporarySGSourceCidr != "" {
+		if len(c.TemporarySGSourceCidrs) > 0 {
+			errs = append(errs, fmt.Errorf("Only one of temporary_security_group_source_cidr or temporary_security_group_source_cidrs can be specified."))
+		} else {
+			c.TemporarySGSourceCidrs = []string{c.TemporarySGSourceCidr}
+		}
+	}
+
+	if c.TemporarySGSourcePublicIp {
+		if len(c.TemporarySGSourceCidrs) > 0 {
+			errs = append(errs, fmt.Errorf("temporary_security_group_source_public_ip cannot be used with temporary_security_group_source_cidrs."))
+		}
+	} else if len(c.TemporarySGSourceCidrs) == 0 {
+		c.TemporarySGSourceCidrs = []string{"0.0.0.0/0"}
+	}
+
+	for _, cidr := range c.TemporarySGSourceCidrs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			errs = append(errs, fmt.Errorf("Error parsing temporary_security_group_source_cidrs: %s", err.Error()))
 		}
 	}
 
@@ -210,6 +293,17 @@ func (c *RunConfig) Prepare(ctx *interpolate.Context) []error {
 		}
 	}
 
+	if !c.CPUOptions.Empty() {
+		if c.CPUOptions.CoreCount <= 0 || c.CPUOptions.ThreadsPerCore <= 0 {
+			errs = append(errs, fmt.Errorf("cpu_options must set both core_count and threads_per_core."))
+		} else if c.CPUOptions.ThreadsPerCore > 2 {
+			errs = append(errs, fmt.Errorf("cpu_options threads_per_core must be 1 or 2."))
+		}
+		if c.IsSpotInstance() {
+			errs = append(errs, fmt.Errorf("cpu_options cannot be used with Spot Instances."))
+		}
+	}
+
 	return errs
 }
 
@@ -3,6 +3,7 @@ package common
 import (
 	"io/ioutil"
 	"os"
+	"reflect"
 	"regexp"
 	"testing"
 
@@ -52,6 +53,98 @@ func TestRunConfigPrepare_InstanceType(t *testing.T) {
 	}
 }
 
+func TestRunConfigPrepare_LaunchTemplate(t *testing.T) {
+	c := testConfig()
+	// The instance type can come from the launch template
+	c.InstanceType = ""
+	c.LaunchTemplate = LaunchTemplateOptions{Name: "builders", Version: "$Latest"}
+	if err := c.Prepare(nil); len(err) != 0 {
+		t.Fatalf("err: %s", err)
+	}
+
+	spec := c.LaunchTemplate.Specification()
+	if *spec.LaunchTemplateName != "builders" || *spec.Version != "$Latest" || spec.LaunchTemplateId != nil {
+		t.Fatalf("bad: %s", spec)
+	}
+
+	c.LaunchTemplate = LaunchTemplateOptions{ID: "lt-0123", Name: "builders"}
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatalf("Should error if both the id and the name of the launch_template are specified")
+	}
+
+	c.LaunchTemplate = LaunchTemplateOptions{Version: "2"}
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatalf("Should error if neither the id nor the name of the launch_template is specified")
+	}
+
+	c.InstanceType = "m5.large"
+	c.LaunchTemplate = LaunchTemplateOptions{ID: "lt-0123"}
+	c.SpotPrice = "auto"
+	c.SpotPriceAutoProduct = "Linux/UNIX"
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatalf("Should error if a launch_template is used with a Spot Instance")
+	}
+}
+
+func TestRunConfigPrepare_Tenancy(t *testing.T) {
+	c := testConfig()
+	c.Tenancy = "dedicated"
+	if err := c.Prepare(nil); len(err) != 0 {
+		t.Fatalf("err: %s", err)
+	}
+
+	c.Tenancy = "shared"
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatalf("Should error if tenancy is not default, dedicated or host")
+	}
+}
+
+func TestRunConfigPrepare_TemporarySGSourceCidrs(t *testing.T) {
+	c := testConfig()
+	if err := c.Prepare(nil); len(err) != 0 {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(c.TemporarySGSourceCidrs, []string{"0.0.0.0/0"}) {
+		t.Fatalf("bad default: %#v", c.TemporarySGSourceCidrs)
+	}
+
+	c = testConfig()
+	c.TemporarySGSourceCidr = "10.0.0.0/8"
+	if err := c.Prepare(nil); len(err) != 0 {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(c.TemporarySGSourceCidrs, []string{"10.0.0.0/8"}) {
+		t.Fatalf("bad: %#v", c.TemporarySGSourceCidrs)
+	}
+
+	c = testConfig()
+	c.TemporarySGSourceCidrs = []string{"10.0.0.0/8", "bad"}
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatalf("Should error if a CIDR block can't be parsed")
+	}
+
+	c = testConfig()
+	c.TemporarySGSourceCidr = "10.0.0.0/8"
+	c.TemporarySGSourceCidrs = []string{"192.168.0.0/16"}
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatalf("Should error if both temporary_security_group_source_cidr and temporary_security_group_source_cidrs are set")
+	}
+
+	c = testConfig()
+	c.TemporarySGSourcePublicIp = true
+	if err := c.Prepare(nil); len(err) != 0 {
+		t.Fatalf("err: %s", err)
+	}
+	if len(c.TemporarySGSourceCidrs) != 0 {
+		t.Fatalf("The public IP address should be detected instead: %#v", c.TemporarySGSourceCidrs)
+	}
+
+	c.TemporarySGSourceCidrs = []string{"10.0.0.0/8"}
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatalf("Should error if temporary_security_group_source_public_ip is used with CIDR blocks")
+	}
+}
+
 func TestRunConfigPrepare_SourceAmi(t *testing.T) {
 	c := testConfig()
 	c.SourceAmi = ""
@@ -124,6 +217,32 @@ func TestRunConfigPrepare_EnableT2UnlimitedBadWithSpotInstanceRequest(t *testing
 	}
 }
 
+func TestRunConfigPrepare_CPUOptions(t *testing.T) {
+	c := testConfig()
+	c.CPUOptions = CPUOptions{CoreCount: 2, ThreadsPerCore: 1}
+	if err := c.Prepare(nil); len(err) != 0 {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Both must be set
+	c.CPUOptions = CPUOptions{ThreadsPerCore: 1}
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatalf("Should error if core_count is not set")
+	}
+
+	c.CPUOptions = CPUOptions{CoreCount: 2, ThreadsPerCore: 4}
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatalf("Should error if threads_per_core is more than 2")
+	}
+
+	c.CPUOptions = CPUOptions{CoreCount: 2, ThreadsPerCore: 1}
+	c.SpotPrice = "auto"
+	c.SpotPriceAutoProduct = "Linux/UNIX"
+	if err := c.Prepare(nil); len(err) != 1 {
+		t.Fatalf("Should error if cpu_options are used with a Spot Instance")
+	}
+}
+
 func TestRunConfigPrepare_SpotAuto(t *testing.T) {
 	c := testConfig()
 	c.SpotPrice = "auto"
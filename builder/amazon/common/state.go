@@ -129,7 +129,7 @@ func WaitUntilVolumeDetached(ctx aws.Context, conn *ec2.EC2, volumeId string) er
 	return err
 }
 
-func WaitUntilImageImported(ctx aws.Context, conn *ec2.EC2, taskID string) error {
+func WaitUntilImageImported(ctx aws.Context, conn *ec2.EC2, taskID string, opts ...request.WaiterOption) error {
 	importInput := ec2.DescribeImportImageTasksInput{
 		ImportTaskIds: []*string{&taskID},
 	}
@@ -137,7 +137,7 @@ func WaitUntilImageImported(ctx aws.Context, conn *ec2.EC2, taskID string) error
 	err := WaitForImageToBeImported(conn,
 		ctx,
 		&importInput,
-		getWaiterOptions()...)
+		append(getWaiterOptions(), opts...)...)
 	return err
 }
 
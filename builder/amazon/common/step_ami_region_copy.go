@@ -8,6 +8,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
 
+	"github.com/hashicorp/packer/helper/ledger"
 	"github.com/hashicorp/packer/helper/multistep"
 	"github.com/hashicorp/packer/packer"
 )
@@ -18,6 +19,9 @@ type StepAMIRegionCopy struct {
 	RegionKeyIds      map[string]string
 	EncryptBootVolume bool
 	Name              string
+
+	lock      sync.Mutex
+	resources []*ledger.Resource
 }
 
 func (s *StepAMIRegionCopy) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
@@ -53,7 +57,7 @@ func (s *StepAMIRegionCopy) Run(ctx context.Context, state multistep.StateBag) m
 
 		go func(region string) {
 			defer wg.Done()
-			id, snapshotIds, err := amiRegionCopy(ctx, state, s.AccessConfig, s.Name, ami, region, *ec2conn.Config.Region, regKeyID)
+			id, snapshotIds, err := s.amiRegionCopy(ctx, state, s.AccessConfig, s.Name, ami, region, *ec2conn.Config.Region, regKeyID)
 			lock.Lock()
 			defer lock.Unlock()
 			amis[region] = id
@@ -79,13 +83,41 @@ func (s *StepAMIRegionCopy) Run(ctx context.Context, state multistep.StateBag) m
 	return multistep.ActionContinue
 }
 
+// Cleanup deregisters the copies when the build is cancelled or halted.
 func (s *StepAMIRegionCopy) Cleanup(state multistep.StateBag) {
-	// No cleanup...
+	if len(s.resources) == 0 {
+		return
+	}
+
+	_, cancelled := state.GetOk(multistep.StateCancelled)
+	_, halted := state.GetOk(multistep.StateHalted)
+	if !cancelled && !halted {
+		for _, r := range s.resources {
+			ledger.Release(r)
+		}
+		return
+	}
+
+	ui := state.Get("ui").(packer.Ui)
+	session, err := s.AccessConfig.Session()
+	if err != nil {
+		ui.Error(fmt.Sprintf("Error deregistering the copies of the AMI, they may still be around: %s", err))
+		return
+	}
+	for _, r := range s.resources {
+		ui.Say(fmt.Sprintf("Deregistering the copy %s in %s because cancellation or error...", r.ID, r.Region))
+		regionconn := ec2.New(session.Copy(&aws.Config{Region: aws.String(r.Region)}))
+		if err := DeleteAMI(regionconn, r.ID); err != nil {
+			ui.Error(fmt.Sprintf("Error deregistering AMI, may still be around: %s", err))
+			continue
+		}
+		ledger.Release(r)
+	}
 }
 
 // amiRegionCopy does a copy for the given AMI to the target region and
 // returns the resulting ID and snapshot IDs, or error.
-func amiRegionCopy(ctx context.Context, state multistep.StateBag, config *AccessConfig, name string, imageId string,
+func (s *StepAMIRegionCopy) amiRegionCopy(ctx context.Context, state multistep.StateBag, config *AccessConfig, name string, imageId string,
 	target string, source string, keyID string) (string, []string, error) {
 	snapshotIds := []string{}
 	isEncrypted := false
@@ -115,6 +147,9 @@ func amiRegionCopy(ctx context.Context, state multistep.StateBag, config *Access
 		return "", snapshotIds, fmt.Errorf("Error Copying AMI (%s) to region (%s): %s",
 			imageId, target, err)
 	}
+	s.lock.Lock()
+	s.resources = append(s.resources, TrackResource(regionconn, "image", *resp.ImageId))
+	s.lock.Unlock()
 
 	// Wait for the image to become ready
 	if err := WaitUntilAMIAvailable(ctx, regionconn, *resp.ImageId); err != nil {
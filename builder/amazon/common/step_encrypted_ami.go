@@ -7,12 +7,14 @@ import (
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/hashicorp/packer/helper/ledger"
 	"github.com/hashicorp/packer/helper/multistep"
 	"github.com/hashicorp/packer/packer"
 )
 
 type StepCreateEncryptedAMICopy struct {
 	image             *ec2.Image
+	resource          *ledger.Resource
 	KeyID             string
 	EncryptBootVolume bool
 	Name              string
@@ -64,6 +66,8 @@ func (s *StepCreateEncryptedAMICopy) Run(ctx context.Context, state multistep.St
 		return multistep.ActionHalt
 	}
 
+	s.resource = TrackResource(ec2conn, "image", *copyResp.ImageId)
+
 	// Wait for the copy to become ready
 	ui.Say("Waiting for AMI copy to become ready...")
 	if err := WaitUntilAMIAvailable(ctx, ec2conn, *copyResp.ImageId); err != nil {
@@ -152,13 +156,14 @@ OuterLoop:
 }
 
 func (s *StepCreateEncryptedAMICopy) Cleanup(state multistep.StateBag) {
-	if s.image == nil {
+	if s.resource == nil {
 		return
 	}
 
 	_, cancelled := state.GetOk(multistep.StateCancelled)
 	_, halted := state.GetOk(multistep.StateHalted)
 	if !cancelled && !halted {
+		ledger.Release(s.resource)
 		return
 	}
 
@@ -166,9 +171,9 @@ func (s *StepCreateEncryptedAMICopy) Cleanup(state multistep.StateBag) {
 	ui := state.Get("ui").(packer.Ui)
 
 	ui.Say("Deregistering the AMI because cancellation or error...")
-	deregisterOpts := &ec2.DeregisterImageInput{ImageId: s.image.ImageId}
-	if _, err := ec2conn.DeregisterImage(deregisterOpts); err != nil {
+	if err := DeleteAMI(ec2conn, s.resource.ID); err != nil {
 		ui.Error(fmt.Sprintf("Error deregistering AMI, may still be around: %s", err))
 		return
 	}
+	ledger.Release(s.resource)
 }
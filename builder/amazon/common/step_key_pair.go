@@ -8,6 +8,7 @@ import (
 
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/hashicorp/packer/helper/communicator"
+	"github.com/hashicorp/packer/helper/ledger"
 	"github.com/hashicorp/packer/helper/multistep"
 	"github.com/hashicorp/packer/packer"
 )
@@ -18,6 +19,7 @@ type StepKeyPair struct {
 	DebugKeyPath string
 
 	doCleanup bool
+	resource  *ledger.Resource
 }
 
 func (s *StepKeyPair) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
@@ -63,6 +65,7 @@ func (s *StepKeyPair) Run(_ context.Context, state multistep.StateBag) multistep
 	}
 
 	s.doCleanup = true
+	s.resource = TrackResource(ec2conn, "key_pair", s.Comm.SSHTemporaryKeyPairName)
 
 	// Set some data for use in future steps
 	s.Comm.SSHKeyPairName = s.Comm.SSHTemporaryKeyPairName
@@ -111,6 +114,8 @@ func (s *StepKeyPair) Cleanup(state multistep.StateBag) {
 	if err != nil {
 		ui.Error(fmt.Sprintf(
 			"Error cleaning up keypair. Please delete the key manually: %s", s.Comm.SSHTemporaryKeyPairName))
+	} else {
+		ledger.Release(s.resource)
 	}
 
 	// Also remove the physical key if we're debugging.
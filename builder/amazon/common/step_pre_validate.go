@@ -5,7 +5,9 @@ import (
 	"fmt"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/hashicorp/packer/common"
 	"github.com/hashicorp/packer/helper/multistep"
 	"github.com/hashicorp/packer/packer"
 )
@@ -13,9 +15,15 @@ import (
 // StepPreValidate provides an opportunity to pre-validate any configuration for
 // the build before actually doing any time consuming work
 //
+// When an available AMI of the name of the account exists and IfExists is
+// "skip", the build stops there, with the existing AMI and its copies in the
+// Regions as its artifact. "amis_existing" is set in the state so the
+// artifact doesn't deregister them when it is destroyed.
 type StepPreValidate struct {
 	DestAmiName     string
 	ForceDeregister bool
+	IfExists        string
+	Regions         []string
 }
 
 func (s *StepPreValidate) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
@@ -41,6 +49,22 @@ func (s *StepPreValidate) Run(_ context.Context, state multistep.StateBag) multi
 		return multistep.ActionHalt
 	}
 
+	if len(resp.Images) > 0 && s.IfExists == common.IfExistsSkip {
+		amis, err := s.existingAmis(state)
+		if err != nil {
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		if amis != nil {
+			ui.Say(fmt.Sprintf("AMI %s already exists with the name, skipping the build",
+				amis[*ec2conn.Config.Region]))
+			state.Put("amis", amis)
+			state.Put("amis_existing", true)
+			return multistep.ActionHalt
+		}
+	}
+
 	if len(resp.Images) > 0 {
 		err := fmt.Errorf("Error: name conflicts with an existing AMI: %s", *resp.Images[0].ImageId)
 		state.Put("error", err)
@@ -51,4 +75,61 @@ func (s *StepPreValidate) Run(_ context.Context, state multistep.StateBag) multi
 	return multistep.ActionContinue
 }
 
+// existingAmis returns the available AMIs of the name of the account, by
+// region, or nil if there is none in the region of the build. The copies
+// must exist too, which they don't when the build failed while copying.
+func (s *StepPreValidate) existingAmis(state multistep.StateBag) (map[string]string, error) {
+	ec2conn := state.Get("ec2").(*ec2.EC2)
+	session := state.Get("awsSession").(*session.Session)
+
+	region := *ec2conn.Config.Region
+	id, err := s.ownAmi(ec2conn)
+	if err != nil || id == "" {
+		return nil, err
+	}
+
+	amis := map[string]string{region: id}
+	for _, target := range s.Regions {
+		if _, ok := amis[target]; ok {
+			continue
+		}
+
+		regionconn := ec2.New(session.Copy(&aws.Config{
+			Region: aws.String(target),
+		}))
+		id, err := s.ownAmi(regionconn)
+		if err != nil {
+			return nil, err
+		}
+		if id == "" {
+			return nil, fmt.Errorf("AMI %s exists in %s but its copy in %s doesn't, "+
+				"set if_exists to replace to build it again", amis[region], region, target)
+		}
+		amis[target] = id
+	}
+
+	return amis, nil
+}
+
+// ownAmi returns the available AMI of the name of the account in the region
+// of the connection, or "" if there is none.
+func (s *StepPreValidate) ownAmi(ec2conn *ec2.EC2) (string, error) {
+	resp, err := ec2conn.DescribeImages(&ec2.DescribeImagesInput{
+		Owners: []*string{aws.String("self")},
+		Filters: []*ec2.Filter{{
+			Name:   aws.String("name"),
+			Values: []*string{aws.String(s.DestAmiName)},
+		}, {
+			Name:   aws.String("state"),
+			Values: []*string{aws.String("available")},
+		}}})
+	if err != nil {
+		return "", fmt.Errorf("Error querying AMI in %s: %s", *ec2conn.Config.Region, err)
+	}
+	if len(resp.Images) == 0 {
+		return "", nil
+	}
+	return *resp.Images[0].ImageId, nil
+}
+
 func (s *StepPreValidate) Cleanup(multistep.StateBag) {}
@@ -12,7 +12,9 @@ import (
 	"github.com/aws/aws-sdk-go/service/ec2"
 
 	retry "github.com/hashicorp/packer/common"
+	commonhelper "github.com/hashicorp/packer/helper/common"
 	"github.com/hashicorp/packer/helper/communicator"
+	"github.com/hashicorp/packer/helper/ledger"
 	"github.com/hashicorp/packer/helper/multistep"
 	"github.com/hashicorp/packer/packer"
 	"github.com/hashicorp/packer/template/interpolate"
@@ -21,6 +23,8 @@ import (
 type StepRunSourceInstance struct {
 	AssociatePublicIpAddress          bool
 	BlockDevices                      BlockDevices
+	BuildName                         string
+	CPUOptions                        CPUOptions
 	Comm                              *communicator.Config
 	Ctx                               interpolate.Context
 	Debug                             bool
@@ -31,13 +35,17 @@ type StepRunSourceInstance struct {
 	InstanceInitiatedShutdownBehavior string
 	InstanceType                      string
 	IsRestricted                      bool
+	LaunchTemplate                    LaunchTemplateOptions
+	PlacementGroup                    string
 	SourceAMI                         string
 	Tags                              TagMap
+	Tenancy                           string
 	UserData                          string
 	UserDataFile                      string
 	VolumeTags                        TagMap
 
 	instanceId string
+	resource   *ledger.Resource
 }
 
 func (s *StepRunSourceInstance) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
@@ -105,7 +113,6 @@ func (s *StepRunSourceInstance) Run(ctx context.Context, state multistep.StateBa
 	az := state.Get("availability_zone").(string)
 	runOpts := &ec2.RunInstancesInput{
 		ImageId:             &s.SourceAMI,
-		InstanceType:        &s.InstanceType,
 		UserData:            &userData,
 		MaxCount:            aws.Int64(1),
 		MinCount:            aws.Int64(1),
@@ -115,11 +122,45 @@ func (s *StepRunSourceInstance) Run(ctx context.Context, state multistep.StateBa
 		EbsOptimized:        &s.EbsOptimized,
 	}
 
+	if s.InstanceType != "" {
+		runOpts.InstanceType = &s.InstanceType
+	}
+
+	if s.PlacementGroup != "" {
+		runOpts.Placement.GroupName = &s.PlacementGroup
+	}
+	if s.Tenancy != "" {
+		runOpts.Placement.Tenancy = &s.Tenancy
+	}
+
+	if spec := s.LaunchTemplate.Specification(); spec != nil {
+		ui.Message(fmt.Sprintf("Launching from the launch template %s", launchTemplateName(spec)))
+		runOpts.LaunchTemplate = spec
+
+		// Settings that aren't set are left to the launch template
+		if s.IamInstanceProfile == "" {
+			runOpts.IamInstanceProfile = nil
+		}
+		if userData == "" {
+			runOpts.UserData = nil
+		}
+		if !s.EbsOptimized {
+			runOpts.EbsOptimized = nil
+		}
+	}
+
 	if s.EnableT2Unlimited {
 		creditOption := "unlimited"
 		runOpts.CreditSpecification = &ec2.CreditSpecificationRequest{CpuCredits: &creditOption}
 	}
 
+	if !s.CPUOptions.Empty() {
+		runOpts.CpuOptions = &ec2.CpuOptionsRequest{
+			CoreCount:      aws.Int64(s.CPUOptions.CoreCount),
+			ThreadsPerCore: aws.Int64(s.CPUOptions.ThreadsPerCore),
+		}
+	}
+
 	// Collect tags for tagging on resource creation
 	var tagSpecs []*ec2.TagSpecification
 
@@ -184,6 +225,7 @@ func (s *StepRunSourceInstance) Run(ctx context.Context, state multistep.StateBa
 
 	// Set the instance ID so that the cleanup works properly
 	s.instanceId = instanceId
+	s.resource = TrackResource(ec2conn, "instance", instanceId)
 
 	ui.Message(fmt.Sprintf("Instance ID: %s", instanceId))
 	ui.Say(fmt.Sprintf("Waiting for instance (%v) to become ready...", instanceId))
@@ -223,6 +265,8 @@ func (s *StepRunSourceInstance) Run(ctx context.Context, state multistep.StateBa
 	}
 
 	state.Put("instance", instance)
+	commonhelper.SetBuildValue("ID", s.instanceId, s.BuildName)
+	commonhelper.SetBuildValue("SourceImage", s.SourceAMI, s.BuildName)
 
 	// If we're in a region that doesn't support tagging on instance creation,
 	// do that now.
@@ -306,6 +350,21 @@ func (s *StepRunSourceInstance) Cleanup(state multistep.StateBag) {
 
 		if err := WaitUntilInstanceTerminated(aws.BackgroundContext(), ec2conn, s.instanceId); err != nil {
 			ui.Error(err.Error())
+			return
 		}
+		ledger.Release(s.resource)
+	}
+}
+
+// launchTemplateName is the launch template of the specification, as its
+// name or ID and its version.
+func launchTemplateName(spec *ec2.LaunchTemplateSpecification) string {
+	name := aws.StringValue(spec.LaunchTemplateName)
+	if name == "" {
+		name = aws.StringValue(spec.LaunchTemplateId)
+	}
+	if spec.Version != nil {
+		name = fmt.Sprintf("%s (version %s)", name, *spec.Version)
 	}
+	return name
 }
@@ -14,7 +14,9 @@ import (
 	"github.com/aws/aws-sdk-go/service/ec2"
 
 	retry "github.com/hashicorp/packer/common"
+	commonhelper "github.com/hashicorp/packer/helper/common"
 	"github.com/hashicorp/packer/helper/communicator"
+	"github.com/hashicorp/packer/helper/ledger"
 	"github.com/hashicorp/packer/helper/multistep"
 	"github.com/hashicorp/packer/packer"
 	"github.com/hashicorp/packer/template/interpolate"
@@ -24,6 +26,7 @@ type StepRunSpotInstance struct {
 	AssociatePublicIpAddress          bool
 	BlockDevices                      BlockDevices
 	BlockDurationMinutes              int64
+	BuildName                         string
 	Debug                             bool
 	Comm                              *communicator.Config
 	EbsOptimized                      bool
@@ -31,11 +34,13 @@ type StepRunSpotInstance struct {
 	IamInstanceProfile                string
 	InstanceInitiatedShutdownBehavior string
 	InstanceType                      string
+	PlacementGroup                    string
 	SourceAMI                         string
 	SpotPrice                         string
 	SpotPriceProduct                  string
 	SpotTags                          TagMap
 	Tags                              TagMap
+	Tenancy                           string
 	VolumeTags                        TagMap
 	UserData                          string
 	UserDataFile                      string
@@ -43,6 +48,9 @@ type StepRunSpotInstance struct {
 
 	instanceId  string
 	spotRequest *ec2.SpotInstanceRequest
+
+	spotRequestResource *ledger.Resource
+	instanceResource    *ledger.Resource
 }
 
 func (s *StepRunSpotInstance) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
@@ -171,6 +179,13 @@ func (s *StepRunSpotInstance) Run(ctx context.Context, state multistep.StateBag)
 		EbsOptimized:        &s.EbsOptimized,
 	}
 
+	if s.PlacementGroup != "" {
+		runOpts.Placement.GroupName = &s.PlacementGroup
+	}
+	if s.Tenancy != "" {
+		runOpts.Placement.Tenancy = &s.Tenancy
+	}
+
 	subnetId := state.Get("subnet_id").(string)
 
 	if subnetId != "" && s.AssociatePublicIpAddress {
@@ -208,6 +223,7 @@ func (s *StepRunSpotInstance) Run(ctx context.Context, state multistep.StateBag)
 	}
 
 	s.spotRequest = runSpotResp.SpotInstanceRequests[0]
+	s.spotRequestResource = TrackResource(ec2conn, "spot_request", *s.spotRequest.SpotInstanceRequestId)
 
 	spotRequestId := s.spotRequest.SpotInstanceRequestId
 	ui.Message(fmt.Sprintf("Waiting for spot request (%s) to become active...", *spotRequestId))
@@ -259,6 +275,7 @@ func (s *StepRunSpotInstance) Run(ctx context.Context, state multistep.StateBag)
 
 	// Set the instance ID so that the cleanup works properly
 	s.instanceId = instanceId
+	s.instanceResource = TrackResource(ec2conn, "instance", instanceId)
 
 	ui.Message(fmt.Sprintf("Instance ID: %s", instanceId))
 	ui.Say(fmt.Sprintf("Waiting for instance (%v) to become ready...", instanceId))
@@ -355,6 +372,8 @@ func (s *StepRunSpotInstance) Run(ctx context.Context, state multistep.StateBag)
 	}
 
 	state.Put("instance", instance)
+	commonhelper.SetBuildValue("ID", s.instanceId, s.BuildName)
+	commonhelper.SetBuildValue("SourceImage", s.SourceAMI, s.BuildName)
 
 	return multistep.ActionContinue
 }
@@ -379,7 +398,7 @@ func (s *StepRunSpotInstance) Cleanup(state multistep.StateBag) {
 		if err != nil {
 			ui.Error(err.Error())
 		}
-
+		ledger.Release(s.spotRequestResource)
 	}
 
 	// Terminate the source instance if it exists
@@ -392,6 +411,8 @@ func (s *StepRunSpotInstance) Cleanup(state multistep.StateBag) {
 
 		if err := WaitUntilInstanceTerminated(aws.BackgroundContext(), ec2conn, s.instanceId); err != nil {
 			ui.Error(err.Error())
+			return
 		}
+		ledger.Release(s.instanceResource)
 	}
 }
@@ -12,17 +12,20 @@ import (
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/hashicorp/packer/common/uuid"
 	"github.com/hashicorp/packer/helper/communicator"
+	"github.com/hashicorp/packer/helper/ledger"
 	"github.com/hashicorp/packer/helper/multistep"
 	"github.com/hashicorp/packer/packer"
 )
 
 type StepSecurityGroup struct {
-	CommConfig            *communicator.Config
-	SecurityGroupFilter   SecurityGroupFilterOptions
-	SecurityGroupIds      []string
-	TemporarySGSourceCidr string
+	CommConfig                *communicator.Config
+	SecurityGroupFilter       SecurityGroupFilterOptions
+	SecurityGroupIds          []string
+	TemporarySGSourceCidrs    []string
+	TemporarySGSourcePublicIp bool
 
 	createdGroupId string
+	resource       *ledger.Resource
 }
 
 func (s *StepSecurityGroup) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
@@ -102,6 +105,7 @@ func (s *StepSecurityGroup) Run(_ context.Context, state multistep.StateBag) mul
 
 	// Set the group ID so we can delete it later
 	s.createdGroupId = *groupResp.GroupId
+	s.resource = TrackResource(ec2conn, "security_group", s.createdGroupId)
 
 	// Wait for the security group become available for authorizing
 	log.Printf("[DEBUG] Waiting for temporary security group: %s", s.createdGroupId)
@@ -119,26 +123,40 @@ func (s *StepSecurityGroup) Run(_ context.Context, state multistep.StateBag) mul
 		return multistep.ActionHalt
 	}
 
+	cidrs := s.TemporarySGSourceCidrs
+	if s.TemporarySGSourcePublicIp {
+		ui.Say("Detecting the public IP address of this host...")
+		cidr, err := publicIPCidr()
+		if err != nil {
+			err := fmt.Errorf("Error detecting the public IP address of this host: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		cidrs = []string{cidr}
+	}
+
 	// Authorize the SSH access for the security group
+	permission := &ec2.IpPermission{
+		FromPort:   aws.Int64(int64(port)),
+		ToPort:     aws.Int64(int64(port)),
+		IpProtocol: aws.String("tcp"),
+	}
+	for _, cidr := range cidrs {
+		if strings.Contains(cidr, ":") {
+			permission.Ipv6Ranges = append(permission.Ipv6Ranges, &ec2.Ipv6Range{CidrIpv6: aws.String(cidr)})
+		} else {
+			permission.IpRanges = append(permission.IpRanges, &ec2.IpRange{CidrIp: aws.String(cidr)})
+		}
+	}
 	groupRules := &ec2.AuthorizeSecurityGroupIngressInput{
-		GroupId: groupResp.GroupId,
-		IpPermissions: []*ec2.IpPermission{
-			{
-				FromPort: aws.Int64(int64(port)),
-				ToPort:   aws.Int64(int64(port)),
-				IpRanges: []*ec2.IpRange{
-					{
-						CidrIp: aws.String(s.TemporarySGSourceCidr),
-					},
-				},
-				IpProtocol: aws.String("tcp"),
-			},
-		},
+		GroupId:       groupResp.GroupId,
+		IpPermissions: []*ec2.IpPermission{permission},
 	}
 
 	ui.Say(fmt.Sprintf(
 		"Authorizing access to port %d from %s in the temporary security group...",
-		port, s.TemporarySGSourceCidr))
+		port, strings.Join(cidrs, ", ")))
 	_, err = ec2conn.AuthorizeSecurityGroupIngress(groupRules)
 	if err != nil {
 		err := fmt.Errorf("Error authorizing temporary security group: %s", err)
@@ -177,7 +195,9 @@ func (s *StepSecurityGroup) Cleanup(state multistep.StateBag) {
 	if err != nil {
 		ui.Error(fmt.Sprintf(
 			"Error cleaning up security group. Please delete the group manually: %s", s.createdGroupId))
+		return
 	}
+	ledger.Release(s.resource)
 }
 
 func waitUntilSecurityGroupExists(c *ec2.EC2, input *ec2.DescribeSecurityGroupsInput) error {
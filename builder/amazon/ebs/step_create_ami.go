@@ -8,12 +8,14 @@ import (
 	"github.com/aws/aws-sdk-go/service/ec2"
 	awscommon "github.com/hashicorp/packer/builder/amazon/common"
 	"github.com/hashicorp/packer/common/random"
+	"github.com/hashicorp/packer/helper/ledger"
 	"github.com/hashicorp/packer/helper/multistep"
 	"github.com/hashicorp/packer/packer"
 )
 
 type stepCreateAMI struct {
-	image *ec2.Image
+	image    *ec2.Image
+	resource *ledger.Resource
 }
 
 func (s *stepCreateAMI) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
@@ -46,6 +48,7 @@ func (s *stepCreateAMI) Run(ctx context.Context, state multistep.StateBag) multi
 	}
 
 	// Set the AMI ID in the state
+	s.resource = awscommon.TrackResource(ec2conn, "image", *createResp.ImageId)
 	ui.Message(fmt.Sprintf("AMI: %s", *createResp.ImageId))
 	amis := make(map[string]string)
 	amis[*ec2conn.Config.Region] = *createResp.ImageId
@@ -91,13 +94,14 @@ func (s *stepCreateAMI) Run(ctx context.Context, state multistep.StateBag) multi
 }
 
 func (s *stepCreateAMI) Cleanup(state multistep.StateBag) {
-	if s.image == nil {
+	if s.resource == nil {
 		return
 	}
 
 	_, cancelled := state.GetOk(multistep.StateCancelled)
 	_, halted := state.GetOk(multistep.StateHalted)
 	if !cancelled && !halted {
+		ledger.Release(s.resource)
 		return
 	}
 
@@ -105,9 +109,9 @@ func (s *stepCreateAMI) Cleanup(state multistep.StateBag) {
 	ui := state.Get("ui").(packer.Ui)
 
 	ui.Say("Deregistering the AMI because cancellation or error...")
-	deregisterOpts := &ec2.DeregisterImageInput{ImageId: s.image.ImageId}
-	if _, err := ec2conn.DeregisterImage(deregisterOpts); err != nil {
+	if err := awscommon.DeleteAMI(ec2conn, s.resource.ID); err != nil {
 		ui.Error(fmt.Sprintf("Error deregistering AMI, may still be around: %s", err))
 		return
 	}
+	ledger.Release(s.resource)
 }
@@ -21,6 +21,8 @@ const BuilderId = "mitchellh.amazon.ebssurrogate"
 
 type Config struct {
 	common.PackerConfig    `mapstructure:",squash"`
+	common.IfExistsConfig  `mapstructure:",squash"`
+	common.CloudInitConfig `mapstructure:",squash"`
 	awscommon.AccessConfig `mapstructure:",squash"`
 	awscommon.RunConfig    `mapstructure:",squash"`
 	awscommon.BlockDevices `mapstructure:",squash"`
@@ -57,14 +59,16 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 		return nil, err
 	}
 
-	if b.config.PackerConfig.PackerForce {
+	if b.config.PackerConfig.PackerForce || b.config.IfExists == common.IfExistsReplace {
 		b.config.AMIForceDeregister = true
 	}
 
 	// Accumulate any errors
 	var errs *packer.MultiError
 	errs = packer.MultiErrorAppend(errs, b.config.AccessConfig.Prepare(&b.config.ctx)...)
+	errs = packer.MultiErrorAppend(errs, b.config.IfExistsConfig.Prepare()...)
 	errs = packer.MultiErrorAppend(errs, b.config.RunConfig.Prepare(&b.config.ctx)...)
+	errs = packer.MultiErrorAppend(errs, b.config.CloudInitConfig.Prepare()...)
 	errs = packer.MultiErrorAppend(errs,
 		b.config.AMIConfig.Prepare(&b.config.AccessConfig, &b.config.ctx)...)
 	errs = packer.MultiErrorAppend(errs, b.config.BlockDevices.Prepare(&b.config.ctx)...)
@@ -122,6 +126,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			AssociatePublicIpAddress:          b.config.AssociatePublicIpAddress,
 			BlockDevices:                      b.config.BlockDevices,
 			BlockDurationMinutes:              b.config.BlockDurationMinutes,
+			BuildName:                         b.config.PackerBuildName,
 			Ctx:                               b.config.ctx,
 			Comm:                              &b.config.RunConfig.Comm,
 			Debug:                             b.config.PackerDebug,
@@ -130,11 +135,13 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			IamInstanceProfile:                b.config.IamInstanceProfile,
 			InstanceInitiatedShutdownBehavior: b.config.InstanceInitiatedShutdownBehavior,
 			InstanceType:                      b.config.InstanceType,
+			PlacementGroup:                    b.config.PlacementGroup,
 			SourceAMI:                         b.config.SourceAmi,
 			SpotPrice:                         b.config.SpotPrice,
 			SpotPriceProduct:                  b.config.SpotPriceAutoProduct,
 			SpotTags:                          b.config.SpotTags,
 			Tags:                              b.config.RunTags,
+			Tenancy:                           b.config.Tenancy,
 			UserData:                          b.config.UserData,
 			UserDataFile:                      b.config.UserDataFile,
 			VolumeTags:                        b.config.VolumeRunTags,
@@ -143,6 +150,8 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		instanceStep = &awscommon.StepRunSourceInstance{
 			AssociatePublicIpAddress:          b.config.AssociatePublicIpAddress,
 			BlockDevices:                      b.config.BlockDevices,
+			BuildName:                         b.config.PackerBuildName,
+			CPUOptions:                        b.config.CPUOptions,
 			Comm:                              &b.config.RunConfig.Comm,
 			Ctx:                               b.config.ctx,
 			Debug:                             b.config.PackerDebug,
@@ -153,8 +162,11 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			InstanceInitiatedShutdownBehavior: b.config.InstanceInitiatedShutdownBehavior,
 			InstanceType:                      b.config.InstanceType,
 			IsRestricted:                      b.config.IsChinaCloud() || b.config.IsGovCloud(),
+			LaunchTemplate:                    b.config.LaunchTemplate,
+			PlacementGroup:                    b.config.PlacementGroup,
 			SourceAMI:                         b.config.SourceAmi,
 			Tags:                              b.config.RunTags,
+			Tenancy:                           b.config.Tenancy,
 			UserData:                          b.config.UserData,
 			UserDataFile:                      b.config.UserDataFile,
 			VolumeTags:                        b.config.VolumeRunTags,
@@ -169,6 +181,8 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		&awscommon.StepPreValidate{
 			DestAmiName:     b.config.AMIName,
 			ForceDeregister: b.config.AMIForceDeregister,
+			IfExists:        b.config.IfExists,
+			Regions:         b.config.AMIRegions,
 		},
 		&awscommon.StepSourceAMIInfo{
 			SourceAmi:                b.config.SourceAmi,
@@ -192,10 +206,11 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			DebugKeyPath: fmt.Sprintf("ec2_%s.pem", b.config.PackerBuildName),
 		},
 		&awscommon.StepSecurityGroup{
-			SecurityGroupFilter:   b.config.SecurityGroupFilter,
-			SecurityGroupIds:      b.config.SecurityGroupIds,
-			CommConfig:            &b.config.RunConfig.Comm,
-			TemporarySGSourceCidr: b.config.TemporarySGSourceCidr,
+			SecurityGroupFilter:       b.config.SecurityGroupFilter,
+			SecurityGroupIds:          b.config.SecurityGroupIds,
+			CommConfig:                &b.config.RunConfig.Comm,
+			TemporarySGSourceCidrs:    b.config.TemporarySGSourceCidrs,
+			TemporarySGSourcePublicIp: b.config.TemporarySGSourcePublicIp,
 		},
 		&awscommon.StepCleanupVolumes{
 			BlockDevices: b.config.BlockDevices,
@@ -208,12 +223,17 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			BuildName: b.config.PackerBuildName,
 		},
 		&communicator.StepConnect{
-			Config: &b.config.RunConfig.Comm,
+			Config:    &b.config.RunConfig.Comm,
+			BuildName: b.config.PackerBuildName,
 			Host: awscommon.SSHHost(
 				ec2conn,
 				b.config.Comm.SSHInterface),
 			SSHConfig: b.config.RunConfig.Comm.SSHConfigFunc(),
 		},
+		&common.StepWaitCloudInit{
+			Config: &b.config.CloudInitConfig,
+			Comm:   &b.config.RunConfig.Comm,
+		},
 		&common.StepProvision{},
 		&common.StepCleanupTempKeys{
 			Comm: &b.config.RunConfig.Comm,
@@ -282,10 +302,12 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 
 	if amis, ok := state.GetOk("amis"); ok {
 		// Build the artifact and return it
+		_, existing := state.GetOk("amis_existing")
 		artifact := &awscommon.Artifact{
 			Amis:           amis.(map[string]string),
 			BuilderIdValue: BuilderId,
 			Session:        session,
+			Existing:       existing,
 		}
 
 		return artifact, nil
@@ -7,6 +7,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	awscommon "github.com/hashicorp/packer/builder/amazon/common"
+	"github.com/hashicorp/packer/helper/ledger"
 	"github.com/hashicorp/packer/helper/multistep"
 	"github.com/hashicorp/packer/packer"
 )
@@ -19,6 +20,7 @@ type StepRegisterAMI struct {
 	EnableAMIENASupport      *bool
 	EnableAMISriovNetSupport bool
 	image                    *ec2.Image
+	resource                 *ledger.Resource
 }
 
 func (s *StepRegisterAMI) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
@@ -44,10 +46,10 @@ func (s *StepRegisterAMI) Run(ctx context.Context, state multistep.StateBag) mul
 		// As of February 2017, this applies to C3, C4, D2, I2, R3, and M4 (excluding m4.16xlarge)
 		registerOpts.SriovNetSupport = aws.String("simple")
 	}
-	if s.EnableAMIENASupport != nil && *s.EnableAMIENASupport {
-		// Set EnaSupport to true
+	if s.EnableAMIENASupport != nil {
+		// Set EnaSupport
 		// As of February 2017, this applies to C5, I3, P2, R4, X1, and m4.16xlarge
-		registerOpts.EnaSupport = aws.Bool(true)
+		registerOpts.EnaSupport = aws.Bool(*s.EnableAMIENASupport)
 	}
 	registerResp, err := ec2conn.RegisterImage(registerOpts)
 	if err != nil {
@@ -57,6 +59,7 @@ func (s *StepRegisterAMI) Run(ctx context.Context, state multistep.StateBag) mul
 	}
 
 	// Set the AMI ID in the state
+	s.resource = awscommon.TrackResource(ec2conn, "image", *registerResp.ImageId)
 	ui.Say(fmt.Sprintf("AMI: %s", *registerResp.ImageId))
 	amis := make(map[string]string)
 	amis[*ec2conn.Config.Region] = *registerResp.ImageId
@@ -93,13 +96,14 @@ func (s *StepRegisterAMI) Run(ctx context.Context, state multistep.StateBag) mul
 }
 
 func (s *StepRegisterAMI) Cleanup(state multistep.StateBag) {
-	if s.image == nil {
+	if s.resource == nil {
 		return
 	}
 
 	_, cancelled := state.GetOk(multistep.StateCancelled)
 	_, halted := state.GetOk(multistep.StateHalted)
 	if !cancelled && !halted {
+		ledger.Release(s.resource)
 		return
 	}
 
@@ -107,11 +111,11 @@ func (s *StepRegisterAMI) Cleanup(state multistep.StateBag) {
 	ui := state.Get("ui").(packer.Ui)
 
 	ui.Say("Deregistering the AMI because cancellation or error...")
-	deregisterOpts := &ec2.DeregisterImageInput{ImageId: s.image.ImageId}
-	if _, err := ec2conn.DeregisterImage(deregisterOpts); err != nil {
+	if err := awscommon.DeleteAMI(ec2conn, s.resource.ID); err != nil {
 		ui.Error(fmt.Sprintf("Error deregistering AMI, may still be around: %s", err))
 		return
 	}
+	ledger.Release(s.resource)
 }
 
 func (s *StepRegisterAMI) combineDevices(snapshotIds map[string]string) []*ec2.BlockDeviceMapping {
@@ -9,6 +9,7 @@ import (
 	"github.com/aws/aws-sdk-go/service/ec2"
 	multierror "github.com/hashicorp/go-multierror"
 	awscommon "github.com/hashicorp/packer/builder/amazon/common"
+	"github.com/hashicorp/packer/helper/ledger"
 	"github.com/hashicorp/packer/helper/multistep"
 	"github.com/hashicorp/packer/packer"
 )
@@ -20,6 +21,8 @@ import (
 type StepSnapshotVolumes struct {
 	LaunchDevices []*ec2.BlockDeviceMapping
 	snapshotIds   map[string]string
+	resources     []*ledger.Resource
+	lock          sync.Mutex
 }
 
 func (s *StepSnapshotVolumes) snapshotVolume(ctx context.Context, deviceName string, state multistep.StateBag) error {
@@ -49,7 +52,10 @@ func (s *StepSnapshotVolumes) snapshotVolume(ctx context.Context, deviceName str
 	}
 
 	// Set the snapshot ID so we can delete it later
+	s.lock.Lock()
 	s.snapshotIds[deviceName] = *createSnapResp.SnapshotId
+	s.resources = append(s.resources, awscommon.TrackResource(ec2conn, "snapshot", *createSnapResp.SnapshotId))
+	s.lock.Unlock()
 
 	// Wait for snapshot to be created
 	err = awscommon.WaitUntilSnapshotDone(ctx, ec2conn, *createSnapResp.SnapshotId)
@@ -68,7 +74,9 @@ func (s *StepSnapshotVolumes) Run(ctx context.Context, state multistep.StateBag)
 		go func(device *ec2.BlockDeviceMapping) {
 			defer wg.Done()
 			if err := s.snapshotVolume(ctx, *device.DeviceName, state); err != nil {
+				s.lock.Lock()
 				errs = multierror.Append(errs, err)
+				s.lock.Unlock()
 			}
 		}(device)
 	}
@@ -97,11 +105,19 @@ func (s *StepSnapshotVolumes) Cleanup(state multistep.StateBag) {
 		ec2conn := state.Get("ec2").(*ec2.EC2)
 		ui := state.Get("ui").(packer.Ui)
 		ui.Say("Removing snapshots since we cancelled or halted...")
-		for _, snapshotId := range s.snapshotIds {
-			_, err := ec2conn.DeleteSnapshot(&ec2.DeleteSnapshotInput{SnapshotId: &snapshotId})
+		for _, r := range s.resources {
+			_, err := ec2conn.DeleteSnapshot(&ec2.DeleteSnapshotInput{SnapshotId: &r.ID})
 			if err != nil {
 				ui.Error(fmt.Sprintf("Error: %s", err))
+				continue
 			}
+			ledger.Release(r)
 		}
+		return
+	}
+
+	// The snapshots are part of the AMI now
+	for _, r := range s.resources {
+		ledger.Release(r)
 	}
 }
@@ -26,6 +26,8 @@ const BuilderId = "mitchellh.amazon.instance"
 // settable from the template.
 type Config struct {
 	common.PackerConfig    `mapstructure:",squash"`
+	common.IfExistsConfig  `mapstructure:",squash"`
+	common.CloudInitConfig `mapstructure:",squash"`
 	awscommon.AccessConfig `mapstructure:",squash"`
 	awscommon.AMIConfig    `mapstructure:",squash"`
 	awscommon.BlockDevices `mapstructure:",squash"`
@@ -77,7 +79,7 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 		return nil, err
 	}
 
-	if b.config.PackerConfig.PackerForce {
+	if b.config.PackerConfig.PackerForce || b.config.IfExists == common.IfExistsReplace {
 		b.config.AMIForceDeregister = true
 	}
 
@@ -127,10 +129,12 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 	// Accumulate any errors
 	var errs *packer.MultiError
 	errs = packer.MultiErrorAppend(errs, b.config.AccessConfig.Prepare(&b.config.ctx)...)
+	errs = packer.MultiErrorAppend(errs, b.config.IfExistsConfig.Prepare()...)
 	errs = packer.MultiErrorAppend(errs, b.config.BlockDevices.Prepare(&b.config.ctx)...)
 	errs = packer.MultiErrorAppend(errs,
 		b.config.AMIConfig.Prepare(&b.config.AccessConfig, &b.config.ctx)...)
 	errs = packer.MultiErrorAppend(errs, b.config.RunConfig.Prepare(&b.config.ctx)...)
+	errs = packer.MultiErrorAppend(errs, b.config.CloudInitConfig.Prepare()...)
 
 	if b.config.AccountId == "" {
 		errs = packer.MultiErrorAppend(errs, errors.New("account_id is required"))
@@ -192,17 +196,20 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			AssociatePublicIpAddress: b.config.AssociatePublicIpAddress,
 			BlockDevices:             b.config.BlockDevices,
 			BlockDurationMinutes:     b.config.BlockDurationMinutes,
+			BuildName:                b.config.PackerBuildName,
 			Ctx:                      b.config.ctx,
 			Comm:                     &b.config.RunConfig.Comm,
 			Debug:                    b.config.PackerDebug,
 			EbsOptimized:             b.config.EbsOptimized,
 			IamInstanceProfile:       b.config.IamInstanceProfile,
 			InstanceType:             b.config.InstanceType,
+			PlacementGroup:           b.config.PlacementGroup,
 			SourceAMI:                b.config.SourceAmi,
 			SpotPrice:                b.config.SpotPrice,
 			SpotPriceProduct:         b.config.SpotPriceAutoProduct,
 			Tags:                     b.config.RunTags,
 			SpotTags:                 b.config.SpotTags,
+			Tenancy:                  b.config.Tenancy,
 			UserData:                 b.config.UserData,
 			UserDataFile:             b.config.UserDataFile,
 		}
@@ -210,6 +217,8 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		instanceStep = &awscommon.StepRunSourceInstance{
 			AssociatePublicIpAddress: b.config.AssociatePublicIpAddress,
 			BlockDevices:             b.config.BlockDevices,
+			BuildName:                b.config.PackerBuildName,
+			CPUOptions:               b.config.CPUOptions,
 			Comm:                     &b.config.RunConfig.Comm,
 			Ctx:                      b.config.ctx,
 			Debug:                    b.config.PackerDebug,
@@ -218,8 +227,11 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			IamInstanceProfile:       b.config.IamInstanceProfile,
 			InstanceType:             b.config.InstanceType,
 			IsRestricted:             b.config.IsChinaCloud() || b.config.IsGovCloud(),
+			LaunchTemplate:           b.config.LaunchTemplate,
+			PlacementGroup:           b.config.PlacementGroup,
 			SourceAMI:                b.config.SourceAmi,
 			Tags:                     b.config.RunTags,
+			Tenancy:                  b.config.Tenancy,
 			UserData:                 b.config.UserData,
 			UserDataFile:             b.config.UserDataFile,
 		}
@@ -230,6 +242,8 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		&awscommon.StepPreValidate{
 			DestAmiName:     b.config.AMIName,
 			ForceDeregister: b.config.AMIForceDeregister,
+			IfExists:        b.config.IfExists,
+			Regions:         b.config.AMIRegions,
 		},
 		&awscommon.StepSourceAMIInfo{
 			SourceAmi:                b.config.SourceAmi,
@@ -253,10 +267,11 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			DebugKeyPath: fmt.Sprintf("ec2_%s.pem", b.config.PackerBuildName),
 		},
 		&awscommon.StepSecurityGroup{
-			CommConfig:            &b.config.RunConfig.Comm,
-			SecurityGroupFilter:   b.config.SecurityGroupFilter,
-			SecurityGroupIds:      b.config.SecurityGroupIds,
-			TemporarySGSourceCidr: b.config.TemporarySGSourceCidr,
+			CommConfig:                &b.config.RunConfig.Comm,
+			SecurityGroupFilter:       b.config.SecurityGroupFilter,
+			SecurityGroupIds:          b.config.SecurityGroupIds,
+			TemporarySGSourceCidrs:    b.config.TemporarySGSourceCidrs,
+			TemporarySGSourcePublicIp: b.config.TemporarySGSourcePublicIp,
 		},
 		instanceStep,
 		&awscommon.StepGetPassword{
@@ -266,12 +281,17 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			BuildName: b.config.PackerBuildName,
 		},
 		&communicator.StepConnect{
-			Config: &b.config.RunConfig.Comm,
+			Config:    &b.config.RunConfig.Comm,
+			BuildName: b.config.PackerBuildName,
 			Host: awscommon.SSHHost(
 				ec2conn,
 				b.config.Comm.SSHInterface),
 			SSHConfig: b.config.RunConfig.Comm.SSHConfigFunc(),
 		},
+		&common.StepWaitCloudInit{
+			Config: &b.config.CloudInitConfig,
+			Comm:   &b.config.RunConfig.Comm,
+		},
 		&common.StepProvision{},
 		&common.StepCleanupTempKeys{
 			Comm: &b.config.RunConfig.Comm,
@@ -332,10 +352,12 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 	}
 
 	// Build the artifact and return it
+	_, existing := state.GetOk("amis_existing")
 	artifact := &awscommon.Artifact{
 		Amis:           state.Get("amis").(map[string]string),
 		BuilderIdValue: BuilderId,
 		Session:        session,
+		Existing:       existing,
 	}
 
 	return artifact, nil
@@ -7,6 +7,7 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	awscommon "github.com/hashicorp/packer/builder/amazon/common"
+	"github.com/hashicorp/packer/helper/ledger"
 	"github.com/hashicorp/packer/helper/multistep"
 	"github.com/hashicorp/packer/packer"
 )
@@ -14,6 +15,7 @@ import (
 type StepRegisterAMI struct {
 	EnableAMIENASupport      *bool
 	EnableAMISriovNetSupport bool
+	resource                 *ledger.Resource
 }
 
 func (s *StepRegisterAMI) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
@@ -52,6 +54,7 @@ func (s *StepRegisterAMI) Run(ctx context.Context, state multistep.StateBag) mul
 	}
 
 	// Set the AMI ID in the state
+	s.resource = awscommon.TrackResource(ec2conn, "image", *registerResp.ImageId)
 	ui.Say(fmt.Sprintf("AMI: %s", *registerResp.ImageId))
 	amis := make(map[string]string)
 	amis[*ec2conn.Config.Region] = *registerResp.ImageId
@@ -71,4 +74,25 @@ func (s *StepRegisterAMI) Run(ctx context.Context, state multistep.StateBag) mul
 	return multistep.ActionContinue
 }
 
-func (s *StepRegisterAMI) Cleanup(multistep.StateBag) {}
+func (s *StepRegisterAMI) Cleanup(state multistep.StateBag) {
+	if s.resource == nil {
+		return
+	}
+
+	_, cancelled := state.GetOk(multistep.StateCancelled)
+	_, halted := state.GetOk(multistep.StateHalted)
+	if !cancelled && !halted {
+		ledger.Release(s.resource)
+		return
+	}
+
+	ec2conn := state.Get("ec2").(*ec2.EC2)
+	ui := state.Get("ui").(packer.Ui)
+
+	ui.Say("Deregistering the AMI because cancellation or error...")
+	if err := awscommon.DeleteAMI(ec2conn, s.resource.ID); err != nil {
+		ui.Error(fmt.Sprintf("Error deregistering AMI, may still be around: %s", err))
+		return
+	}
+	ledger.Release(s.resource)
+}
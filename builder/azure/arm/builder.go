@@ -171,10 +171,16 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			NewStepValidateTemplate(azureClient, ui, b.config, GetVirtualMachineDeployment),
 			NewStepDeployTemplate(azureClient, ui, b.config, deploymentName, GetVirtualMachineDeployment),
 			NewStepGetIPAddress(azureClient, ui, endpointConnectType),
+			NewStepBastionTunnel(b.config, ui),
 			&communicator.StepConnectSSH{
 				Config:    &b.config.Comm,
 				Host:      lin.SSHHost,
 				SSHConfig: b.config.Comm.SSHConfigFunc(),
+				SSHPort:   b.communicatorPort(b.config.Comm.SSHPort),
+			},
+			&packerCommon.StepWaitCloudInit{
+				Config: &b.config.CloudInitConfig,
+				Comm:   &b.config.Comm,
 			},
 			&packerCommon.StepProvision{},
 			&packerCommon.StepCleanupTempKeys{
@@ -201,6 +207,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			NewStepValidateTemplate(azureClient, ui, b.config, GetVirtualMachineDeployment),
 			NewStepDeployTemplate(azureClient, ui, b.config, deploymentName, GetVirtualMachineDeployment),
 			NewStepGetIPAddress(azureClient, ui, endpointConnectType),
+			NewStepBastionTunnel(b.config, ui),
 			&StepSaveWinRMPassword{
 				Password:  b.config.tmpAdminPassword,
 				BuildName: b.config.PackerBuildName,
@@ -216,8 +223,16 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 						Password: b.config.tmpAdminPassword,
 					}, nil
 				},
+				WinRMPort: b.communicatorPort(b.config.Comm.WinRMPort),
+			},
+			&packerCommon.StepWaitCloudInit{
+				Config: &b.config.CloudInitConfig,
+				Comm:   &b.config.Comm,
 			},
 			&packerCommon.StepProvision{},
+			&communicator.StepScramblePassword{
+				Config: &b.config.Comm,
+			},
 			NewStepGetOSDisk(azureClient, ui),
 			NewStepGetAdditionalDisks(azureClient, ui),
 			NewStepSnapshotOSDisk(azureClient, ui, b.config.isManagedImage()),
@@ -302,6 +317,17 @@ func (b *Builder) writeSSHPrivateKey(ui packer.Ui, debugKeyPath string) {
 	}
 }
 
+// communicatorPort is the port the communicator connects to, the local
+// end of the Azure Bastion tunnel when there is one.
+func (b *Builder) communicatorPort(port int) func(multistep.StateBag) (int, error) {
+	return func(stateBag multistep.StateBag) (int, error) {
+		if tunnelPort, ok := stateBag.GetOk(constants.ArmBastionTunnelPort); ok {
+			return tunnelPort.(int), nil
+		}
+		return port, nil
+	}
+}
+
 func (b *Builder) isPublicPrivateNetworkCommunication() bool {
 	return DefaultPrivateVirtualNetworkWithPublicIp != b.config.PrivateVirtualNetworkWithPublicIp
 }
@@ -342,6 +368,7 @@ func (b *Builder) configureStateBag(stateBag multistep.StateBag) {
 	stateBag.Put(constants.AuthorizedKey, b.config.sshAuthorizedKey)
 
 	stateBag.Put(constants.ArmTags, b.config.AzureTags)
+	stateBag.Put(constants.ArmBuildResourceTags, b.config.buildResourceTags())
 	stateBag.Put(constants.ArmComputeName, b.config.tmpComputeName)
 	stateBag.Put(constants.ArmDeploymentName, b.config.tmpDeploymentName)
 	if b.config.OSType == constants.Target_Windows {
@@ -58,6 +58,7 @@ var (
 	reResourceGroupName    = regexp.MustCompile(validResourceGroupNameRe)
 	reSnapshotName         = regexp.MustCompile("^[A-Za-z0-9_]{10,79}$")
 	reSnapshotPrefix       = regexp.MustCompile("^[A-Za-z0-9_]{10,59}$")
+	reTempNamePrefix       = regexp.MustCompile("^[a-z][a-z0-9]{0,9}$")
 )
 
 type PlanInformation struct {
@@ -76,7 +77,8 @@ type SharedImageGallery struct {
 }
 
 type Config struct {
-	common.PackerConfig `mapstructure:",squash"`
+	common.PackerConfig    `mapstructure:",squash"`
+	common.CloudInitConfig `mapstructure:",squash"`
 
 	// Authentication via OAUTH
 	ClientID       string `mapstructure:"client_id"`
@@ -116,6 +118,8 @@ type Config struct {
 
 	// Deployment
 	AzureTags                         map[string]*string `mapstructure:"azure_tags"`
+	TempResourceTags                  map[string]*string `mapstructure:"temp_resource_tags"`
+	TempNamePrefix                    string             `mapstructure:"temp_name_prefix"`
 	ResourceGroupName                 string             `mapstructure:"resource_group_name"`
 	StorageAccount                    string             `mapstructure:"storage_account"`
 	TempComputeName                   string             `mapstructure:"temp_compute_name"`
@@ -128,6 +132,8 @@ type Config struct {
 	VirtualNetworkName                string `mapstructure:"virtual_network_name"`
 	VirtualNetworkSubnetName          string `mapstructure:"virtual_network_subnet_name"`
 	VirtualNetworkResourceGroupName   string `mapstructure:"virtual_network_resource_group_name"`
+	BastionName                       string `mapstructure:"bastion_name"`
+	BastionResourceGroupName          string `mapstructure:"bastion_resource_group_name"`
 	CustomDataFile                    string `mapstructure:"custom_data_file"`
 	customData                        string
 	PlanInfo                          PlanInformation `mapstructure:"plan_info"`
@@ -316,6 +322,7 @@ func newConfig(raws ...interface{}) (*Config, []string, error) {
 
 	var errs *packer.MultiError
 	errs = packer.MultiErrorAppend(errs, c.Comm.Prepare(&c.ctx)...)
+	errs = packer.MultiErrorAppend(errs, c.CloudInitConfig.Prepare()...)
 
 	assertRequiredParametersSet(&c, errs)
 	assertTagProperties(&c, errs)
@@ -374,11 +381,19 @@ func setWinRMCertificate(c *Config) error {
 }
 
 func setRuntimeValues(c *Config) {
-	var tempName = NewTempName()
+	var tempName = NewTempNameWithPrefix(c.TempNamePrefix)
 
 	c.tmpAdminPassword = tempName.AdminPassword
+	if c.PackerBuildName != "" {
+		// The same password as {{build_password}} in the template, like for
+		// the elevated_password of provisioners
+		if password, err := commonhelper.BuildPassword(c.PackerBuildName); err == nil {
+			c.tmpAdminPassword = password
+		}
+	}
 	// store so that we can access this later during provisioning
 	commonhelper.SetSharedState("winrm_password", c.tmpAdminPassword, c.PackerConfig.PackerBuildName)
+	commonhelper.SetBuildValue("Password", c.tmpAdminPassword, c.PackerConfig.PackerBuildName)
 	packer.LogSecretFilter.Set(c.tmpAdminPassword)
 
 	c.tmpCertificatePassword = tempName.CertificatePassword
@@ -465,6 +480,10 @@ func setCustomData(c *Config) error {
 }
 
 func provideDefaultValues(c *Config) {
+	if c.TempNamePrefix == "" {
+		c.TempNamePrefix = DefaultTempNamePrefix
+	}
+
 	if c.VMSize == "" {
 		c.VMSize = DefaultVMSize
 	}
@@ -486,12 +505,31 @@ func provideDefaultValues(c *Config) {
 	}
 }
 
-func assertTagProperties(c *Config, errs *packer.MultiError) {
-	if len(c.AzureTags) > 15 {
-		errs = packer.MultiErrorAppend(errs, fmt.Errorf("a max of 15 tags are supported, but %d were provided", len(c.AzureTags)))
+// buildResourceTags are the tags of the temporary resources of the build,
+// azure_tags and temp_resource_tags. The images and snapshots the build
+// creates only have azure_tags.
+func (c *Config) buildResourceTags() map[string]*string {
+	if len(c.TempResourceTags) == 0 {
+		return c.AzureTags
 	}
 
+	tags := make(map[string]*string, len(c.AzureTags)+len(c.TempResourceTags))
 	for k, v := range c.AzureTags {
+		tags[k] = v
+	}
+	for k, v := range c.TempResourceTags {
+		tags[k] = v
+	}
+	return tags
+}
+
+func assertTagProperties(c *Config, errs *packer.MultiError) {
+	tags := c.buildResourceTags()
+	if len(tags) > 15 {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("a max of 15 tags are supported, but %d were provided", len(tags)))
+	}
+
+	for k, v := range tags {
 		if len(k) > 512 {
 			errs = packer.MultiErrorAppend(errs, fmt.Errorf("the tag name %q exceeds (%d) the 512 character limit", k, len(k)))
 		}
@@ -713,6 +751,24 @@ func assertRequiredParametersSet(c *Config, errs *packer.MultiError) {
 	if c.VirtualNetworkName == "" && c.VirtualNetworkSubnetName != "" {
 		errs = packer.MultiErrorAppend(errs, fmt.Errorf("If virtual_network_subnet_name is specified, so must virtual_network_name"))
 	}
+	if c.TempNamePrefix != DefaultTempNamePrefix {
+		if !reTempNamePrefix.MatchString(c.TempNamePrefix) {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("The temp_name_prefix must be 1 to 10 lowercase letters and numbers, starting with a letter"))
+		} else if c.OSType == constants.Target_Windows && len(c.TempNamePrefix) > 3 {
+			// Windows computer names are at most 15 characters
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("The temp_name_prefix of Windows builds must be at most 3 characters"))
+		}
+	}
+
+	if c.VirtualNetworkName == "" && c.BastionName != "" {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("If bastion_name is specified, so must virtual_network_name"))
+	}
+	if c.BastionName == "" && c.BastionResourceGroupName != "" {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("If bastion_resource_group_name is specified, so must bastion_name"))
+	}
+	if c.BastionName != "" && c.PrivateVirtualNetworkWithPublicIp {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("bastion_name cannot be used with private_virtual_network_with_public_ip, the VM is reached through its public IP"))
+	}
 
 	/////////////////////////////////////////////
 	// Plan Info
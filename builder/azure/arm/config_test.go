@@ -2,6 +2,7 @@ package arm
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -351,6 +352,41 @@ func TestConfigVirtualNetworkSubnetNameMustBeSetWithVirtualNetworkName(t *testin
 	}
 }
 
+func TestConfigBastionNameMustBeSetWithVirtualNetworkName(t *testing.T) {
+	config := map[string]string{
+		"capture_name_prefix":    "ignore",
+		"capture_container_name": "ignore",
+		"location":               "ignore",
+		"image_url":              "ignore",
+		"storage_account":        "ignore",
+		"resource_group_name":    "ignore",
+		"subscription_id":        "ignore",
+		"os_type":                constants.Target_Linux,
+		"communicator":           "none",
+		"bastion_name":           "MyBastion",
+	}
+
+	_, _, err := newConfig(config, getPackerConfiguration())
+	if err == nil {
+		t.Error("Expected Config to reject bastion_name, if virtual_network_name is not set.")
+	}
+
+	config["virtual_network_name"] = "MyVirtualNetwork"
+	c, _, err := newConfig(config, getPackerConfiguration())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.BastionName != "MyBastion" {
+		t.Errorf("Expected Config to set bastion_name to MyBastion, but got %q", c.BastionName)
+	}
+
+	config["private_virtual_network_with_public_ip"] = "true"
+	_, _, err = newConfig(config, getPackerConfiguration())
+	if err == nil {
+		t.Error("Expected Config to reject bastion_name, if private_virtual_network_with_public_ip is set.")
+	}
+}
+
 func TestConfigShouldDefaultToPublicCloud(t *testing.T) {
 	c, _, _ := newConfig(getArmBuilderConfiguration(), getPackerConfiguration())
 
@@ -786,6 +822,131 @@ func TestConfigShouldAcceptTags(t *testing.T) {
 	}
 }
 
+func TestConfigShouldMergeTempResourceTags(t *testing.T) {
+	config := map[string]interface{}{
+		"capture_name_prefix":    "ignore",
+		"capture_container_name": "ignore",
+		"image_offer":            "ignore",
+		"image_publisher":        "ignore",
+		"image_sku":              "ignore",
+		"location":               "ignore",
+		"storage_account":        "ignore",
+		"resource_group_name":    "ignore",
+		"subscription_id":        "ignore",
+		"communicator":           "none",
+		// Does not matter for this test case, just pick one.
+		"os_type": constants.Target_Linux,
+		"azure_tags": map[string]string{
+			"tag01": "value01",
+			"tag02": "value02",
+		},
+		"temp_resource_tags": map[string]string{
+			"tag02": "temp02",
+			"tag03": "temp03",
+		},
+	}
+
+	c, _, err := newConfig(config, getPackerConfiguration())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(c.AzureTags) != 2 {
+		t.Fatalf("expected to find 2 azure tags, but got %d", len(c.AzureTags))
+	}
+
+	tags := c.buildResourceTags()
+	if len(tags) != 3 {
+		t.Fatalf("expected to find 3 build resource tags, but got %d", len(tags))
+	}
+	if *tags["tag01"] != "value01" || *tags["tag02"] != "temp02" || *tags["tag03"] != "temp03" {
+		t.Errorf("expected temp_resource_tags to be merged over azure_tags, but got %v", tags)
+	}
+}
+
+func TestConfigShouldRejectMergedTagsInExcessOf15(t *testing.T) {
+	azureTags := map[string]string{}
+	tempTags := map[string]string{}
+	for i := 0; i < 8; i++ {
+		azureTags[fmt.Sprintf("tag%.2d", i)] = "ignored"
+		tempTags[fmt.Sprintf("temp%.2d", i)] = "ignored"
+	}
+
+	config := map[string]interface{}{
+		"capture_name_prefix":    "ignore",
+		"capture_container_name": "ignore",
+		"image_offer":            "ignore",
+		"image_publisher":        "ignore",
+		"image_sku":              "ignore",
+		"location":               "ignore",
+		"storage_account":        "ignore",
+		"resource_group_name":    "ignore",
+		"subscription_id":        "ignore",
+		"communicator":           "none",
+		// Does not matter for this test case, just pick one.
+		"os_type":            constants.Target_Linux,
+		"azure_tags":         azureTags,
+		"temp_resource_tags": tempTags,
+	}
+
+	_, _, err := newConfig(config, getPackerConfiguration())
+
+	if err == nil {
+		t.Fatal("expected config to reject based on an excessive amount of merged tags (> 15)")
+	}
+}
+
+func TestConfigTempNamePrefix(t *testing.T) {
+	cases := []struct {
+		prefix string
+		osType string
+		ok     bool
+	}{
+		{"", constants.Target_Windows, true},
+		{"lab", constants.Target_Windows, true},
+		{"labs", constants.Target_Windows, false},
+		{"mylinuxlab", constants.Target_Linux, true},
+		{"mylinuxlabs", constants.Target_Linux, false},
+		{"Lab", constants.Target_Linux, false},
+		{"1ab", constants.Target_Linux, false},
+		{"la-b", constants.Target_Linux, false},
+	}
+
+	for _, tc := range cases {
+		config := map[string]interface{}{
+			"capture_name_prefix":    "ignore",
+			"capture_container_name": "ignore",
+			"image_offer":            "ignore",
+			"image_publisher":        "ignore",
+			"image_sku":              "ignore",
+			"location":               "ignore",
+			"storage_account":        "ignore",
+			"resource_group_name":    "ignore",
+			"subscription_id":        "ignore",
+			"communicator":           "none",
+			"os_type":                tc.osType,
+			"temp_name_prefix":       tc.prefix,
+		}
+
+		c, _, err := newConfig(config, getPackerConfiguration())
+		if (err == nil) != tc.ok {
+			t.Errorf("temp_name_prefix %q: expected ok=%t, got %v", tc.prefix, tc.ok, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+
+		prefix := tc.prefix
+		if prefix == "" {
+			prefix = "pkr"
+		}
+		if !strings.HasPrefix(c.tmpComputeName, prefix+"vm") {
+			t.Errorf("temp_name_prefix %q: expected the compute name to start with %q, got %q", tc.prefix, prefix+"vm", c.tmpComputeName)
+		}
+	}
+}
+
 func TestConfigShouldRejectTagsInExcessOf15AcceptTags(t *testing.T) {
 	tooManyTags := map[string]string{}
 	for i := 0; i < 16; i++ {
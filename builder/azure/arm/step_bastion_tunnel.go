@@ -0,0 +1,162 @@
+package arm
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/packer/builder/azure/common/constants"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// StepBastionTunnel opens a tunnel to the VM through Azure Bastion with the
+// Azure CLI, for VMs in virtual networks without a public IP the host
+// running Packer can't reach. The communicator then connects to the local
+// end of the tunnel. It does nothing if no bastion_name is set.
+type StepBastionTunnel struct {
+	config  *Config
+	start   func(args []string) (stop func(), exited <-chan error, err error)
+	timeout time.Duration
+	say     func(message string)
+	error   func(e error)
+
+	stop func()
+}
+
+func NewStepBastionTunnel(config *Config, ui packer.Ui) *StepBastionTunnel {
+	var step = &StepBastionTunnel{
+		config:  config,
+		timeout: 2 * time.Minute,
+		say:     func(message string) { ui.Say(message) },
+		error:   func(e error) { ui.Error(e.Error()) },
+	}
+
+	step.start = startAzCommand
+	return step
+}
+
+// startAzCommand runs the Azure CLI with the arguments. Its output is in the
+// error it exits with.
+func startAzCommand(args []string) (func(), <-chan error, error) {
+	var output bytes.Buffer
+	cmd := exec.Command("az", args...)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Start(); err != nil {
+		return nil, nil, err
+	}
+
+	exited := make(chan error, 1)
+	go func() {
+		err := cmd.Wait()
+		if err == nil {
+			err = fmt.Errorf("the command exited")
+		}
+		exited <- fmt.Errorf("%s: %s", err, strings.TrimSpace(output.String()))
+	}()
+	return func() { cmd.Process.Kill() }, exited, nil
+}
+
+func (s *StepBastionTunnel) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	if s.config.BastionName == "" {
+		return multistep.ActionContinue
+	}
+
+	s.say("Opening a tunnel to the VM through Azure Bastion ...")
+
+	var resourceGroupName = state.Get(constants.ArmResourceGroupName).(string)
+	var computeName = state.Get(constants.ArmComputeName).(string)
+	var bastionResourceGroupName = s.config.BastionResourceGroupName
+	if bastionResourceGroupName == "" {
+		bastionResourceGroupName = s.config.VirtualNetworkResourceGroupName
+	}
+
+	s.say(fmt.Sprintf(" -> BastionName              : '%s'", s.config.BastionName))
+	s.say(fmt.Sprintf(" -> BastionResourceGroupName : '%s'", bastionResourceGroupName))
+
+	localPort, err := freeLocalPort()
+	if err == nil {
+		vmID := fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Compute/virtualMachines/%s",
+			s.config.SubscriptionID, resourceGroupName, computeName)
+		err = s.open(ctx, bastionTunnelArgs(s.config.BastionName, bastionResourceGroupName, vmID, s.config.Comm.Port(), localPort), localPort)
+	}
+	if err != nil {
+		err = fmt.Errorf("Error opening the tunnel through Azure Bastion: %s", err)
+		state.Put(constants.Error, err)
+		s.error(err)
+
+		return multistep.ActionHalt
+	}
+
+	state.Put(constants.SSHHost, "127.0.0.1")
+	state.Put(constants.ArmBastionTunnelPort, localPort)
+	s.say(fmt.Sprintf(" -> Local Port               : '%d'", localPort))
+
+	return multistep.ActionContinue
+}
+
+// open starts the tunnel, and waits for its local port to accept
+// connections.
+func (s *StepBastionTunnel) open(ctx context.Context, args []string, localPort int) error {
+	stop, exited, err := s.start(args)
+	if err != nil {
+		return err
+	}
+	s.stop = stop
+
+	address := net.JoinHostPort("127.0.0.1", strconv.Itoa(localPort))
+	deadline := time.Now().Add(s.timeout)
+	for {
+		conn, err := net.DialTimeout("tcp", address, time.Second)
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timeout waiting for the tunnel on %s", address)
+		}
+
+		select {
+		case err := <-exited:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func bastionTunnelArgs(bastionName, resourceGroupName, vmID string, port, localPort int) []string {
+	return []string{
+		"network", "bastion", "tunnel",
+		"--name", bastionName,
+		"--resource-group", resourceGroupName,
+		"--target-resource-id", vmID,
+		"--resource-port", strconv.Itoa(port),
+		"--port", strconv.Itoa(localPort),
+	}
+}
+
+// freeLocalPort is a port of the loopback interface nothing listens on.
+func freeLocalPort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+func (s *StepBastionTunnel) Cleanup(multistep.StateBag) {
+	if s.stop != nil {
+		s.say("Closing the tunnel through Azure Bastion ...")
+		s.stop()
+		s.stop = nil
+	}
+}
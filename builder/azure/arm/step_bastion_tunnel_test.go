@@ -0,0 +1,109 @@
+package arm
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer/builder/azure/common/constants"
+	"github.com/hashicorp/packer/helper/communicator"
+	"github.com/hashicorp/packer/helper/multistep"
+)
+
+func TestStepBastionTunnelShouldDoNothingWithoutBastion(t *testing.T) {
+	var testSubject = &StepBastionTunnel{
+		config: &Config{},
+		start: func([]string) (func(), <-chan error, error) {
+			t.Fatal("The tunnel should not be started.")
+			return nil, nil, nil
+		},
+		say:   func(message string) {},
+		error: func(e error) {},
+	}
+
+	stateBag := createTestStateBagStepBastionTunnel()
+	if result := testSubject.Run(context.Background(), stateBag); result != multistep.ActionContinue {
+		t.Fatalf("Expected the step to return 'ActionContinue', but got '%d'.", result)
+	}
+	if _, ok := stateBag.GetOk(constants.SSHHost); ok {
+		t.Fatalf("Expected the step to not set stateBag['%s'], but it was.", constants.SSHHost)
+	}
+}
+
+func TestStepBastionTunnelShouldConnectToTheLocalPort(t *testing.T) {
+	var args []string
+	stopped := false
+	var testSubject = &StepBastionTunnel{
+		config: &Config{
+			SubscriptionID:                  "Unit Test: SubscriptionID",
+			BastionName:                     "Unit Test: BastionName",
+			VirtualNetworkResourceGroupName: "Unit Test: VirtualNetworkResourceGroupName",
+			Comm:                            communicator.Config{Type: "ssh", SSHPort: 22},
+		},
+		start: func(a []string) (func(), <-chan error, error) {
+			args = a
+			l, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", a[len(a)-1]))
+			if err != nil {
+				return nil, nil, err
+			}
+			return func() { stopped = true; l.Close() }, nil, nil
+		},
+		timeout: 10 * time.Second,
+		say:     func(message string) {},
+		error:   func(e error) {},
+	}
+
+	stateBag := createTestStateBagStepBastionTunnel()
+	if result := testSubject.Run(context.Background(), stateBag); result != multistep.ActionContinue {
+		t.Fatalf("Expected the step to return 'ActionContinue', but got '%d': %s", result, stateBag.Get(constants.Error))
+	}
+	testSubject.Cleanup(stateBag)
+	if !stopped {
+		t.Fatal("Expected the tunnel to be closed.")
+	}
+
+	port := stateBag.Get(constants.ArmBastionTunnelPort).(int)
+	if host := stateBag.Get(constants.SSHHost).(string); host != "127.0.0.1" {
+		t.Fatalf("Expected the value of stateBag[%s] to be '127.0.0.1', but got '%s'.", constants.SSHHost, host)
+	}
+
+	expected := bastionTunnelArgs(
+		"Unit Test: BastionName",
+		"Unit Test: VirtualNetworkResourceGroupName",
+		"/subscriptions/Unit Test: SubscriptionID/resourceGroups/Unit Test: ResourceGroupName/providers/Microsoft.Compute/virtualMachines/Unit Test: ComputeName",
+		22, port)
+	if !reflect.DeepEqual(args, expected) {
+		t.Fatalf("Expected the arguments %v, but got %v.", expected, args)
+	}
+}
+
+func TestStepBastionTunnelShouldFailIfTheTunnelExits(t *testing.T) {
+	var testSubject = &StepBastionTunnel{
+		config: &Config{BastionName: "Unit Test: BastionName"},
+		start: func([]string) (func(), <-chan error, error) {
+			exited := make(chan error, 1)
+			exited <- context.Canceled
+			return func() {}, exited, nil
+		},
+		timeout: 10 * time.Second,
+		say:     func(message string) {},
+		error:   func(e error) {},
+	}
+
+	stateBag := createTestStateBagStepBastionTunnel()
+	if result := testSubject.Run(context.Background(), stateBag); result != multistep.ActionHalt {
+		t.Fatalf("Expected the step to return 'ActionHalt', but got '%d'.", result)
+	}
+	if _, ok := stateBag.GetOk(constants.Error); !ok {
+		t.Fatalf("Expected the step to set stateBag['%s'], but it was not.", constants.Error)
+	}
+}
+
+func createTestStateBagStepBastionTunnel() multistep.StateBag {
+	stateBag := new(multistep.BasicStateBag)
+	stateBag.Put(constants.ArmResourceGroupName, "Unit Test: ResourceGroupName")
+	stateBag.Put(constants.ArmComputeName, "Unit Test: ComputeName")
+	return stateBag
+}
@@ -145,6 +145,33 @@ func TestStepCreateResourceGroupShouldTakeStepArgumentsFromStateBag(t *testing.T
 	}
 }
 
+func TestStepCreateResourceGroupShouldTagWithBuildResourceTags(t *testing.T) {
+	var actualTags map[string]*string
+
+	var testSubject = &StepCreateResourceGroup{
+		create: func(_ context.Context, resourceGroupName string, location string, tags map[string]*string) error {
+			actualTags = tags
+			return nil
+		},
+		say:    func(message string) {},
+		error:  func(e error) {},
+		exists: func(context.Context, string) (bool, error) { return false, nil },
+	}
+
+	stateBag := createTestStateBagStepCreateResourceGroup()
+	value := "build"
+	stateBag.Put(constants.ArmBuildResourceTags, map[string]*string{"owner": &value})
+	var result = testSubject.Run(context.Background(), stateBag)
+
+	if result != multistep.ActionContinue {
+		t.Fatalf("Expected the step to return 'ActionContinue', but got '%d'.", result)
+	}
+
+	if len(actualTags) != 1 || actualTags["owner"] != &value {
+		t.Fatalf("Expected the step to source 'constants.ArmBuildResourceTags' from the state bag, but got %v.", actualTags)
+	}
+}
+
 func TestStepCreateResourceGroupMarkShouldFailIfTryingExistingButDoesntExist(t *testing.T) {
 	var testSubject = &StepCreateResourceGroup{
 		create: func(context.Context, string, string, map[string]*string) error {
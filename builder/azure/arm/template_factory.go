@@ -23,7 +23,8 @@ func GetKeyVaultDeployment(config *Config) (*resources.Deployment, error) {
 	}
 
 	builder, _ := template.NewTemplateBuilder(template.KeyVault)
-	builder.SetTags(&config.AzureTags)
+	tags := config.buildResourceTags()
+	builder.SetTags(&tags)
 
 	doc, _ := builder.ToJSON()
 	return createDeploymentParameters(*doc, params)
@@ -117,7 +118,8 @@ func GetVirtualMachineDeployment(config *Config) (*resources.Deployment, error)
 			config.VirtualNetworkSubnetName)
 	}
 
-	builder.SetTags(&config.AzureTags)
+	tags := config.buildResourceTags()
+	builder.SetTags(&tags)
 	doc, _ := builder.ToJSON()
 	return createDeploymentParameters(*doc, params)
 }
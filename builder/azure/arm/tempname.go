@@ -2,7 +2,6 @@ package arm
 
 import (
 	"fmt"
-	"strings"
 
 	"github.com/hashicorp/packer/common/random"
 )
@@ -21,51 +20,37 @@ type TempName struct {
 	VirtualNetworkName  string
 }
 
+const DefaultTempNamePrefix = "pkr"
+
 func NewTempName() *TempName {
+	return NewTempNameWithPrefix(DefaultTempNamePrefix)
+}
+
+// NewTempNameWithPrefix returns the names of the temporary resources, which
+// start with the prefix, and the name of the resource group too if the
+// prefix isn't the default one.
+func NewTempNameWithPrefix(prefix string) *TempName {
 	tempName := &TempName{}
 
 	suffix := random.AlphaNumLower(10)
-	tempName.ComputeName = fmt.Sprintf("pkrvm%s", suffix)
-	tempName.DeploymentName = fmt.Sprintf("pkrdp%s", suffix)
-	tempName.KeyVaultName = fmt.Sprintf("pkrkv%s", suffix)
-	tempName.OSDiskName = fmt.Sprintf("pkros%s", suffix)
-	tempName.NicName = fmt.Sprintf("pkrni%s", suffix)
-	tempName.PublicIPAddressName = fmt.Sprintf("pkrip%s", suffix)
-	tempName.SubnetName = fmt.Sprintf("pkrsn%s", suffix)
-	tempName.VirtualNetworkName = fmt.Sprintf("pkrvn%s", suffix)
-	tempName.ResourceGroupName = fmt.Sprintf("packer-Resource-Group-%s", suffix)
+	tempName.ComputeName = fmt.Sprintf("%svm%s", prefix, suffix)
+	tempName.DeploymentName = fmt.Sprintf("%sdp%s", prefix, suffix)
+	tempName.KeyVaultName = fmt.Sprintf("%skv%s", prefix, suffix)
+	tempName.OSDiskName = fmt.Sprintf("%sos%s", prefix, suffix)
+	tempName.NicName = fmt.Sprintf("%sni%s", prefix, suffix)
+	tempName.PublicIPAddressName = fmt.Sprintf("%sip%s", prefix, suffix)
+	tempName.SubnetName = fmt.Sprintf("%ssn%s", prefix, suffix)
+	tempName.VirtualNetworkName = fmt.Sprintf("%svn%s", prefix, suffix)
+	if prefix == DefaultTempNamePrefix {
+		tempName.ResourceGroupName = fmt.Sprintf("packer-Resource-Group-%s", suffix)
+	} else {
+		tempName.ResourceGroupName = fmt.Sprintf("%s-Resource-Group-%s", prefix, suffix)
+	}
 
-	tempName.AdminPassword = generatePassword()
+	// Azure wants three of upper case, lower case, numbers and special
+	// characters, the password has them all
+	tempName.AdminPassword = random.Password(32)
 	tempName.CertificatePassword = random.AlphaNum(32)
 
 	return tempName
 }
-
-// generate a password that is acceptable to Azure
-// Three of the four items must be met.
-//  1. Contains an uppercase character
-//  2. Contains a lowercase character
-//  3. Contains a numeric digit
-//  4. Contains a special character
-func generatePassword() string {
-	var s string
-	for i := 0; i < 100; i++ {
-		s := random.AlphaNum(32)
-		if !strings.ContainsAny(s, random.PossibleNumbers) {
-			continue
-		}
-
-		if !strings.ContainsAny(s, random.PossibleLowerCase) {
-			continue
-		}
-
-		if !strings.ContainsAny(s, random.PossibleUpperCase) {
-			continue
-		}
-
-		return s
-	}
-
-	// if an acceptable password cannot be generated in 100 tries, give up
-	return s
-}
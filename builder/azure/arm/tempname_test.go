@@ -43,6 +43,26 @@ func TestTempNameShouldCreatePrefixedRandomNames(t *testing.T) {
 	}
 }
 
+func TestTempNameWithPrefixShouldPrefixNames(t *testing.T) {
+	tempName := NewTempNameWithPrefix("lab")
+
+	if strings.Index(tempName.ComputeName, "labvm") != 0 {
+		t.Errorf("Expected ComputeName to begin with 'labvm', but got '%s'!", tempName.ComputeName)
+	}
+
+	if strings.Index(tempName.KeyVaultName, "labkv") != 0 {
+		t.Errorf("Expected KeyVaultName to begin with 'labkv', but got '%s'!", tempName.KeyVaultName)
+	}
+
+	if strings.Index(tempName.ResourceGroupName, "lab-Resource-Group-") != 0 {
+		t.Errorf("Expected ResourceGroupName to begin with 'lab-Resource-Group-', but got '%s'!", tempName.ResourceGroupName)
+	}
+
+	if !strings.HasSuffix(tempName.ResourceGroupName, tempName.ComputeName[5:]) {
+		t.Errorf("Expected ResourceGroupName to end with '%s', but got '%s'!", tempName.ComputeName[5:], tempName.ResourceGroupName)
+	}
+}
+
 func TestTempAdminPassword(t *testing.T) {
 	tempName := NewTempName()
 
@@ -86,9 +86,14 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		new(stepDropletInfo),
 		&communicator.StepConnect{
 			Config:    &b.config.Comm,
+			BuildName: b.config.PackerBuildName,
 			Host:      commHost,
 			SSHConfig: b.config.Comm.SSHConfigFunc(),
 		},
+		&common.StepWaitCloudInit{
+			Config: &b.config.CloudInitConfig,
+			Comm:   &b.config.Comm,
+		},
 		new(common.StepProvision),
 		&common.StepCleanupTempKeys{
 			Comm: &b.config.Comm,
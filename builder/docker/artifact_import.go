@@ -2,6 +2,7 @@ package docker
 
 import (
 	"fmt"
+	"log"
 )
 
 // ImportArtifact is an Artifact implementation for when a container is
@@ -10,6 +11,10 @@ type ImportArtifact struct {
 	BuilderIdValue string
 	Driver         Driver
 	IdValue        string
+
+	// Existing is set when the image was already there and the build left
+	// it untouched, in which case Destroy won't delete it.
+	Existing bool
 }
 
 func (a *ImportArtifact) BuilderId() string {
@@ -25,6 +30,9 @@ func (a *ImportArtifact) Id() string {
 }
 
 func (a *ImportArtifact) String() string {
+	if a.Existing {
+		return fmt.Sprintf("Docker image already existed: %s", a.Id())
+	}
 	return fmt.Sprintf("Imported Docker image: %s", a.Id())
 }
 
@@ -33,5 +41,9 @@ func (*ImportArtifact) State(name string) interface{} {
 }
 
 func (a *ImportArtifact) Destroy() error {
+	if a.Existing {
+		log.Printf("Not deleting image that already existed: %s", a.Id())
+		return nil
+	}
 	return a.Driver.DeleteImage(a.Id())
 }
@@ -56,3 +56,19 @@ func TestImportArtifactDestroy(t *testing.T) {
 		t.Fatalf("err: %#v", err)
 	}
 }
+
+func TestImportArtifactDestroy_existing(t *testing.T) {
+	d := new(MockDriver)
+	a := &ImportArtifact{
+		Driver:   d,
+		IdValue:  "foo",
+		Existing: true,
+	}
+
+	if err := a.Destroy(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if d.DeleteImageCalled {
+		t.Fatal("delete image should not be called")
+	}
+}
@@ -47,6 +47,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		&StepRun{},
 		&communicator.StepConnect{
 			Config:    &b.config.Comm,
+			BuildName: b.config.PackerBuildName,
 			Host:      commHost,
 			SSHConfig: b.config.Comm.SSHConfigFunc(),
 			CustomConnect: map[string]multistep.Step{
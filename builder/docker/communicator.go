@@ -9,6 +9,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"syscall"
@@ -28,23 +29,7 @@ type Communicator struct {
 }
 
 func (c *Communicator) Start(remote *packer.RemoteCmd) error {
-	dockerArgs := []string{
-		"exec",
-		"-i",
-		c.ContainerID,
-		"/bin/sh",
-		"-c",
-		fmt.Sprintf("(%s)", remote.Command),
-	}
-
-	if c.Config.Pty {
-		dockerArgs = append(dockerArgs[:2], append([]string{"-t"}, dockerArgs[2:]...)...)
-	}
-
-	if c.Config.ExecUser != "" {
-		dockerArgs = append(dockerArgs[:2],
-			append([]string{"-u", c.Config.ExecUser}, dockerArgs[2:]...)...)
-	}
+	dockerArgs := c.execArgs(remote)
 
 	cmd := exec.Command("docker", dockerArgs...)
 
@@ -74,6 +59,58 @@ func (c *Communicator) Start(remote *packer.RemoteCmd) error {
 	return nil
 }
 
+// execArgs returns the arguments to `docker exec` for running the given
+// command in the container. The user, working directory, and environment
+// of the command win over the exec_* options in the config.
+func (c *Communicator) execArgs(remote *packer.RemoteCmd) []string {
+	dockerArgs := []string{"exec", "-i"}
+
+	if c.Config.Pty {
+		dockerArgs = append(dockerArgs, "-t")
+	}
+
+	user := c.Config.ExecUser
+	if remote.User != "" {
+		user = remote.User
+	}
+	if user != "" {
+		dockerArgs = append(dockerArgs, "-u", user)
+	}
+
+	workdir := c.Config.ExecWorkdir
+	if remote.Workdir != "" {
+		workdir = remote.Workdir
+	}
+	if workdir != "" {
+		dockerArgs = append(dockerArgs, "-w", workdir)
+	}
+
+	env := make(map[string]string, len(c.Config.ExecEnv)+len(remote.Env))
+	for k, v := range c.Config.ExecEnv {
+		env[k] = v
+	}
+	for k, v := range remote.Env {
+		env[k] = v
+	}
+
+	// Sort the keys so the command line is stable between runs
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		dockerArgs = append(dockerArgs, "-e", fmt.Sprintf("%s=%s", k, env[k]))
+	}
+
+	return append(dockerArgs,
+		c.ContainerID,
+		"/bin/sh",
+		"-c",
+		fmt.Sprintf("(%s)", remote.Command),
+	)
+}
+
 // Upload uploads a file to the docker container
 func (c *Communicator) Upload(dst string, src io.Reader, fi *os.FileInfo) error {
 	if fi == nil {
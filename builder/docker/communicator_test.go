@@ -5,6 +5,7 @@ import (
 	"io/ioutil"
 	"os"
 	"os/exec"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -18,6 +19,43 @@ func TestCommunicator_impl(t *testing.T) {
 	var _ packer.Communicator = new(Communicator)
 }
 
+func TestCommunicator_execArgs(t *testing.T) {
+	c := &Communicator{
+		ContainerID: "abc",
+		Config: &Config{
+			ExecUser:    "app",
+			ExecWorkdir: "/srv",
+			ExecEnv: map[string]string{
+				"B": "2",
+				"A": "1",
+			},
+		},
+	}
+
+	expected := []string{
+		"exec", "-i", "-u", "app", "-w", "/srv", "-e", "A=1", "-e", "B=2",
+		"abc", "/bin/sh", "-c", "(echo foo)",
+	}
+	if args := c.execArgs(&packer.RemoteCmd{Command: "echo foo"}); !reflect.DeepEqual(args, expected) {
+		t.Fatalf("bad: %#v", args)
+	}
+
+	// The settings of the command win
+	cmd := &packer.RemoteCmd{
+		Command: "echo foo",
+		User:    "root",
+		Workdir: "/tmp",
+		Env:     map[string]string{"B": "3", "C": "4"},
+	}
+	expected = []string{
+		"exec", "-i", "-u", "root", "-w", "/tmp", "-e", "A=1", "-e", "B=3", "-e", "C=4",
+		"abc", "/bin/sh", "-c", "(echo foo)",
+	}
+	if args := c.execArgs(cmd); !reflect.DeepEqual(args, expected) {
+		t.Fatalf("bad: %#v", args)
+	}
+}
+
 // TestUploadDownload verifies that basic upload / download functionality works
 func TestUploadDownload(t *testing.T) {
 	ui := packer.TestUi(t)
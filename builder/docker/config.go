@@ -3,6 +3,8 @@ package docker
 import (
 	"fmt"
 	"os"
+	"path"
+	"strings"
 
 	"github.com/hashicorp/packer/common"
 	"github.com/hashicorp/packer/helper/communicator"
@@ -16,6 +18,7 @@ var (
 	errArtifactNotUsed     = fmt.Errorf("No instructions given for handling the artifact; expected commit, discard, or export_path")
 	errArtifactUseConflict = fmt.Errorf("Cannot specify more than one of commit, discard, and export_path")
 	errExportPathNotFile   = fmt.Errorf("export_path must be a file, not a directory")
+	errExecWorkdirNotAbs   = fmt.Errorf("exec_workdir must be an absolute path")
 	errImageNotSpecified   = fmt.Errorf("Image must be specified")
 )
 
@@ -28,8 +31,10 @@ type Config struct {
 	Commit         bool
 	ContainerDir   string `mapstructure:"container_dir"`
 	Discard        bool
-	ExecUser       string `mapstructure:"exec_user"`
-	ExportPath     string `mapstructure:"export_path"`
+	ExecEnv        map[string]string `mapstructure:"exec_env"`
+	ExecUser       string            `mapstructure:"exec_user"`
+	ExecWorkdir    string            `mapstructure:"exec_workdir"`
+	ExportPath     string            `mapstructure:"export_path"`
 	Image          string
 	Message        string
 	Privileged     bool `mapstructure:"privileged"`
@@ -120,6 +125,17 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 		c.ContainerDir = "/packer-files"
 	}
 
+	if c.ExecWorkdir != "" && !path.IsAbs(c.ExecWorkdir) {
+		errs = packer.MultiErrorAppend(errs, errExecWorkdirNotAbs)
+	}
+
+	for k := range c.ExecEnv {
+		if k == "" || strings.Contains(k, "=") {
+			errs = packer.MultiErrorAppend(errs,
+				fmt.Errorf("Invalid environment variable name in exec_env: '%s'", k))
+		}
+	}
+
 	if c.EcrLogin && c.LoginServer == "" {
 		errs = packer.MultiErrorAppend(errs, fmt.Errorf("ECR login requires login server to be provided."))
 	}
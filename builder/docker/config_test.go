@@ -141,3 +141,31 @@ func TestConfigPrepare_pull(t *testing.T) {
 		t.Fatal("should not pull")
 	}
 }
+
+func TestConfigPrepare_execWorkdir(t *testing.T) {
+	raw := testConfig()
+
+	// Relative
+	raw["exec_workdir"] = "app"
+	_, warns, errs := NewConfig(raw)
+	testConfigErr(t, warns, errs)
+
+	// Absolute
+	raw["exec_workdir"] = "/app"
+	_, warns, errs = NewConfig(raw)
+	testConfigOk(t, warns, errs)
+}
+
+func TestConfigPrepare_execEnv(t *testing.T) {
+	raw := testConfig()
+
+	// Bad key
+	raw["exec_env"] = map[string]string{"FOO=BAR": "baz"}
+	_, warns, errs := NewConfig(raw)
+	testConfigErr(t, warns, errs)
+
+	// Good
+	raw["exec_env"] = map[string]string{"FOO": "bar"}
+	_, warns, errs = NewConfig(raw)
+	testConfigOk(t, warns, errs)
+}
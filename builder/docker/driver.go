@@ -22,6 +22,10 @@ type Driver interface {
 	// Import imports a container from a tar file
 	Import(path, repo string) (string, error)
 
+	// ImageExists says whether there is a local image of the name, like
+	// repository:tag.
+	ImageExists(name string) (bool, error)
+
 	// IPAddress returns the address of the container that can be used
 	// for external access.
 	IPAddress(id string) (string, error)
@@ -33,6 +37,13 @@ type Driver interface {
 	// Logout. This can only be called if Login succeeded.
 	Logout(repo string) error
 
+	// ManifestCreate adds the given images to a local manifest list,
+	// creating the list if it doesn't exist yet.
+	ManifestCreate(list string, images []string) error
+
+	// ManifestPush pushes a local manifest list to its registry.
+	ManifestPush(list string) error
+
 	// Pull should pull down the given image.
 	Pull(image string) error
 
@@ -130,6 +130,20 @@ func (d *DockerDriver) Import(path string, repo string) (string, error) {
 	return strings.TrimSpace(stdout.String()), nil
 }
 
+func (d *DockerDriver) ImageExists(name string) (bool, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("docker", "inspect", "--type=image", "--format", "{{ .Id }}", name)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if strings.Contains(strings.ToLower(stderr.String()), "no such") {
+			return false, nil
+		}
+		return false, fmt.Errorf("Error: %s\n\nStderr: %s", err, stderr.String())
+	}
+
+	return true, nil
+}
+
 func (d *DockerDriver) IPAddress(id string) (string, error) {
 	var stderr, stdout bytes.Buffer
 	cmd := exec.Command(
@@ -219,6 +233,20 @@ func (d *DockerDriver) Pull(image string) error {
 	return runAndStream(cmd, d.Ui)
 }
 
+func (d *DockerDriver) ManifestCreate(list string, images []string) error {
+	args := append([]string{"manifest", "create", "--amend", list}, images...)
+	cmd := exec.Command("docker", args...)
+	// docker manifest is experimental in older versions of the CLI.
+	cmd.Env = append(os.Environ(), "DOCKER_CLI_EXPERIMENTAL=enabled")
+	return runAndStream(cmd, d.Ui)
+}
+
+func (d *DockerDriver) ManifestPush(list string) error {
+	cmd := exec.Command("docker", "manifest", "push", list)
+	cmd.Env = append(os.Environ(), "DOCKER_CLI_EXPERIMENTAL=enabled")
+	return runAndStream(cmd, d.Ui)
+}
+
 func (d *DockerDriver) Push(name string) error {
 	cmd := exec.Command("docker", "push", name)
 	return runAndStream(cmd, d.Ui)
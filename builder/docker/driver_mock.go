@@ -38,8 +38,23 @@ type MockDriver struct {
 	LogoutRepo   string
 	LogoutErr    error
 
+	ImageExistsCalled bool
+	ImageExistsName   string
+	ImageExistsResult bool
+	ImageExistsErr    error
+
+	ManifestCreateCalled bool
+	ManifestCreateList   string
+	ManifestCreateImages []string
+	ManifestCreateErr    error
+
+	ManifestPushCalled bool
+	ManifestPushList   string
+	ManifestPushErr    error
+
 	PushCalled bool
 	PushName   string
+	PushNames  []string
 	PushErr    error
 
 	SaveImageCalled bool
@@ -134,9 +149,29 @@ func (d *MockDriver) Pull(image string) error {
 	return d.PullError
 }
 
+func (d *MockDriver) ImageExists(name string) (bool, error) {
+	d.ImageExistsCalled = true
+	d.ImageExistsName = name
+	return d.ImageExistsResult, d.ImageExistsErr
+}
+
+func (d *MockDriver) ManifestCreate(list string, images []string) error {
+	d.ManifestCreateCalled = true
+	d.ManifestCreateList = list
+	d.ManifestCreateImages = images
+	return d.ManifestCreateErr
+}
+
+func (d *MockDriver) ManifestPush(list string) error {
+	d.ManifestPushCalled = true
+	d.ManifestPushList = list
+	return d.ManifestPushErr
+}
+
 func (d *MockDriver) Push(name string) error {
 	d.PushCalled = true
 	d.PushName = name
+	d.PushNames = append(d.PushNames, name)
 	return d.PushErr
 }
 
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	commonhelper "github.com/hashicorp/packer/helper/common"
 	"github.com/hashicorp/packer/helper/multistep"
 	"github.com/hashicorp/packer/packer"
 )
@@ -42,6 +43,8 @@ func (s *StepRun) Run(_ context.Context, state multistep.StateBag) multistep.Ste
 	// Save the container ID
 	s.containerId = containerId
 	state.Put("container_id", s.containerId)
+	commonhelper.SetBuildValue("ID", s.containerId, config.PackerBuildName)
+	commonhelper.SetBuildValue("SourceImage", config.Image, config.PackerBuildName)
 	ui.Message(fmt.Sprintf("Container ID: %s", s.containerId))
 	return multistep.ActionContinue
 }
@@ -10,6 +10,10 @@ type Artifact struct {
 	image  *Image
 	driver Driver
 	config *Config
+
+	// existing is true when the image existed before the build, which
+	// skipped building it, so it isn't deleted.
+	existing bool
 }
 
 // BuilderId returns the builder Id.
@@ -19,6 +23,10 @@ func (*Artifact) BuilderId() string {
 
 // Destroy destroys the GCE image represented by the artifact.
 func (a *Artifact) Destroy() error {
+	if a.existing {
+		log.Printf("Keeping the image that existed before the build: %s", a.image.Name)
+		return nil
+	}
 	log.Printf("Destroying image: %s", a.image.Name)
 	errCh := a.driver.DeleteImage(a.image.Name)
 	return <-errCh
@@ -36,6 +44,9 @@ func (a *Artifact) Id() string {
 
 // String returns the string representation of the artifact.
 func (a *Artifact) String() string {
+	if a.existing {
+		return fmt.Sprintf("A disk image already existed: %v", a.image.Name)
+	}
 	return fmt.Sprintf("A disk image was created: %v", a.image.Name)
 }
 
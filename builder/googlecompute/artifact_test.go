@@ -9,3 +9,15 @@ import (
 func TestArtifact_impl(t *testing.T) {
 	var _ packer.Artifact = new(Artifact)
 }
+
+func TestArtifactDestroy_existing(t *testing.T) {
+	driver := new(DriverMock)
+	a := &Artifact{image: &Image{Name: "packer-image"}, driver: driver, existing: true}
+
+	if err := a.Destroy(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if driver.DeleteImageName != "" {
+		t.Fatalf("should not delete the existing image: %s", driver.DeleteImageName)
+	}
+}
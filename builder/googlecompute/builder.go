@@ -66,16 +66,23 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		},
 		&communicator.StepConnect{
 			Config:      &b.config.Comm,
+			BuildName:   b.config.PackerBuildName,
 			Host:        commHost,
 			SSHConfig:   b.config.Comm.SSHConfigFunc(),
 			WinRMConfig: winrmConfig,
 		},
+		&common.StepWaitCloudInit{
+			Config: &b.config.CloudInitConfig,
+			Comm:   &b.config.Comm,
+		},
 		new(common.StepProvision),
 		&common.StepCleanupTempKeys{
 			Comm: &b.config.Comm,
 		},
 	}
-	if _, exists := b.config.Metadata[StartupScriptKey]; exists || b.config.StartupScriptFile != "" {
+	_, linuxScript := b.config.Metadata[StartupScriptKey]
+	_, windowsScript := b.config.Metadata[StartupScriptWindowsKey]
+	if linuxScript || windowsScript || b.config.StartupScriptFile != "" {
 		steps = append(steps, new(StepWaitStartupScript))
 	}
 	steps = append(steps, new(StepTeardownInstance), new(StepCreateImage))
@@ -93,10 +100,12 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		return nil, nil
 	}
 
+	_, existing := state.GetOk("image_existing")
 	artifact := &Artifact{
-		image:  state.Get("image").(*Image),
-		driver: driver,
-		config: b.config,
+		image:    state.Get("image").(*Image),
+		driver:   driver,
+		config:   b.config,
+		existing: existing,
 	}
 	return artifact, nil
 }
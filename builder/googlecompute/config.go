@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/hashicorp/packer/common"
@@ -21,8 +22,10 @@ var reImageFamily = regexp.MustCompile(`^[a-z]([-a-z0-9]{0,61}[a-z0-9])?$`)
 // both the publicly settable state as well as the privately generated
 // state of the config object.
 type Config struct {
-	common.PackerConfig `mapstructure:",squash"`
-	Comm                communicator.Config `mapstructure:",squash"`
+	common.PackerConfig    `mapstructure:",squash"`
+	common.CloudInitConfig `mapstructure:",squash"`
+	common.IfExistsConfig  `mapstructure:",squash"`
+	Comm                   communicator.Config `mapstructure:",squash"`
 
 	AccountFile string `mapstructure:"account_file"`
 	ProjectId   string `mapstructure:"project_id"`
@@ -113,12 +116,17 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 	}
 	// Setting OnHostMaintenance Correct Defaults
 	//   "MIGRATE" : Possible and default if Preemptible is false
-	//   "TERMINATE": Required if Preemptible is true
+	//   "TERMINATE": Required if Preemptible is true, and default with
+	//                accelerators, which can't be live migrated
 	if c.Preemptible {
 		c.OnHostMaintenance = "TERMINATE"
 	} else {
 		if c.OnHostMaintenance == "" {
-			c.OnHostMaintenance = "MIGRATE"
+			if c.AcceleratorCount > 0 {
+				c.OnHostMaintenance = "TERMINATE"
+			} else {
+				c.OnHostMaintenance = "MIGRATE"
+			}
 		}
 	}
 
@@ -170,6 +178,8 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 	if es := c.Comm.Prepare(&c.ctx); len(es) > 0 {
 		errs = packer.MultiErrorAppend(errs, es...)
 	}
+	errs = packer.MultiErrorAppend(errs, c.CloudInitConfig.Prepare()...)
+	errs = packer.MultiErrorAppend(errs, c.IfExistsConfig.Prepare()...)
 
 	// Process required parameters.
 	if c.ProjectId == "" {
@@ -212,24 +222,29 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 	}
 
 	if c.OmitExternalIP && c.Address != "" {
-		errs = packer.MultiErrorAppend(fmt.Errorf("you can not specify an external address when 'omit_external_ip' is true"))
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("you can not specify an external address when 'omit_external_ip' is true"))
 	}
 
 	if c.OmitExternalIP && !c.UseInternalIP {
-		errs = packer.MultiErrorAppend(fmt.Errorf("'use_internal_ip' must be true if 'omit_external_ip' is true"))
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("'use_internal_ip' must be true if 'omit_external_ip' is true"))
 	}
 
 	if c.AcceleratorCount > 0 && len(c.AcceleratorType) == 0 {
-		errs = packer.MultiErrorAppend(fmt.Errorf("'accelerator_type' must be set when 'accelerator_count' is more than 0"))
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("'accelerator_type' must be set when 'accelerator_count' is more than 0"))
+	}
+
+	// A bare accelerator type like "nvidia-tesla-k80" is the one of the zone
+	if c.AcceleratorType != "" && !strings.Contains(c.AcceleratorType, "/") {
+		c.AcceleratorType = fmt.Sprintf("projects/%s/zones/%s/acceleratorTypes/%s", c.ProjectId, c.Zone, c.AcceleratorType)
 	}
 
 	if c.AcceleratorCount > 0 && c.OnHostMaintenance != "TERMINATE" {
-		errs = packer.MultiErrorAppend(fmt.Errorf("'on_host_maintenance' must be set to 'TERMINATE' when 'accelerator_count' is more than 0"))
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("'on_host_maintenance' must be set to 'TERMINATE' when 'accelerator_count' is more than 0"))
 	}
 
 	// If DisableDefaultServiceAccount is provided, don't allow a value for ServiceAccountEmail
 	if c.DisableDefaultServiceAccount && c.ServiceAccountEmail != "" {
-		errs = packer.MultiErrorAppend(fmt.Errorf("you may not specify a 'service_account_email' when 'disable_default_service_account' is true"))
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("you may not specify a 'service_account_email' when 'disable_default_service_account' is true"))
 	}
 
 	if c.StartupScriptFile != "" {
@@ -247,6 +262,12 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 	return c, nil, nil
 }
 
+// replaceImage says whether an existing image of the name is deleted before
+// creating the image.
+func (c *Config) replaceImage() bool {
+	return c.PackerForce || c.IfExists == common.IfExistsReplace
+}
+
 func (c *Config) CalcTimeout() error {
 	stateTimeout, err := time.ParseDuration(c.RawStateTimeout)
 	if err != nil {
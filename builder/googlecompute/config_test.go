@@ -250,6 +250,11 @@ func TestConfigPrepareAccelerator(t *testing.T) {
 			[]interface{}{1, "TERMINATE", "something_valid"},
 			false,
 		},
+		{
+			[]string{"accelerator_count", "on_host_maintenance", "accelerator_type"},
+			[]interface{}{1, nil, "something_valid"},
+			false,
+		},
 	}
 
 	for _, tc := range cases {
@@ -279,6 +284,32 @@ func TestConfigPrepareAccelerator(t *testing.T) {
 	}
 }
 
+func TestConfigPrepareAccelerator_defaults(t *testing.T) {
+	raw, tempfile := testConfig(t)
+	defer os.Remove(tempfile)
+
+	raw["accelerator_count"] = 2
+	raw["accelerator_type"] = "nvidia-tesla-k80"
+
+	c, warns, errs := NewConfig(raw)
+	testConfigOk(t, warns, errs)
+
+	if c.OnHostMaintenance != "TERMINATE" {
+		t.Fatalf("bad on_host_maintenance: %s", c.OnHostMaintenance)
+	}
+	expected := "projects/hashicorp/zones/us-east1-a/acceleratorTypes/nvidia-tesla-k80"
+	if c.AcceleratorType != expected {
+		t.Fatalf("bad accelerator_type: %s", c.AcceleratorType)
+	}
+
+	raw["accelerator_type"] = "projects/p/zones/z/acceleratorTypes/nvidia-tesla-v100"
+	c, warns, errs = NewConfig(raw)
+	testConfigOk(t, warns, errs)
+	if c.AcceleratorType != "projects/p/zones/z/acceleratorTypes/nvidia-tesla-v100" {
+		t.Fatalf("bad accelerator_type: %s", c.AcceleratorType)
+	}
+}
+
 func TestConfigPrepareServiceAccount(t *testing.T) {
 	cases := []struct {
 		Keys   []string
@@ -34,6 +34,10 @@ type Driver interface {
 	// GetInstanceMetadata gets a metadata variable for the instance, name.
 	GetInstanceMetadata(zone, name, key string) (string, error)
 
+	// GetGuestAttribute gets a guest attribute of the instance, name,
+	// empty if the instance hasn't set it yet.
+	GetGuestAttribute(zone, name, namespace, key string) (string, error)
+
 	// GetInternalIP gets the GCE-internal IP address for the instance.
 	GetInternalIP(zone, name string) (string, error)
 
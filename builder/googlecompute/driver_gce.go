@@ -10,10 +10,12 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
 	compute "google.golang.org/api/compute/v1"
+	"google.golang.org/api/googleapi"
 
 	"github.com/hashicorp/packer/common"
 	"github.com/hashicorp/packer/helper/useragent"
@@ -28,6 +30,7 @@ import (
 // Create an instance using NewDriverGCE.
 type driverGCE struct {
 	projectId string
+	client    *http.Client
 	service   *compute.Service
 	ui        packer.Ui
 }
@@ -89,6 +92,7 @@ func NewDriverGCE(ui packer.Ui, p string, a *AccountFile) (Driver, error) {
 
 	return &driverGCE{
 		projectId: p,
+		client:    client,
 		service:   service,
 		ui:        ui,
 	}, nil
@@ -247,6 +251,40 @@ func (d *driverGCE) GetInstanceMetadata(zone, name, key string) (string, error)
 	return "", fmt.Errorf("Instance metadata key, %s, not found.", key)
 }
 
+func (d *driverGCE) GetGuestAttribute(zone, name, namespace, key string) (string, error) {
+	// The compute client doesn't have getGuestAttributes yet
+	target := fmt.Sprintf("%s%s/zones/%s/instances/%s/getGuestAttributes?variableKey=%s",
+		d.service.BasePath, url.PathEscape(d.projectId), url.PathEscape(zone), url.PathEscape(name),
+		url.QueryEscape(namespace+"/"+key))
+	req, err := http.NewRequest("GET", target, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", d.service.UserAgent)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	// The attribute isn't found until the instance sets it
+	if resp.StatusCode == http.StatusNotFound {
+		return "", nil
+	}
+	if err := googleapi.CheckResponse(resp); err != nil {
+		return "", err
+	}
+
+	var attributes struct {
+		VariableValue string `json:"variableValue"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&attributes); err != nil {
+		return "", err
+	}
+	return attributes.VariableValue, nil
+}
+
 func (d *driverGCE) GetNatIP(zone, name string) (string, error) {
 	instance, err := d.service.Instances.Get(d.projectId, zone, name).Do()
 	if err != nil {
@@ -48,6 +48,13 @@ type DriverMock struct {
 	GetInstanceMetadataResult string
 	GetInstanceMetadataErr    error
 
+	GetGuestAttributeZone      string
+	GetGuestAttributeName      string
+	GetGuestAttributeNamespace string
+	GetGuestAttributeKey       string
+	GetGuestAttributeResult    string
+	GetGuestAttributeErr       error
+
 	GetNatIPZone   string
 	GetNatIPName   string
 	GetNatIPResult string
@@ -188,6 +195,14 @@ func (d *DriverMock) GetInstanceMetadata(zone, name, key string) (string, error)
 	return d.GetInstanceMetadataResult, d.GetInstanceMetadataErr
 }
 
+func (d *DriverMock) GetGuestAttribute(zone, name, namespace, key string) (string, error) {
+	d.GetGuestAttributeZone = zone
+	d.GetGuestAttributeName = name
+	d.GetGuestAttributeNamespace = namespace
+	d.GetGuestAttributeKey = key
+	return d.GetGuestAttributeResult, d.GetGuestAttributeErr
+}
+
 func (d *DriverMock) GetNatIP(zone, name string) (string, error) {
 	d.GetNatIPZone = zone
 	d.GetNatIPName = name
@@ -7,6 +7,12 @@ import (
 const StartupScriptKey string = "startup-script"
 const StartupScriptStatusKey string = "startup-script-status"
 const StartupWrappedScriptKey string = "packer-wrapped-startup-script"
+const StartupScriptWindowsKey string = "windows-startup-script-ps1"
+
+// Windows instances report the status of the startup script in a guest
+// attribute, which they can set without any credentials.
+const EnableGuestAttributesKey string = "enable-guest-attributes"
+const GuestAttributeNamespace string = "packer"
 
 const StartupScriptStatusDone string = "done"
 const StartupScriptStatusError string = "error"
@@ -56,4 +62,31 @@ SetMetadata %s %s
 exit $RETVAL
 `, StartupWrappedScriptKey, StartupScriptStatusKey, StartupScriptStatusDone)
 
-var StartupScriptWindows string = ""
+// StartupScriptWindows runs as a Windows startup script, once the instance
+// is through sysprep and its first boot setup.
+var StartupScriptWindows string = fmt.Sprintf(`Write-Output "Packer startup script starting."
+$MetadataUrl = "http://metadata.google.internal/computeMetadata/v1/instance"
+$Headers = @{"Metadata-Flavor" = "Google"}
+$Status = "%s"
+
+try {
+  $StartupScript = Invoke-RestMethod -Uri "$MetadataUrl/attributes/%s" -Headers $Headers
+} catch {
+  $StartupScript = ""
+}
+
+if ($StartupScript) {
+  Write-Output "Executing user-provided startup script..."
+  $StartupScriptPath = Join-Path $env:TEMP "packer-wrapped-startup-script.ps1"
+  Set-Content -Path $StartupScriptPath -Value $StartupScript
+  powershell.exe -NoProfile -ExecutionPolicy Bypass -File $StartupScriptPath
+  if ($LASTEXITCODE -ne 0) {
+    Write-Output "User-provided startup script exited with $LASTEXITCODE."
+    $Status = "%s"
+  }
+  Remove-Item -Path $StartupScriptPath -Force
+}
+
+Write-Output "Packer startup script done."
+Invoke-RestMethod -Method Put -Uri "$MetadataUrl/guest-attributes/%s/%s" -Headers $Headers -Body $Status | Out-Null
+`, StartupScriptStatusDone, StartupWrappedScriptKey, StartupScriptStatusError, GuestAttributeNamespace, StartupScriptStatusKey)
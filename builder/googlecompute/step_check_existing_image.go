@@ -4,12 +4,15 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/hashicorp/packer/common"
 	"github.com/hashicorp/packer/helper/multistep"
 	"github.com/hashicorp/packer/packer"
 )
 
 // StepCheckExistingImage represents a Packer build step that checks if the
-// target image already exists, and aborts immediately if so.
+// target image already exists, and aborts immediately if so. With if_exists
+// set to skip, the build stops there instead, with the existing image as its
+// artifact, which isn't deleted when it is destroyed.
 type StepCheckExistingImage int
 
 // Run executes the Packer build step that checks if the image already exists.
@@ -20,7 +23,20 @@ func (s *StepCheckExistingImage) Run(_ context.Context, state multistep.StateBag
 
 	ui.Say("Checking image does not exist...")
 	c.imageAlreadyExists = d.ImageExists(c.ImageName)
-	if !c.PackerForce && c.imageAlreadyExists {
+	if !c.replaceImage() && c.imageAlreadyExists && c.IfExists == common.IfExistsSkip {
+		image, err := d.GetImageFromProject(c.ProjectId, c.ImageName, false)
+		if err != nil {
+			err := fmt.Errorf("Error getting the existing image %s: %s", c.ImageName, err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		ui.Say(fmt.Sprintf("Image %s already exists, skipping the build", c.ImageName))
+		state.Put("image", image)
+		state.Put("image_existing", true)
+		return multistep.ActionHalt
+	}
+	if !c.replaceImage() && c.imageAlreadyExists {
 		err := fmt.Errorf("Image %s already exists.\n"+
 			"Use the force flag to delete it prior to building.", c.ImageName)
 		state.Put("error", err)
@@ -32,3 +32,49 @@ func TestStepCheckExistingImage(t *testing.T) {
 		t.Fatalf("bad: %#v", driver.ImageExistsName)
 	}
 }
+
+func TestStepCheckExistingImage_skip(t *testing.T) {
+	state := testState(t)
+	step := new(StepCheckExistingImage)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.IfExists = "skip"
+	driver := state.Get("driver").(*DriverMock)
+	driver.ImageExistsResult = true
+	driver.GetImageFromProjectResult = &Image{Name: config.ImageName}
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("error"); ok {
+		t.Fatal("should not have error")
+	}
+	if image, ok := state.GetOk("image"); !ok || image.(*Image).Name != config.ImageName {
+		t.Fatalf("bad: %#v", image)
+	}
+	if driver.GetImageFromProjectName != config.ImageName {
+		t.Fatalf("bad: %s", driver.GetImageFromProjectName)
+	}
+	if _, ok := state.GetOk("image_existing"); !ok {
+		t.Fatal("should mark the image as existing")
+	}
+}
+
+func TestStepCheckExistingImage_replace(t *testing.T) {
+	state := testState(t)
+	step := new(StepCheckExistingImage)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.IfExists = "replace"
+	driver := state.Get("driver").(*DriverMock)
+	driver.ImageExistsResult = true
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if !config.imageAlreadyExists {
+		t.Fatal("should know the image exists")
+	}
+}
@@ -23,7 +23,7 @@ func (s *StepCreateImage) Run(_ context.Context, state multistep.StateBag) multi
 	driver := state.Get("driver").(Driver)
 	ui := state.Get("ui").(packer.Ui)
 
-	if config.PackerForce && config.imageAlreadyExists {
+	if config.replaceImage() && config.imageAlreadyExists {
 		ui.Say("Deleting previous image...")
 
 		errCh := driver.DeleteImage(config.ImageName)
@@ -7,6 +7,7 @@ import (
 	"io/ioutil"
 	"time"
 
+	commonhelper "github.com/hashicorp/packer/helper/common"
 	"github.com/hashicorp/packer/helper/multistep"
 	"github.com/hashicorp/packer/packer"
 )
@@ -37,19 +38,22 @@ func (c *Config) createInstanceMetadata(sourceImage *Image, sshPublicKey string)
 		instanceMetadata[sshMetaKey] = sshKeys
 	}
 
-	// Wrap any startup script with our own startup script.
+	// Wrap any startup script with our own startup script. The startup
+	// scripts of Windows instances are PowerShell scripts.
+	startupScriptKey := StartupScriptKey
+	if sourceImage.IsWindows() {
+		startupScriptKey = StartupScriptWindowsKey
+	}
 	if c.StartupScriptFile != "" {
 		var content []byte
 		content, err = ioutil.ReadFile(c.StartupScriptFile)
 		instanceMetadata[StartupWrappedScriptKey] = string(content)
-	} else if wrappedStartupScript, exists := instanceMetadata[StartupScriptKey]; exists {
+	} else if wrappedStartupScript, exists := instanceMetadata[startupScriptKey]; exists {
 		instanceMetadata[StartupWrappedScriptKey] = wrappedStartupScript
 	}
 	if sourceImage.IsWindows() {
-		// Windows startup script support is not yet implemented.
-		// Mark the startup script as done.
-		instanceMetadata[StartupScriptKey] = StartupScriptWindows
-		instanceMetadata[StartupScriptStatusKey] = StartupScriptStatusDone
+		instanceMetadata[StartupScriptWindowsKey] = StartupScriptWindows
+		instanceMetadata[EnableGuestAttributesKey] = "TRUE"
 	} else {
 		instanceMetadata[StartupScriptKey] = StartupScriptLinux
 		instanceMetadata[StartupScriptStatusKey] = StartupScriptStatusNotDone
@@ -92,6 +96,9 @@ func (s *StepCreateInstance) Run(_ context.Context, state multistep.StateBag) mu
 	if sourceImage.IsWindows() && c.Comm.Type == "winrm" && c.Comm.WinRMPassword == "" {
 		state.Put("create_windows_password", true)
 	}
+	if sourceImage.IsWindows() {
+		state.Put("startup_script_guest_attribute", true)
+	}
 
 	ui.Say("Creating instance...")
 	name := c.InstanceName
@@ -152,6 +159,8 @@ func (s *StepCreateInstance) Run(_ context.Context, state multistep.StateBag) mu
 
 	// Things succeeded, store the name so we can remove it later
 	state.Put("instance_name", name)
+	commonhelper.SetBuildValue("ID", name, c.PackerBuildName)
+	commonhelper.SetBuildValue("SourceImage", sourceImage.Name, c.PackerBuildName)
 
 	return multistep.ActionContinue
 }
@@ -325,3 +325,20 @@ func TestCreateInstanceMetadata_noPublicKey(t *testing.T) {
 	// ensure the ssh metadata hasn't changed
 	assert.Equal(t, metadata["sshKeys"], sshKeys, "Instance metadata should not have been modified")
 }
+
+func TestCreateInstanceMetadata_windowsStartupScript(t *testing.T) {
+	state := testState(t)
+	c := state.Get("config").(*Config)
+	image := StubImage("test-image", "test-project", []string{"windows"}, 100)
+	c.Metadata = map[string]string{StartupScriptWindowsKey: "Write-Output hello"}
+
+	// create our metadata
+	metadata, err := c.createInstanceMetadata(image, "")
+
+	assert.True(t, err == nil, "Metadata creation should have succeeded.")
+
+	// ensure the startup script is wrapped and reports in a guest attribute
+	assert.Equal(t, metadata[StartupWrappedScriptKey], "Write-Output hello", "Instance metadata should contain the wrapped startup script")
+	assert.Equal(t, metadata[StartupScriptWindowsKey], StartupScriptWindows, "Instance metadata should contain the Windows startup script")
+	assert.Equal(t, metadata[EnableGuestAttributesKey], "TRUE", "Instance metadata should enable guest attributes")
+}
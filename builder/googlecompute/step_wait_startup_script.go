@@ -13,19 +13,28 @@ import (
 type StepWaitStartupScript int
 
 // Run reads the instance metadata and looks for the log entry
-// indicating the startup script finished.
+// indicating the startup script finished. Windows instances report it in a
+// guest attribute instead.
 func (s *StepWaitStartupScript) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
 	config := state.Get("config").(*Config)
 	driver := state.Get("driver").(Driver)
 	ui := state.Get("ui").(packer.Ui)
 	instanceName := state.Get("instance_name").(string)
+	_, guestAttribute := state.GetOk("startup_script_guest_attribute")
 
 	ui.Say("Waiting for any running startup script to finish...")
 
 	// Keep checking the serial port output to see if the startup script is done.
 	err := common.Retry(10, 60, 0, func(_ uint) (bool, error) {
-		status, err := driver.GetInstanceMetadata(config.Zone,
-			instanceName, StartupScriptStatusKey)
+		var status string
+		var err error
+		if guestAttribute {
+			status, err = driver.GetGuestAttribute(config.Zone,
+				instanceName, GuestAttributeNamespace, StartupScriptStatusKey)
+		} else {
+			status, err = driver.GetInstanceMetadata(config.Zone,
+				instanceName, StartupScriptStatusKey)
+		}
 
 		if err != nil {
 			err := fmt.Errorf("Error getting startup script status: %s", err)
@@ -30,3 +30,44 @@ func TestStepWaitStartupScript(t *testing.T) {
 	assert.Equal(t, d.GetInstanceMetadataZone, testZone, "Incorrect zone passed to GetInstanceMetadata.")
 	assert.Equal(t, d.GetInstanceMetadataName, testInstanceName, "Incorrect instance name passed to GetInstanceMetadata.")
 }
+
+func TestStepWaitStartupScript_guestAttribute(t *testing.T) {
+	state := testState(t)
+	step := new(StepWaitStartupScript)
+	c := state.Get("config").(*Config)
+	d := state.Get("driver").(*DriverMock)
+
+	testZone := "test-zone"
+	testInstanceName := "test-instance-name"
+
+	c.Zone = testZone
+	state.Put("instance_name", testInstanceName)
+	state.Put("startup_script_guest_attribute", true)
+
+	// This step stops when it gets Done back from the guest attribute.
+	d.GetGuestAttributeResult = StartupScriptStatusDone
+
+	// Run the step.
+	assert.Equal(t, step.Run(context.Background(), state), multistep.ActionContinue, "Step should have passed and continued.")
+
+	// Check that GetGuestAttribute was called properly.
+	assert.Equal(t, d.GetGuestAttributeZone, testZone, "Incorrect zone passed to GetGuestAttribute.")
+	assert.Equal(t, d.GetGuestAttributeName, testInstanceName, "Incorrect instance name passed to GetGuestAttribute.")
+	assert.Equal(t, d.GetGuestAttributeNamespace, GuestAttributeNamespace, "Incorrect namespace passed to GetGuestAttribute.")
+	assert.Equal(t, d.GetGuestAttributeKey, StartupScriptStatusKey, "Incorrect key passed to GetGuestAttribute.")
+	assert.Equal(t, d.GetInstanceMetadataKey, "", "GetInstanceMetadata should not have been called.")
+}
+
+func TestStepWaitStartupScript_guestAttributeError(t *testing.T) {
+	state := testState(t)
+	step := new(StepWaitStartupScript)
+	d := state.Get("driver").(*DriverMock)
+
+	state.Put("instance_name", "test-instance-name")
+	state.Put("startup_script_guest_attribute", true)
+	d.GetGuestAttributeResult = StartupScriptStatusError
+
+	assert.Equal(t, step.Run(context.Background(), state), multistep.ActionHalt, "Step should have failed on a startup script error.")
+	_, ok := state.GetOk("error")
+	assert.True(t, ok, "State should have an error.")
+}
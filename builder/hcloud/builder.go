@@ -55,9 +55,14 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		&stepCreateServer{},
 		&communicator.StepConnect{
 			Config:    &b.config.Comm,
+			BuildName: b.config.PackerBuildName,
 			Host:      getServerIP,
 			SSHConfig: b.config.Comm.SSHConfigFunc(),
 		},
+		&common.StepWaitCloudInit{
+			Config: &b.config.CloudInitConfig,
+			Comm:   &b.config.Comm,
+		},
 		&common.StepProvision{},
 		&common.StepCleanupTempKeys{
 			Comm: &b.config.Comm,
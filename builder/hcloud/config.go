@@ -18,8 +18,9 @@ import (
 )
 
 type Config struct {
-	common.PackerConfig `mapstructure:",squash"`
-	Comm                communicator.Config `mapstructure:",squash"`
+	common.PackerConfig    `mapstructure:",squash"`
+	common.CloudInitConfig `mapstructure:",squash"`
+	Comm                   communicator.Config `mapstructure:",squash"`
 
 	HCloudToken  string        `mapstructure:"token"`
 	Endpoint     string        `mapstructure:"endpoint"`
@@ -92,6 +93,7 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 	if es := c.Comm.Prepare(&c.ctx); len(es) > 0 {
 		errs = packer.MultiErrorAppend(errs, es...)
 	}
+	errs = packer.MultiErrorAppend(errs, c.CloudInitConfig.Prepare()...)
 	if c.HCloudToken == "" {
 		// Required configurations that will display errors if not set
 		errs = packer.MultiErrorAppend(
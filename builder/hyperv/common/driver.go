@@ -70,6 +70,10 @@ type Driver interface {
 
 	DeleteVirtualSwitch(string) error
 
+	CreateNATVirtualSwitch(string, *NATNetwork, bool) (bool, error)
+
+	DeleteNATVirtualSwitch(string, *NATNetwork, bool) error
+
 	CreateVirtualMachine(string, string, string, int64, int64, int64, string, uint, bool, bool) error
 
 	AddVirtualMachineHardDrive(string, string, string, int64, int64, string) error
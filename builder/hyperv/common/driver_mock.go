@@ -111,6 +111,19 @@ type DriverMock struct {
 	CreateVirtualSwitch_Return     bool
 	CreateVirtualSwitch_Err        error
 
+	CreateNATVirtualSwitch_Called     bool
+	CreateNATVirtualSwitch_SwitchName string
+	CreateNATVirtualSwitch_Network    *NATNetwork
+	CreateNATVirtualSwitch_DHCP       bool
+	CreateNATVirtualSwitch_Return     bool
+	CreateNATVirtualSwitch_Err        error
+
+	DeleteNATVirtualSwitch_Called     bool
+	DeleteNATVirtualSwitch_SwitchName string
+	DeleteNATVirtualSwitch_Network    *NATNetwork
+	DeleteNATVirtualSwitch_DHCP       bool
+	DeleteNATVirtualSwitch_Err        error
+
 	AddVirtualMachineHardDrive_Called         bool
 	AddVirtualMachineHardDrive_VmName         string
 	AddVirtualMachineHardDrive_VhdFile        string
@@ -395,6 +408,22 @@ func (d *DriverMock) CreateVirtualSwitch(switchName string, switchType string) (
 	return d.CreateVirtualSwitch_Return, d.CreateVirtualSwitch_Err
 }
 
+func (d *DriverMock) CreateNATVirtualSwitch(switchName string, network *NATNetwork, dhcp bool) (bool, error) {
+	d.CreateNATVirtualSwitch_Called = true
+	d.CreateNATVirtualSwitch_SwitchName = switchName
+	d.CreateNATVirtualSwitch_Network = network
+	d.CreateNATVirtualSwitch_DHCP = dhcp
+	return d.CreateNATVirtualSwitch_Return, d.CreateNATVirtualSwitch_Err
+}
+
+func (d *DriverMock) DeleteNATVirtualSwitch(switchName string, network *NATNetwork, dhcp bool) error {
+	d.DeleteNATVirtualSwitch_Called = true
+	d.DeleteNATVirtualSwitch_SwitchName = switchName
+	d.DeleteNATVirtualSwitch_Network = network
+	d.DeleteNATVirtualSwitch_DHCP = dhcp
+	return d.DeleteNATVirtualSwitch_Err
+}
+
 func (d *DriverMock) AddVirtualMachineHardDrive(vmName string, vhdFile string, vhdName string,
 	vhdSizeBytes int64, vhdDiskBlockSize int64, controllerType string) error {
 	d.AddVirtualMachineHardDrive_Called = true
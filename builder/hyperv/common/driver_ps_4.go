@@ -175,6 +175,15 @@ func (d *HypervPS4Driver) CreateVirtualSwitch(switchName string, switchType stri
 	return hyperv.CreateVirtualSwitch(switchName, switchType)
 }
 
+func (d *HypervPS4Driver) CreateNATVirtualSwitch(switchName string, network *NATNetwork, dhcp bool) (bool, error) {
+	return hyperv.CreateNATVirtualSwitch(switchName, network.Prefix, network.HostIP, network.PrefixLength, dhcp,
+		network.NetworkID, network.SubnetMask, network.DHCPStart, network.DHCPEnd)
+}
+
+func (d *HypervPS4Driver) DeleteNATVirtualSwitch(switchName string, network *NATNetwork, dhcp bool) error {
+	return hyperv.DeleteNATVirtualSwitch(switchName, network.NetworkID, dhcp)
+}
+
 func (d *HypervPS4Driver) AddVirtualMachineHardDrive(vmName string, vhdFile string, vhdName string,
 	vhdSizeBytes int64, diskBlockSize int64, controllerType string) error {
 	return hyperv.AddVirtualMachineHardDiskDrive(vmName, vhdFile, vhdName, vhdSizeBytes,
@@ -0,0 +1,23 @@
+package common
+
+import (
+	"github.com/hashicorp/packer/common/guestip"
+	"github.com/hashicorp/packer/helper/multistep"
+)
+
+// IPDiscoveryStrategies are the ways of finding the IP address of the guest
+// for the ip_discovery option: the addresses the integration services of the
+// guest report, like CommHost, and the ARP table of the host.
+var IPDiscoveryStrategies = map[string]guestip.Strategy{
+	guestip.Tools: CommHost,
+	guestip.ARP: func(state multistep.StateBag) (string, error) {
+		vmName := state.Get("vmName").(string)
+		driver := state.Get("driver").(Driver)
+
+		mac, err := driver.Mac(vmName)
+		if err != nil {
+			return "", err
+		}
+		return guestip.ARPLookup(mac)
+	},
+}
@@ -29,8 +29,12 @@ type StepCreateSwitch struct {
 	NetAdapterName string
 	// Specifies the interface description of the network adapter to be bound to the switch to be created.
 	NetAdapterInterfaceDescription string
+	// Specifies the NAT of the switch to be created, an internal switch whose network is routed to the one of
+	// the host, and optionally served by the DHCP server of the host.
+	NAT *SwitchNATConfig
 
 	createdSwitch bool
+	natNetwork    *NATNetwork
 }
 
 func (s *StepCreateSwitch) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
@@ -43,7 +47,13 @@ func (s *StepCreateSwitch) Run(_ context.Context, state multistep.StateBag) mult
 
 	ui.Say(fmt.Sprintf("Creating switch '%v' if required...", s.SwitchName))
 
-	createdSwitch, err := driver.CreateVirtualSwitch(s.SwitchName, s.SwitchType)
+	var createdSwitch bool
+	var err error
+	if s.NAT != nil && !s.NAT.Empty() {
+		createdSwitch, err = s.createNATSwitch(driver, ui)
+	} else {
+		createdSwitch, err = driver.CreateVirtualSwitch(s.SwitchName, s.SwitchType)
+	}
 	if err != nil {
 		err := fmt.Errorf("Error creating switch: %s", err)
 		state.Put("error", err)
@@ -64,6 +74,27 @@ func (s *StepCreateSwitch) Run(_ context.Context, state multistep.StateBag) mult
 	return multistep.ActionContinue
 }
 
+// createNATSwitch creates the NAT switch. What was created of it is
+// deleted if it can't be created entirely.
+func (s *StepCreateSwitch) createNATSwitch(driver Driver, ui packer.Ui) (bool, error) {
+	network, err := s.NAT.Network()
+	if err != nil {
+		return false, err
+	}
+
+	ui.Message(fmt.Sprintf("    with NAT to %s, the host being %s", network.Prefix, network.HostIP))
+	created, err := driver.CreateNATVirtualSwitch(s.SwitchName, network, s.NAT.SwitchDHCP)
+	if err != nil {
+		if err := driver.DeleteNATVirtualSwitch(s.SwitchName, network, s.NAT.SwitchDHCP); err != nil {
+			ui.Error(fmt.Sprintf("Error deleting the switch that couldn't be created: %s", err))
+		}
+		return false, err
+	}
+
+	s.natNetwork = network
+	return created, nil
+}
+
 func (s *StepCreateSwitch) Cleanup(state multistep.StateBag) {
 	if len(s.SwitchName) == 0 || !s.createdSwitch {
 		return
@@ -73,7 +104,12 @@ func (s *StepCreateSwitch) Cleanup(state multistep.StateBag) {
 	ui := state.Get("ui").(packer.Ui)
 	ui.Say("Unregistering and deleting switch...")
 
-	err := driver.DeleteVirtualSwitch(s.SwitchName)
+	var err error
+	if s.natNetwork != nil {
+		err = driver.DeleteNATVirtualSwitch(s.SwitchName, s.natNetwork, s.NAT.SwitchDHCP)
+	} else {
+		err = driver.DeleteVirtualSwitch(s.SwitchName)
+	}
 	if err != nil {
 		ui.Error(fmt.Sprintf("Error deleting switch: %s", err))
 	}
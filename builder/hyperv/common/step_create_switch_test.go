@@ -0,0 +1,89 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/packer/helper/multistep"
+)
+
+func TestStepCreateSwitch_impl(t *testing.T) {
+	var _ multistep.Step = new(StepCreateSwitch)
+}
+
+func TestStepCreateSwitch(t *testing.T) {
+	state := testState(t)
+	step := &StepCreateSwitch{
+		SwitchName: "packer-test",
+		NAT:        new(SwitchNATConfig),
+	}
+	driver := state.Get("driver").(*DriverMock)
+	driver.CreateVirtualSwitch_Return = true
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if !driver.CreateVirtualSwitch_Called || driver.CreateVirtualSwitch_SwitchType != SwitchTypeInternal {
+		t.Fatalf("should create an internal switch: %#v", driver.CreateVirtualSwitch_SwitchType)
+	}
+	if driver.CreateNATVirtualSwitch_Called {
+		t.Fatal("should not create a NAT switch")
+	}
+
+	step.Cleanup(state)
+	if !driver.DeleteVirtualSwitch_Called {
+		t.Fatal("should delete the switch")
+	}
+}
+
+func TestStepCreateSwitch_nat(t *testing.T) {
+	state := testState(t)
+	step := &StepCreateSwitch{
+		SwitchName: "packer-test",
+		NAT:        &SwitchNATConfig{SwitchNATCidr: "10.0.75.0/24", SwitchDHCP: true},
+	}
+	driver := state.Get("driver").(*DriverMock)
+	driver.CreateNATVirtualSwitch_Return = true
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if driver.CreateVirtualSwitch_Called {
+		t.Fatal("should not create a plain switch")
+	}
+	if !driver.CreateNATVirtualSwitch_Called || !driver.CreateNATVirtualSwitch_DHCP {
+		t.Fatal("should create a NAT switch with DHCP")
+	}
+	if driver.CreateNATVirtualSwitch_Network.HostIP != "10.0.75.1" {
+		t.Fatalf("bad host ip: %s", driver.CreateNATVirtualSwitch_Network.HostIP)
+	}
+
+	step.Cleanup(state)
+	if !driver.DeleteNATVirtualSwitch_Called || driver.DeleteVirtualSwitch_Called {
+		t.Fatal("should delete the NAT switch")
+	}
+	if driver.DeleteNATVirtualSwitch_Network.NetworkID != "10.0.75.0" {
+		t.Fatalf("bad network: %s", driver.DeleteNATVirtualSwitch_Network.NetworkID)
+	}
+}
+
+func TestStepCreateSwitch_natError(t *testing.T) {
+	state := testState(t)
+	step := &StepCreateSwitch{
+		SwitchName: "packer-test",
+		NAT:        &SwitchNATConfig{SwitchNATCidr: "10.0.75.0/24"},
+	}
+	driver := state.Get("driver").(*DriverMock)
+	driver.CreateNATVirtualSwitch_Err = errors.New("New-NetNat failed")
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("error"); !ok {
+		t.Fatal("should have error")
+	}
+	if !driver.DeleteNATVirtualSwitch_Called {
+		t.Fatal("should delete what was created of the switch")
+	}
+}
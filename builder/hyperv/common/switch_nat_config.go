@@ -0,0 +1,84 @@
+package common
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+// SwitchNATConfig is the configuration of the NAT of the switch that is
+// created for the build, and of its DHCP server.
+type SwitchNATConfig struct {
+	SwitchNATCidr string `mapstructure:"switch_nat_cidr"`
+	SwitchDHCP    bool   `mapstructure:"switch_dhcp"`
+}
+
+// NATNetwork are the addresses of the network of a NAT switch. The host is
+// the first address of the network, and DHCP leases the others.
+type NATNetwork struct {
+	Prefix       string
+	NetworkID    string
+	SubnetMask   string
+	PrefixLength int
+	HostIP       string
+	DHCPStart    string
+	DHCPEnd      string
+}
+
+func (c *SwitchNATConfig) Prepare(ctx *interpolate.Context) []error {
+	var errs []error
+
+	if c.SwitchNATCidr == "" {
+		if c.SwitchDHCP {
+			errs = append(errs, fmt.Errorf("switch_dhcp can only be set with switch_nat_cidr"))
+		}
+		return errs
+	}
+
+	if _, err := c.Network(); err != nil {
+		errs = append(errs, fmt.Errorf("switch_nat_cidr is invalid: %s", err))
+	}
+	return errs
+}
+
+// Empty says whether no NAT is configured.
+func (c *SwitchNATConfig) Empty() bool {
+	return c.SwitchNATCidr == ""
+}
+
+// Network returns the addresses of the network of switch_nat_cidr, an IPv4
+// network with room for the host and at least one guest.
+func (c *SwitchNATConfig) Network() (*NATNetwork, error) {
+	_, network, err := net.ParseCIDR(c.SwitchNATCidr)
+	if err != nil {
+		return nil, err
+	}
+	ip := network.IP.To4()
+	if ip == nil {
+		return nil, fmt.Errorf("%s isn't an IPv4 network", c.SwitchNATCidr)
+	}
+	ones, _ := network.Mask.Size()
+	if ones > 30 {
+		return nil, fmt.Errorf("%s is too small, the prefix length can be at most 30", c.SwitchNATCidr)
+	}
+
+	first := binary.BigEndian.Uint32(ip)
+	last := first | ^binary.BigEndian.Uint32(network.Mask)
+	return &NATNetwork{
+		Prefix:       network.String(),
+		NetworkID:    ip.String(),
+		SubnetMask:   net.IP(network.Mask).String(),
+		PrefixLength: ones,
+		HostIP:       ipv4(first + 1).String(),
+		DHCPStart:    ipv4(first + 2).String(),
+		DHCPEnd:      ipv4(last - 1).String(),
+	}, nil
+}
+
+func ipv4(n uint32) net.IP {
+	ip := make(net.IP, net.IPv4len)
+	binary.BigEndian.PutUint32(ip, n)
+	return ip
+}
@@ -0,0 +1,52 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSwitchNATConfigPrepare(t *testing.T) {
+	c := new(SwitchNATConfig)
+	if errs := c.Prepare(testConfigTemplate(t)); len(errs) > 0 {
+		t.Fatalf("err: %#v", errs)
+	}
+	if !c.Empty() {
+		t.Fatal("should be empty")
+	}
+
+	c = &SwitchNATConfig{SwitchDHCP: true}
+	if errs := c.Prepare(testConfigTemplate(t)); len(errs) == 0 {
+		t.Fatal("should have error with switch_dhcp but no switch_nat_cidr")
+	}
+
+	for _, cidr := range []string{"nope", "fd00::/64", "192.168.0.0/31"} {
+		c = &SwitchNATConfig{SwitchNATCidr: cidr}
+		if errs := c.Prepare(testConfigTemplate(t)); len(errs) == 0 {
+			t.Fatalf("should have error: %s", cidr)
+		}
+	}
+}
+
+func TestSwitchNATConfigNetwork(t *testing.T) {
+	c := &SwitchNATConfig{SwitchNATCidr: "192.168.240.7/24", SwitchDHCP: true}
+	if errs := c.Prepare(testConfigTemplate(t)); len(errs) > 0 {
+		t.Fatalf("err: %#v", errs)
+	}
+
+	network, err := c.Network()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := &NATNetwork{
+		Prefix:       "192.168.240.0/24",
+		NetworkID:    "192.168.240.0",
+		SubnetMask:   "255.255.255.0",
+		PrefixLength: 24,
+		HostIP:       "192.168.240.1",
+		DHCPStart:    "192.168.240.2",
+		DHCPEnd:      "192.168.240.254",
+	}
+	if !reflect.DeepEqual(network, expected) {
+		t.Fatalf("bad: %#v", network)
+	}
+}
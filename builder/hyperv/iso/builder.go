@@ -11,6 +11,7 @@ import (
 	hypervcommon "github.com/hashicorp/packer/builder/hyperv/common"
 	"github.com/hashicorp/packer/common"
 	"github.com/hashicorp/packer/common/bootcommand"
+	"github.com/hashicorp/packer/common/guestip"
 	powershell "github.com/hashicorp/packer/common/powershell"
 	"github.com/hashicorp/packer/common/powershell/hyperv"
 	"github.com/hashicorp/packer/helper/communicator"
@@ -49,14 +50,17 @@ type Builder struct {
 }
 
 type Config struct {
-	common.PackerConfig         `mapstructure:",squash"`
-	common.HTTPConfig           `mapstructure:",squash"`
-	common.ISOConfig            `mapstructure:",squash"`
-	common.FloppyConfig         `mapstructure:",squash"`
-	bootcommand.BootConfig      `mapstructure:",squash"`
-	hypervcommon.OutputConfig   `mapstructure:",squash"`
-	hypervcommon.SSHConfig      `mapstructure:",squash"`
-	hypervcommon.ShutdownConfig `mapstructure:",squash"`
+	common.PackerConfig          `mapstructure:",squash"`
+	common.HTTPConfig            `mapstructure:",squash"`
+	common.ISOConfig             `mapstructure:",squash"`
+	common.FloppyConfig          `mapstructure:",squash"`
+	bootcommand.BootConfig       `mapstructure:",squash"`
+	hypervcommon.OutputConfig    `mapstructure:",squash"`
+	hypervcommon.SSHConfig       `mapstructure:",squash"`
+	hypervcommon.SwitchNATConfig `mapstructure:",squash"`
+	hypervcommon.ShutdownConfig  `mapstructure:",squash"`
+	common.GuestOSConfig         `mapstructure:",squash"`
+	GuestIP                      guestip.Config `mapstructure:",squash"`
 
 	// The size, in megabytes, of the hard disk to create for the VM.
 	// By default, this is 130048 (about 127 GB).
@@ -142,8 +146,11 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 	errs = packer.MultiErrorAppend(errs, b.config.FloppyConfig.Prepare(&b.config.ctx)...)
 	errs = packer.MultiErrorAppend(errs, b.config.HTTPConfig.Prepare(&b.config.ctx)...)
 	errs = packer.MultiErrorAppend(errs, b.config.OutputConfig.Prepare(&b.config.ctx, &b.config.PackerConfig)...)
+	errs = packer.MultiErrorAppend(errs, b.config.GuestIP.Prepare(guestip.Tools, guestip.ARP)...)
 	errs = packer.MultiErrorAppend(errs, b.config.SSHConfig.Prepare(&b.config.ctx)...)
 	errs = packer.MultiErrorAppend(errs, b.config.ShutdownConfig.Prepare(&b.config.ctx)...)
+	errs = packer.MultiErrorAppend(errs, b.config.GuestOSConfig.Prepare()...)
+	errs = packer.MultiErrorAppend(errs, b.config.SwitchNATConfig.Prepare(&b.config.ctx)...)
 
 	if len(b.config.ISOConfig.ISOUrls) < 1 ||
 		(strings.ToLower(filepath.Ext(b.config.ISOConfig.ISOUrls[0])) != ".vhd" &&
@@ -172,7 +179,11 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 	log.Println(fmt.Sprintf("%s: %v", "VMName", b.config.VMName))
 
 	if b.config.SwitchName == "" {
-		b.config.SwitchName = b.detectSwitchName()
+		if b.config.SwitchNATConfig.Empty() {
+			b.config.SwitchName = b.detectSwitchName()
+		} else {
+			b.config.SwitchName = fmt.Sprintf("packer-%s", b.config.PackerBuildName)
+		}
 	}
 
 	if b.config.Cpu < 1 {
@@ -184,7 +195,7 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 	}
 
 	if b.config.Generation == 2 {
-		if len(b.config.FloppyFiles) > 0 || len(b.config.FloppyDirectories) > 0 {
+		if len(b.config.FloppyFiles) > 0 || len(b.config.FloppyDirectories) > 0 || b.config.Unattend != nil {
 			err = errors.New("Generation 2 vms don't support floppy drives. Use ISO image instead.")
 			errs = packer.MultiErrorAppend(errs, err)
 		}
@@ -299,6 +310,10 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 		errs = packer.MultiErrorAppend(errs, err)
 	}
 
+	if b.config.ShutdownCommand == "" {
+		b.config.ShutdownCommand = b.config.GuestOSConfig.DefaultShutdownCommand(&b.config.SSHConfig.Comm)
+	}
+
 	// Warnings
 
 	if b.config.ShutdownCommand == "" {
@@ -377,15 +392,18 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		&common.StepDownload{
 			Checksum:     b.config.ISOChecksum,
 			ChecksumType: b.config.ISOChecksumType,
+			Size:         b.config.ISOSize,
 			Description:  "ISO",
 			ResultKey:    "iso_path",
 			Url:          b.config.ISOUrls,
 			Extension:    b.config.TargetExtension,
+			Extract:      true,
 			TargetPath:   b.config.TargetPath,
 		},
 		&common.StepCreateFloppy{
 			Files:       b.config.FloppyConfig.FloppyFiles,
 			Directories: b.config.FloppyConfig.FloppyDirectories,
+			Unattend:    b.config.FloppyConfig.Unattend,
 		},
 		&common.StepHTTPServer{
 			HTTPDir:     b.config.HTTPDir,
@@ -394,6 +412,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		},
 		&hypervcommon.StepCreateSwitch{
 			SwitchName: b.config.SwitchName,
+			NAT:        &b.config.SwitchNATConfig,
 		},
 		&hypervcommon.StepCreateVM{
 			VMName:                         b.config.VMName,
@@ -453,16 +472,27 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		// configure the communicator ssh, winrm
 		&communicator.StepConnect{
 			Config:    &b.config.SSHConfig.Comm,
-			Host:      hypervcommon.CommHost,
+			BuildName: b.config.PackerBuildName,
+			Host:      b.config.GuestIP.CommHost(hypervcommon.CommHost, hypervcommon.IPDiscoveryStrategies),
 			SSHConfig: b.config.SSHConfig.Comm.SSHConfigFunc(),
 		},
 
 		// provision requires communicator to be setup
 		&common.StepProvision{},
 
+		// Remove the temporary files of the provisioners if guest_os_family is set
+		&common.StepCleanupTempFiles{
+			Comm:    &b.config.SSHConfig.Comm,
+			GuestOS: &b.config.GuestOSConfig,
+		},
+		&communicator.StepScramblePassword{
+			Config: &b.config.SSHConfig.Comm,
+		},
+
 		// Remove ephemeral key from authorized_hosts if using SSH communicator
 		&common.StepCleanupTempKeys{
-			Comm: &b.config.SSHConfig.Comm,
+			Comm:    &b.config.SSHConfig.Comm,
+			GuestOS: &b.config.GuestOSConfig,
 		},
 
 		&hypervcommon.StepShutdown{
@@ -514,7 +544,11 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		return nil, errors.New("Build was halted.")
 	}
 
-	return hypervcommon.NewArtifact(b.config.OutputDir)
+	artifact, err := hypervcommon.NewArtifact(b.config.OutputDir)
+	if err != nil {
+		return nil, err
+	}
+	return common.WithUnverifiedDownloads(artifact, state), nil
 }
 
 // Cancel.
@@ -33,6 +33,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		steps = append(steps,
 			&communicator.StepConnect{
 				Config:    &b.config.CommConfig,
+				BuildName: b.config.PackerBuildName,
 				Host:      CommHost(b.config.CommConfig.Host()),
 				SSHConfig: b.config.CommConfig.SSHConfigFunc(),
 			},
@@ -44,6 +44,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		new(stepCreateServer),
 		&communicator.StepConnect{
 			Config:    &b.config.Comm,
+			BuildName: b.config.PackerBuildName,
 			Host:      commHost,
 			SSHConfig: b.config.Comm.SSHConfigFunc(),
 		},
@@ -187,7 +187,7 @@ func (c *AccessConfig) computeV2Client() (*gophercloud.ServiceClient, error) {
 	})
 }
 
-func (c *AccessConfig) imageV2Client() (*gophercloud.ServiceClient, error) {
+func (c *AccessConfig) ImageV2Client() (*gophercloud.ServiceClient, error) {
 	return openstack.NewImageServiceV2(c.osClient, gophercloud.EndpointOpts{
 		Region:       c.Region,
 		Availability: c.getEndpointType(),
@@ -20,7 +20,7 @@ func (s *stepAddImageMembers) Run(_ context.Context, state multistep.StateBag) m
 		return multistep.ActionContinue
 	}
 
-	imageClient, err := config.imageV2Client()
+	imageClient, err := config.ImageV2Client()
 	if err != nil {
 		err = fmt.Errorf("Error initializing image service client: %s", err)
 		state.Put("error", err)
@@ -35,7 +35,7 @@ func (s *StepCreateVolume) Run(_ context.Context, state multistep.StateBag) mult
 		state.Put("error", err)
 		return multistep.ActionHalt
 	}
-	imageClient, err := config.imageV2Client()
+	imageClient, err := config.ImageV2Client()
 	if err != nil {
 		err = fmt.Errorf("Error initializing image client: %s", err)
 		state.Put("error", err)
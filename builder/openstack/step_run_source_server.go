@@ -9,6 +9,7 @@ import (
 	"github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/bootfromvolume"
 	"github.com/gophercloud/gophercloud/openstack/compute/v2/extensions/keypairs"
 	"github.com/gophercloud/gophercloud/openstack/compute/v2/servers"
+	commonhelper "github.com/hashicorp/packer/helper/common"
 	"github.com/hashicorp/packer/helper/multistep"
 	"github.com/hashicorp/packer/packer"
 )
@@ -137,6 +138,8 @@ func (s *StepRunSourceServer) Run(_ context.Context, state multistep.StateBag) m
 
 	s.server = latestServer.(*servers.Server)
 	state.Put("server", s.server)
+	commonhelper.SetBuildValue("ID", s.server.ID, config.PackerBuildName)
+	commonhelper.SetBuildValue("SourceImage", sourceImage, config.PackerBuildName)
 
 	return multistep.ActionContinue
 }
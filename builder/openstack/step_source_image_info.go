@@ -28,7 +28,7 @@ func (s *StepSourceImageInfo) Run(_ context.Context, state multistep.StateBag) m
 		return multistep.ActionContinue
 	}
 
-	client, err := config.imageV2Client()
+	client, err := config.ImageV2Client()
 
 	if s.SourceImageName != "" {
 		s.SourceImageOpts = images.ListOpts{
@@ -19,7 +19,7 @@ func (s *stepUpdateImageVisibility) Run(_ context.Context, state multistep.State
 	if config.ImageVisibility == "" {
 		return multistep.ActionContinue
 	}
-	imageClient, err := config.imageV2Client()
+	imageClient, err := config.ImageV2Client()
 	if err != nil {
 		err = fmt.Errorf("Error initializing image service client: %s", err)
 		state.Put("error", err)
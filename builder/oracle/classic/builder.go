@@ -99,6 +99,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			},
 			&communicator.StepConnect{
 				Config:    &b.config.Comm,
+				BuildName: b.config.PackerBuildName,
 				Host:      ocommon.CommHost,
 				SSHConfig: b.config.Comm.SSHConfigFunc(),
 			},
@@ -162,6 +163,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			&stepCreateInstance{},
 			&communicator.StepConnect{
 				Config:    &b.config.Comm,
+				BuildName: b.config.PackerBuildName,
 				Host:      ocommon.CommHost,
 				SSHConfig: b.config.Comm.SSHConfigFunc(),
 			},
@@ -20,8 +20,9 @@ import (
 )
 
 type Config struct {
-	common.PackerConfig `mapstructure:",squash"`
-	Comm                communicator.Config `mapstructure:",squash"`
+	common.PackerConfig    `mapstructure:",squash"`
+	common.CloudInitConfig `mapstructure:",squash"`
+	Comm                   communicator.Config `mapstructure:",squash"`
 
 	ConfigProvider ocicommon.ConfigurationProvider
 
@@ -135,6 +136,7 @@ func NewConfig(raws ...interface{}) (*Config, error) {
 	if es := c.Comm.Prepare(&c.ctx); len(es) > 0 {
 		errs = packer.MultiErrorAppend(errs, es...)
 	}
+	errs = packer.MultiErrorAppend(errs, c.CloudInitConfig.Prepare()...)
 
 	if userOCID, _ := configProvider.UserOCID(); userOCID == "" {
 		errs = packer.MultiErrorAppend(
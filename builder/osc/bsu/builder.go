@@ -0,0 +1,279 @@
+// The bsu package contains a packer.Builder implementation that builds OMIs
+// for the Outscale cloud, with the EC2 compatible API of Outscale.
+//
+// It follows the workflow of the amazon-ebs builder, and shares its
+// configuration, but doesn't use any AWS endpoint. OMIs are always backed
+// by BSU volumes, the EBS volumes of Outscale.
+package bsu
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/aws/aws-sdk-go/service/ec2"
+	awscommon "github.com/hashicorp/packer/builder/amazon/common"
+	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/helper/communicator"
+	"github.com/hashicorp/packer/helper/config"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+// The unique ID for this builder
+const BuilderId = "outscale.bsu"
+
+// The endpoint of the EC2 compatible API of an Outscale region
+const endpointFormat = "https://fcu.%s.outscale.com"
+
+type Config struct {
+	common.PackerConfig    `mapstructure:",squash"`
+	common.CloudInitConfig `mapstructure:",squash"`
+	awscommon.AccessConfig `mapstructure:",squash"`
+	awscommon.AMIConfig    `mapstructure:",squash"`
+	awscommon.BlockDevices `mapstructure:",squash"`
+	awscommon.RunConfig    `mapstructure:",squash"`
+	VolumeRunTags          awscommon.TagMap `mapstructure:"run_volume_tags"`
+
+	ctx interpolate.Context
+}
+
+type Builder struct {
+	config Config
+	runner multistep.Runner
+}
+
+func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
+	b.config.ctx.Funcs = awscommon.TemplateFuncs
+	err := config.Decode(&b.config, &config.DecodeOpts{
+		Interpolate:        true,
+		InterpolateContext: &b.config.ctx,
+		InterpolateFilter: &interpolate.RenderFilter{
+			Exclude: []string{
+				"ami_description",
+				"run_tags",
+				"run_volume_tags",
+				"snapshot_tags",
+				"tags",
+			},
+		},
+	}, raws...)
+	if err != nil {
+		return nil, err
+	}
+
+	if b.config.PackerConfig.PackerForce {
+		b.config.AMIForceDeregister = true
+	}
+
+	// Accumulate any errors
+	var errs *packer.MultiError
+	if b.config.RawRegion == "" {
+		errs = packer.MultiErrorAppend(errs, errors.New("region is required"))
+	} else if b.config.CustomEndpointEc2 == "" {
+		b.config.CustomEndpointEc2 = fmt.Sprintf(endpointFormat, b.config.RawRegion)
+	}
+
+	errs = packer.MultiErrorAppend(errs, b.config.AccessConfig.Prepare(&b.config.ctx)...)
+	errs = packer.MultiErrorAppend(errs,
+		b.config.AMIConfig.Prepare(&b.config.AccessConfig, &b.config.ctx)...)
+	errs = packer.MultiErrorAppend(errs, b.config.BlockDevices.Prepare(&b.config.ctx)...)
+	errs = packer.MultiErrorAppend(errs, b.config.RunConfig.Prepare(&b.config.ctx)...)
+	errs = packer.MultiErrorAppend(errs, b.config.CloudInitConfig.Prepare()...)
+	errs = packer.MultiErrorAppend(errs, b.config.unsupported()...)
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return nil, errs
+	}
+
+	packer.LogSecretFilter.Set(b.config.AccessKey, b.config.SecretKey, b.config.Token)
+	return nil, nil
+}
+
+// unsupported returns the errors of the options of amazon-ebs which have
+// no equivalent in Outscale.
+func (c *Config) unsupported() []error {
+	var errs []error
+	options := map[string]bool{
+		// The OMIs of other regions need the endpoints of these regions
+		"ami_regions":             len(c.AMIRegions) > 0,
+		"enable_t2_unlimited":     c.EnableT2Unlimited,
+		"ena_support":             c.AMIENASupport != nil && *c.AMIENASupport,
+		"encrypt_boot":            c.AMIEncryptBootVolume,
+		"iam_instance_profile":    c.IamInstanceProfile != "",
+		"kms_key_id":              c.AMIKmsKeyId != "",
+		"launch_template":         !c.LaunchTemplate.Empty(),
+		"region_kms_key_ids":      len(c.AMIRegionKMSKeyIDs) > 0,
+		"spot_price":              c.SpotPrice != "",
+		"spot_price_auto_product": c.SpotPriceAutoProduct != "",
+		"sriov_support":           c.AMISriovNetSupport,
+	}
+	for _, option := range []string{
+		"ami_regions", "enable_t2_unlimited", "ena_support", "encrypt_boot",
+		"iam_instance_profile", "kms_key_id", "launch_template",
+		"region_kms_key_ids", "spot_price", "spot_price_auto_product",
+		"sriov_support",
+	} {
+		if options[option] {
+			errs = append(errs, fmt.Errorf("%s isn't supported by Outscale", option))
+		}
+	}
+	return errs
+}
+
+func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packer.Artifact, error) {
+	session, err := b.config.Session()
+	if err != nil {
+		return nil, err
+	}
+	if !b.config.AMISkipRegionValidation {
+		err := b.config.AccessConfig.ValidateRegion(b.config.RawRegion)
+		if err != nil {
+			return nil, fmt.Errorf("error validating regions: %v", err)
+		}
+	}
+	ec2conn := ec2.New(session)
+
+	// Setup the state bag and initial state for the steps
+	state := new(multistep.BasicStateBag)
+	state.Put("config", &b.config)
+	state.Put("ec2", ec2conn)
+	state.Put("awsSession", session)
+	state.Put("hook", hook)
+	state.Put("ui", ui)
+
+	// Build the steps
+	steps := []multistep.Step{
+		&awscommon.StepPreValidate{
+			DestAmiName:     b.config.AMIName,
+			ForceDeregister: b.config.AMIForceDeregister,
+		},
+		&awscommon.StepSourceAMIInfo{
+			SourceAmi:   b.config.SourceAmi,
+			AmiFilters:  b.config.SourceAmiFilter,
+			AMIVirtType: b.config.AMIVirtType,
+		},
+		&awscommon.StepNetworkInfo{
+			VpcId:               b.config.VpcId,
+			VpcFilter:           b.config.VpcFilter,
+			SecurityGroupIds:    b.config.SecurityGroupIds,
+			SecurityGroupFilter: b.config.SecurityGroupFilter,
+			SubnetId:            b.config.SubnetId,
+			SubnetFilter:        b.config.SubnetFilter,
+			AvailabilityZone:    b.config.AvailabilityZone,
+		},
+		&awscommon.StepKeyPair{
+			Debug:        b.config.PackerDebug,
+			Comm:         &b.config.RunConfig.Comm,
+			DebugKeyPath: fmt.Sprintf("osc_%s.pem", b.config.PackerBuildName),
+		},
+		&awscommon.StepSecurityGroup{
+			SecurityGroupFilter:       b.config.SecurityGroupFilter,
+			SecurityGroupIds:          b.config.SecurityGroupIds,
+			CommConfig:                &b.config.RunConfig.Comm,
+			TemporarySGSourceCidrs:    b.config.TemporarySGSourceCidrs,
+			TemporarySGSourcePublicIp: b.config.TemporarySGSourcePublicIp,
+		},
+		&awscommon.StepCleanupVolumes{
+			BlockDevices: b.config.BlockDevices,
+		},
+		&awscommon.StepRunSourceInstance{
+			AssociatePublicIpAddress:          b.config.AssociatePublicIpAddress,
+			BlockDevices:                      b.config.BlockDevices,
+			BuildName:                         b.config.PackerBuildName,
+			CPUOptions:                        b.config.CPUOptions,
+			Comm:                              &b.config.RunConfig.Comm,
+			Ctx:                               b.config.ctx,
+			Debug:                             b.config.PackerDebug,
+			EbsOptimized:                      b.config.EbsOptimized,
+			ExpectedRootDevice:                "ebs",
+			InstanceInitiatedShutdownBehavior: b.config.InstanceInitiatedShutdownBehavior,
+			InstanceType:                      b.config.InstanceType,
+			// Outscale doesn't tag the resources as they are created
+			IsRestricted:   true,
+			PlacementGroup: b.config.PlacementGroup,
+			SourceAMI:      b.config.SourceAmi,
+			Tags:           b.config.RunTags,
+			Tenancy:        b.config.Tenancy,
+			UserData:       b.config.UserData,
+			UserDataFile:   b.config.UserDataFile,
+			VolumeTags:     b.config.VolumeRunTags,
+		},
+		&awscommon.StepGetPassword{
+			Debug:     b.config.PackerDebug,
+			Comm:      &b.config.RunConfig.Comm,
+			Timeout:   b.config.WindowsPasswordTimeout,
+			BuildName: b.config.PackerBuildName,
+		},
+		&communicator.StepConnect{
+			Config:    &b.config.RunConfig.Comm,
+			BuildName: b.config.PackerBuildName,
+			Host: awscommon.SSHHost(
+				ec2conn,
+				b.config.Comm.SSHInterface),
+			SSHConfig: b.config.RunConfig.Comm.SSHConfigFunc(),
+		},
+		&common.StepWaitCloudInit{
+			Config: &b.config.CloudInitConfig,
+			Comm:   &b.config.RunConfig.Comm,
+		},
+		&common.StepProvision{},
+		&common.StepCleanupTempKeys{
+			Comm: &b.config.RunConfig.Comm,
+		},
+		&awscommon.StepStopEBSBackedInstance{
+			DisableStopInstance: b.config.DisableStopInstance,
+		},
+		&awscommon.StepDeregisterAMI{
+			AccessConfig:        &b.config.AccessConfig,
+			ForceDeregister:     b.config.AMIForceDeregister,
+			ForceDeleteSnapshot: b.config.AMIForceDeleteSnapshot,
+			AMIName:             b.config.AMIName,
+		},
+		&stepCreateOMI{},
+		&awscommon.StepModifyAMIAttributes{
+			Description:    b.config.AMIDescription,
+			Users:          b.config.AMIUsers,
+			Groups:         b.config.AMIGroups,
+			ProductCodes:   b.config.AMIProductCodes,
+			SnapshotUsers:  b.config.SnapshotUsers,
+			SnapshotGroups: b.config.SnapshotGroups,
+			Ctx:            b.config.ctx,
+		},
+		&awscommon.StepCreateTags{
+			Tags:         b.config.AMITags,
+			SnapshotTags: b.config.SnapshotTags,
+			Ctx:          b.config.ctx,
+		},
+	}
+
+	// Run!
+	b.runner = common.NewRunner(steps, b.config.PackerConfig, ui)
+	b.runner.Run(state)
+	// If there was an error, return that
+	if rawErr, ok := state.GetOk("error"); ok {
+		return nil, rawErr.(error)
+	}
+
+	// If there are no OMIs, then just return
+	if _, ok := state.GetOk("amis"); !ok {
+		return nil, nil
+	}
+
+	// Build the artifact and return it
+	artifact := &awscommon.Artifact{
+		Amis:           state.Get("amis").(map[string]string),
+		BuilderIdValue: BuilderId,
+		Session:        session,
+	}
+
+	return artifact, nil
+}
+
+func (b *Builder) Cancel() {
+	if b.runner != nil {
+		log.Println("Cancelling the step runner...")
+		b.runner.Cancel()
+	}
+}
@@ -0,0 +1,82 @@
+package bsu
+
+import (
+	"testing"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+func testConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"access_key":    "foo",
+		"secret_key":    "bar",
+		"source_ami":    "foo",
+		"instance_type": "foo",
+		"region":        "eu-west-2",
+		"ssh_username":  "root",
+		"ami_name":      "foo",
+	}
+}
+
+func TestBuilder_ImplementsBuilder(t *testing.T) {
+	var raw interface{}
+	raw = &Builder{}
+	if _, ok := raw.(packer.Builder); !ok {
+		t.Fatalf("Builder should be a builder")
+	}
+}
+
+func TestBuilderPrepare_Endpoint(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	warnings, err := b.Prepare(config)
+	if len(warnings) > 0 {
+		t.Fatalf("bad: %#v", warnings)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.CustomEndpointEc2 != "https://fcu.eu-west-2.outscale.com" {
+		t.Fatalf("bad: %s", b.config.CustomEndpointEc2)
+	}
+
+	// A set endpoint is kept
+	b = Builder{}
+	config["custom_endpoint_ec2"] = "https://fcu.example.com"
+	_, err = b.Prepare(config)
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.CustomEndpointEc2 != "https://fcu.example.com" {
+		t.Fatalf("bad: %s", b.config.CustomEndpointEc2)
+	}
+}
+
+func TestBuilderPrepare_Region(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	delete(config, "region")
+	_, err := b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestBuilderPrepare_Unsupported(t *testing.T) {
+	cases := map[string]interface{}{
+		"ami_regions":          []string{"us-east-2"},
+		"encrypt_boot":         true,
+		"iam_instance_profile": "foo",
+		"spot_price":           "auto",
+		"sriov_support":        true,
+	}
+	for option, value := range cases {
+		var b Builder
+		config := testConfig()
+		config[option] = value
+		_, err := b.Prepare(config)
+		if err == nil {
+			t.Fatalf("%s should have error", option)
+		}
+	}
+}
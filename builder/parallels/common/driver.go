@@ -28,6 +28,10 @@ type Driver interface {
 	// Import a VM
 	Import(string, string, string, bool) error
 
+	// MachineArchitecture is the architecture of the VMs of the host,
+	// arm64 on Apple silicon and x86_64 otherwise.
+	MachineArchitecture() (string, error)
+
 	// Checks if the VM with the given name is running.
 	IsRunning(string) (bool, error)
 
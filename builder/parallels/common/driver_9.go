@@ -260,6 +260,18 @@ func (d *Parallels9Driver) Verify() error {
 	return nil
 }
 
+// MachineArchitecture returns the architecture of the VMs of the host. It
+// asks the CPU rather than Go, as Packer may run with Rosetta on Apple
+// silicon.
+func (d *Parallels9Driver) MachineArchitecture() (string, error) {
+	// The key doesn't exist on Intel hosts
+	out, err := exec.Command("sysctl", "-n", "hw.optional.arm64").Output()
+	if err == nil && strings.TrimSpace(string(out)) == "1" {
+		return ArchitectureARM64, nil
+	}
+	return ArchitectureX86_64, nil
+}
+
 // Version returns the version of Parallels Desktop installed on that host.
 func (d *Parallels9Driver) Version() (string, error) {
 	out, err := exec.Command(d.PrlctlPath, "--version").Output()
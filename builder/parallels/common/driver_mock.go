@@ -26,6 +26,10 @@ type DriverMock struct {
 	ImportDstPath string
 	ImportErr     error
 
+	MachineArchitectureCalled bool
+	MachineArchitectureResult string
+	MachineArchitectureErr    error
+
 	IsRunningName   string
 	IsRunningReturn bool
 	IsRunningErr    error
@@ -90,6 +94,11 @@ func (d *DriverMock) Import(name, srcPath, dstPath string, reassignMAC bool) err
 	return d.ImportErr
 }
 
+func (d *DriverMock) MachineArchitecture() (string, error) {
+	d.MachineArchitectureCalled = true
+	return d.MachineArchitectureResult, d.MachineArchitectureErr
+}
+
 func (d *DriverMock) IsRunning(name string) (bool, error) {
 	d.Lock()
 	defer d.Unlock()
@@ -0,0 +1,50 @@
+package common
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// StepMachineArchitecture is a step that finds the architecture of the VMs
+// of the host, and checks it is the one of the configuration.
+//
+// Uses:
+//   driver Driver
+//   ui packer.Ui
+//
+// Produces:
+//   machine_architecture string
+type StepMachineArchitecture struct {
+	Architecture string
+}
+
+// Run sets the value of "machine_architecture".
+func (s *StepMachineArchitecture) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	driver := state.Get("driver").(Driver)
+	ui := state.Get("ui").(packer.Ui)
+
+	architecture, err := driver.MachineArchitecture()
+	if err != nil {
+		err := fmt.Errorf("Error finding the machine architecture: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	if s.Architecture != "" && s.Architecture != architecture {
+		err := fmt.Errorf("Parallels Desktop on this host runs %s VMs, not %s VMs",
+			architecture, s.Architecture)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	state.Put("machine_architecture", architecture)
+	return multistep.ActionContinue
+}
+
+// Cleanup does nothing.
+func (s *StepMachineArchitecture) Cleanup(multistep.StateBag) {}
@@ -0,0 +1,48 @@
+package common
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/packer/helper/multistep"
+)
+
+func TestStepMachineArchitecture_impl(t *testing.T) {
+	var _ multistep.Step = new(StepMachineArchitecture)
+}
+
+func TestStepMachineArchitecture(t *testing.T) {
+	state := testState(t)
+	step := new(StepMachineArchitecture)
+
+	driver := state.Get("driver").(*DriverMock)
+	driver.MachineArchitectureResult = ArchitectureARM64
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("error"); ok {
+		t.Fatal("should NOT have error")
+	}
+	if !driver.MachineArchitectureCalled {
+		t.Fatal("machine architecture should be called")
+	}
+	if architecture := state.Get("machine_architecture"); architecture != ArchitectureARM64 {
+		t.Fatalf("bad: %#v", architecture)
+	}
+}
+
+func TestStepMachineArchitecture_mismatch(t *testing.T) {
+	state := testState(t)
+	step := &StepMachineArchitecture{Architecture: ArchitectureX86_64}
+
+	driver := state.Get("driver").(*DriverMock)
+	driver.MachineArchitectureResult = ArchitectureARM64
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("error"); !ok {
+		t.Fatal("should have error")
+	}
+}
@@ -13,6 +13,7 @@ import (
 //
 // Uses:
 //   driver Driver
+//   machine_architecture string
 //
 // Produces:
 //   parallels_tools_path string
@@ -21,7 +22,8 @@ type StepPrepareParallelsTools struct {
 	ParallelsToolsMode   string
 }
 
-// Run sets the value of "parallels_tools_path".
+// Run sets the value of "parallels_tools_path". The Linux and Windows tools
+// of arm64 VMs are the "lin-arm" and "win-arm" flavors.
 func (s *StepPrepareParallelsTools) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
 	driver := state.Get("driver").(Driver)
 
@@ -29,7 +31,14 @@ func (s *StepPrepareParallelsTools) Run(_ context.Context, state multistep.State
 		return multistep.ActionContinue
 	}
 
-	path, err := driver.ToolsISOPath(s.ParallelsToolsFlavor)
+	flavor := s.ParallelsToolsFlavor
+	if architecture, _ := state.Get("machine_architecture").(string); architecture == ArchitectureARM64 {
+		if flavor == "lin" || flavor == "win" {
+			flavor += "-arm"
+		}
+	}
+
+	path, err := driver.ToolsISOPath(flavor)
 
 	if err != nil {
 		state.Put("error", err)
@@ -40,7 +49,7 @@ func (s *StepPrepareParallelsTools) Run(_ context.Context, state multistep.State
 		state.Put("error", fmt.Errorf(
 			"Couldn't find Parallels Tools for the '%s' flavor! Please, check the\n"+
 				"value of 'parallels_tools_flavor'. Valid flavors are: 'win', 'lin',\n"+
-				"'mac', 'os2' and 'other'", s.ParallelsToolsFlavor))
+				"'mac', 'os2' and 'other'", flavor))
 		return multistep.ActionHalt
 	}
 
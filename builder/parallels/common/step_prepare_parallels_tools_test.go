@@ -114,3 +114,29 @@ func TestStepPrepareParallelsTools_nonExist(t *testing.T) {
 		t.Fatal("should NOT have parallels_tools_path")
 	}
 }
+
+func TestStepPrepareParallelsTools_arm64(t *testing.T) {
+	tf, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	tf.Close()
+	defer os.Remove(tf.Name())
+
+	state := testState(t)
+	state.Put("machine_architecture", ArchitectureARM64)
+	step := &StepPrepareParallelsTools{
+		ParallelsToolsMode:   "",
+		ParallelsToolsFlavor: "lin",
+	}
+
+	driver := state.Get("driver").(*DriverMock)
+	driver.ToolsISOPathResult = tf.Name()
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if driver.ToolsISOPathFlavor != "lin-arm" {
+		t.Fatalf("bad: %#v", driver.ToolsISOPathFlavor)
+	}
+}
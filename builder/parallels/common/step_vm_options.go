@@ -0,0 +1,51 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// StepVMOptions is a step that sets the typed options of the VM.
+//
+// Uses:
+//   driver Driver
+//   machine_architecture string
+//   ui packer.Ui
+//   vmName string
+//
+// Produces:
+type StepVMOptions struct {
+	Config *VMOptionsConfig
+}
+
+// Run executes `prlctl set` with the options of the VM.
+func (s *StepVMOptions) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	driver := state.Get("driver").(Driver)
+	ui := state.Get("ui").(packer.Ui)
+	vmName := state.Get("vmName").(string)
+	architecture, _ := state.Get("machine_architecture").(string)
+
+	args := s.Config.Args(architecture)
+	if len(args) == 0 {
+		return multistep.ActionContinue
+	}
+
+	ui.Say("Setting the VM options...")
+	command := append([]string{"set", vmName}, args...)
+	ui.Message(fmt.Sprintf("Executing: prlctl %s", strings.Join(command, " ")))
+	if err := driver.Prlctl(command...); err != nil {
+		err = fmt.Errorf("Error setting the VM options: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+// Cleanup does nothing.
+func (s *StepVMOptions) Cleanup(multistep.StateBag) {}
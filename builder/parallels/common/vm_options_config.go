@@ -0,0 +1,85 @@
+package common
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+// These are the architectures of the VMs of Parallels Desktop.
+const (
+	ArchitectureX86_64 string = "x86_64"
+	ArchitectureARM64         = "arm64"
+)
+
+// VMOptionsConfig contains the typed options of the VM, set with `prlctl
+// set` before the custom prlctl commands are run.
+type VMOptionsConfig struct {
+	MachineArchitecture  string `mapstructure:"machine_architecture"`
+	EFIBoot              bool   `mapstructure:"efi_boot"`
+	VideoMemory          int    `mapstructure:"video_memory"`
+	StartupView          string `mapstructure:"startup_view"`
+	IsolateVM            bool   `mapstructure:"isolate_vm"`
+	NestedVirtualization bool   `mapstructure:"nested_virtualization"`
+}
+
+var validStartupViews = []string{"same", "window", "coherence", "fullscreen", "modality", "headless"}
+
+// Prepare validates the options of the VM.
+func (c *VMOptionsConfig) Prepare(ctx *interpolate.Context) []error {
+	var errs []error
+
+	switch c.MachineArchitecture {
+	case "", ArchitectureX86_64, ArchitectureARM64:
+	default:
+		errs = append(errs, fmt.Errorf("machine_architecture must be one of %s or %s",
+			ArchitectureX86_64, ArchitectureARM64))
+	}
+
+	if c.VideoMemory < 0 {
+		errs = append(errs, fmt.Errorf("An invalid video memory size was specified (video_memory < 0): %d", c.VideoMemory))
+	}
+
+	if c.StartupView != "" {
+		valid := false
+		for _, view := range validStartupViews {
+			if c.StartupView == view {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			errs = append(errs, fmt.Errorf("startup_view is invalid. Must be one of: %v", validStartupViews))
+		}
+	}
+
+	if c.MachineArchitecture == ArchitectureARM64 && c.EFIBoot {
+		errs = append(errs, fmt.Errorf("efi_boot can't be set for arm64 VMs, which always boot with EFI"))
+	}
+
+	return errs
+}
+
+// Args are the arguments of `prlctl set` for the options of the VM, none if
+// no option is set. arm64 VMs always boot with EFI, so efi_boot isn't set
+// for them.
+func (c *VMOptionsConfig) Args(architecture string) []string {
+	var args []string
+	if c.EFIBoot && architecture != ArchitectureARM64 {
+		args = append(args, "--efi-boot", "on")
+	}
+	if c.VideoMemory > 0 {
+		args = append(args, "--videosize", strconv.Itoa(c.VideoMemory))
+	}
+	if c.StartupView != "" {
+		args = append(args, "--startup-view", c.StartupView)
+	}
+	if c.IsolateVM {
+		args = append(args, "--isolate-vm", "on")
+	}
+	if c.NestedVirtualization {
+		args = append(args, "--nested-virt", "on")
+	}
+	return args
+}
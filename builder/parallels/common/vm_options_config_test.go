@@ -0,0 +1,61 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestVMOptionsConfigPrepare(t *testing.T) {
+	c := new(VMOptionsConfig)
+	if errs := c.Prepare(testConfigTemplate(t)); len(errs) > 0 {
+		t.Fatalf("err: %#v", errs)
+	}
+
+	if args := c.Args(ArchitectureX86_64); len(args) > 0 {
+		t.Fatalf("bad: %#v", args)
+	}
+}
+
+func TestVMOptionsConfigPrepare_invalid(t *testing.T) {
+	cases := []VMOptionsConfig{
+		{MachineArchitecture: "ppc"},
+		{VideoMemory: -1},
+		{StartupView: "minimized"},
+		{MachineArchitecture: ArchitectureARM64, EFIBoot: true},
+	}
+
+	for _, c := range cases {
+		if errs := c.Prepare(testConfigTemplate(t)); len(errs) == 0 {
+			t.Fatalf("should have error: %#v", c)
+		}
+	}
+}
+
+func TestVMOptionsConfigArgs(t *testing.T) {
+	c := &VMOptionsConfig{
+		EFIBoot:              true,
+		VideoMemory:          256,
+		StartupView:          "headless",
+		IsolateVM:            true,
+		NestedVirtualization: true,
+	}
+	if errs := c.Prepare(testConfigTemplate(t)); len(errs) > 0 {
+		t.Fatalf("err: %#v", errs)
+	}
+
+	expected := []string{
+		"--efi-boot", "on",
+		"--videosize", "256",
+		"--startup-view", "headless",
+		"--isolate-vm", "on",
+		"--nested-virt", "on",
+	}
+	if args := c.Args(ArchitectureX86_64); !reflect.DeepEqual(args, expected) {
+		t.Fatalf("bad: %#v", args)
+	}
+
+	// arm64 VMs always boot with EFI
+	if args := c.Args(ArchitectureARM64); !reflect.DeepEqual(args, expected[2:]) {
+		t.Fatalf("bad: %#v", args)
+	}
+}
@@ -34,8 +34,10 @@ type Config struct {
 	parallelscommon.PrlctlPostConfig    `mapstructure:",squash"`
 	parallelscommon.PrlctlVersionConfig `mapstructure:",squash"`
 	parallelscommon.ShutdownConfig      `mapstructure:",squash"`
+	common.GuestOSConfig                `mapstructure:",squash"`
 	parallelscommon.SSHConfig           `mapstructure:",squash"`
 	parallelscommon.ToolsConfig         `mapstructure:",squash"`
+	parallelscommon.VMOptionsConfig     `mapstructure:",squash"`
 
 	DiskSize           uint     `mapstructure:"disk_size"`
 	DiskType           string   `mapstructure:"disk_type"`
@@ -82,8 +84,10 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 	errs = packer.MultiErrorAppend(errs, b.config.PrlctlPostConfig.Prepare(&b.config.ctx)...)
 	errs = packer.MultiErrorAppend(errs, b.config.PrlctlVersionConfig.Prepare(&b.config.ctx)...)
 	errs = packer.MultiErrorAppend(errs, b.config.ShutdownConfig.Prepare(&b.config.ctx)...)
+	errs = packer.MultiErrorAppend(errs, b.config.GuestOSConfig.Prepare()...)
 	errs = packer.MultiErrorAppend(errs, b.config.SSHConfig.Prepare(&b.config.ctx)...)
 	errs = packer.MultiErrorAppend(errs, b.config.ToolsConfig.Prepare(&b.config.ctx)...)
+	errs = packer.MultiErrorAppend(errs, b.config.VMOptionsConfig.Prepare(&b.config.ctx)...)
 	errs = packer.MultiErrorAppend(errs, b.config.BootConfig.Prepare(&b.config.ctx)...)
 
 	if b.config.DiskSize == 0 {
@@ -127,6 +131,10 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 			errs, errors.New("hard_drive_interface can only be ide, sata, or scsi"))
 	}
 
+	if b.config.ShutdownCommand == "" {
+		b.config.ShutdownCommand = b.config.GuestOSConfig.DefaultShutdownCommand(&b.config.SSHConfig.Comm)
+	}
+
 	// Warnings
 	if b.config.ShutdownCommand == "" {
 		warnings = append(warnings,
@@ -149,6 +157,9 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 	}
 
 	steps := []multistep.Step{
+		&parallelscommon.StepMachineArchitecture{
+			Architecture: b.config.MachineArchitecture,
+		},
 		&parallelscommon.StepPrepareParallelsTools{
 			ParallelsToolsFlavor: b.config.ParallelsToolsFlavor,
 			ParallelsToolsMode:   b.config.ParallelsToolsMode,
@@ -156,8 +167,10 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		&common.StepDownload{
 			Checksum:     b.config.ISOChecksum,
 			ChecksumType: b.config.ISOChecksumType,
+			Size:         b.config.ISOSize,
 			Description:  "ISO",
 			Extension:    b.config.TargetExtension,
+			Extract:      true,
 			ResultKey:    "iso_path",
 			TargetPath:   b.config.TargetPath,
 			Url:          b.config.ISOUrls,
@@ -169,6 +182,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		&common.StepCreateFloppy{
 			Files:       b.config.FloppyConfig.FloppyFiles,
 			Directories: b.config.FloppyConfig.FloppyDirectories,
+			Unattend:    b.config.FloppyConfig.Unattend,
 		},
 		&common.StepHTTPServer{
 			HTTPDir:     b.config.HTTPDir,
@@ -183,6 +197,9 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			ParallelsToolsMode: b.config.ParallelsToolsMode,
 		},
 		new(parallelscommon.StepAttachFloppy),
+		&parallelscommon.StepVMOptions{
+			Config: &b.config.VMOptionsConfig,
+		},
 		&parallelscommon.StepPrlctl{
 			Commands: b.config.Prlctl,
 			Ctx:      b.config.ctx,
@@ -198,6 +215,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		},
 		&communicator.StepConnect{
 			Config:    &b.config.SSHConfig.Comm,
+			BuildName: b.config.PackerBuildName,
 			Host:      parallelscommon.CommHost,
 			SSHConfig: b.config.SSHConfig.Comm.SSHConfigFunc(),
 		},
@@ -211,8 +229,16 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			Ctx:                     b.config.ctx,
 		},
 		new(common.StepProvision),
+		&common.StepCleanupTempFiles{
+			Comm:    &b.config.SSHConfig.Comm,
+			GuestOS: &b.config.GuestOSConfig,
+		},
+		&communicator.StepScramblePassword{
+			Config: &b.config.SSHConfig.Comm,
+		},
 		&common.StepCleanupTempKeys{
-			Comm: &b.config.SSHConfig.Comm,
+			Comm:    &b.config.SSHConfig.Comm,
+			GuestOS: &b.config.GuestOSConfig,
 		},
 		&parallelscommon.StepShutdown{
 			Command: b.config.ShutdownCommand,
@@ -254,7 +280,11 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		return nil, errors.New("Build was halted.")
 	}
 
-	return parallelscommon.NewArtifact(b.config.OutputDir)
+	artifact, err := parallelscommon.NewArtifact(b.config.OutputDir)
+	if err != nil {
+		return nil, err
+	}
+	return common.WithUnverifiedDownloads(artifact, state), nil
 }
 
 func (b *Builder) Cancel() {
@@ -13,6 +13,7 @@ import (
 //
 // Uses:
 //   driver Driver
+//   machine_architecture string
 //   ui packer.Ui
 //   vmName string
 //
@@ -23,12 +24,18 @@ func (s *stepSetBootOrder) Run(_ context.Context, state multistep.StateBag) mult
 	driver := state.Get("driver").(parallelscommon.Driver)
 	ui := state.Get("ui").(packer.Ui)
 	vmName := state.Get("vmName").(string)
+	architecture, _ := state.Get("machine_architecture").(string)
 
-	// Set new boot order
+	// Set new boot order. The EFI firmware of arm64 VMs can't boot from the
+	// network.
+	bootOrder := "hdd0 cdrom0 net0"
+	if architecture == parallelscommon.ArchitectureARM64 {
+		bootOrder = "hdd0 cdrom0"
+	}
 	ui.Say("Setting the boot order...")
 	command := []string{
 		"set", vmName,
-		"--device-bootorder", fmt.Sprintf("hdd0 cdrom0 net0"),
+		"--device-bootorder", bootOrder,
 	}
 
 	if err := driver.Prlctl(command...); err != nil {
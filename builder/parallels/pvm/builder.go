@@ -50,6 +50,9 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 
 	// Build the steps.
 	steps := []multistep.Step{
+		&parallelscommon.StepMachineArchitecture{
+			Architecture: b.config.MachineArchitecture,
+		},
 		&parallelscommon.StepPrepareParallelsTools{
 			ParallelsToolsMode:   b.config.ParallelsToolsMode,
 			ParallelsToolsFlavor: b.config.ParallelsToolsFlavor,
@@ -61,6 +64,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		&common.StepCreateFloppy{
 			Files:       b.config.FloppyConfig.FloppyFiles,
 			Directories: b.config.FloppyConfig.FloppyDirectories,
+			Unattend:    b.config.FloppyConfig.Unattend,
 		},
 		&StepImport{
 			Name:       b.config.VMName,
@@ -70,6 +74,9 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			ParallelsToolsMode: b.config.ParallelsToolsMode,
 		},
 		new(parallelscommon.StepAttachFloppy),
+		&parallelscommon.StepVMOptions{
+			Config: &b.config.VMOptionsConfig,
+		},
 		&parallelscommon.StepPrlctl{
 			Commands: b.config.Prlctl,
 			Ctx:      b.config.ctx,
@@ -85,6 +92,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		},
 		&communicator.StepConnect{
 			Config:    &b.config.SSHConfig.Comm,
+			BuildName: b.config.PackerBuildName,
 			Host:      parallelscommon.CommHost,
 			SSHConfig: b.config.SSHConfig.Comm.SSHConfigFunc(),
 		},
@@ -102,8 +110,16 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			Command: b.config.ShutdownCommand,
 			Timeout: b.config.ShutdownTimeout,
 		},
+		&common.StepCleanupTempFiles{
+			Comm:    &b.config.SSHConfig.Comm,
+			GuestOS: &b.config.GuestOSConfig,
+		},
+		&communicator.StepScramblePassword{
+			Config: &b.config.SSHConfig.Comm,
+		},
 		&common.StepCleanupTempKeys{
-			Comm: &b.config.SSHConfig.Comm,
+			Comm:    &b.config.SSHConfig.Comm,
+			GuestOS: &b.config.GuestOSConfig,
 		},
 		&parallelscommon.StepPrlctl{
 			Commands: b.config.PrlctlPost,
@@ -22,8 +22,10 @@ type Config struct {
 	parallelscommon.PrlctlVersionConfig `mapstructure:",squash"`
 	parallelscommon.SSHConfig           `mapstructure:",squash"`
 	parallelscommon.ShutdownConfig      `mapstructure:",squash"`
+	common.GuestOSConfig                `mapstructure:",squash"`
 	bootcommand.BootConfig              `mapstructure:",squash"`
 	parallelscommon.ToolsConfig         `mapstructure:",squash"`
+	parallelscommon.VMOptionsConfig     `mapstructure:",squash"`
 
 	SourcePath     string `mapstructure:"source_path"`
 	SkipCompaction bool   `mapstructure:"skip_compaction"`
@@ -64,8 +66,10 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 	errs = packer.MultiErrorAppend(errs, c.PrlctlVersionConfig.Prepare(&c.ctx)...)
 	errs = packer.MultiErrorAppend(errs, c.BootConfig.Prepare(&c.ctx)...)
 	errs = packer.MultiErrorAppend(errs, c.ShutdownConfig.Prepare(&c.ctx)...)
+	errs = packer.MultiErrorAppend(errs, c.GuestOSConfig.Prepare()...)
 	errs = packer.MultiErrorAppend(errs, c.SSHConfig.Prepare(&c.ctx)...)
 	errs = packer.MultiErrorAppend(errs, c.ToolsConfig.Prepare(&c.ctx)...)
+	errs = packer.MultiErrorAppend(errs, c.VMOptionsConfig.Prepare(&c.ctx)...)
 
 	if c.SourcePath == "" {
 		errs = packer.MultiErrorAppend(errs, fmt.Errorf("source_path is required"))
@@ -76,6 +80,10 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 		}
 	}
 
+	if c.ShutdownCommand == "" {
+		c.ShutdownCommand = c.GuestOSConfig.DefaultShutdownCommand(&c.SSHConfig.Comm)
+	}
+
 	// Warnings
 	var warnings []string
 	if c.ShutdownCommand == "" {
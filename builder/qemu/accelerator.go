@@ -0,0 +1,70 @@
+package qemu
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// hostAccelerator is the accelerator Packer uses when none is set. It's a
+// variable so that tests don't depend on the host.
+var hostAccelerator = detectAccelerator
+
+// detectAccelerator returns the hardware accelerator of the host, kvm on
+// Linux, hvf on macOS and whpx on Windows, if it can be used with the QEMU
+// binary. It returns tcg otherwise, with the reason why.
+func detectAccelerator(qemuBinary string) (string, string) {
+	switch runtime.GOOS {
+	case "darwin":
+		out, err := exec.Command("sysctl", "-n", "kern.hv_support").Output()
+		if err != nil || strings.TrimSpace(string(out)) != "1" {
+			return "tcg", "this Mac doesn't support the Hypervisor framework"
+		}
+		return qemuAccelerator(qemuBinary, "hvf")
+	case "windows":
+		// The library is only there when the feature is enabled
+		dll := filepath.Join(os.Getenv("SystemRoot"), "System32", "WinHvPlatform.dll")
+		if _, err := os.Stat(dll); err != nil {
+			return "tcg", "the Windows Hypervisor Platform feature isn't enabled"
+		}
+		return qemuAccelerator(qemuBinary, "whpx")
+	default:
+		// /dev/kvm is a kernel module that may be loaded if kvm is
+		// installed and the host supports VT-x extensions. To make sure
+		// this will actually work we need to os.Open() it. If os.Open fails
+		// the kernel module was not installed or loaded correctly.
+		fp, err := os.Open("/dev/kvm")
+		if err != nil {
+			return "tcg", fmt.Sprintf("/dev/kvm can't be opened: %s", err)
+		}
+		fp.Close()
+		return "kvm", ""
+	}
+}
+
+// qemuAccelerator returns the accelerator if the QEMU binary has it, and tcg
+// with the reason why otherwise.
+func qemuAccelerator(qemuBinary, accelerator string) (string, string) {
+	out, err := exec.Command(qemuBinary, "-accel", "help").Output()
+	if err != nil {
+		return "tcg", fmt.Sprintf("the accelerators of %s can't be listed: %s", qemuBinary, err)
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		if strings.TrimSpace(line) == accelerator {
+			return accelerator, ""
+		}
+	}
+	return "tcg", fmt.Sprintf("%s doesn't support the %s accelerator", qemuBinary, accelerator)
+}
+
+// defaultDisplay is the display of QEMU when the VM isn't headless. The
+// QEMU builds of macOS have the cocoa display rather than sdl.
+func defaultDisplay() string {
+	if runtime.GOOS == "darwin" {
+		return "cocoa"
+	}
+	return "sdl"
+}
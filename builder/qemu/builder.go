@@ -7,11 +7,15 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
-	"runtime"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/hashicorp/packer/common"
 	"github.com/hashicorp/packer/common/bootcommand"
+	"github.com/hashicorp/packer/common/guestip"
+	"github.com/hashicorp/packer/common/outputstore"
+	"github.com/hashicorp/packer/common/preflight"
 	"github.com/hashicorp/packer/helper/communicator"
 	"github.com/hashicorp/packer/helper/config"
 	"github.com/hashicorp/packer/helper/multistep"
@@ -28,6 +32,7 @@ var accels = map[string]struct{}{
 	"xen":  {},
 	"hax":  {},
 	"hvf":  {},
+	"whpx": {},
 }
 
 var netDevice = map[string]bool{
@@ -93,6 +98,11 @@ type Config struct {
 	bootcommand.VNCConfig `mapstructure:",squash"`
 	Comm                  communicator.Config `mapstructure:",squash"`
 	common.FloppyConfig   `mapstructure:",squash"`
+	common.ZeroFillConfig `mapstructure:",squash"`
+	common.GuestOSConfig  `mapstructure:",squash"`
+	Preflight             preflight.Config   `mapstructure:",squash"`
+	GuestIP               guestip.Config     `mapstructure:",squash"`
+	OutputStore           outputstore.Config `mapstructure:"output_store"`
 
 	ISOSkipCache      bool       `mapstructure:"iso_skip_cache"`
 	Accelerator       string     `mapstructure:"accelerator"`
@@ -116,10 +126,14 @@ type Config struct {
 	SSHHostPortMin    uint       `mapstructure:"ssh_host_port_min"`
 	SSHHostPortMax    uint       `mapstructure:"ssh_host_port_max"`
 	UseDefaultDisplay bool       `mapstructure:"use_default_display"`
+	Display           string     `mapstructure:"display"`
 	VNCBindAddress    string     `mapstructure:"vnc_bind_address"`
 	VNCPortMin        uint       `mapstructure:"vnc_port_min"`
 	VNCPortMax        uint       `mapstructure:"vnc_port_max"`
+	VNCUsePassword    bool       `mapstructure:"vnc_use_password"`
+	VNCPassword       string     `mapstructure:"vnc_password"`
 	VMName            string     `mapstructure:"vm_name"`
+	QemuGuestAgent    bool       `mapstructure:"qemu_guest_agent"`
 
 	// These are deprecated, but we keep them around for BC
 	// TODO(@mitchellh): remove
@@ -128,10 +142,12 @@ type Config struct {
 	// TODO(mitchellh): deprecate
 	RunOnce bool `mapstructure:"run_once"`
 
-	RawShutdownTimeout string `mapstructure:"shutdown_timeout"`
+	RawShutdownTimeout   string `mapstructure:"shutdown_timeout"`
+	RawGuestAgentTimeout string `mapstructure:"qemu_guest_agent_timeout"`
 
-	shutdownTimeout time.Duration ``
-	ctx             interpolate.Context
+	shutdownTimeout   time.Duration ``
+	guestAgentTimeout time.Duration ``
+	ctx               interpolate.Context
 }
 
 func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
@@ -168,26 +184,6 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 		b.config.DetectZeroes = "off"
 	}
 
-	if b.config.Accelerator == "" {
-		if runtime.GOOS == "windows" {
-			b.config.Accelerator = "tcg"
-		} else {
-			// /dev/kvm is a kernel module that may be loaded if kvm is
-			// installed and the host supports VT-x extensions. To make sure
-			// this will actually work we need to os.Open() it. If os.Open fails
-			// the kernel module was not installed or loaded correctly.
-			if fp, err := os.Open("/dev/kvm"); err != nil {
-				b.config.Accelerator = "tcg"
-			} else {
-				fp.Close()
-				b.config.Accelerator = "kvm"
-			}
-		}
-		log.Printf("use detected accelerator: %s", b.config.Accelerator)
-	} else {
-		log.Printf("use specified accelerator: %s", b.config.Accelerator)
-	}
-
 	if b.config.MachineType == "" {
 		b.config.MachineType = "pc"
 	}
@@ -200,6 +196,19 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 		b.config.QemuBinary = "qemu-system-x86_64"
 	}
 
+	if b.config.Accelerator == "" {
+		var reason string
+		b.config.Accelerator, reason = hostAccelerator(b.config.QemuBinary)
+		if reason != "" {
+			warnings = append(warnings, fmt.Sprintf("No hardware accelerator can be used, as %s. The VM will\n"+
+				"be emulated with the tcg accelerator, which is much slower. Set accelerator\n"+
+				"to choose one.", reason))
+		}
+		log.Printf("use detected accelerator: %s", b.config.Accelerator)
+	} else {
+		log.Printf("use specified accelerator: %s", b.config.Accelerator)
+	}
+
 	if b.config.SSHHostPortMin == 0 {
 		b.config.SSHHostPortMin = 2222
 	}
@@ -230,6 +239,7 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 
 	errs = packer.MultiErrorAppend(errs, b.config.FloppyConfig.Prepare(&b.config.ctx)...)
 	errs = packer.MultiErrorAppend(errs, b.config.VNCConfig.Prepare(&b.config.ctx)...)
+	errs = packer.MultiErrorAppend(errs, b.config.GuestIP.Prepare(guestip.Tools, guestip.ARP)...)
 
 	if b.config.NetDevice == "" {
 		b.config.NetDevice = "virtio-net"
@@ -253,9 +263,15 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 	errs = packer.MultiErrorAppend(errs, isoErrs...)
 
 	errs = packer.MultiErrorAppend(errs, b.config.HTTPConfig.Prepare(&b.config.ctx)...)
+	errs = packer.MultiErrorAppend(errs, b.config.ZeroFillConfig.Prepare(b.config.Comm.Type)...)
+	errs = packer.MultiErrorAppend(errs, b.config.OutputStore.Prepare()...)
 	if es := b.config.Comm.Prepare(&b.config.ctx); len(es) > 0 {
 		errs = packer.MultiErrorAppend(errs, es...)
 	}
+	errs = packer.MultiErrorAppend(errs, b.config.GuestOSConfig.Prepare()...)
+	if b.config.ShutdownCommand == "" {
+		b.config.ShutdownCommand = b.config.GuestOSConfig.DefaultShutdownCommand(&b.config.Comm)
+	}
 
 	if !(b.config.Format == "qcow2" || b.config.Format == "raw") {
 		errs = packer.MultiErrorAppend(
@@ -274,7 +290,7 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 
 	if _, ok := accels[b.config.Accelerator]; !ok {
 		errs = packer.MultiErrorAppend(
-			errs, errors.New("invalid accelerator, only 'kvm', 'tcg', 'xen', 'hax', 'hvf', or 'none' are allowed"))
+			errs, errors.New("invalid accelerator, only 'kvm', 'tcg', 'xen', 'hax', 'hvf', 'whpx', or 'none' are allowed"))
 	}
 
 	if _, ok := netDevice[b.config.NetDevice]; !ok {
@@ -320,6 +336,22 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 			errs, fmt.Errorf("Failed parsing shutdown_timeout: %s", err))
 	}
 
+	// The guest agent is needed to discover the IP address with it, and as
+	// the communicator
+	if b.config.GuestIP.Uses(guestip.Tools) || b.config.Comm.Type == "qemu-agent" {
+		b.config.QemuGuestAgent = true
+	}
+
+	if b.config.RawGuestAgentTimeout == "" {
+		b.config.RawGuestAgentTimeout = "5m"
+	}
+
+	b.config.guestAgentTimeout, err = time.ParseDuration(b.config.RawGuestAgentTimeout)
+	if err != nil {
+		errs = packer.MultiErrorAppend(
+			errs, fmt.Errorf("Failed parsing qemu_guest_agent_timeout: %s", err))
+	}
+
 	if b.config.SSHHostPortMin > b.config.SSHHostPortMax {
 		errs = packer.MultiErrorAppend(
 			errs, errors.New("ssh_host_port_min must be less than ssh_host_port_max"))
@@ -330,6 +362,16 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 			errs, fmt.Errorf("vnc_port_min must be less than vnc_port_max"))
 	}
 
+	if b.config.VNCPassword != "" {
+		b.config.VNCUsePassword = true
+	}
+
+	// VNC authentication only uses the first 8 characters
+	if len(b.config.VNCPassword) > 8 {
+		errs = packer.MultiErrorAppend(
+			errs, errors.New("vnc_password must be at most 8 characters"))
+	}
+
 	if b.config.QemuArgs == nil {
 		b.config.QemuArgs = make([][]string, 0)
 	}
@@ -342,6 +384,21 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 }
 
 func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packer.Artifact, error) {
+	// Check that the host has what the build needs
+	err := preflight.Run(ui, &b.config.Preflight, &preflight.Checks{
+		DiskDir:  b.config.OutputDir,
+		DiskSize: uint64(b.config.DiskSize),
+		Memory:   b.config.memorySize(),
+		KVM:      b.config.Accelerator == "kvm",
+		Binaries: []preflight.Binary{
+			{Name: b.config.QemuBinary, Hint: "install QEMU, or set qemu_binary to its path"},
+			{Name: "qemu-img", Hint: "install QEMU, or add the directory of qemu-img to the PATH"},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	// Create the driver that we'll use to communicate with Qemu
 	driver, err := b.newDriver(b.config.QemuBinary)
 	if err != nil {
@@ -362,8 +419,10 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		steps = append(steps, &common.StepDownload{
 			Checksum:     b.config.ISOChecksum,
 			ChecksumType: b.config.ISOChecksumType,
+			Size:         b.config.ISOSize,
 			Description:  "ISO",
 			Extension:    b.config.TargetExtension,
+			Extract:      true,
 			ResultKey:    "iso_path",
 			TargetPath:   b.config.TargetPath,
 			Url:          b.config.ISOUrls,
@@ -381,6 +440,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		&common.StepCreateFloppy{
 			Files:       b.config.FloppyConfig.FloppyFiles,
 			Directories: b.config.FloppyConfig.FloppyDirectories,
+			Unattend:    b.config.FloppyConfig.Unattend,
 		},
 		new(stepCreateDisk),
 		new(stepCopyDisk),
@@ -392,7 +452,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		},
 	)
 
-	if b.config.Comm.Type != "none" {
+	if b.config.commForwarded() {
 		steps = append(steps,
 			new(stepForwardSSH),
 		)
@@ -400,7 +460,12 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 
 	steps = append(steps,
 		new(stepConfigureVNC),
+		new(stepConfigureGuestAgent),
 		steprun,
+		&common.StepScreenshotOnFailure{
+			BuildName:  b.config.PackerBuildName,
+			Screenshot: vncScreenshot,
+		},
 		&stepTypeBootCommand{},
 	)
 
@@ -408,10 +473,14 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		steps = append(steps,
 			&communicator.StepConnect{
 				Config:    &b.config.Comm,
-				Host:      commHost,
+				BuildName: b.config.PackerBuildName,
+				Host:      b.config.GuestIP.CommHost(commHost, ipDiscoveryStrategies(&b.config)),
 				SSHConfig: b.config.Comm.SSHConfigFunc(),
-				SSHPort:   commPort,
-				WinRMPort: commPort,
+				SSHPort:   guestCommPort(&b.config.GuestIP, b.config.Comm.Port()),
+				WinRMPort: guestCommPort(&b.config.GuestIP, b.config.Comm.Port()),
+				CustomConnect: map[string]multistep.Step{
+					"qemu-agent": new(stepConnectGuestAgent),
+				},
 			},
 		)
 	}
@@ -421,11 +490,22 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 	)
 
 	steps = append(steps,
+		&common.StepCleanupTempFiles{
+			Comm:    &b.config.Comm,
+			GuestOS: &b.config.GuestOSConfig,
+		},
+		&communicator.StepScramblePassword{
+			Config: &b.config.Comm,
+		},
 		&common.StepCleanupTempKeys{
-			Comm: &b.config.Comm,
+			Comm:    &b.config.Comm,
+			GuestOS: &b.config.GuestOSConfig,
 		},
 	)
 	steps = append(steps,
+		&common.StepZeroFill{
+			Config: &b.config.ZeroFillConfig,
+		},
 		new(stepShutdown),
 	)
 
@@ -488,7 +568,46 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 	artifact.state["diskSize"] = uint64(b.config.DiskSize)
 	artifact.state["domainType"] = b.config.Accelerator
 
-	return artifact, nil
+	return outputstore.Upload(ui, &b.config.OutputStore, common.WithUnverifiedDownloads(artifact, state))
+}
+
+// memorySize is the memory of the VM in megabytes, the one of the -m
+// argument of qemuargs if there is one.
+func (c *Config) memorySize() uint64 {
+	size := "512M"
+	for _, arg := range c.QemuArgs {
+		if len(arg) > 1 && arg[0] == "-m" {
+			size = arg[1]
+		}
+	}
+
+	// Like "2G" or "size=2048,slots=2,maxmem=4G", megabytes by default
+	for _, part := range strings.Split(size, ",") {
+		if strings.HasPrefix(part, "size=") || !strings.Contains(part, "=") {
+			size = strings.TrimPrefix(part, "size=")
+			break
+		}
+	}
+	if size == "" {
+		return 0
+	}
+	kb := uint64(1024)
+	switch strings.ToUpper(size[len(size)-1:]) {
+	case "K":
+		kb = 1
+	case "M":
+	case "G":
+		kb = 1024 * 1024
+	case "T":
+		kb = 1024 * 1024 * 1024
+	default:
+		size += "M"
+	}
+	n, err := strconv.ParseUint(size[:len(size)-1], 10, 64)
+	if err != nil {
+		return 0
+	}
+	return n * kb / 1024
 }
 
 func (b *Builder) Cancel() {
@@ -5,7 +5,9 @@ import (
 	"io/ioutil"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/packer/packer"
 )
@@ -40,6 +42,11 @@ eHLc1nkCgYEApm/motCTPN32nINZ+Vvywbv64ZD+gtpeMNP3CLrbe1X9O+H52AXa
 -----END RSA PRIVATE KEY-----
 `
 
+func init() {
+	// Don't warn about the accelerator of the host in the tests
+	hostAccelerator = func(string) (string, string) { return "kvm", "" }
+}
+
 func testConfig() map[string]interface{} {
 	return map[string]interface{}{
 		"iso_checksum":            "foo",
@@ -94,6 +101,79 @@ func TestBuilderPrepare_Defaults(t *testing.T) {
 	}
 }
 
+func TestBuilderPrepare_Accelerator(t *testing.T) {
+	defer func(f func(string) (string, string)) { hostAccelerator = f }(hostAccelerator)
+	hostAccelerator = func(string) (string, string) { return "tcg", "the host has none" }
+
+	var b Builder
+	config := testConfig()
+	warns, err := b.Prepare(config)
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if len(warns) != 1 || !strings.Contains(warns[0], "the host has none") {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if b.config.Accelerator != "tcg" {
+		t.Fatalf("bad: %s", b.config.Accelerator)
+	}
+
+	// A set accelerator isn't detected
+	b = Builder{}
+	config = testConfig()
+	config["accelerator"] = "whpx"
+	warns, err = b.Prepare(config)
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if b.config.Accelerator != "whpx" {
+		t.Fatalf("bad: %s", b.config.Accelerator)
+	}
+
+	b = Builder{}
+	config = testConfig()
+	config["accelerator"] = "bogus"
+	_, err = b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestBuilderPrepare_GuestOSFamily(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	config["guest_os_family"] = "linux-systemd"
+	config["ssh_username"] = "root"
+	_, err := b.Prepare(config)
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.ShutdownCommand != "systemctl poweroff" {
+		t.Fatalf("bad: %s", b.config.ShutdownCommand)
+	}
+
+	// A set shutdown command is kept
+	b = Builder{}
+	config["shutdown_command"] = "halt -p"
+	_, err = b.Prepare(config)
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.ShutdownCommand != "halt -p" {
+		t.Fatalf("bad: %s", b.config.ShutdownCommand)
+	}
+
+	b = Builder{}
+	config["guest_os_family"] = "bogus"
+	_, err = b.Prepare(config)
+	if err == nil {
+		t.Fatal("should have error")
+	}
+}
+
 func TestBuilderPrepare_VNCBindAddress(t *testing.T) {
 	var b Builder
 	config := testConfig()
@@ -430,6 +510,136 @@ func TestBuilderPrepare_SSHHostPort(t *testing.T) {
 	}
 }
 
+func TestBuilderPrepare_VNCPassword(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Bad
+	config["vnc_password"] = "toolongpw"
+	b = Builder{}
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Good
+	config["vnc_password"] = "secret"
+	b = Builder{}
+	warns, err = b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if !b.config.VNCUsePassword {
+		t.Fatal("should use the password")
+	}
+}
+
+func TestBuilderPrepare_IPDiscovery(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Bad
+	config["ip_discovery"] = []string{"dhcp"}
+	b = Builder{}
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Good
+	config["ip_discovery"] = []string{"tools", "arp"}
+	b = Builder{}
+	warns, err = b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if !b.config.GuestIP.Uses("tools") {
+		t.Fatal("should use the guest agent")
+	}
+}
+
+func TestBuilderPrepare_OutputStore(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Bad
+	config["output_store"] = map[string]interface{}{"url": "ftp://example.com/images"}
+	b = Builder{}
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Good
+	config["output_store"] = map[string]interface{}{
+		"url":        "s3://bucket/images",
+		"keep_local": true,
+	}
+	b = Builder{}
+	warns, err = b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if !b.config.OutputStore.KeepLocal || b.config.OutputStore.PartSize != 64 {
+		t.Fatalf("bad: %#v", b.config.OutputStore)
+	}
+}
+
+func TestBuilderPrepare_QemuGuestAgent(t *testing.T) {
+	var b Builder
+	config := testConfig()
+
+	// Bad
+	config["qemu_guest_agent_timeout"] = "nope"
+	b = Builder{}
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// The communicator uses the guest agent
+	delete(config, "qemu_guest_agent_timeout")
+	config["communicator"] = "qemu-agent"
+	b = Builder{}
+	warns, err = b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if !b.config.QemuGuestAgent {
+		t.Fatal("should use the guest agent")
+	}
+	if b.config.guestAgentTimeout != 5*time.Minute {
+		t.Fatalf("bad: %s", b.config.guestAgentTimeout)
+	}
+	if b.config.commForwarded() {
+		t.Fatal("should not forward a port")
+	}
+}
+
 func TestBuilderPrepare_SSHPrivateKey(t *testing.T) {
 	var b Builder
 	config := testConfig()
@@ -568,3 +778,23 @@ func TestBuilderPrepare_QemuArgs(t *testing.T) {
 		t.Fatalf("bad: %#v", b.config.QemuArgs)
 	}
 }
+
+func TestConfig_memorySize(t *testing.T) {
+	cases := []struct {
+		Args     [][]string
+		Expected uint64
+	}{
+		{nil, 512},
+		{[][]string{{"-m", "1024"}}, 1024},
+		{[][]string{{"-m", "2G"}}, 2048},
+		{[][]string{{"-m", "1048576k"}}, 1024},
+		{[][]string{{"-m", "size=4g,slots=2,maxmem=8G"}}, 4096},
+		{[][]string{{"-m", "nope"}}, 0},
+	}
+	for _, tc := range cases {
+		c := &Config{QemuArgs: tc.Args}
+		if actual := c.memorySize(); actual != tc.Expected {
+			t.Fatalf("%v: bad: %d", tc.Args, actual)
+		}
+	}
+}
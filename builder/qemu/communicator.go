@@ -0,0 +1,191 @@
+package qemu
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"time"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+// guestAgentChunkSize is how much of a file is sent or read by a command of
+// the guest agent, which limits the size of its messages.
+const guestAgentChunkSize = 48 * 1024
+
+// guestAgentComm is the "qemu-agent" communicator, which runs commands and
+// transfers files with the QEMU guest agent instead of over the network.
+// Commands are run by /bin/sh, and their output is only written once they
+// exit.
+type guestAgentComm struct {
+	agent *guestAgent
+}
+
+func (c *guestAgentComm) Start(cmd *packer.RemoteCmd) error {
+	args := map[string]interface{}{
+		"path":           "/bin/sh",
+		"arg":            []string{"-c", cmd.Command},
+		"capture-output": true,
+	}
+	if cmd.Stdin != nil {
+		input, err := ioutil.ReadAll(cmd.Stdin)
+		if err != nil {
+			return err
+		}
+		if len(input) > 0 {
+			args["input-data"] = base64.StdEncoding.EncodeToString(input)
+		}
+	}
+
+	var started struct {
+		PID int `json:"pid"`
+	}
+	if err := c.agent.execute("guest-exec", args, &started); err != nil {
+		return err
+	}
+	log.Printf("[DEBUG] Started process %d in the guest: %s", started.PID, cmd.Command)
+
+	go func() {
+		status, err := c.waitExec(started.PID)
+		if err != nil {
+			log.Printf("[ERROR] Error waiting for process %d in the guest: %s", started.PID, err)
+			cmd.SetExited(packer.CmdDisconnect)
+			return
+		}
+		writeBase64(cmd.Stdout, status.OutData)
+		writeBase64(cmd.Stderr, status.ErrData)
+
+		exitStatus := status.ExitCode
+		if status.Signal != 0 {
+			exitStatus = 128 + status.Signal
+		}
+		cmd.SetExited(exitStatus)
+	}()
+	return nil
+}
+
+type guestExecStatus struct {
+	Exited   bool   `json:"exited"`
+	ExitCode int    `json:"exitcode"`
+	Signal   int    `json:"signal"`
+	OutData  string `json:"out-data"`
+	ErrData  string `json:"err-data"`
+}
+
+// waitExec polls the process started with guest-exec until it exits.
+func (c *guestAgentComm) waitExec(pid int) (*guestExecStatus, error) {
+	for {
+		var status guestExecStatus
+		args := map[string]interface{}{"pid": pid}
+		if err := c.agent.execute("guest-exec-status", args, &status); err != nil {
+			return nil, err
+		}
+		if status.Exited {
+			return &status, nil
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+func writeBase64(w io.Writer, data string) {
+	if w == nil || data == "" {
+		return
+	}
+	decoded, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		log.Printf("[ERROR] Error decoding the output of the guest: %s", err)
+		return
+	}
+	w.Write(decoded)
+}
+
+func (c *guestAgentComm) Upload(path string, input io.Reader, fi *os.FileInfo) error {
+	handle, err := c.openFile(path, "wb")
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, guestAgentChunkSize)
+	for {
+		n, err := input.Read(buf)
+		if n > 0 {
+			args := map[string]interface{}{
+				"handle":  handle,
+				"buf-b64": base64.StdEncoding.EncodeToString(buf[:n]),
+			}
+			if err := c.agent.execute("guest-file-write", args, nil); err != nil {
+				c.closeFile(handle)
+				return err
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			c.closeFile(handle)
+			return err
+		}
+	}
+	return c.closeFile(handle)
+}
+
+func (c *guestAgentComm) UploadDir(dst string, src string, excl []string) error {
+	return errors.New("UploadDir is not implemented when communicator = 'qemu-agent'")
+}
+
+func (c *guestAgentComm) Download(path string, output io.Writer) error {
+	handle, err := c.openFile(path, "rb")
+	if err != nil {
+		return err
+	}
+
+	for {
+		var read struct {
+			Count int    `json:"count"`
+			Data  string `json:"buf-b64"`
+			EOF   bool   `json:"eof"`
+		}
+		args := map[string]interface{}{
+			"handle": handle,
+			"count":  guestAgentChunkSize,
+		}
+		if err := c.agent.execute("guest-file-read", args, &read); err != nil {
+			c.closeFile(handle)
+			return err
+		}
+		data, err := base64.StdEncoding.DecodeString(read.Data)
+		if err != nil {
+			c.closeFile(handle)
+			return fmt.Errorf("Error decoding %s: %s", path, err)
+		}
+		if _, err := output.Write(data); err != nil {
+			c.closeFile(handle)
+			return err
+		}
+		if read.EOF || read.Count == 0 {
+			break
+		}
+	}
+	return c.closeFile(handle)
+}
+
+func (c *guestAgentComm) DownloadDir(src string, dst string, excl []string) error {
+	return errors.New("DownloadDir is not implemented when communicator = 'qemu-agent'")
+}
+
+func (c *guestAgentComm) openFile(path, mode string) (int, error) {
+	var handle int
+	args := map[string]interface{}{"path": path, "mode": mode}
+	if err := c.agent.execute("guest-file-open", args, &handle); err != nil {
+		return 0, fmt.Errorf("Error opening %s in the guest: %s", path, err)
+	}
+	return handle, nil
+}
+
+func (c *guestAgentComm) closeFile(handle int) error {
+	return c.agent.execute("guest-file-close", map[string]interface{}{"handle": handle}, nil)
+}
@@ -0,0 +1,124 @@
+package qemu
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+func TestGuestAgentComm_impl(t *testing.T) {
+	var _ packer.Communicator = new(guestAgentComm)
+}
+
+func TestGuestAgentCommStart(t *testing.T) {
+	var execArgs struct {
+		Path  string   `json:"path"`
+		Arg   []string `json:"arg"`
+		Input string   `json:"input-data"`
+	}
+	polls := 0
+	agent, done := fakeGuestAgent(t, map[string]func(json.RawMessage) string{
+		"guest-exec": func(args json.RawMessage) string {
+			json.Unmarshal(args, &execArgs)
+			return `{"return": {"pid": 42}}`
+		},
+		"guest-exec-status": func(json.RawMessage) string {
+			polls++
+			if polls == 1 {
+				return `{"return": {"exited": false}}`
+			}
+			return fmt.Sprintf(`{"return": {"exited": true, "exitcode": 3, "out-data": %q}}`,
+				base64.StdEncoding.EncodeToString([]byte("hello\n")))
+		},
+	})
+	defer done()
+
+	var stdout bytes.Buffer
+	cmd := &packer.RemoteCmd{
+		Command: "echo hello",
+		Stdin:   strings.NewReader("input"),
+		Stdout:  &stdout,
+	}
+	comm := &guestAgentComm{agent: agent}
+	if err := comm.Start(cmd); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	cmd.Wait()
+
+	if execArgs.Path != "/bin/sh" || len(execArgs.Arg) != 2 || execArgs.Arg[1] != "echo hello" {
+		t.Fatalf("bad: %#v", execArgs)
+	}
+	if execArgs.Input != base64.StdEncoding.EncodeToString([]byte("input")) {
+		t.Fatalf("bad: %s", execArgs.Input)
+	}
+	if cmd.ExitStatus != 3 {
+		t.Fatalf("bad: %d", cmd.ExitStatus)
+	}
+	if stdout.String() != "hello\n" {
+		t.Fatalf("bad: %q", stdout.String())
+	}
+}
+
+func TestGuestAgentCommUploadDownload(t *testing.T) {
+	files := make(map[string]*bytes.Buffer)
+	var open string
+	agent, done := fakeGuestAgent(t, map[string]func(json.RawMessage) string{
+		"guest-file-open": func(args json.RawMessage) string {
+			var a struct {
+				Path string `json:"path"`
+				Mode string `json:"mode"`
+			}
+			json.Unmarshal(args, &a)
+			if a.Mode == "wb" {
+				files[a.Path] = new(bytes.Buffer)
+			} else if _, ok := files[a.Path]; !ok {
+				return `{"error": {"class": "GenericError", "desc": "No such file"}}`
+			}
+			open = a.Path
+			return `{"return": 1000}`
+		},
+		"guest-file-write": func(args json.RawMessage) string {
+			var a struct {
+				Data string `json:"buf-b64"`
+			}
+			json.Unmarshal(args, &a)
+			data, _ := base64.StdEncoding.DecodeString(a.Data)
+			files[open].Write(data)
+			return fmt.Sprintf(`{"return": {"count": %d, "eof": false}}`, len(data))
+		},
+		"guest-file-read": func(args json.RawMessage) string {
+			var a struct {
+				Count int `json:"count"`
+			}
+			json.Unmarshal(args, &a)
+			data := files[open].Next(a.Count)
+			return fmt.Sprintf(`{"return": {"count": %d, "buf-b64": %q, "eof": %t}}`,
+				len(data), base64.StdEncoding.EncodeToString(data), files[open].Len() == 0)
+		},
+		"guest-file-close": answer(`{"return": {}}`),
+	})
+	defer done()
+
+	contents := strings.Repeat("packer", guestAgentChunkSize/3)
+	comm := &guestAgentComm{agent: agent}
+	if err := comm.Upload("/tmp/file", strings.NewReader(contents), nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var downloaded bytes.Buffer
+	if err := comm.Download("/tmp/file", &downloaded); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if downloaded.String() != contents {
+		t.Fatalf("bad: %d bytes", downloaded.Len())
+	}
+
+	if err := comm.Download("/tmp/nope", &downloaded); err == nil {
+		t.Fatal("should have error")
+	}
+}
@@ -0,0 +1,168 @@
+package qemu
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/packer/common/guestip"
+)
+
+// guestAgent runs the commands of the QEMU guest agent, through the
+// virtio-serial port of stepConfigureGuestAgent. See
+// https://qemu.weilnetz.de/doc/qemu-ga-ref.html for the commands.
+type guestAgent struct {
+	path string
+
+	// timeout is how long a command has to answer, the guest agent
+	// doesn't answer at all until it runs in the guest.
+	timeout time.Duration
+
+	// QEMU takes one connection to the socket at a time
+	l sync.Mutex
+}
+
+func newGuestAgent(path string, timeout time.Duration) *guestAgent {
+	return &guestAgent{path: path, timeout: timeout}
+}
+
+// guestAgentError is an error the guest agent answered a command with.
+type guestAgentError struct {
+	Class string `json:"class"`
+	Desc  string `json:"desc"`
+}
+
+func (e *guestAgentError) Error() string {
+	return e.Desc
+}
+
+// execute runs the command with the arguments, and decodes what it returns
+// into result unless it is nil.
+func (a *guestAgent) execute(command string, args interface{}, result interface{}) error {
+	return a.run(command, args, result, true)
+}
+
+// send runs a command that doesn't answer when it works, like
+// guest-shutdown.
+func (a *guestAgent) send(command string, args interface{}) error {
+	return a.run(command, args, nil, false)
+}
+
+func (a *guestAgent) run(command string, args interface{}, result interface{}, answers bool) error {
+	a.l.Lock()
+	defer a.l.Unlock()
+
+	conn, err := net.DialTimeout("unix", a.path, a.timeout)
+	if err != nil {
+		return fmt.Errorf("Error connecting to the guest agent: %s", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(a.timeout))
+	enc := json.NewEncoder(conn)
+	dec := json.NewDecoder(conn)
+
+	// The guest agent may still have the answers of an earlier connection
+	// to send, those before the one to guest-sync are skipped.
+	id := rand.Int31()
+	syncCmd := map[string]interface{}{
+		"execute":   "guest-sync",
+		"arguments": map[string]interface{}{"id": id},
+	}
+	if err := enc.Encode(syncCmd); err != nil {
+		return fmt.Errorf("Error writing to the guest agent: %s", err)
+	}
+	for {
+		var resp struct {
+			Return json.RawMessage `json:"return"`
+		}
+		if err := dec.Decode(&resp); err != nil {
+			return fmt.Errorf("Error reading from the guest agent: %s", err)
+		}
+		if string(resp.Return) == strconv.Itoa(int(id)) {
+			break
+		}
+	}
+
+	req := map[string]interface{}{"execute": command}
+	if args != nil {
+		req["arguments"] = args
+	}
+	if err := enc.Encode(req); err != nil {
+		return fmt.Errorf("Error writing to the guest agent: %s", err)
+	}
+	if !answers {
+		return nil
+	}
+
+	var resp struct {
+		Return json.RawMessage  `json:"return"`
+		Error  *guestAgentError `json:"error"`
+	}
+	if err := dec.Decode(&resp); err != nil {
+		return fmt.Errorf("Error reading from the guest agent: %s", err)
+	}
+	if resp.Error != nil {
+		return fmt.Errorf("Error running %s in the guest agent: %s", command, resp.Error)
+	}
+	if result != nil {
+		if err := json.Unmarshal(resp.Return, result); err != nil {
+			return fmt.Errorf("Error reading the result of %s: %s", command, err)
+		}
+	}
+	return nil
+}
+
+// ping checks that the guest agent runs.
+func (a *guestAgent) ping() error {
+	return a.execute("guest-ping", nil, nil)
+}
+
+// interfaceIP is the IPv4 address of the interface of the guest with the
+// MAC address.
+func (a *guestAgent) interfaceIP(mac string) (string, error) {
+	want, err := guestip.NormalizeMAC(mac)
+	if err != nil {
+		return "", err
+	}
+
+	var interfaces []struct {
+		HardwareAddress string `json:"hardware-address"`
+		IPAddresses     []struct {
+			Type    string `json:"ip-address-type"`
+			Address string `json:"ip-address"`
+		} `json:"ip-addresses"`
+	}
+	if err := a.execute("guest-network-get-interfaces", nil, &interfaces); err != nil {
+		return "", err
+	}
+
+	for _, iface := range interfaces {
+		if hw, err := guestip.NormalizeMAC(iface.HardwareAddress); err != nil || hw != want {
+			continue
+		}
+		for _, addr := range iface.IPAddresses {
+			if addr.Type == "ipv4" {
+				return addr.Address, nil
+			}
+		}
+	}
+	return "", errors.New("the guest agent didn't report an address for the network device yet")
+}
+
+// shutdown powers the guest off, like its own shutdown command.
+func (a *guestAgent) shutdown() error {
+	return a.send("guest-shutdown", map[string]interface{}{"mode": "powerdown"})
+}
+
+// fsFreeze flushes and freezes the file systems of the guest, so its disks
+// are consistent until they are thawed. It returns how many were frozen.
+func (a *guestAgent) fsFreeze() (int, error) {
+	var n int
+	err := a.execute("guest-fsfreeze-freeze", nil, &n)
+	return n, err
+}
@@ -0,0 +1,153 @@
+package qemu
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// fakeGuestAgent answers the commands of the guest agent on a socket with
+// the handlers, after a stale answer like one left from an earlier
+// connection. A handler returning "" doesn't answer.
+func fakeGuestAgent(t *testing.T, handlers map[string]func(args json.RawMessage) string) (*guestAgent, func()) {
+	dir, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	path := filepath.Join(dir, "agent.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			r := bufio.NewReader(conn)
+			fmt.Fprintln(conn, `{"return": 1}`)
+			for {
+				line, err := r.ReadBytes('\n')
+				if err != nil {
+					break
+				}
+				var cmd struct {
+					Execute   string          `json:"execute"`
+					Arguments json.RawMessage `json:"arguments"`
+				}
+				json.Unmarshal(line, &cmd)
+				if cmd.Execute == "guest-sync" {
+					var args struct {
+						ID int `json:"id"`
+					}
+					json.Unmarshal(cmd.Arguments, &args)
+					fmt.Fprintf(conn, `{"return": %d}`+"\n", args.ID)
+					continue
+				}
+
+				handler, ok := handlers[cmd.Execute]
+				if !ok {
+					fmt.Fprintln(conn, `{"error": {"class": "CommandNotFound", "desc": "unknown command"}}`)
+					continue
+				}
+				if resp := handler(cmd.Arguments); resp != "" {
+					fmt.Fprintln(conn, resp)
+				}
+			}
+			conn.Close()
+		}
+	}()
+
+	return newGuestAgent(path, time.Second), func() {
+		l.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func answer(resp string) func(json.RawMessage) string {
+	return func(json.RawMessage) string {
+		return resp
+	}
+}
+
+func TestGuestAgentInterfaceIP(t *testing.T) {
+	agent, done := fakeGuestAgent(t, map[string]func(json.RawMessage) string{
+		"guest-network-get-interfaces": answer(`{"return": [
+			{"name": "lo", "hardware-address": "00:00:00:00:00:00", "ip-addresses": [
+				{"ip-address-type": "ipv4", "ip-address": "127.0.0.1"}]},
+			{"name": "eth0", "hardware-address": "52:54:00:12:34:56", "ip-addresses": [
+				{"ip-address-type": "ipv6", "ip-address": "fe80::5054:ff:fe12:3456"},
+				{"ip-address-type": "ipv4", "ip-address": "10.0.2.15"}]}]}`),
+	})
+	defer done()
+
+	ip, err := agent.interfaceIP("52-54-00-12-34-56")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if ip != "10.0.2.15" {
+		t.Fatalf("bad: %s", ip)
+	}
+}
+
+func TestGuestAgentInterfaceIP_noAddress(t *testing.T) {
+	agent, done := fakeGuestAgent(t, map[string]func(json.RawMessage) string{
+		"guest-network-get-interfaces": answer(`{"return": [
+			{"name": "eth0", "hardware-address": "52:54:00:12:34:56"}]}`),
+	})
+	defer done()
+
+	if _, err := agent.interfaceIP(defaultMAC); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestGuestAgentExecute_error(t *testing.T) {
+	agent, done := fakeGuestAgent(t, nil)
+	defer done()
+
+	if err := agent.ping(); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestGuestAgentShutdown(t *testing.T) {
+	shutdown := make(chan string, 1)
+	agent, done := fakeGuestAgent(t, map[string]func(json.RawMessage) string{
+		"guest-shutdown": func(args json.RawMessage) string {
+			shutdown <- string(args)
+			return ""
+		},
+		"guest-fsfreeze-freeze": answer(`{"return": 2}`),
+	})
+	defer done()
+
+	if err := agent.shutdown(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	select {
+	case args := <-shutdown:
+		if args != `{"mode":"powerdown"}` {
+			t.Fatalf("bad: %s", args)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("should shut down")
+	}
+
+	n, err := agent.fsFreeze()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if n != 2 {
+		t.Fatalf("bad: %d", n)
+	}
+}
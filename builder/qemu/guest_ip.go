@@ -0,0 +1,49 @@
+package qemu
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/hashicorp/packer/common/guestip"
+	"github.com/hashicorp/packer/helper/multistep"
+)
+
+// defaultMAC is the MAC address QEMU gives the first network device without
+// one.
+const defaultMAC = "52:54:00:12:34:56"
+
+var macArgRe = regexp.MustCompile(`(?:^|,)mac=([0-9a-fA-F:]+)`)
+
+// guestMAC is the MAC address of the first network device qemuargs sets one
+// for, or the one QEMU gives by default.
+func (c *Config) guestMAC() string {
+	for _, args := range c.QemuArgs {
+		if len(args) < 2 {
+			continue
+		}
+		switch args[0] {
+		case "-device", "-net", "-nic":
+			for _, arg := range args[1:] {
+				if m := macArgRe.FindStringSubmatch(arg); m != nil {
+					return m[1]
+				}
+			}
+		}
+	}
+	return defaultMAC
+}
+
+// ipDiscoveryStrategies are the ways of finding the IP address of the guest
+// for the ip_discovery option: the QEMU guest agent running in the guest,
+// and the ARP table of the host.
+func ipDiscoveryStrategies(config *Config) map[string]guestip.Strategy {
+	return map[string]guestip.Strategy{
+		guestip.Tools: func(state multistep.StateBag) (string, error) {
+			agent := newGuestAgent(state.Get("qemu_agent_path").(string), 5*time.Second)
+			return agent.interfaceIP(config.guestMAC())
+		},
+		guestip.ARP: func(multistep.StateBag) (string, error) {
+			return guestip.ARPLookup(config.guestMAC())
+		},
+	}
+}
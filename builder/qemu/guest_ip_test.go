@@ -0,0 +1,29 @@
+package qemu
+
+import (
+	"testing"
+)
+
+func TestConfigGuestMAC(t *testing.T) {
+	cases := []struct {
+		Args     [][]string
+		Expected string
+	}{
+		{nil, defaultMAC},
+		{[][]string{{"-m", "1024"}}, defaultMAC},
+		{
+			[][]string{{"-device", "virtio-net,netdev=net0,mac=52:54:00:aa:bb:cc"}},
+			"52:54:00:aa:bb:cc",
+		},
+		{
+			[][]string{{"-net", "nic,mac=52:54:00:11:22:33,model=e1000"}},
+			"52:54:00:11:22:33",
+		},
+	}
+	for i, tc := range cases {
+		c := &Config{QemuArgs: tc.Args}
+		if actual := c.guestMAC(); actual != tc.Expected {
+			t.Fatalf("%d: bad: %s", i, actual)
+		}
+	}
+}
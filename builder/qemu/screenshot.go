@@ -0,0 +1,25 @@
+package qemu
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/mitchellh/go-vnc"
+)
+
+// vncScreenshot saves the screen of the VM to path over VNC, for
+// common.StepScreenshotOnFailure.
+func vncScreenshot(state multistep.StateBag, path string) error {
+	vncIP := state.Get("vnc_ip").(string)
+	vncPort := state.Get("vnc_port").(uint)
+
+	var auth []vnc.ClientAuth
+	if vncPassword, ok := state.GetOk("vnc_password"); ok {
+		auth = []vnc.ClientAuth{&vnc.PasswordAuth{Password: vncPassword.(string)}}
+	}
+
+	address := fmt.Sprintf("%s:%d", vncIP, vncPort)
+	return common.VNCScreenshot(address, auth, path, 30*time.Second)
+}
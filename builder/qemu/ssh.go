@@ -1,9 +1,17 @@
 package qemu
 
 import (
+	"github.com/hashicorp/packer/common/guestip"
 	"github.com/hashicorp/packer/helper/multistep"
 )
 
+// commForwarded says whether the communicator connects through a port
+// forwarded from the host, which all but the "none" and "qemu-agent" ones
+// do.
+func (c *Config) commForwarded() bool {
+	return c.Comm.Type != "none" && c.Comm.Type != "qemu-agent"
+}
+
 func commHost(state multistep.StateBag) (string, error) {
 	return "127.0.0.1", nil
 }
@@ -12,3 +20,14 @@ func commPort(state multistep.StateBag) (int, error) {
 	sshHostPort := state.Get("sshHostPort").(uint)
 	return int(sshHostPort), nil
 }
+
+// guestCommPort is the port of the communicator: the forwarded one on the
+// host, or the one of the guest when its IP address is discovered.
+func guestCommPort(config *guestip.Config, port int) func(multistep.StateBag) (int, error) {
+	if !config.Enabled() {
+		return commPort
+	}
+	return func(multistep.StateBag) (int, error) {
+		return port, nil
+	}
+}
@@ -0,0 +1,53 @@
+package qemu
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// This step makes the socket of the QEMU guest agent, when it is used.
+//
+// Uses:
+//   config *config
+//   ui     packer.Ui
+//
+// Produces:
+//   qemu_agent_path string - The socket of the guest agent.
+type stepConfigureGuestAgent struct {
+	dir string
+}
+
+func (s *stepConfigureGuestAgent) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+
+	if !config.QemuGuestAgent {
+		return multistep.ActionContinue
+	}
+
+	// The socket is in its own directory since the path of unix sockets is
+	// limited in length, which the output directory may not be.
+	dir, err := ioutil.TempDir("", "packer-qemu")
+	if err != nil {
+		err := fmt.Errorf("Error creating the guest agent directory: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	s.dir = dir
+	state.Put("qemu_agent_path", filepath.Join(dir, "agent.sock"))
+
+	return multistep.ActionContinue
+}
+
+func (s *stepConfigureGuestAgent) Cleanup(multistep.StateBag) {
+	if s.dir != "" {
+		os.RemoveAll(s.dir)
+	}
+}
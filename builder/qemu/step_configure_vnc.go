@@ -3,9 +3,12 @@ package qemu
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"math/rand"
 	"net"
+	"os"
+	"path/filepath"
 
 	"github.com/hashicorp/packer/helper/multistep"
 	"github.com/hashicorp/packer/packer"
@@ -19,9 +22,14 @@ import (
 //
 // Produces:
 //   vnc_port uint - The port that VNC is configured to listen on.
-type stepConfigureVNC struct{}
+//   vnc_password string - The password of VNC, when it uses one.
+//   qemu_monitor_path string - The socket of the monitor to set the
+//     password with, when VNC uses one.
+type stepConfigureVNC struct {
+	monitorDir string
+}
 
-func (stepConfigureVNC) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+func (s *stepConfigureVNC) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
 	config := state.Get("config").(*Config)
 	ui := state.Get("ui").(packer.Ui)
 
@@ -52,7 +60,33 @@ func (stepConfigureVNC) Run(_ context.Context, state multistep.StateBag) multist
 	state.Put("vnc_port", vncPort)
 	state.Put("vnc_ip", config.VNCBindAddress)
 
+	if config.VNCUsePassword {
+		password := config.VNCPassword
+		if password == "" {
+			password = randomVNCPassword()
+		}
+
+		// The socket is in its own directory since the path of unix
+		// sockets is limited in length, which the output directory may
+		// not be.
+		dir, err := ioutil.TempDir("", "packer-qemu")
+		if err != nil {
+			err := fmt.Errorf("Error creating the QEMU monitor directory: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+		s.monitorDir = dir
+
+		state.Put("vnc_password", password)
+		state.Put("qemu_monitor_path", filepath.Join(dir, "monitor.sock"))
+	}
+
 	return multistep.ActionContinue
 }
 
-func (stepConfigureVNC) Cleanup(multistep.StateBag) {}
+func (s *stepConfigureVNC) Cleanup(multistep.StateBag) {
+	if s.monitorDir != "" {
+		os.RemoveAll(s.monitorDir)
+	}
+}
@@ -0,0 +1,63 @@
+package qemu
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// This step waits for the QEMU guest agent to run in the guest, and uses it
+// as the communicator. It is the connect step of the "qemu-agent"
+// communicator.
+//
+// Uses:
+//   config          *config
+//   qemu_agent_path string
+//   ui              packer.Ui
+//
+// Produces:
+//   communicator packer.Communicator
+type stepConnectGuestAgent struct{}
+
+func (s *stepConnectGuestAgent) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	ui := state.Get("ui").(packer.Ui)
+	agent := newGuestAgent(state.Get("qemu_agent_path").(string), 5*time.Second)
+
+	ui.Say("Waiting for the QEMU guest agent to become available...")
+	log.Printf("[INFO] Waiting for the guest agent, up to timeout: %s", config.guestAgentTimeout)
+	timeout := time.After(config.guestAgentTimeout)
+	for {
+		err := agent.ping()
+		if err == nil {
+			break
+		}
+		log.Printf("[DEBUG] The guest agent isn't available yet: %s", err)
+
+		select {
+		case <-timeout:
+			err := fmt.Errorf("Timeout waiting for the QEMU guest agent.")
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		case <-ctx.Done():
+			log.Println("[WARN] Interrupt detected, quitting waiting for the guest agent.")
+			return multistep.ActionHalt
+		case <-time.After(time.Second):
+			if _, ok := state.GetOk(multistep.StateCancelled); ok {
+				log.Println("[WARN] Interrupt detected, quitting waiting for the guest agent.")
+				return multistep.ActionHalt
+			}
+		}
+	}
+
+	ui.Say("Connected to the QEMU guest agent!")
+	state.Put("communicator", &guestAgentComm{agent: newGuestAgent(agent.path, time.Minute)})
+	return multistep.ActionContinue
+}
+
+func (s *stepConnectGuestAgent) Cleanup(multistep.StateBag) {}
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/packer/helper/multistep"
 	"github.com/hashicorp/packer/packer"
@@ -47,6 +48,15 @@ func (s *stepRun) Run(_ context.Context, state multistep.StateBag) multistep.Ste
 		return multistep.ActionHalt
 	}
 
+	if monitorPath, ok := state.GetOk("qemu_monitor_path"); ok {
+		password := state.Get("vnc_password").(string)
+		if err := setVNCPassword(monitorPath.(string), password, 30*time.Second); err != nil {
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
 	return multistep.ActionContinue
 }
 
@@ -78,7 +88,7 @@ func getCommandArgs(bootDrive string, state multistep.StateBag) ([]string, error
 
 	defaultArgs["-name"] = vmName
 	defaultArgs["-machine"] = fmt.Sprintf("type=%s", config.MachineType)
-	if config.Comm.Type != "none" {
+	if config.commForwarded() {
 		sshHostPort = state.Get("sshHostPort").(uint)
 		defaultArgs["-netdev"] = fmt.Sprintf("user,id=user.0,hostfwd=tcp::%v-:%d", sshHostPort, config.Comm.Port())
 	} else {
@@ -123,10 +133,14 @@ func getCommandArgs(bootDrive string, state multistep.StateBag) ([]string, error
 			vncIp := vncIpRaw.(string)
 			vncPort := vncPortRaw.(uint)
 
+			auth := "without a password"
+			if vncPassword, ok := state.GetOk("vnc_password"); ok {
+				auth = fmt.Sprintf("with the password \"%s\"", vncPassword)
+			}
 			ui.Message(fmt.Sprintf(
 				"The VM will be run headless, without a GUI. If you want to\n"+
-					"view the screen of the VM, connect via VNC without a password to\n"+
-					"vnc://%s:%d", vncIp, vncPort))
+					"view the screen of the VM, connect via VNC %s to\n"+
+					"vnc://%s:%d", auth, vncIp, vncPort))
 		} else {
 			ui.Message("The VM will be run headless, without a GUI, as configured.\n" +
 				"If the run isn't succeeding as you expect, please enable the GUI\n" +
@@ -135,7 +149,11 @@ func getCommandArgs(bootDrive string, state multistep.StateBag) ([]string, error
 	} else {
 		if qemuMajor >= 2 {
 			if !config.UseDefaultDisplay {
-				defaultArgs["-display"] = "sdl"
+				display := config.Display
+				if display == "" {
+					display = defaultDisplay()
+				}
+				defaultArgs["-display"] = display
 			}
 		} else {
 			ui.Message("WARNING: The version of qemu  on your host doesn't support display mode.\n" +
@@ -153,9 +171,19 @@ func getCommandArgs(bootDrive string, state multistep.StateBag) ([]string, error
 	defaultArgs["-m"] = "512M"
 	defaultArgs["-vnc"] = vnc
 
+	// The password of VNC is set through the monitor once QEMU runs
+	if monitorPath, ok := state.GetOk("qemu_monitor_path"); ok {
+		defaultArgs["-vnc"] = vnc + ",password"
+		defaultArgs["-monitor"] = fmt.Sprintf("unix:%s,server,nowait", monitorPath)
+	}
+
 	// Append the accelerator to the machine type if it is specified
 	if config.Accelerator != "none" {
 		defaultArgs["-machine"] = fmt.Sprintf("%s,accel=%s", defaultArgs["-machine"], config.Accelerator)
+		// The interrupt controller of WHPX doesn't work with most guests
+		if config.Accelerator == "whpx" {
+			defaultArgs["-machine"] = fmt.Sprintf("%s,kernel-irqchip=off", defaultArgs["-machine"])
+		}
 	} else {
 		ui.Message("WARNING: The VM will be started with no hardware acceleration.\n" +
 			"The installation may take considerably longer to finish.\n")
@@ -174,7 +202,7 @@ func getCommandArgs(bootDrive string, state multistep.StateBag) ([]string, error
 
 		httpPort := state.Get("http_port").(uint)
 		ctx := config.ctx
-		if config.Comm.Type != "none" {
+		if config.commForwarded() {
 			ctx.Data = qemuArgsTemplateData{
 				"10.0.2.2",
 				httpPort,
@@ -233,6 +261,14 @@ func getCommandArgs(bootDrive string, state multistep.StateBag) ([]string, error
 		}
 	}
 
+	// The guest agent is added even with devices in qemuargs
+	if agentPath, ok := state.GetOk("qemu_agent_path"); ok {
+		inArgs["-chardev"] = append(inArgs["-chardev"],
+			fmt.Sprintf("socket,path=%s,server,nowait,id=qga0", agentPath))
+		inArgs["-device"] = append(inArgs["-device"],
+			"virtio-serial", "virtserialport,chardev=qga0,name=org.qemu.guest_agent.0")
+	}
+
 	// Flatten to array of strings
 	outArgs := make([]string, 0)
 	for key, values := range inArgs {
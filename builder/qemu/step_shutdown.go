@@ -12,12 +12,14 @@ import (
 )
 
 // This step shuts down the machine. It first attempts to do so gracefully,
-// but ultimately forcefully shuts it down if that fails.
+// with the shutdown command or the guest agent, but ultimately forcefully
+// shuts it down if that fails.
 //
 // Uses:
 //   communicator packer.Communicator
 //   config *config
 //   driver Driver
+//   qemu_agent_path string
 //   ui     packer.Ui
 //
 // Produces:
@@ -73,8 +75,17 @@ func (s *stepShutdown) Run(_ context.Context, state multistep.StateBag) multiste
 			ui.Error(err.Error())
 			return multistep.ActionHalt
 		}
-	} else {
+	} else if !s.agentShutdown(state) {
 		ui.Say("Halting the virtual machine...")
+		if agentPath, ok := state.GetOk("qemu_agent_path"); ok {
+			// The disk is consistent like after a shutdown
+			agent := newGuestAgent(agentPath.(string), 30*time.Second)
+			if n, err := agent.fsFreeze(); err != nil {
+				log.Printf("[WARN] Error freezing the file systems of the guest: %s", err)
+			} else {
+				log.Printf("Froze %d file systems of the guest", n)
+			}
+		}
 		if err := driver.Stop(); err != nil {
 			err := fmt.Errorf("Error stopping VM: %s", err)
 			state.Put("error", err)
@@ -88,3 +99,36 @@ func (s *stepShutdown) Run(_ context.Context, state multistep.StateBag) multiste
 }
 
 func (s *stepShutdown) Cleanup(state multistep.StateBag) {}
+
+// agentShutdown shuts the machine down with the guest agent, when there is
+// one, and says whether it did.
+func (s *stepShutdown) agentShutdown(state multistep.StateBag) bool {
+	config := state.Get("config").(*Config)
+	driver := state.Get("driver").(Driver)
+	ui := state.Get("ui").(packer.Ui)
+
+	agentPath, ok := state.GetOk("qemu_agent_path")
+	if !ok {
+		return false
+	}
+
+	ui.Say("Gracefully halting virtual machine with the guest agent...")
+	agent := newGuestAgent(agentPath.(string), 30*time.Second)
+	if err := agent.shutdown(); err != nil {
+		log.Printf("[WARN] Error shutting down with the guest agent: %s", err)
+		return false
+	}
+
+	cancelCh := make(chan struct{}, 1)
+	go func() {
+		defer close(cancelCh)
+		<-time.After(config.shutdownTimeout)
+	}()
+
+	log.Printf("Waiting max %s for shutdown to complete", config.shutdownTimeout)
+	if ok := driver.WaitForShutdown(cancelCh); !ok {
+		log.Printf("[WARN] Timeout while waiting for the guest agent to shut down the machine")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,81 @@
+package qemu
+
+import (
+	"bufio"
+	"fmt"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+)
+
+// monitorPrompt is what the human monitor of QEMU writes when it waits for
+// a command.
+const monitorPrompt = "(qemu) "
+
+// randomVNCPassword returns a password of the 8 characters VNC
+// authentication uses.
+func randomVNCPassword() string {
+	charSet := []byte("0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
+	password := make([]byte, 8)
+	for i := range password {
+		password[i] = charSet[rand.Intn(len(charSet))]
+	}
+	return string(password)
+}
+
+// setVNCPassword sets the password of the VNC server through the monitor
+// socket of QEMU. QEMU doesn't take the password on the command line, and
+// refuses VNC clients until it is set. The socket is retried until the
+// timeout while QEMU starts.
+func setVNCPassword(socketPath, password string, timeout time.Duration) error {
+	var conn net.Conn
+	var err error
+	deadline := time.Now().Add(timeout)
+	for {
+		conn, err = net.Dial("unix", socketPath)
+		if err == nil || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if err != nil {
+		return fmt.Errorf("Error connecting to the QEMU monitor: %s", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(deadline.Add(10 * time.Second))
+
+	r := bufio.NewReader(conn)
+	if _, err := readMonitor(r); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(conn, "set_password vnc %s\n", password); err != nil {
+		return fmt.Errorf("Error writing to the QEMU monitor: %s", err)
+	}
+	out, err := readMonitor(r)
+	if err != nil {
+		return err
+	}
+
+	// The monitor echoes the command, anything else is an error.
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.Contains(line, "set_password") {
+			return fmt.Errorf("Error setting the VNC password: %s", line)
+		}
+	}
+	return nil
+}
+
+// readMonitor reads the output of the monitor up to the next prompt.
+func readMonitor(r *bufio.Reader) (string, error) {
+	var out strings.Builder
+	for !strings.HasSuffix(out.String(), monitorPrompt) {
+		b, err := r.ReadByte()
+		if err != nil {
+			return "", fmt.Errorf("Error reading from the QEMU monitor: %s", err)
+		}
+		out.WriteByte(b)
+	}
+	return strings.TrimSuffix(out.String(), monitorPrompt), nil
+}
@@ -0,0 +1,85 @@
+package qemu
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testMonitor is a human monitor of QEMU on a unix socket, which answers
+// the commands with the output of reply.
+func testMonitor(t *testing.T, reply func(string) string) (string, <-chan string, func()) {
+	dir, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	path := filepath.Join(dir, "monitor.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	commands := make(chan string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte("QEMU 2.11.1 monitor - type 'help' for more information\r\n" + monitorPrompt))
+		line, err := bufio.NewReader(conn).ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimSpace(line)
+		commands <- line
+		conn.Write([]byte(line + "\r\n" + reply(line) + monitorPrompt))
+	}()
+
+	return path, commands, func() {
+		l.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestRandomVNCPassword(t *testing.T) {
+	if password := randomVNCPassword(); len(password) != 8 {
+		t.Fatalf("bad: %q", password)
+	}
+}
+
+func TestSetVNCPassword(t *testing.T) {
+	path, commands, cleanup := testMonitor(t, func(string) string { return "" })
+	defer cleanup()
+
+	if err := setVNCPassword(path, "secret", time.Second); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if command := <-commands; command != "set_password vnc secret" {
+		t.Fatalf("bad: %q", command)
+	}
+}
+
+func TestSetVNCPassword_error(t *testing.T) {
+	path, _, cleanup := testMonitor(t, func(string) string {
+		return "Could not set password\r\n"
+	})
+	defer cleanup()
+
+	err := setVNCPassword(path, "secret", time.Second)
+	if err == nil || !strings.Contains(err.Error(), "Could not set password") {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+func TestSetVNCPassword_noMonitor(t *testing.T) {
+	path := filepath.Join(os.TempDir(), "packer-nope", "monitor.sock")
+	if err := setVNCPassword(path, "secret", 200*time.Millisecond); err == nil {
+		t.Fatal("should error")
+	}
+}
@@ -16,8 +16,9 @@ import (
 )
 
 type Config struct {
-	common.PackerConfig `mapstructure:",squash"`
-	Comm                communicator.Config `mapstructure:",squash"`
+	common.PackerConfig    `mapstructure:",squash"`
+	common.CloudInitConfig `mapstructure:",squash"`
+	Comm                   communicator.Config `mapstructure:",squash"`
 
 	Token        string `mapstructure:"api_token"`
 	Organization string `mapstructure:"api_access_key"`
@@ -95,6 +96,7 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 	if es := c.Comm.Prepare(&c.ctx); len(es) > 0 {
 		errs = packer.MultiErrorAppend(errs, es...)
 	}
+	errs = packer.MultiErrorAppend(errs, c.CloudInitConfig.Prepare()...)
 	if c.Organization == "" {
 		errs = packer.MultiErrorAppend(
 			errs, errors.New("Scaleway Organization ID must be specified"))
@@ -13,6 +13,11 @@ type Artifact struct {
 	// BuilderIDValue is the unique ID for the builder that created this Image
 	BuilderIDValue string
 
+	// ExportImage and ExportManifest are the Manta URLs of the image file
+	// and of its manifest, when the image was exported
+	ExportImage    string
+	ExportManifest string
+
 	// SDC connection for cleanup etc
 	Driver Driver
 }
@@ -30,11 +35,20 @@ func (a *Artifact) Id() string {
 }
 
 func (a *Artifact) String() string {
+	if a.ExportImage != "" {
+		return fmt.Sprintf("Image was created: %s, exported to: %s", a.ImageID, a.ExportImage)
+	}
 	return fmt.Sprintf("Image was created: %s", a.ImageID)
 }
 
 func (a *Artifact) State(name string) interface{} {
 	//TODO(jen20): Figure out how to make this work with Atlas
+	switch name {
+	case "manta_image":
+		return a.ExportImage
+	case "manta_manifest":
+		return a.ExportManifest
+	}
 	return nil
 }
 
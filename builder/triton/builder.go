@@ -34,6 +34,7 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 	errs = multierror.Append(errs, b.config.AccessConfig.Prepare(&b.config.ctx)...)
 	errs = multierror.Append(errs, b.config.SourceMachineConfig.Prepare(&b.config.ctx)...)
 	errs = multierror.Append(errs, b.config.Comm.Prepare(&b.config.ctx)...)
+	errs = multierror.Append(errs, b.config.CloudInitConfig.Prepare()...)
 	errs = multierror.Append(errs, b.config.TargetImageConfig.Prepare(&b.config.ctx)...)
 
 	// If we are using an SSH agent to sign requests, and no private key has been
@@ -62,17 +63,24 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 
 	steps := []multistep.Step{
 		&StepCreateSourceMachine{},
+		&StepCreateFirewallRules{},
 		&communicator.StepConnect{
 			Config:    &config.Comm,
+			BuildName: b.config.PackerBuildName,
 			Host:      commHost,
 			SSHConfig: b.config.Comm.SSHConfigFunc(),
 		},
+		&common.StepWaitCloudInit{
+			Config: &b.config.CloudInitConfig,
+			Comm:   &config.Comm,
+		},
 		&common.StepProvision{},
 		&common.StepCleanupTempKeys{
 			Comm: &config.Comm,
 		},
 		&StepStopMachine{},
 		&StepCreateImageFromMachine{},
+		&StepExportImage{},
 		&StepDeleteMachine{},
 	}
 
@@ -94,6 +102,10 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		BuilderIDValue: BuilderId,
 		Driver:         driver,
 	}
+	if exportImage, ok := state.GetOk("export_image"); ok {
+		artifact.ExportImage = exportImage.(string)
+		artifact.ExportManifest = state.Get("export_manifest").(string)
+	}
 
 	return artifact, nil
 }
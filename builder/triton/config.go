@@ -7,10 +7,11 @@ import (
 )
 
 type Config struct {
-	common.PackerConfig `mapstructure:",squash"`
-	AccessConfig        `mapstructure:",squash"`
-	SourceMachineConfig `mapstructure:",squash"`
-	TargetImageConfig   `mapstructure:",squash"`
+	common.PackerConfig    `mapstructure:",squash"`
+	common.CloudInitConfig `mapstructure:",squash"`
+	AccessConfig           `mapstructure:",squash"`
+	SourceMachineConfig    `mapstructure:",squash"`
+	TargetImageConfig      `mapstructure:",squash"`
 
 	Comm communicator.Config `mapstructure:",squash"`
 
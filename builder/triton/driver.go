@@ -8,8 +8,12 @@ type Driver interface {
 	GetImage(config Config) (string, error)
 	CreateImageFromMachine(machineId string, config Config) (string, error)
 	CreateMachine(config Config) (string, error)
+	CreateFirewallRule(rule string, description string) (string, error)
+	DeleteFirewallRule(ruleId string) error
 	DeleteImage(imageId string) error
 	DeleteMachine(machineId string) error
+	ExportImage(imageId string, mantaPath string) (string, string, error)
+	GetNetworkSubnet(networkId string) (string, error)
 	GetMachineIP(machineId string) (string, error)
 	StopMachine(machineId string) error
 	WaitForImageCreation(imageId string, timeout time.Duration) error
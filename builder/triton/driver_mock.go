@@ -1,6 +1,7 @@
 package triton
 
 import (
+	"fmt"
 	"time"
 )
 
@@ -11,17 +12,28 @@ type DriverMock struct {
 	CreateMachineId  string
 	CreateMachineErr error
 
+	CreateFirewallRules   []string
+	CreateFirewallRuleErr error
+
+	DeleteFirewallRuleIds []string
+	DeleteFirewallRuleErr error
+
 	DeleteImageId  string
 	DeleteImageErr error
 
 	DeleteMachineId  string
 	DeleteMachineErr error
 
+	ExportImagePath string
+	ExportImageErr  error
+
 	GetImageId  string
 	GetImageErr error
 
 	GetMachineErr error
 
+	GetNetworkSubnetErr error
+
 	StopMachineId  string
 	StopMachineErr error
 
@@ -60,6 +72,26 @@ func (d *DriverMock) CreateMachine(config Config) (string, error) {
 	return d.CreateMachineId, nil
 }
 
+func (d *DriverMock) CreateFirewallRule(rule string, description string) (string, error) {
+	if d.CreateFirewallRuleErr != nil {
+		return "", d.CreateFirewallRuleErr
+	}
+
+	d.CreateFirewallRules = append(d.CreateFirewallRules, rule)
+
+	return fmt.Sprintf("rule-%d", len(d.CreateFirewallRules)), nil
+}
+
+func (d *DriverMock) DeleteFirewallRule(ruleId string) error {
+	if d.DeleteFirewallRuleErr != nil {
+		return d.DeleteFirewallRuleErr
+	}
+
+	d.DeleteFirewallRuleIds = append(d.DeleteFirewallRuleIds, ruleId)
+
+	return nil
+}
+
 func (d *DriverMock) DeleteImage(imageId string) error {
 	if d.DeleteImageErr != nil {
 		return d.DeleteImageErr
@@ -80,6 +112,24 @@ func (d *DriverMock) DeleteMachine(machineId string) error {
 	return nil
 }
 
+func (d *DriverMock) ExportImage(imageId string, mantaPath string) (string, string, error) {
+	if d.ExportImageErr != nil {
+		return "", "", d.ExportImageErr
+	}
+
+	d.ExportImagePath = mantaPath
+
+	return mantaPath + "/image.zfs.gz", mantaPath + "/image.imgmanifest", nil
+}
+
+func (d *DriverMock) GetNetworkSubnet(networkId string) (string, error) {
+	if d.GetNetworkSubnetErr != nil {
+		return "", d.GetNetworkSubnetErr
+	}
+
+	return "10.0.0.0/24", nil
+}
+
 func (d *DriverMock) GetMachineIP(machineId string) (string, error) {
 	if d.GetMachineErr != nil {
 		return "", d.GetMachineErr
@@ -10,6 +10,7 @@ import (
 	"github.com/hashicorp/packer/packer"
 	"github.com/joyent/triton-go/compute"
 	terrors "github.com/joyent/triton-go/errors"
+	"github.com/joyent/triton-go/network"
 )
 
 type driverTriton struct {
@@ -108,6 +109,63 @@ func (d *driverTriton) CreateMachine(config Config) (string, error) {
 	return machine.ID, nil
 }
 
+func (d *driverTriton) CreateFirewallRule(rule string, description string) (string, error) {
+	networkClient, err := d.client.Network()
+	if err != nil {
+		return "", err
+	}
+	fwRule, err := networkClient.Firewall().CreateRule(context.Background(), &network.CreateRuleInput{
+		Enabled:     true,
+		Rule:        rule,
+		Description: description,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return fwRule.ID, nil
+}
+
+func (d *driverTriton) DeleteFirewallRule(ruleId string) error {
+	networkClient, err := d.client.Network()
+	if err != nil {
+		return err
+	}
+	return networkClient.Firewall().DeleteRule(context.Background(), &network.DeleteRuleInput{
+		ID: ruleId,
+	})
+}
+
+// ExportImage exports the image to the directory of Manta, and returns the
+// URLs of the image file and of its manifest.
+func (d *driverTriton) ExportImage(imageId string, mantaPath string) (string, string, error) {
+	computeClient, _ := d.client.Compute()
+	location, err := computeClient.Images().Export(context.Background(), &compute.ExportImageInput{
+		ImageID:   imageId,
+		MantaPath: mantaPath,
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	return location.MantaURL + location.ImagePath, location.MantaURL + location.ManifestPath, nil
+}
+
+func (d *driverTriton) GetNetworkSubnet(networkId string) (string, error) {
+	networkClient, err := d.client.Network()
+	if err != nil {
+		return "", err
+	}
+	result, err := networkClient.Get(context.Background(), &network.GetInput{
+		ID: networkId,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return result.Subnet, nil
+}
+
 func (d *driverTriton) DeleteImage(imageId string) error {
 	computeClient, _ := d.client.Compute()
 	return computeClient.Images().Delete(context.Background(), &compute.DeleteImageInput{
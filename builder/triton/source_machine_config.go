@@ -2,6 +2,7 @@ package triton
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/hashicorp/packer/template/interpolate"
 )
@@ -13,12 +14,24 @@ type SourceMachineConfig struct {
 	MachinePackage         string             `mapstructure:"source_machine_package"`
 	MachineImage           string             `mapstructure:"source_machine_image"`
 	MachineNetworks        []string           `mapstructure:"source_machine_networks"`
+	MachineNICs            []MachineNIC       `mapstructure:"source_machine_nics"`
 	MachineMetadata        map[string]string  `mapstructure:"source_machine_metadata"`
 	MachineTags            map[string]string  `mapstructure:"source_machine_tags"`
 	MachineFirewallEnabled bool               `mapstructure:"source_machine_firewall_enabled"`
 	MachineImageFilters    MachineImageFilter `mapstructure:"source_machine_image_filter"`
 }
 
+// MachineNIC is a network the source machine is attached to, with the
+// firewall rules of the traffic coming from its subnet.
+type MachineNIC struct {
+	Network string `mapstructure:"network"`
+
+	// FirewallRules are the actions of the rules, like "ALLOW tcp PORT 22".
+	// They are applied to the traffic from the subnet of the network to the
+	// source machine.
+	FirewallRules []string `mapstructure:"firewall_rules"`
+}
+
 type MachineImageFilter struct {
 	MostRecent bool `mapstructure:"most_recent"`
 	Name       string
@@ -46,6 +59,30 @@ func (c *SourceMachineConfig) Prepare(ctx *interpolate.Context) []error {
 		errs = append(errs, fmt.Errorf("You cannot specify a Machine Image and also Machine Name filter"))
 	}
 
+	if len(c.MachineNICs) > 0 {
+		if len(c.MachineNetworks) > 0 {
+			errs = append(errs, fmt.Errorf("Only one of source_machine_networks or source_machine_nics can be specified"))
+		}
+
+		c.MachineNetworks = make([]string, 0, len(c.MachineNICs))
+		for i, nic := range c.MachineNICs {
+			if nic.Network == "" {
+				errs = append(errs, fmt.Errorf("A network must be specified for source_machine_nics[%d]", i))
+			}
+			for _, rule := range nic.FirewallRules {
+				action := strings.ToUpper(strings.SplitN(strings.TrimSpace(rule), " ", 2)[0])
+				if action != "ALLOW" && action != "BLOCK" {
+					errs = append(errs, fmt.Errorf("The firewall rule %q of source_machine_nics[%d] must start with ALLOW or BLOCK", rule, i))
+				}
+			}
+			if len(nic.FirewallRules) > 0 {
+				// The rules are only applied when the firewall is enabled
+				c.MachineFirewallEnabled = true
+			}
+			c.MachineNetworks = append(c.MachineNetworks, nic.Network)
+		}
+	}
+
 	if c.MachineNetworks == nil {
 		c.MachineNetworks = []string{}
 	}
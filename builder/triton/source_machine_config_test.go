@@ -1,6 +1,7 @@
 package triton
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -26,6 +27,47 @@ func TestSourceMachineConfig_Prepare(t *testing.T) {
 	}
 }
 
+func TestSourceMachineConfig_PrepareNICs(t *testing.T) {
+	sc := testSourceMachineConfig(t)
+	sc.MachineNetworks = nil
+	sc.MachineNICs = []MachineNIC{
+		{Network: "test-network-1"},
+		{Network: "test-network-2", FirewallRules: []string{"ALLOW tcp PORT 22"}},
+	}
+	errs := sc.Prepare(nil)
+	if errs != nil {
+		t.Fatalf("should not error: %#v", errs)
+	}
+	expected := []string{"test-network-1", "test-network-2"}
+	if !reflect.DeepEqual(sc.MachineNetworks, expected) {
+		t.Fatalf("bad: %#v", sc.MachineNetworks)
+	}
+	if !sc.MachineFirewallEnabled {
+		t.Fatal("should enable the firewall")
+	}
+
+	// The networks are set with the NICs
+	sc = testSourceMachineConfig(t)
+	sc.MachineNICs = []MachineNIC{{Network: "test-network-1"}}
+	if errs := sc.Prepare(nil); errs == nil {
+		t.Fatalf("should error: %#v", sc)
+	}
+
+	sc = testSourceMachineConfig(t)
+	sc.MachineNetworks = nil
+	sc.MachineNICs = []MachineNIC{{FirewallRules: []string{"ALLOW tcp PORT 22"}}}
+	if errs := sc.Prepare(nil); errs == nil {
+		t.Fatalf("should error: %#v", sc)
+	}
+
+	sc = testSourceMachineConfig(t)
+	sc.MachineNetworks = nil
+	sc.MachineNICs = []MachineNIC{{Network: "test-network-1", FirewallRules: []string{"FROM any TO all vms ALLOW tcp PORT 22"}}}
+	if errs := sc.Prepare(nil); errs == nil {
+		t.Fatalf("should error: %#v", sc)
+	}
+}
+
 func testSourceMachineConfig(t *testing.T) SourceMachineConfig {
 	return SourceMachineConfig{
 		MachineName:    "test-machine",
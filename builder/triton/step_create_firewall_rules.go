@@ -0,0 +1,66 @@
+package triton
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// StepCreateFirewallRules creates the firewall rules of the NICs of the
+// source machine, for the traffic coming from the subnets of their
+// networks. The rules are deleted with the machine.
+type StepCreateFirewallRules struct {
+	ruleIds []string
+}
+
+func (s *StepCreateFirewallRules) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	driver := state.Get("driver").(Driver)
+	ui := state.Get("ui").(packer.Ui)
+
+	machineId := state.Get("machine").(string)
+
+	for _, nic := range config.MachineNICs {
+		if len(nic.FirewallRules) == 0 {
+			continue
+		}
+
+		ui.Say(fmt.Sprintf("Creating firewall rules for network %s...", nic.Network))
+		subnet, err := driver.GetNetworkSubnet(nic.Network)
+		if err != nil {
+			state.Put("error", fmt.Errorf("Problem getting the subnet of network %s: %s", nic.Network, err))
+			return multistep.ActionHalt
+		}
+
+		for _, action := range nic.FirewallRules {
+			rule := fmt.Sprintf("FROM subnet %s TO vm %s %s", subnet, machineId, action)
+			ruleId, err := driver.CreateFirewallRule(rule, "Packer build of "+config.ImageName)
+			if err != nil {
+				state.Put("error", fmt.Errorf("Problem creating firewall rule %q: %s", rule, err))
+				return multistep.ActionHalt
+			}
+			s.ruleIds = append(s.ruleIds, ruleId)
+		}
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *StepCreateFirewallRules) Cleanup(state multistep.StateBag) {
+	if len(s.ruleIds) == 0 {
+		return
+	}
+
+	driver := state.Get("driver").(Driver)
+	ui := state.Get("ui").(packer.Ui)
+
+	ui.Say("Deleting firewall rules...")
+	for _, ruleId := range s.ruleIds {
+		if err := driver.DeleteFirewallRule(ruleId); err != nil {
+			ui.Error(fmt.Sprintf("Problem deleting firewall rule (%s): %s", ruleId, err))
+		}
+	}
+	s.ruleIds = nil
+}
@@ -0,0 +1,65 @@
+package triton
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/packer/helper/multistep"
+)
+
+func TestStepCreateFirewallRules(t *testing.T) {
+	state := testState(t)
+	step := new(StepCreateFirewallRules)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.MachineNICs = []MachineNIC{
+		{Network: "test-network-1"},
+		{Network: "test-network-2", FirewallRules: []string{"ALLOW tcp PORT 22", "BLOCK udp PORT all"}},
+	}
+	driver := state.Get("driver").(*DriverMock)
+	state.Put("machine", "test-machine-id")
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	expected := []string{
+		"FROM subnet 10.0.0.0/24 TO vm test-machine-id ALLOW tcp PORT 22",
+		"FROM subnet 10.0.0.0/24 TO vm test-machine-id BLOCK udp PORT all",
+	}
+	if !reflect.DeepEqual(driver.CreateFirewallRules, expected) {
+		t.Fatalf("bad: %#v", driver.CreateFirewallRules)
+	}
+
+	step.Cleanup(state)
+
+	if !reflect.DeepEqual(driver.DeleteFirewallRuleIds, []string{"rule-1", "rule-2"}) {
+		t.Fatalf("should've deleted the rules: %#v", driver.DeleteFirewallRuleIds)
+	}
+}
+
+func TestStepCreateFirewallRules_CreateFirewallRuleError(t *testing.T) {
+	state := testState(t)
+	step := new(StepCreateFirewallRules)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.MachineNICs = []MachineNIC{
+		{Network: "test-network-1", FirewallRules: []string{"ALLOW tcp PORT 22"}},
+	}
+	driver := state.Get("driver").(*DriverMock)
+	state.Put("machine", "test-machine-id")
+
+	driver.CreateFirewallRuleErr = errors.New("error")
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	if _, ok := state.GetOk("error"); !ok {
+		t.Fatalf("should have error")
+	}
+}
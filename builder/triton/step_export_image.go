@@ -0,0 +1,42 @@
+package triton
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// StepExportImage exports the created image to the Manta directory of
+// image_export_manta_path, if it is set.
+type StepExportImage struct{}
+
+func (s *StepExportImage) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	config := state.Get("config").(*Config)
+	driver := state.Get("driver").(Driver)
+	ui := state.Get("ui").(packer.Ui)
+
+	if config.ImageExportPath == "" {
+		return multistep.ActionContinue
+	}
+
+	imageId := state.Get("image").(string)
+
+	ui.Say(fmt.Sprintf("Exporting image to Manta (%s)...", config.ImageExportPath))
+	imageURL, manifestURL, err := driver.ExportImage(imageId, config.ImageExportPath)
+	if err != nil {
+		state.Put("error", fmt.Errorf("Problem exporting image to Manta: %s", err))
+		return multistep.ActionHalt
+	}
+	ui.Message(fmt.Sprintf("Image exported: %s", imageURL))
+
+	state.Put("export_image", imageURL)
+	state.Put("export_manifest", manifestURL)
+
+	return multistep.ActionContinue
+}
+
+func (s *StepExportImage) Cleanup(state multistep.StateBag) {
+	// No cleanup
+}
@@ -0,0 +1,69 @@
+package triton
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/packer/helper/multistep"
+)
+
+func TestStepExportImage(t *testing.T) {
+	state := testState(t)
+	step := new(StepExportImage)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.ImageExportPath = "/test-account/stor/images"
+	driver := state.Get("driver").(*DriverMock)
+	state.Put("image", "test-image-id")
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	if driver.ExportImagePath != config.ImageExportPath {
+		t.Fatalf("bad: %s", driver.ExportImagePath)
+	}
+	if _, ok := state.GetOk("export_image"); !ok {
+		t.Fatalf("should have export_image")
+	}
+}
+
+func TestStepExportImage_NoPath(t *testing.T) {
+	state := testState(t)
+	step := new(StepExportImage)
+	defer step.Cleanup(state)
+
+	driver := state.Get("driver").(*DriverMock)
+	state.Put("image", "test-image-id")
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	if driver.ExportImagePath != "" {
+		t.Fatalf("should not export: %s", driver.ExportImagePath)
+	}
+}
+
+func TestStepExportImage_ExportImageError(t *testing.T) {
+	state := testState(t)
+	step := new(StepExportImage)
+	defer step.Cleanup(state)
+
+	config := state.Get("config").(*Config)
+	config.ImageExportPath = "/test-account/stor/images"
+	driver := state.Get("driver").(*DriverMock)
+	state.Put("image", "test-image-id")
+
+	driver.ExportImageErr = errors.New("error")
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	if _, ok := state.GetOk("error"); !ok {
+		t.Fatalf("should have error")
+	}
+}
@@ -16,6 +16,7 @@ type TargetImageConfig struct {
 	ImageEULA        string            `mapstructure:"image_eula_url"`
 	ImageACL         []string          `mapstructure:"image_acls"`
 	ImageTags        map[string]string `mapstructure:"image_tags"`
+	ImageExportPath  string            `mapstructure:"image_export_manta_path"`
 }
 
 // Prepare performs basic validation on a TargetImageConfig struct.
@@ -7,6 +7,8 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+
+	"github.com/hashicorp/packer/common/preflight"
 )
 
 // A driver is able to talk to VirtualBox and perform certain
@@ -25,6 +27,10 @@ type Driver interface {
 	// Delete a VM by name
 	Delete(string) error
 
+	// GuestProperty returns the value of the guest property of the VM, or
+	// an empty string if it isn't set.
+	GuestProperty(vm string, property string) (string, error)
+
 	// Import a VM
 	Import(string, string, []string) error
 
@@ -44,6 +50,10 @@ type Driver interface {
 	// VBoxManage executes the given VBoxManage command
 	VBoxManage(...string) error
 
+	// VMInfo returns the machine readable information of the VM, like
+	// "nic1" and "macaddress1".
+	VMInfo(string) (map[string]string, error)
+
 	// Verify checks to make sure that this driver should function
 	// properly. If there is any indication the driver can't function,
 	// this will return an error.
@@ -54,8 +64,29 @@ type Driver interface {
 }
 
 func NewDriver() (Driver, error) {
-	var vboxmanagePath string
+	vboxmanagePath, err := FindVBoxManage()
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("VBoxManage path: %s", vboxmanagePath)
+	driver := &VBox42Driver{vboxmanagePath}
+	if err := driver.Verify(); err != nil {
+		return nil, err
+	}
+
+	return driver, nil
+}
 
+// VBoxManageBinary is VBoxManage, for the preflight checks.
+var VBoxManageBinary = preflight.Binary{
+	Name: "VBoxManage",
+	Find: FindVBoxManage,
+	Hint: "install VirtualBox, or add the directory of VBoxManage to the PATH",
+}
+
+// FindVBoxManage returns the path of VBoxManage.
+func FindVBoxManage() (string, error) {
 	// On Windows, we check VBOX_INSTALL_PATH env var for the path
 	if runtime.GOOS == "windows" {
 		vars := []string{"VBOX_INSTALL_PATH", "VBOX_MSI_INSTALL_PATH"}
@@ -64,29 +95,14 @@ func NewDriver() (Driver, error) {
 			if value != "" {
 				log.Printf(
 					"[DEBUG] builder/virtualbox: %s = %s", key, value)
-				vboxmanagePath = findVBoxManageWindows(value)
-			}
-			if vboxmanagePath != "" {
-				break
+				if path := findVBoxManageWindows(value); path != "" {
+					return path, nil
+				}
 			}
 		}
 	}
 
-	if vboxmanagePath == "" {
-		var err error
-		vboxmanagePath, err = exec.LookPath("VBoxManage")
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	log.Printf("VBoxManage path: %s", vboxmanagePath)
-	driver := &VBox42Driver{vboxmanagePath}
-	if err := driver.Verify(); err != nil {
-		return nil, err
-	}
-
-	return driver, nil
+	return exec.LookPath("VBoxManage")
 }
 
 func findVBoxManageWindows(paths string) string {
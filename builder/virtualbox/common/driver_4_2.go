@@ -63,6 +63,23 @@ func (d *VBox42Driver) Delete(name string) error {
 	})
 }
 
+func (d *VBox42Driver) GuestProperty(vmName string, property string) (string, error) {
+	var stdout bytes.Buffer
+
+	cmd := exec.Command(d.VBoxManagePath, "guestproperty", "get", vmName, property)
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return "", err
+	}
+
+	// The output is "No value set!" without a value
+	output := strings.TrimSpace(stdout.String())
+	if !strings.HasPrefix(output, "Value: ") {
+		return "", nil
+	}
+	return strings.TrimPrefix(output, "Value: "), nil
+}
+
 func (d *VBox42Driver) Iso() (string, error) {
 	var stdout bytes.Buffer
 
@@ -196,6 +213,34 @@ func (d *VBox42Driver) VBoxManage(args ...string) error {
 	return err
 }
 
+func (d *VBox42Driver) VMInfo(name string) (map[string]string, error) {
+	var stdout bytes.Buffer
+
+	cmd := exec.Command(d.VBoxManagePath, "showvminfo", name, "--machinereadable")
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return nil, err
+	}
+	return parseVMInfo(stdout.String()), nil
+}
+
+// parseVMInfo reads the key="value" lines of showvminfo --machinereadable.
+func parseVMInfo(output string) map[string]string {
+	info := make(map[string]string)
+	for _, line := range strings.Split(output, "\n") {
+		// Need to trim off CR character when running in windows
+		line = strings.TrimRight(line, "\r")
+
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key := strings.Trim(parts[0], `"`)
+		info[key] = strings.Trim(parts[1], `"`)
+	}
+	return info
+}
+
 func (d *VBox42Driver) Verify() error {
 	return nil
 }
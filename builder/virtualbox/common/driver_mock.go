@@ -17,6 +17,10 @@ type DriverMock struct {
 	DeleteName   string
 	DeleteErr    error
 
+	GuestPropertyName   string
+	GuestPropertyResult map[string]string
+	GuestPropertyErr    error
+
 	ImportCalled bool
 	ImportName   string
 	ImportPath   string
@@ -39,6 +43,10 @@ type DriverMock struct {
 	VBoxManageCalls [][]string
 	VBoxManageErrs  []error
 
+	VMInfoName   string
+	VMInfoResult map[string]string
+	VMInfoErr    error
+
 	VerifyCalled bool
 	VerifyErr    error
 
@@ -65,6 +73,11 @@ func (d *DriverMock) Delete(name string) error {
 	return d.DeleteErr
 }
 
+func (d *DriverMock) GuestProperty(vm string, property string) (string, error) {
+	d.GuestPropertyName = property
+	return d.GuestPropertyResult[property], d.GuestPropertyErr
+}
+
 func (d *DriverMock) Import(name string, path string, flags []string) error {
 	d.ImportCalled = true
 	d.ImportName = name
@@ -105,6 +118,11 @@ func (d *DriverMock) VBoxManage(args ...string) error {
 	return nil
 }
 
+func (d *DriverMock) VMInfo(name string) (map[string]string, error) {
+	d.VMInfoName = name
+	return d.VMInfoResult, d.VMInfoErr
+}
+
 func (d *DriverMock) Verify() error {
 	d.VerifyCalled = true
 	return d.VerifyErr
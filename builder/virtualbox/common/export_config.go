@@ -3,11 +3,15 @@ package common
 import (
 	"errors"
 
+	"github.com/hashicorp/packer/common/outputstore"
+	"github.com/hashicorp/packer/common/ovf"
 	"github.com/hashicorp/packer/template/interpolate"
 )
 
 type ExportConfig struct {
-	Format string `mapstructure:"format"`
+	Format      string             `mapstructure:"format"`
+	OVFMetadata ovf.Metadata       `mapstructure:"ovf_metadata"`
+	OutputStore outputstore.Config `mapstructure:"output_store"`
 }
 
 func (c *ExportConfig) Prepare(ctx *interpolate.Context) []error {
@@ -20,6 +24,8 @@ func (c *ExportConfig) Prepare(ctx *interpolate.Context) []error {
 		errs = append(errs,
 			errors.New("invalid format, only 'ovf' or 'ova' are allowed"))
 	}
+	errs = append(errs, c.OVFMetadata.Prepare()...)
+	errs = append(errs, c.OutputStore.Prepare()...)
 
 	return errs
 }
@@ -32,3 +32,24 @@ func TestExportConfigPrepare_BootWait(t *testing.T) {
 		t.Fatalf("should not have error: %s", errs)
 	}
 }
+
+func TestExportConfigPrepare_OVFMetadata(t *testing.T) {
+	var c *ExportConfig
+	var errs []error
+
+	// Bad
+	c = new(ExportConfig)
+	c.OVFMetadata.EULAFile = "i/dont/exist"
+	errs = c.Prepare(testConfigTemplate(t))
+	if len(errs) == 0 {
+		t.Fatalf("bad: %#v", errs)
+	}
+
+	// Good
+	c = new(ExportConfig)
+	c.OVFMetadata.Product = "packer"
+	errs = c.Prepare(testConfigTemplate(t))
+	if len(errs) > 0 {
+		t.Fatalf("should not have error: %s", errs)
+	}
+}
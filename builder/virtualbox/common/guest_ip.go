@@ -0,0 +1,115 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/hashicorp/packer/common/guestip"
+	"github.com/hashicorp/packer/helper/multistep"
+)
+
+// IPDiscoveryStrategies are the ways of finding the IP address of the guest
+// for the ip_discovery option: the addresses the Guest Additions report, and
+// the ARP table of the host. Only the network adapters that can be reached
+// from the host are looked for, not the NAT ones.
+func IPDiscoveryStrategies(driver Driver) map[string]guestip.Strategy {
+	return map[string]guestip.Strategy{
+		guestip.Tools: func(state multistep.StateBag) (string, error) {
+			vmName := state.Get("vmName").(string)
+			macs, err := reachableMACs(driver, vmName)
+			if err != nil {
+				return "", err
+			}
+			return guestAdditionsIP(driver, vmName, macs)
+		},
+		guestip.ARP: func(state multistep.StateBag) (string, error) {
+			vmName := state.Get("vmName").(string)
+			macs, err := reachableMACs(driver, vmName)
+			if err != nil {
+				return "", err
+			}
+			var lastErr error
+			for _, mac := range macs {
+				ip, err := guestip.ARPLookup(mac)
+				if err == nil {
+					return ip, nil
+				}
+				lastErr = err
+			}
+			return "", lastErr
+		},
+	}
+}
+
+// CommPort returns the port of the communicator, which is the one of the
+// guest when its address is discovered, instead of the one NAT forwards.
+func CommPort(config *guestip.Config, port int) func(multistep.StateBag) (int, error) {
+	if !config.Enabled() {
+		return SSHPort
+	}
+	return func(multistep.StateBag) (int, error) {
+		return port, nil
+	}
+}
+
+// reachableMACs are the MAC addresses of the network adapters of the VM
+// that aren't disabled or NAT.
+func reachableMACs(driver Driver, vmName string) ([]string, error) {
+	info, err := driver.VMInfo(vmName)
+	if err != nil {
+		return nil, err
+	}
+
+	var macs []string
+	for i := 1; ; i++ {
+		nic, ok := info[fmt.Sprintf("nic%d", i)]
+		if !ok {
+			break
+		}
+		if nic == "none" || nic == "nat" || nic == "natnetwork" {
+			continue
+		}
+		mac, err := guestip.NormalizeMAC(info[fmt.Sprintf("macaddress%d", i)])
+		if err != nil {
+			return nil, err
+		}
+		macs = append(macs, mac)
+	}
+	if len(macs) == 0 {
+		return nil, errors.New("the VM has no network adapter the host can reach, only NAT ones")
+	}
+	return macs, nil
+}
+
+// guestAdditionsIP is the IPv4 address the Guest Additions report for the
+// first interface of the guest with one of the MAC addresses.
+func guestAdditionsIP(driver Driver, vmName string, macs []string) (string, error) {
+	raw, err := driver.GuestProperty(vmName, "/VirtualBox/GuestInfo/Net/Count")
+	if err != nil {
+		return "", err
+	}
+	count, _ := strconv.Atoi(raw)
+
+	for i := 0; i < count; i++ {
+		raw, err := driver.GuestProperty(vmName, fmt.Sprintf("/VirtualBox/GuestInfo/Net/%d/MAC", i))
+		if err != nil {
+			return "", err
+		}
+		mac, err := guestip.NormalizeMAC(raw)
+		if err != nil || !containsMAC(macs, mac) {
+			continue
+		}
+		return driver.GuestProperty(vmName, fmt.Sprintf("/VirtualBox/GuestInfo/Net/%d/V4/IP", i))
+	}
+	return "", errors.New("the Guest Additions didn't report an address yet")
+}
+
+func containsMAC(macs []string, mac string) bool {
+	for _, m := range macs {
+		if m == mac {
+			return true
+		}
+	}
+	return false
+}
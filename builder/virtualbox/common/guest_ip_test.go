@@ -0,0 +1,76 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/packer/helper/multistep"
+)
+
+func testVMInfo() map[string]string {
+	return map[string]string{
+		"nic1":        "nat",
+		"macaddress1": "080027AAAAAA",
+		"nic2":        "hostonly",
+		"macaddress2": "080027BBBBBB",
+		"nic3":        "none",
+	}
+}
+
+func TestParseVMInfo(t *testing.T) {
+	info := parseVMInfo("name=\"packer\"\r\nnic1=\"nat\"\nmacaddress1=\"080027AAAAAA\"\nbad\n")
+	expected := map[string]string{
+		"name":        "packer",
+		"nic1":        "nat",
+		"macaddress1": "080027AAAAAA",
+	}
+	if !reflect.DeepEqual(info, expected) {
+		t.Fatalf("bad: %#v", info)
+	}
+}
+
+func TestReachableMACs(t *testing.T) {
+	driver := &DriverMock{VMInfoResult: testVMInfo()}
+	macs, err := reachableMACs(driver, "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(macs, []string{"08:00:27:bb:bb:bb"}) {
+		t.Fatalf("bad: %#v", macs)
+	}
+
+	// Only NAT
+	driver.VMInfoResult = map[string]string{"nic1": "nat", "macaddress1": "080027AAAAAA"}
+	if _, err := reachableMACs(driver, "packer"); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestIPDiscoveryStrategies_tools(t *testing.T) {
+	state := new(multistep.BasicStateBag)
+	state.Put("vmName", "packer")
+	driver := &DriverMock{
+		VMInfoResult: testVMInfo(),
+		GuestPropertyResult: map[string]string{
+			"/VirtualBox/GuestInfo/Net/Count":   "2",
+			"/VirtualBox/GuestInfo/Net/0/MAC":   "080027AAAAAA",
+			"/VirtualBox/GuestInfo/Net/0/V4/IP": "10.0.2.15",
+			"/VirtualBox/GuestInfo/Net/1/MAC":   "080027BBBBBB",
+			"/VirtualBox/GuestInfo/Net/1/V4/IP": "192.168.56.101",
+		},
+	}
+
+	ip, err := IPDiscoveryStrategies(driver)["tools"](state)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if ip != "192.168.56.101" {
+		t.Fatalf("bad: %s", ip)
+	}
+
+	// Not reported yet
+	driver.GuestPropertyResult = map[string]string{}
+	if _, err := IPDiscoveryStrategies(driver)["tools"](state); err == nil {
+		t.Fatal("should have error")
+	}
+}
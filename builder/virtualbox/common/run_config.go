@@ -12,6 +12,8 @@ type RunConfig struct {
 	VRDPBindAddress string `mapstructure:"vrdp_bind_address"`
 	VRDPPortMin     uint   `mapstructure:"vrdp_port_min"`
 	VRDPPortMax     uint   `mapstructure:"vrdp_port_max"`
+	VRDPUsername    string `mapstructure:"vrdp_username"`
+	VRDPPassword    string `mapstructure:"vrdp_password"`
 }
 
 func (c *RunConfig) Prepare(ctx *interpolate.Context) (errs []error) {
@@ -27,6 +29,10 @@ func (c *RunConfig) Prepare(ctx *interpolate.Context) (errs []error) {
 		c.VRDPPortMax = 6000
 	}
 
+	if c.VRDPUsername == "" {
+		c.VRDPUsername = "packer"
+	}
+
 	if c.VRDPPortMin > c.VRDPPortMax {
 		errs = append(
 			errs, fmt.Errorf("vrdp_port_min must be less than vrdp_port_max"))
@@ -27,3 +27,24 @@ func TestRunConfigPrepare_VRDPBindAddress(t *testing.T) {
 		t.Fatalf("should not have error: %s", errs)
 	}
 }
+
+func TestRunConfigPrepare_VRDPUsername(t *testing.T) {
+	c := new(RunConfig)
+	errs := c.Prepare(testConfigTemplate(t))
+	if len(errs) > 0 {
+		t.Fatalf("should not have error: %s", errs)
+	}
+	if c.VRDPUsername != "packer" {
+		t.Fatalf("bad value: %s", c.VRDPUsername)
+	}
+
+	c = new(RunConfig)
+	c.VRDPUsername = "build"
+	errs = c.Prepare(testConfigTemplate(t))
+	if len(errs) > 0 {
+		t.Fatalf("should not have error: %s", errs)
+	}
+	if c.VRDPUsername != "build" {
+		t.Fatalf("bad value: %s", c.VRDPUsername)
+	}
+}
@@ -0,0 +1,13 @@
+package common
+
+import (
+	"github.com/hashicorp/packer/helper/multistep"
+)
+
+// Screenshot saves the screen of the VM to path, for
+// common.StepScreenshotOnFailure.
+func Screenshot(state multistep.StateBag, path string) error {
+	driver := state.Get("driver").(Driver)
+	vmName := state.Get("vmName").(string)
+	return driver.VBoxManage("controlvm", vmName, "screenshotpng", path)
+}
@@ -0,0 +1,87 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// This step compacts the VDI disks attached to the VM, which leaves out the
+// blocks of zeros, unless Skip is true. VirtualBox can't compact the other
+// formats, they are skipped.
+//
+// Uses:
+//   driver Driver
+//   ui     packer.Ui
+//   vmName string
+//
+// Produces:
+//   <nothing>
+type StepCompactDisk struct {
+	Skip bool
+}
+
+func (s *StepCompactDisk) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	driver := state.Get("driver").(Driver)
+	ui := state.Get("ui").(packer.Ui)
+	vmName := state.Get("vmName").(string)
+
+	if s.Skip {
+		log.Println("Skipping disk compaction step...")
+		return multistep.ActionContinue
+	}
+
+	info, err := driver.VMInfo(vmName)
+	if err != nil {
+		err := fmt.Errorf("Error reading the disks of the VM: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	ui.Say("Compacting all attached virtual disks...")
+	for i, disk := range attachedDisks(info) {
+		if !strings.EqualFold(filepath.Ext(disk), ".vdi") {
+			ui.Message(fmt.Sprintf("Skipping virtual disk %d, only VDI disks can be compacted", i+1))
+			continue
+		}
+		ui.Message(fmt.Sprintf("Compacting virtual disk %d", i+1))
+		if err := driver.VBoxManage("modifyhd", disk, "--compact"); err != nil {
+			err := fmt.Errorf("Error compacting disk: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *StepCompactDisk) Cleanup(state multistep.StateBag) {}
+
+// attachedDisks are the paths of the disks attached to the storage
+// controllers, from showvminfo --machinereadable, where they are like
+// "SATA Controller-0-0"="/path/disk.vdi".
+func attachedDisks(info map[string]string) []string {
+	var disks []string
+	for key, value := range info {
+		if strings.Contains(key, "-ImageUUID-") || strings.Contains(key, "-IsEjected") {
+			continue
+		}
+		if strings.Count(key, "-") < 2 {
+			continue
+		}
+		switch strings.ToLower(filepath.Ext(value)) {
+		case ".vdi", ".vmdk", ".vhd", ".hdd":
+			disks = append(disks, value)
+		}
+	}
+	sort.Strings(disks)
+	return disks
+}
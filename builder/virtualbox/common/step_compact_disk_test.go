@@ -0,0 +1,53 @@
+package common
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/packer/helper/multistep"
+)
+
+func TestStepCompactDisk_impl(t *testing.T) {
+	var _ multistep.Step = new(StepCompactDisk)
+}
+
+func TestStepCompactDisk(t *testing.T) {
+	state := testState(t)
+	state.Put("vmName", "foo")
+	driver := state.Get("driver").(*DriverMock)
+	driver.VMInfoResult = map[string]string{
+		"SATA Controller-0-0":           "/tmp/packer/disk.vdi",
+		"SATA Controller-ImageUUID-0-0": "4b2a5c4a-5a14-4a0e-8d0e-5c6ad0f1c6c7",
+		"SATA Controller-1-0":           "/tmp/packer/disk2.vmdk",
+		"IDE Controller-1-0":            "/tmp/packer/os.iso",
+		"IDE Controller-0-0":            "none",
+	}
+
+	step := new(StepCompactDisk)
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("error"); ok {
+		t.Fatal("should NOT have error")
+	}
+
+	expected := [][]string{{"modifyhd", "/tmp/packer/disk.vdi", "--compact"}}
+	if !reflect.DeepEqual(driver.VBoxManageCalls, expected) {
+		t.Fatalf("bad: %#v", driver.VBoxManageCalls)
+	}
+}
+
+func TestStepCompactDisk_skip(t *testing.T) {
+	state := testState(t)
+	state.Put("vmName", "foo")
+	driver := state.Get("driver").(*DriverMock)
+
+	step := &StepCompactDisk{Skip: true}
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if driver.VMInfoName != "" || len(driver.VBoxManageCalls) != 0 {
+		t.Fatal("should not compact")
+	}
+}
@@ -2,6 +2,7 @@ package common
 
 import (
 	"context"
+	"crypto/sha256"
 	"fmt"
 	"log"
 	"math/rand"
@@ -21,10 +22,16 @@ import (
 //
 // Produces:
 // vrdp_port unit - The port that VRDP is configured to listen on.
+//
+// With a password, VRDP uses the VBoxAuthSimple authentication library,
+// which authenticates the user against the password hash in the extra data
+// of the VM.
 type StepConfigureVRDP struct {
 	VRDPBindAddress string
 	VRDPPortMin     uint
 	VRDPPortMax     uint
+	VRDPUsername    string
+	VRDPPassword    string
 }
 
 func (s *StepConfigureVRDP) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
@@ -51,14 +58,36 @@ func (s *StepConfigureVRDP) Run(_ context.Context, state multistep.StateBag) mul
 		}
 	}
 
+	if s.VRDPPassword != "" {
+		command := []string{
+			"setextradata", vmName,
+			fmt.Sprintf("VBoxAuthSimple/users/%s", s.VRDPUsername),
+			fmt.Sprintf("%x", sha256.Sum256([]byte(s.VRDPPassword))),
+		}
+		if err := driver.VBoxManage(command...); err != nil {
+			err := fmt.Errorf("Error setting the VRDP password: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
 	command := []string{
 		"modifyvm", vmName,
 		"--vrdeaddress", fmt.Sprintf("%s", s.VRDPBindAddress),
-		"--vrdeauthtype", "null",
+	}
+	if s.VRDPPassword != "" {
+		command = append(command,
+			"--vrdeauthtype", "external",
+			"--vrdeauthlibrary", "VBoxAuthSimple")
+	} else {
+		command = append(command, "--vrdeauthtype", "null")
+	}
+	command = append(command,
 		"--vrde", "on",
 		"--vrdeport",
 		fmt.Sprintf("%d", vrdpPort),
-	}
+	)
 	if err := driver.VBoxManage(command...); err != nil {
 		err := fmt.Errorf("Error enabling VRDP: %s", err)
 		state.Put("error", err)
@@ -68,6 +97,9 @@ func (s *StepConfigureVRDP) Run(_ context.Context, state multistep.StateBag) mul
 
 	state.Put("vrdpIp", s.VRDPBindAddress)
 	state.Put("vrdpPort", vrdpPort)
+	if s.VRDPPassword != "" {
+		state.Put("vrdpUsername", s.VRDPUsername)
+	}
 
 	return multistep.ActionContinue
 }
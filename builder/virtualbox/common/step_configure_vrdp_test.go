@@ -0,0 +1,79 @@
+package common
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/packer/helper/multistep"
+)
+
+func TestStepConfigureVRDP_impl(t *testing.T) {
+	var _ multistep.Step = new(StepConfigureVRDP)
+}
+
+func TestStepConfigureVRDP(t *testing.T) {
+	state := testState(t)
+	state.Put("vmName", "foo")
+	step := &StepConfigureVRDP{VRDPBindAddress: "127.0.0.1"}
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	driver := state.Get("driver").(*DriverMock)
+	if len(driver.VBoxManageCalls) != 1 {
+		t.Fatalf("bad: %#v", driver.VBoxManageCalls)
+	}
+	expected := []string{
+		"modifyvm", "foo",
+		"--vrdeaddress", "127.0.0.1",
+		"--vrdeauthtype", "null",
+		"--vrde", "on",
+		"--vrdeport", "0",
+	}
+	if !reflect.DeepEqual(driver.VBoxManageCalls[0], expected) {
+		t.Fatalf("bad: %#v", driver.VBoxManageCalls[0])
+	}
+	if _, ok := state.GetOk("vrdpUsername"); ok {
+		t.Fatal("should not have a username")
+	}
+}
+
+func TestStepConfigureVRDP_password(t *testing.T) {
+	state := testState(t)
+	state.Put("vmName", "foo")
+	step := &StepConfigureVRDP{
+		VRDPBindAddress: "127.0.0.1",
+		VRDPUsername:    "packer",
+		VRDPPassword:    "secret",
+	}
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	driver := state.Get("driver").(*DriverMock)
+	expected := [][]string{
+		{
+			"setextradata", "foo", "VBoxAuthSimple/users/packer",
+			fmt.Sprintf("%x", sha256.Sum256([]byte("secret"))),
+		},
+		{
+			"modifyvm", "foo",
+			"--vrdeaddress", "127.0.0.1",
+			"--vrdeauthtype", "external",
+			"--vrdeauthlibrary", "VBoxAuthSimple",
+			"--vrde", "on",
+			"--vrdeport", "0",
+		},
+	}
+	if !reflect.DeepEqual(driver.VBoxManageCalls, expected) {
+		t.Fatalf("bad: %#v", driver.VBoxManageCalls)
+	}
+	if username := state.Get("vrdpUsername"); username != "packer" {
+		t.Fatalf("bad: %#v", username)
+	}
+}
@@ -35,6 +35,14 @@ type StepDownloadGuestAdditions struct {
 	GuestAdditionsURL    string
 	GuestAdditionsSHA256 string
 	Ctx                  interpolate.Context
+
+	forceDownload bool
+	offline       bool
+}
+
+func (s *StepDownloadGuestAdditions) SetDownloadMode(force, offline bool) {
+	s.forceDownload = force
+	s.offline = offline
 }
 
 func (s *StepDownloadGuestAdditions) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
@@ -139,6 +147,8 @@ func (s *StepDownloadGuestAdditions) Run(ctx context.Context, state multistep.St
 		Description:  "Guest additions",
 		ResultKey:    "guest_additions_path",
 		Url:          []string{url},
+		Force:        s.forceDownload,
+		Offline:      s.offline,
 	}
 
 	return downStep.Run(ctx, state)
@@ -168,6 +178,8 @@ func (s *StepDownloadGuestAdditions) downloadAdditionsSHA256(ctx context.Context
 		ResultKey:   "guest_additions_checksums_path",
 		TargetPath:  checksumsFile.Name(),
 		Url:         []string{checksumsUrl},
+		Force:       s.forceDownload,
+		Offline:     s.offline,
 	}
 
 	action := downStep.Run(ctx, state)
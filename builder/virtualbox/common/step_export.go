@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/hashicorp/packer/common/ovf"
 	"github.com/hashicorp/packer/helper/multistep"
 	"github.com/hashicorp/packer/packer"
 )
@@ -25,6 +26,7 @@ type StepExport struct {
 	Bundling       VBoxBundleConfig
 	SkipNatMapping bool
 	SkipExport     bool
+	Metadata       ovf.Metadata
 }
 
 func (s *StepExport) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
@@ -86,6 +88,16 @@ func (s *StepExport) Run(_ context.Context, state multistep.StateBag) multistep.
 		return multistep.ActionHalt
 	}
 
+	if !s.Metadata.Empty() {
+		ui.Say("Setting the OVF metadata...")
+		if err := ovf.Apply(outputPath, &s.Metadata); err != nil {
+			err := fmt.Errorf("Error setting the OVF metadata: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
 	state.Put("exportPath", outputPath)
 
 	return multistep.ActionContinue
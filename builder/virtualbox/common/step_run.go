@@ -37,10 +37,14 @@ func (s *StepRun) Run(_ context.Context, state multistep.StateBag) multistep.Ste
 			vrdpIp := vrdpIpRaw.(string)
 			vrdpPort := vrdpPortRaw.(uint)
 
+			auth := "without a password"
+			if username, ok := state.GetOk("vrdpUsername"); ok {
+				auth = fmt.Sprintf("as the user \"%s\" with the configured password", username)
+			}
 			ui.Message(fmt.Sprintf(
 				"The VM will be run headless, without a GUI. If you want to\n"+
-					"view the screen of the VM, connect via VRDP without a password to\n"+
-					"rdp://%s:%d", vrdpIp, vrdpPort))
+					"view the screen of the VM, connect via VRDP %s to\n"+
+					"rdp://%s:%d", auth, vrdpIp, vrdpPort))
 		} else {
 			ui.Message("The VM will be run headless, without a GUI, as configured.\n" +
 				"If the run isn't succeeding as you expect, please enable the GUI\n" +
@@ -9,6 +9,9 @@ import (
 	vboxcommon "github.com/hashicorp/packer/builder/virtualbox/common"
 	"github.com/hashicorp/packer/common"
 	"github.com/hashicorp/packer/common/bootcommand"
+	"github.com/hashicorp/packer/common/guestip"
+	"github.com/hashicorp/packer/common/outputstore"
+	"github.com/hashicorp/packer/common/preflight"
 	"github.com/hashicorp/packer/helper/communicator"
 	"github.com/hashicorp/packer/helper/config"
 	"github.com/hashicorp/packer/helper/multistep"
@@ -28,18 +31,22 @@ type Config struct {
 	common.HTTPConfig               `mapstructure:",squash"`
 	common.ISOConfig                `mapstructure:",squash"`
 	common.FloppyConfig             `mapstructure:",squash"`
+	common.ZeroFillConfig           `mapstructure:",squash"`
 	bootcommand.BootConfig          `mapstructure:",squash"`
 	vboxcommon.ExportConfig         `mapstructure:",squash"`
 	vboxcommon.ExportOpts           `mapstructure:",squash"`
 	vboxcommon.OutputConfig         `mapstructure:",squash"`
 	vboxcommon.RunConfig            `mapstructure:",squash"`
 	vboxcommon.ShutdownConfig       `mapstructure:",squash"`
+	common.GuestOSConfig            `mapstructure:",squash"`
 	vboxcommon.SSHConfig            `mapstructure:",squash"`
 	vboxcommon.HWConfig             `mapstructure:",squash"`
 	vboxcommon.VBoxManageConfig     `mapstructure:",squash"`
 	vboxcommon.VBoxManagePostConfig `mapstructure:",squash"`
 	vboxcommon.VBoxVersionConfig    `mapstructure:",squash"`
 	vboxcommon.VBoxBundleConfig     `mapstructure:",squash"`
+	Preflight                       preflight.Config `mapstructure:",squash"`
+	GuestIP                         guestip.Config   `mapstructure:",squash"`
 
 	DiskSize               uint   `mapstructure:"disk_size"`
 	GuestAdditionsMode     string `mapstructure:"guest_additions_mode"`
@@ -92,8 +99,11 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 		errs, b.config.OutputConfig.Prepare(&b.config.ctx, &b.config.PackerConfig)...)
 	errs = packer.MultiErrorAppend(errs, b.config.HTTPConfig.Prepare(&b.config.ctx)...)
 	errs = packer.MultiErrorAppend(errs, b.config.RunConfig.Prepare(&b.config.ctx)...)
+	errs = packer.MultiErrorAppend(errs, b.config.GuestIP.Prepare(guestip.Tools, guestip.ARP)...)
 	errs = packer.MultiErrorAppend(errs, b.config.ShutdownConfig.Prepare(&b.config.ctx)...)
+	errs = packer.MultiErrorAppend(errs, b.config.GuestOSConfig.Prepare()...)
 	errs = packer.MultiErrorAppend(errs, b.config.SSHConfig.Prepare(&b.config.ctx)...)
+	errs = packer.MultiErrorAppend(errs, b.config.ZeroFillConfig.Prepare(b.config.SSHConfig.Comm.Type)...)
 	errs = packer.MultiErrorAppend(errs, b.config.HWConfig.Prepare(&b.config.ctx)...)
 	errs = packer.MultiErrorAppend(errs, b.config.VBoxBundleConfig.Prepare(&b.config.ctx)...)
 	errs = packer.MultiErrorAppend(errs, b.config.VBoxManageConfig.Prepare(&b.config.ctx)...)
@@ -172,6 +182,10 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 		b.config.GuestAdditionsSHA256 = strings.ToLower(b.config.GuestAdditionsSHA256)
 	}
 
+	if b.config.ShutdownCommand == "" {
+		b.config.ShutdownCommand = b.config.GuestOSConfig.DefaultShutdownCommand(&b.config.SSHConfig.Comm)
+	}
+
 	// Warnings
 	if b.config.ShutdownCommand == "" {
 		warnings = append(warnings,
@@ -187,6 +201,18 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 }
 
 func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packer.Artifact, error) {
+	// Check that the host has what the build needs
+	err := preflight.Run(ui, &b.config.Preflight, &preflight.Checks{
+		DiskDir:      b.config.OutputDir,
+		DiskSize:     uint64(b.config.DiskSize),
+		Memory:       uint64(b.config.MemorySize),
+		HardwareVirt: strings.HasSuffix(b.config.GuestOSType, "_64"),
+		Binaries:     []preflight.Binary{vboxcommon.VBoxManageBinary},
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	// Create the driver that we'll use to communicate with VirtualBox
 	driver, err := vboxcommon.NewDriver()
 	if err != nil {
@@ -203,8 +229,10 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		&common.StepDownload{
 			Checksum:     b.config.ISOChecksum,
 			ChecksumType: b.config.ISOChecksumType,
+			Size:         b.config.ISOSize,
 			Description:  "ISO",
 			Extension:    b.config.TargetExtension,
+			Extract:      true,
 			ResultKey:    "iso_path",
 			TargetPath:   b.config.TargetPath,
 			Url:          b.config.ISOUrls,
@@ -216,6 +244,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		&common.StepCreateFloppy{
 			Files:       b.config.FloppyConfig.FloppyFiles,
 			Directories: b.config.FloppyConfig.FloppyDirectories,
+			Unattend:    b.config.FloppyConfig.Unattend,
 		},
 		&common.StepHTTPServer{
 			HTTPDir:     b.config.HTTPDir,
@@ -233,6 +262,8 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			VRDPBindAddress: b.config.VRDPBindAddress,
 			VRDPPortMin:     b.config.VRDPPortMin,
 			VRDPPortMax:     b.config.VRDPPortMax,
+			VRDPUsername:    b.config.VRDPUsername,
+			VRDPPassword:    b.config.VRDPPassword,
 		},
 		new(vboxcommon.StepAttachFloppy),
 		&vboxcommon.StepForwardSSH{
@@ -248,6 +279,10 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		&vboxcommon.StepRun{
 			Headless: b.config.Headless,
 		},
+		&common.StepScreenshotOnFailure{
+			BuildName:  b.config.PackerBuildName,
+			Screenshot: vboxcommon.Screenshot,
+		},
 		&vboxcommon.StepTypeBootCommand{
 			BootWait:      b.config.BootWait,
 			BootCommand:   b.config.FlatBootCommand(),
@@ -257,10 +292,13 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		},
 		&communicator.StepConnect{
 			Config:    &b.config.SSHConfig.Comm,
-			Host:      vboxcommon.CommHost(b.config.SSHConfig.Comm.SSHHost),
+			BuildName: b.config.PackerBuildName,
+			Host: b.config.GuestIP.CommHost(
+				vboxcommon.CommHost(b.config.SSHConfig.Comm.SSHHost),
+				vboxcommon.IPDiscoveryStrategies(driver)),
 			SSHConfig: b.config.SSHConfig.Comm.SSHConfigFunc(),
-			SSHPort:   vboxcommon.SSHPort,
-			WinRMPort: vboxcommon.SSHPort,
+			SSHPort:   vboxcommon.CommPort(&b.config.GuestIP, b.config.SSHConfig.Comm.Port()),
+			WinRMPort: vboxcommon.CommPort(&b.config.GuestIP, b.config.SSHConfig.Comm.Port()),
 		},
 		&vboxcommon.StepUploadVersion{
 			Path: *b.config.VBoxVersionFile,
@@ -271,8 +309,19 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			Ctx:                b.config.ctx,
 		},
 		new(common.StepProvision),
+		&common.StepCleanupTempFiles{
+			Comm:    &b.config.SSHConfig.Comm,
+			GuestOS: &b.config.GuestOSConfig,
+		},
+		&communicator.StepScramblePassword{
+			Config: &b.config.SSHConfig.Comm,
+		},
 		&common.StepCleanupTempKeys{
-			Comm: &b.config.SSHConfig.Comm,
+			Comm:    &b.config.SSHConfig.Comm,
+			GuestOS: &b.config.GuestOSConfig,
+		},
+		&common.StepZeroFill{
+			Config: &b.config.ZeroFillConfig,
 		},
 		&vboxcommon.StepShutdown{
 			Command: b.config.ShutdownCommand,
@@ -282,6 +331,9 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		&vboxcommon.StepRemoveDevices{
 			Bundling: b.config.VBoxBundleConfig,
 		},
+		&vboxcommon.StepCompactDisk{
+			Skip: !b.config.ZeroFillFreeSpace,
+		},
 		&vboxcommon.StepVBoxManage{
 			Commands: b.config.VBoxManagePost,
 			Ctx:      b.config.ctx,
@@ -293,6 +345,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			Bundling:       b.config.VBoxBundleConfig,
 			SkipNatMapping: b.config.SSHSkipNatMapping,
 			SkipExport:     b.config.SkipExport,
+			Metadata:       b.config.OVFMetadata,
 		},
 	}
 
@@ -323,7 +376,11 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		return nil, errors.New("Build was halted.")
 	}
 
-	return vboxcommon.NewArtifact(b.config.OutputDir)
+	artifact, err := vboxcommon.NewArtifact(b.config.OutputDir)
+	if err != nil {
+		return nil, err
+	}
+	return outputstore.Upload(ui, &b.config.OutputStore, common.WithUnverifiedDownloads(artifact, state))
 }
 
 func (b *Builder) Cancel() {
@@ -7,6 +7,8 @@ import (
 
 	vboxcommon "github.com/hashicorp/packer/builder/virtualbox/common"
 	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/common/outputstore"
+	"github.com/hashicorp/packer/common/preflight"
 	"github.com/hashicorp/packer/helper/communicator"
 	"github.com/hashicorp/packer/helper/multistep"
 	"github.com/hashicorp/packer/packer"
@@ -33,6 +35,14 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, error) {
 // Run executes a Packer build and returns a packer.Artifact representing
 // a VirtualBox appliance.
 func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packer.Artifact, error) {
+	// Check that the host has what the build needs
+	err := preflight.Run(ui, &b.config.Preflight, &preflight.Checks{
+		Binaries: []preflight.Binary{vboxcommon.VBoxManageBinary},
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	// Create the driver that we'll use to communicate with VirtualBox
 	driver, err := vboxcommon.NewDriver()
 	if err != nil {
@@ -58,6 +68,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		&common.StepCreateFloppy{
 			Files:       b.config.FloppyConfig.FloppyFiles,
 			Directories: b.config.FloppyConfig.FloppyDirectories,
+			Unattend:    b.config.FloppyConfig.Unattend,
 		},
 		&common.StepHTTPServer{
 			HTTPDir:     b.config.HTTPDir,
@@ -90,6 +101,8 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			VRDPBindAddress: b.config.VRDPBindAddress,
 			VRDPPortMin:     b.config.VRDPPortMin,
 			VRDPPortMax:     b.config.VRDPPortMax,
+			VRDPUsername:    b.config.VRDPUsername,
+			VRDPPassword:    b.config.VRDPPassword,
 		},
 		new(vboxcommon.StepAttachFloppy),
 		&vboxcommon.StepForwardSSH{
@@ -105,6 +118,10 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		&vboxcommon.StepRun{
 			Headless: b.config.Headless,
 		},
+		&common.StepScreenshotOnFailure{
+			BuildName:  b.config.PackerBuildName,
+			Screenshot: vboxcommon.Screenshot,
+		},
 		&vboxcommon.StepTypeBootCommand{
 			BootWait:      b.config.BootWait,
 			BootCommand:   b.config.FlatBootCommand(),
@@ -114,10 +131,13 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		},
 		&communicator.StepConnect{
 			Config:    &b.config.SSHConfig.Comm,
-			Host:      vboxcommon.CommHost(b.config.SSHConfig.Comm.SSHHost),
+			BuildName: b.config.PackerBuildName,
+			Host: b.config.GuestIP.CommHost(
+				vboxcommon.CommHost(b.config.SSHConfig.Comm.SSHHost),
+				vboxcommon.IPDiscoveryStrategies(driver)),
 			SSHConfig: b.config.SSHConfig.Comm.SSHConfigFunc(),
-			SSHPort:   vboxcommon.SSHPort,
-			WinRMPort: vboxcommon.SSHPort,
+			SSHPort:   vboxcommon.CommPort(&b.config.GuestIP, b.config.SSHConfig.Comm.Port()),
+			WinRMPort: vboxcommon.CommPort(&b.config.GuestIP, b.config.SSHConfig.Comm.Port()),
 		},
 		&vboxcommon.StepUploadVersion{
 			Path: *b.config.VBoxVersionFile,
@@ -128,8 +148,19 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			Ctx:                b.config.ctx,
 		},
 		new(common.StepProvision),
+		&common.StepCleanupTempFiles{
+			Comm:    &b.config.SSHConfig.Comm,
+			GuestOS: &b.config.GuestOSConfig,
+		},
+		&communicator.StepScramblePassword{
+			Config: &b.config.SSHConfig.Comm,
+		},
 		&common.StepCleanupTempKeys{
-			Comm: &b.config.SSHConfig.Comm,
+			Comm:    &b.config.SSHConfig.Comm,
+			GuestOS: &b.config.GuestOSConfig,
+		},
+		&common.StepZeroFill{
+			Config: &b.config.ZeroFillConfig,
 		},
 		&vboxcommon.StepShutdown{
 			Command: b.config.ShutdownCommand,
@@ -137,6 +168,9 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			Delay:   b.config.PostShutdownDelay,
 		},
 		new(vboxcommon.StepRemoveDevices),
+		&vboxcommon.StepCompactDisk{
+			Skip: !b.config.ZeroFillFreeSpace,
+		},
 		&vboxcommon.StepVBoxManage{
 			Commands: b.config.VBoxManagePost,
 			Ctx:      b.config.ctx,
@@ -147,6 +181,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			ExportOpts:     b.config.ExportOpts.ExportOpts,
 			SkipNatMapping: b.config.SSHSkipNatMapping,
 			SkipExport:     b.config.SkipExport,
+			Metadata:       b.config.OVFMetadata,
 		},
 	}
 
@@ -168,7 +203,11 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		return nil, errors.New("Build was halted.")
 	}
 
-	return vboxcommon.NewArtifact(b.config.OutputDir)
+	artifact, err := vboxcommon.NewArtifact(b.config.OutputDir)
+	if err != nil {
+		return nil, err
+	}
+	return outputstore.Upload(ui, &b.config.OutputStore, artifact)
 }
 
 // Cancel.
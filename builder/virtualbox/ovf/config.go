@@ -7,6 +7,8 @@ import (
 	vboxcommon "github.com/hashicorp/packer/builder/virtualbox/common"
 	"github.com/hashicorp/packer/common"
 	"github.com/hashicorp/packer/common/bootcommand"
+	"github.com/hashicorp/packer/common/guestip"
+	"github.com/hashicorp/packer/common/preflight"
 	"github.com/hashicorp/packer/helper/config"
 	"github.com/hashicorp/packer/packer"
 	"github.com/hashicorp/packer/template/interpolate"
@@ -17,6 +19,7 @@ type Config struct {
 	common.PackerConfig             `mapstructure:",squash"`
 	common.HTTPConfig               `mapstructure:",squash"`
 	common.FloppyConfig             `mapstructure:",squash"`
+	common.ZeroFillConfig           `mapstructure:",squash"`
 	bootcommand.BootConfig          `mapstructure:",squash"`
 	vboxcommon.ExportConfig         `mapstructure:",squash"`
 	vboxcommon.ExportOpts           `mapstructure:",squash"`
@@ -24,9 +27,12 @@ type Config struct {
 	vboxcommon.RunConfig            `mapstructure:",squash"`
 	vboxcommon.SSHConfig            `mapstructure:",squash"`
 	vboxcommon.ShutdownConfig       `mapstructure:",squash"`
+	common.GuestOSConfig            `mapstructure:",squash"`
 	vboxcommon.VBoxManageConfig     `mapstructure:",squash"`
 	vboxcommon.VBoxManagePostConfig `mapstructure:",squash"`
 	vboxcommon.VBoxVersionConfig    `mapstructure:",squash"`
+	Preflight                       preflight.Config `mapstructure:",squash"`
+	GuestIP                         guestip.Config   `mapstructure:",squash"`
 
 	Checksum             string   `mapstructure:"checksum"`
 	ChecksumType         string   `mapstructure:"checksum_type"`
@@ -86,8 +92,11 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 	errs = packer.MultiErrorAppend(errs, c.HTTPConfig.Prepare(&c.ctx)...)
 	errs = packer.MultiErrorAppend(errs, c.OutputConfig.Prepare(&c.ctx, &c.PackerConfig)...)
 	errs = packer.MultiErrorAppend(errs, c.RunConfig.Prepare(&c.ctx)...)
+	errs = packer.MultiErrorAppend(errs, c.GuestIP.Prepare(guestip.Tools, guestip.ARP)...)
 	errs = packer.MultiErrorAppend(errs, c.ShutdownConfig.Prepare(&c.ctx)...)
+	errs = packer.MultiErrorAppend(errs, c.GuestOSConfig.Prepare()...)
 	errs = packer.MultiErrorAppend(errs, c.SSHConfig.Prepare(&c.ctx)...)
+	errs = packer.MultiErrorAppend(errs, c.ZeroFillConfig.Prepare(c.SSHConfig.Comm.Type)...)
 	errs = packer.MultiErrorAppend(errs, c.VBoxManageConfig.Prepare(&c.ctx)...)
 	errs = packer.MultiErrorAppend(errs, c.VBoxManagePostConfig.Prepare(&c.ctx)...)
 	errs = packer.MultiErrorAppend(errs, c.VBoxVersionConfig.Prepare(&c.ctx)...)
@@ -134,6 +143,10 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 		c.GuestAdditionsSHA256 = strings.ToLower(c.GuestAdditionsSHA256)
 	}
 
+	if c.ShutdownCommand == "" {
+		c.ShutdownCommand = c.GuestOSConfig.DefaultShutdownCommand(&c.SSHConfig.Comm)
+	}
+
 	// Warnings
 	var warnings []string
 	if c.ShutdownCommand == "" {
@@ -435,6 +435,12 @@ func (ESX5Driver) UpdateVMX(_, password string, port uint, data map[string]strin
 	}
 }
 
+// GuestToolsIP returns the address of the VM on the ports of the virtual
+// switch, which VMware Tools reports.
+func (d *ESX5Driver) GuestToolsIP(state multistep.StateBag) (string, error) {
+	return d.CommHost(state)
+}
+
 func (d *ESX5Driver) CommHost(state multistep.StateBag) (string, error) {
 	sshc := state.Get("sshConfig").(*SSHConfig).Comm
 	port := sshc.SSHPort
@@ -76,6 +76,10 @@ func (d *Fusion5Driver) CommHost(state multistep.StateBag) (string, error) {
 	return CommHost(d.SSHConfig)(state)
 }
 
+func (d *Fusion5Driver) GuestToolsIP(state multistep.StateBag) (string, error) {
+	return vmrunGuestIP(d.vmrunPath(), "fusion", state)
+}
+
 func (d *Fusion5Driver) Start(vmxPath string, headless bool) error {
 	guiArgument := "gui"
 	if headless == true {
@@ -103,6 +103,10 @@ func (d *Player5Driver) CommHost(state multistep.StateBag) (string, error) {
 	return CommHost(d.SSHConfig)(state)
 }
 
+func (d *Player5Driver) GuestToolsIP(state multistep.StateBag) (string, error) {
+	return vmrunGuestIP(d.VmrunPath, "player", state)
+}
+
 func (d *Player5Driver) Start(vmxPath string, headless bool) error {
 	guiArgument := "gui"
 	if headless {
@@ -76,6 +76,10 @@ func (d *Workstation9Driver) CommHost(state multistep.StateBag) (string, error)
 	return CommHost(d.SSHConfig)(state)
 }
 
+func (d *Workstation9Driver) GuestToolsIP(state multistep.StateBag) (string, error) {
+	return vmrunGuestIP(d.VmrunPath, "ws", state)
+}
+
 func (d *Workstation9Driver) Start(vmxPath string, headless bool) error {
 	guiArgument := "gui"
 	if headless {
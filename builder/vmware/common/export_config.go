@@ -1,17 +1,22 @@
 package common
 
 import (
+	"errors"
 	"fmt"
 
+	"github.com/hashicorp/packer/common/outputstore"
+	"github.com/hashicorp/packer/common/ovf"
 	"github.com/hashicorp/packer/template/interpolate"
 )
 
 type ExportConfig struct {
-	Format         string   `mapstructure:"format"`
-	OVFToolOptions []string `mapstructure:"ovftool_options"`
-	SkipExport     bool     `mapstructure:"skip_export"`
-	KeepRegistered bool     `mapstructure:"keep_registered"`
-	SkipCompaction bool     `mapstructure:"skip_compaction"`
+	Format         string             `mapstructure:"format"`
+	OVFToolOptions []string           `mapstructure:"ovftool_options"`
+	SkipExport     bool               `mapstructure:"skip_export"`
+	KeepRegistered bool               `mapstructure:"keep_registered"`
+	SkipCompaction bool               `mapstructure:"skip_compaction"`
+	OVFMetadata    ovf.Metadata       `mapstructure:"ovf_metadata"`
+	OutputStore    outputstore.Config `mapstructure:"output_store"`
 }
 
 func (c *ExportConfig) Prepare(ctx *interpolate.Context) []error {
@@ -22,5 +27,10 @@ func (c *ExportConfig) Prepare(ctx *interpolate.Context) []error {
 				errs, fmt.Errorf("format must be one of ova, ovf, or vmx"))
 		}
 	}
+	errs = append(errs, c.OVFMetadata.Prepare()...)
+	if c.Format == "vmx" && !c.OVFMetadata.Empty() {
+		errs = append(errs, errors.New("ovf_metadata can only be set with the ova or ovf format"))
+	}
+	errs = append(errs, c.OutputStore.Prepare()...)
 	return errs
 }
@@ -0,0 +1,55 @@
+package common
+
+import (
+	"fmt"
+	"net"
+	"os/exec"
+
+	"github.com/hashicorp/packer/common/guestip"
+	"github.com/hashicorp/packer/helper/multistep"
+)
+
+// GuestToolsIPFinder is a driver that can ask VMware Tools in the guest for
+// its IP address.
+type GuestToolsIPFinder interface {
+	GuestToolsIP(multistep.StateBag) (string, error)
+}
+
+// IPDiscoveryStrategies are the ways the driver can find the IP address of
+// the guest with, for the ip_discovery option. ESXi only has the addresses
+// VMware Tools reports, the other ones also read the DHCP leases and the ARP
+// table of the host.
+func IPDiscoveryStrategies(driver Driver) map[string]guestip.Strategy {
+	strategies := make(map[string]guestip.Strategy)
+	if finder, ok := driver.(GuestToolsIPFinder); ok {
+		strategies[guestip.Tools] = finder.GuestToolsIP
+	}
+	if _, ok := driver.(*ESX5Driver); ok {
+		return strategies
+	}
+
+	strategies[guestip.DHCP] = driver.GuestIP
+	strategies[guestip.ARP] = func(state multistep.StateBag) (string, error) {
+		mac, err := driver.GuestAddress(state)
+		if err != nil {
+			return "", err
+		}
+		return guestip.ARPLookup(mac)
+	}
+	return strategies
+}
+
+// vmrunGuestIP asks VMware Tools for the IP address of the guest with vmrun,
+// with the host type of the product, like "ws".
+func vmrunGuestIP(vmrunPath, hostType string, state multistep.StateBag) (string, error) {
+	vmxPath := state.Get("vmx_path").(string)
+	cmd := exec.Command(vmrunPath, "-T", hostType, "getGuestIPAddress", vmxPath)
+	stdout, _, err := runAndLog(cmd)
+	if err != nil {
+		return "", err
+	}
+	if net.ParseIP(stdout) == nil {
+		return "", fmt.Errorf("VMware Tools didn't report an IP address: %s", stdout)
+	}
+	return stdout, nil
+}
@@ -0,0 +1,29 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/hashicorp/packer/helper/multistep"
+)
+
+func TestIPDiscoveryStrategies(t *testing.T) {
+	state := new(multistep.BasicStateBag)
+	driver := &DriverMock{GuestIPResult: "192.168.1.10"}
+	strategies := IPDiscoveryStrategies(driver)
+
+	if _, ok := strategies["tools"]; ok {
+		t.Fatal("the mock can't ask VMware Tools")
+	}
+	ip, err := strategies["dhcp"](state)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if ip != "192.168.1.10" || !driver.GuestIPCalled {
+		t.Fatalf("bad: %s", ip)
+	}
+
+	strategies = IPDiscoveryStrategies(new(ESX5Driver))
+	if len(strategies) != 1 || strategies["tools"] == nil {
+		t.Fatalf("bad: %#v", strategies)
+	}
+}
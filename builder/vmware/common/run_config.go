@@ -13,6 +13,7 @@ type RunConfig struct {
 	VNCPortMin         uint   `mapstructure:"vnc_port_min"`
 	VNCPortMax         uint   `mapstructure:"vnc_port_max"`
 	VNCDisablePassword bool   `mapstructure:"vnc_disable_password"`
+	VNCPassword        string `mapstructure:"vnc_password"`
 }
 
 func (c *RunConfig) Prepare(ctx *interpolate.Context) (errs []error) {
@@ -32,5 +33,14 @@ func (c *RunConfig) Prepare(ctx *interpolate.Context) (errs []error) {
 		errs = append(errs, fmt.Errorf("vnc_port_min must be less than vnc_port_max"))
 	}
 
+	if c.VNCPassword != "" && c.VNCDisablePassword {
+		errs = append(errs, fmt.Errorf("vnc_password can't be set with vnc_disable_password"))
+	}
+
+	// VNC authentication only uses the first 8 characters
+	if len(c.VNCPassword) > 8 {
+		errs = append(errs, fmt.Errorf("vnc_password must be at most 8 characters"))
+	}
+
 	return
 }
@@ -0,0 +1,29 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestRunConfigPrepare_VNCPassword(t *testing.T) {
+	cases := []struct {
+		Password string
+		Disable  bool
+		Err      bool
+	}{
+		{"", false, false},
+		{"", true, false},
+		{"secret", false, false},
+		{"secret", true, true},
+		{"toolongpw", false, true},
+	}
+	for _, tc := range cases {
+		c := &RunConfig{
+			VNCPassword:        tc.Password,
+			VNCDisablePassword: tc.Disable,
+		}
+		errs := c.Prepare(testConfigTemplate(t))
+		if (len(errs) > 0) != tc.Err {
+			t.Fatalf("%#v: bad: %s", tc, errs)
+		}
+	}
+}
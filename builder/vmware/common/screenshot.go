@@ -0,0 +1,28 @@
+package common
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/mitchellh/go-vnc"
+)
+
+// VNCScreenshot saves the screen of the VM to path over VNC, for
+// common.StepScreenshotOnFailure.
+func VNCScreenshot(state multistep.StateBag, path string) error {
+	vncIp, ok := state.GetOk("vnc_ip")
+	if !ok {
+		return fmt.Errorf("VNC isn't configured")
+	}
+	vncPort := state.Get("vnc_port").(uint)
+
+	auth := []vnc.ClientAuth{new(vnc.ClientAuthNone)}
+	if vncPassword, ok := state.GetOk("vnc_password"); ok && vncPassword.(string) != "" {
+		auth = []vnc.ClientAuth{&vnc.PasswordAuth{Password: vncPassword.(string)}}
+	}
+
+	address := fmt.Sprintf("%s:%d", vncIp, vncPort)
+	return common.VNCScreenshot(address, auth, path, 30*time.Second)
+}
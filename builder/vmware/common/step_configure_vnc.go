@@ -25,6 +25,7 @@ type StepConfigureVNC struct {
 	VNCPortMin         uint
 	VNCPortMax         uint
 	VNCDisablePassword bool
+	VNCPassword        string
 }
 
 type VNCAddressFinder interface {
@@ -107,7 +108,10 @@ func (s *StepConfigureVNC) Run(_ context.Context, state multistep.StateBag) mult
 		return multistep.ActionHalt
 	}
 
-	vncPassword := VNCPassword(s.VNCDisablePassword)
+	vncPassword := s.VNCPassword
+	if vncPassword == "" {
+		vncPassword = VNCPassword(s.VNCDisablePassword)
+	}
 
 	log.Printf("Found available VNC port: %d", vncPort)
 
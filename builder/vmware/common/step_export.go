@@ -7,9 +7,11 @@ import (
 	"net/url"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
 	"strings"
 
+	"github.com/hashicorp/packer/common/ovf"
 	"github.com/hashicorp/packer/helper/multistep"
 	"github.com/hashicorp/packer/packer"
 )
@@ -24,6 +26,7 @@ type StepExport struct {
 	VMName         string
 	OVFToolOptions []string
 	OutputDir      string
+	Metadata       ovf.Metadata
 }
 
 func GetOVFTool() string {
@@ -104,7 +107,32 @@ func (s *StepExport) Run(_ context.Context, state multistep.StateBag) multistep.
 
 	ui.Message(fmt.Sprintf("%s", out.String()))
 
+	if !s.Metadata.Empty() {
+		ui.Say("Setting the OVF metadata...")
+		if err := s.applyMetadata(); err != nil {
+			err := fmt.Errorf("Error setting the OVF metadata: %s", err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
 	return multistep.ActionContinue
 }
 
 func (s *StepExport) Cleanup(state multistep.StateBag) {}
+
+// applyMetadata sets the metadata in the OVF and OVA files ovftool
+// exported, which can be in a directory of the VM in the output directory.
+func (s *StepExport) applyMetadata() error {
+	return filepath.Walk(s.OutputDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".ovf", ".ova":
+			return ovf.Apply(path, &s.Metadata)
+		}
+		return nil
+	})
+}
@@ -8,6 +8,7 @@ import (
 
 	vmwcommon "github.com/hashicorp/packer/builder/vmware/common"
 	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/common/outputstore"
 	"github.com/hashicorp/packer/helper/communicator"
 	"github.com/hashicorp/packer/helper/multistep"
 	"github.com/hashicorp/packer/packer"
@@ -67,6 +68,12 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 	state.Put("driverConfig", &b.config.DriverConfig)
 	state.Put("temporaryDevices", []string{}) // Devices (in .vmx) created by packer during building
 
+	// The console can only be captured over VNC
+	var screenshot func(multistep.StateBag, string) error
+	if !b.config.DisableVNC {
+		screenshot = vmwcommon.VNCScreenshot
+	}
+
 	steps := []multistep.Step{
 		&vmwcommon.StepPrepareTools{
 			RemoteType:        b.config.RemoteType,
@@ -75,8 +82,10 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		&common.StepDownload{
 			Checksum:     b.config.ISOChecksum,
 			ChecksumType: b.config.ISOChecksumType,
+			Size:         b.config.ISOSize,
 			Description:  "ISO",
 			Extension:    b.config.TargetExtension,
+			Extract:      true,
 			ResultKey:    "iso_path",
 			TargetPath:   b.config.TargetPath,
 			Url:          b.config.ISOUrls,
@@ -87,6 +96,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		&common.StepCreateFloppy{
 			Files:       b.config.FloppyConfig.FloppyFiles,
 			Directories: b.config.FloppyConfig.FloppyDirectories,
+			Unattend:    b.config.FloppyConfig.Unattend,
 		},
 		&stepRemoteUpload{
 			Key:       "floppy_path",
@@ -116,6 +126,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			VNCPortMin:         b.config.VNCPortMin,
 			VNCPortMax:         b.config.VNCPortMax,
 			VNCDisablePassword: b.config.VNCDisablePassword,
+			VNCPassword:        b.config.VNCPassword,
 		},
 		&vmwcommon.StepRegister{
 			Format:         b.config.Format,
@@ -126,6 +137,10 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			DurationBeforeStop: 5 * time.Second,
 			Headless:           b.config.Headless,
 		},
+		&common.StepScreenshotOnFailure{
+			BuildName:  b.config.PackerBuildName,
+			Screenshot: screenshot,
+		},
 		&vmwcommon.StepTypeBootCommand{
 			BootWait:    b.config.BootWait,
 			VNCEnabled:  !b.config.DisableVNC,
@@ -136,7 +151,8 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 		},
 		&communicator.StepConnect{
 			Config:    &b.config.SSHConfig.Comm,
-			Host:      driver.CommHost,
+			BuildName: b.config.PackerBuildName,
+			Host:      b.config.GuestIP.CommHost(driver.CommHost, vmwcommon.IPDiscoveryStrategies(driver)),
 			SSHConfig: b.config.SSHConfig.Comm.SSHConfigFunc(),
 		},
 		&vmwcommon.StepUploadTools{
@@ -146,8 +162,19 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			Ctx:               b.config.ctx,
 		},
 		&common.StepProvision{},
+		&common.StepCleanupTempFiles{
+			Comm:    &b.config.SSHConfig.Comm,
+			GuestOS: &b.config.GuestOSConfig,
+		},
+		&communicator.StepScramblePassword{
+			Config: &b.config.SSHConfig.Comm,
+		},
 		&common.StepCleanupTempKeys{
-			Comm: &b.config.SSHConfig.Comm,
+			Comm:    &b.config.SSHConfig.Comm,
+			GuestOS: &b.config.GuestOSConfig,
+		},
+		&common.StepZeroFill{
+			Config: &b.config.ZeroFillConfig,
 		},
 		&vmwcommon.StepShutdown{
 			Command: b.config.ShutdownCommand,
@@ -176,6 +203,7 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 			VMName:         b.config.VMName,
 			OVFToolOptions: b.config.OVFToolOptions,
 			OutputDir:      exportOutputPath,
+			Metadata:       b.config.OVFMetadata,
 		},
 	}
 
@@ -198,8 +226,12 @@ func (b *Builder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packe
 	}
 
 	// Compile the artifact list
-	return vmwcommon.NewArtifact(b.config.RemoteType, b.config.Format, exportOutputPath,
+	artifact, err := vmwcommon.NewArtifact(b.config.RemoteType, b.config.Format, exportOutputPath,
 		b.config.VMName, b.config.SkipExport, b.config.KeepRegistered, state)
+	if err != nil {
+		return nil, err
+	}
+	return outputstore.Upload(ui, &b.config.OutputStore, common.WithUnverifiedDownloads(artifact, state))
 }
 
 func (b *Builder) Cancel() {
@@ -256,6 +256,39 @@ func TestBuilderPrepare_Format(t *testing.T) {
 	}
 }
 
+func TestBuilderPrepare_OVFMetadata(t *testing.T) {
+	var b Builder
+	config := testConfig()
+	config["ovf_metadata"] = map[string]interface{}{
+		"product":    "packer",
+		"properties": map[string]string{"key": "value"},
+	}
+
+	// Bad
+	config["format"] = "vmx"
+	warns, err := b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Good
+	config["format"] = "ova"
+	b = Builder{}
+	warns, err = b.Prepare(config)
+	if len(warns) > 0 {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+	if b.config.OVFMetadata.Product != "packer" || b.config.OVFMetadata.Properties["key"] != "value" {
+		t.Fatalf("bad: %#v", b.config.OVFMetadata)
+	}
+}
+
 func TestBuilderPrepare_InvalidKey(t *testing.T) {
 	var b Builder
 	config := testConfig()
@@ -9,6 +9,7 @@ import (
 	vmwcommon "github.com/hashicorp/packer/builder/vmware/common"
 	"github.com/hashicorp/packer/common"
 	"github.com/hashicorp/packer/common/bootcommand"
+	"github.com/hashicorp/packer/common/guestip"
 	"github.com/hashicorp/packer/helper/config"
 	"github.com/hashicorp/packer/packer"
 	"github.com/hashicorp/packer/template/interpolate"
@@ -19,16 +20,19 @@ type Config struct {
 	common.HTTPConfig        `mapstructure:",squash"`
 	common.ISOConfig         `mapstructure:",squash"`
 	common.FloppyConfig      `mapstructure:",squash"`
+	common.ZeroFillConfig    `mapstructure:",squash"`
 	bootcommand.VNCConfig    `mapstructure:",squash"`
 	vmwcommon.DriverConfig   `mapstructure:",squash"`
 	vmwcommon.HWConfig       `mapstructure:",squash"`
 	vmwcommon.OutputConfig   `mapstructure:",squash"`
 	vmwcommon.RunConfig      `mapstructure:",squash"`
 	vmwcommon.ShutdownConfig `mapstructure:",squash"`
+	common.GuestOSConfig     `mapstructure:",squash"`
 	vmwcommon.SSHConfig      `mapstructure:",squash"`
 	vmwcommon.ToolsConfig    `mapstructure:",squash"`
 	vmwcommon.VMXConfig      `mapstructure:",squash"`
 	vmwcommon.ExportConfig   `mapstructure:",squash"`
+	GuestIP                  guestip.Config `mapstructure:",squash"`
 
 	// disk drives
 	AdditionalDiskSize []uint `mapstructure:"disk_additional_size"`
@@ -82,12 +86,16 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 		c.OutputConfig.Prepare(&c.ctx, &c.PackerConfig)...)
 	errs = packer.MultiErrorAppend(errs, c.RunConfig.Prepare(&c.ctx)...)
 	errs = packer.MultiErrorAppend(errs, c.ShutdownConfig.Prepare(&c.ctx)...)
+	errs = packer.MultiErrorAppend(errs, c.GuestOSConfig.Prepare()...)
 	errs = packer.MultiErrorAppend(errs, c.SSHConfig.Prepare(&c.ctx)...)
+	errs = packer.MultiErrorAppend(errs, c.ZeroFillConfig.Prepare(c.SSHConfig.Comm.Type)...)
 	errs = packer.MultiErrorAppend(errs, c.ToolsConfig.Prepare(&c.ctx)...)
 	errs = packer.MultiErrorAppend(errs, c.VMXConfig.Prepare(&c.ctx)...)
 	errs = packer.MultiErrorAppend(errs, c.FloppyConfig.Prepare(&c.ctx)...)
 	errs = packer.MultiErrorAppend(errs, c.VNCConfig.Prepare(&c.ctx)...)
 	errs = packer.MultiErrorAppend(errs, c.ExportConfig.Prepare(&c.ctx)...)
+	errs = packer.MultiErrorAppend(errs,
+		c.GuestIP.Prepare(guestip.DHCP, guestip.Tools, guestip.ARP)...)
 
 	if c.DiskName == "" {
 		c.DiskName = "disk"
@@ -181,6 +189,15 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 		errs = packer.MultiErrorAppend(errs, err)
 	}
 
+	if c.RemoteType != "" && c.SkipExport && !c.OutputStore.Empty() {
+		errs = packer.MultiErrorAppend(errs,
+			fmt.Errorf("output_store can't be set with skip_export, the files stay on the remote host"))
+	}
+
+	if c.ShutdownCommand == "" {
+		c.ShutdownCommand = c.GuestOSConfig.DefaultShutdownCommand(&c.SSHConfig.Comm)
+	}
+
 	// Warnings
 	if c.ShutdownCommand == "" {
 		warnings = append(warnings,
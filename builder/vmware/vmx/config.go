@@ -7,6 +7,7 @@ import (
 	vmwcommon "github.com/hashicorp/packer/builder/vmware/common"
 	"github.com/hashicorp/packer/common"
 	"github.com/hashicorp/packer/common/bootcommand"
+	"github.com/hashicorp/packer/common/guestip"
 	"github.com/hashicorp/packer/helper/config"
 	"github.com/hashicorp/packer/packer"
 	"github.com/hashicorp/packer/template/interpolate"
@@ -17,15 +18,18 @@ type Config struct {
 	common.PackerConfig      `mapstructure:",squash"`
 	common.HTTPConfig        `mapstructure:",squash"`
 	common.FloppyConfig      `mapstructure:",squash"`
+	common.ZeroFillConfig    `mapstructure:",squash"`
 	bootcommand.VNCConfig    `mapstructure:",squash"`
 	vmwcommon.DriverConfig   `mapstructure:",squash"`
 	vmwcommon.OutputConfig   `mapstructure:",squash"`
 	vmwcommon.RunConfig      `mapstructure:",squash"`
 	vmwcommon.ShutdownConfig `mapstructure:",squash"`
+	common.GuestOSConfig     `mapstructure:",squash"`
 	vmwcommon.SSHConfig      `mapstructure:",squash"`
 	vmwcommon.ToolsConfig    `mapstructure:",squash"`
 	vmwcommon.VMXConfig      `mapstructure:",squash"`
 	vmwcommon.ExportConfig   `mapstructure:",squash"`
+	GuestIP                  guestip.Config `mapstructure:",squash"`
 
 	Linked     bool   `mapstructure:"linked"`
 	RemoteType string `mapstructure:"remote_type"`
@@ -64,12 +68,16 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 	errs = packer.MultiErrorAppend(errs, c.OutputConfig.Prepare(&c.ctx, &c.PackerConfig)...)
 	errs = packer.MultiErrorAppend(errs, c.RunConfig.Prepare(&c.ctx)...)
 	errs = packer.MultiErrorAppend(errs, c.ShutdownConfig.Prepare(&c.ctx)...)
+	errs = packer.MultiErrorAppend(errs, c.GuestOSConfig.Prepare()...)
 	errs = packer.MultiErrorAppend(errs, c.SSHConfig.Prepare(&c.ctx)...)
+	errs = packer.MultiErrorAppend(errs, c.ZeroFillConfig.Prepare(c.SSHConfig.Comm.Type)...)
 	errs = packer.MultiErrorAppend(errs, c.ToolsConfig.Prepare(&c.ctx)...)
 	errs = packer.MultiErrorAppend(errs, c.VMXConfig.Prepare(&c.ctx)...)
 	errs = packer.MultiErrorAppend(errs, c.FloppyConfig.Prepare(&c.ctx)...)
 	errs = packer.MultiErrorAppend(errs, c.VNCConfig.Prepare(&c.ctx)...)
 	errs = packer.MultiErrorAppend(errs, c.ExportConfig.Prepare(&c.ctx)...)
+	errs = packer.MultiErrorAppend(errs,
+		c.GuestIP.Prepare(guestip.DHCP, guestip.Tools, guestip.ARP)...)
 
 	if c.RemoteType == "" {
 		if c.SourcePath == "" {
@@ -98,6 +106,11 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 		errs = packer.MultiErrorAppend(errs, err)
 	}
 
+	if c.RemoteType != "" && c.SkipExport && !c.OutputStore.Empty() {
+		errs = packer.MultiErrorAppend(errs,
+			fmt.Errorf("output_store can't be set with skip_export, the files stay on the remote host"))
+	}
+
 	if c.Format == "" {
 		c.Format = "ovf"
 	}
@@ -107,6 +120,10 @@ func NewConfig(raws ...interface{}) (*Config, []string, error) {
 			fmt.Errorf("format must be one of ova, ovf, or vmx"))
 	}
 
+	if c.ShutdownCommand == "" {
+		c.ShutdownCommand = c.GuestOSConfig.DefaultShutdownCommand(&c.SSHConfig.Comm)
+	}
+
 	// Warnings
 	var warnings []string
 	if c.ShutdownCommand == "" {
@@ -27,13 +27,29 @@ func runCheckpoint(c *config) {
 		return
 	}
 
-	configDir, err := packer.ConfigDir()
+	params, err := checkpointParams(c)
 	if err != nil {
 		log.Printf("[ERR] Checkpoint setup error: %s", err)
 		checkpointResult <- nil
 		return
 	}
 
+	resp, err := checkpoint.Check(params)
+	if err != nil {
+		log.Printf("[ERR] Checkpoint error: %s", err)
+		resp = nil
+	}
+
+	checkpointResult <- resp
+}
+
+// checkpointParams returns the parameters of a Checkpoint request.
+func checkpointParams(c *config) (*checkpoint.CheckParams, error) {
+	configDir, err := packer.ConfigDir()
+	if err != nil {
+		return nil, err
+	}
+
 	version := packerVersion.Version
 	if packerVersion.VersionPrerelease != "" {
 		version += fmt.Sprintf("-%s", packerVersion.VersionPrerelease)
@@ -45,18 +61,12 @@ func runCheckpoint(c *config) {
 		signaturePath = ""
 	}
 
-	resp, err := checkpoint.Check(&checkpoint.CheckParams{
+	return &checkpoint.CheckParams{
 		Product:       "packer",
 		Version:       version,
 		SignatureFile: signaturePath,
 		CacheFile:     filepath.Join(configDir, "checkpoint_cache"),
-	})
-	if err != nil {
-		log.Printf("[ERR] Checkpoint error: %s", err)
-		resp = nil
-	}
-
-	checkpointResult <- resp
+	}, nil
 }
 
 // commandVersionCheck implements command.VersionCheckFunc and is used
@@ -69,15 +79,50 @@ func commandVersionCheck() (command.VersionCheckInfo, error) {
 		return zero, nil
 	}
 
+	return versionCheckInfo(info), nil
+}
+
+// commandVersionForceCheck implements command.VersionCheckFunc for
+// "packer version -check". Unlike commandVersionCheck it asks for the
+// latest version right away, even if checkpoint is disabled, since the
+// user requested it.
+func commandVersionForceCheck() (command.VersionCheckInfo, error) {
+	var zero command.VersionCheckInfo
+	c := loadedConfig
+	if c == nil {
+		c = &config{}
+	}
+
+	params, err := checkpointParams(c)
+	if err != nil {
+		return zero, err
+	}
+	params.Force = true
+	params.CacheFile = ""
+
+	info, err := checkpoint.Check(params)
+	if err != nil {
+		return zero, err
+	}
+	return versionCheckInfo(info), nil
+}
+
+func versionCheckInfo(info *checkpoint.CheckResponse) command.VersionCheckInfo {
 	// Build the alerts that we may have received about our version
 	alerts := make([]string, len(info.Alerts))
 	for i, a := range info.Alerts {
 		alerts[i] = a.Message
+		if a.Level != "" {
+			alerts[i] = fmt.Sprintf("[%s] %s", a.Level, alerts[i])
+		}
+		if a.URL != "" {
+			alerts[i] += fmt.Sprintf(" (%s)", a.URL)
+		}
 	}
 
 	return command.VersionCheckInfo{
 		Outdated: info.Outdated,
 		Latest:   info.CurrentVersion,
 		Alerts:   alerts,
-	}, nil
+	}
 }
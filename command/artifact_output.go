@@ -0,0 +1,120 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+// artifactOutput is what -artifact-output writes at the end of a run: the
+// builds and their artifacts, whether the template has post-processors
+// like the manifest one or not.
+type artifactOutput struct {
+	sync.Mutex
+	PackerRunUUID string                 `json:"packer_run_uuid,omitempty"`
+	Builds        []*artifactOutputBuild `json:"builds"`
+}
+
+type artifactOutputBuild struct {
+	Name        string                    `json:"name"`
+	BuilderType string                    `json:"builder_type"`
+	Status      string                    `json:"status"`
+	Seconds     float64                   `json:"duration_seconds"`
+	Error       string                    `json:"error,omitempty"`
+	Artifacts   []*artifactOutputArtifact `json:"artifacts"`
+}
+
+type artifactOutputArtifact struct {
+	BuilderId string   `json:"builder_id"`
+	Id        string   `json:"id"`
+	String    string   `json:"string"`
+	Files     []string `json:"files"`
+
+	// Metadata is what builders like amazon-ebs say about the artifact,
+	// like its region.
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+func newArtifactOutput() *artifactOutput {
+	return &artifactOutput{
+		PackerRunUUID: os.Getenv("PACKER_RUN_UUID"),
+		Builds:        []*artifactOutputBuild{},
+	}
+}
+
+// Add records a finished build.
+func (o *artifactOutput) Add(name, builderType, status string, d time.Duration, artifacts []packer.Artifact, err error) {
+	b := &artifactOutputBuild{
+		Name:        name,
+		BuilderType: builderType,
+		Status:      status,
+		Seconds:     d.Seconds(),
+		Artifacts:   []*artifactOutputArtifact{},
+	}
+	if err != nil {
+		b.Error = err.Error()
+	}
+	for _, a := range artifacts {
+		if a == nil {
+			continue
+		}
+		files := a.Files()
+		if files == nil {
+			files = []string{}
+		}
+		b.Artifacts = append(b.Artifacts, &artifactOutputArtifact{
+			BuilderId: a.BuilderId(),
+			Id:        a.Id(),
+			String:    a.String(),
+			Files:     files,
+			Metadata:  artifactMetadata(a.State("atlas.artifact.metadata")),
+		})
+	}
+
+	o.Lock()
+	defer o.Unlock()
+	o.Builds = append(o.Builds, b)
+}
+
+// artifactMetadata is the metadata of an artifact as strings. The artifacts
+// of plugins have it decoded as maps of interfaces.
+func artifactMetadata(raw interface{}) map[string]string {
+	metadata := make(map[string]string)
+	switch v := raw.(type) {
+	case map[string]string:
+		return v
+	case map[string]interface{}:
+		for k, value := range v {
+			metadata[k] = fmt.Sprint(value)
+		}
+	case map[interface{}]interface{}:
+		for k, value := range v {
+			metadata[fmt.Sprint(k)] = fmt.Sprint(value)
+		}
+	}
+	if len(metadata) == 0 {
+		return nil
+	}
+	return metadata
+}
+
+// Write writes the builds to the file, sorted by name.
+func (o *artifactOutput) Write(path string) error {
+	o.Lock()
+	defer o.Unlock()
+
+	sort.Slice(o.Builds, func(i, j int) bool {
+		return o.Builds[i].Name < o.Builds[j].Name
+	})
+	raw, err := json.MarshalIndent(o, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(raw, '\n'), 0644)
+}
@@ -0,0 +1,91 @@
+package command
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestBuildArtifactOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "artifacts.json")
+
+	c := &BuildCommand{
+		Meta: testMetaFile(t),
+	}
+	args := []string{
+		"-only=vanilla,chocolate",
+		"-artifact-output=" + path,
+		filepath.Join(testFixture("build-only"), "template.json"),
+	}
+
+	defer cleanup()
+	if code := c.Run(args); code != 0 {
+		fatalCommand(t, c.Meta)
+	}
+
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var output artifactOutput
+	if err := json.Unmarshal(raw, &output); err != nil {
+		t.Fatalf("bad: %s\n%s", err, raw)
+	}
+
+	if len(output.Builds) != 2 {
+		t.Fatalf("bad: %s", raw)
+	}
+	for i, name := range []string{"chocolate", "vanilla"} {
+		b := output.Builds[i]
+		if b.Name != name || b.BuilderType != "file" || b.Status != "success" || b.Error != "" {
+			t.Fatalf("bad: %#v", b)
+		}
+		if len(b.Artifacts) != 1 {
+			t.Fatalf("bad: %#v", b.Artifacts)
+		}
+		a := b.Artifacts[0]
+		if a.BuilderId != "packer.file" || a.Id != "File" {
+			t.Fatalf("bad: %#v", a)
+		}
+		if !reflect.DeepEqual(a.Files, []string{name + ".txt"}) {
+			t.Fatalf("bad: %#v", a.Files)
+		}
+	}
+}
+
+func TestArtifactMetadata(t *testing.T) {
+	cases := []struct {
+		raw      interface{}
+		expected map[string]string
+	}{
+		{nil, nil},
+		{"foo", nil},
+		{map[string]string{}, map[string]string{}},
+		{
+			map[string]string{"region.us-east-1": "ami-1234"},
+			map[string]string{"region.us-east-1": "ami-1234"},
+		},
+		{
+			map[string]interface{}{"size": 10},
+			map[string]string{"size": "10"},
+		},
+		{
+			map[interface{}]interface{}{"region.us-east-1": "ami-1234"},
+			map[string]string{"region.us-east-1": "ami-1234"},
+		},
+	}
+
+	for _, tc := range cases {
+		if actual := artifactMetadata(tc.raw); !reflect.DeepEqual(actual, tc.expected) {
+			t.Fatalf("%#v: bad: %#v", tc.raw, actual)
+		}
+	}
+}
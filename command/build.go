@@ -3,15 +3,17 @@ package command
 import (
 	"bytes"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
-	"os/signal"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
-	"syscall"
+	"time"
 
 	"github.com/hashicorp/packer/helper/enumflag"
+	"github.com/hashicorp/packer/helper/flag-slice"
 	"github.com/hashicorp/packer/packer"
 	"github.com/hashicorp/packer/template"
 
@@ -23,17 +25,35 @@ type BuildCommand struct {
 }
 
 func (c *BuildCommand) Run(args []string) int {
-	var cfgColor, cfgDebug, cfgForce, cfgTimestamp, cfgParallel bool
-	var cfgOnError string
+	var cfgColor, cfgDebug, cfgForce, cfgTimestamp, cfgParallel, cfgParallelPP, cfgShowVars bool
+	var cfgForceDownload, cfgOffline, cfgQuiet bool
+	cfgOnError := c.OnError
+	var cfgDashboard bool
+	var cfgDashboardLines int
+	var cfgCleanupTimeout time.Duration
+	var cfgDebugSkip []string
+	var cfgDebugTrace string
+	var cfgArtifactOutput string
 	flags := c.Meta.FlagSet("build", FlagSetBuildFilter|FlagSetVars)
 	flags.Usage = func() { c.Ui.Say(c.Help()) }
+	flags.StringVar(&cfgArtifactOutput, "artifact-output", "", "")
 	flags.BoolVar(&cfgColor, "color", true, "")
 	flags.BoolVar(&cfgDebug, "debug", false, "")
+	flags.Var((*sliceflag.StringFlag)(&cfgDebugSkip), "debug-skip", "")
+	flags.StringVar(&cfgDebugTrace, "debug-trace", "", "")
 	flags.BoolVar(&cfgForce, "force", false, "")
+	flags.BoolVar(&cfgForceDownload, "force-download", false, "")
+	flags.BoolVar(&cfgOffline, "offline", false, "")
 	flags.BoolVar(&cfgTimestamp, "timestamp-ui", false, "")
 	flagOnError := enumflag.New(&cfgOnError, "cleanup", "abort", "ask")
 	flags.Var(flagOnError, "on-error", "")
 	flags.BoolVar(&cfgParallel, "parallel", true, "")
+	flags.BoolVar(&cfgParallelPP, "parallel-post-processors", false, "")
+	flags.BoolVar(&cfgQuiet, "quiet", false, "")
+	flags.BoolVar(&cfgShowVars, "show-vars", false, "")
+	flags.BoolVar(&cfgDashboard, "dashboard", true, "")
+	flags.IntVar(&cfgDashboardLines, "dashboard-lines", 0, "")
+	flags.DurationVar(&cfgCleanupTimeout, "cleanup-timeout", defaultCleanupTimeout, "")
 	if err := flags.Parse(args); err != nil {
 		return 1
 	}
@@ -44,6 +64,11 @@ func (c *BuildCommand) Run(args []string) int {
 		return 1
 	}
 
+	if cfgForceDownload && cfgOffline {
+		c.Ui.Error("-force-download and -offline can't be used together.")
+		return 1
+	}
+
 	// Parse the template
 	var tpl *template.Template
 	var err error
@@ -53,6 +78,11 @@ func (c *BuildCommand) Run(args []string) int {
 		return 1
 	}
 
+	if err := c.Meta.LoadAutoVarFiles(tpl); err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
 	// Get the core
 	core, err := c.Meta.Core(tpl)
 	if err != nil {
@@ -60,8 +90,16 @@ func (c *BuildCommand) Run(args []string) int {
 		return 1
 	}
 
+	if cfgShowVars {
+		c.Meta.ShowVars(core)
+	}
+
 	// Get the builds we care about
-	buildNames := c.Meta.BuildNames(core)
+	buildNames, err := c.Meta.BuildNames(core)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
 	builds := make([]packer.Build, 0, len(buildNames))
 	for _, n := range buildNames {
 		b, err := core.Build(n)
@@ -79,6 +117,32 @@ func (c *BuildCommand) Run(args []string) int {
 		c.Ui.Say("Debug mode enabled. Builds will not be parallelized.")
 	}
 
+	// The builds append to the trace, it starts empty. The builders don't
+	// necessarily run in the same directory.
+	if cfgDebugTrace != "" {
+		if cfgDebugTrace, err = filepath.Abs(cfgDebugTrace); err == nil {
+			err = ioutil.WriteFile(cfgDebugTrace, nil, 0644)
+		}
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error creating the step trace: %s", err))
+			return 1
+		}
+	}
+
+	// The artifacts are written at the end, to the path as it is now, and
+	// an unwritable one fails before the builds start.
+	var output *artifactOutput
+	if cfgArtifactOutput != "" {
+		if cfgArtifactOutput, err = filepath.Abs(cfgArtifactOutput); err == nil {
+			output = newArtifactOutput()
+			err = output.Write(cfgArtifactOutput)
+		}
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Error creating the artifact output: %s", err))
+			return 1
+		}
+	}
+
 	// Compile all the UIs for the builds
 	colors := [5]packer.UiColor{
 		packer.UiColorGreen,
@@ -88,42 +152,81 @@ func (c *BuildCommand) Run(args []string) int {
 		packer.UiColorBlue,
 	}
 	buildUis := make(map[string]packer.Ui)
-	for i, b := range buildNames {
-		var ui packer.Ui
-		ui = c.Ui
-		if cfgColor {
-			ui = &packer.ColoredUi{
-				Color: colors[i%len(colors)],
-				Ui:    ui,
-			}
-			if _, ok := c.Ui.(*packer.MachineReadableUi); !ok {
-				ui.Say(fmt.Sprintf("%s output will be in this color.", b))
-				if i+1 == len(buildNames) {
-					// Add a newline between the color output and the actual output
-					c.Ui.Say("")
+
+	// Show a dashboard instead of interleaving the output of the builds
+	// when they run at the same time on a terminal. Asking on error needs
+	// the plain output to prompt, and timestamps and machine-readable
+	// output need each line as it comes. The rest of the output goes
+	// through the dashboard while it is shown.
+	var dashboard *buildDashboard
+	if out, ok := useDashboard(c.Ui, len(builds)); ok && cfgDashboard && cfgParallel && !cfgDebug && !cfgTimestamp && cfgOnError != "ask" {
+		dashboard = newBuildDashboard(out, cfgColor && os.Getenv("PACKER_NO_COLOR") == "", cfgDashboardLines)
+		dashboard.level = c.Ui.(*packer.BasicUi).Level
+		c.Ui = dashboard.Wrap(c.Ui)
+		for _, b := range buildNames {
+			buildUis[b] = dashboard.Ui(b)
+		}
+	} else {
+		for i, b := range buildNames {
+			var ui packer.Ui
+			ui = c.Ui
+			if cfgColor {
+				ui = &packer.ColoredUi{
+					Color: colors[i%len(colors)],
+					Ui:    ui,
 				}
-				// Now add timestamps if requested
-				if cfgTimestamp {
-					ui = &packer.TimestampedUi{
-						Ui: ui,
+				if _, ok := c.Ui.(*packer.MachineReadableUi); !ok {
+					ui.Say(fmt.Sprintf("%s output will be in this color.", b))
+					if i+1 == len(buildNames) {
+						// Add a newline between the color output and the actual output
+						c.Ui.Say("")
 					}
 				}
 			}
+
+			// Now add timestamps if requested, machine-readable output
+			// already has them.
+			if _, ok := c.Ui.(*packer.MachineReadableUi); !ok && cfgTimestamp {
+				ui = &packer.TimestampedUi{
+					Ui: ui,
+				}
+			}
+
+			buildUis[b] = ui
 		}
+	}
 
-		buildUis[b] = ui
+	// Count how the builds use the download cache, to sum it up at the end
+	downloads := newDownloadStats()
+	for b, ui := range buildUis {
+		buildUis[b] = downloads.Ui(ui)
+		if cfgQuiet {
+			buildUis[b] = &quietUi{Ui: buildUis[b]}
+		}
 	}
 
 	log.Printf("Build debug mode: %v", cfgDebug)
 	log.Printf("Force build: %v", cfgForce)
+	log.Printf("Force download: %v", cfgForceDownload)
+	log.Printf("Offline: %v", cfgOffline)
 	log.Printf("On error: %v", cfgOnError)
+	log.Printf("Debug skip: %v", cfgDebugSkip)
+	log.Printf("Debug trace: %v", cfgDebugTrace)
+	log.Printf("Artifact output: %v", cfgArtifactOutput)
+	log.Printf("Parallel post-processors: %v", cfgParallelPP)
+	log.Printf("Quiet: %v", cfgQuiet)
 
 	// Set the debug and force mode and prepare all the builds
 	for _, b := range builds {
 		log.Printf("Preparing build: %s", b.Name())
 		b.SetDebug(cfgDebug)
 		b.SetForce(cfgForce)
+		b.SetForceDownload(cfgForceDownload)
+		b.SetOffline(cfgOffline)
 		b.SetOnError(cfgOnError)
+		b.SetDebugSkip(cfgDebugSkip)
+		b.SetDebugTrace(cfgDebugTrace)
+		b.SetParallelPostProcessors(cfgParallelPP)
 
 		warnings, err := b.Prepare()
 		if err != nil {
@@ -132,51 +235,44 @@ func (c *BuildCommand) Run(args []string) int {
 		}
 		if len(warnings) > 0 {
 			ui := buildUis[b.Name()]
-			ui.Say(fmt.Sprintf("Warnings for build '%s':\n", b.Name()))
+			packer.UiLog(ui, packer.UiLevelWarn, fmt.Sprintf("Warnings for build '%s':\n", b.Name()))
 			for _, warning := range warnings {
-				ui.Say(fmt.Sprintf("* %s", warning))
+				packer.UiLog(ui, packer.UiLevelWarn, fmt.Sprintf("* %s", warning))
 			}
-			ui.Say("")
+			packer.UiLog(ui, packer.UiLevelWarn, "")
 		}
 	}
 
-	// Run all the builds in parallel and wait for them to complete
-	var interruptWg, wg sync.WaitGroup
-	interrupted := false
+	if dashboard != nil {
+		dashboard.Start()
+	}
+
+	// Run all the builds in parallel and wait for them to complete. When
+	// interrupted, the builds are cancelled and have until the cleanup
+	// deadline to clean up.
+	interrupts := newInterruptHandler(c.Ui, cfgCleanupTimeout)
+	defer interrupts.Stop()
+	var wg sync.WaitGroup
 	var artifacts = struct {
 		sync.RWMutex
 		m map[string][]packer.Artifact
 	}{m: make(map[string][]packer.Artifact)}
 	errors := make(map[string]error)
-	// ctx := context.Background()
+	finished := true
 	for _, b := range builds {
 		// Increment the waitgroup so we wait for this item to finish properly
 		wg.Add(1)
-		// buildCtx, cancelCtx := ctx.WithCancel()
-
-		// Handle interrupts for this build
-		sigCh := make(chan os.Signal, 1)
-		signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
-		defer signal.Stop(sigCh)
-		go func(b packer.Build) {
-			<-sigCh
-			interruptWg.Add(1)
-			defer interruptWg.Done()
-			interrupted = true
-
-			log.Printf("Stopping build: %s", b.Name())
-			b.Cancel()
-			//cancelCtx()
-			log.Printf("Build cancelled: %s", b.Name())
-		}(b)
+		interrupts.Start(b)
 
 		// Run the build in a goroutine
 		go func(b packer.Build) {
 			defer wg.Done()
+			defer interrupts.Done(b)
 
 			name := b.Name()
 			log.Printf("Starting build run: %s", name)
 			ui := buildUis[name]
+			start := time.Now()
 			runArtifacts, err := b.Run(ui, c.Cache)
 
 			if err != nil {
@@ -188,37 +284,63 @@ func (c *BuildCommand) Run(args []string) int {
 				artifacts.m[name] = runArtifacts
 				artifacts.Unlock()
 			}
+
+			d := time.Since(start)
+			c.notify(ui, core, name, d, runArtifacts, err, interrupts.Interrupted())
+			if output != nil {
+				status := buildStatus(err, interrupts.Interrupted())
+				output.Add(name, builderType(core, name), status, d, runArtifacts, err)
+			}
+
+			if dashboard != nil {
+				dashboard.Finish(name, err)
+			}
 		}(b)
 
 		if cfgDebug {
 			log.Printf("Debug enabled, so waiting for build to finish: %s", b.Name())
-			wg.Wait()
+			finished = interrupts.Wait(&wg)
 		}
 
 		if !cfgParallel {
 			log.Printf("Parallelization disabled, waiting for build to finish: %s", b.Name())
-			wg.Wait()
+			finished = interrupts.Wait(&wg)
 		}
 
-		if interrupted {
+		if interrupts.Interrupted() {
 			log.Println("Interrupted, not going to start any more builds.")
 			break
 		}
 	}
 
-	// Wait for both the builds to complete and the interrupt handler,
-	// if it is interrupted.
+	// Wait for the builds to complete, or for the cleanup deadline if
+	// interrupted.
 	log.Printf("Waiting on builds to complete...")
-	wg.Wait()
+	if finished {
+		finished = interrupts.Wait(&wg)
+	}
 
-	log.Printf("Builds completed. Waiting on interrupt barrier...")
-	interruptWg.Wait()
+	if dashboard != nil {
+		dashboard.Stop()
+	}
 
-	if interrupted {
-		c.Ui.Say("Cleanly cancelled builds after being interrupted.")
+	// The builds that finished are written even when interrupted
+	if output != nil {
+		if err := output.Write(cfgArtifactOutput); err != nil {
+			c.Ui.Error(fmt.Sprintf("Error writing the artifact output: %s", err))
+		}
+	}
+
+	if interrupts.Interrupted() {
+		reportLeftovers(c.Ui, interrupts.Running())
+		if finished {
+			c.Ui.Say("Cleanly cancelled builds after being interrupted.")
+		}
 		return 1
 	}
 
+	downloads.Report(c.Ui)
+
 	if len(errors) > 0 {
 		c.Ui.Machine("error-count", strconv.FormatInt(int64(len(errors)), 10))
 
@@ -234,6 +356,8 @@ func (c *BuildCommand) Run(args []string) int {
 
 			c.Ui.Error(fmt.Sprintf("--> %s: %s", name, err))
 		}
+
+		reportLeftovers(c.Ui, nil)
 	}
 
 	if len(artifacts.m) > 0 {
@@ -292,6 +416,40 @@ func (c *BuildCommand) Run(args []string) int {
 	return 0
 }
 
+// notify sends the notifications of the Packer configuration about the
+// build that is done. Failing to notify doesn't fail the build.
+func (c *BuildCommand) notify(ui packer.Ui, core *packer.Core, name string, d time.Duration, artifacts []packer.Artifact, err error, interrupted bool) {
+	if len(c.CoreConfig.Notifications) == 0 {
+		return
+	}
+
+	status := buildStatus(err, interrupted)
+	result := packer.NewBuildResult(name, builderType(core, name), status, d, artifacts, err)
+	if err := packer.SendNotifications(c.CoreConfig.Notifications, result); err != nil {
+		packer.UiLog(ui, packer.UiLevelWarn, fmt.Sprintf("Warning: failed to send notifications for '%s': %s", name, err))
+	}
+}
+
+// buildStatus is the status of a finished build.
+func buildStatus(err error, interrupted bool) string {
+	switch {
+	case interrupted:
+		return packer.BuildStatusCancelled
+	case err != nil:
+		return packer.BuildStatusFailure
+	}
+	return packer.BuildStatusSuccess
+}
+
+// builderType is the type of the builder of the build, its name if the
+// template doesn't say.
+func builderType(core *packer.Core, name string) string {
+	if b, ok := core.Template.Builders[name]; ok {
+		return b.Type
+	}
+	return name
+}
+
 func (*BuildCommand) Help() string {
 	helpText := `
 Usage: packer build [options] TEMPLATE
@@ -301,17 +459,30 @@ Usage: packer build [options] TEMPLATE
 
 Options:
 
+  -artifact-output=path         Write the builds and their artifacts to this file, as JSON.
+  -cleanup-timeout=15m          When interrupted, how long to wait for the builds to clean up. 0 waits as long as it takes.
   -color=false                  Disable color output. (Default: color)
   -debug                        Debug mode enabled for builds.
-  -except=foo,bar,baz           Build all builds other than these.
-  -only=foo,bar,baz             Build only the specified builds.
+  -debug-skip=StepA,StepB       Skip these steps of the builds, and don't clean them up.
+  -debug-trace=path             Write when each step starts and ends to this file, as JSON lines.
+  -except=foo,bar,baz           Build all builds other than these. Globs and /regexps/ match names and builder types.
+  -only=foo,bar,baz             Build only the specified builds. Globs and /regexps/ match names and builder types.
   -force                        Force a build to continue if artifacts exist, deletes existing artifacts.
+  -force-download               Download the files the builds need again, even if they are in the cache.
   -machine-readable             Produce machine-readable output.
+  -offline                      Fail instead of downloading the files the builds need that aren't in the cache.
   -on-error=[cleanup|abort|ask] If the build fails do: clean up (default), abort, or ask.
   -parallel=false               Disable parallelization. (Default: parallel)
-  -timestamp-ui                 Enable prefixing of each ui output with an RFC3339 timestamp.
+  -parallel-post-processors     Run the post-processor chains of each build in parallel.
+  -quiet                        Only show the steps, warnings and errors of the builds, and the artifacts.
+                                The stdout of the provisioners is still logged.
+  -dashboard=false              Interleave the output of parallel builds instead of showing a live status per build.
+  -dashboard-lines=N            Show the last N lines of output under each build on the dashboard.
+  -show-vars                    Print the variables the builds use, with sensitive values masked.
+  -timestamp-ui                 Enable prefixing of each ui output with an RFC3339 timestamp, without the dashboard.
   -var 'key=value'              Variable for templates, can be used multiple times.
-  -var-file=path                JSON file containing user variables.
+  -var-file=path                JSON or YAML file containing user variables, can be used multiple times.
+                                *.auto.pkrvars.json and .yaml files next to the template are loaded first.
 `
 
 	return strings.TrimSpace(helpText)
@@ -322,21 +493,32 @@ func (*BuildCommand) Synopsis() string {
 }
 
 func (*BuildCommand) AutocompleteArgs() complete.Predictor {
-	return complete.PredictNothing
+	return predictTemplates
 }
 
 func (*BuildCommand) AutocompleteFlags() complete.Flags {
 	return complete.Flags{
-		"-color":            complete.PredictNothing,
-		"-debug":            complete.PredictNothing,
-		"-except":           complete.PredictNothing,
-		"-only":             complete.PredictNothing,
-		"-force":            complete.PredictNothing,
-		"-machine-readable": complete.PredictNothing,
-		"-on-error":         complete.PredictNothing,
-		"-parallel":         complete.PredictNothing,
-		"-timestamp-ui":     complete.PredictNothing,
-		"-var":              complete.PredictNothing,
-		"-var-file":         complete.PredictNothing,
+		"-artifact-output":          complete.PredictFiles("*"),
+		"-cleanup-timeout":          complete.PredictNothing,
+		"-color":                    complete.PredictNothing,
+		"-dashboard":                complete.PredictNothing,
+		"-dashboard-lines":          complete.PredictNothing,
+		"-debug":                    complete.PredictNothing,
+		"-debug-skip":               complete.PredictNothing,
+		"-debug-trace":              complete.PredictFiles("*"),
+		"-except":                   predictBuildNames,
+		"-only":                     predictBuildNames,
+		"-force":                    complete.PredictNothing,
+		"-force-download":           complete.PredictNothing,
+		"-machine-readable":         complete.PredictNothing,
+		"-offline":                  complete.PredictNothing,
+		"-on-error":                 complete.PredictSet("cleanup", "abort", "ask"),
+		"-parallel":                 complete.PredictNothing,
+		"-parallel-post-processors": complete.PredictNothing,
+		"-quiet":                    complete.PredictNothing,
+		"-show-vars":                complete.PredictNothing,
+		"-timestamp-ui":             complete.PredictNothing,
+		"-var":                      complete.PredictNothing,
+		"-var-file":                 predictVarFiles,
 	}
 }
@@ -0,0 +1,442 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/packer"
+	"github.com/mattn/go-isatty"
+	"github.com/mattn/go-runewidth"
+)
+
+// dashboardRefresh is how often the dashboard is drawn again.
+const dashboardRefresh = 250 * time.Millisecond
+
+// buildDashboard shows one status line per build while builds run in
+// parallel on a terminal, instead of interleaving their output. The output
+// of each build is kept and printed in one piece above the dashboard once
+// the build is done, so the logs are still all there afterwards.
+type buildDashboard struct {
+	out   io.Writer
+	color bool
+
+	// logLines is how many of the last lines of output to show under
+	// each running build.
+	logLines int
+
+	// level is the verbosity of the messages of the builds.
+	level packer.UiLevel
+
+	lock   sync.Mutex
+	builds []*dashboardBuild
+
+	// shown is set while the dashboard is drawn, above is the output that
+	// isn't of a build to print above it.
+	shown bool
+	above []string
+
+	drawn  int
+	tick   int
+	stopCh chan struct{}
+	doneCh chan struct{}
+}
+
+// dashboardBuild is the packer.Ui of one build on the dashboard.
+type dashboardBuild struct {
+	d     *buildDashboard
+	name  string
+	start time.Time
+	end   time.Time
+	err   error
+	done  bool
+
+	// status is the last announcement of the build, detail the last
+	// message since.
+	status string
+	detail string
+
+	// The lines of output not printed yet, and the last ones for
+	// logLines.
+	pending []string
+	recent  []string
+
+	progressTotal   int64
+	progressCurrent int64
+}
+
+var _ packer.Ui = new(dashboardBuild)
+
+// useDashboard says whether the output of the builds should go to a
+// dashboard: when there is more than one build running at the same time,
+// and the output is a terminal.
+func useDashboard(ui packer.Ui, builds int) (io.Writer, bool) {
+	basic, ok := ui.(*packer.BasicUi)
+	if !ok || builds < 2 {
+		return nil, false
+	}
+	f, ok := basic.Writer.(*os.File)
+	if !ok || !isatty.IsTerminal(f.Fd()) {
+		return nil, false
+	}
+	return f, true
+}
+
+func newBuildDashboard(out io.Writer, color bool, logLines int) *buildDashboard {
+	return &buildDashboard{
+		out:      out,
+		color:    color,
+		logLines: logLines,
+		stopCh:   make(chan struct{}),
+		doneCh:   make(chan struct{}),
+	}
+}
+
+// Ui returns the UI the build with the given name writes to.
+func (d *buildDashboard) Ui(name string) packer.Ui {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	b := &dashboardBuild{
+		d:     d,
+		name:  name,
+		start: time.Now(),
+	}
+	d.builds = append(d.builds, b)
+	return b
+}
+
+// Finish marks the build as done, failed if err is set.
+func (d *buildDashboard) Finish(name string, err error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	for _, b := range d.builds {
+		if b.name == name {
+			b.done = true
+			b.err = err
+			b.end = time.Now()
+		}
+	}
+}
+
+// Wrap returns a Ui for the output that isn't of a build, like the one
+// about interrupts, which is printed above the dashboard while it is shown
+// so the redraws don't mix with it.
+func (d *buildDashboard) Wrap(ui packer.Ui) packer.Ui {
+	return &dashboardUi{Ui: ui, d: d}
+}
+
+// Start draws the dashboard until Stop is called.
+func (d *buildDashboard) Start() {
+	d.lock.Lock()
+	d.shown = true
+	d.lock.Unlock()
+
+	go func() {
+		defer close(d.doneCh)
+
+		ticker := time.NewTicker(dashboardRefresh)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				d.draw()
+			case <-d.stopCh:
+				d.lock.Lock()
+				d.shown = false
+				d.lock.Unlock()
+				d.draw()
+				return
+			}
+		}
+	}()
+}
+
+// Stop draws the dashboard one last time, with all the output left, and
+// stops updating it.
+func (d *buildDashboard) Stop() {
+	close(d.stopCh)
+	<-d.doneCh
+}
+
+func (d *buildDashboard) draw() {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	width, _, err := common.GetTerminalDimensions()
+	if err != nil || width <= 0 {
+		width = 80
+	}
+
+	var buf strings.Builder
+
+	// Go back to the top of the dashboard and clear it
+	if d.drawn > 0 {
+		fmt.Fprintf(&buf, "\x1b[%dA\x1b[J", d.drawn)
+	}
+
+	// The output of the builds that are done, and the one that isn't of a
+	// build, goes above the dashboard and stays there.
+	for _, line := range d.above {
+		buf.WriteString(line + "\n")
+	}
+	d.above = nil
+	for _, b := range d.builds {
+		if b.done && len(b.pending) > 0 {
+			for _, line := range b.pending {
+				buf.WriteString(line + "\n")
+			}
+			b.pending = nil
+		}
+	}
+
+	nameWidth := 0
+	for _, b := range d.builds {
+		if w := runewidth.StringWidth(b.name); w > nameWidth {
+			nameWidth = w
+		}
+	}
+
+	d.tick++
+	d.drawn = 0
+	for _, b := range d.builds {
+		for _, line := range d.buildLines(b, nameWidth) {
+			buf.WriteString(runewidth.Truncate(line, width-1, "") + "\x1b[0m\n")
+			d.drawn++
+		}
+	}
+
+	if _, err := io.WriteString(d.out, buf.String()); err != nil {
+		log.Printf("[ERR] Failed to write the dashboard: %s", err)
+	}
+}
+
+// buildLines returns the status line of the build, followed by its last
+// lines of output while it runs if asked for.
+func (d *buildDashboard) buildLines(b *dashboardBuild, nameWidth int) []string {
+	state, color := "running", "\x1b[36m"
+	end := time.Now()
+	switch {
+	case b.done && b.err != nil:
+		state, color = "failed", "\x1b[31m"
+		end = b.end
+	case b.done:
+		state, color = "done", "\x1b[32m"
+		end = b.end
+	}
+	if !d.color {
+		color = ""
+	}
+
+	spinner := " "
+	if !b.done {
+		spinner = string(`|/-\`[d.tick%4])
+	}
+
+	text := b.status
+	if b.detail != "" {
+		text += ": " + b.detail
+	}
+	if b.progressTotal > 0 && !b.done {
+		text = fmt.Sprintf("%3d%% %s", b.progressCurrent*100/b.progressTotal, text)
+	}
+
+	lines := []string{fmt.Sprintf("%s %s%-7s%s %s %8s  %s",
+		spinner, color, state, resetColor(d.color),
+		runewidth.FillRight(b.name, nameWidth),
+		end.Sub(b.start).Round(time.Second), text)}
+
+	if !b.done && d.logLines > 0 {
+		recent := b.recent
+		if len(recent) > d.logLines {
+			recent = recent[len(recent)-d.logLines:]
+		}
+		for _, line := range recent {
+			lines = append(lines, "    "+line)
+		}
+	}
+
+	return lines
+}
+
+func resetColor(color bool) string {
+	if color {
+		return "\x1b[0m"
+	}
+	return ""
+}
+
+// add records the output of the build, message is already prefixed by the
+// name of the build.
+func (b *dashboardBuild) add(message string, status bool) {
+	b.d.lock.Lock()
+	defer b.d.lock.Unlock()
+
+	lines := strings.Split(message, "\n")
+	b.pending = append(b.pending, lines...)
+	b.recent = append(b.recent, lines...)
+	if len(b.recent) > 100 {
+		b.recent = b.recent[len(b.recent)-100:]
+	}
+
+	// Keep the last line with something to say, without the prefix
+	last := ""
+	for _, line := range lines {
+		if text := strings.TrimSpace(stripTarget(line, b.name)); text != "" {
+			last = text
+		}
+	}
+	if last == "" {
+		return
+	}
+	if status {
+		b.status = last
+		b.detail = ""
+	} else {
+		b.detail = last
+	}
+}
+
+// stripTarget removes the "==> name: " prefix that the build adds.
+func stripTarget(line, name string) string {
+	line = strings.TrimLeft(strings.TrimPrefix(line, "==>"), " ")
+	return strings.TrimPrefix(line, name+":")
+}
+
+func (b *dashboardBuild) Ask(string) (string, error) {
+	return "", errors.New("can't ask for input while the build dashboard is shown")
+}
+
+func (b *dashboardBuild) Say(message string) {
+	log.Printf("ui: %s", message)
+	if b.d.level.Shows(packer.UiLevelInfo) {
+		b.add(message, true)
+	}
+}
+
+func (b *dashboardBuild) Message(message string) {
+	log.Printf("ui: %s", message)
+	if b.d.level.Shows(packer.UiLevelInfo) {
+		b.add(message, false)
+	}
+}
+
+func (b *dashboardBuild) Error(message string) {
+	log.Printf("ui error: %s", message)
+	b.add(message, true)
+}
+
+func (b *dashboardBuild) Log(level packer.UiLevel, message string) {
+	log.Printf("ui %s: %s", level, message)
+	if b.d.level.Shows(level) {
+		b.add(message, level >= packer.UiLevelInfo)
+	}
+}
+
+func (b *dashboardBuild) Output(stream, line string) {
+	log.Printf("ui %s: %s", stream, line)
+	if b.d.level.Shows(packer.UiLevelInfo) {
+		b.add(line, false)
+	}
+}
+
+func (b *dashboardBuild) Machine(t string, args ...string) {
+	log.Printf("machine readable: %s %#v", t, args)
+}
+
+func (b *dashboardBuild) ProgressBar() packer.ProgressBar {
+	return &dashboardProgressBar{b: b}
+}
+
+// dashboardUi prints the output above the dashboard while it is shown, and
+// with its Ui otherwise.
+type dashboardUi struct {
+	packer.Ui
+	d *buildDashboard
+}
+
+// print keeps the message for the next draw, it returns false when the
+// dashboard isn't shown.
+func (u *dashboardUi) print(message string, color string) bool {
+	u.d.lock.Lock()
+	defer u.d.lock.Unlock()
+
+	if !u.d.shown {
+		return false
+	}
+	for _, line := range strings.Split(message, "\n") {
+		if u.d.color && color != "" {
+			line = color + line + "\x1b[0m"
+		}
+		u.d.above = append(u.d.above, line)
+	}
+	return true
+}
+
+func (u *dashboardUi) Say(message string) {
+	if !u.print(message, "") {
+		u.Ui.Say(message)
+	}
+}
+
+func (u *dashboardUi) Message(message string) {
+	if !u.print(message, "") {
+		u.Ui.Message(message)
+	}
+}
+
+func (u *dashboardUi) Error(message string) {
+	if !u.print(message, "\x1b[31m") {
+		u.Ui.Error(message)
+	}
+}
+
+// dashboardProgressBar shows the progress in the status line of the build
+// since there is no room for a progress bar.
+type dashboardProgressBar struct {
+	b *dashboardBuild
+}
+
+func (p *dashboardProgressBar) Start(total int64) {
+	p.b.d.lock.Lock()
+	defer p.b.d.lock.Unlock()
+
+	p.b.progressTotal += total
+}
+
+func (p *dashboardProgressBar) Add(current int64) {
+	p.b.d.lock.Lock()
+	defer p.b.d.lock.Unlock()
+
+	p.b.progressCurrent += current
+}
+
+func (p *dashboardProgressBar) NewProxyReader(r io.Reader) io.Reader {
+	return &dashboardProxyReader{r: r, p: p}
+}
+
+func (p *dashboardProgressBar) Finish() {
+	p.b.d.lock.Lock()
+	defer p.b.d.lock.Unlock()
+
+	p.b.progressTotal = 0
+	p.b.progressCurrent = 0
+}
+
+type dashboardProxyReader struct {
+	r io.Reader
+	p *dashboardProgressBar
+}
+
+func (r *dashboardProxyReader) Read(data []byte) (int, error) {
+	n, err := r.r.Read(data)
+	r.p.Add(int64(n))
+	return n, err
+}
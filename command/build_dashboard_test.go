@@ -0,0 +1,117 @@
+package command
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+func TestUseDashboard(t *testing.T) {
+	ui := &packer.BasicUi{Writer: new(bytes.Buffer)}
+	if _, ok := useDashboard(ui, 2); ok {
+		t.Fatal("should not use the dashboard when not writing to a terminal")
+	}
+	if _, ok := useDashboard(&packer.MachineReadableUi{Writer: new(bytes.Buffer)}, 2); ok {
+		t.Fatal("should not use the dashboard with machine-readable output")
+	}
+}
+
+func TestBuildDashboard(t *testing.T) {
+	out := new(bytes.Buffer)
+	d := newBuildDashboard(out, false, 1)
+	chocolate := d.Ui("chocolate")
+	vanilla := d.Ui("vanilla")
+
+	chocolate.Say("==> chocolate: Creating the file...")
+	chocolate.Message("    chocolate: writing")
+	vanilla.Say("==> vanilla: Waiting for SSH to become available...")
+	d.draw()
+
+	first := out.String()
+	if strings.Contains(first, "Creating the file...\n") {
+		t.Fatalf("the output of running builds should not be printed: %s", first)
+	}
+	for _, s := range []string{
+		"running chocolate",
+		"Creating the file...: writing",
+		"    " + "    chocolate: writing",
+		"running vanilla",
+		"Waiting for SSH to become available...",
+	} {
+		if !strings.Contains(first, s) {
+			t.Fatalf("missing %q: %s", s, first)
+		}
+	}
+
+	out.Reset()
+	d.Finish("chocolate", nil)
+	d.Finish("vanilla", errors.New("timeout"))
+	d.Start()
+	d.Stop()
+
+	last := out.String()
+	if !strings.HasPrefix(last, "\x1b[4A\x1b[J") {
+		t.Fatalf("the dashboard should be cleared: %q", last)
+	}
+	for _, s := range []string{
+		"==> chocolate: Creating the file...\n    chocolate: writing\n",
+		"==> vanilla: Waiting for SSH to become available...\n",
+		"done    chocolate",
+		"failed  vanilla",
+	} {
+		if !strings.Contains(last, s) {
+			t.Fatalf("missing %q: %q", s, last)
+		}
+	}
+}
+
+func TestDashboardBuild_progress(t *testing.T) {
+	d := newBuildDashboard(new(bytes.Buffer), false, 0)
+	ui := d.Ui("foo")
+	ui.Say("==> foo: Downloading...")
+
+	bar := ui.ProgressBar()
+	bar.Start(200)
+	bar.NewProxyReader(strings.NewReader(strings.Repeat("x", 50))).Read(make([]byte, 100))
+
+	lines := d.buildLines(d.builds[0], 3)
+	if !strings.Contains(lines[0], " 25% Downloading...") {
+		t.Fatalf("bad: %q", lines[0])
+	}
+
+	bar.Finish()
+	lines = d.buildLines(d.builds[0], 3)
+	if strings.Contains(lines[0], "%") {
+		t.Fatalf("bad: %q", lines[0])
+	}
+}
+
+func TestBuildDashboard_Wrap(t *testing.T) {
+	out := new(bytes.Buffer)
+	d := newBuildDashboard(out, false, 0)
+	d.Ui("foo")
+
+	plain := new(bytes.Buffer)
+	ui := d.Wrap(&packer.BasicUi{Writer: plain, ErrorWriter: plain})
+
+	// The output goes to the Ui when the dashboard isn't shown
+	ui.Say("before")
+	if plain.String() != "before\n" {
+		t.Fatalf("bad: %q", plain.String())
+	}
+
+	d.Start()
+	ui.Error("Interrupt received.")
+	d.Stop()
+	ui.Say("after")
+
+	if !strings.Contains(out.String(), "Interrupt received.\n") {
+		t.Fatalf("the output should be above the dashboard: %q", out.String())
+	}
+	if plain.String() != "before\nafter\n" {
+		t.Fatalf("bad: %q", plain.String())
+	}
+}
@@ -0,0 +1,73 @@
+package command
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// buildPattern is a single entry of the -only or -except flags. It is
+// either an exact name, a glob like "amazon-*", or a regular expression
+// between slashes like "/^vmware-(iso|vmx)$/".
+type buildPattern struct {
+	raw  string
+	glob bool
+	re   *regexp.Regexp
+}
+
+func parseBuildPattern(raw string) (*buildPattern, error) {
+	p := &buildPattern{raw: raw}
+
+	if len(raw) > 1 && strings.HasPrefix(raw, "/") && strings.HasSuffix(raw, "/") {
+		re, err := regexp.Compile(raw[1 : len(raw)-1])
+		if err != nil {
+			return nil, fmt.Errorf("Invalid build pattern %q: %s", raw, err)
+		}
+		p.re = re
+		return p, nil
+	}
+
+	if strings.ContainsAny(raw, "*?[") {
+		if _, err := path.Match(raw, ""); err != nil {
+			return nil, fmt.Errorf("Invalid build pattern %q: %s", raw, err)
+		}
+		p.glob = true
+	}
+
+	return p, nil
+}
+
+// Match reports whether the pattern matches a build, by its name or by
+// the type of its builder.
+func (p *buildPattern) Match(name, builderType string) bool {
+	for _, s := range []string{name, builderType} {
+		switch {
+		case p.re != nil:
+			if p.re.MatchString(s) {
+				return true
+			}
+		case p.glob:
+			if ok, _ := path.Match(p.raw, s); ok {
+				return true
+			}
+		default:
+			if p.raw == s {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func parseBuildPatterns(raws []string) ([]*buildPattern, error) {
+	result := make([]*buildPattern, 0, len(raws))
+	for _, raw := range raws {
+		p, err := parseBuildPattern(raw)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, p)
+	}
+	return result, nil
+}
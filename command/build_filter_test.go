@@ -0,0 +1,42 @@
+package command
+
+import (
+	"testing"
+)
+
+func TestBuildPattern(t *testing.T) {
+	cases := []struct {
+		pattern     string
+		name        string
+		builderType string
+		expected    bool
+	}{
+		{"web", "web", "amazon-ebs", true},
+		{"amazon-ebs", "web", "amazon-ebs", true},
+		{"web", "web-debug", "amazon-ebs", false},
+		{"amazon-*", "web", "amazon-ebs", true},
+		{"*-debug", "web-debug", "qemu", true},
+		{"*-debug", "web", "qemu", false},
+		{"/^vmware-(iso|vmx)$/", "base", "vmware-vmx", true},
+		{"/^vmware-(iso|vmx)$/", "base", "vmware-vmx-debug", false},
+		{"/debug/", "web-debug", "qemu", true},
+	}
+
+	for _, tc := range cases {
+		p, err := parseBuildPattern(tc.pattern)
+		if err != nil {
+			t.Fatalf("%s: %s", tc.pattern, err)
+		}
+		if actual := p.Match(tc.name, tc.builderType); actual != tc.expected {
+			t.Errorf("%s matching %s (%s): expected %t", tc.pattern, tc.name, tc.builderType, tc.expected)
+		}
+	}
+}
+
+func TestBuildPattern_invalid(t *testing.T) {
+	for _, pattern := range []string{"/(/", "[a-"} {
+		if _, err := parseBuildPattern(pattern); err == nil {
+			t.Errorf("%s: should error", pattern)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package command
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	awscommon "github.com/hashicorp/packer/builder/amazon/common"
+	"github.com/hashicorp/packer/helper/ledger"
+
+	"github.com/posener/complete"
+)
+
+// ledgerDeleters are how the resources of each provider in the ledger
+// are deleted.
+var ledgerDeleters = map[string]ledger.Deleter{
+	awscommon.LedgerProvider: awscommon.DeleteLedgerResource,
+}
+
+// cleanupOrder is the order resources are deleted in: what uses other
+// resources goes first.
+var cleanupOrder = map[string]int{
+	"instance":     0,
+	"spot_request": 1,
+	"image":        2,
+	"volume":       3,
+	"snapshot":     4,
+}
+
+type CleanupCommand struct {
+	Meta
+}
+
+func (c *CleanupCommand) Run(args []string) int {
+	var cfgDryRun, cfgForce bool
+	var cfgOlderThan time.Duration
+	flags := c.Meta.FlagSet("cleanup", FlagSetNone)
+	flags.Usage = func() { c.Ui.Say(c.Help()) }
+	flags.BoolVar(&cfgDryRun, "dry-run", false, "")
+	flags.BoolVar(&cfgForce, "force", false, "")
+	flags.DurationVar(&cfgOlderThan, "older-than", 0, "")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	if len(flags.Args()) != 0 {
+		flags.Usage()
+		return 1
+	}
+
+	resources, err := ledger.List()
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Error reading the ledger: %s", err))
+		return 1
+	}
+
+	sort.SliceStable(resources, func(i, j int) bool {
+		return typeOrder(resources[i].Type) < typeOrder(resources[j].Type)
+	})
+
+	deleted, skipped, failed := 0, 0, 0
+	for _, r := range resources {
+		age := time.Since(r.Created)
+		if cfgOlderThan > 0 && age < cfgOlderThan {
+			skipped++
+			continue
+		}
+		if !cfgForce && !r.Orphaned() {
+			c.Ui.Message(fmt.Sprintf(
+				"Skipping %s: the build that created it may still be running (pid %d on %s)",
+				r, r.PID, r.Host))
+			skipped++
+			continue
+		}
+
+		if cfgDryRun {
+			c.Ui.Say(fmt.Sprintf("Would delete %s, created %s ago", r, age.Round(time.Second)))
+			c.Ui.Machine("cleanup-resource", r.Provider, r.Type, r.ID, "dry-run")
+			continue
+		}
+
+		deleter, ok := ledgerDeleters[r.Provider]
+		if !ok {
+			c.Ui.Error(fmt.Sprintf("Don't know how to delete %s", r))
+			c.Ui.Machine("cleanup-resource", r.Provider, r.Type, r.ID, "failed")
+			failed++
+			continue
+		}
+
+		c.Ui.Say(fmt.Sprintf("Deleting %s, created %s ago...", r, age.Round(time.Second)))
+		if err := deleter(r); err != nil {
+			c.Ui.Error(fmt.Sprintf("Error deleting %s: %s", r, err))
+			c.Ui.Machine("cleanup-resource", r.Provider, r.Type, r.ID, "failed")
+			failed++
+			continue
+		}
+
+		ledger.Release(r)
+		c.Ui.Machine("cleanup-resource", r.Provider, r.Type, r.ID, "deleted")
+		deleted++
+	}
+
+	if !cfgDryRun {
+		c.Ui.Say(fmt.Sprintf("Deleted %d resources, %d failed, %d skipped.", deleted, failed, skipped))
+	}
+	if failed > 0 {
+		return 1
+	}
+
+	return 0
+}
+
+func typeOrder(t string) int {
+	if order, ok := cleanupOrder[t]; ok {
+		return order
+	}
+	return len(cleanupOrder)
+}
+
+func (*CleanupCommand) Help() string {
+	helpText := `
+Usage: packer cleanup [options]
+
+  Deletes the cloud resources that builds left behind. The Amazon builders
+  record the resources they create, such as instances, key pairs, security
+  groups, AMIs, and snapshots, and remove them from the record once they
+  are deleted or kept. Those still there after a build crashed or was
+  killed are deleted by this command.
+
+  Resources of builds that may still be running, because the process that
+  created them still exists or ran on another host, are skipped.
+
+Options:
+
+  -dry-run               List the resources that would be deleted.
+  -force                 Also delete the resources of builds that may still be running.
+  -older-than=duration   Only delete resources created longer ago than this, such as 2h.
+`
+
+	return strings.TrimSpace(helpText)
+}
+
+func (*CleanupCommand) Synopsis() string {
+	return "delete cloud resources left behind by builds"
+}
+
+func (*CleanupCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (*CleanupCommand) AutocompleteFlags() complete.Flags {
+	return complete.Flags{
+		"-dry-run":    complete.PredictNothing,
+		"-force":      complete.PredictNothing,
+		"-older-than": complete.PredictNothing,
+	}
+}
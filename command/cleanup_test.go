@@ -0,0 +1,164 @@
+package command
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer/helper/ledger"
+)
+
+// testLedger fills a ledger with an orphaned instance and key pair, and a
+// volume of this process, and deletes them with a fake provider.
+func testLedger(t *testing.T) (*[]string, func()) {
+	dir, err := ioutil.TempDir("", "packer-ledger")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	oldDir := os.Getenv("PACKER_LEDGER_DIR")
+	os.Setenv("PACKER_LEDGER_DIR", dir)
+
+	// A process that is gone
+	cmd := exec.Command("go", "version")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	host, _ := os.Hostname()
+
+	orphans := []*ledger.Resource{
+		{Provider: "fake", Type: "key_pair", ID: "packer_key", Created: time.Now().Add(-3 * time.Hour)},
+		{Provider: "fake", Type: "instance", ID: "i-1", Created: time.Now().Add(-time.Hour)},
+	}
+	for i, r := range orphans {
+		r.PID = cmd.Process.Pid
+		r.Host = host
+		raw, _ := json.Marshal(r)
+		if err := ioutil.WriteFile(filepath.Join(dir, r.ID+".json"), raw, 0644); err != nil {
+			t.Fatalf("%d: err: %s", i, err)
+		}
+	}
+	ledger.Track(&ledger.Resource{Provider: "fake", Type: "volume", ID: "vol-1"})
+
+	var deleted []string
+	oldDeleters := ledgerDeleters
+	ledgerDeleters = map[string]ledger.Deleter{
+		"fake": func(r *ledger.Resource) error {
+			deleted = append(deleted, r.ID)
+			return nil
+		},
+	}
+
+	return &deleted, func() {
+		ledgerDeleters = oldDeleters
+		os.Setenv("PACKER_LEDGER_DIR", oldDir)
+		os.RemoveAll(dir)
+	}
+}
+
+func TestCleanupCommand(t *testing.T) {
+	deleted, done := testLedger(t)
+	defer done()
+
+	c := &CleanupCommand{
+		Meta: testMeta(t),
+	}
+	if code := c.Run(nil); code != 0 {
+		fatalCommand(t, c.Meta)
+	}
+
+	// Instances go first, the volume of a running build stays
+	if strings.Join(*deleted, ",") != "i-1,packer_key" {
+		t.Fatalf("bad: %#v", *deleted)
+	}
+	resources, err := ledger.List()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(resources) != 1 || resources[0].ID != "vol-1" {
+		t.Fatalf("bad: %#v", resources)
+	}
+}
+
+func TestCleanupCommand_dryRun(t *testing.T) {
+	deleted, done := testLedger(t)
+	defer done()
+
+	c := &CleanupCommand{
+		Meta: testMeta(t),
+	}
+	if code := c.Run([]string{"-dry-run"}); code != 0 {
+		fatalCommand(t, c.Meta)
+	}
+
+	if len(*deleted) != 0 {
+		t.Fatalf("bad: %#v", *deleted)
+	}
+	out, _ := outputCommand(t, c.Meta)
+	if !strings.Contains(out, "Would delete fake instance i-1") {
+		t.Fatalf("bad: %s", out)
+	}
+}
+
+func TestCleanupCommand_forceOlderThan(t *testing.T) {
+	deleted, done := testLedger(t)
+	defer done()
+
+	c := &CleanupCommand{
+		Meta: testMeta(t),
+	}
+	if code := c.Run([]string{"-force", "-older-than=2h"}); code != 0 {
+		fatalCommand(t, c.Meta)
+	}
+	if strings.Join(*deleted, ",") != "packer_key" {
+		t.Fatalf("bad: %#v", *deleted)
+	}
+
+	*deleted = nil
+	if code := c.Run([]string{"-force"}); code != 0 {
+		fatalCommand(t, c.Meta)
+	}
+	if strings.Join(*deleted, ",") != "i-1,vol-1" {
+		t.Fatalf("bad: %#v", *deleted)
+	}
+}
+
+func TestCleanupCommand_error(t *testing.T) {
+	_, done := testLedger(t)
+	defer done()
+
+	ledgerDeleters["fake"] = func(r *ledger.Resource) error {
+		return errors.New("still in use")
+	}
+
+	c := &CleanupCommand{
+		Meta: testMeta(t),
+	}
+	if code := c.Run(nil); code != 1 {
+		t.Fatalf("bad: %d", code)
+	}
+
+	// Whatever failed stays in the ledger to try again
+	resources, err := ledger.List()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(resources) != 3 {
+		t.Fatalf("bad: %#v", resources)
+	}
+}
+
+func TestTypeOrder(t *testing.T) {
+	// What uses other resources is deleted first
+	order := []string{"instance", "spot_request", "image", "volume", "snapshot", "key_pair"}
+	for i := 1; i < len(order); i++ {
+		if typeOrder(order[i-1]) >= typeOrder(order[i]) {
+			t.Fatalf("%s should be deleted before %s", order[i-1], order[i])
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package command
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/packer/template"
+	"github.com/posener/complete"
+)
+
+// predictTemplates completes template arguments, and predictVarFiles the
+// values of -var-file. Both are JSON or YAML.
+var predictTemplates = complete.PredictOr(
+	complete.PredictFiles("*.json"),
+	complete.PredictFiles("*.yaml"),
+	complete.PredictFiles("*.yml"),
+)
+
+var predictVarFiles = predictTemplates
+
+// predictBuildNames completes the values of -only and -except with the
+// builds, and builder types, of the template on the command line. The
+// template usually comes after the flags, which the shell hasn't shown yet,
+// so the templates in the current directory are used without one. Values
+// are comma separated, so only the part after the last comma is completed.
+var predictBuildNames = complete.PredictFunc(func(a complete.Args) []string {
+	var paths []string
+	for _, arg := range a.All {
+		isTemplate := strings.HasSuffix(arg, ".json") || template.IsYAML(arg)
+		if arg != a.Last && isTemplate && !strings.HasPrefix(arg, "-") {
+			paths = []string{arg}
+		}
+	}
+	if len(paths) == 0 {
+		for _, pattern := range []string{"*.json", "*.yaml", "*.yml"} {
+			matches, _ := filepath.Glob(pattern)
+			paths = append(paths, matches...)
+		}
+	}
+
+	names := make(map[string]bool)
+	for _, path := range paths {
+		tpl, err := template.ParseFile(path)
+		if err != nil {
+			continue
+		}
+		for name, b := range tpl.Builders {
+			names[name] = true
+			names[b.Type] = true
+		}
+	}
+
+	prefix, current := "", a.Last
+	if i := strings.LastIndex(a.Last, ","); i >= 0 {
+		prefix, current = a.Last[:i+1], a.Last[i+1:]
+	}
+	given := make(map[string]bool)
+	for _, n := range strings.Split(prefix, ",") {
+		given[n] = true
+	}
+
+	var result []string
+	for name := range names {
+		if !given[name] && strings.HasPrefix(name, current) {
+			result = append(result, prefix+name)
+		}
+	}
+	sort.Strings(result)
+	return result
+})
@@ -0,0 +1,49 @@
+package command
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/posener/complete"
+)
+
+func TestPredictBuildNames(t *testing.T) {
+	tpl := filepath.Join(testFixture("build-only"), "template.json")
+
+	cases := []struct {
+		last     string
+		expected []string
+	}{
+		{"ch", []string{"cherry", "chocolate"}},
+		{"", []string{"cherry", "chocolate", "file", "vanilla"}},
+		{"chocolate,", []string{"chocolate,cherry", "chocolate,file", "chocolate,vanilla"}},
+		{"chocolate,v", []string{"chocolate,vanilla"}},
+		{"x", nil},
+	}
+	for _, tc := range cases {
+		a := complete.Args{
+			All:  []string{tpl, "-only", tc.last},
+			Last: tc.last,
+		}
+		if actual := predictBuildNames.Predict(a); !reflect.DeepEqual(actual, tc.expected) {
+			t.Errorf("%q: expected %#v, got %#v", tc.last, tc.expected, actual)
+		}
+	}
+
+	// Without a template, the ones in the current directory are used
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.Chdir(wd)
+	if err := os.Chdir(testFixture("build-only")); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	a := complete.Args{All: []string{"-only", "va"}, Last: "va"}
+	if actual := predictBuildNames.Predict(a); !reflect.DeepEqual(actual, []string{"vanilla"}) {
+		t.Errorf("bad: %#v", actual)
+	}
+}
@@ -0,0 +1,341 @@
+package command
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/packer/template"
+	"github.com/posener/complete"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// convertExtensions are the extensions of the files ConvertCommand writes,
+// by format.
+var convertExtensions = map[string]string{
+	"hcl2": ".pkr.hcl",
+	"json": ".json",
+	"yaml": ".yaml",
+}
+
+// ConvertCommand converts templates between JSON, YAML, and HCL2.
+type ConvertCommand struct {
+	Meta
+}
+
+func (c *ConvertCommand) Run(args []string) int {
+	var cfgFormat, cfgOutput string
+	var cfgForce bool
+	flags := c.Meta.FlagSet("convert", FlagSetNone)
+	flags.Usage = func() { c.Ui.Say(c.Help()) }
+	flags.StringVar(&cfgFormat, "format", "hcl2", "format")
+	flags.StringVar(&cfgOutput, "output", "", "output")
+	flags.BoolVar(&cfgForce, "force", false, "force")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	args = flags.Args()
+	if len(args) == 0 {
+		flags.Usage()
+		return 1
+	}
+	if _, ok := convertExtensions[cfgFormat]; !ok {
+		c.Ui.Error(fmt.Sprintf("Unknown format %q, the formats are hcl2, json, and yaml", cfgFormat))
+		return 1
+	}
+	if cfgOutput != "" && len(args) > 1 {
+		c.Ui.Error("-output can only be used to convert one template")
+		return 1
+	}
+
+	failed := false
+	for _, path := range args {
+		if err := c.convert(path, cfgFormat, cfgOutput, cfgForce); err != nil {
+			c.Ui.Error(fmt.Sprintf("%s: %s", path, err))
+			failed = true
+		}
+	}
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+func (c *ConvertCommand) convert(path, format, output string, force bool) error {
+	if output == "" {
+		output = convertOutputPath(path, format)
+		if output == path {
+			return fmt.Errorf("the template is already %s", strings.ToUpper(format))
+		}
+	}
+	if output != "-" {
+		if _, err := os.Stat(output); err == nil && !force {
+			return fmt.Errorf("%s already exists, use -force to overwrite it", output)
+		}
+	}
+
+	// Packer has to be able to read the template, or the result would
+	// be of no use.
+	if _, err := template.ParseFile(path); err != nil {
+		return err
+	}
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	tpl, err := readOrderedTemplate(path, contents)
+	if err != nil {
+		return err
+	}
+
+	var warnings []string
+	if template.IsYAML(path) && hasYAMLComments(contents) {
+		warnings = append(warnings, "the comments of YAML templates can't be converted, add them back by hand")
+	}
+
+	var out []byte
+	switch format {
+	case "hcl2":
+		conv := &hcl2Converter{}
+		out = conv.Convert(tpl)
+		warnings = append(warnings, conv.warnings...)
+	case "json":
+		out, err = convertJSON(tpl)
+	case "yaml":
+		out, err = convertYAML(tpl)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, w := range warnings {
+		c.Ui.Error(fmt.Sprintf("Warning: %s: %s", path, w))
+	}
+	if output == "-" {
+		c.Ui.Say(strings.TrimSuffix(string(out), "\n"))
+		return nil
+	}
+	if err := ioutil.WriteFile(output, out, 0644); err != nil {
+		return fmt.Errorf("Failed to write %s: %s", output, err)
+	}
+	c.Ui.Say(fmt.Sprintf("Converted %s to %s", path, output))
+	return nil
+}
+
+// convertOutputPath is the file a template is converted to next to it, such
+// as web.pkr.hcl for web.json. It's the template itself if it's already in
+// the format.
+func convertOutputPath(path, format string) string {
+	ext := strings.ToLower(filepath.Ext(path))
+	switch {
+	case format == "json" && ext == ".json", format == "yaml" && template.IsYAML(path):
+		return path
+	case ext == ".json" || template.IsYAML(path):
+		return strings.TrimSuffix(path, filepath.Ext(path)) + convertExtensions[format]
+	default:
+		return path + convertExtensions[format]
+	}
+}
+
+// readOrderedTemplate reads a JSON or YAML template keeping the order of the
+// keys, so the converted template reads like the original.
+func readOrderedTemplate(path string, contents []byte) (orderedObject, error) {
+	var v interface{}
+	if template.IsYAML(path) {
+		var raw yaml.MapSlice
+		if err := yaml.Unmarshal(contents, &raw); err != nil {
+			return nil, err
+		}
+		v = orderedYAML(raw)
+	} else {
+		dec := json.NewDecoder(bytes.NewReader(contents))
+		dec.UseNumber()
+		var err error
+		if v, err = orderedJSON(dec); err != nil {
+			return nil, err
+		}
+	}
+
+	tpl, ok := v.(orderedObject)
+	if !ok {
+		return nil, fmt.Errorf("the template isn't an object")
+	}
+	return tpl, nil
+}
+
+func orderedJSON(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if n, ok := tok.(json.Number); ok {
+		// Numbers stay integers where they are, for YAML.
+		if i, err := n.Int64(); err == nil {
+			return i, nil
+		}
+		return n.Float64()
+	}
+
+	switch tok {
+	case json.Delim('{'):
+		result := orderedObject{}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := orderedJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, keyValue{key.(string), value})
+		}
+		_, err := dec.Token()
+		return result, err
+	case json.Delim('['):
+		result := []interface{}{}
+		for dec.More() {
+			value, err := orderedJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, value)
+		}
+		_, err := dec.Token()
+		return result, err
+	default:
+		return tok, nil
+	}
+}
+
+func orderedYAML(v interface{}) interface{} {
+	switch v := v.(type) {
+	case yaml.MapSlice:
+		result := make(orderedObject, 0, len(v))
+		for _, item := range v {
+			result = append(result, keyValue{fmt.Sprint(item.Key), orderedYAML(item.Value)})
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, value := range v {
+			result[i] = orderedYAML(value)
+		}
+		return result
+	default:
+		return v
+	}
+}
+
+func hasYAMLComments(contents []byte) bool {
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	for scanner.Scan() {
+		if strings.HasPrefix(strings.TrimSpace(scanner.Text()), "#") {
+			return true
+		}
+	}
+	return false
+}
+
+// templateComments splits the root level comments, the keys starting with
+// an underscore, from the rest of the template.
+func templateComments(tpl orderedObject) ([]string, orderedObject) {
+	var comments []string
+	var rest orderedObject
+	for _, kv := range tpl {
+		if !strings.HasPrefix(kv.Key, "_") {
+			rest = append(rest, kv)
+			continue
+		}
+		switch v := kv.Value.(type) {
+		case string:
+			comments = append(comments, strings.Split(v, "\n")...)
+		case []interface{}:
+			for _, line := range v {
+				comments = append(comments, fmt.Sprint(line))
+			}
+		default:
+			rest = append(rest, kv)
+		}
+	}
+	return comments, rest
+}
+
+func convertJSON(tpl orderedObject) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(tpl); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func convertYAML(tpl orderedObject) ([]byte, error) {
+	comments, rest := templateComments(tpl)
+	out, err := yaml.Marshal(rest)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	writeComments(&buf, comments, "")
+	if len(comments) > 0 {
+		buf.WriteByte('\n')
+	}
+	buf.Write(out)
+	return buf.Bytes(), nil
+}
+
+func writeComments(w io.Writer, comments []string, indent string) {
+	for _, line := range comments {
+		fmt.Fprintln(w, strings.TrimRight(indent+"# "+line, " "))
+	}
+}
+
+func (*ConvertCommand) Help() string {
+	helpText := `
+Usage: packer convert [options] TEMPLATE...
+
+  Converts templates to HCL2, or between JSON and YAML. Each template is
+  written next to it with the extension of the format, such as web.pkr.hcl
+  for web.json, and is left as it is.
+
+  The comments of JSON templates, their root keys starting with an
+  underscore, become comments. What can't be converted automatically is
+  reported, and marked with a TODO comment in HCL2 templates.
+
+Options:
+
+  -format=hcl2     The format to convert to: hcl2 (default), json, or yaml.
+  -output=path     Write the template to this file, - for the output, when
+                   converting one template.
+  -force           Overwrite the templates that already exist.
+`
+
+	return strings.TrimSpace(helpText)
+}
+
+func (*ConvertCommand) Synopsis() string {
+	return "convert templates to HCL2, JSON, or YAML"
+}
+
+func (*ConvertCommand) AutocompleteArgs() complete.Predictor {
+	return predictTemplates
+}
+
+func (*ConvertCommand) AutocompleteFlags() complete.Flags {
+	return complete.Flags{
+		"-format": complete.PredictSet("hcl2", "json", "yaml"),
+		"-output": complete.PredictFiles("*"),
+		"-force":  complete.PredictNothing,
+	}
+}
@@ -0,0 +1,808 @@
+package command
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template/parse"
+
+	"github.com/hashicorp/packer/template"
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+// hcl2Identifier matches the names HCL2 takes without quotes.
+var hcl2Identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// hcl2Converter writes a template as HCL2: variable blocks for the
+// variables, a source block for each builder, and a build block with the
+// provisioners and post-processors. The Go templates in the strings are
+// turned into HCL2 expressions where there is an equivalent, and what needs
+// to be done by hand is collected in warnings.
+type hcl2Converter struct {
+	warnings []string
+
+	// builders are the types of the builders by name, for only and except.
+	builders map[string]string
+
+	// inVariable is set while converting the default of a variable.
+	inVariable bool
+
+	// timestamp is set if the template uses the timestamp local.
+	timestamp bool
+}
+
+// hcl2Item is an attribute, a block, or a comment of an HCL2 body.
+type hcl2Item struct {
+	Name  string
+	Value string
+
+	Labels []string
+	Body   []*hcl2Item
+	Block  bool
+
+	Todos []string
+}
+
+func (c *hcl2Converter) Convert(tpl orderedObject) []byte {
+	comments, tpl := templateComments(tpl)
+
+	var description string
+	var builders, provisioners, postProcessors []interface{}
+	var variables, provisionerGroups, postProcessorGroups orderedObject
+	sensitive := make(map[string]bool)
+	var packer *hcl2Item
+	var body []*hcl2Item
+	for _, kv := range tpl {
+		switch kv.Key {
+		case "description":
+			description, _ = kv.Value.(string)
+		case "min_packer_version":
+			packer = &hcl2Item{Name: "packer", Block: true, Body: []*hcl2Item{
+				{Name: "required_version", Value: hcl2Quote(">= " + fmt.Sprint(kv.Value))},
+			}}
+		case "variables":
+			variables, _ = kv.Value.(orderedObject)
+		case "sensitive-variables":
+			names, _ := kv.Value.([]interface{})
+			for _, name := range names {
+				sensitive[fmt.Sprint(name)] = true
+			}
+		case "builders":
+			builders, _ = kv.Value.([]interface{})
+		case "provisioners":
+			provisioners, _ = kv.Value.([]interface{})
+		case "post-processors":
+			postProcessors, _ = kv.Value.([]interface{})
+		case "provisioner-groups":
+			provisionerGroups, _ = kv.Value.(orderedObject)
+		case "post-processor-groups":
+			postProcessorGroups, _ = kv.Value.(orderedObject)
+		default:
+			c.warn(kv.Key, "HCL2 templates have no %s, it was left out", kv.Key)
+		}
+	}
+
+	for _, kv := range variables {
+		body = append(body, c.variable(kv.Key, kv.Value, sensitive[kv.Key]))
+	}
+
+	c.builders = make(map[string]string)
+	var sources []string
+	for i, raw := range builders {
+		b, _ := raw.(orderedObject)
+		source := c.source(fmt.Sprintf("builders[%d]", i), b)
+		body = append(body, source)
+		sources = append(sources, fmt.Sprintf("source.%s.%s", source.Labels[0], source.Labels[1]))
+	}
+
+	build := &hcl2Item{Name: "build", Block: true}
+	if description != "" {
+		build.Body = append(build.Body, &hcl2Item{Name: "description", Value: c.str("description", description)})
+	}
+	build.Body = append(build.Body, &hcl2Item{Name: "sources", Value: hcl2List(quoteAll(sources))})
+
+	// HCL2 has no groups, their members are copied where they are used.
+	for i, raw := range provisioners {
+		path := fmt.Sprintf("provisioners[%d]", i)
+		p, _ := raw.(orderedObject)
+		name, ok := groupName(p)
+		if !ok {
+			build.Body = append(build.Body, c.component(path, "provisioner", p))
+			continue
+		}
+
+		group, ok := lookup(provisionerGroups, name)
+		if !ok {
+			build.Body = append(build.Body, &hcl2Item{Todos: []string{c.warn(path,
+				"the provisioner group %q isn't in the template, copy its provisioners here", name)}})
+			continue
+		}
+		members, _ := group.([]interface{})
+		for j, member := range members {
+			m, _ := member.(orderedObject)
+			if m = withinGroup(m, p); m != nil {
+				build.Body = append(build.Body, c.component(
+					fmt.Sprintf("provisioner-groups.%s[%d]", name, j), "provisioner", m))
+			}
+		}
+	}
+	for i, raw := range postProcessors {
+		path := fmt.Sprintf("post-processors[%d]", i)
+		ref, _ := raw.(orderedObject)
+		name, ok := groupName(ref)
+		if !ok {
+			build.Body = append(build.Body, c.postProcessorChain(path, raw))
+			continue
+		}
+
+		group, ok := lookup(postProcessorGroups, name)
+		if !ok {
+			build.Body = append(build.Body, &hcl2Item{Todos: []string{c.warn(path,
+				"the post-processor group %q isn't in the template, copy its post-processors here", name)}})
+			continue
+		}
+		chains, _ := group.([]interface{})
+		for j, chain := range chains {
+			var within []interface{}
+			for _, pp := range postProcessorList(chain) {
+				if pp = withinGroup(pp, ref); pp != nil {
+					within = append(within, pp)
+				}
+			}
+			if len(within) > 0 {
+				build.Body = append(build.Body, c.postProcessorChain(
+					fmt.Sprintf("post-processor-groups.%s[%d]", name, j), within))
+			}
+		}
+	}
+
+	if c.timestamp {
+		// The Unix timestamp has no HCL2 equivalent, timestamp() returns
+		// an RFC 3339 time.
+		locals := &hcl2Item{Name: "locals", Block: true, Body: []*hcl2Item{
+			{Name: "timestamp", Value: `regex_replace(timestamp(), "[- TZ:]", "")`},
+		}}
+		body = append([]*hcl2Item{locals}, body...)
+	}
+	if packer != nil {
+		body = append([]*hcl2Item{packer}, body...)
+	}
+	body = append(body, build)
+
+	var buf bytes.Buffer
+	writeComments(&buf, comments, "")
+	for i, item := range body {
+		if i > 0 || len(comments) > 0 {
+			buf.WriteByte('\n')
+		}
+		writeHCL2([]*hcl2Item{item}, &buf, "")
+	}
+	return buf.Bytes()
+}
+
+func (c *hcl2Converter) warn(path, format string, args ...interface{}) string {
+	msg := fmt.Sprintf(format, args...)
+	c.warnings = append(c.warnings, fmt.Sprintf("%s: %s", path, msg))
+	return msg
+}
+
+func (c *hcl2Converter) variable(name string, value interface{}, sensitive bool) *hcl2Item {
+	block := &hcl2Item{Name: "variable", Labels: []string{name}, Block: true}
+	if !hcl2Identifier.MatchString(name) {
+		block.Todos = append(block.Todos, c.warn("variables."+name,
+			"the name isn't valid in HCL2, rename the variable"))
+	}
+	block.Body = append(block.Body, &hcl2Item{Name: "type", Value: "string"})
+
+	// A variable without a default, null, is required.
+	if value != nil {
+		c.inVariable = true
+		defer func() { c.inVariable = false }()
+		n := len(c.warnings)
+		item := &hcl2Item{Name: "default", Value: c.value("variables."+name, value)}
+		item.Todos = append(item.Todos, c.warnings[n:]...)
+		if strings.Contains(item.Value, "var.") {
+			item.Todos = append(item.Todos, c.warn("variables."+name,
+				"the default of a variable can't use other variables in HCL2, use a local instead"))
+		}
+		block.Body = append(block.Body, item)
+	}
+	if sensitive {
+		block.Body = append(block.Body, &hcl2Item{Name: "sensitive", Value: "true"})
+	}
+	return block
+}
+
+func (c *hcl2Converter) source(path string, b orderedObject) *hcl2Item {
+	var typ, name string
+	var config orderedObject
+	for _, kv := range b {
+		switch kv.Key {
+		case "type":
+			typ = fmt.Sprint(kv.Value)
+		case "name":
+			name = fmt.Sprint(kv.Value)
+		default:
+			config = append(config, kv)
+		}
+	}
+	if name == "" {
+		name = typ
+	}
+	c.builders[name] = typ
+
+	block := &hcl2Item{Name: "source", Labels: []string{typ, name}, Block: true}
+	if !hcl2Identifier.MatchString(name) {
+		block.Todos = append(block.Todos, c.warn(path,
+			"the name %q can't be used in the sources of the build, rename the builder", name))
+	}
+	block.Body = c.body(path, config)
+	return block
+}
+
+// component converts a provisioner or a post-processor.
+func (c *hcl2Converter) component(path, kind string, config orderedObject) *hcl2Item {
+	block := &hcl2Item{Name: kind, Block: true}
+	var rest orderedObject
+	for _, kv := range config {
+		switch kv.Key {
+		case "type":
+			block.Labels = []string{fmt.Sprint(kv.Value)}
+		case "only", "except":
+			// HCL2 refers to the builders as sources.
+			names, _ := kv.Value.([]interface{})
+			var sources []interface{}
+			for _, name := range names {
+				n := fmt.Sprint(name)
+				if typ, ok := c.builders[n]; ok {
+					sources = append(sources, fmt.Sprintf("source.%s.%s", typ, n))
+				} else {
+					block.Todos = append(block.Todos, c.warn(path, "%s: there is no builder %q", kv.Key, n))
+					sources = append(sources, n)
+				}
+			}
+			rest = append(rest, keyValue{kv.Key, sources})
+		default:
+			rest = append(rest, kv)
+		}
+	}
+	block.Body = append(c.body(path, rest), block.Body...)
+	return block
+}
+
+// postProcessorChain converts a post-processor, or a sequence of them.
+func (c *hcl2Converter) postProcessorChain(path string, raw interface{}) *hcl2Item {
+	chain, ok := raw.([]interface{})
+	if !ok || len(chain) == 1 {
+		return c.postProcessor(path, postProcessorList(raw)[0])
+	}
+
+	block := &hcl2Item{Name: "post-processors", Block: true}
+	for j, pp := range chain {
+		block.Body = append(block.Body, c.postProcessor(fmt.Sprintf("%s[%d]", path, j), pp))
+	}
+	return block
+}
+
+func (c *hcl2Converter) postProcessor(path string, raw interface{}) *hcl2Item {
+	if name, ok := raw.(string); ok {
+		return &hcl2Item{Name: "post-processor", Labels: []string{name}, Block: true}
+	}
+	config, _ := raw.(orderedObject)
+	return c.component(path, "post-processor", config)
+}
+
+// postProcessorList is the post-processors of a sequence, or the
+// post-processor in one, as objects.
+func postProcessorList(raw interface{}) []interface{} {
+	list, ok := raw.([]interface{})
+	if !ok {
+		list = []interface{}{raw}
+	}
+	result := make([]interface{}, len(list))
+	for i, pp := range list {
+		if name, ok := pp.(string); ok {
+			pp = orderedObject{{"type", name}}
+		}
+		result[i] = pp
+	}
+	return result
+}
+
+// groupName is the group a provisioner or post-processor uses, if it is the
+// use of one.
+func groupName(v orderedObject) (string, bool) {
+	group, ok := lookup(v, "group")
+	if !ok {
+		return "", false
+	}
+	return fmt.Sprint(group), true
+}
+
+func lookup(v orderedObject, key string) (interface{}, bool) {
+	for _, kv := range v {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return nil, false
+}
+
+// withinGroup narrows the builds of a member of a group down to the ones of
+// its use, or returns nil if there is none left.
+func withinGroup(member interface{}, ref orderedObject) orderedObject {
+	m, _ := member.(orderedObject)
+	names := func(v orderedObject, key string) []string {
+		raw, _ := lookup(v, key)
+		list, _ := raw.([]interface{})
+		var result []string
+		for _, n := range list {
+			result = append(result, fmt.Sprint(n))
+		}
+		return result
+	}
+	inner := template.OnlyExcept{Only: names(m, "only"), Except: names(m, "except")}
+	outer := template.OnlyExcept{Only: names(ref, "only"), Except: names(ref, "except")}
+	oe, ok := inner.Within(outer)
+	if !ok {
+		return nil
+	}
+
+	list := func(names []string) []interface{} {
+		result := make([]interface{}, len(names))
+		for i, n := range names {
+			result[i] = n
+		}
+		return result
+	}
+	var result orderedObject
+	for _, kv := range m {
+		if kv.Key == "only" || kv.Key == "except" {
+			continue
+		}
+		result = append(result, kv)
+		if kv.Key == "type" {
+			if len(oe.Only) > 0 {
+				result = append(result, keyValue{"only", list(oe.Only)})
+			}
+			if len(oe.Except) > 0 {
+				result = append(result, keyValue{"except", list(oe.Except)})
+			}
+		}
+	}
+	return result
+}
+
+// body converts the settings of a component. Lists of objects, like
+// launch_block_device_mappings, are blocks in HCL2, other values are
+// attributes.
+func (c *hcl2Converter) body(path string, config orderedObject) []*hcl2Item {
+	var body []*hcl2Item
+	for _, kv := range config {
+		itemPath := path + "." + kv.Key
+		var todos []string
+		if !hcl2Identifier.MatchString(kv.Key) {
+			todos = append(todos, c.warn(itemPath, "the setting name isn't valid in HCL2"))
+		}
+
+		if blocks, ok := objectList(kv.Value); ok {
+			for i, b := range blocks {
+				body = append(body, &hcl2Item{
+					Name:  kv.Key,
+					Block: true,
+					Body:  c.body(fmt.Sprintf("%s[%d]", itemPath, i), b),
+					Todos: todos,
+				})
+			}
+			continue
+		}
+		n := len(c.warnings)
+		value := c.value(itemPath, kv.Value)
+		todos = append(todos, c.warnings[n:]...)
+		body = append(body, &hcl2Item{Name: kv.Key, Value: value, Todos: todos})
+	}
+	return body
+}
+
+func objectList(v interface{}) ([]orderedObject, bool) {
+	list, ok := v.([]interface{})
+	if !ok || len(list) == 0 {
+		return nil, false
+	}
+	result := make([]orderedObject, len(list))
+	for i, item := range list {
+		if result[i], ok = item.(orderedObject); !ok {
+			return nil, false
+		}
+	}
+	return result, true
+}
+
+// value converts a value to an HCL2 expression.
+func (c *hcl2Converter) value(path string, v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return c.str(path, v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = c.value(fmt.Sprintf("%s[%d]", path, i), item)
+		}
+		return hcl2List(items)
+	case orderedObject:
+		if len(v) == 0 {
+			return "{}"
+		}
+		var body []*hcl2Item
+		for _, kv := range v {
+			name := kv.Key
+			if !hcl2Identifier.MatchString(name) {
+				name = hcl2Quote(name)
+			}
+			body = append(body, &hcl2Item{Name: name, Value: c.value(path+"."+kv.Key, kv.Value)})
+		}
+		var buf bytes.Buffer
+		buf.WriteString("{\n")
+		writeHCL2(body, &buf, "  ")
+		buf.WriteString("}")
+		return buf.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// str converts a string, and the Go templates in it, to an HCL2 string.
+// The Go templates that have no HCL2 equivalent are kept as they are, the
+// ones that read the data of the builders and provisioners, like
+// {{.HTTPIP}}, still work that way.
+func (c *hcl2Converter) str(path, s string) string {
+	if !strings.Contains(s, "{{") {
+		return hcl2Quote(s)
+	}
+
+	tree, err := parseGoTemplate(s)
+	if err != nil {
+		c.warn(path, "the template can't be parsed, it was kept as it is: %s", err)
+		return hcl2Quote(s)
+	}
+
+	// A string that is only an expression, like {{user `region`}}, is the
+	// expression.
+	if nodes := tree.Root.Nodes; len(nodes) == 1 {
+		if action, ok := nodes[0].(*parse.ActionNode); ok {
+			if expr, ok := c.pipe(path, action.Pipe); ok {
+				return expr
+			}
+			return hcl2Quote(s)
+		}
+	}
+
+	var buf strings.Builder
+	buf.WriteByte('"')
+	for _, node := range tree.Root.Nodes {
+		switch node := node.(type) {
+		case *parse.TextNode:
+			buf.WriteString(hcl2Escape(string(node.Text)))
+		case *parse.ActionNode:
+			if expr, ok := c.pipe(path, node.Pipe); ok {
+				buf.WriteString("${" + expr + "}")
+			} else {
+				buf.WriteString(hcl2Escape(node.String()))
+			}
+		default:
+			c.warn(path, "%s has no HCL2 equivalent, it was kept as a Go template", node)
+			buf.WriteString(hcl2Escape(node.String()))
+		}
+	}
+	buf.WriteByte('"')
+	return buf.String()
+}
+
+// parseGoTemplate parses a Go template of a template setting. The functions
+// of the builders, like clean_ami_name, are only known to them, so any
+// function is accepted.
+func parseGoTemplate(s string) (*parse.Tree, error) {
+	funcs := make(map[string]interface{})
+	for name := range interpolate.FuncGens {
+		funcs[name] = nil
+	}
+	undefined := regexp.MustCompile(`function "([^"]+)" not defined`)
+	for {
+		trees, err := parse.Parse("template", s, "{{", "}}", funcs)
+		if err == nil {
+			return trees["template"], nil
+		}
+		m := undefined.FindStringSubmatch(err.Error())
+		if m == nil || funcs[m[1]] != nil {
+			return nil, err
+		}
+		funcs[m[1]] = struct{}{}
+	}
+}
+
+// pipe converts a pipeline, like user `name` | lower, to an expression.
+func (c *hcl2Converter) pipe(path string, pipe *parse.PipeNode) (string, bool) {
+	if len(pipe.Decl) > 0 {
+		c.warn(path, "{{%s}} has no HCL2 equivalent, it was kept as a Go template", pipe)
+		return "", false
+	}
+
+	var result string
+	for i, cmd := range pipe.Cmds {
+		fn, ok := cmd.Args[0].(*parse.IdentifierNode)
+		if !ok {
+			// The data of the builders and provisioners, like .HTTPIP,
+			// stays a Go template.
+			return "", false
+		}
+
+		args := cmd.Args[1:]
+		var exprs []string
+		for _, arg := range args {
+			expr, ok := c.arg(path, arg)
+			if !ok {
+				return "", false
+			}
+			exprs = append(exprs, expr)
+		}
+		if i > 0 {
+			exprs = append(exprs, result)
+			args = append(args, nil)
+		}
+
+		if result, ok = c.call(path, fn.Ident, args, exprs); !ok {
+			c.warn(path, "{{%s}} has no HCL2 equivalent, it was kept as a Go template", pipe)
+			return "", false
+		}
+	}
+	return result, true
+}
+
+func (c *hcl2Converter) arg(path string, node parse.Node) (string, bool) {
+	switch node := node.(type) {
+	case *parse.StringNode:
+		return hcl2Quote(node.Text), true
+	case *parse.NumberNode:
+		return node.Text, true
+	case *parse.BoolNode:
+		return node.String(), true
+	case *parse.IdentifierNode:
+		expr, ok := c.call(path, node.Ident, nil, nil)
+		if !ok {
+			c.warn(path, "{{%s}} has no HCL2 equivalent, it was kept as a Go template", node)
+		}
+		return expr, ok
+	case *parse.PipeNode:
+		return c.pipe(path, node)
+	default:
+		return "", false
+	}
+}
+
+// call converts a call of a template function to an HCL2 expression. args
+// are the arguments as they were, to read the names of variables and build
+// values from, and exprs the arguments converted.
+func (c *hcl2Converter) call(path, name string, args []parse.Node, exprs []string) (string, bool) {
+	literal := func(i int) (string, bool) {
+		if s, ok := args[i].(*parse.StringNode); ok {
+			return s.Text, true
+		}
+		return "", false
+	}
+	fn := func(hclName string, n int) (string, bool) {
+		if len(exprs) != n {
+			return "", false
+		}
+		return fmt.Sprintf("%s(%s)", hclName, strings.Join(exprs, ", ")), true
+	}
+
+	switch name {
+	case "user":
+		if len(args) != 1 {
+			return "", false
+		}
+		if v, ok := literal(0); ok && hcl2Identifier.MatchString(v) {
+			return "var." + v, true
+		}
+	case "build":
+		if len(args) != 1 {
+			return "", false
+		}
+		if v, ok := literal(0); ok && hcl2Identifier.MatchString(v) {
+			return "build." + v, true
+		}
+	case "env":
+		if !c.inVariable {
+			c.warn(path, "HCL2 only has env in the defaults of variables, move it to one")
+		}
+		return fn("env", 1)
+	case "timestamp":
+		if len(exprs) == 0 {
+			c.timestamp = true
+			return "local.timestamp", true
+		}
+	case "isotime":
+		switch len(exprs) {
+		case 0:
+			return "timestamp()", true
+		case 1:
+			return fn("legacy_isotime", 1)
+		}
+	case "uuid":
+		return fn("uuidv4", 0)
+	case "build_name":
+		if len(exprs) == 0 {
+			return "build.name", true
+		}
+	case "build_type":
+		if len(exprs) == 0 {
+			return "build.type", true
+		}
+	case "template_dir":
+		if len(exprs) == 0 {
+			return "path.root", true
+		}
+	case "pwd":
+		if len(exprs) == 0 {
+			return "path.cwd", true
+		}
+	case "packer_version":
+		if len(exprs) == 0 {
+			return "packer.version", true
+		}
+	case "lower", "upper", "md5", "sha256", "sha512":
+		return fn(name, 1)
+	case "base64_encode", "base64_decode":
+		return fn(strings.Replace(name, "_", "", 1), 1)
+	case "clean_ami_name", "clean_image_name", "clean_resource_name":
+		return fn("clean_resource_name", 1)
+	case "consul_key":
+		return fn("consul_key", 1)
+	case "vault":
+		return fn("vault", 2)
+	case "split":
+		if len(exprs) == 3 {
+			return fmt.Sprintf("split(%s, %s)[%s]", exprs[1], exprs[0], exprs[2]), true
+		}
+	case "replace":
+		if len(exprs) == 3 {
+			return fmt.Sprintf("replace(%s, %s, %s)", exprs[2], exprs[0], exprs[1]), true
+		}
+	case "regex_replace":
+		if len(exprs) == 3 {
+			return fmt.Sprintf("regex_replace(%s, %s, %s)", exprs[2], exprs[0], exprs[1]), true
+		}
+	case "join":
+		if len(exprs) >= 2 {
+			return fmt.Sprintf("join(%s, %s)", exprs[0], hcl2List(exprs[1:])), true
+		}
+	case "trim":
+		switch len(exprs) {
+		case 1:
+			return fn("trimspace", 1)
+		case 2:
+			return fmt.Sprintf("trim(%s, %s)", exprs[1], exprs[0]), true
+		}
+	case "add", "sub", "mul", "div", "mod":
+		if len(exprs) == 2 {
+			op := map[string]string{"add": "+", "sub": "-", "mul": "*", "div": "/", "mod": "%"}[name]
+			return fmt.Sprintf("(%s %s %s)", exprs[0], op, exprs[1]), true
+		}
+	case "min", "max":
+		if len(exprs) > 0 {
+			return fmt.Sprintf("%s(%s)", name, strings.Join(exprs, ", ")), true
+		}
+	}
+	return "", false
+}
+
+func hcl2Escape(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		`"`, `\"`,
+		"\n", `\n`,
+		"\r", `\r`,
+		"\t", `\t`,
+		"${", "$${",
+		"%{", "%%{",
+	).Replace(s)
+}
+
+func hcl2Quote(s string) string {
+	return `"` + hcl2Escape(s) + `"`
+}
+
+func quoteAll(values []string) []string {
+	result := make([]string, len(values))
+	for i, v := range values {
+		result[i] = hcl2Quote(v)
+	}
+	return result
+}
+
+// hcl2List is a list on one line if it's short, or one item per line.
+func hcl2List(items []string) string {
+	oneLine := "[" + strings.Join(items, ", ") + "]"
+	if len(oneLine) <= 60 && !strings.Contains(oneLine, "\n") {
+		return oneLine
+	}
+
+	var buf strings.Builder
+	buf.WriteString("[\n")
+	for _, item := range items {
+		buf.WriteString("  " + strings.Replace(item, "\n", "\n  ", -1) + ",\n")
+	}
+	buf.WriteString("]")
+	return buf.String()
+}
+
+// writeHCL2 writes a body the way terraform fmt does: the equal signs of
+// consecutive attributes are aligned and blocks are apart.
+func writeHCL2(body []*hcl2Item, buf *bytes.Buffer, indent string) {
+	for i := 0; i < len(body); i++ {
+		item := body[i]
+		if item.Name == "" {
+			// Only the comments, where something has to be added by hand.
+			if i > 0 {
+				buf.WriteByte('\n')
+			}
+			writeComments(buf, todoComments(item.Todos), indent)
+			continue
+		}
+		if item.Block {
+			if i > 0 {
+				buf.WriteByte('\n')
+			}
+			writeComments(buf, todoComments(item.Todos), indent)
+			buf.WriteString(indent + item.Name)
+			for _, label := range item.Labels {
+				buf.WriteString(" " + hcl2Quote(label))
+			}
+			if len(item.Body) == 0 {
+				buf.WriteString(" {}\n")
+				continue
+			}
+			buf.WriteString(" {\n")
+			writeHCL2(item.Body, buf, indent+"  ")
+			buf.WriteString(indent + "}\n")
+			if i+1 < len(body) && !body[i+1].Block && body[i+1].Name != "" {
+				buf.WriteByte('\n')
+			}
+			continue
+		}
+
+		// The attributes up to the next block or comment are aligned.
+		end := i + 1
+		for end < len(body) && !body[end].Block && len(body[end].Todos) == 0 {
+			end++
+		}
+		width := 0
+		for _, a := range body[i:end] {
+			if len(a.Name) > width {
+				width = len(a.Name)
+			}
+		}
+		for _, a := range body[i:end] {
+			writeComments(buf, todoComments(a.Todos), indent)
+			value := strings.Replace(a.Value, "\n", "\n"+indent, -1)
+			fmt.Fprintf(buf, "%s%-*s = %s\n", indent, width, a.Name, value)
+		}
+		i = end - 1
+	}
+}
+
+func todoComments(todos []string) []string {
+	sort.Strings(todos)
+	result := make([]string, len(todos))
+	for i, todo := range todos {
+		result[i] = "TODO: " + todo
+	}
+	return result
+}
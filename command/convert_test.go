@@ -0,0 +1,125 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer/packer"
+	"github.com/stretchr/testify/assert"
+)
+
+func testConvert(t *testing.T, args ...string) (int, string, string) {
+	out, errOut := &strings.Builder{}, &strings.Builder{}
+	c := &ConvertCommand{
+		Meta: testMeta(t),
+	}
+	c.Ui = &packer.BasicUi{
+		Writer:      out,
+		ErrorWriter: errOut,
+	}
+	code := c.Run(args)
+	return code, out.String(), errOut.String()
+}
+
+func TestConvert_hcl2(t *testing.T) {
+	code, out, errOut := testConvert(t, "-output", "-", filepath.Join(testFixture("convert"), "template.json"))
+	if code != 0 {
+		t.Fatalf("bad: %d\n%s", code, errOut)
+	}
+
+	expected, err := ioutil.ReadFile(filepath.Join(testFixture("convert"), "template.pkr.hcl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, string(expected), out)
+
+	for _, w := range []string{
+		"push: HCL2 templates have no push",
+		"provisioners[0].inline[1]: {{sed `s/a/b/` `abc`}} has no HCL2 equivalent",
+	} {
+		if !strings.Contains(errOut, w) {
+			t.Errorf("no warning %q in:\n%s", w, errOut)
+		}
+	}
+	if strings.Contains(errOut, "env in the defaults") {
+		t.Errorf("env in a default shouldn't warn:\n%s", errOut)
+	}
+}
+
+func TestConvert_yaml(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// To YAML next to the template, and back to JSON.
+	contents, err := ioutil.ReadFile(filepath.Join(testFixture("convert"), "template.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "template.json")
+	if err := ioutil.WriteFile(path, contents, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if code, _, errOut := testConvert(t, "-format", "yaml", path); code != 0 {
+		t.Fatalf("bad: %d\n%s", code, errOut)
+	}
+	yamlContents, err := ioutil.ReadFile(filepath.Join(dir, "template.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := ioutil.ReadFile(filepath.Join(testFixture("convert"), "template.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, string(expected), string(yamlContents))
+
+	if code, _, _ := testConvert(t, "-format", "yaml", path); code != 1 {
+		t.Fatal("should refuse to overwrite the YAML template")
+	}
+
+	code, out, errOut := testConvert(t, "-format", "json", "-output", "-", filepath.Join(dir, "template.yaml"))
+	if code != 0 {
+		t.Fatalf("bad: %d\n%s", code, errOut)
+	}
+	if !strings.Contains(errOut, "comments of YAML templates") {
+		t.Fatalf("no warning about the comments:\n%s", errOut)
+	}
+	assert.Contains(t, out, `"home": "{{env `+"`HOME`"+`}}",`)
+	assert.Contains(t, out, `"volume_size": 40`)
+}
+
+func TestConvert_groups(t *testing.T) {
+	code, out, errOut := testConvert(t, "-output", "-", filepath.Join(testFixture("convert"), "groups.json"))
+	if code != 0 {
+		t.Fatalf("bad: %d\n%s", code, errOut)
+	}
+
+	expected := `  provisioner "shell" {
+    except = ["source.docker.b"]
+    inline = ["lock"]
+  }
+
+  # TODO: the provisioner group "shared" isn't in the template, copy its provisioners here
+}`
+	if !strings.Contains(out, expected) {
+		t.Fatalf("bad:\n%s", out)
+	}
+	if strings.Contains(out, "lock b") {
+		t.Fatalf("the provisioner only for b should be left out:\n%s", out)
+	}
+}
+
+func TestConvert_invalidTemplate(t *testing.T) {
+	if code, _, _ := testConvert(t, "-output", "-", filepath.Join(testFixture("fix-invalid"), "template.json")); code != 1 {
+		t.Fatal("should fail")
+	}
+	if code, _, _ := testConvert(t, "-format", "xml", filepath.Join(testFixture("convert"), "template.json")); code != 1 {
+		t.Fatal("should fail")
+	}
+}
@@ -0,0 +1,115 @@
+package command
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+// downloadStats counts the "download-cache" messages of the builds, to sum
+// up how the download cache was used once they are finished.
+type downloadStats struct {
+	sync.Mutex
+	hits   int
+	misses map[string]int
+}
+
+func newDownloadStats() *downloadStats {
+	return &downloadStats{misses: make(map[string]int)}
+}
+
+// Ui returns the ui of a build that counts its downloads.
+func (s *downloadStats) Ui(ui packer.Ui) packer.Ui {
+	return &downloadStatsUi{Ui: ui, stats: s}
+}
+
+func (s *downloadStats) record(args []string) {
+	if len(args) == 0 {
+		return
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	switch args[0] {
+	case "hit":
+		s.hits++
+	case "miss":
+		reason := "unknown"
+		if len(args) > 1 {
+			reason = args[1]
+		}
+		s.misses[reason]++
+	}
+}
+
+// downloadReasons are the reasons of the misses in the summary.
+var downloadReasons = map[string]string{
+	"not-cached":        "not in the cache",
+	"checksum-mismatch": "checksum mismatch",
+	"no-checksum":       "without checksum",
+	"forced":            "forced",
+	"size-mismatch":     "size mismatch",
+}
+
+// Report says how many files were reused from the cache and how many were
+// downloaded, and why. Nothing is said when nothing was downloaded.
+func (s *downloadStats) Report(ui packer.Ui) {
+	s.Lock()
+	defer s.Unlock()
+
+	var reasons []string
+	misses := 0
+	for reason, n := range s.misses {
+		reasons = append(reasons, reason)
+		misses += n
+	}
+	if s.hits+misses == 0 {
+		return
+	}
+	sort.Strings(reasons)
+
+	ui.Machine("download-cache-summary", "hits", strconv.Itoa(s.hits))
+	ui.Machine("download-cache-summary", "misses", strconv.Itoa(misses))
+
+	summary := fmt.Sprintf("\n==> Download cache: %d reused, %d downloaded", s.hits, misses)
+	if misses > 0 {
+		details := make([]string, len(reasons))
+		for i, reason := range reasons {
+			ui.Machine("download-cache-summary", "miss", reason, strconv.Itoa(s.misses[reason]))
+
+			text, ok := downloadReasons[reason]
+			if !ok {
+				text = reason
+			}
+			details[i] = fmt.Sprintf("%d %s", s.misses[reason], text)
+		}
+		summary += fmt.Sprintf(" (%s)", strings.Join(details, ", "))
+	}
+	ui.Say(summary)
+}
+
+// downloadStatsUi records the "download-cache" messages of a build before
+// passing them on.
+type downloadStatsUi struct {
+	packer.Ui
+	stats *downloadStats
+}
+
+func (u *downloadStatsUi) Machine(t string, args ...string) {
+	if t == "download-cache" {
+		u.stats.record(args)
+	}
+	u.Ui.Machine(t, args...)
+}
+
+func (u *downloadStatsUi) Log(level packer.UiLevel, message string) {
+	packer.UiLog(u.Ui, level, message)
+}
+
+func (u *downloadStatsUi) Output(stream, line string) {
+	packer.UiOutput(u.Ui, stream, line)
+}
@@ -0,0 +1,36 @@
+package command
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+func TestDownloadStats(t *testing.T) {
+	stats := newDownloadStats()
+	a := stats.Ui(new(packer.NoopUi))
+	b := stats.Ui(new(packer.NoopUi))
+
+	a.Machine("download-cache", "hit", "http://example.com/a.iso")
+	b.Machine("download-cache", "miss", "not-cached", "http://example.com/b.iso")
+	b.Machine("download-cache", "miss", "checksum-mismatch", "http://example.com/c.iso")
+	a.Machine("download-cache", "miss", "not-cached", "http://example.com/d.iso")
+	a.Machine("artifact-count", "1")
+
+	var out bytes.Buffer
+	stats.Report(&packer.BasicUi{Writer: &out})
+	expected := "Download cache: 1 reused, 3 downloaded (1 checksum mismatch, 2 not in the cache)"
+	if !strings.Contains(out.String(), expected) {
+		t.Fatalf("bad: %s", out.String())
+	}
+}
+
+func TestDownloadStats_none(t *testing.T) {
+	var out bytes.Buffer
+	newDownloadStats().Report(&packer.BasicUi{Writer: &out})
+	if out.Len() > 0 {
+		t.Fatalf("bad: %s", out.String())
+	}
+}
@@ -173,7 +173,7 @@ func (c *FixCommand) Synopsis() string {
 }
 
 func (c *FixCommand) AutocompleteArgs() complete.Predictor {
-	return complete.PredictNothing
+	return predictTemplates
 }
 
 func (c *FixCommand) AutocompleteFlags() complete.Flags {
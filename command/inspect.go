@@ -1,6 +1,7 @@
 package command
 
 import (
+	"encoding/json"
 	"fmt"
 	"sort"
 	"strings"
@@ -15,8 +16,10 @@ type InspectCommand struct {
 }
 
 func (c *InspectCommand) Run(args []string) int {
+	var cfgJSON bool
 	flags := c.Meta.FlagSet("inspect", FlagSetNone)
 	flags.Usage = func() { c.Ui.Say(c.Help()) }
+	flags.BoolVar(&cfgJSON, "json", false, "json")
 	if err := flags.Parse(args); err != nil {
 		return 1
 	}
@@ -34,6 +37,16 @@ func (c *InspectCommand) Run(args []string) int {
 		return 1
 	}
 
+	if cfgJSON {
+		out, err := json.MarshalIndent(newInspectTemplate(tpl), "", "  ")
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Failed to encode template: %s", err))
+			return 1
+		}
+		c.Ui.Say(string(out))
+		return 0
+	}
+
 	// Convenience...
 	ui := c.Ui
 
@@ -163,6 +176,7 @@ Usage: packer inspect TEMPLATE
 
 Options:
 
+  -json              Output a JSON description of the template
   -machine-readable  Machine-readable output
 `
 
@@ -174,11 +188,136 @@ func (c *InspectCommand) Synopsis() string {
 }
 
 func (c *InspectCommand) AutocompleteArgs() complete.Predictor {
-	return complete.PredictNothing
+	return predictTemplates
 }
 
 func (c *InspectCommand) AutocompleteFlags() complete.Flags {
 	return complete.Flags{
+		"-json":             complete.PredictNothing,
 		"-machine-readable": complete.PredictNothing,
 	}
 }
+
+// inspectTemplate is the JSON description of a template written by
+// "packer inspect -json". Everything is sorted or kept in template order so
+// the output is stable.
+type inspectTemplate struct {
+	Description      string                   `json:"description"`
+	MinPackerVersion string                   `json:"min_packer_version"`
+	Variables        []inspectVariable        `json:"variables"`
+	Builders         []inspectBuilder         `json:"builders"`
+	Provisioners     []inspectProvisioner     `json:"provisioners"`
+	PostProcessors   [][]inspectPostProcessor `json:"post_processors"`
+}
+
+type inspectVariable struct {
+	Name      string `json:"name"`
+	Default   string `json:"default"`
+	Required  bool   `json:"required"`
+	Sensitive bool   `json:"sensitive"`
+}
+
+type inspectBuilder struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type inspectProvisioner struct {
+	Type        string   `json:"type"`
+	Only        []string `json:"only"`
+	Except      []string `json:"except"`
+	Override    []string `json:"override"`
+	PauseBefore string   `json:"pause_before,omitempty"`
+}
+
+type inspectPostProcessor struct {
+	Type              string   `json:"type"`
+	Only              []string `json:"only"`
+	Except            []string `json:"except"`
+	KeepInputArtifact bool     `json:"keep_input_artifact"`
+}
+
+func newInspectTemplate(tpl *template.Template) *inspectTemplate {
+	result := &inspectTemplate{
+		Description:      tpl.Description,
+		MinPackerVersion: tpl.MinVersion,
+		Variables:        []inspectVariable{},
+		Builders:         []inspectBuilder{},
+		Provisioners:     []inspectProvisioner{},
+		PostProcessors:   [][]inspectPostProcessor{},
+	}
+
+	names := make([]string, 0, len(tpl.Variables))
+	for k := range tpl.Variables {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		v := tpl.Variables[k]
+		variable := inspectVariable{
+			Name:     k,
+			Default:  v.Default,
+			Required: v.Required,
+		}
+		for _, sensitive := range tpl.SensitiveVariables {
+			if sensitive == v {
+				variable.Default = "<sensitive>"
+				variable.Sensitive = true
+			}
+		}
+		result.Variables = append(result.Variables, variable)
+	}
+
+	names = make([]string, 0, len(tpl.Builders))
+	for k := range tpl.Builders {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		result.Builders = append(result.Builders, inspectBuilder{
+			Name: k,
+			Type: tpl.Builders[k].Type,
+		})
+	}
+
+	for _, p := range tpl.Provisioners {
+		provisioner := inspectProvisioner{
+			Type:     p.Type,
+			Only:     stringsOrEmpty(p.Only),
+			Except:   stringsOrEmpty(p.Except),
+			Override: []string{},
+		}
+		for k := range p.Override {
+			provisioner.Override = append(provisioner.Override, k)
+		}
+		sort.Strings(provisioner.Override)
+		if p.PauseBefore > 0 {
+			provisioner.PauseBefore = p.PauseBefore.String()
+		}
+		result.Provisioners = append(result.Provisioners, provisioner)
+	}
+
+	for _, chain := range tpl.PostProcessors {
+		sequence := make([]inspectPostProcessor, 0, len(chain))
+		for _, p := range chain {
+			sequence = append(sequence, inspectPostProcessor{
+				Type:              p.Type,
+				Only:              stringsOrEmpty(p.Only),
+				Except:            stringsOrEmpty(p.Except),
+				KeepInputArtifact: p.KeepInputArtifact,
+			})
+		}
+		result.PostProcessors = append(result.PostProcessors, sequence)
+	}
+
+	return result
+}
+
+// stringsOrEmpty makes sure a missing list is written as [] rather than
+// null, so consumers don't have to handle both.
+func stringsOrEmpty(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
@@ -0,0 +1,48 @@
+package command
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/packer/template"
+)
+
+func TestInspectTemplate(t *testing.T) {
+	tpl, err := template.ParseFile(filepath.Join(testFixture("inspect"), "template.json"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := &inspectTemplate{
+		Description: "inspect test",
+		Variables: []inspectVariable{
+			{Name: "optional", Default: "foo"},
+			{Name: "password", Default: "<sensitive>", Sensitive: true},
+			{Name: "required", Required: true},
+		},
+		Builders: []inspectBuilder{
+			{Name: "chocolate", Type: "file"},
+			{Name: "null", Type: "null"},
+		},
+		Provisioners: []inspectProvisioner{
+			{
+				Type:        "shell-local",
+				Only:        []string{"chocolate"},
+				Except:      []string{},
+				Override:    []string{"null"},
+				PauseBefore: "5s",
+			},
+		},
+		PostProcessors: [][]inspectPostProcessor{
+			{
+				{Type: "compress", Only: []string{}, Except: []string{}},
+				{Type: "checksum", Only: []string{}, Except: []string{"null"}, KeepInputArtifact: true},
+			},
+		},
+	}
+
+	if actual := newInspectTemplate(tpl); !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("bad:\n%#v\n\nexpected:\n%#v", actual, expected)
+	}
+}
@@ -0,0 +1,227 @@
+package command
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/hashicorp/packer/helper/ledger"
+	"github.com/hashicorp/packer/packer"
+)
+
+// defaultCleanupTimeout is how long builds get to clean up once
+// interrupted, unless -cleanup-timeout says otherwise.
+const defaultCleanupTimeout = 15 * time.Minute
+
+// interruptHandler cancels the running builds on the first SIGINT or
+// SIGTERM and gives them until the cleanup deadline to clean up after
+// themselves. A second interrupt stops waiting right away.
+type interruptHandler struct {
+	ui      packer.Ui
+	timeout time.Duration
+
+	lock        sync.Mutex
+	running     map[string]packer.Build
+	cancelling  map[string]bool
+	interrupted bool
+	expired     bool
+
+	sigCh     chan os.Signal
+	expiredCh chan struct{}
+	timer     *time.Timer
+}
+
+// newInterruptHandler handles the interrupts until Stop is called. A
+// timeout of zero waits for the cleanup as long as it takes.
+func newInterruptHandler(ui packer.Ui, timeout time.Duration) *interruptHandler {
+	h := &interruptHandler{
+		ui:         ui,
+		timeout:    timeout,
+		running:    make(map[string]packer.Build),
+		cancelling: make(map[string]bool),
+		sigCh:      make(chan os.Signal, 1),
+		expiredCh:  make(chan struct{}),
+	}
+
+	signal.Notify(h.sigCh, os.Interrupt, syscall.SIGTERM)
+	go h.handle()
+	return h
+}
+
+func (h *interruptHandler) handle() {
+	for range h.sigCh {
+		h.lock.Lock()
+		if h.expired {
+			h.lock.Unlock()
+			continue
+		}
+		if h.interrupted {
+			h.lock.Unlock()
+			h.ui.Error("Interrupted again, not waiting for the cleanup to finish.")
+			h.expire()
+			continue
+		}
+		h.interrupted = true
+		builds := make([]packer.Build, 0, len(h.running))
+		for _, b := range h.running {
+			builds = append(builds, b)
+		}
+		h.lock.Unlock()
+
+		msg := "Interrupted, cancelling the builds and cleaning up. Interrupt again to stop waiting."
+		if h.timeout > 0 {
+			msg = fmt.Sprintf("Interrupted, cancelling the builds and cleaning up for up to %s. "+
+				"Interrupt again to stop waiting.", h.timeout)
+		}
+		h.ui.Error(msg)
+
+		for _, b := range builds {
+			h.cancel(b)
+		}
+
+		if h.timeout > 0 {
+			h.lock.Lock()
+			h.timer = time.AfterFunc(h.timeout, func() {
+				h.ui.Error(fmt.Sprintf("The builds didn't clean up within %s, not waiting anymore.", h.timeout))
+				h.expire()
+			})
+			h.lock.Unlock()
+		}
+	}
+}
+
+// cancel cancels the build, which blocks until it's done cleaning up.
+func (h *interruptHandler) cancel(b packer.Build) {
+	h.lock.Lock()
+	if h.cancelling[b.Name()] {
+		h.lock.Unlock()
+		return
+	}
+	h.cancelling[b.Name()] = true
+	h.lock.Unlock()
+
+	go func() {
+		log.Printf("Stopping build: %s", b.Name())
+		b.Cancel()
+		log.Printf("Build cancelled: %s", b.Name())
+	}()
+}
+
+func (h *interruptHandler) expire() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if !h.expired {
+		h.expired = true
+		close(h.expiredCh)
+	}
+}
+
+// Start records that the build is running, to be cancelled on interrupt.
+// A build started after the interrupt is cancelled right away.
+func (h *interruptHandler) Start(b packer.Build) {
+	h.lock.Lock()
+	h.running[b.Name()] = b
+	interrupted := h.interrupted
+	h.lock.Unlock()
+
+	if interrupted {
+		h.cancel(b)
+	}
+}
+
+// Done records that the build is done, cleanup included.
+func (h *interruptHandler) Done(b packer.Build) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	delete(h.running, b.Name())
+}
+
+// Interrupted says whether Packer got interrupted.
+func (h *interruptHandler) Interrupted() bool {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	return h.interrupted
+}
+
+// Wait waits for the builds of wg to be done, or for the cleanup
+// deadline. It returns false if it stopped waiting with builds still
+// running.
+func (h *interruptHandler) Wait(wg *sync.WaitGroup) bool {
+	doneCh := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(doneCh)
+	}()
+
+	select {
+	case <-doneCh:
+		return true
+	case <-h.expiredCh:
+		return false
+	}
+}
+
+// Expired is closed once Packer stops waiting for the builds to clean up.
+func (h *interruptHandler) Expired() <-chan struct{} {
+	return h.expiredCh
+}
+
+// Running returns the names of the builds still running.
+func (h *interruptHandler) Running() []string {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	names := make([]string, 0, len(h.running))
+	for n := range h.running {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Stop stops handling interrupts.
+func (h *interruptHandler) Stop() {
+	signal.Stop(h.sigCh)
+	close(h.sigCh)
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if h.timer != nil {
+		h.timer.Stop()
+	}
+}
+
+// reportLeftovers tells about the builds that were still cleaning up, and
+// the resources of this run that are left in the ledger, if any.
+func reportLeftovers(ui packer.Ui, running []string) {
+	for _, n := range running {
+		ui.Error(fmt.Sprintf("Build '%s' didn't finish cleaning up.", n))
+	}
+
+	resources, err := ledger.ListRun(os.Getenv("PACKER_RUN_UUID"))
+	if err != nil {
+		log.Printf("[WARN] Failed to read the ledger: %s", err)
+		return
+	}
+	if len(resources) == 0 {
+		if len(running) > 0 {
+			ui.Error("Resources these builds created may still exist.")
+		}
+		return
+	}
+
+	ui.Error("\n==> These resources could not be cleaned up:")
+	for _, r := range resources {
+		ui.Machine("leftover-resource", r.Provider, r.Type, r.ID, r.Region)
+		ui.Error(fmt.Sprintf("--> %s", r))
+	}
+	ui.Error("Delete them with `packer cleanup` once Packer has exited.")
+}
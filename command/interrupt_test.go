@@ -0,0 +1,136 @@
+// +build !windows
+
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer/helper/ledger"
+	"github.com/hashicorp/packer/packer"
+)
+
+// interruptBuild is a build whose cleanup, started by Cancel, takes
+// cleanup long, or forever if it is zero.
+type interruptBuild struct {
+	packer.Build
+
+	name    string
+	cleanup time.Duration
+	wg      *sync.WaitGroup
+}
+
+func (b *interruptBuild) Name() string { return b.name }
+
+func (b *interruptBuild) Cancel() {
+	if b.cleanup == 0 {
+		select {}
+	}
+	time.Sleep(b.cleanup)
+	b.wg.Done()
+}
+
+func interrupt(t *testing.T) {
+	if err := syscall.Kill(os.Getpid(), syscall.SIGINT); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestInterruptHandler(t *testing.T) {
+	h := newInterruptHandler(packer.TestUi(t), time.Minute)
+	defer h.Stop()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	h.Start(&interruptBuild{name: "quick", cleanup: 10 * time.Millisecond, wg: &wg})
+
+	interrupt(t)
+	if !h.Wait(&wg) {
+		t.Fatal("the build should have cleaned up in time")
+	}
+	if !h.Interrupted() {
+		t.Fatal("should be interrupted")
+	}
+}
+
+func TestInterruptHandler_deadline(t *testing.T) {
+	h := newInterruptHandler(packer.TestUi(t), 50*time.Millisecond)
+	defer h.Stop()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	quick := &interruptBuild{name: "quick", cleanup: 10 * time.Millisecond, wg: &wg}
+	h.Start(quick)
+	h.Start(&interruptBuild{name: "stuck", wg: &wg})
+	go func() {
+		// The quick build is done once it cleaned up
+		time.Sleep(30 * time.Millisecond)
+		h.Done(quick)
+	}()
+
+	interrupt(t)
+	start := time.Now()
+	if h.Wait(&wg) {
+		t.Fatal("the stuck build can't be done")
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Fatalf("waited too long: %s", d)
+	}
+	if running := strings.Join(h.Running(), ","); running != "stuck" {
+		t.Fatalf("bad: %s", running)
+	}
+}
+
+func TestInterruptHandler_twice(t *testing.T) {
+	h := newInterruptHandler(packer.TestUi(t), 0)
+	defer h.Stop()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	h.Start(&interruptBuild{name: "stuck", wg: &wg})
+
+	interrupt(t)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		interrupt(t)
+	}()
+	if h.Wait(&wg) {
+		t.Fatal("the stuck build can't be done")
+	}
+}
+
+func TestReportLeftovers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer-ledger")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	oldDir, oldRun := os.Getenv("PACKER_LEDGER_DIR"), os.Getenv("PACKER_RUN_UUID")
+	defer os.Setenv("PACKER_LEDGER_DIR", oldDir)
+	defer os.Setenv("PACKER_RUN_UUID", oldRun)
+	os.Setenv("PACKER_LEDGER_DIR", dir)
+
+	os.Setenv("PACKER_RUN_UUID", "other")
+	ledger.Track(&ledger.Resource{Provider: "amazon", Type: "instance", ID: "i-other"})
+	os.Setenv("PACKER_RUN_UUID", "this")
+	ledger.Track(&ledger.Resource{Provider: "amazon", Type: "instance", ID: "i-this", Region: "us-east-1"})
+
+	m := testMeta(t)
+	reportLeftovers(m.Ui, []string{"stuck"})
+
+	_, stderr := outputCommand(t, m)
+	if !strings.Contains(stderr, "Build 'stuck' didn't finish cleaning up.") {
+		t.Fatalf("bad: %s", stderr)
+	}
+	if !strings.Contains(stderr, "--> amazon instance i-this (us-east-1)") {
+		t.Fatalf("bad: %s", stderr)
+	}
+	if strings.Contains(stderr, "i-other") {
+		t.Fatalf("the resources of other runs are not reported: %s", stderr)
+	}
+}
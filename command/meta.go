@@ -5,8 +5,8 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"strings"
 
-	"github.com/hashicorp/packer/helper/flag-kv"
 	"github.com/hashicorp/packer/helper/flag-slice"
 	"github.com/hashicorp/packer/packer"
 	"github.com/hashicorp/packer/template"
@@ -30,10 +30,22 @@ type Meta struct {
 	Ui         packer.Ui
 	Version    string
 
+	// Plugins are the binaries of the external plugins that were
+	// discovered.
+	Plugins []string
+
+	// OnError is the default of -on-error, and VarFiles the variable
+	// files loaded before all others, from the core config.
+	OnError  string
+	VarFiles []string
+
 	// These are set by command-line flags
 	flagBuildExcept []string
 	flagBuildOnly   []string
 	flagVars        map[string]string
+
+	// varSources is where each of flagVars comes from, for -show-vars
+	varSources map[string]string
 }
 
 // Core returns the core for the given template given the configured
@@ -55,50 +67,68 @@ func (m *Meta) Core(tpl *template.Template) (*packer.Core, error) {
 
 // BuildNames returns the list of builds that are in the given core
 // that we care about taking into account the only and except flags.
-func (m *Meta) BuildNames(c *packer.Core) []string {
-	// TODO: test
-
-	// Filter the "only"
-	if len(m.flagBuildOnly) > 0 {
-		// Build a set of all the available names
-		nameSet := make(map[string]struct{})
-		for _, n := range c.BuildNames() {
-			nameSet[n] = struct{}{}
-		}
+// Both flags take names, globs, or regular expressions between slashes,
+// which are matched against the build names and builder types.
+func (m *Meta) BuildNames(c *packer.Core) ([]string, error) {
+	if len(m.flagBuildOnly) == 0 && len(m.flagBuildExcept) == 0 {
+		// We care about everything
+		return c.BuildNames(), nil
+	}
+
+	only, err := parseBuildPatterns(m.flagBuildOnly)
+	if err != nil {
+		return nil, err
+	}
+	except, err := parseBuildPatterns(m.flagBuildExcept)
+	if err != nil {
+		return nil, err
+	}
 
-		// Build our result set which we pre-allocate some sane number
-		result := make([]string, 0, len(m.flagBuildOnly))
-		for _, n := range m.flagBuildOnly {
-			if _, ok := nameSet[n]; ok {
-				result = append(result, n)
+	// Keep track of the patterns that select nothing, those are most
+	// likely typos.
+	used := make(map[*buildPattern]bool)
+	match := func(patterns []*buildPattern, name, builderType string) bool {
+		matched := false
+		for _, p := range patterns {
+			if p.Match(name, builderType) {
+				used[p] = true
+				matched = true
 			}
 		}
-
-		return result
+		return matched
 	}
 
-	// Filter the "except"
-	if len(m.flagBuildExcept) > 0 {
-		// Build a set of the things we don't want
-		nameSet := make(map[string]struct{})
-		for _, n := range m.flagBuildExcept {
-			nameSet[n] = struct{}{}
+	var result, skipped []string
+	for _, n := range c.BuildNames() {
+		builderType := n
+		if b, ok := c.Template.Builders[n]; ok {
+			builderType = b.Type
 		}
 
-		// Build our result set which is the names of all builds except
-		// those in the given set.
-		names := c.BuildNames()
-		result := make([]string, 0, len(names))
-		for _, n := range names {
-			if _, ok := nameSet[n]; !ok {
-				result = append(result, n)
-			}
+		if len(only) > 0 && !match(only, n, builderType) {
+			skipped = append(skipped, n)
+			continue
 		}
-		return result
+		if match(except, n, builderType) {
+			skipped = append(skipped, n)
+			continue
+		}
+		result = append(result, n)
+	}
+
+	for _, p := range append(only, except...) {
+		if !used[p] {
+			m.Ui.Error(fmt.Sprintf("Warning: '%s' does not match any build", p.raw))
+		}
+	}
+	if len(result) > 0 {
+		m.Ui.Say(fmt.Sprintf("Selected builds: %s", strings.Join(result, ", ")))
+	}
+	if len(skipped) > 0 {
+		m.Ui.Say(fmt.Sprintf("Skipped builds: %s", strings.Join(skipped, ", ")))
 	}
 
-	// We care about everything
-	return c.BuildNames()
+	return result, nil
 }
 
 // FlagSet returns a FlagSet with the common flags that every
@@ -117,8 +147,8 @@ func (m *Meta) FlagSet(n string, fs FlagSetFlags) *flag.FlagSet {
 
 	// FlagSetVars tells us what variables to use
 	if fs&FlagSetVars != 0 {
-		f.Var((*kvflag.Flag)(&m.flagVars), "var", "")
-		f.Var((*kvflag.FlagJSON)(&m.flagVars), "var-file", "")
+		f.Var(&varFlag{m: m}, "var", "")
+		f.Var(&varFlag{m: m, file: true}, "var-file", "")
 	}
 
 	// Create an io.Writer that writes to our Ui properly for errors.
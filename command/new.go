@@ -0,0 +1,238 @@
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/posener/complete"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// NewCommand writes a starter template for a builder and provisioners.
+type NewCommand struct {
+	Meta
+}
+
+func (c *NewCommand) Run(args []string) int {
+	var cfgOutput string
+	var cfgForce bool
+	flags := c.Meta.FlagSet("new", FlagSetNone)
+	flags.Usage = func() { c.Ui.Say(c.Help()) }
+	flags.StringVar(&cfgOutput, "output", "", "output")
+	flags.BoolVar(&cfgForce, "force", false, "force")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	args = flags.Args()
+	if len(args) < 1 {
+		flags.Usage()
+		return 1
+	}
+
+	provisioners := args[1:]
+	if len(provisioners) == 0 {
+		provisioners = []string{"shell"}
+	}
+
+	out, err := newTemplate(args[0], provisioners)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	if cfgOutput == "" {
+		c.Ui.Say(string(out))
+		return 0
+	}
+
+	if _, err := os.Stat(cfgOutput); err == nil && !cfgForce {
+		c.Ui.Error(fmt.Sprintf("%s already exists, use -force to overwrite it", cfgOutput))
+		return 1
+	}
+	if err := ioutil.WriteFile(cfgOutput, append(out, '\n'), 0644); err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to write template: %s", err))
+		return 1
+	}
+	c.Ui.Say(fmt.Sprintf("Wrote %s. Check it with: packer validate %s", cfgOutput, cfgOutput))
+	return 0
+}
+
+// newTemplate generates the template for a builder and provisioners.
+func newTemplate(builder string, provisioners []string) ([]byte, error) {
+	b, ok := builderScaffolds[builder]
+	if !ok {
+		return nil, fmt.Errorf("Unknown builder %q. Available builders: %s",
+			builder, strings.Join(scaffoldNames(builderScaffolds), ", "))
+	}
+
+	comments := []string{
+		fmt.Sprintf("Generated by: packer new %s %s", builder, strings.Join(provisioners, " ")),
+	}
+	comments = append(comments, scaffoldComments("builders", builder, b)...)
+
+	variables := orderedObject{}
+	addVariables := func(s *scaffold) {
+		for _, v := range s.Variables {
+			if !variables.has(v.Key) {
+				variables = append(variables, keyValue(v))
+			}
+		}
+	}
+	addVariables(b)
+
+	var provs []orderedObject
+	for _, name := range provisioners {
+		p, ok := provisionerScaffolds[name]
+		if !ok {
+			return nil, fmt.Errorf("Unknown provisioner %q. Available provisioners: %s",
+				name, strings.Join(scaffoldNames(provisionerScaffolds), ", "))
+		}
+		addVariables(p)
+		provs = append(provs, scaffoldObject(name, p))
+		comments = append(comments, scaffoldComments("provisioners", name, p)...)
+	}
+
+	tpl := orderedObject{
+		{"_comment", comments},
+	}
+	if len(variables) > 0 {
+		tpl = append(tpl, keyValue{"variables", variables})
+	}
+	tpl = append(tpl, keyValue{"builders", []orderedObject{scaffoldObject(builder, b)}})
+	if len(provs) > 0 {
+		tpl = append(tpl, keyValue{"provisioners", provs})
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(tpl); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSpace(buf.Bytes()), nil
+}
+
+func scaffoldObject(typ string, s *scaffold) orderedObject {
+	result := orderedObject{{"type", typ}}
+	for _, setting := range s.Settings {
+		result = append(result, keyValue(setting))
+	}
+	return result
+}
+
+func scaffoldComments(kind, typ string, s *scaffold) []string {
+	result := []string{
+		"",
+		fmt.Sprintf("%s: https://www.packer.io/docs/%s/%s.html", typ, kind, typ),
+	}
+	result = append(result, s.Notes...)
+	if len(s.Optional) > 0 {
+		result = append(result, "Some of its other options:")
+		for _, o := range s.Optional {
+			result = append(result, fmt.Sprintf("  %s - %s", o.Key, o.Description))
+		}
+	}
+	return result
+}
+
+func scaffoldNames(scaffolds map[string]*scaffold) []string {
+	result := make([]string, 0, len(scaffolds))
+	for k := range scaffolds {
+		result = append(result, k)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// keyValue is a member of an orderedObject.
+type keyValue struct {
+	Key   string
+	Value interface{}
+}
+
+// orderedObject is a JSON object that keeps its keys in order, so that
+// generated templates read top to bottom.
+type orderedObject []keyValue
+
+func (o orderedObject) has(key string) bool {
+	for _, kv := range o {
+		if kv.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func (o orderedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, kv := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		// Boot commands are full of <>, don't escape them.
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		if err := enc.Encode(kv.Key); err != nil {
+			return nil, err
+		}
+		buf.WriteByte(':')
+		if err := enc.Encode(kv.Value); err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (o orderedObject) MarshalYAML() (interface{}, error) {
+	result := make(yaml.MapSlice, len(o))
+	for i, kv := range o {
+		result[i] = yaml.MapItem{Key: kv.Key, Value: kv.Value}
+	}
+	return result, nil
+}
+
+func (*NewCommand) Help() string {
+	helpText := `
+Usage: packer new [options] BUILDER [PROVISIONER...]
+
+  Writes a starter template for the given builder and provisioners. The
+  provisioners default to shell. The template has the settings needed to
+  get going, and a comment listing some of the other options.
+
+Builders: ` + strings.Join(scaffoldNames(builderScaffolds), ", ") + `
+Provisioners: ` + strings.Join(scaffoldNames(provisionerScaffolds), ", ") + `
+
+Options:
+
+  -output=path  Write the template to this file instead of the output.
+  -force        Overwrite the file given with -output if it exists.
+`
+
+	return strings.TrimSpace(helpText)
+}
+
+func (*NewCommand) Synopsis() string {
+	return "generate a starter template"
+}
+
+func (*NewCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictSet(append(
+		scaffoldNames(builderScaffolds),
+		scaffoldNames(provisionerScaffolds)...)...)
+}
+
+func (*NewCommand) AutocompleteFlags() complete.Flags {
+	return complete.Flags{
+		"-output": complete.PredictFiles("*.json"),
+		"-force":  complete.PredictNothing,
+	}
+}
@@ -0,0 +1,343 @@
+package command
+
+// scaffoldSetting is a key of a generated template with its value.
+type scaffoldSetting struct {
+	Key   string
+	Value interface{}
+}
+
+// scaffoldOption is an optional setting that is described in the comments
+// of a generated template.
+type scaffoldOption struct {
+	Key         string
+	Description string
+}
+
+// scaffold describes how "packer new" generates a builder or provisioner.
+type scaffold struct {
+	// Variables are added to the variables of the template. A nil value
+	// makes the variable required.
+	Variables []scaffoldSetting
+
+	// Settings make up the configuration of the component, next to its
+	// type. They are the least that is needed for it to work.
+	Settings []scaffoldSetting
+
+	Optional []scaffoldOption
+
+	// Notes are added to the comments as they are.
+	Notes []string
+}
+
+var kickstartNotes = []string{
+	"The boot_command starts a kickstart install from http/ks.cfg, which " +
+		"has to be written. It must create the user packer with the password " +
+		"packer, and enable sshd.",
+	"Set iso_url and iso_checksum to the install ISO of your distribution.",
+}
+
+var builderScaffolds = map[string]*scaffold{
+	"amazon-ebs": {
+		Variables: []scaffoldSetting{
+			{"aws_access_key", "{{env `AWS_ACCESS_KEY_ID`}}"},
+			{"aws_secret_key", "{{env `AWS_SECRET_ACCESS_KEY`}}"},
+			{"region", "us-east-1"},
+		},
+		Settings: []scaffoldSetting{
+			{"access_key", "{{user `aws_access_key`}}"},
+			{"secret_key", "{{user `aws_secret_key`}}"},
+			{"region", "{{user `region`}}"},
+			{"source_ami_filter", orderedObject{
+				{"filters", orderedObject{
+					{"virtualization-type", "hvm"},
+					{"name", "ubuntu/images/*ubuntu-bionic-18.04-amd64-server-*"},
+					{"root-device-type", "ebs"},
+				}},
+				{"owners", []string{"099720109477"}},
+				{"most_recent", true},
+			}},
+			{"instance_type", "t2.micro"},
+			{"ssh_username", "ubuntu"},
+			{"ami_name", "packer-example-{{timestamp}}"},
+		},
+		Optional: []scaffoldOption{
+			{"ami_description", "A description for the AMI."},
+			{"ami_regions", "Copy the AMI to these regions."},
+			{"ami_users", "Account IDs to share the AMI with."},
+			{"encrypt_boot", "Encrypt the root volume of the AMI."},
+			{"launch_block_device_mappings", "Change the volumes of the instance, such as the size of the root volume."},
+			{"subnet_id", "Launch the instance in this subnet instead of the default VPC."},
+			{"tags", "Tags for the AMI and its snapshots."},
+		},
+	},
+
+	"azure-arm": {
+		Variables: []scaffoldSetting{
+			{"client_id", "{{env `ARM_CLIENT_ID`}}"},
+			{"client_secret", "{{env `ARM_CLIENT_SECRET`}}"},
+			{"subscription_id", "{{env `ARM_SUBSCRIPTION_ID`}}"},
+			{"resource_group", nil},
+		},
+		Settings: []scaffoldSetting{
+			{"client_id", "{{user `client_id`}}"},
+			{"client_secret", "{{user `client_secret`}}"},
+			{"subscription_id", "{{user `subscription_id`}}"},
+			{"managed_image_resource_group_name", "{{user `resource_group`}}"},
+			{"managed_image_name", "packer-example-{{timestamp}}"},
+			{"os_type", "Linux"},
+			{"image_publisher", "Canonical"},
+			{"image_offer", "UbuntuServer"},
+			{"image_sku", "18.04-LTS"},
+			{"location", "East US"},
+			{"vm_size", "Standard_DS2_v2"},
+		},
+		Optional: []scaffoldOption{
+			{"azure_tags", "Tags for the resources the build creates."},
+			{"build_resource_group_name", "Build in this existing resource group instead of a temporary one."},
+			{"os_disk_size_gb", "The size of the OS disk."},
+			{"tenant_id", "The tenant of the service principal, looked up by default."},
+			{"virtual_network_name", "Build in this existing virtual network."},
+		},
+		Notes: []string{
+			"The resource group of the image must exist before the build.",
+		},
+	},
+
+	"digitalocean": {
+		Variables: []scaffoldSetting{
+			{"api_token", "{{env `DIGITALOCEAN_API_TOKEN`}}"},
+		},
+		Settings: []scaffoldSetting{
+			{"api_token", "{{user `api_token`}}"},
+			{"image", "ubuntu-18-04-x64"},
+			{"region", "nyc3"},
+			{"size", "s-1vcpu-1gb"},
+			{"ssh_username", "root"},
+			{"snapshot_name", "packer-example-{{timestamp}}"},
+		},
+		Optional: []scaffoldOption{
+			{"droplet_name", "The name of the droplet used for the build."},
+			{"monitoring", "Enable monitoring for the droplet."},
+			{"private_networking", "Enable private networking for the droplet."},
+			{"snapshot_regions", "Copy the snapshot to these regions."},
+			{"tags", "Tags for the droplet."},
+		},
+	},
+
+	"docker": {
+		Settings: []scaffoldSetting{
+			{"image", "ubuntu:18.04"},
+			{"commit", true},
+		},
+		Optional: []scaffoldOption{
+			{"changes", "Dockerfile instructions, like CMD or ENV, to apply to the image."},
+			{"export_path", "Export the container to this tar file instead of committing it."},
+			{"pull", "Pull the image before the build. Defaults to true."},
+			{"run_command", "The arguments of docker run for the build container."},
+			{"volumes", "Host directories to mount into the container."},
+		},
+		Notes: []string{
+			"Add the docker-tag post-processor to tag the committed image.",
+		},
+	},
+
+	"googlecompute": {
+		Variables: []scaffoldSetting{
+			{"project_id", nil},
+		},
+		Settings: []scaffoldSetting{
+			{"project_id", "{{user `project_id`}}"},
+			{"source_image_family", "ubuntu-1804-lts"},
+			{"zone", "us-central1-a"},
+			{"ssh_username", "packer"},
+			{"image_name", "packer-example-{{timestamp}}"},
+		},
+		Optional: []scaffoldOption{
+			{"account_file", "A JSON key file of a service account, instead of the default credentials."},
+			{"disk_size", "The size of the disk in GB."},
+			{"image_family", "Add the image to this family."},
+			{"machine_type", "The machine type of the build instance."},
+			{"network", "Build in this network instead of default."},
+			{"preemptible", "Use a preemptible instance."},
+		},
+	},
+
+	"null": {
+		Variables: []scaffoldSetting{
+			{"ssh_host", nil},
+			{"ssh_username", nil},
+			{"ssh_password", nil},
+		},
+		Settings: []scaffoldSetting{
+			{"ssh_host", "{{user `ssh_host`}}"},
+			{"ssh_username", "{{user `ssh_username`}}"},
+			{"ssh_password", "{{user `ssh_password`}}"},
+		},
+		Optional: []scaffoldOption{
+			{"communicator", "Set to winrm to connect to a Windows machine, or none to only run shell-local."},
+			{"ssh_port", "The SSH port of the machine."},
+			{"ssh_private_key_file", "A private key to log in with instead of a password."},
+		},
+		Notes: []string{
+			"The null builder creates nothing, it runs provisioners on an existing machine.",
+		},
+	},
+
+	"qemu": {
+		Variables: []scaffoldSetting{
+			{"iso_url", nil},
+			{"iso_checksum", nil},
+		},
+		Settings: []scaffoldSetting{
+			{"iso_url", "{{user `iso_url`}}"},
+			{"iso_checksum", "{{user `iso_checksum`}}"},
+			{"iso_checksum_type", "sha256"},
+			{"output_directory", "output-qemu"},
+			{"disk_size", 10240},
+			{"format", "qcow2"},
+			{"http_directory", "http"},
+			{"ssh_username", "packer"},
+			{"ssh_password", "packer"},
+			{"ssh_timeout", "30m"},
+			{"shutdown_command", "echo 'packer' | sudo -S shutdown -P now"},
+			{"boot_wait", "10s"},
+			{"boot_command", []string{
+				"<tab> text ks=http://{{ .HTTPIP }}:{{ .HTTPPort }}/ks.cfg<enter><wait>",
+			}},
+		},
+		Optional: []scaffoldOption{
+			{"accelerator", "The accelerator to use, such as kvm or hvf."},
+			{"disk_interface", "The interface of the disk, such as virtio or ide."},
+			{"headless", "Don't show the console of the virtual machine."},
+			{"qemuargs", "Extra arguments for qemu, such as -m to set the memory."},
+			{"vm_name", "The name of the disk image."},
+		},
+		Notes: kickstartNotes,
+	},
+
+	"virtualbox-iso": {
+		Variables: []scaffoldSetting{
+			{"iso_url", nil},
+			{"iso_checksum", nil},
+		},
+		Settings: []scaffoldSetting{
+			{"guest_os_type", "RedHat_64"},
+			{"iso_url", "{{user `iso_url`}}"},
+			{"iso_checksum", "{{user `iso_checksum`}}"},
+			{"iso_checksum_type", "sha256"},
+			{"http_directory", "http"},
+			{"ssh_username", "packer"},
+			{"ssh_password", "packer"},
+			{"ssh_timeout", "30m"},
+			{"shutdown_command", "echo 'packer' | sudo -S shutdown -P now"},
+			{"boot_wait", "10s"},
+			{"boot_command", []string{
+				"<tab> text ks=http://{{ .HTTPIP }}:{{ .HTTPPort }}/ks.cfg<enter><wait>",
+			}},
+		},
+		Optional: []scaffoldOption{
+			{"disk_size", "The size of the disk in MB."},
+			{"format", "Export the virtual machine as ovf or ova."},
+			{"guest_additions_mode", "Set to disable to skip uploading the guest additions."},
+			{"headless", "Don't show the console of the virtual machine."},
+			{"vboxmanage", "VBoxManage commands to run before booting, such as setting the memory."},
+			{"vm_name", "The name of the virtual machine."},
+		},
+		Notes: kickstartNotes,
+	},
+
+	"vmware-iso": {
+		Variables: []scaffoldSetting{
+			{"iso_url", nil},
+			{"iso_checksum", nil},
+		},
+		Settings: []scaffoldSetting{
+			{"guest_os_type", "centos-64"},
+			{"iso_url", "{{user `iso_url`}}"},
+			{"iso_checksum", "{{user `iso_checksum`}}"},
+			{"iso_checksum_type", "sha256"},
+			{"http_directory", "http"},
+			{"ssh_username", "packer"},
+			{"ssh_password", "packer"},
+			{"ssh_timeout", "30m"},
+			{"shutdown_command", "echo 'packer' | sudo -S shutdown -P now"},
+			{"boot_wait", "10s"},
+			{"boot_command", []string{
+				"<tab> text ks=http://{{ .HTTPIP }}:{{ .HTTPPort }}/ks.cfg<enter><wait>",
+			}},
+		},
+		Optional: []scaffoldOption{
+			{"disk_size", "The size of the disk in MB."},
+			{"headless", "Don't show the console of the virtual machine."},
+			{"network", "The network type, such as nat or bridged."},
+			{"vm_name", "The name of the virtual machine."},
+			{"vmx_data", "Settings for the VMX file, such as memsize and numvcpus."},
+		},
+		Notes: kickstartNotes,
+	},
+}
+
+var provisionerScaffolds = map[string]*scaffold{
+	"ansible": {
+		Settings: []scaffoldSetting{
+			{"playbook_file", "./playbook.yml"},
+		},
+		Optional: []scaffoldOption{
+			{"ansible_env_vars", "Environment variables for ansible-playbook."},
+			{"extra_arguments", "Extra arguments for ansible-playbook, such as --tags."},
+			{"galaxy_file", "A requirements file to install roles from with ansible-galaxy."},
+			{"groups", "The inventory groups to put the machine in."},
+			{"user", "The user to run the playbook as."},
+		},
+		Notes: []string{
+			"The ansible provisioner runs ansible-playbook on the machine running Packer.",
+		},
+	},
+
+	"file": {
+		Settings: []scaffoldSetting{
+			{"source", "./files/"},
+			{"destination", "/tmp/"},
+		},
+		Optional: []scaffoldOption{
+			{"direction", "Set to download to copy from the machine instead."},
+		},
+	},
+
+	"powershell": {
+		Settings: []scaffoldSetting{
+			{"inline", []string{"Write-Host 'Hello from Packer'"}},
+		},
+		Optional: []scaffoldOption{
+			{"elevated_user", "Run the scripts as this user, with elevated privileges."},
+			{"environment_vars", "Environment variables for the scripts, as KEY=value."},
+			{"script", "Run this local script instead of inline commands."},
+			{"scripts", "Run these local scripts in order instead of inline commands."},
+		},
+	},
+
+	"shell": {
+		Settings: []scaffoldSetting{
+			{"inline", []string{"echo 'Hello from Packer'"}},
+		},
+		Optional: []scaffoldOption{
+			{"environment_vars", "Environment variables for the scripts, as KEY=value."},
+			{"execute_command", "How to run the scripts, such as with sudo."},
+			{"expect_disconnect", "Allow the scripts to reboot the machine."},
+			{"script", "Run this local script instead of inline commands."},
+			{"scripts", "Run these local scripts in order instead of inline commands."},
+		},
+	},
+
+	"shell-local": {
+		Settings: []scaffoldSetting{
+			{"inline", []string{"echo 'Hello from the machine running Packer'"}},
+		},
+		Optional: []scaffoldOption{
+			{"environment_vars", "Environment variables for the scripts, as KEY=value."},
+			{"execute_command", "How to run the scripts."},
+			{"script", "Run this script instead of inline commands."},
+			{"scripts", "Run these scripts in order instead of inline commands."},
+		},
+	},
+}
@@ -0,0 +1,60 @@
+package command
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer/template"
+)
+
+func TestNewTemplate(t *testing.T) {
+	for _, builder := range scaffoldNames(builderScaffolds) {
+		out, err := newTemplate(builder, scaffoldNames(provisionerScaffolds))
+		if err != nil {
+			t.Fatalf("%s: %s", builder, err)
+		}
+
+		tpl, err := template.Parse(bytes.NewReader(out))
+		if err != nil {
+			t.Fatalf("%s: %s\n%s", builder, err, out)
+		}
+		if err := tpl.Validate(); err != nil {
+			t.Fatalf("%s: %s\n%s", builder, err, out)
+		}
+		if len(tpl.Builders) != 1 || tpl.Builders[builder] == nil {
+			t.Fatalf("%s: bad builders: %#v", builder, tpl.Builders)
+		}
+		if len(tpl.Provisioners) != len(provisionerScaffolds) {
+			t.Fatalf("%s: bad provisioners: %#v", builder, tpl.Provisioners)
+		}
+		if strings.Contains(string(out), `\u003c`) {
+			t.Fatalf("%s: escaped output:\n%s", builder, out)
+		}
+	}
+}
+
+func TestNewTemplate_unknown(t *testing.T) {
+	if _, err := newTemplate("foo", []string{"shell"}); err == nil {
+		t.Fatal("should error on unknown builder")
+	}
+	if _, err := newTemplate("docker", []string{"foo"}); err == nil {
+		t.Fatal("should error on unknown provisioner")
+	}
+}
+
+func TestNewTemplate_order(t *testing.T) {
+	out, err := newTemplate("docker", []string{"shell"})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	s := string(out)
+	if !(strings.Index(s, `"_comment"`) < strings.Index(s, `"builders"`) &&
+		strings.Index(s, `"builders"`) < strings.Index(s, `"provisioners"`)) {
+		t.Fatalf("bad order:\n%s", s)
+	}
+	if !(strings.Index(s, `"type": "docker"`) < strings.Index(s, `"image"`)) {
+		t.Fatalf("type should come first:\n%s", s)
+	}
+}
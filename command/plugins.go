@@ -0,0 +1,135 @@
+package command
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/packer/helper/pluginlock"
+	"github.com/hashicorp/packer/version"
+	"github.com/mitchellh/cli"
+	"github.com/posener/complete"
+)
+
+// PluginsCommand only shows the help of its subcommands.
+type PluginsCommand struct {
+	Meta
+}
+
+func (c *PluginsCommand) Run(args []string) int {
+	return cli.RunResultHelp
+}
+
+func (*PluginsCommand) Help() string {
+	helpText := `
+Usage: packer plugins <subcommand> [options]
+
+  Manages the external plugins Packer runs.
+`
+
+	return strings.TrimSpace(helpText)
+}
+
+func (*PluginsCommand) Synopsis() string {
+	return "manage the external plugins"
+}
+
+// PluginsLockCommand writes the lockfile of the discovered plugins.
+type PluginsLockCommand struct {
+	Meta
+}
+
+func (c *PluginsLockCommand) Run(args []string) int {
+	flags := c.Meta.FlagSet("plugins lock", FlagSetNone)
+	flags.Usage = func() { c.Ui.Say(c.Help()) }
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	if len(flags.Args()) != 0 {
+		flags.Usage()
+		return 1
+	}
+
+	path := os.Getenv(pluginlock.EnvLockfile)
+	if path == "" {
+		path = pluginlock.ProjectLockfile
+	}
+	lock, err := pluginlock.Load(path)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	found := make(map[string]bool)
+	for _, bin := range c.Plugins {
+		name := filepath.Base(bin)
+		found[name] = true
+
+		p, err := lock.Lock(bin)
+		if err != nil {
+			c.Ui.Error(err.Error())
+			return 1
+		}
+		if p.VerifyWith != "" {
+			if err := lock.Verify(bin); err != nil {
+				c.Ui.Error(err.Error())
+				return 1
+			}
+		}
+		c.Ui.Say(fmt.Sprintf("Locked %s: %s", name, p.SHA256))
+		c.Ui.Machine("plugin-lock", name, p.SHA256)
+	}
+
+	var removed []string
+	for name := range lock.Plugins {
+		if !found[name] {
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(removed)
+	for _, name := range removed {
+		delete(lock.Plugins, name)
+		c.Ui.Say(fmt.Sprintf("Removed %s, it wasn't found", name))
+	}
+
+	lock.PackerVersion = version.Version
+	if err := lock.Save(); err != nil {
+		c.Ui.Error(fmt.Sprintf("Error writing the plugin lockfile %s: %s", path, err))
+		return 1
+	}
+
+	c.Ui.Say(fmt.Sprintf("Locked %d plugins and Packer %s in %s", len(c.Plugins), lock.PackerVersion, path))
+	return 0
+}
+
+func (*PluginsLockCommand) Help() string {
+	helpText := `
+Usage: packer plugins lock
+
+  Records the SHA256 sums of the external plugins Packer finds, and the
+  version of Packer, in packer.lock in the current directory, or in the
+  file PACKER_PLUGIN_LOCKFILE points to. Packer then only runs these
+  plugins, with this version of Packer, while the lockfile is there.
+
+  Commit packer.lock with the templates so the builds elsewhere, like in
+  CI, use exactly the plugins that were tested locally. Run this command
+  again to update it after installing or upgrading plugins.
+`
+
+	return strings.TrimSpace(helpText)
+}
+
+func (*PluginsLockCommand) Synopsis() string {
+	return "lock the plugins to the ones installed"
+}
+
+func (*PluginsLockCommand) AutocompleteArgs() complete.Predictor {
+	return complete.PredictNothing
+}
+
+func (*PluginsLockCommand) AutocompleteFlags() complete.Flags {
+	return complete.Flags{}
+}
@@ -0,0 +1,60 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer/helper/pluginlock"
+	"github.com/hashicorp/packer/version"
+)
+
+func TestPluginsLockCommand(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer-plugins")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	bin := filepath.Join(dir, "packer-builder-foo")
+	if err := ioutil.WriteFile(bin, []byte("plugin"), 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	lockPath := filepath.Join(dir, "packer.lock")
+	old := `{"packer_version": "0.1.0", "plugins": {"packer-builder-gone": {"sha256": "` +
+		strings.Repeat("0", 64) + `"}}}`
+	if err := ioutil.WriteFile(lockPath, []byte(old), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.Setenv(pluginlock.EnvLockfile, os.Getenv(pluginlock.EnvLockfile))
+	os.Setenv(pluginlock.EnvLockfile, lockPath)
+
+	c := &PluginsLockCommand{
+		Meta: testMeta(t),
+	}
+	c.Plugins = []string{bin}
+	if code := c.Run(nil); code != 0 {
+		fatalCommand(t, c.Meta)
+	}
+
+	lock, err := pluginlock.Load(lockPath)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if lock.PackerVersion != version.Version {
+		t.Fatalf("bad: %s", lock.PackerVersion)
+	}
+	if len(lock.Plugins) != 1 {
+		t.Fatalf("the plugins that are gone are removed: %#v", lock.Plugins)
+	}
+	if err := lock.Verify(bin); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	out, _ := outputCommand(t, c.Meta)
+	if !strings.Contains(out, "Removed packer-builder-gone") {
+		t.Fatalf("bad: %s", out)
+	}
+}
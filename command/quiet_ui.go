@@ -0,0 +1,34 @@
+package command
+
+import (
+	"log"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+// quietUi is the ui of a build with -quiet. It only passes on the step
+// headers, warnings and errors, and what the provisioners write to stderr;
+// the details, like what they write to stdout, are only logged.
+type quietUi struct {
+	packer.Ui
+}
+
+func (u *quietUi) Message(message string) {
+	log.Printf("ui: %s", message)
+}
+
+func (u *quietUi) Output(stream, line string) {
+	if stream == packer.UiStreamStdout {
+		log.Printf("ui %s: %s", stream, line)
+		return
+	}
+	packer.UiOutput(u.Ui, stream, line)
+}
+
+func (u *quietUi) Log(level packer.UiLevel, message string) {
+	if level < packer.UiLevelInfo {
+		log.Printf("ui %s: %s", level, message)
+		return
+	}
+	packer.UiLog(u.Ui, level, message)
+}
@@ -0,0 +1,26 @@
+package command
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+func TestQuietUi(t *testing.T) {
+	var out bytes.Buffer
+	ui := &quietUi{Ui: &packer.BasicUi{Writer: &out}}
+
+	ui.Say("==> step")
+	ui.Message("details")
+	ui.Output(packer.UiStreamStdout, "stdout of the provisioner")
+	ui.Output(packer.UiStreamStderr, "stderr of the provisioner")
+	ui.Log(packer.UiLevelDebug, "debug")
+	ui.Log(packer.UiLevelWarn, "warning")
+	ui.Error("error")
+
+	expected := "==> step\nstderr of the provisioner\nwarning\nerror\n"
+	if out.String() != expected {
+		t.Fatalf("bad: %#v", out.String())
+	}
+}
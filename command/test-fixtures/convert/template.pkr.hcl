@@ -0,0 +1,69 @@
+# Builds the web image.
+
+packer {
+  required_version = ">= 1.3.0"
+}
+
+locals {
+  timestamp = regex_replace(timestamp(), "[- TZ:]", "")
+}
+
+variable "region" {
+  type    = string
+  default = "us-east-1"
+}
+
+variable "home" {
+  type    = string
+  default = env("HOME")
+}
+
+variable "password" {
+  type      = string
+  sensitive = true
+}
+
+source "amazon-ebs" "web" {
+  region        = var.region
+  ami_name      = "web ${local.timestamp}"
+  instance_type = "t2.micro"
+
+  launch_block_device_mappings {
+    device_name = "/dev/sda1"
+    volume_size = 40
+  }
+
+  tags = {
+    Name  = upper(var.region)
+    Build = uuidv4()
+  }
+}
+
+source "docker" "docker" {
+  image  = "ubuntu"
+  commit = true
+}
+
+build {
+  description = "The web image"
+  sources     = ["source.amazon-ebs.web", "source.docker.docker"]
+
+  provisioner "shell" {
+    only = ["source.amazon-ebs.web"]
+    # TODO: provisioners[0].inline[1]: {{sed `s/a/b/` `abc`}} has no HCL2 equivalent, it was kept as a Go template
+    inline = [
+      "echo ${build.Host} \"$${HOME}\"",
+      "echo {{sed `s/a/b/` `abc`}}",
+    ]
+  }
+
+  post-processor "compress" {}
+
+  post-processors {
+    post-processor "docker-tag" {
+      repository = "web"
+    }
+
+    post-processor "docker-push" {}
+  }
+}
@@ -0,0 +1,406 @@
+package command
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/packer/helper/flag-slice"
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/template"
+
+	"github.com/posener/complete"
+)
+
+type TestCommand struct {
+	Meta
+}
+
+// testCaseResult is the result of one build of a test.
+type testCaseResult struct {
+	Test     string
+	Build    string
+	Duration time.Duration
+	Err      error
+}
+
+func (c *TestCommand) Run(args []string) int {
+	var cfgJUnit string
+	var cfgKeepArtifacts bool
+	var cfgCleanupTimeout time.Duration
+	var cfgOnly, cfgExcept []string
+	flags := c.Meta.FlagSet("test", FlagSetVars)
+	flags.Usage = func() { c.Ui.Say(c.Help()) }
+	flags.StringVar(&cfgJUnit, "junit", "", "")
+	flags.BoolVar(&cfgKeepArtifacts, "keep-artifacts", false, "")
+	flags.DurationVar(&cfgCleanupTimeout, "cleanup-timeout", defaultCleanupTimeout, "")
+	flags.Var((*sliceflag.StringFlag)(&cfgOnly), "only", "")
+	flags.Var((*sliceflag.StringFlag)(&cfgExcept), "except", "")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
+	args = flags.Args()
+	if len(args) != 1 {
+		flags.Usage()
+		return 1
+	}
+
+	// Parse the template
+	tpl, err := template.ParseFile(args[0])
+	if err != nil {
+		c.Ui.Error(fmt.Sprintf("Failed to parse template: %s", err))
+		return 1
+	}
+
+	if err := c.Meta.LoadAutoVarFiles(tpl); err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+
+	tests, err := selectTests(tpl.Tests, cfgOnly, cfgExcept)
+	if err != nil {
+		c.Ui.Error(err.Error())
+		return 1
+	}
+	if len(tests) == 0 {
+		c.Ui.Error("No tests to run. Tests are declared in the \"tests\" section of the template.")
+		return 1
+	}
+
+	// Stop at the next build on interrupt, the running build is cancelled
+	// and has until the cleanup deadline to clean up after itself.
+	interrupts := newInterruptHandler(c.Ui, cfgCleanupTimeout)
+	defer interrupts.Stop()
+
+	var results []*testCaseResult
+	finished := true
+	for _, test := range tests {
+		if interrupts.Interrupted() || !finished {
+			break
+		}
+
+		c.Ui.Say(fmt.Sprintf("==> Running test '%s'", test.Name))
+		core, err := c.Meta.Core(testTemplate(tpl, test))
+		if err != nil {
+			results = append(results, &testCaseResult{Test: test.Name, Err: err})
+			c.Ui.Error(fmt.Sprintf("Test '%s' failed: %s", test.Name, err))
+			continue
+		}
+
+		names := test.Builds
+		if len(names) == 0 {
+			names = core.BuildNames()
+		}
+		for _, n := range names {
+			if interrupts.Interrupted() {
+				break
+			}
+
+			// The build runs in the background so that waiting for it is
+			// bounded by the cleanup deadline once interrupted. A build
+			// that is left running can't write the result anymore.
+			result := &testCaseResult{Test: test.Name, Build: n}
+			start := time.Now()
+			errCh := make(chan error, 1)
+			go func() {
+				errCh <- c.runTestBuild(core, n, test.KeepArtifacts || cfgKeepArtifacts, interrupts)
+			}()
+			select {
+			case result.Err = <-errCh:
+			case <-interrupts.Expired():
+				finished = false
+			}
+			if !finished {
+				break
+			}
+			result.Duration = time.Since(start)
+
+			results = append(results, result)
+		}
+	}
+
+	failures := 0
+	c.Ui.Say("\n==> Test results:")
+	for _, r := range results {
+		name := r.Test
+		if r.Build != "" {
+			name += "/" + r.Build
+		}
+
+		ui := &packer.TargetedUI{
+			Target: name,
+			Ui:     c.Ui,
+		}
+		if r.Err != nil {
+			failures++
+			ui.Machine("test-result", "fail", r.Err.Error())
+			c.Ui.Error(fmt.Sprintf("--> FAIL %s (%s): %s", name, r.Duration.Round(time.Second), r.Err))
+		} else {
+			ui.Machine("test-result", "pass")
+			c.Ui.Say(fmt.Sprintf("--> PASS %s (%s)", name, r.Duration.Round(time.Second)))
+		}
+	}
+	c.Ui.Machine("test-count", strconv.Itoa(len(results)))
+	c.Ui.Machine("test-failure-count", strconv.Itoa(failures))
+
+	if cfgJUnit != "" {
+		if err := writeJUnit(cfgJUnit, results); err != nil {
+			c.Ui.Error(fmt.Sprintf("Error writing JUnit report: %s", err))
+			return 1
+		}
+	}
+
+	if interrupts.Interrupted() {
+		reportLeftovers(c.Ui, interrupts.Running())
+		if finished {
+			c.Ui.Say("Cleanly cancelled tests after being interrupted.")
+		}
+		return 1
+	}
+	if failures > 0 {
+		return 1
+	}
+
+	return 0
+}
+
+// runTestBuild runs one build of a test and destroys its artifacts unless
+// they are kept. The build is cancelled by interrupts while it runs.
+func (c *TestCommand) runTestBuild(core *packer.Core, n string, keep bool, interrupts *interruptHandler) error {
+	b, err := core.Build(n)
+	if err != nil {
+		return fmt.Errorf("Failed to initialize build '%s': %s", n, err)
+	}
+
+	warnings, err := b.Prepare()
+	if err != nil {
+		return err
+	}
+	for _, warning := range warnings {
+		c.Ui.Say(fmt.Sprintf("Warning for build '%s': %s", n, warning))
+	}
+
+	interrupts.Start(b)
+	artifacts, err := b.Run(c.Ui, c.Cache)
+	interrupts.Done(b)
+	if err != nil {
+		return err
+	}
+
+	if keep {
+		for _, a := range artifacts {
+			if a != nil {
+				c.Ui.Say(fmt.Sprintf("Keeping artifact of '%s': %s", n, a.String()))
+			}
+		}
+		return nil
+	}
+
+	var errs []string
+	for _, a := range artifacts {
+		if a == nil {
+			continue
+		}
+
+		log.Printf("Destroying artifact of %s: %s", n, a.Id())
+		if err := a.Destroy(); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", a.Id(), err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("Error destroying artifacts: %s", strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+// selectTests returns the tests matching the -only and -except patterns,
+// which take names, globs and regular expressions like for builds.
+func selectTests(tests []*template.Test, only, except []string) ([]*template.Test, error) {
+	onlyPatterns, err := parseBuildPatterns(only)
+	if err != nil {
+		return nil, err
+	}
+	exceptPatterns, err := parseBuildPatterns(except)
+	if err != nil {
+		return nil, err
+	}
+
+	match := func(patterns []*buildPattern, name string) bool {
+		for _, p := range patterns {
+			if p.Match(name, name) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var result []*template.Test
+	for _, t := range tests {
+		if len(onlyPatterns) > 0 && !match(onlyPatterns, t.Name) {
+			continue
+		}
+		if match(exceptPatterns, t.Name) {
+			continue
+		}
+		result = append(result, t)
+	}
+
+	return result, nil
+}
+
+// testTemplate returns the template to build for the test: the
+// provisioners of the test run after the ones of the template, and there
+// are no post-processors since the artifacts are thrown away.
+func testTemplate(tpl *template.Template, test *template.Test) *template.Template {
+	result := *tpl
+	result.Provisioners = make([]*template.Provisioner, 0, len(tpl.Provisioners)+len(test.Provisioners))
+	result.Provisioners = append(result.Provisioners, tpl.Provisioners...)
+	result.Provisioners = append(result.Provisioners, test.Provisioners...)
+	result.PostProcessors = nil
+	result.Tests = nil
+	return &result
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name          `xml:"testsuites"`
+	Tests    int               `xml:"tests,attr"`
+	Failures int               `xml:"failures,attr"`
+	Time     string            `xml:"time,attr"`
+	Suites   []*junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     string           `xml:"time,attr"`
+	Cases    []*junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// newJUnitReport groups the results by test, one test suite per test and
+// one test case per build.
+func newJUnitReport(results []*testCaseResult) *junitTestSuites {
+	report := &junitTestSuites{}
+	suites := make(map[string]*junitTestSuite)
+	durations := make(map[string]time.Duration)
+	var total time.Duration
+	for _, r := range results {
+		suite, ok := suites[r.Test]
+		if !ok {
+			suite = &junitTestSuite{Name: r.Test}
+			suites[r.Test] = suite
+			report.Suites = append(report.Suites, suite)
+		}
+
+		// A test that fails before building has no build
+		name := r.Build
+		if name == "" {
+			name = r.Test
+		}
+
+		tc := &junitTestCase{
+			Name:      name,
+			ClassName: r.Test,
+			Time:      junitTime(r.Duration),
+		}
+		if r.Err != nil {
+			tc.Failure = &junitFailure{
+				Message: r.Err.Error(),
+				Text:    r.Err.Error(),
+			}
+			suite.Failures++
+			report.Failures++
+		}
+
+		suite.Tests++
+		suite.Cases = append(suite.Cases, tc)
+		report.Tests++
+		durations[r.Test] += r.Duration
+		total += r.Duration
+	}
+
+	for _, suite := range report.Suites {
+		suite.Time = junitTime(durations[suite.Name])
+	}
+	report.Time = junitTime(total)
+
+	return report
+}
+
+func junitTime(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
+
+func writeJUnit(path string, results []*testCaseResult) error {
+	out, err := xml.MarshalIndent(newJUnitReport(results), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, append([]byte(xml.Header), append(out, '\n')...), 0644)
+}
+
+func (*TestCommand) Help() string {
+	helpText := `
+Usage: packer test [options] TEMPLATE
+
+  Runs the tests declared in the "tests" section of the template. Each test
+  builds its builds, or all of them, runs its provisioners on the machine
+  after the ones of the template to check it, and destroys the artifacts.
+  Post-processors are not run.
+
+  The exit status is 1 if any test fails.
+
+Options:
+
+  -cleanup-timeout=15m   When interrupted, how long to wait for the build to clean up.
+  -except=foo,bar,baz    Run all tests other than these. Accepts globs and /regexps/.
+  -only=foo,bar,baz      Run only these tests. Accepts globs and /regexps/.
+  -junit=path            Write a JUnit XML report of the results to path.
+  -keep-artifacts        Keep the artifacts of all the tests instead of destroying them.
+  -machine-readable      Produce machine-readable output.
+  -var 'key=value'       Variable for templates, can be used multiple times.
+  -var-file=path         JSON or YAML file containing user variables, can be used multiple times.
+`
+
+	return strings.TrimSpace(helpText)
+}
+
+func (*TestCommand) Synopsis() string {
+	return "run the tests of a template"
+}
+
+func (*TestCommand) AutocompleteArgs() complete.Predictor {
+	return predictTemplates
+}
+
+func (*TestCommand) AutocompleteFlags() complete.Flags {
+	return complete.Flags{
+		"-cleanup-timeout":  complete.PredictNothing,
+		"-except":           complete.PredictNothing,
+		"-only":             complete.PredictNothing,
+		"-junit":            complete.PredictFiles("*.xml"),
+		"-keep-artifacts":   complete.PredictNothing,
+		"-machine-readable": complete.PredictNothing,
+		"-var":              complete.PredictNothing,
+		"-var-file":         predictVarFiles,
+	}
+}
@@ -0,0 +1,92 @@
+package command
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTestCommand(t *testing.T) {
+	c := &TestCommand{
+		Meta: testMetaFile(t),
+	}
+
+	args := []string{
+		"-only=flavors,kept",
+		filepath.Join(testFixture("test"), "template.json"),
+	}
+
+	defer cleanup()
+
+	if code := c.Run(args); code != 0 {
+		fatalCommand(t, c.Meta)
+	}
+
+	if fileExists("chocolate.txt") {
+		t.Error("the artifacts of the test should be destroyed")
+	}
+	if !fileExists("vanilla.txt") {
+		t.Error("the artifacts of the kept test should be kept")
+	}
+}
+
+func TestTestCommand_junit(t *testing.T) {
+	c := &TestCommand{
+		Meta: testMetaFile(t),
+	}
+
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+	report := filepath.Join(td, "report.xml")
+
+	args := []string{
+		"-except=kept",
+		"-junit", report,
+		filepath.Join(testFixture("test"), "template.json"),
+	}
+
+	defer cleanup()
+
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("the broken test should fail: %d", code)
+	}
+
+	raw, err := ioutil.ReadFile(report)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	var suites junitTestSuites
+	if err := xml.Unmarshal(raw, &suites); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if suites.Tests != 3 || suites.Failures != 1 || len(suites.Suites) != 2 {
+		t.Fatalf("bad: %s", raw)
+	}
+	broken := suites.Suites[1]
+	if broken.Name != "broken" || broken.Cases[0].Failure == nil {
+		t.Fatalf("bad: %s", raw)
+	}
+	if suites.Suites[0].Cases[1].Name != "vanilla" || suites.Suites[0].Cases[1].Failure != nil {
+		t.Fatalf("bad: %s", raw)
+	}
+}
+
+func TestTestCommand_noTests(t *testing.T) {
+	c := &TestCommand{
+		Meta: testMetaFile(t),
+	}
+
+	args := []string{
+		filepath.Join(testFixture("build-only"), "template.json"),
+	}
+
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("bad exit code: %d", code)
+	}
+}
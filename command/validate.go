@@ -19,10 +19,13 @@ type ValidateCommand struct {
 }
 
 func (c *ValidateCommand) Run(args []string) int {
-	var cfgSyntaxOnly bool
+	var cfgSyntaxOnly, cfgJSON bool
+	var cfgEvaluateDatasources bool
 	flags := c.Meta.FlagSet("validate", FlagSetBuildFilter|FlagSetVars)
 	flags.Usage = func() { c.Ui.Say(c.Help()) }
 	flags.BoolVar(&cfgSyntaxOnly, "syntax-only", false, "check syntax only")
+	flags.BoolVar(&cfgEvaluateDatasources, "evaluate-datasources", true, "evaluate consul_key and vault")
+	flags.BoolVar(&cfgJSON, "json", false, "json")
 	if err := flags.Parse(args); err != nil {
 		return 1
 	}
@@ -36,36 +39,101 @@ func (c *ValidateCommand) Run(args []string) int {
 	// Parse the template
 	tpl, err := template.ParseFile(args[0])
 	if err != nil {
-		c.Ui.Error(fmt.Sprintf("Failed to parse template: %s", err))
-		return 1
+		d := &validateDiagnostic{
+			Severity: "error",
+			Summary:  fmt.Sprintf("Failed to parse template: %s", err),
+		}
+		if syntaxErr, ok := err.(*template.SyntaxError); ok {
+			d.Line, d.Column = syntaxErr.Line, syntaxErr.Column
+		}
+		return c.fail(cfgJSON, d)
+	}
+
+	if err := c.Meta.LoadAutoVarFiles(tpl); err != nil {
+		return c.fail(cfgJSON, &validateDiagnostic{Severity: "error", Summary: err.Error()})
 	}
 
 	// If we're only checking syntax, then we're done already
 	if cfgSyntaxOnly {
+		if cfgJSON {
+			return c.report(true, nil)
+		}
 		c.Ui.Say("Syntax-only check passed. Everything looks okay.")
 		return 0
 	}
 
+	// YAML templates are parsed from the JSON they convert to, whose
+	// positions don't match the lines of the file, so they have none.
+	raw := tpl.RawContents
+	if template.IsYAML(tpl.Path) {
+		raw = nil
+	}
+	positions := template.NewPositions(raw)
+	var diags []*validateDiagnostic
+	addDiag := func(severity, summary, build, path string) *validateDiagnostic {
+		d := &validateDiagnostic{
+			Severity: severity,
+			Summary:  summary,
+			Build:    build,
+		}
+		if path != "" {
+			d.Line, d.Column = positions.Position(path)
+		} else if b, ok := tpl.Builders[build]; ok {
+			d.Line, d.Column = b.Pos.Line, b.Pos.Column
+		}
+		diags = append(diags, d)
+		return d
+	}
+	// addPrepareDiag points to the component of the error, unless it is
+	// from a file the template includes.
+	addPrepareDiag := func(err error, build string) {
+		perr, ok := err.(*packer.PrepareError)
+		if !ok || perr.Pos.Filename != tpl.Path {
+			addDiag("error", err.Error(), build, "")
+			return
+		}
+		d := addDiag("error", perr.Summary(), build, "")
+		if perr.Pos.Line > 0 {
+			d.Line, d.Column = perr.Pos.Line, perr.Pos.Column
+		}
+	}
+
+	// Report all missing variables at once, the core stops at the first
+	// problem it finds.
+	missing := missingVariables(tpl, c.Meta.flagVars)
+	for _, k := range missing {
+		addDiag("error", fmt.Sprintf("required variable not set: %s", k), "", "variables."+k)
+	}
+	for _, k := range unusedVariables(tpl) {
+		addDiag("warning", fmt.Sprintf("variable '%s' is never used", k), "", "variables."+k)
+	}
+	if len(missing) > 0 {
+		return c.report(cfgJSON, diags)
+	}
+
+	if !cfgEvaluateDatasources {
+		config := *c.Meta.CoreConfig
+		config.SkipDatasources = true
+		c.Meta.CoreConfig = &config
+	}
+
 	// Get the core
 	core, err := c.Meta.Core(tpl)
 	if err != nil {
-		c.Ui.Error(err.Error())
-		return 1
+		return c.fail(cfgJSON, &validateDiagnostic{Severity: "error", Summary: err.Error()})
 	}
 
-	errs := make([]error, 0)
-	warnings := make(map[string][]string)
-
 	// Get the builds we care about
-	buildNames := c.Meta.BuildNames(core)
+	buildNames, err := c.Meta.BuildNames(core)
+	if err != nil {
+		return c.fail(cfgJSON, &validateDiagnostic{Severity: "error", Summary: err.Error()})
+	}
 	builds := make([]packer.Build, 0, len(buildNames))
 	for _, n := range buildNames {
 		b, err := core.Build(n)
 		if err != nil {
-			c.Ui.Error(fmt.Sprintf(
-				"Failed to initialize build '%s': %s",
-				n, err))
-			return 1
+			addDiag("error", fmt.Sprintf("Failed to initialize build '%s': %s", n, err), n, "")
+			continue
 		}
 
 		builds = append(builds, b)
@@ -75,11 +143,20 @@ func (c *ValidateCommand) Run(args []string) int {
 	for _, b := range builds {
 		log.Printf("Preparing build: %s", b.Name())
 		warns, err := b.Prepare()
-		if len(warns) > 0 {
-			warnings[b.Name()] = warns
+		for _, warning := range warns {
+			addDiag("warning", warning, b.Name(), "")
 		}
-		if err != nil {
-			errs = append(errs, fmt.Errorf("Errors validating build '%s'. %s", b.Name(), err))
+		if err == nil {
+			continue
+		}
+
+		// Report each problem on its own, so they can be told apart.
+		if merr, ok := err.(*packer.MultiError); ok {
+			for _, e := range merr.Errors {
+				addPrepareDiag(e, b.Name())
+			}
+		} else {
+			addPrepareDiag(err, b.Name())
 		}
 	}
 
@@ -107,8 +184,10 @@ func (c *ValidateCommand) Run(args []string) int {
 		}
 		input, err = fixer.Fix(input)
 		if err != nil {
-			c.Ui.Error(fmt.Sprintf("Error checking against fixers: %s", err))
-			return 1
+			return c.fail(cfgJSON, &validateDiagnostic{
+				Severity: "error",
+				Summary:  fmt.Sprintf("Error checking against fixers: %s", err),
+			})
 		}
 	}
 	// delete empty top-level keys since the fixers seem to add them
@@ -129,22 +208,73 @@ func (c *ValidateCommand) Run(args []string) int {
 	json.Unmarshal(j, &fixedData)
 
 	if diff := cmp.Diff(templateData, fixedData); diff != "" {
-		c.Ui.Say("[warning] Fixable configuration found.")
-		c.Ui.Say("You may need to run `packer fix` to get your build to run")
-		c.Ui.Say("correctly. See debug log for more information.\n")
+		addDiag("warning", "Fixable configuration found. You may need to run "+
+			"`packer fix` to get your build to run correctly. See debug log "+
+			"for more information.", "", "")
 		log.Printf("Fixable config differences:\n%s", diff)
 	}
 
+	return c.report(cfgJSON, diags)
+}
+
+// fail reports a problem that stops validation early. Without -json it is
+// shown as is.
+func (c *ValidateCommand) fail(asJSON bool, d *validateDiagnostic) int {
+	if asJSON {
+		return c.report(true, []*validateDiagnostic{d})
+	}
+	c.Ui.Error(d.Summary)
+	return 1
+}
+
+// report outputs the diagnostics and returns the exit status.
+func (c *ValidateCommand) report(asJSON bool, diags []*validateDiagnostic) int {
+	result := &validateResult{Diagnostics: []*validateDiagnostic{}}
+	var errs, warnings []*validateDiagnostic
+	for _, d := range diags {
+		if d.Severity == "error" {
+			errs = append(errs, d)
+		} else {
+			warnings = append(warnings, d)
+		}
+		result.Diagnostics = append(result.Diagnostics, d)
+	}
+	result.Valid = len(errs) == 0
+	result.ErrorCount = len(errs)
+	result.WarningCount = len(warnings)
+
+	status := 0
+	if !result.Valid {
+		status = 1
+	}
+
+	if asJSON {
+		out, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			c.Ui.Error(fmt.Sprintf("Failed to encode diagnostics: %s", err))
+			return 1
+		}
+		c.Ui.Say(string(out))
+		return status
+	}
+
 	if len(errs) > 0 {
 		c.Ui.Error("Template validation failed. Errors are shown below.\n")
 		for i, err := range errs {
-			c.Ui.Error(err.Error())
+			c.Ui.Error(err.String())
 
 			if (i + 1) < len(errs) {
 				c.Ui.Error("")
 			}
 		}
-		return 1
+
+		if len(warnings) > 0 {
+			c.Ui.Say("\nThere were also some warnings:\n")
+			for _, warning := range warnings {
+				c.Ui.Say(fmt.Sprintf("* %s", warning))
+			}
+		}
+		return status
 	}
 
 	if len(warnings) > 0 {
@@ -152,18 +282,14 @@ func (c *ValidateCommand) Run(args []string) int {
 		c.Ui.Say("These are ONLY WARNINGS, and Packer will attempt to build the")
 		c.Ui.Say("template despite them, but they should be paid attention to.\n")
 
-		for build, warns := range warnings {
-			c.Ui.Say(fmt.Sprintf("Warnings for build '%s':\n", build))
-			for _, warning := range warns {
-				c.Ui.Say(fmt.Sprintf("* %s", warning))
-			}
+		for _, warning := range warnings {
+			c.Ui.Say(fmt.Sprintf("* %s", warning))
 		}
-
-		return 0
+		return status
 	}
 
 	c.Ui.Say("Template validated successfully.")
-	return 0
+	return status
 }
 
 func (*ValidateCommand) Help() string {
@@ -180,10 +306,13 @@ Usage: packer validate [options] TEMPLATE
 Options:
 
   -syntax-only           Only check syntax. Do not verify config of the template.
-  -except=foo,bar,baz    Validate all builds other than these.
-  -only=foo,bar,baz      Validate only these builds.
+  -evaluate-datasources=false  Do not read consul_key and vault lookups.
+  -json                  Output the problems found as JSON.
+  -except=foo,bar,baz    Validate all builds other than these. Accepts globs and /regexps/.
+  -only=foo,bar,baz      Validate only these builds. Accepts globs and /regexps/.
   -var 'key=value'       Variable for templates, can be used multiple times.
-  -var-file=path         JSON file containing user variables.
+  -var-file=path         JSON or YAML file containing user variables, can be used multiple times.
+                         *.auto.pkrvars.json and .yaml files next to the template are loaded first.
 `
 
 	return strings.TrimSpace(helpText)
@@ -194,15 +323,17 @@ func (*ValidateCommand) Synopsis() string {
 }
 
 func (*ValidateCommand) AutocompleteArgs() complete.Predictor {
-	return complete.PredictNothing
+	return predictTemplates
 }
 
 func (*ValidateCommand) AutocompleteFlags() complete.Flags {
 	return complete.Flags{
-		"-syntax-only": complete.PredictNothing,
-		"-except":      complete.PredictNothing,
-		"-only":        complete.PredictNothing,
-		"-var":         complete.PredictNothing,
-		"-var-file":    complete.PredictNothing,
+		"-syntax-only":          complete.PredictNothing,
+		"-evaluate-datasources": complete.PredictNothing,
+		"-json":                 complete.PredictNothing,
+		"-except":               predictBuildNames,
+		"-only":                 predictBuildNames,
+		"-var":                  complete.PredictNothing,
+		"-var-file":             predictVarFiles,
 	}
 }
@@ -0,0 +1,72 @@
+package command
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/packer/template"
+)
+
+// validateDiagnostic is a single problem found by "packer validate".
+type validateDiagnostic struct {
+	Severity string `json:"severity"`
+	Summary  string `json:"summary"`
+	Build    string `json:"build,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+}
+
+func (d *validateDiagnostic) String() string {
+	var location []string
+	if d.Build != "" {
+		location = append(location, fmt.Sprintf("build '%s'", d.Build))
+	}
+	if d.Line > 0 {
+		location = append(location, fmt.Sprintf("line %d, column %d", d.Line, d.Column))
+	}
+	if len(location) == 0 {
+		return d.Summary
+	}
+	return fmt.Sprintf("%s: %s", strings.Join(location, ", "), d.Summary)
+}
+
+// validateResult is what "packer validate -json" writes.
+type validateResult struct {
+	Valid        bool                  `json:"valid"`
+	ErrorCount   int                   `json:"error_count"`
+	WarningCount int                   `json:"warning_count"`
+	Diagnostics  []*validateDiagnostic `json:"diagnostics"`
+}
+
+// missingVariables returns the required variables of tpl that vars has no
+// value for.
+func missingVariables(tpl *template.Template, vars map[string]string) []string {
+	var result []string
+	for k, v := range tpl.Variables {
+		if !v.Required {
+			continue
+		}
+		if _, ok := vars[k]; !ok {
+			result = append(result, k)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// unusedVariables returns the variables of tpl that are never read with the
+// user function, neither by the template nor by other variables.
+func unusedVariables(tpl *template.Template) []string {
+	var result []string
+	for k := range tpl.Variables {
+		name := regexp.QuoteMeta(k)
+		re := regexp.MustCompile("user\\s+(`" + name + "`|\\\\\"" + name + "\\\\\")")
+		if !re.Match(tpl.RawContents) {
+			result = append(result, k)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
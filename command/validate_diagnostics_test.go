@@ -0,0 +1,34 @@
+package command
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/packer/template"
+)
+
+func TestMissingVariables(t *testing.T) {
+	tpl, err := template.ParseFile(filepath.Join(testFixture("validate-variables"), "template.json"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if missing := missingVariables(tpl, nil); !reflect.DeepEqual(missing, []string{"target"}) {
+		t.Fatalf("bad: %#v", missing)
+	}
+	if missing := missingVariables(tpl, map[string]string{"target": "out.txt"}); len(missing) > 0 {
+		t.Fatalf("bad: %#v", missing)
+	}
+}
+
+func TestUnusedVariables(t *testing.T) {
+	tpl, err := template.ParseFile(filepath.Join(testFixture("validate-variables"), "template.json"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if unused := unusedVariables(tpl); !reflect.DeepEqual(unused, []string{"unused"}) {
+		t.Fatalf("bad: %#v", unused)
+	}
+}
@@ -1,7 +1,10 @@
 package command
 
 import (
+	"encoding/json"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -42,3 +45,60 @@ func TestValidateCommandBadVersion(t *testing.T) {
 	}
 	t.Log(stdout)
 }
+
+func TestValidateCommand_missingVariables(t *testing.T) {
+	c := &ValidateCommand{
+		Meta: testMetaFile(t),
+	}
+	args := []string{
+		"-json",
+		filepath.Join(testFixture("validate-variables"), "template.json"),
+	}
+
+	if code := c.Run(args); code != 1 {
+		t.Errorf("Expected exit code 1")
+	}
+
+	stdout, _ := outputCommand(t, c.Meta)
+	var result validateResult
+	if err := json.Unmarshal([]byte(stdout), &result); err != nil {
+		t.Fatalf("err: %s\n%s", err, stdout)
+	}
+
+	expected := []*validateDiagnostic{
+		{Severity: "error", Summary: "required variable not set: target", Line: 4, Column: 5},
+		{Severity: "warning", Summary: "variable 'unused' is never used", Line: 5, Column: 5},
+	}
+	if result.Valid || !reflect.DeepEqual(result.Diagnostics, expected) {
+		t.Fatalf("bad: %s", stdout)
+	}
+}
+
+func TestValidateCommand_errorPositions(t *testing.T) {
+	c := &ValidateCommand{
+		Meta: testMetaFile(t),
+	}
+	args := []string{
+		"-json",
+		filepath.Join(testFixture("validate-positions"), "template.json"),
+	}
+
+	if code := c.Run(args); code != 1 {
+		t.Errorf("Expected exit code 1")
+	}
+
+	stdout, _ := outputCommand(t, c.Meta)
+	var result validateResult
+	if err := json.Unmarshal([]byte(stdout), &result); err != nil {
+		t.Fatalf("err: %s\n%s", err, stdout)
+	}
+
+	if len(result.Diagnostics) != 1 {
+		t.Fatalf("bad: %s", stdout)
+	}
+	d := result.Diagnostics[0]
+	if d.Build != "file" || d.Line != 3 || d.Column != 5 ||
+		!strings.HasPrefix(d.Summary, "builders[0] (file): invalid 'content': ") {
+		t.Fatalf("bad: %s", stdout)
+	}
+}
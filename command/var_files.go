@@ -0,0 +1,168 @@
+package command
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"sort"
+
+	"github.com/hashicorp/packer/helper/flag-kv"
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/template"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// autoVarFilePatterns match the variable files in the template directory
+// that are loaded without being given with -var-file.
+var autoVarFilePatterns = []string{
+	"*.auto.pkrvars.json",
+	"*.auto.pkrvars.yaml",
+	"*.auto.pkrvars.yml",
+}
+
+// varFlag is a flag.Value for -var and -var-file that records where each
+// variable comes from. The flags are applied in the order they are given,
+// so later values override earlier ones.
+type varFlag struct {
+	m    *Meta
+	file bool
+}
+
+func (v *varFlag) String() string {
+	return ""
+}
+
+func (v *varFlag) Set(raw string) error {
+	var vars map[string]string
+	source := "-var"
+	if v.file {
+		var err error
+		if vars, err = loadVarFile(raw); err != nil {
+			return err
+		}
+		source = raw
+	} else if err := (*kvflag.Flag)(&vars).Set(raw); err != nil {
+		return err
+	}
+
+	for k, value := range vars {
+		v.m.setVar(k, value, source)
+	}
+	return nil
+}
+
+func (m *Meta) setVar(k, v, source string) {
+	if m.flagVars == nil {
+		m.flagVars = make(map[string]string)
+	}
+	if m.varSources == nil {
+		m.varSources = make(map[string]string)
+	}
+	m.flagVars[k] = v
+	m.varSources[k] = source
+}
+
+// LoadAutoVarFiles loads the variable files of the core config, then the
+// ones matching autoVarFilePatterns next to the template in lexical order.
+// Variables set on the command line take precedence over them.
+func (m *Meta) LoadAutoVarFiles(tpl *template.Template) error {
+	paths := append([]string{}, m.VarFiles...)
+	if tpl.Path != "" && tpl.Path != "-" {
+		var matches []string
+		for _, pattern := range autoVarFilePatterns {
+			found, err := filepath.Glob(filepath.Join(filepath.Dir(tpl.Path), pattern))
+			if err != nil {
+				return err
+			}
+			matches = append(matches, found...)
+		}
+		sort.Strings(matches)
+		paths = append(paths, matches...)
+	}
+
+	// Stack the files before merging so the command line still wins
+	// over all of them.
+	auto := make(map[string]string)
+	sources := make(map[string]string)
+	for _, path := range paths {
+		log.Printf("Loading variables from %s", path)
+
+		vars, err := loadVarFile(path)
+		if err != nil {
+			return err
+		}
+		for k, v := range vars {
+			auto[k] = v
+			sources[k] = path
+		}
+	}
+
+	for k, v := range auto {
+		if _, ok := m.flagVars[k]; ok {
+			continue
+		}
+		m.setVar(k, v, sources[k])
+	}
+	return nil
+}
+
+// loadVarFile reads the variables of a JSON variable file, or of a YAML one
+// if the file ends in .yaml or .yml.
+func loadVarFile(path string) (map[string]string, error) {
+	var vars map[string]string
+	if !template.IsYAML(path) {
+		err := (*kvflag.FlagJSON)(&vars).Set(path)
+		return vars, err
+	}
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(contents, &vars); err != nil {
+		return nil, fmt.Errorf("Error reading variables in '%s': %s", path, err)
+	}
+	return vars, nil
+}
+
+// ShowVars prints the user variables of the core the way the builds see
+// them, with where each value comes from. Sensitive values are masked.
+func (m *Meta) ShowVars(core *packer.Core) {
+	vars := core.Context().UserVariables
+
+	sensitive := make(map[string]bool)
+	for k, v := range core.Template.Variables {
+		for _, s := range core.Template.SensitiveVariables {
+			if s == v {
+				sensitive[k] = true
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(vars))
+	for k := range vars {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	if len(keys) == 0 {
+		m.Ui.Say("No variables.")
+		return
+	}
+
+	m.Ui.Say("Variables:")
+	for _, k := range keys {
+		value := vars[k]
+		if sensitive[k] {
+			value = "<sensitive>"
+		}
+
+		source, ok := m.varSources[k]
+		if !ok {
+			source = "default"
+		}
+
+		m.Ui.Say(fmt.Sprintf("  %s = %q (%s)", k, value, source))
+	}
+}
@@ -0,0 +1,140 @@
+package command
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer/template"
+)
+
+func TestVarFlag_order(t *testing.T) {
+	m := testMeta(t)
+	flags := m.FlagSet("test", FlagSetVars)
+	args := []string{
+		"-var-file", filepath.Join(testFixture("var-files"), "prod.json"),
+		"-var", "name=cli",
+		"-var-file", filepath.Join(testFixture("var-files"), "staging.json"),
+	}
+	if err := flags.Parse(args); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if m.flagVars["name"] != "staging" {
+		t.Fatalf("later files should override: %#v", m.flagVars)
+	}
+	if m.flagVars["size"] != "xlarge" {
+		t.Fatalf("bad: %#v", m.flagVars)
+	}
+	if !strings.HasSuffix(m.varSources["size"], "prod.json") {
+		t.Fatalf("bad: %#v", m.varSources)
+	}
+}
+
+func TestMetaLoadAutoVarFiles(t *testing.T) {
+	tpl, err := template.ParseFile(filepath.Join(testFixture("var-files"), "template.json"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	m := testMeta(t)
+	m.setVar("region", "ap-south-1", "-var")
+	if err := m.LoadAutoVarFiles(tpl); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := map[string]string{
+		// The command line wins over the auto-loaded files
+		"region":   "ap-south-1",
+		"size":     "large",
+		"password": "hunter2",
+	}
+	for k, v := range expected {
+		if m.flagVars[k] != v {
+			t.Fatalf("%s: expected %q, got %q", k, v, m.flagVars[k])
+		}
+	}
+	if _, ok := m.flagVars["name"]; ok {
+		t.Fatalf("bad: %#v", m.flagVars)
+	}
+	if !strings.HasSuffix(m.varSources["size"], "b.auto.pkrvars.json") {
+		t.Fatalf("bad: %#v", m.varSources)
+	}
+}
+
+func TestMetaLoadAutoVarFiles_coreConfig(t *testing.T) {
+	dir := testFixture("var-files")
+	tpl, err := template.ParseFile(filepath.Join(dir, "template.json"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	m := testMeta(t)
+	m.VarFiles = []string{filepath.Join(dir, "prod.json")}
+	if err := m.LoadAutoVarFiles(tpl); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The files next to the template win over the ones of the core config
+	if m.flagVars["size"] != "large" {
+		t.Fatalf("bad: %#v", m.flagVars)
+	}
+	if m.flagVars["name"] != "prod" || !strings.HasSuffix(m.varSources["name"], "prod.json") {
+		t.Fatalf("bad: %#v %#v", m.flagVars, m.varSources)
+	}
+}
+
+func TestMetaShowVars(t *testing.T) {
+	tpl, err := template.ParseFile(filepath.Join(testFixture("var-files"), "template.json"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	m := testMeta(t)
+	if err := m.LoadAutoVarFiles(tpl); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	core, err := m.Core(tpl)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	m.ShowVars(core)
+
+	out, _ := outputCommand(t, m)
+	if strings.Contains(out, "hunter2") {
+		t.Fatalf("sensitive value shown: %s", out)
+	}
+	for _, line := range []string{
+		`password = "<sensitive>"`,
+		`name = "default" (default)`,
+		`size = "large" (`,
+	} {
+		if !strings.Contains(out, line) {
+			t.Fatalf("missing %q: %s", line, out)
+		}
+	}
+}
+
+func TestMetaLoadAutoVarFiles_yaml(t *testing.T) {
+	dir := testFixture("var-files-yaml")
+	tpl, err := template.ParseFile(filepath.Join(dir, "template.yaml"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	m := testMeta(t)
+	if err := m.LoadAutoVarFiles(tpl); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if m.flagVars["disk_size"] != "40" || m.flagVars["region"] != "eu-west-1" {
+		t.Fatalf("bad: %#v", m.flagVars)
+	}
+
+	flags := m.FlagSet("test", FlagSetVars)
+	if err := flags.Parse([]string{"-var-file", filepath.Join(dir, "prod.yaml")}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if m.flagVars["region"] != "eu-central-1" {
+		t.Fatalf("bad: %#v", m.flagVars)
+	}
+}
@@ -2,8 +2,12 @@ package command
 
 import (
 	"fmt"
+	"strings"
 
+	goversion "github.com/hashicorp/go-version"
+	"github.com/hashicorp/packer/template"
 	"github.com/hashicorp/packer/version"
+	"github.com/posener/complete"
 )
 
 // VersionCommand is a Command implementation prints the version.
@@ -11,6 +15,11 @@ type VersionCommand struct {
 	Meta
 
 	CheckFunc VersionCheckFunc
+
+	// ForceCheckFunc is used instead of CheckFunc for -check, or when
+	// AlwaysCheck is set by the version_check setting.
+	ForceCheckFunc VersionCheckFunc
+	AlwaysCheck    bool
 }
 
 // VersionCheckFunc is the callback called by the Version command to
@@ -27,37 +36,129 @@ type VersionCheckInfo struct {
 }
 
 func (c *VersionCommand) Help() string {
-	return "Prints the Packer version, and checks for new release."
+	helpText := `
+Usage: packer version [options] [TEMPLATE...]
+
+  Prints the Packer version, and checks for new release.
+
+  Given templates, also checks that this version of Packer satisfies their
+  min_packer_version, and exits with a non-zero status if it doesn't.
+
+Options:
+
+  -check  Check for a newer version and security advisories right away,
+          even if checkpoint is disabled. Exits with a non-zero status if
+          the check fails.
+`
+
+	return strings.TrimSpace(helpText)
 }
 
 func (c *VersionCommand) Run(args []string) int {
+	var cfgCheck bool
+	flags := c.Meta.FlagSet("version", FlagSetNone)
+	flags.Usage = func() { c.Ui.Say(c.Help()) }
+	flags.BoolVar(&cfgCheck, "check", false, "check")
+	if err := flags.Parse(args); err != nil {
+		return 1
+	}
+
 	c.Ui.Machine("version", version.Version)
 	c.Ui.Machine("version-prelease", version.VersionPrerelease)
 	c.Ui.Machine("version-commit", version.GitCommit)
 
 	c.Ui.Say(fmt.Sprintf("Packer v%s", version.FormattedVersion()))
 
+	status := 0
+
+	checkFunc := c.CheckFunc
+	if (cfgCheck || c.AlwaysCheck) && c.ForceCheckFunc != nil {
+		checkFunc = c.ForceCheckFunc
+	}
+
 	// If we have a version check function, then let's check for
 	// the latest version as well.
-	if c.CheckFunc != nil {
+	if checkFunc != nil {
 
 		// Check the latest version
-		info, err := c.CheckFunc()
+		info, err := checkFunc()
 		if err != nil {
 			c.Ui.Error(fmt.Sprintf(
 				"\nError checking latest version: %s", err))
+			if cfgCheck {
+				status = 1
+			}
 		}
 		if info.Outdated {
+			c.Ui.Machine("version-latest", info.Latest)
 			c.Ui.Say(fmt.Sprintf(
 				"\nYour version of Packer is out of date! The latest version\n"+
 					"is %s. You can update by downloading from www.packer.io/downloads.html",
 				info.Latest))
+		} else if err == nil && cfgCheck {
+			c.Ui.Say("\nYour version of Packer is up to date.")
+		}
+		if len(info.Alerts) > 0 {
+			c.Ui.Say("\nAlerts for this version of Packer:\n")
+			for _, alert := range info.Alerts {
+				c.Ui.Machine("version-alert", alert)
+				c.Ui.Say(fmt.Sprintf("* %s", alert))
+			}
+		}
+	}
+
+	// Check the templates given against this version
+	for _, path := range flags.Args() {
+		if err := c.checkTemplate(path); err != nil {
+			c.Ui.Error(fmt.Sprintf("\n%s: %s", path, err))
+			status = 1
 		}
 	}
 
-	return 0
+	return status
+}
+
+// checkTemplate checks that this version of Packer satisfies the
+// min_packer_version of a template.
+func (c *VersionCommand) checkTemplate(path string) error {
+	tpl, err := template.ParseFile(path)
+	if err != nil {
+		return err
+	}
+	if tpl.MinVersion == "" {
+		return nil
+	}
+
+	current := version.Version
+	if c.CoreConfig != nil && c.CoreConfig.Version != "" {
+		current = c.CoreConfig.Version
+	}
+	versionActual, err := goversion.NewVersion(current)
+	if err != nil {
+		return err
+	}
+	versionMin, err := goversion.NewVersion(tpl.MinVersion)
+	if err != nil {
+		return fmt.Errorf("min_packer_version is invalid: %s", err)
+	}
+	if versionActual.LessThan(versionMin) {
+		return fmt.Errorf(
+			"This template requires Packer version %s or higher; using %s",
+			versionMin, versionActual)
+	}
+	return nil
 }
 
 func (c *VersionCommand) Synopsis() string {
 	return "Prints the Packer version"
 }
+
+func (c *VersionCommand) AutocompleteArgs() complete.Predictor {
+	return predictTemplates
+}
+
+func (c *VersionCommand) AutocompleteFlags() complete.Flags {
+	return complete.Flags{
+		"-check": complete.PredictNothing,
+	}
+}
@@ -1,6 +1,9 @@
 package command
 
 import (
+	"errors"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/mitchellh/cli"
@@ -9,3 +12,63 @@ import (
 func TestVersionCommand_implements(t *testing.T) {
 	var _ cli.Command = &VersionCommand{}
 }
+
+func TestVersionCommand_check(t *testing.T) {
+	var checked, forced bool
+	c := &VersionCommand{
+		Meta: testMeta(t),
+		CheckFunc: func() (VersionCheckInfo, error) {
+			checked = true
+			return VersionCheckInfo{}, nil
+		},
+		ForceCheckFunc: func() (VersionCheckInfo, error) {
+			forced = true
+			return VersionCheckInfo{
+				Outdated: true,
+				Latest:   "101.0.0",
+				Alerts:   []string{"[critical] upgrade now"},
+			}, nil
+		},
+	}
+
+	if code := c.Run([]string{"-check"}); code != 0 {
+		fatalCommand(t, c.Meta)
+	}
+	if checked || !forced {
+		t.Fatalf("-check should use ForceCheckFunc")
+	}
+
+	stdout, _ := outputCommand(t, c.Meta)
+	if !strings.Contains(stdout, "101.0.0") || !strings.Contains(stdout, "* [critical] upgrade now") {
+		t.Fatalf("bad output:\n%s", stdout)
+	}
+}
+
+func TestVersionCommand_checkError(t *testing.T) {
+	c := &VersionCommand{
+		Meta: testMeta(t),
+		ForceCheckFunc: func() (VersionCheckInfo, error) {
+			return VersionCheckInfo{}, errors.New("offline")
+		},
+	}
+
+	if code := c.Run([]string{"-check"}); code != 1 {
+		t.Fatalf("a failed -check should exit with 1")
+	}
+}
+
+func TestVersionCommand_template(t *testing.T) {
+	args := []string{filepath.Join(testFixture("validate"), "template.json")}
+
+	c := &VersionCommand{Meta: testMeta(t)}
+	c.CoreConfig.Version = "100.0.0"
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("should fail when min_packer_version isn't met")
+	}
+
+	c = &VersionCommand{Meta: testMeta(t)}
+	c.CoreConfig.Version = "102.0.0"
+	if code := c.Run(args); code != 0 {
+		fatalCommand(t, c.Meta)
+	}
+}
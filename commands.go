@@ -12,6 +12,10 @@ var Commands map[string]cli.CommandFactory
 // before the CLI is started.
 var CommandMeta *command.Meta
 
+// loadedConfig is the core configuration, also written before the CLI is
+// started.
+var loadedConfig *config
+
 const ErrorPrefix = "e:"
 const OutputPrefix = "o:"
 
@@ -23,6 +27,18 @@ func init() {
 			}, nil
 		},
 
+		"cleanup": func() (cli.Command, error) {
+			return &command.CleanupCommand{
+				Meta: *CommandMeta,
+			}, nil
+		},
+
+		"convert": func() (cli.Command, error) {
+			return &command.ConvertCommand{
+				Meta: *CommandMeta,
+			}, nil
+		},
+
 		"fix": func() (cli.Command, error) {
 			return &command.FixCommand{
 				Meta: *CommandMeta,
@@ -35,6 +51,30 @@ func init() {
 			}, nil
 		},
 
+		"new": func() (cli.Command, error) {
+			return &command.NewCommand{
+				Meta: *CommandMeta,
+			}, nil
+		},
+
+		"plugins": func() (cli.Command, error) {
+			return &command.PluginsCommand{
+				Meta: *CommandMeta,
+			}, nil
+		},
+
+		"plugins lock": func() (cli.Command, error) {
+			return &command.PluginsLockCommand{
+				Meta: *CommandMeta,
+			}, nil
+		},
+
+		"test": func() (cli.Command, error) {
+			return &command.TestCommand{
+				Meta: *CommandMeta,
+			}, nil
+		},
+
 		"validate": func() (cli.Command, error) {
 			return &command.ValidateCommand{
 				Meta: *CommandMeta,
@@ -43,8 +83,10 @@ func init() {
 
 		"version": func() (cli.Command, error) {
 			return &command.VersionCommand{
-				Meta:      *CommandMeta,
-				CheckFunc: commandVersionCheck,
+				Meta:           *CommandMeta,
+				CheckFunc:      commandVersionCheck,
+				ForceCheckFunc: commandVersionForceCheck,
+				AlwaysCheck:    loadedConfig != nil && loadedConfig.VersionCheck,
 			}, nil
 		},
 
@@ -1,3 +1,6 @@
+// Package chroot contains the communicator and the steps of the builders
+// that build images from a device mounted on the host, with the
+// provisioners run in a chroot of it, like amazon-chroot.
 package chroot
 
 import (
@@ -0,0 +1,9 @@
+package chroot
+
+import "github.com/hashicorp/packer/template/interpolate"
+
+// interpolateContextProvider is the config of the state of the builders,
+// for the steps that interpolate commands with its context.
+type interpolateContextProvider interface {
+	GetContext() interpolate.Context
+}
@@ -11,26 +11,31 @@ import (
 	"github.com/hashicorp/packer/packer"
 )
 
+// DefaultCopyFiles are the files copied into the chroot by default, the
+// resolv.conf of the host so names resolve in the chroot.
+var DefaultCopyFiles = []string{"/etc/resolv.conf"}
+
 // StepCopyFiles copies some files from the host into the chroot environment.
 //
 // Produces:
 //   copy_files_cleanup CleanupFunc - A function to clean up the copied files
 //   early.
 type StepCopyFiles struct {
+	Files []string
+
 	files []string
 }
 
 func (s *StepCopyFiles) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
-	config := state.Get("config").(*Config)
 	mountPath := state.Get("mount_path").(string)
 	ui := state.Get("ui").(packer.Ui)
 	wrappedCommand := state.Get("wrappedCommand").(CommandWrapper)
 	stderr := new(bytes.Buffer)
 
-	s.files = make([]string, 0, len(config.CopyFiles))
-	if len(config.CopyFiles) > 0 {
+	s.files = make([]string, 0, len(s.Files))
+	if len(s.Files) > 0 {
 		ui.Say("Copying files from host to chroot...")
-		for _, path := range config.CopyFiles {
+		for _, path := range s.Files {
 			ui.Message(path)
 			chrootPath := filepath.Join(mountPath, path)
 			log.Printf("Copying '%s' to '%s'", path, chrootPath)
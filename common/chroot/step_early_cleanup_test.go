@@ -0,0 +1,39 @@
+package chroot
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+type testCleanup struct {
+	called bool
+}
+
+func (c *testCleanup) CleanupFunc(multistep.StateBag) error {
+	c.called = true
+	return nil
+}
+
+func TestStepEarlyCleanup_missingSteps(t *testing.T) {
+	state := new(multistep.BasicStateBag)
+	state.Put("ui", &packer.BasicUi{
+		Reader: new(bytes.Buffer),
+		Writer: new(bytes.Buffer),
+	})
+
+	// A builder without volumes to attach, like of a local device
+	mountDevice := new(testCleanup)
+	state.Put("mount_device_cleanup", mountDevice)
+
+	step := new(StepEarlyCleanup)
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if !mountDevice.called {
+		t.Fatal("should clean up the mount of the device")
+	}
+}
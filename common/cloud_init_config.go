@@ -0,0 +1,81 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// The agents the guest can be waited for before provisioning.
+const (
+	CloudInitAgentCloudInit = "cloud-init"
+	CloudInitAgentWAAgent   = "waagent"
+	CloudInitAgentEC2Launch = "ec2launch"
+	CloudInitAgentMarker    = "marker"
+)
+
+// DefaultCloudInitMarkerFile is the file cloud-init writes once it has run
+// all its modules, user data included.
+const DefaultCloudInitMarkerFile = "/var/lib/cloud/instance/boot-finished"
+
+// CloudInitConfig contains the configuration for waiting for the agent that
+// runs the user data of a cloud instance before provisioning it, so that the
+// provisioners don't race it, like for the locks of apt or yum.
+type CloudInitConfig struct {
+	WaitForCloudInit    string        `mapstructure:"wait_for_cloud_init"`
+	CloudInitMarkerFile string        `mapstructure:"cloud_init_marker_file"`
+	CloudInitTimeout    time.Duration `mapstructure:"cloud_init_timeout"`
+}
+
+func (c *CloudInitConfig) Prepare() []error {
+	if c.WaitForCloudInit == "" {
+		return nil
+	}
+
+	var errs []error
+	switch c.WaitForCloudInit {
+	case CloudInitAgentCloudInit, CloudInitAgentWAAgent, CloudInitAgentEC2Launch:
+	case CloudInitAgentMarker:
+		if c.CloudInitMarkerFile == "" {
+			errs = append(errs, fmt.Errorf("cloud_init_marker_file must be set when wait_for_cloud_init is %s", CloudInitAgentMarker))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("wait_for_cloud_init must be one of %s, %s, %s or %s",
+			CloudInitAgentCloudInit, CloudInitAgentWAAgent, CloudInitAgentEC2Launch, CloudInitAgentMarker))
+	}
+
+	if c.CloudInitTimeout == 0 {
+		c.CloudInitTimeout = 10 * time.Minute
+	}
+	return errs
+}
+
+// checkCommand is the command that tells whether the agent is done. It
+// exits with 0 when it is done, with 2 when it failed, and with another
+// status when it is still running.
+func (c *CloudInitConfig) checkCommand(commType string) string {
+	switch c.WaitForCloudInit {
+	case CloudInitAgentCloudInit:
+		// Versions of cloud-init without the status command only have the
+		// marker file
+		return fmt.Sprintf(`s=$(cloud-init status 2>/dev/null); case "$s" in `+
+			`*error*) exit 2;; *done*|*disabled*) exit 0;; "") test -f %s;; *) exit 1;; esac`,
+			DefaultCloudInitMarkerFile)
+	case CloudInitAgentWAAgent:
+		return "test -f /var/lib/waagent/provisioned"
+	case CloudInitAgentEC2Launch:
+		// EC2Launch v1 writes the log of the user data once it ran it, v2
+		// logs it in the log of its agent
+		return `powershell -NoProfile -Command "` +
+			`if ((Test-Path 'C:\ProgramData\Amazon\EC2-Windows\Launch\Log\UserdataExecution.log') -or ` +
+			`(Select-String -Quiet -Pattern 'Script execution finished' -Path 'C:\ProgramData\Amazon\EC2Launch\log\agent.log' -ErrorAction SilentlyContinue)) ` +
+			`{ exit 0 } else { exit 1 }"`
+	case CloudInitAgentMarker:
+		if commType == "winrm" {
+			return fmt.Sprintf(`powershell -NoProfile -Command "if (Test-Path '%s') { exit 0 } else { exit 1 }"`,
+				strings.Replace(c.CloudInitMarkerFile, "'", "''", -1))
+		}
+		return fmt.Sprintf("test -f '%s'", strings.Replace(c.CloudInitMarkerFile, "'", `'\''`, -1))
+	}
+	return ""
+}
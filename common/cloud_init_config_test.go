@@ -0,0 +1,48 @@
+package common
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCloudInitConfigPrepare(t *testing.T) {
+	c := &CloudInitConfig{}
+	if errs := c.Prepare(); len(errs) > 0 {
+		t.Fatalf("should not have error: %s", errs)
+	}
+	if c.CloudInitTimeout != 0 {
+		t.Fatalf("bad: %s", c.CloudInitTimeout)
+	}
+
+	for _, agent := range []string{"cloud-init", "waagent", "ec2launch"} {
+		c := &CloudInitConfig{WaitForCloudInit: agent}
+		if errs := c.Prepare(); len(errs) > 0 {
+			t.Fatalf("%s should not have error: %s", agent, errs)
+		}
+		if c.CloudInitTimeout != 10*time.Minute {
+			t.Fatalf("bad: %s", c.CloudInitTimeout)
+		}
+	}
+
+	c = &CloudInitConfig{WaitForCloudInit: "marker"}
+	if errs := c.Prepare(); len(errs) == 0 {
+		t.Fatal("should have error")
+	}
+
+	c = &CloudInitConfig{WaitForCloudInit: "marker", CloudInitMarkerFile: "/var/lib/done"}
+	if errs := c.Prepare(); len(errs) > 0 {
+		t.Fatalf("should not have error: %s", errs)
+	}
+	if command := c.checkCommand("ssh"); command != "test -f '/var/lib/done'" {
+		t.Fatalf("bad: %s", command)
+	}
+	if command := c.checkCommand("winrm"); !strings.Contains(command, "Test-Path '/var/lib/done'") {
+		t.Fatalf("bad: %s", command)
+	}
+
+	c = &CloudInitConfig{WaitForCloudInit: "bogus"}
+	if errs := c.Prepare(); len(errs) == 0 {
+		t.Fatal("should have error")
+	}
+}
@@ -6,11 +6,14 @@ import (
 	"crypto/sha1"
 	"crypto/sha256"
 	"crypto/sha512"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"hash"
 	"io"
+	"io/ioutil"
 	"log"
 	"net/http"
 	"net/url"
@@ -18,6 +21,7 @@ import (
 	"path"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 
 	"github.com/hashicorp/packer/packer"
@@ -57,6 +61,7 @@ type DownloadConfig struct {
 // A DownloadClient helps download, verify checksums, etc.
 type DownloadClient struct {
 	config *DownloadConfig
+	ui     packer.Ui
 }
 
 // HashForType returns the Hash implementation for the given string
@@ -81,14 +86,9 @@ func HashForType(t string) hash.Hash {
 func NewDownloadClient(c *DownloadConfig, ui packer.Ui) *DownloadClient {
 	// Create downloader map if it hasn't been specified already.
 	if c.DownloaderMap == nil {
-		c.DownloaderMap = map[string]Downloader{
-			"file":  &FileDownloader{Ui: ui, bufferSize: nil},
-			"http":  &HTTPDownloader{Ui: ui, userAgent: c.UserAgent},
-			"https": &HTTPDownloader{Ui: ui, userAgent: c.UserAgent},
-			"smb":   &SMBDownloader{Ui: ui, bufferSize: nil},
-		}
+		c.DownloaderMap = registeredDownloaders(ui, c)
 	}
-	return &DownloadClient{config: c}
+	return &DownloadClient{config: c, ui: ui}
 }
 
 // Downloader defines what capabilities a downloader should have.
@@ -110,6 +110,16 @@ type RemoteDownloader interface {
 	Download(*os.File, *url.URL) error
 }
 
+// lockTarget takes the advisory lock of the target path, see LockPath.
+func (d *DownloadClient) lockTarget() (func(), error) {
+	target := d.config.TargetPath
+	return LockPath(target, func() {
+		if d.ui != nil {
+			d.ui.Message(fmt.Sprintf("Waiting for another download of %s to finish...", target))
+		}
+	})
+}
+
 func (d *DownloadClient) Cancel() {
 	// TODO(mitchellh): Implement
 }
@@ -155,6 +165,21 @@ func (d *DownloadClient) Get() (string, error) {
 
 	// If we're copying the file, then just use the actual downloader
 	if d.config.CopyFile {
+		// Downloads of the same file, by this process or by other ones,
+		// wait for each other instead of writing it at the same time.
+		var unlock func()
+		unlock, err = d.lockTarget()
+		if err != nil {
+			return "", err
+		}
+		defer unlock()
+
+		// The file may have been downloaded while waiting
+		if verify, _ := d.VerifyChecksum(d.config.TargetPath); verify {
+			log.Println("[DEBUG] Checksum matched after waiting, no download needed.")
+			return d.config.TargetPath, nil
+		}
+
 		var f *os.File
 		finalPath = d.config.TargetPath
 
@@ -165,6 +190,12 @@ func (d *DownloadClient) Get() (string, error) {
 
 		log.Printf("[DEBUG] Downloading: %s", u.String())
 		err = remote.Download(f, u)
+		if err == nil && d.ui != nil {
+			// The core counts the bytes downloaded for telemetry
+			if fi, statErr := f.Stat(); statErr == nil {
+				d.ui.Machine("download-bytes", u.Scheme, strconv.FormatInt(fi.Size(), 10))
+			}
+		}
 		f.Close()
 		if err != nil {
 			return "", err
@@ -234,83 +265,73 @@ func (d *HTTPDownloader) Resume() {
 	// TODO(mitchellh): Implement
 }
 
-func (d *HTTPDownloader) Download(dst *os.File, src *url.URL) error {
-	log.Printf("Starting download over HTTP: %s", src.String())
-
-	// Seek to the beginning by default
-	if _, err := dst.Seek(0, 0); err != nil {
-		return err
+// downloadTLSConfig trusts the CAs in SSL_CERT_FILE for the downloads,
+// on the platforms whose system roots don't honor it too. It's nil when
+// the system roots are enough.
+func downloadTLSConfig() *tls.Config {
+	path := os.Getenv("SSL_CERT_FILE")
+	if path == "" {
+		return nil
 	}
 
-	var current int64
-
-	// Make the request. We first make a HEAD request so we can check
-	// if the server supports range queries. If the server/URL doesn't
-	// support HEAD requests, we just fall back to GET.
-	req, err := http.NewRequest("HEAD", src.String(), nil)
+	pem, err := ioutil.ReadFile(path)
 	if err != nil {
-		return err
+		log.Printf("[WARN] (download) Error reading SSL_CERT_FILE: %s", err)
+		return nil
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
 	}
+	if !pool.AppendCertsFromPEM(pem) {
+		log.Printf("[WARN] (download) No certificates in SSL_CERT_FILE %s", path)
+		return nil
+	}
+	return &tls.Config{RootCAs: pool}
+}
 
-	if d.userAgent != "" {
-		req.Header.Set("User-Agent", d.userAgent)
+func (d *HTTPDownloader) Download(dst *os.File, src *url.URL) error {
+	log.Printf("Starting download over HTTP: %s", src.String())
+
+	// What was already downloaded of the file is kept if the server
+	// supports range queries.
+	var current int64
+	if fi, err := dst.Stat(); err == nil {
+		current = fi.Size()
 	}
 
 	httpClient := &http.Client{
 		Transport: &http.Transport{
-			Proxy: http.ProxyFromEnvironment,
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: downloadTLSConfig(),
 		},
 	}
 
-	resp, err := httpClient.Do(req)
-	if err != nil || resp == nil {
-
-		if resp == nil {
-			log.Printf("[DEBUG] (download) HTTP connection error: %s", err.Error())
-
-		} else if resp.StatusCode >= 400 && resp.StatusCode < 600 {
-			log.Printf("[DEBUG] (download) Non-successful HTTP status code (%s) while making HEAD request: %s", resp.Status, err.Error())
-
-		} else {
-			log.Printf("[DEBUG] (download) Error making HTTP HEAD request (%s): %s", resp.Status, err.Error())
-		}
-
-	} else {
-
-		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-			// If the HEAD request succeeded, then attempt to set the range
-			// query if we can.
-
-			if resp.Header.Get("Accept-Ranges") == "bytes" {
-				if fi, err := dst.Stat(); err == nil {
-					if _, err = dst.Seek(0, os.SEEK_END); err == nil {
-						req.Header.Set("Range", fmt.Sprintf("bytes=%d-", fi.Size()))
-
-						current = fi.Size()
-					}
-				}
-			}
-
-		} else {
-			log.Printf("[DEBUG] (download) Unexpected HTTP response during HEAD request: %s", resp.Status)
-		}
+	// We first make a HEAD request so we can check if the server supports
+	// range queries. If the server/URL doesn't support HEAD requests, like
+	// presigned S3 URLs, the GET request probes it with the range instead.
+	if current > 0 && !d.headAcceptsRanges(httpClient, src) {
+		current = 0
 	}
 
-	// Set the request to GET now, and redo the query to download
-	req.Method = "GET"
-
-	resp, err = httpClient.Do(req)
-	if err == nil && (resp.StatusCode >= 400 && resp.StatusCode < 600) {
-		return fmt.Errorf("Error making HTTP GET request: %s", resp.Status)
-
-	} else if err != nil {
-		if resp == nil {
-			return fmt.Errorf("HTTP connection error: %s", err.Error())
+	resp, err := d.get(httpClient, src, current)
+	if err == nil && current > 0 {
+		current, resp, err = d.resumeFrom(httpClient, src, current, resp)
+	}
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
 
-		} else if resp.StatusCode >= 400 && resp.StatusCode < 600 {
-			return fmt.Errorf("HTTP %s error: %s", resp.Status, err.Error())
-		}
-		return fmt.Errorf("HTTP error: %s", err.Error())
+	if current < 0 {
+		// The file was already complete
+		return nil
+	}
+	if _, err := dst.Seek(current, io.SeekStart); err != nil {
+		return err
+	}
+	if err := dst.Truncate(current); err != nil {
+		return err
 	}
 
 	total := current + resp.ContentLength
@@ -323,6 +344,7 @@ func (d *HTTPDownloader) Download(dst *os.File, src *url.URL) error {
 	body := bar.NewProxyReader(resp.Body)
 
 	var buffer [4096]byte
+	var written int64
 	for {
 		n, err := body.Read(buffer[:])
 		if err != nil && err != io.EOF {
@@ -332,14 +354,132 @@ func (d *HTTPDownloader) Download(dst *os.File, src *url.URL) error {
 		if _, werr := dst.Write(buffer[:n]); werr != nil {
 			return werr
 		}
+		written += int64(n)
 
 		if err == io.EOF {
 			break
 		}
 	}
+
+	// Files downloaded without a checksum are at least checked for this
+	if resp.ContentLength >= 0 && written != resp.ContentLength {
+		return fmt.Errorf("Downloaded %d bytes, the server announced %d", written, resp.ContentLength)
+	}
 	return nil
 }
 
+// headAcceptsRanges says whether the file may be downloaded with range
+// queries. It is only false when the HEAD request succeeds and says they
+// aren't supported, the ranged GET request is the probe otherwise.
+func (d *HTTPDownloader) headAcceptsRanges(httpClient *http.Client, src *url.URL) bool {
+	req, err := http.NewRequest("HEAD", src.String(), nil)
+	if err != nil {
+		return false
+	}
+	if d.userAgent != "" {
+		req.Header.Set("User-Agent", d.userAgent)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		log.Printf("[DEBUG] (download) Error making HTTP HEAD request, probing the range with GET: %s", err)
+		return true
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		log.Printf("[DEBUG] (download) Unexpected HTTP response during HEAD request, probing the range with GET: %s", resp.Status)
+		return true
+	}
+	return resp.Header.Get("Accept-Ranges") == "bytes"
+}
+
+// get makes the GET request of the file, from offset when it's set.
+func (d *HTTPDownloader) get(httpClient *http.Client, src *url.URL, offset int64) (*http.Response, error) {
+	req, err := http.NewRequest("GET", src.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if d.userAgent != "" {
+		req.Header.Set("User-Agent", d.userAgent)
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP connection error: %s", err.Error())
+	}
+	// The range can't be satisfied when the file is already complete
+	if resp.StatusCode >= 400 && resp.StatusCode < 600 &&
+		!(offset > 0 && resp.StatusCode == http.StatusRequestedRangeNotSatisfiable) {
+		resp.Body.Close()
+		return nil, fmt.Errorf("Error making HTTP GET request: %s", resp.Status)
+	}
+	return resp, nil
+}
+
+// resumeFrom checks the response to the ranged GET request. It returns
+// where the body goes in the file, 0 when the server sent the whole file,
+// or -1 when the file was already complete.
+func (d *HTTPDownloader) resumeFrom(httpClient *http.Client, src *url.URL, offset int64, resp *http.Response) (int64, *http.Response, error) {
+	start, total, ok := parseContentRange(resp.Header.Get("Content-Range"))
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && ok && start == offset:
+		log.Printf("[DEBUG] (download) Resuming the download at %d bytes", offset)
+		return offset, resp, nil
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable && total == offset:
+		log.Printf("[DEBUG] (download) The file was already downloaded")
+		return -1, resp, nil
+	case resp.StatusCode == http.StatusOK:
+		log.Printf("[DEBUG] (download) The server ignored the range, downloading the whole file")
+		return 0, resp, nil
+	}
+
+	// The range doesn't match what was downloaded, start over
+	log.Printf("[DEBUG] (download) Unexpected ranged response (%s, %q), downloading the whole file",
+		resp.Status, resp.Header.Get("Content-Range"))
+	resp.Body.Close()
+	resp, err := d.get(httpClient, src, 0)
+	return 0, resp, err
+}
+
+// parseContentRange parses a Content-Range header like "bytes 100-199/200"
+// or "bytes */200". start is -1 for the latter, and total is -1 when it
+// isn't known.
+func parseContentRange(header string) (start, total int64, ok bool) {
+	header = strings.TrimSpace(header)
+	if !strings.HasPrefix(header, "bytes ") {
+		return 0, 0, false
+	}
+	parts := strings.SplitN(strings.TrimPrefix(header, "bytes "), "/", 2)
+	if len(parts) != 2 {
+		return 0, 0, false
+	}
+
+	total = -1
+	if parts[1] != "*" {
+		var err error
+		if total, err = strconv.ParseInt(parts[1], 10, 64); err != nil {
+			return 0, 0, false
+		}
+	}
+
+	if parts[0] == "*" {
+		return -1, total, true
+	}
+	i := strings.Index(parts[0], "-")
+	if i < 0 {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseInt(parts[0][:i], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return start, total, true
+}
+
 // FileDownloader is an implementation of Downloader that downloads
 // files using the regular filesystem.
 type FileDownloader struct {
@@ -0,0 +1,137 @@
+package common
+
+import (
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// RemoteFilename is the name of the file at the URL. For HTTP URLs it's the
+// filename of the Content-Disposition header, or the last segment of the
+// URL redirects end at, like for release redirectors whose URLs are like
+// "download?id=123". It's the last segment of the URL otherwise.
+func RemoteFilename(raw, userAgent string) (string, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return urlFilename(u), nil
+	}
+
+	httpClient := &http.Client{
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: downloadTLSConfig(),
+		},
+	}
+
+	// Servers that reject HEAD requests get a GET request of the first byte
+	resp, err := filenameRequest(httpClient, "HEAD", raw, userAgent)
+	if err != nil {
+		log.Printf("[DEBUG] (download) Error making HTTP HEAD request, trying GET: %s", err)
+		resp, err = filenameRequest(httpClient, "GET", raw, userAgent)
+	}
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	if name := dispositionFilename(resp.Header.Get("Content-Disposition")); name != "" {
+		return name, nil
+	}
+	return urlFilename(resp.Request.URL), nil
+}
+
+func filenameRequest(httpClient *http.Client, method, raw, userAgent string) (*http.Response, error) {
+	req, err := http.NewRequest(method, raw, nil)
+	if err != nil {
+		return nil, err
+	}
+	if userAgent != "" {
+		req.Header.Set("User-Agent", userAgent)
+	}
+	if method == "GET" {
+		req.Header.Set("Range", "bytes=0-0")
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("Error making HTTP %s request: %s", method, resp.Status)
+	}
+	return resp, nil
+}
+
+// dispositionFilename is the filename of a Content-Disposition header, only
+// its base name so it can't point out of the directory it goes to.
+func dispositionFilename(header string) string {
+	if header == "" {
+		return ""
+	}
+	_, params, err := mime.ParseMediaType(header)
+	if err != nil {
+		return ""
+	}
+	return safeFilename(params["filename"])
+}
+
+func urlFilename(u *url.URL) string {
+	return safeFilename(path.Base(u.Path))
+}
+
+// urlBase is the last segment of the URL, empty if there is none.
+func urlBase(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	return urlFilename(u)
+}
+
+// isLocalURL says whether the file of the URL is on the local disk, so it
+// can be used in offline mode.
+func isLocalURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && u.Scheme == "file"
+}
+
+func safeFilename(name string) string {
+	name = filepath.Base(strings.Replace(name, "\\", "/", -1))
+	if name == "." || name == ".." || name == "/" || name == string(os.PathSeparator) {
+		return ""
+	}
+	return name
+}
+
+// hasFilename says whether the last segment of the URL says what the file
+// is, with an extension.
+func hasFilename(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return true
+	}
+	return path.Ext(u.Path) != ""
+}
+
+// isDirTarget says whether the target path of a download is a directory
+// the file goes in, an existing one or a path ending with a separator.
+func isDirTarget(target string) bool {
+	if target == "" {
+		return false
+	}
+	if strings.HasSuffix(target, "/") || strings.HasSuffix(target, string(os.PathSeparator)) {
+		return true
+	}
+	fi, err := os.Stat(target)
+	return err == nil && fi.IsDir()
+}
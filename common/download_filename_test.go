@@ -0,0 +1,62 @@
+package common
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRemoteFilename(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/download":
+			http.Redirect(rw, r, "/releases/os-1.2.iso?token=abc", http.StatusFound)
+		case "/attachment":
+			rw.Header().Set("Content-Disposition", `attachment; filename="os-1.3.iso"`)
+		case "/presigned":
+			// Only signed for GET
+			if r.Method == "HEAD" {
+				rw.WriteHeader(http.StatusForbidden)
+				return
+			}
+			rw.Header().Set("Content-Disposition", `attachment; filename="os-1.4.iso"`)
+		}
+	}))
+	defer ts.Close()
+
+	cases := []struct {
+		URL      string
+		Expected string
+	}{
+		{ts.URL + "/download?id=123", "os-1.2.iso"},
+		{ts.URL + "/attachment?id=123", "os-1.3.iso"},
+		{ts.URL + "/presigned?signature=abc", "os-1.4.iso"},
+		{"file:///var/isos/os-1.5.iso", "os-1.5.iso"},
+	}
+	for _, tc := range cases {
+		name, err := RemoteFilename(tc.URL, "")
+		if err != nil {
+			t.Fatalf("%s: err: %s", tc.URL, err)
+		}
+		if name != tc.Expected {
+			t.Fatalf("%s: bad: %s", tc.URL, name)
+		}
+	}
+}
+
+func TestDispositionFilename(t *testing.T) {
+	cases := map[string]string{
+		`attachment; filename="os.iso"`:           "os.iso",
+		`attachment; filename="../../etc/os.iso"`: "os.iso",
+		`attachment; filename="..\\..\\os.iso"`:   "os.iso",
+		`attachment; filename=".."`:               "",
+		`attachment; filename*=UTF-8''os%20.iso`:  "os .iso",
+		`attachment`:                              "",
+		``:                                        "",
+	}
+	for header, expected := range cases {
+		if name := dispositionFilename(header); name != expected {
+			t.Errorf("%s: bad: %q", header, name)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+// DownloaderFactory returns the downloader of a download, which must be a
+// RemoteDownloader.
+type DownloaderFactory func(ui packer.Ui, config *DownloadConfig) Downloader
+
+// downloaders are the downloaders of the URL schemes, the ones of Packer and
+// the ones registered by builders and plugins.
+var downloaders = struct {
+	sync.RWMutex
+	m map[string]DownloaderFactory
+}{m: map[string]DownloaderFactory{
+	"file": func(ui packer.Ui, _ *DownloadConfig) Downloader {
+		return &FileDownloader{Ui: ui, bufferSize: nil}
+	},
+	"http": func(ui packer.Ui, c *DownloadConfig) Downloader {
+		return &HTTPDownloader{Ui: ui, userAgent: c.UserAgent}
+	},
+	"https": func(ui packer.Ui, c *DownloadConfig) Downloader {
+		return &HTTPDownloader{Ui: ui, userAgent: c.UserAgent}
+	},
+	"smb": func(ui packer.Ui, _ *DownloadConfig) Downloader {
+		return &SMBDownloader{Ui: ui, bufferSize: nil}
+	},
+}}
+
+// RegisterDownloader makes the downloader of the factory download the URLs
+// of the scheme, like "artifactory", for the DownloadClient and everything
+// that uses it, such as the iso_url of the builders. It's meant to be called
+// from an init function, in the process of the plugin that downloads. It
+// panics if the scheme already has a downloader.
+func RegisterDownloader(scheme string, factory DownloaderFactory) {
+	scheme = strings.ToLower(scheme)
+	if factory == nil {
+		panic(fmt.Sprintf("downloader of %q is nil", scheme))
+	}
+
+	downloaders.Lock()
+	defer downloaders.Unlock()
+	if _, ok := downloaders.m[scheme]; ok {
+		panic(fmt.Sprintf("scheme %q already has a downloader", scheme))
+	}
+	downloaders.m[scheme] = factory
+}
+
+func registeredDownloaders(ui packer.Ui, c *DownloadConfig) map[string]Downloader {
+	downloaders.RLock()
+	defer downloaders.RUnlock()
+
+	result := make(map[string]Downloader, len(downloaders.m))
+	for scheme, factory := range downloaders.m {
+		result[scheme] = factory(ui, c)
+	}
+	return result
+}
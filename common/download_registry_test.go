@@ -0,0 +1,76 @@
+package common
+
+import (
+	"io/ioutil"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+// casDownloader downloads the files of a content-addressed store, whose
+// URLs are like cas://sha256/<checksum>.
+type casDownloader struct {
+	files map[string]string
+}
+
+func (*casDownloader) Resume()                         {}
+func (*casDownloader) Cancel()                         {}
+func (*casDownloader) ProgressBar() packer.ProgressBar { return &packer.NoopProgressBar{} }
+
+func (d *casDownloader) Download(dst *os.File, src *url.URL) error {
+	_, err := dst.WriteString(d.files[src.Path])
+	return err
+}
+
+func TestRegisterDownloader(t *testing.T) {
+	cas := &casDownloader{files: map[string]string{"/abc": "hello\n"}}
+	RegisterDownloader("CAS", func(packer.Ui, *DownloadConfig) Downloader {
+		return cas
+	})
+	defer func() {
+		downloaders.Lock()
+		delete(downloaders.m, "cas")
+		downloaders.Unlock()
+	}()
+
+	if _, err := ValidatedURL("cas://sha256/abc"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	tf, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	tf.Close()
+	defer os.Remove(tf.Name())
+
+	client := NewDownloadClient(&DownloadConfig{
+		Url:        "cas://sha256/abc",
+		TargetPath: tf.Name(),
+		CopyFile:   true,
+	}, new(packer.NoopUi))
+	path, err := client.Get()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if string(raw) != "hello\n" {
+		t.Fatalf("bad: %q", raw)
+	}
+}
+
+func TestRegisterDownloader_duplicate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("should panic")
+		}
+	}()
+	RegisterDownloader("https", func(packer.Ui, *DownloadConfig) Downloader {
+		return &HTTPDownloader{}
+	})
+}
@@ -3,6 +3,7 @@ package common
 import (
 	"crypto/md5"
 	"encoding/hex"
+	"encoding/pem"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -11,7 +12,10 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/packer/packer"
 )
@@ -78,6 +82,42 @@ func TestDownloadClient_basic(t *testing.T) {
 	}
 }
 
+func TestDownloadClient_sslCertFile(t *testing.T) {
+	tf, _ := ioutil.TempFile("", "packer")
+	tf.Close()
+	defer os.Remove(tf.Name())
+
+	ts := httptest.NewTLSServer(http.FileServer(http.Dir("./test-fixtures/root")))
+	defer ts.Close()
+
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})
+	cf, _ := ioutil.TempFile("", "packer-ca")
+	cf.Write(cert)
+	cf.Close()
+	defer os.Remove(cf.Name())
+	defer os.Setenv("SSL_CERT_FILE", os.Getenv("SSL_CERT_FILE"))
+	os.Setenv("SSL_CERT_FILE", cf.Name())
+
+	client := NewDownloadClient(&DownloadConfig{
+		Url:        ts.URL + "/basic.txt",
+		TargetPath: tf.Name(),
+		CopyFile:   true,
+	}, new(packer.NoopUi))
+
+	path, err := client.Get()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if string(raw) != "hello\n" {
+		t.Fatalf("bad: %s", string(raw))
+	}
+}
+
 func TestDownloadClient_checksumBad(t *testing.T) {
 	checksum, err := hex.DecodeString("b2946ac92492d2347c6235b4d2611184")
 	if err != nil {
@@ -230,6 +270,111 @@ func TestDownloadClient_resume(t *testing.T) {
 	}
 }
 
+func TestDownloadClient_resumeWithoutHead(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Existing string
+		Handler  http.HandlerFunc
+		Expected string
+	}{
+		{
+			// Like presigned S3 URLs, that are only signed for GET
+			"ranged GET",
+			"he",
+			func(rw http.ResponseWriter, r *http.Request) {
+				http.ServeFile(rw, r, "./test-fixtures/root/basic.txt")
+			},
+			"hello\n",
+		},
+		{
+			"range ignored",
+			"junk data longer than the file",
+			func(rw http.ResponseWriter, r *http.Request) {
+				rw.Write([]byte("hello\n"))
+			},
+			"hello\n",
+		},
+		{
+			"already complete",
+			"hello\n",
+			func(rw http.ResponseWriter, r *http.Request) {
+				http.ServeFile(rw, r, "./test-fixtures/root/basic.txt")
+			},
+			"hello\n",
+		},
+		{
+			"range mismatch",
+			"junk data longer than the file",
+			func(rw http.ResponseWriter, r *http.Request) {
+				http.ServeFile(rw, r, "./test-fixtures/root/basic.txt")
+			},
+			"hello\n",
+		},
+	}
+
+	for _, tc := range cases {
+		tf, _ := ioutil.TempFile("", "packer")
+		tf.Write([]byte(tc.Existing))
+		tf.Close()
+		defer os.Remove(tf.Name())
+
+		var ranges []string
+		ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if r.Method == "HEAD" {
+				rw.WriteHeader(http.StatusForbidden)
+				return
+			}
+			ranges = append(ranges, r.Header.Get("Range"))
+			tc.Handler(rw, r)
+		}))
+
+		client := NewDownloadClient(&DownloadConfig{
+			Url:        ts.URL + "/basic.txt",
+			TargetPath: tf.Name(),
+			CopyFile:   true,
+		}, new(packer.NoopUi))
+		path, err := client.Get()
+		ts.Close()
+		if err != nil {
+			t.Fatalf("%s: err: %s", tc.Name, err)
+		}
+
+		raw, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatalf("%s: err: %s", tc.Name, err)
+		}
+		if string(raw) != tc.Expected {
+			t.Fatalf("%s: bad: %q", tc.Name, raw)
+		}
+		if len(ranges) == 0 || ranges[0] != fmt.Sprintf("bytes=%d-", len(tc.Existing)) {
+			t.Fatalf("%s: the GET request should probe the range: %#v", tc.Name, ranges)
+		}
+	}
+}
+
+func TestParseContentRange(t *testing.T) {
+	cases := []struct {
+		Header string
+		Start  int64
+		Total  int64
+		Ok     bool
+	}{
+		{"bytes 100-199/200", 100, 200, true},
+		{"bytes 0-0/*", 0, -1, true},
+		{"bytes */200", -1, 200, true},
+		{"", 0, 0, false},
+		{"bytes 100/200", 0, 0, false},
+		{"items 0-1/2", 0, 0, false},
+	}
+
+	for _, tc := range cases {
+		start, total, ok := parseContentRange(tc.Header)
+		if start != tc.Start || total != tc.Total || ok != tc.Ok {
+			t.Errorf("%q: got %d, %d, %t", tc.Header, start, total, ok)
+		}
+	}
+}
+
 func TestDownloadClient_usesDefaultUserAgent(t *testing.T) {
 	tf, err := ioutil.TempFile("", "packer")
 	if err != nil {
@@ -525,3 +670,68 @@ func TestFileUriTransforms(t *testing.T) {
 		t.Logf("TestFileUriTransforms : Result Path '%s'", res)
 	}
 }
+
+func TestDownloadClient_lock(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			atomic.AddInt32(&requests, 1)
+		}
+		// Slow enough for the downloads to overlap
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte("hello\n"))
+	}))
+	defer ts.Close()
+
+	checksum, err := hex.DecodeString("b1946ac92492d2347c6235b4d2611184")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	target := filepath.Join(td, "basic.txt")
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client := NewDownloadClient(&DownloadConfig{
+				Url:        ts.URL + "/basic.txt",
+				TargetPath: target,
+				Hash:       HashForType("md5"),
+				Checksum:   checksum,
+				CopyFile:   true,
+			}, new(packer.NoopUi))
+			_, err := client.Get()
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	// The downloads waiting for the lock reuse the downloaded file
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Fatalf("bad: %d downloads", n)
+	}
+	raw, err := ioutil.ReadFile(target)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if string(raw) != "hello\n" {
+		t.Fatalf("bad: %q", raw)
+	}
+	if _, err := os.Stat(target + ".lock"); !os.IsNotExist(err) {
+		t.Fatalf("the lock file should be removed: %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+package common
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// archiveType is the type of archive at the path or URL, "zip", "tar.gz",
+// or "tgz", or empty if it isn't one extractArchive can extract.
+func archiveType(path string) string {
+	if u, err := url.Parse(path); err == nil && u.Path != "" {
+		path = u.Path
+	}
+	path = strings.ToLower(path)
+	for _, t := range []string{"zip", "tar.gz", "tgz"} {
+		if strings.HasSuffix(path, "."+t) {
+			return t
+		}
+	}
+	return ""
+}
+
+// extractArchive writes the file of the archive to dst. The archive must
+// have a single file, directories aside.
+func extractArchive(archive, dst string) error {
+	var err error
+	switch archiveType(archive) {
+	case "zip":
+		err = extractZip(archive, dst)
+	case "tar.gz", "tgz":
+		err = extractTarGz(archive, dst)
+	default:
+		return fmt.Errorf("unsupported archive: %s", archive)
+	}
+	if err != nil {
+		os.Remove(dst)
+	}
+	return err
+}
+
+func extractZip(archive, dst string) error {
+	r, err := zip.OpenReader(archive)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	var files []*zip.File
+	for _, f := range r.File {
+		if !f.FileInfo().IsDir() {
+			files = append(files, f)
+		}
+	}
+	if len(files) != 1 {
+		return fmt.Errorf("the archive must have a single file, it has %d", len(files))
+	}
+
+	src, err := files[0].Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	return writeExtracted(src, dst)
+}
+
+func extractTarGz(archive, dst string) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	found := false
+	tr := tar.NewReader(gz)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if h.Typeflag != tar.TypeReg && h.Typeflag != tar.TypeRegA {
+			continue
+		}
+		if found {
+			return fmt.Errorf("the archive must have a single file, it has more")
+		}
+		found = true
+		if err := writeExtracted(tr, dst); err != nil {
+			return err
+		}
+	}
+	if !found {
+		return fmt.Errorf("the archive must have a single file, it has none")
+	}
+	return nil
+}
+
+func writeExtracted(src io.Reader, dst string) error {
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, src); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
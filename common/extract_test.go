@@ -0,0 +1,116 @@
+package common
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeZip writes an archive with the files to path.
+func writeZip(t *testing.T, path string, files map[string]string) {
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer f.Close()
+	w := zip.NewWriter(f)
+	for name, contents := range files {
+		fw, err := w.Create(name)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		fw.Write([]byte(contents))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+// writeTarGz writes an archive with the files to path.
+func writeTarGz(t *testing.T, path string, files map[string]string) {
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	w := tar.NewWriter(gz)
+	w.WriteHeader(&tar.Header{Name: "dir/", Typeflag: tar.TypeDir, Mode: 0755})
+	for name, contents := range files {
+		w.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(contents))})
+		w.Write([]byte(contents))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestArchiveType(t *testing.T) {
+	cases := map[string]string{
+		"foo.iso":                              "",
+		"foo.zip":                              "zip",
+		"http://example.com/foo.ISO.ZIP?a=b":   "zip",
+		"file:///tmp/foo.tar.gz":               "tar.gz",
+		"foo.tgz":                              "tgz",
+		"http://example.com/foo.iso?f=bar.zip": "",
+	}
+	for input, expected := range cases {
+		if actual := archiveType(input); actual != expected {
+			t.Fatalf("%s: bad: %q", input, actual)
+		}
+	}
+}
+
+func TestExtractArchive(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	writeZip(t, filepath.Join(td, "a.zip"), map[string]string{"dir/a.iso": "zip"})
+	writeTarGz(t, filepath.Join(td, "a.tar.gz"), map[string]string{"dir/a.iso": "tar"})
+	writeZip(t, filepath.Join(td, "two.zip"), map[string]string{"a": "a", "b": "b"})
+	writeTarGz(t, filepath.Join(td, "two.tgz"), map[string]string{"a": "a", "b": "b"})
+	writeTarGz(t, filepath.Join(td, "none.tgz"), nil)
+
+	cases := []struct {
+		Archive  string
+		Contents string
+		Err      bool
+	}{
+		{"a.zip", "zip", false},
+		{"a.tar.gz", "tar", false},
+		{"two.zip", "", true},
+		{"two.tgz", "", true},
+		{"none.tgz", "", true},
+	}
+	for _, tc := range cases {
+		dst := filepath.Join(td, "result.iso")
+		err := extractArchive(filepath.Join(td, tc.Archive), dst)
+		if (err != nil) != tc.Err {
+			t.Fatalf("%s: err: %s", tc.Archive, err)
+		}
+		if tc.Err {
+			if _, err := os.Stat(dst); err == nil {
+				t.Fatalf("%s: shouldn't leave the file", tc.Archive)
+			}
+			continue
+		}
+
+		contents, err := ioutil.ReadFile(dst)
+		if err != nil {
+			t.Fatalf("%s: err: %s", tc.Archive, err)
+		}
+		if string(contents) != tc.Contents {
+			t.Fatalf("%s: bad: %q", tc.Archive, contents)
+		}
+	}
+}
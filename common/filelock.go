@@ -0,0 +1,65 @@
+package common
+
+import (
+	"fmt"
+	"os"
+)
+
+// LockPath takes the advisory lock of the path, on a ".lock" file next to
+// it, and returns the function that releases it. Builds running at the same
+// time, in this process or another one, take it in turn. waiting, if not
+// nil, is called before waiting for another one to release it. The lock file
+// is removed when it is released, and the lock is released when the process
+// dies.
+func LockPath(target string, waiting func()) (func(), error) {
+	unlock, _, err := lockPath(target, true, waiting)
+	return unlock, err
+}
+
+// TryLockPath is LockPath without waiting: it says whether it got the lock.
+func TryLockPath(target string) (func(), bool, error) {
+	return lockPath(target, false, nil)
+}
+
+func lockPath(target string, wait bool, waiting func()) (func(), bool, error) {
+	path := target + ".lock"
+	for {
+		f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0666)
+		if err != nil {
+			return nil, false, fmt.Errorf("Error locking %s: %s", target, err)
+		}
+
+		ok, err := tryLockFile(f)
+		if err == nil && !ok {
+			if !wait {
+				f.Close()
+				return nil, false, nil
+			}
+			if waiting != nil {
+				waiting()
+			}
+			err = lockFile(f)
+		}
+		if err != nil {
+			f.Close()
+			return nil, false, fmt.Errorf("Error locking %s: %s", target, err)
+		}
+
+		// The one that had the lock may have removed the file, the lock is
+		// then on a file the others don't see.
+		locked, err := f.Stat()
+		if err == nil {
+			var current os.FileInfo
+			current, err = os.Stat(path)
+			if err == nil && os.SameFile(locked, current) {
+				return func() {
+					os.Remove(path)
+					unlockFile(f)
+					f.Close()
+				}, true, nil
+			}
+		}
+		unlockFile(f)
+		f.Close()
+	}
+}
@@ -0,0 +1,53 @@
+// +build windows
+
+package common
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	_LOCKFILE_FAIL_IMMEDIATELY = 0x1
+	_LOCKFILE_EXCLUSIVE_LOCK   = 0x2
+
+	_ERROR_LOCK_VIOLATION syscall.Errno = 33
+)
+
+var kernel32_LockFileExProc = kernel32.NewProc("LockFileEx")
+var kernel32_UnlockFileExProc = kernel32.NewProc("UnlockFileEx")
+
+func kernel32_LockFileEx(f *os.File, flags uint32) error {
+	var ol syscall.Overlapped
+	ok, _, err := kernel32_LockFileExProc.Call(
+		f.Fd(), uintptr(flags), 0, 1, 0, uintptr(unsafe.Pointer(&ol)))
+	if int(ok) == 0 {
+		return error(err)
+	}
+	return nil
+}
+
+// tryLockFile takes an exclusive advisory lock on the file, and says whether
+// it got it without waiting.
+func tryLockFile(f *os.File) (bool, error) {
+	err := kernel32_LockFileEx(f, _LOCKFILE_EXCLUSIVE_LOCK|_LOCKFILE_FAIL_IMMEDIATELY)
+	if err == _ERROR_LOCK_VIOLATION {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// lockFile takes an exclusive advisory lock on the file, waiting for it.
+func lockFile(f *os.File) error {
+	return kernel32_LockFileEx(f, _LOCKFILE_EXCLUSIVE_LOCK)
+}
+
+func unlockFile(f *os.File) error {
+	var ol syscall.Overlapped
+	ok, _, err := kernel32_UnlockFileExProc.Call(f.Fd(), 0, 1, 0, uintptr(unsafe.Pointer(&ol)))
+	if int(ok) == 0 {
+		return error(err)
+	}
+	return nil
+}
@@ -10,8 +10,9 @@ import (
 )
 
 type FloppyConfig struct {
-	FloppyFiles       []string `mapstructure:"floppy_files"`
-	FloppyDirectories []string `mapstructure:"floppy_dirs"`
+	FloppyFiles       []string        `mapstructure:"floppy_files"`
+	FloppyDirectories []string        `mapstructure:"floppy_dirs"`
+	Unattend          *UnattendConfig `mapstructure:"floppy_unattend"`
 }
 
 func (c *FloppyConfig) Prepare(ctx *interpolate.Context) []error {
@@ -48,5 +49,15 @@ func (c *FloppyConfig) Prepare(ctx *interpolate.Context) []error {
 		}
 	}
 
+	if c.Unattend != nil {
+		for _, path := range c.FloppyFiles {
+			if strings.EqualFold(filepath.Base(path), UnattendFile) {
+				errs = append(errs, fmt.Errorf(
+					"floppy_files can't have an %s with floppy_unattend", UnattendFile))
+			}
+		}
+		errs = append(errs, c.Unattend.Prepare(ctx)...)
+	}
+
 	return errs
 }
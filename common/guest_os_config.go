@@ -0,0 +1,96 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/packer/helper/communicator"
+	"github.com/hashicorp/packer/packer"
+)
+
+// The guest OS families which have default commands.
+const (
+	GuestOSLinuxSystemd = "linux-systemd"
+	GuestOSLinuxSysV    = "linux-sysv"
+	GuestOSWindows      = "windows"
+	GuestOSBSD          = "bsd"
+)
+
+// GuestOSConfig contains the family of the OS of the guest, which gives the
+// default shutdown command, and the commands that remove the temporary files
+// of the provisioners and the temporary SSH key before the guest is shut
+// down. Nothing is done by default when no family is set.
+type GuestOSConfig struct {
+	GuestOSFamily string `mapstructure:"guest_os_family"`
+}
+
+func (c *GuestOSConfig) Prepare() []error {
+	switch c.GuestOSFamily {
+	case "", GuestOSLinuxSystemd, GuestOSLinuxSysV, GuestOSWindows, GuestOSBSD:
+		return nil
+	}
+	return []error{fmt.Errorf("guest_os_family must be one of %s, %s, %s or %s",
+		GuestOSLinuxSystemd, GuestOSLinuxSysV, GuestOSWindows, GuestOSBSD)}
+}
+
+// DefaultShutdownCommand is the command that powers off the guest, empty
+// when no family is set. Unix commands are run with sudo unless the
+// communicator logs in as root, with the SSH password when there is one.
+func (c *GuestOSConfig) DefaultShutdownCommand(comm *communicator.Config) string {
+	switch c.GuestOSFamily {
+	case GuestOSLinuxSystemd:
+		return sudo(comm) + "systemctl poweroff"
+	case GuestOSLinuxSysV:
+		return sudo(comm) + "shutdown -h now"
+	case GuestOSBSD:
+		return sudo(comm) + "shutdown -p now"
+	case GuestOSWindows:
+		return `shutdown /s /t 10 /f /d p:4:1 /c "Packer Shutdown"`
+	}
+	return ""
+}
+
+// CleanupCommand is the command that removes the files the provisioners
+// upload to the temporary directory of the guest, like the scripts of the
+// shell and powershell provisioners, empty when no family is set.
+func (c *GuestOSConfig) CleanupCommand(comm *communicator.Config) string {
+	switch c.GuestOSFamily {
+	case GuestOSLinuxSystemd, GuestOSLinuxSysV, GuestOSBSD:
+		return sudo(comm) + "rm -rf /tmp/script_*.sh /tmp/packer-*"
+	case GuestOSWindows:
+		return `powershell -NoProfile -Command "Remove-Item -Recurse -Force -ErrorAction SilentlyContinue C:\Windows\Temp\script-*, C:\Windows\Temp\packer-*"`
+	}
+	return ""
+}
+
+// RemoveKeyCommands are the commands that remove the temporary SSH key of
+// the name from the authorized keys of the user and of the administrators.
+func (c *GuestOSConfig) RemoveKeyCommands(comm *communicator.Config, keyName string) []string {
+	switch c.GuestOSFamily {
+	case GuestOSLinuxSystemd, GuestOSLinuxSysV, GuestOSBSD:
+		return []string{
+			fmt.Sprintf("sed -i.bak '/ssh-rsa.*%s$/d' ~/.ssh/authorized_keys; rm ~/.ssh/authorized_keys.bak", keyName),
+			fmt.Sprintf("%[1]ssed -i.bak '/ssh-rsa.*%[2]s$/d' /root/.ssh/authorized_keys; %[1]srm /root/.ssh/authorized_keys.bak", sudo(comm), keyName),
+		}
+	case GuestOSWindows:
+		remove := `powershell -NoProfile -Command "$f = %s; if (Test-Path $f) { (Get-Content $f) | Where-Object { $_ -notmatch '%s$' } | Set-Content $f }"`
+		return []string{
+			fmt.Sprintf(remove, `Join-Path $env:USERPROFILE '.ssh\authorized_keys'`, keyName),
+			fmt.Sprintf(remove, `'C:\ProgramData\ssh\administrators_authorized_keys'`, keyName),
+		}
+	}
+	return nil
+}
+
+// sudo is the prefix of the commands that need root on Unix guests.
+func sudo(comm *communicator.Config) string {
+	if comm.Type == "ssh" && comm.SSHUsername == "root" {
+		return ""
+	}
+	if comm.Type == "ssh" && comm.SSHPassword != "" {
+		packer.LogSecretFilter.Set(comm.SSHPassword)
+		password := strings.Replace(comm.SSHPassword, "'", `'\''`, -1)
+		return fmt.Sprintf("echo '%s' | sudo -S -p '' ", password)
+	}
+	return "sudo -n "
+}
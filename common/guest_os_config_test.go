@@ -0,0 +1,66 @@
+package common
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer/helper/communicator"
+)
+
+func TestGuestOSConfigPrepare(t *testing.T) {
+	for _, family := range []string{"", "linux-systemd", "linux-sysv", "windows", "bsd"} {
+		c := &GuestOSConfig{GuestOSFamily: family}
+		if errs := c.Prepare(); len(errs) > 0 {
+			t.Fatalf("%s should not have error: %s", family, errs)
+		}
+	}
+
+	c := &GuestOSConfig{GuestOSFamily: "linux"}
+	if errs := c.Prepare(); len(errs) == 0 {
+		t.Fatal("should have error")
+	}
+}
+
+func TestGuestOSConfigDefaultShutdownCommand(t *testing.T) {
+	cases := []struct {
+		family   string
+		comm     communicator.Config
+		expected string
+	}{
+		{"", communicator.Config{Type: "ssh"}, ""},
+		{"linux-systemd", communicator.Config{Type: "ssh", SSHUsername: "root"}, "systemctl poweroff"},
+		{"linux-systemd", communicator.Config{Type: "ssh", SSHUsername: "packer"}, "sudo -n systemctl poweroff"},
+		{"linux-sysv", communicator.Config{Type: "ssh", SSHUsername: "packer", SSHPassword: "it's"},
+			`echo 'it'\''s' | sudo -S -p '' shutdown -h now`},
+		{"bsd", communicator.Config{Type: "ssh", SSHUsername: "root"}, "shutdown -p now"},
+		{"windows", communicator.Config{Type: "winrm"}, `shutdown /s /t 10 /f /d p:4:1 /c "Packer Shutdown"`},
+	}
+	for _, tc := range cases {
+		c := &GuestOSConfig{GuestOSFamily: tc.family}
+		if command := c.DefaultShutdownCommand(&tc.comm); command != tc.expected {
+			t.Fatalf("%s: bad: %s", tc.family, command)
+		}
+	}
+}
+
+func TestGuestOSConfigRemoveKeyCommands(t *testing.T) {
+	comm := &communicator.Config{Type: "ssh", SSHUsername: "packer"}
+
+	c := &GuestOSConfig{}
+	if commands := c.RemoveKeyCommands(comm, "packer_key"); len(commands) != 0 {
+		t.Fatalf("bad: %#v", commands)
+	}
+
+	for _, family := range []string{"linux-systemd", "linux-sysv", "windows", "bsd"} {
+		c := &GuestOSConfig{GuestOSFamily: family}
+		commands := c.RemoveKeyCommands(comm, "packer_key")
+		if len(commands) != 2 {
+			t.Fatalf("%s: bad: %#v", family, commands)
+		}
+		for _, command := range commands {
+			if !strings.Contains(command, "packer_key$") {
+				t.Fatalf("%s: bad: %s", family, command)
+			}
+		}
+	}
+}
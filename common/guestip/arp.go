@@ -0,0 +1,77 @@
+package guestip
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	arpIPRe  = regexp.MustCompile(`\b(\d{1,3}\.\d{1,3}\.\d{1,3}\.\d{1,3})\b`)
+	arpMACRe = regexp.MustCompile(`\b([0-9a-fA-F]{1,2}[:-]){5}[0-9a-fA-F]{1,2}\b`)
+)
+
+// ARPLookup returns the IP address the ARP table of the host has for the
+// MAC address. The table only has the guests that talked to the host, like
+// to get their kickstart file from the HTTP server.
+func ARPLookup(mac string) (string, error) {
+	want, err := NormalizeMAC(mac)
+	if err != nil {
+		return "", err
+	}
+
+	table, err := arpTable()
+	if err != nil {
+		return "", fmt.Errorf("Error reading the ARP table: %s", err)
+	}
+	if ip, ok := parseARPTable(table)[want]; ok {
+		return ip, nil
+	}
+	return "", fmt.Errorf("no ARP entry for MAC address %s", mac)
+}
+
+// parseARPTable maps the MAC addresses of the ARP table to their IP
+// addresses. It reads /proc/net/arp, and the output of arp -a on Windows,
+// macOS, and the BSDs.
+func parseARPTable(table string) map[string]string {
+	result := make(map[string]string)
+	for _, line := range strings.Split(table, "\n") {
+		ip := arpIPRe.FindString(line)
+		mac, err := NormalizeMAC(arpMACRe.FindString(line))
+		if ip == "" || err != nil || mac == "00:00:00:00:00:00" {
+			continue
+		}
+		result[mac] = ip
+	}
+	return result
+}
+
+// NormalizeMAC formats the MAC address as lowercase pairs of hex digits
+// separated with colons. It takes the formats of the hypervisors, like
+// 00-15-5D-01-02-03, 0:15:5d:1:2:3 or 00155D010203.
+func NormalizeMAC(mac string) (string, error) {
+	var parts []string
+	if strings.ContainsAny(mac, ":-") {
+		parts = strings.FieldsFunc(mac, func(r rune) bool { return r == ':' || r == '-' })
+	} else {
+		for i := 0; i+2 <= len(mac); i += 2 {
+			parts = append(parts, mac[i:i+2])
+		}
+		if len(mac)%2 != 0 {
+			parts = nil
+		}
+	}
+	if len(parts) != 6 {
+		return "", fmt.Errorf("invalid MAC address: %q", mac)
+	}
+
+	for i, part := range parts {
+		b, err := strconv.ParseUint(part, 16, 8)
+		if err != nil {
+			return "", fmt.Errorf("invalid MAC address: %q", mac)
+		}
+		parts[i] = fmt.Sprintf("%02x", b)
+	}
+	return strings.Join(parts, ":"), nil
+}
@@ -0,0 +1,10 @@
+package guestip
+
+import (
+	"io/ioutil"
+)
+
+func arpTable() (string, error) {
+	contents, err := ioutil.ReadFile("/proc/net/arp")
+	return string(contents), err
+}
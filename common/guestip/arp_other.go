@@ -0,0 +1,12 @@
+// +build !linux
+
+package guestip
+
+import (
+	"os/exec"
+)
+
+func arpTable() (string, error) {
+	out, err := exec.Command("arp", "-a").Output()
+	return string(out), err
+}
@@ -0,0 +1,56 @@
+package guestip
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeMAC(t *testing.T) {
+	cases := map[string]string{
+		"00:15:5d:01:02:03": "00:15:5d:01:02:03",
+		"00-15-5D-01-02-03": "00:15:5d:01:02:03",
+		"0:15:5d:1:2:3":     "00:15:5d:01:02:03",
+		"00155D010203":      "00:15:5d:01:02:03",
+	}
+	for input, expected := range cases {
+		actual, err := NormalizeMAC(input)
+		if err != nil || actual != expected {
+			t.Fatalf("%s: bad: %s %s", input, actual, err)
+		}
+	}
+
+	for _, input := range []string{"", "00:15:5d", "00155D01020", "zz:15:5d:01:02:03"} {
+		if _, err := NormalizeMAC(input); err == nil {
+			t.Fatalf("%s: should error", input)
+		}
+	}
+}
+
+func TestParseARPTable(t *testing.T) {
+	proc := `IP address       HW type     Flags       HW address            Mask     Device
+192.168.1.1      0x1         0x2         00:11:22:33:44:55     *        eth0
+192.168.1.9      0x1         0x0         00:00:00:00:00:00     *        eth0
+`
+	darwin := `? (192.168.1.5) at 0:15:5d:1:2:3 on en0 ifscope [ethernet]
+? (192.168.1.255) at (incomplete) on en0 ifscope [ethernet]
+`
+	windows := `
+Interface: 192.168.1.2 --- 0x4
+  Internet Address      Physical Address      Type
+  192.168.1.7           00-15-5d-01-02-04     dynamic
+`
+
+	cases := []struct {
+		Table    string
+		Expected map[string]string
+	}{
+		{proc, map[string]string{"00:11:22:33:44:55": "192.168.1.1"}},
+		{darwin, map[string]string{"00:15:5d:01:02:03": "192.168.1.5"}},
+		{windows, map[string]string{"00:15:5d:01:02:04": "192.168.1.7"}},
+	}
+	for _, tc := range cases {
+		if actual := parseARPTable(tc.Table); !reflect.DeepEqual(actual, tc.Expected) {
+			t.Fatalf("bad: %#v", actual)
+		}
+	}
+}
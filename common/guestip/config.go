@@ -0,0 +1,155 @@
+// Package guestip finds the IP address of the guest of a local VM, with the
+// ways of finding it the template prefers.
+package guestip
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/packer/helper/multistep"
+)
+
+// The strategies of finding the IP address. Builders support the ones they
+// can, and all support Static.
+const (
+	// DHCP reads the leases of the DHCP server of the hypervisor.
+	DHCP = "dhcp"
+
+	// Tools asks the tools or agent running in the guest.
+	Tools = "tools"
+
+	// ARP reads the ARP table of the host for the MAC address of the VM.
+	ARP = "arp"
+
+	// Static is the address set in static_ip.
+	Static = "static"
+)
+
+// Config is the configuration of how the IP address of the guest is found.
+type Config struct {
+	IPDiscovery           []string `mapstructure:"ip_discovery"`
+	RawIPDiscoveryTimeout string   `mapstructure:"ip_discovery_timeout"`
+	StaticIP              string   `mapstructure:"static_ip"`
+
+	IPDiscoveryTimeout time.Duration ``
+}
+
+// Prepare checks that the strategies are among the ones the builder
+// supports.
+func (c *Config) Prepare(supported ...string) []error {
+	var errs []error
+
+	if c.StaticIP != "" && len(c.IPDiscovery) == 0 {
+		c.IPDiscovery = []string{Static}
+	}
+
+	if c.RawIPDiscoveryTimeout == "" {
+		c.RawIPDiscoveryTimeout = "1m"
+	}
+	var err error
+	c.IPDiscoveryTimeout, err = time.ParseDuration(c.RawIPDiscoveryTimeout)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("Failed parsing ip_discovery_timeout: %s", err))
+	}
+
+	supported = append(supported, Static)
+	for _, strategy := range c.IPDiscovery {
+		if !contains(supported, strategy) {
+			errs = append(errs, fmt.Errorf(
+				"Unknown ip_discovery strategy %q, this builder supports: %s",
+				strategy, strings.Join(supported, ", ")))
+		}
+	}
+
+	if c.Uses(Static) && c.StaticIP == "" {
+		errs = append(errs, errors.New("static_ip must be set to use the static ip_discovery"))
+	}
+	if c.StaticIP != "" && net.ParseIP(c.StaticIP) == nil {
+		errs = append(errs, fmt.Errorf("static_ip is not an IP address: %s", c.StaticIP))
+	}
+
+	return errs
+}
+
+// Enabled says whether the IP address is found with the strategies, instead
+// of the way of the builder.
+func (c *Config) Enabled() bool {
+	return len(c.IPDiscovery) > 0
+}
+
+// Uses says whether the strategy is one of the configured ones.
+func (c *Config) Uses(strategy string) bool {
+	return contains(c.IPDiscovery, strategy)
+}
+
+// Strategy finds the IP address of the guest, or returns an error when it
+// can't yet.
+type Strategy func(multistep.StateBag) (string, error)
+
+// CommHost returns the function the communicator finds its host with. The
+// strategies are tried in order and the first address found is used, but a
+// strategy is only tried once the ones before it had ip_discovery_timeout
+// each to find one. Without ip_discovery, it is fallback, the way of the
+// builder.
+func (c *Config) CommHost(fallback func(multistep.StateBag) (string, error), strategies map[string]Strategy) func(multistep.StateBag) (string, error) {
+	if !c.Enabled() {
+		return fallback
+	}
+
+	var start time.Time
+	return func(state multistep.StateBag) (string, error) {
+		if start.IsZero() {
+			start = time.Now()
+		}
+		elapsed := time.Since(start)
+
+		var errs []string
+		for i, name := range c.IPDiscovery {
+			if time.Duration(i)*c.IPDiscoveryTimeout > elapsed {
+				break
+			}
+
+			ip, err := c.find(name, strategies, state)
+			if err != nil {
+				log.Printf("IP discovery with %s failed: %s", name, err)
+				errs = append(errs, fmt.Sprintf("%s: %s", name, err))
+				continue
+			}
+			log.Printf("IP discovery with %s found: %s", name, ip)
+			return ip, nil
+		}
+		return "", fmt.Errorf("IP discovery failed: %s", strings.Join(errs, "; "))
+	}
+}
+
+func (c *Config) find(name string, strategies map[string]Strategy, state multistep.StateBag) (string, error) {
+	if name == Static {
+		return c.StaticIP, nil
+	}
+
+	strategy, ok := strategies[name]
+	if !ok {
+		return "", errors.New("not supported")
+	}
+	ip, err := strategy(state)
+	if err != nil {
+		return "", err
+	}
+	if ip == "" {
+		return "", errors.New("no IP address yet")
+	}
+	return ip, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package guestip
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer/helper/multistep"
+)
+
+func TestConfigPrepare(t *testing.T) {
+	cases := []struct {
+		Config Config
+		Err    bool
+	}{
+		{Config{}, false},
+		{Config{IPDiscovery: []string{"tools", "arp"}}, false},
+		{Config{IPDiscovery: []string{"dhcp"}}, true},
+		{Config{IPDiscovery: []string{"static"}}, true},
+		{Config{IPDiscovery: []string{"static"}, StaticIP: "10.0.0.5"}, false},
+		{Config{StaticIP: "nope"}, true},
+		{Config{RawIPDiscoveryTimeout: "bad"}, true},
+	}
+	for _, tc := range cases {
+		c := tc.Config
+		errs := c.Prepare(Tools, ARP)
+		if (len(errs) > 0) != tc.Err {
+			t.Fatalf("%#v: bad: %s", tc.Config, errs)
+		}
+	}
+
+	c := Config{StaticIP: "10.0.0.5"}
+	if errs := c.Prepare(); len(errs) > 0 {
+		t.Fatalf("err: %s", errs)
+	}
+	if !c.Uses(Static) || c.IPDiscoveryTimeout != time.Minute {
+		t.Fatalf("bad: %#v", c)
+	}
+}
+
+func TestConfigCommHost(t *testing.T) {
+	fallback := func(multistep.StateBag) (string, error) { return "127.0.0.1", nil }
+	failing := func(multistep.StateBag) (string, error) { return "", errors.New("not yet") }
+	found := func(multistep.StateBag) (string, error) { return "10.0.0.2", nil }
+	state := new(multistep.BasicStateBag)
+
+	c := Config{}
+	if ip, _ := c.CommHost(fallback, nil)(state); ip != "127.0.0.1" {
+		t.Fatalf("bad: %s", ip)
+	}
+
+	// The strategies are tried in order
+	c = Config{IPDiscovery: []string{Tools, ARP}}
+	host := c.CommHost(fallback, map[string]Strategy{Tools: failing, ARP: found})
+	if ip, err := host(state); err != nil || ip != "10.0.0.2" {
+		t.Fatalf("bad: %s %s", ip, err)
+	}
+
+	// The later ones wait for the timeout of the ones before
+	c = Config{IPDiscovery: []string{Tools, ARP}, IPDiscoveryTimeout: time.Hour}
+	host = c.CommHost(fallback, map[string]Strategy{Tools: failing, ARP: found})
+	if _, err := host(state); err == nil {
+		t.Fatal("should error")
+	}
+
+	c = Config{IPDiscovery: []string{Tools, Static}, StaticIP: "10.0.0.5"}
+	host = c.CommHost(fallback, map[string]Strategy{Tools: failing})
+	if ip, err := host(state); err != nil || ip != "10.0.0.5" {
+		t.Fatalf("bad: %s %s", ip, err)
+	}
+}
@@ -0,0 +1,30 @@
+package common
+
+import "fmt"
+
+// What builders do when the artifact they would create already exists.
+const (
+	IfExistsFail    = "fail"
+	IfExistsSkip    = "skip"
+	IfExistsReplace = "replace"
+)
+
+// IfExistsConfig contains the configuration for what to do when the artifact
+// of the build already exists, like an image of the same name, so running a
+// template again doesn't fail or build the same image twice. Builders that
+// check for their artifact fail by default, and replace it with -force.
+type IfExistsConfig struct {
+	IfExists string `mapstructure:"if_exists"`
+}
+
+func (c *IfExistsConfig) Prepare() []error {
+	switch c.IfExists {
+	case "":
+		c.IfExists = IfExistsFail
+	case IfExistsFail, IfExistsSkip, IfExistsReplace:
+	default:
+		return []error{fmt.Errorf("if_exists must be one of %s, %s or %s",
+			IfExistsFail, IfExistsSkip, IfExistsReplace)}
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package common
+
+import "testing"
+
+func TestIfExistsConfigPrepare(t *testing.T) {
+	c := &IfExistsConfig{}
+	if errs := c.Prepare(); len(errs) > 0 {
+		t.Fatalf("should not have error: %s", errs)
+	}
+	if c.IfExists != IfExistsFail {
+		t.Fatalf("bad: %s", c.IfExists)
+	}
+
+	for _, v := range []string{"fail", "skip", "replace"} {
+		c := &IfExistsConfig{IfExists: v}
+		if errs := c.Prepare(); len(errs) > 0 {
+			t.Fatalf("%s should not have error: %s", v, errs)
+		}
+		if c.IfExists != v {
+			t.Fatalf("bad: %s", c.IfExists)
+		}
+	}
+
+	c = &IfExistsConfig{IfExists: "keep"}
+	if errs := c.Prepare(); len(errs) == 0 {
+		t.Fatal("should have error")
+	}
+}
@@ -20,6 +20,7 @@ type ISOConfig struct {
 	ISOChecksum     string   `mapstructure:"iso_checksum"`
 	ISOChecksumURL  string   `mapstructure:"iso_checksum_url"`
 	ISOChecksumType string   `mapstructure:"iso_checksum_type"`
+	ISOSize         int64    `mapstructure:"iso_size"`
 	ISOUrls         []string `mapstructure:"iso_urls"`
 	TargetPath      string   `mapstructure:"iso_target_path"`
 	TargetExtension string   `mapstructure:"iso_target_extension"`
@@ -110,6 +111,10 @@ func (c *ISOConfig) Prepare(ctx *interpolate.Context) (warnings []string, errs [
 
 	c.ISOChecksum = strings.ToLower(c.ISOChecksum)
 
+	if c.ISOSize < 0 {
+		errs = append(errs, errors.New("iso_size can't be negative"))
+	}
+
 	for i, url := range c.ISOUrls {
 		url, err := ValidatedURL(url)
 		if err != nil {
@@ -126,10 +131,16 @@ func (c *ISOConfig) Prepare(ctx *interpolate.Context) (warnings []string, errs [
 	c.TargetExtension = strings.ToLower(c.TargetExtension)
 
 	// Warnings
-	if c.ISOChecksumType == "none" {
+	if c.ISOChecksumType == "none" && c.ISOSize == 0 {
+		warnings = append(warnings,
+			"A checksum type of 'none' was specified. Since ISO files are so big,\n"+
+				"a checksum is highly recommended. The ISO will only be checked to have\n"+
+				"the size the server announces, set iso_size to check it has a given size.")
+	} else if c.ISOChecksumType == "none" {
 		warnings = append(warnings,
 			"A checksum type of 'none' was specified. Since ISO files are so big,\n"+
-				"a checksum is highly recommended.")
+				"a checksum is highly recommended. The ISO will only be checked to have\n"+
+				"the size of iso_size.")
 	}
 
 	return warnings, errs
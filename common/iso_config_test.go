@@ -10,6 +10,7 @@ import (
 	"os"
 	"reflect"
 	"runtime"
+	"strings"
 	"testing"
 )
 
@@ -296,6 +297,25 @@ func TestISOConfigPrepare_ISOChecksumType(t *testing.T) {
 	}
 }
 
+func TestISOConfigPrepare_ISOSize(t *testing.T) {
+	i := testISOConfig()
+	i.ISOChecksumType = "none"
+	i.ISOSize = 1024
+	warns, err := i.Prepare(nil)
+	if len(warns) != 1 || !strings.Contains(warns[0], "iso_size") {
+		t.Fatalf("bad: %#v", warns)
+	}
+	if err != nil {
+		t.Fatalf("should not have error: %s", err)
+	}
+
+	i = testISOConfig()
+	i.ISOSize = -1
+	if _, err := i.Prepare(nil); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
 func TestISOConfigPrepare_ISOUrl(t *testing.T) {
 	i := testISOConfig()
 
@@ -1,8 +1,14 @@
 package common
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/hashicorp/packer/helper/multistep"
@@ -12,6 +18,12 @@ import (
 // MultistepDebugFn will return a proper multistep.DebugPauseFn to
 // use for debugging if you're using multistep in your builder.
 func MultistepDebugFn(ui packer.Ui) multistep.DebugPauseFn {
+	return debugPauseFn(ui, nil)
+}
+
+// debugPauseFn pauses until enter is pressed. The state can be dumped at
+// the pauses, and with skip, the next step can be skipped after a run.
+func debugPauseFn(ui packer.Ui, skip *debugSkip) multistep.DebugPauseFn {
 	return func(loc multistep.DebugLocation, name string, state multistep.StateBag) {
 		var locationString string
 		switch loc {
@@ -23,18 +35,45 @@ func MultistepDebugFn(ui packer.Ui) multistep.DebugPauseFn {
 			locationString = "at"
 		}
 
+		next := ""
+		if skip != nil && loc == multistep.DebugLocationAfterRun {
+			next = skip.next()
+		}
 		message := fmt.Sprintf(
-			"Pausing %s step '%s'. Press enter to continue.",
+			"Pausing %s step '%s'. Press enter to continue, or d to dump the state.",
 			locationString, name)
+		if next != "" {
+			message = fmt.Sprintf(
+				"Pausing %s step '%s'. Press enter to continue, d to dump the state, or s to skip step '%s'.",
+				locationString, name, next)
+		}
 
-		result := make(chan string, 1)
+		result := make(chan struct{}, 1)
 		go func() {
-			line, err := ui.Ask(message)
-			if err != nil {
-				log.Printf("Error asking for input: %s", err)
-			}
+			defer func() { result <- struct{}{} }()
+			for {
+				line, err := ui.Ask(message)
+				if err != nil {
+					log.Printf("Error asking for input: %s", err)
+					return
+				}
 
-			result <- line
+				switch strings.ToLower(strings.TrimSpace(line)) {
+				case "":
+					return
+				case "d":
+					dumpState(ui, state)
+				case "s":
+					if next == "" {
+						ui.Say(fmt.Sprintf("Incorrect input: %#v", line))
+						continue
+					}
+					skip.skipNext()
+					return
+				default:
+					ui.Say(fmt.Sprintf("Incorrect input: %#v", line))
+				}
+			}
 		}()
 
 		for {
@@ -49,3 +88,234 @@ func MultistepDebugFn(ui packer.Ui) multistep.DebugPauseFn {
 		}
 	}
 }
+
+// sensitiveStateKeys are the parts of the keys of the state that hold
+// secrets, which dumpState doesn't show.
+var sensitiveStateKeys = []string{"password", "secret", "token", "private_key", "privatekey", "credential"}
+
+// dumpState shows the state of the steps without the secrets. Only values of
+// simple types are shown, the others by their type.
+func dumpState(ui packer.Ui, state multistep.StateBag) {
+	bag, ok := state.(interface {
+		Keys() []string
+	})
+	if !ok {
+		ui.Say("The state can't be listed")
+		return
+	}
+	keys := bag.Keys()
+	sort.Strings(keys)
+
+	lines := []string{"State:"}
+	for _, k := range keys {
+		value, ok := state.GetOk(k)
+		if !ok {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("  %s: %s", k, debugValue(k, value)))
+	}
+	ui.Say(strings.Join(lines, "\n"))
+}
+
+func debugValue(key string, value interface{}) string {
+	lower := strings.ToLower(key)
+	for _, s := range sensitiveStateKeys {
+		if strings.Contains(lower, s) {
+			return "<sensitive>"
+		}
+	}
+
+	var s string
+	switch v := value.(type) {
+	case string:
+		s = fmt.Sprintf("%q", v)
+	case []string:
+		s = fmt.Sprintf("%q", v)
+	case bool, int, int32, int64, uint, uint32, uint64, float32, float64:
+		s = fmt.Sprint(v)
+	case error:
+		s = v.Error()
+	default:
+		return fmt.Sprintf("<%T>", v)
+	}
+
+	s = packer.LogSecretFilter.Filter(s)
+	if len(s) > 200 {
+		s = s[:200] + "..."
+	}
+	return s
+}
+
+// debugSkip is the steps to skip: by name, from -debug-skip, and the ones
+// chosen at the pauses.
+type debugSkip struct {
+	l       sync.Mutex
+	steps   []string
+	names   map[string]bool
+	indexes map[int]bool
+	last    int
+}
+
+func newDebugSkip(steps []multistep.Step, names []string) *debugSkip {
+	s := &debugSkip{
+		names:   make(map[string]bool),
+		indexes: make(map[int]bool),
+		last:    -1,
+	}
+	for _, step := range steps {
+		s.steps = append(s.steps, typeName(step))
+	}
+	for _, name := range names {
+		s.names[name] = true
+	}
+	return s
+}
+
+// unknown are the names to skip no step has.
+func (s *debugSkip) unknown() []string {
+	var result []string
+	for name := range s.names {
+		found := false
+		for _, step := range s.steps {
+			if step == name {
+				found = true
+			}
+		}
+		if !found {
+			result = append(result, name)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// ran records that the step at index is starting, and says whether to
+// skip it.
+func (s *debugSkip) ran(index int) bool {
+	s.l.Lock()
+	defer s.l.Unlock()
+	s.last = index
+	return s.indexes[index] || s.names[s.steps[index]]
+}
+
+// next is the name of the step after the last one that ran, if any.
+func (s *debugSkip) next() string {
+	s.l.Lock()
+	defer s.l.Unlock()
+	if s.last+1 < len(s.steps) {
+		return s.steps[s.last+1]
+	}
+	return ""
+}
+
+func (s *debugSkip) skipNext() {
+	s.l.Lock()
+	defer s.l.Unlock()
+	s.indexes[s.last+1] = true
+}
+
+// debugStep skips its step if asked to, and writes when it starts and ends
+// to the trace.
+type debugStep struct {
+	step  multistep.Step
+	index int
+	skip  *debugSkip
+	trace *stepTrace
+	ui    packer.Ui
+
+	skipped bool
+}
+
+func (s *debugStep) InnerStepName() string {
+	return typeName(s.step)
+}
+
+func (s *debugStep) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	name := typeName(s.step)
+	if s.skip.ran(s.index) {
+		s.skipped = true
+		s.ui.Say(fmt.Sprintf("Skipping step '%s'", name))
+		s.trace.write(traceEntry{Step: name, Index: s.index, Event: "skip"})
+		return multistep.ActionContinue
+	}
+
+	s.trace.write(traceEntry{Step: name, Index: s.index, Event: "run"})
+	start := time.Now()
+	action := s.step.Run(ctx, state)
+
+	entry := traceEntry{
+		Step:     name,
+		Index:    s.index,
+		Event:    "run-end",
+		Action:   "continue",
+		Duration: time.Since(start).Seconds(),
+	}
+	if action == multistep.ActionHalt {
+		entry.Action = "halt"
+		if err, ok := state.GetOk("error"); ok {
+			entry.Error = packer.LogSecretFilter.Filter(fmt.Sprint(err))
+		}
+	}
+	s.trace.write(entry)
+	return action
+}
+
+func (s *debugStep) Cleanup(state multistep.StateBag) {
+	if s.skipped {
+		return
+	}
+
+	name := typeName(s.step)
+	s.trace.write(traceEntry{Step: name, Index: s.index, Event: "cleanup"})
+	start := time.Now()
+	s.step.Cleanup(state)
+	s.trace.write(traceEntry{
+		Step:     name,
+		Index:    s.index,
+		Event:    "cleanup-end",
+		Duration: time.Since(start).Seconds(),
+	})
+}
+
+// traceEntry is a line of the trace.
+type traceEntry struct {
+	Time     string  `json:"time"`
+	Build    string  `json:"build"`
+	Step     string  `json:"step"`
+	Index    int     `json:"index"`
+	Event    string  `json:"event"`
+	Action   string  `json:"action,omitempty"`
+	Duration float64 `json:"duration,omitempty"`
+	Error    string  `json:"error,omitempty"`
+}
+
+// stepTrace appends the trace of the steps of a build to a file, as JSON
+// lines. The builds running in parallel share the file, each line is
+// written at once.
+type stepTrace struct {
+	path  string
+	build string
+}
+
+func (t *stepTrace) write(entry traceEntry) {
+	if t == nil {
+		return
+	}
+	entry.Time = time.Now().UTC().Format(time.RFC3339Nano)
+	entry.Build = t.build
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("Error writing the step trace: %s", err)
+		return
+	}
+	f, err := os.OpenFile(t.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Printf("Error writing the step trace: %s", err)
+		return
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		log.Printf("Error writing the step trace: %s", err)
+	}
+}
@@ -0,0 +1,167 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+type debugTestStep struct {
+	ran     *[]string
+	cleaned *[]string
+}
+
+func (s debugTestStep) Run(context.Context, multistep.StateBag) multistep.StepAction {
+	*s.ran = append(*s.ran, "debugTestStep")
+	return multistep.ActionContinue
+}
+
+func (s debugTestStep) Cleanup(multistep.StateBag) {
+	*s.cleaned = append(*s.cleaned, "debugTestStep")
+}
+
+type debugOtherStep struct {
+	ran *[]string
+}
+
+func (s debugOtherStep) Run(context.Context, multistep.StateBag) multistep.StepAction {
+	*s.ran = append(*s.ran, "debugOtherStep")
+	return multistep.ActionContinue
+}
+
+func (s debugOtherStep) Cleanup(multistep.StateBag) {}
+
+func TestNewRunner_debugSkip(t *testing.T) {
+	var ran, cleaned []string
+	steps := []multistep.Step{
+		debugTestStep{&ran, &cleaned},
+		debugOtherStep{&ran},
+	}
+	out := new(bytes.Buffer)
+	ui := &packer.BasicUi{Reader: new(bytes.Buffer), Writer: out, ErrorWriter: out}
+	config := PackerConfig{PackerDebugSkip: []string{"debugTestStep", "StepNope"}}
+
+	NewRunner(steps, config, ui).Run(new(multistep.BasicStateBag))
+
+	if len(ran) != 1 || ran[0] != "debugOtherStep" {
+		t.Fatalf("bad: %#v", ran)
+	}
+	if len(cleaned) != 0 {
+		t.Fatalf("skipped steps should not be cleaned up: %#v", cleaned)
+	}
+	if !strings.Contains(out.String(), "Skipping step 'debugTestStep'") {
+		t.Fatalf("bad: %s", out.String())
+	}
+	if !strings.Contains(out.String(), `no step "StepNope" to skip`) {
+		t.Fatalf("bad: %s", out.String())
+	}
+}
+
+func TestNewRunner_debugSkipNext(t *testing.T) {
+	var ran, cleaned []string
+	steps := []multistep.Step{
+		debugOtherStep{&ran},
+		debugTestStep{&ran, &cleaned},
+	}
+	ui := &packer.BasicUi{
+		Reader: strings.NewReader("x\ns\n\n"),
+		Writer: new(bytes.Buffer),
+	}
+	config := PackerConfig{PackerDebug: true}
+
+	NewRunner(steps, config, ui).Run(new(multistep.BasicStateBag))
+
+	if len(ran) != 1 || ran[0] != "debugOtherStep" {
+		t.Fatalf("bad: %#v", ran)
+	}
+}
+
+func TestNewRunner_debugTrace(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+	path := filepath.Join(td, "trace.json")
+
+	var ran, cleaned []string
+	steps := []multistep.Step{
+		debugTestStep{&ran, &cleaned},
+		debugOtherStep{&ran},
+	}
+	ui := &packer.BasicUi{Reader: new(bytes.Buffer), Writer: new(bytes.Buffer)}
+	config := PackerConfig{
+		PackerBuildName:  "test",
+		PackerDebugSkip:  []string{"debugOtherStep"},
+		PackerDebugTrace: path,
+	}
+
+	NewRunner(steps, config, ui).Run(new(multistep.BasicStateBag))
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	var events []string
+	for _, line := range strings.Split(strings.TrimSpace(string(contents)), "\n") {
+		var entry traceEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if entry.Build != "test" || entry.Time == "" {
+			t.Fatalf("bad: %s", line)
+		}
+		events = append(events, entry.Step+" "+entry.Event+" "+entry.Action)
+	}
+
+	expected := []string{
+		"debugTestStep run ",
+		"debugTestStep run-end continue",
+		"debugOtherStep skip ",
+		"debugTestStep cleanup ",
+		"debugTestStep cleanup-end ",
+	}
+	if strings.Join(events, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("bad: %#v", events)
+	}
+}
+
+func TestDumpState(t *testing.T) {
+	out := new(bytes.Buffer)
+	ui := &packer.BasicUi{Reader: new(bytes.Buffer), Writer: out}
+
+	packer.LogSecretFilter.Set("hunter2")
+	state := new(multistep.BasicStateBag)
+	state.Put("instance_id", "i-1234")
+	state.Put("ssh_password", "swordfish")
+	state.Put("user_data", "password=hunter2")
+	state.Put("count", 3)
+	state.Put("ui", ui)
+	state.Put("long", strings.Repeat("a", 300))
+
+	dumpState(ui, state)
+
+	for _, expected := range []string{
+		`instance_id: "i-1234"`,
+		`ssh_password: <sensitive>`,
+		`user_data: "password=<sensitive>"`,
+		`count: 3`,
+		`ui: <*packer.BasicUi>`,
+		`long: "` + strings.Repeat("a", 199) + `...`,
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Fatalf("missing %q: %s", expected, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "swordfish") || strings.Contains(out.String(), "hunter2") {
+		t.Fatalf("secret in the dump: %s", out.String())
+	}
+}
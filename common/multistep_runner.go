@@ -6,14 +6,29 @@ import (
 	"log"
 	"os"
 	"reflect"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/hashicorp/packer/helper/multistep"
 	"github.com/hashicorp/packer/packer"
+	sdkpacker "github.com/hashicorp/packer/sdk/packer"
 )
 
 func newRunner(steps []multistep.Step, config PackerConfig, ui packer.Ui) (multistep.Runner, multistep.DebugPauseFn) {
+	for _, step := range steps {
+		if d, ok := step.(DownloadStep); ok {
+			d.SetDownloadMode(config.PackerForceDownload, config.PackerOffline)
+		}
+	}
+
+	for i, step := range steps {
+		steps[i] = timedStep{step, ui}
+	}
+	for _, warning := range addStepHooks(steps, config, ui) {
+		packer.UiLog(ui, packer.UiLevelWarn, fmt.Sprintf("Warning: %s", warning))
+	}
+
 	switch config.PackerOnError {
 	case "", "cleanup":
 	case "abort":
@@ -26,8 +41,24 @@ func newRunner(steps []multistep.Step, config PackerConfig, ui packer.Ui) (multi
 		}
 	}
 
+	var skip *debugSkip
+	if config.PackerDebug || len(config.PackerDebugSkip) > 0 || config.PackerDebugTrace != "" {
+		skip = newDebugSkip(steps, config.PackerDebugSkip)
+		for _, name := range skip.unknown() {
+			packer.UiLog(ui, packer.UiLevelWarn, fmt.Sprintf("Warning: there is no step %q to skip", name))
+		}
+
+		var trace *stepTrace
+		if config.PackerDebugTrace != "" {
+			trace = &stepTrace{path: config.PackerDebugTrace, build: config.PackerBuildName}
+		}
+		for i, step := range steps {
+			steps[i] = &debugStep{step: step, index: i, skip: skip, trace: trace, ui: ui}
+		}
+	}
+
 	if config.PackerDebug {
-		pauseFn := MultistepDebugFn(ui)
+		pauseFn := debugPauseFn(ui, skip)
 		return &multistep.DebugRunner{Steps: steps, PauseFn: pauseFn}, pauseFn
 	} else {
 		return &multistep.BasicRunner{Steps: steps}, nil
@@ -54,9 +85,40 @@ func NewRunnerWithPauseFn(steps []multistep.Step, config PackerConfig, ui packer
 }
 
 func typeName(i interface{}) string {
+	if wrapped, ok := i.(multistep.StepWrapper); ok {
+		return wrapped.InnerStepName()
+	}
 	return reflect.Indirect(reflect.ValueOf(i)).Type().Name()
 }
 
+// timedStep reports how long the step ran as the "step-duration" machine
+// readable message, which the build uses for its summary of durations, and
+// as a trace message.
+type timedStep struct {
+	step multistep.Step
+	ui   packer.Ui
+}
+
+func (s timedStep) InnerStepName() string {
+	return typeName(s.step)
+}
+
+func (s timedStep) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	name := typeName(s.step)
+	packer.UiLog(s.ui, packer.UiLevelTrace, fmt.Sprintf("Running step %s", name))
+	start := time.Now()
+	action := s.step.Run(ctx, state)
+	duration := time.Since(start)
+	packer.UiLog(s.ui, packer.UiLevelTrace, fmt.Sprintf("Step %s finished in %s", name, duration.Round(time.Millisecond)))
+	s.ui.Machine("step-duration", name,
+		strconv.FormatFloat(duration.Seconds(), 'f', 3, 64))
+	return action
+}
+
+func (s timedStep) Cleanup(state multistep.StateBag) {
+	s.step.Cleanup(state)
+}
+
 type abortStep struct {
 	step multistep.Step
 	ui   packer.Ui
@@ -108,14 +170,45 @@ func (s askStep) Run(ctx context.Context, state multistep.StateBag) (action mult
 			s.ui.Error(fmt.Sprintf("%s", err))
 		}
 
-		switch ask(s.ui, typeName(s.step), state) {
-		case askCleanup:
-			return
-		case askAbort:
-			os.Exit(1)
-		case askRetry:
-			continue
+		var options askOptions
+		step := s.step
+		if hooked, ok := step.(*hookedStep); ok {
+			step = hooked.step
+		}
+		if timed, ok := step.(timedStep); ok {
+			step = timed.step
 		}
+		_, options.provision = step.(*StepProvision)
+		switch comm := state.Get("communicator").(type) {
+		case packer.Communicator:
+			options.comm = comm
+		case sdkpacker.Communicator:
+			// The builders of the SDK put its communicator in the state
+			options.comm = packer.SDKCommunicator(comm)
+		}
+
+	prompt:
+		for {
+			answer := ask(s.ui, typeName(s.step), state, options)
+			switch answer.response {
+			case askCleanup:
+				return
+			case askAbort:
+				os.Exit(1)
+			case askRetry:
+				break prompt
+			case askRetryFrom:
+				state.Put(stateProvisionStart, answer.provisioner-1)
+				break prompt
+			case askShell:
+				remoteShell(s.ui, options.comm)
+			}
+		}
+
+		// The step gets a clean slate, so a successful retry doesn't
+		// fail the build anyway.
+		state.Remove("error")
+		s.ui.Machine("retry", "step", typeName(s.step))
 	}
 }
 
@@ -129,44 +222,113 @@ const (
 	askCleanup askResponse = iota
 	askAbort
 	askRetry
+	askRetryFrom
+	askShell
 )
 
-func ask(ui packer.Ui, name string, state multistep.StateBag) askResponse {
+// stateProvisionStart is the key StepProvision reads the index of the
+// provisioner to resume from.
+const stateProvisionStart = "provision_start"
+
+// askOptions are the choices that are only offered for some steps.
+type askOptions struct {
+	// provision offers to retry from a provisioner.
+	provision bool
+
+	// comm, when set, offers to run commands on the machine.
+	comm packer.Communicator
+}
+
+type askAnswer struct {
+	response askResponse
+
+	// provisioner is the provisioner to retry from, counting from 1.
+	provisioner int
+}
+
+func ask(ui packer.Ui, name string, state multistep.StateBag, options askOptions) askAnswer {
 	ui.Say(fmt.Sprintf("Step %q failed", name))
 
-	result := make(chan askResponse)
+	result := make(chan askAnswer)
 	go func() {
-		result <- askPrompt(ui)
+		result <- askPrompt(ui, options)
 	}()
 
 	for {
 		select {
-		case response := <-result:
-			return response
+		case answer := <-result:
+			return answer
 		case <-time.After(100 * time.Millisecond):
 			if _, ok := state.GetOk(multistep.StateCancelled); ok {
-				return askCleanup
+				return askAnswer{response: askCleanup}
 			}
 		}
 	}
 }
 
-func askPrompt(ui packer.Ui) askResponse {
+func askPrompt(ui packer.Ui, options askOptions) askAnswer {
+	choices := []string{"[c] Clean up and exit", "[a] abort without cleanup", "[r] retry step"}
+	if options.provision {
+		choices = append(choices, "[p N] retry from provisioner N")
+	}
+	if options.comm != nil {
+		choices = append(choices, "[s] run commands on the machine")
+	}
+	question := strings.Join(choices[:len(choices)-1], ", ") + ", or " + choices[len(choices)-1] + "?"
+
 	for {
-		line, err := ui.Ask("[c] Clean up and exit, [a] abort without cleanup, or [r] retry step (build may fail even if retry succeeds)?")
+		line, err := ui.Ask(question)
 		if err != nil {
 			log.Printf("Error asking for input: %s", err)
 		}
 
-		input := strings.ToLower(line) + "c"
+		input := strings.ToLower(strings.TrimSpace(line)) + "c"
 		switch input[0] {
 		case 'c':
-			return askCleanup
+			return askAnswer{response: askCleanup}
 		case 'a':
-			return askAbort
+			return askAnswer{response: askAbort}
 		case 'r':
-			return askRetry
+			return askAnswer{response: askRetry}
+		case 'p':
+			if !options.provision {
+				break
+			}
+			n, err := strconv.Atoi(strings.TrimSpace(strings.TrimSuffix(input[1:], "c")))
+			if err != nil || n < 1 {
+				ui.Say("Give the number of the provisioner to retry from, starting at 1, such as: p 2")
+				continue
+			}
+			return askAnswer{response: askRetryFrom, provisioner: n}
+		case 's':
+			if options.comm == nil {
+				break
+			}
+			return askAnswer{response: askShell}
 		}
 		ui.Say(fmt.Sprintf("Incorrect input: %#v", line))
 	}
 }
+
+// remoteShell runs the commands the user enters on the machine, one at a
+// time, until an empty line or "exit". There is no terminal, so
+// interactive programs won't work.
+func remoteShell(ui packer.Ui, comm packer.Communicator) {
+	ui.Say("Running commands on the machine. Enter an empty line or \"exit\" to go back.")
+	for {
+		line, err := ui.Ask(">")
+		line = strings.TrimSpace(line)
+		if err != nil || line == "" || line == "exit" {
+			return
+		}
+
+		cmd := &packer.RemoteCmd{Command: line}
+		if err := cmd.StartWithUi(comm, ui); err != nil {
+			ui.Error(fmt.Sprintf("Error running command: %s", err))
+			continue
+		}
+		if cmd.ExitStatus != 0 {
+			ui.Say(fmt.Sprintf("Exit status: %d", cmd.ExitStatus))
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package common
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+func TestAskPrompt(t *testing.T) {
+	cases := []struct {
+		Input    string
+		Options  askOptions
+		Expected askAnswer
+	}{
+		{"c\n", askOptions{}, askAnswer{response: askCleanup}},
+		{"\n", askOptions{}, askAnswer{response: askCleanup}},
+		{"a\n", askOptions{}, askAnswer{response: askAbort}},
+		{"R\n", askOptions{}, askAnswer{response: askRetry}},
+		{"p 2\n", askOptions{provision: true}, askAnswer{response: askRetryFrom, provisioner: 2}},
+		{"p3\n", askOptions{provision: true}, askAnswer{response: askRetryFrom, provisioner: 3}},
+		{"p\np 0\np 1\n", askOptions{provision: true}, askAnswer{response: askRetryFrom, provisioner: 1}},
+		{"p 2\nr\n", askOptions{}, askAnswer{response: askRetry}},
+		{"s\nr\n", askOptions{}, askAnswer{response: askRetry}},
+		{"s\n", askOptions{comm: new(packer.MockCommunicator)}, askAnswer{response: askShell}},
+	}
+
+	for _, tc := range cases {
+		ui := &packer.BasicUi{
+			Reader: strings.NewReader(tc.Input),
+			Writer: new(bytes.Buffer),
+		}
+		actual := askPrompt(ui, tc.Options)
+		if actual != tc.Expected {
+			t.Fatalf("%q: expected %#v, got %#v", tc.Input, tc.Expected, actual)
+		}
+	}
+}
+
+func TestAskPrompt_choices(t *testing.T) {
+	out := new(bytes.Buffer)
+	ui := &packer.BasicUi{
+		Reader: strings.NewReader("c\n"),
+		Writer: out,
+	}
+	askPrompt(ui, askOptions{})
+	if strings.Contains(out.String(), "provisioner") || strings.Contains(out.String(), "[s]") {
+		t.Fatalf("unexpected choices: %s", out.String())
+	}
+
+	out.Reset()
+	ui = &packer.BasicUi{
+		Reader: strings.NewReader("c\n"),
+		Writer: out,
+	}
+	askPrompt(ui, askOptions{provision: true, comm: new(packer.MockCommunicator)})
+	for _, choice := range []string{"[p N] retry from provisioner N", "[s] run commands on the machine"} {
+		if !strings.Contains(out.String(), choice) {
+			t.Fatalf("missing %q: %s", choice, out.String())
+		}
+	}
+}
+
+func TestRemoteShell(t *testing.T) {
+	comm := new(packer.MockCommunicator)
+	ui := &packer.BasicUi{
+		Reader: strings.NewReader("uptime\nexit\nnot run\n"),
+		Writer: new(bytes.Buffer),
+	}
+	remoteShell(ui, comm)
+
+	if !comm.StartCalled {
+		t.Fatal("should run the command")
+	}
+	if comm.StartCmd.Command != "uptime" {
+		t.Fatalf("bad: %s", comm.StartCmd.Command)
+	}
+}
+
+func TestNewRunner_downloadMode(t *testing.T) {
+	step := &StepDownload{}
+	config := PackerConfig{PackerOffline: true}
+	NewRunner([]multistep.Step{step}, config, new(packer.NoopUi))
+	if step.Force || !step.Offline {
+		t.Fatalf("bad: %#v", step)
+	}
+}
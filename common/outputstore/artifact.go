@@ -0,0 +1,149 @@
+package outputstore
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+// Artifact is an artifact whose files were uploaded to a store. Its files
+// are the URLs of the uploads, and the local files too when they are kept.
+type Artifact struct {
+	packer.Artifact
+
+	store     Store
+	names     []string
+	urls      []string
+	keepLocal bool
+}
+
+// Upload uploads the files of the artifact to the store of the
+// configuration, under their paths relative to the directory they have in
+// common. The local files are destroyed once they are all uploaded, unless
+// keep_local is set. The artifact is returned as is if no store is set.
+func Upload(ui packer.Ui, c *Config, artifact packer.Artifact) (packer.Artifact, error) {
+	if c.Empty() || artifact == nil {
+		return artifact, nil
+	}
+
+	ctx := context.TODO()
+	store, err := c.Store(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Error opening the output store: %s", err)
+	}
+
+	files := artifact.Files()
+	names := relativeNames(files)
+	result := &Artifact{
+		Artifact:  artifact,
+		store:     store,
+		keepLocal: c.KeepLocal,
+	}
+	for i, path := range files {
+		ui.Say(fmt.Sprintf("Uploading %s to %s...", path, c.URL))
+		url, err := uploadFile(ctx, store, names[i], path)
+		if err != nil {
+			return nil, fmt.Errorf("Error uploading %s: %s", path, err)
+		}
+		result.names = append(result.names, names[i])
+		result.urls = append(result.urls, url)
+	}
+
+	if !c.KeepLocal {
+		ui.Message("Removing the local files...")
+		if err := artifact.Destroy(); err != nil {
+			return nil, fmt.Errorf("Error removing the uploaded files: %s", err)
+		}
+	}
+	return result, nil
+}
+
+// NewArtifact returns the artifact of files already uploaded to the store,
+// like the ones streamed with a Writer.
+func NewArtifact(artifact packer.Artifact, store Store, names, urls []string, keepLocal bool) *Artifact {
+	return &Artifact{
+		Artifact:  artifact,
+		store:     store,
+		names:     names,
+		urls:      urls,
+		keepLocal: keepLocal,
+	}
+}
+
+func uploadFile(ctx context.Context, store Store, name, path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+	return store.Put(ctx, name, f, fi.Size())
+}
+
+// relativeNames are the paths of the files relative to the directory they
+// have in common, with forward slashes.
+func relativeNames(files []string) []string {
+	if len(files) == 0 {
+		return nil
+	}
+
+	dir := filepath.Dir(files[0])
+	for _, path := range files[1:] {
+		for !strings.HasPrefix(path, dir+string(filepath.Separator)) && dir != filepath.Dir(dir) {
+			dir = filepath.Dir(dir)
+		}
+	}
+
+	names := make([]string, len(files))
+	for i, path := range files {
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			name = filepath.Base(path)
+		}
+		names[i] = filepath.ToSlash(name)
+	}
+	return names
+}
+
+func (a *Artifact) Files() []string {
+	if !a.keepLocal {
+		return a.urls
+	}
+	return append(a.Artifact.Files(), a.urls...)
+}
+
+func (a *Artifact) String() string {
+	s := fmt.Sprintf("uploaded to: %s", strings.Join(a.urls, ", "))
+	if a.keepLocal {
+		s = a.Artifact.String() + ", " + s
+	}
+	return s
+}
+
+func (a *Artifact) State(name string) interface{} {
+	if name == "output_urls" {
+		return a.urls
+	}
+	return a.Artifact.State(name)
+}
+
+// Destroy removes the uploaded files, and the local ones if they were kept.
+func (a *Artifact) Destroy() error {
+	for _, name := range a.names {
+		if err := a.store.Delete(context.TODO(), name); err != nil {
+			return err
+		}
+	}
+	if a.keepLocal {
+		return a.Artifact.Destroy()
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package outputstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+// memoryStore is a Store that keeps the files in memory.
+type memoryStore struct {
+	files map[string]string
+}
+
+func (s *memoryStore) Put(ctx context.Context, name string, r io.Reader, size int64) (string, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	if size >= 0 && int64(len(data)) != size {
+		return "", errors.New("bad size")
+	}
+	s.files[name] = string(data)
+	return "mem://" + name, nil
+}
+
+func (s *memoryStore) Delete(ctx context.Context, name string) error {
+	delete(s.files, name)
+	return nil
+}
+
+func TestRelativeNames(t *testing.T) {
+	files := []string{
+		filepath.Join("out", "disk.vmdk"),
+		filepath.Join("out", "sub", "vm.vmx"),
+	}
+	names := relativeNames(files)
+	if !reflect.DeepEqual(names, []string{"disk.vmdk", "sub/vm.vmx"}) {
+		t.Fatalf("bad: %#v", names)
+	}
+
+	names = relativeNames([]string{filepath.Join("out", "disk.qcow2")})
+	if !reflect.DeepEqual(names, []string{"disk.qcow2"}) {
+		t.Fatalf("bad: %#v", names)
+	}
+}
+
+func TestArtifact(t *testing.T) {
+	store := &memoryStore{files: map[string]string{"disk.img": "data"}}
+	local := &packer.MockArtifact{FilesValue: []string{"disk.img"}}
+	a := NewArtifact(local, store, []string{"disk.img"}, []string{"mem://disk.img"}, false)
+
+	if !reflect.DeepEqual(a.Files(), []string{"mem://disk.img"}) {
+		t.Fatalf("bad: %#v", a.Files())
+	}
+	if !reflect.DeepEqual(a.State("output_urls"), []string{"mem://disk.img"}) {
+		t.Fatalf("bad: %#v", a.State("output_urls"))
+	}
+	if !strings.Contains(a.String(), "mem://disk.img") {
+		t.Fatalf("bad: %s", a.String())
+	}
+
+	if err := a.Destroy(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(store.files) > 0 {
+		t.Fatalf("bad: %#v", store.files)
+	}
+	if local.DestroyCalled {
+		t.Fatal("the local files were removed already")
+	}
+
+	a = NewArtifact(local, store, nil, []string{"mem://disk.img"}, true)
+	if len(a.Files()) != 2 {
+		t.Fatalf("bad: %#v", a.Files())
+	}
+	a.Destroy()
+	if !local.DestroyCalled {
+		t.Fatal("should destroy the local files")
+	}
+}
+
+func TestUpload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "disk.img")
+	if err := ioutil.WriteFile(path, []byte("data"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	s, server := newTestServer(t, 0)
+	defer server.Close()
+	c := Config{URL: server.URL + "/out"}
+	if errs := c.Prepare(); len(errs) > 0 {
+		t.Fatalf("bad: %#v", errs)
+	}
+
+	local := &packer.MockArtifact{FilesValue: []string{path}}
+	ui := packer.TestUi(t)
+	a, err := Upload(ui, &c, local)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if string(s.files["/out/disk.img"]) != "data" {
+		t.Fatalf("bad: %#v", s.files)
+	}
+	if !reflect.DeepEqual(a.Files(), []string{server.URL + "/out/disk.img"}) {
+		t.Fatalf("bad: %#v", a.Files())
+	}
+	if !local.DestroyCalled {
+		t.Fatal("should remove the local files")
+	}
+
+	// Nothing is uploaded without a store
+	local = &packer.MockArtifact{FilesValue: []string{path}}
+	a, err = Upload(ui, &Config{}, local)
+	if err != nil || a != local {
+		t.Fatalf("bad: %#v %v", a, err)
+	}
+}
+
+func TestWriter(t *testing.T) {
+	store := &memoryStore{files: make(map[string]string)}
+	w := NewWriter(context.Background(), store, "archive.tar.gz")
+	if _, err := io.Copy(w, bytes.NewBufferString("archive")); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if store.files["archive.tar.gz"] != "archive" || w.URL() != "mem://archive.tar.gz" {
+		t.Fatalf("bad: %#v %s", store.files, w.URL())
+	}
+
+	w = NewWriter(context.Background(), store, "failed.tar.gz")
+	w.Write([]byte("part"))
+	w.Abort(errors.New("failed"))
+	if _, ok := store.files["failed.tar.gz"]; ok {
+		t.Fatal("should not store an aborted file")
+	}
+}
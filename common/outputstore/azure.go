@@ -0,0 +1,90 @@
+package outputstore
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/Azure/azure-sdk-for-go/storage"
+	"github.com/hashicorp/packer/common"
+)
+
+// azureBlockTries is how many times a block is uploaded before the upload
+// fails.
+const azureBlockTries = 5
+
+// azureStore uploads to Azure Blob Storage as block blobs, a block per part
+// so a failed block is retried on its own.
+type azureStore struct {
+	loc       *location
+	container *storage.Container
+	partSize  int64
+}
+
+func newAzureStore(loc *location, key, endpoint string, partSize int64) (*azureStore, error) {
+	var client storage.Client
+	var err error
+	if endpoint != "" {
+		client, err = storage.NewClient(loc.account, key, endpoint, storage.DefaultAPIVersion, true)
+	} else {
+		client, err = storage.NewBasicClient(loc.account, key)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	blobs := client.GetBlobService()
+	return &azureStore{
+		loc:       loc,
+		container: blobs.GetContainerReference(loc.bucket),
+		partSize:  partSize,
+	}, nil
+}
+
+func (s *azureStore) Put(ctx context.Context, name string, r io.Reader, size int64) (string, error) {
+	blob := s.container.GetBlobReference(s.loc.key(name))
+
+	var blocks []storage.Block
+	buf := make([]byte, s.partSize)
+	for {
+		n, err := io.ReadFull(r, buf)
+		if err == io.EOF {
+			break
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return "", err
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return "", ctxErr
+		}
+
+		// The IDs of the blocks of a blob must all have the same length
+		id := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%08d", len(blocks))))
+		err = common.Retry(1, 16, azureBlockTries, func(i uint) (bool, error) {
+			if err := blob.PutBlock(id, buf[:n], nil); err != nil {
+				log.Printf("Error uploading block %d of %s, attempt %d: %s", len(blocks), name, i+1, err)
+				return false, nil
+			}
+			return true, nil
+		})
+		if err != nil {
+			return "", fmt.Errorf("Error uploading block %d of %s: %s", len(blocks), name, err)
+		}
+		blocks = append(blocks, storage.Block{ID: id, Status: storage.BlockStatusLatest})
+
+		if n < len(buf) {
+			break
+		}
+	}
+
+	if err := blob.PutBlockList(blocks, nil); err != nil {
+		return "", err
+	}
+	return blob.GetURL(), nil
+}
+
+func (s *azureStore) Delete(ctx context.Context, name string) error {
+	return s.container.GetBlobReference(s.loc.key(name)).Delete(nil)
+}
@@ -0,0 +1,166 @@
+// Package outputstore stores the files builders and post-processors output
+// in S3, Google Cloud Storage, Azure Blob Storage, or with HTTP PUT, instead
+// of only on the local disk.
+package outputstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// DefaultPartSize is the size in megabytes of the parts large files are
+// uploaded in.
+const DefaultPartSize = 64
+
+// Config is the output_store object of the builders and post-processors
+// that output files.
+type Config struct {
+	// URL is where the files go: s3://bucket/prefix, gs://bucket/prefix,
+	// azure://account/container/prefix, or an http(s) URL files are PUT
+	// under.
+	URL string `mapstructure:"url"`
+
+	Region      string            `mapstructure:"region"`
+	Endpoint    string            `mapstructure:"endpoint"`
+	AccountFile string            `mapstructure:"account_file"`
+	StorageKey  string            `mapstructure:"storage_key"`
+	Headers     map[string]string `mapstructure:"headers"`
+	PartSize    int               `mapstructure:"part_size"`
+	KeepLocal   bool              `mapstructure:"keep_local"`
+
+	location *location
+}
+
+// location is the parsed URL of the store.
+type location struct {
+	scheme string
+
+	// bucket is the S3 or GCS bucket, or the Azure container.
+	bucket  string
+	account string
+	prefix  string
+
+	// base is the URL of HTTP stores.
+	base *url.URL
+}
+
+func (c *Config) Prepare() []error {
+	if c.Empty() {
+		return nil
+	}
+
+	var errs []error
+	loc, err := parseURL(c.URL)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	c.location = loc
+
+	if c.PartSize == 0 {
+		c.PartSize = DefaultPartSize
+	}
+	if c.PartSize < 5 {
+		errs = append(errs, errors.New("output_store.part_size must be at least 5 (MB)"))
+	}
+
+	if loc != nil {
+		switch loc.scheme {
+		case "azure":
+			if c.PartSize > 100 {
+				errs = append(errs, errors.New("output_store.part_size can't be more than 100 (MB) for Azure"))
+			}
+			if c.StorageKey == "" {
+				c.StorageKey = os.Getenv("AZURE_STORAGE_ACCESS_KEY")
+			}
+			if c.StorageKey == "" {
+				errs = append(errs, errors.New("output_store.storage_key or AZURE_STORAGE_ACCESS_KEY must be set for Azure"))
+			}
+		case "gs":
+			if c.AccountFile != "" {
+				if _, err := os.Stat(c.AccountFile); err != nil {
+					errs = append(errs, fmt.Errorf("Bad output_store.account_file: %s", err))
+				}
+			}
+		}
+		if loc.scheme != "http" && loc.scheme != "https" && len(c.Headers) > 0 {
+			errs = append(errs, errors.New("output_store.headers can only be set for HTTP stores"))
+		}
+	}
+
+	return errs
+}
+
+// Empty says whether no store is set, so the files stay on the local disk.
+func (c *Config) Empty() bool {
+	return c.URL == ""
+}
+
+// Store returns the store of the configuration, Prepare must have succeeded.
+func (c *Config) Store(ctx context.Context) (Store, error) {
+	partSize := int64(c.PartSize) * 1024 * 1024
+	loc := c.location
+	switch loc.scheme {
+	case "s3":
+		return newS3Store(loc, c.Region, c.Endpoint, partSize)
+	case "gs":
+		return newGCSStore(ctx, loc, c.AccountFile, partSize)
+	case "azure":
+		return newAzureStore(loc, c.StorageKey, c.Endpoint, partSize)
+	default:
+		return newHTTPStore(loc, c.Headers), nil
+	}
+}
+
+func parseURL(raw string) (*location, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("Bad output_store.url: %s", err)
+	}
+
+	loc := &location{
+		scheme: u.Scheme,
+		prefix: strings.Trim(u.Path, "/"),
+	}
+	switch u.Scheme {
+	case "s3", "gs":
+		loc.bucket = u.Host
+	case "azure":
+		loc.account = u.Host
+		parts := strings.SplitN(loc.prefix, "/", 2)
+		loc.bucket = parts[0]
+		loc.prefix = ""
+		if len(parts) == 2 {
+			loc.prefix = parts[1]
+		}
+		if loc.account == "" {
+			return nil, fmt.Errorf("output_store.url %q has no storage account", raw)
+		}
+	case "http", "https":
+		if u.Host == "" {
+			return nil, fmt.Errorf("output_store.url %q has no host", raw)
+		}
+		loc.base = u
+		return loc, nil
+	default:
+		return nil, fmt.Errorf(
+			"output_store.url %q must start with s3://, gs://, azure://, http:// or https://", raw)
+	}
+
+	if loc.bucket == "" {
+		return nil, fmt.Errorf("output_store.url %q has no bucket or container", raw)
+	}
+	return loc, nil
+}
+
+// key is where the file of the name is in the bucket.
+func (l *location) key(name string) string {
+	name = strings.TrimLeft(name, "/")
+	if l.prefix == "" {
+		return name
+	}
+	return l.prefix + "/" + name
+}
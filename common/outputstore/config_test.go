@@ -0,0 +1,109 @@
+package outputstore
+
+import (
+	"os"
+	"testing"
+)
+
+func TestParseURL(t *testing.T) {
+	cases := []struct {
+		URL     string
+		Scheme  string
+		Account string
+		Bucket  string
+		Prefix  string
+		Err     bool
+	}{
+		{"s3://bucket", "s3", "", "bucket", "", false},
+		{"s3://bucket/images/", "s3", "", "bucket", "images", false},
+		{"gs://bucket/a/b", "gs", "", "bucket", "a/b", false},
+		{"azure://account/container", "azure", "account", "container", "", false},
+		{"azure://account/container/images", "azure", "account", "container", "images", false},
+		{"https://example.com/upload", "https", "", "", "upload", false},
+		{"s3:///images", "", "", "", "", true},
+		{"azure://account", "", "", "", "", true},
+		{"http:///upload", "", "", "", "", true},
+		{"ftp://example.com", "", "", "", "", true},
+	}
+	for _, tc := range cases {
+		loc, err := parseURL(tc.URL)
+		if (err != nil) != tc.Err {
+			t.Fatalf("%s: bad: %v", tc.URL, err)
+		}
+		if err != nil {
+			continue
+		}
+		if loc.scheme != tc.Scheme || loc.account != tc.Account || loc.bucket != tc.Bucket || loc.prefix != tc.Prefix {
+			t.Fatalf("%s: bad: %#v", tc.URL, loc)
+		}
+	}
+}
+
+func TestLocationKey(t *testing.T) {
+	loc := &location{prefix: "images"}
+	if key := loc.key("disk.qcow2"); key != "images/disk.qcow2" {
+		t.Fatalf("bad: %s", key)
+	}
+	loc.prefix = ""
+	if key := loc.key("/dir/disk.qcow2"); key != "dir/disk.qcow2" {
+		t.Fatalf("bad: %s", key)
+	}
+}
+
+func TestConfigPrepare(t *testing.T) {
+	var c Config
+	if errs := c.Prepare(); len(errs) > 0 {
+		t.Fatalf("bad: %#v", errs)
+	}
+	if !c.Empty() {
+		t.Fatal("should be empty")
+	}
+
+	c = Config{URL: "s3://bucket/prefix"}
+	if errs := c.Prepare(); len(errs) > 0 {
+		t.Fatalf("bad: %#v", errs)
+	}
+	if c.PartSize != DefaultPartSize {
+		t.Fatalf("bad: %d", c.PartSize)
+	}
+
+	c = Config{URL: "s3://bucket", PartSize: 1}
+	if errs := c.Prepare(); len(errs) != 1 {
+		t.Fatalf("bad: %#v", errs)
+	}
+
+	c = Config{URL: "s3://bucket", Headers: map[string]string{"X-Foo": "bar"}}
+	if errs := c.Prepare(); len(errs) != 1 {
+		t.Fatalf("bad: %#v", errs)
+	}
+
+	c = Config{URL: "gs://bucket", AccountFile: "i-dont-exist"}
+	if errs := c.Prepare(); len(errs) != 1 {
+		t.Fatalf("bad: %#v", errs)
+	}
+}
+
+func TestConfigPrepare_azure(t *testing.T) {
+	old := os.Getenv("AZURE_STORAGE_ACCESS_KEY")
+	defer os.Setenv("AZURE_STORAGE_ACCESS_KEY", old)
+
+	os.Setenv("AZURE_STORAGE_ACCESS_KEY", "")
+	c := Config{URL: "azure://account/container"}
+	if errs := c.Prepare(); len(errs) != 1 {
+		t.Fatalf("bad: %#v", errs)
+	}
+
+	os.Setenv("AZURE_STORAGE_ACCESS_KEY", "a2V5")
+	c = Config{URL: "azure://account/container"}
+	if errs := c.Prepare(); len(errs) > 0 {
+		t.Fatalf("bad: %#v", errs)
+	}
+	if c.StorageKey != "a2V5" {
+		t.Fatalf("bad: %s", c.StorageKey)
+	}
+
+	c = Config{URL: "azure://account/container", PartSize: 200}
+	if errs := c.Prepare(); len(errs) != 1 {
+		t.Fatalf("bad: %#v", errs)
+	}
+}
@@ -0,0 +1,64 @@
+package outputstore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/googleapi"
+	storage "google.golang.org/api/storage/v1"
+)
+
+// gcsStore uploads to Google Cloud Storage with resumable uploads, with the
+// account file or the default credentials.
+type gcsStore struct {
+	loc       *location
+	service   *storage.Service
+	chunkSize int
+}
+
+func newGCSStore(ctx context.Context, loc *location, accountFile string, partSize int64) (*gcsStore, error) {
+	var client *http.Client
+	if accountFile != "" {
+		data, err := ioutil.ReadFile(accountFile)
+		if err != nil {
+			return nil, err
+		}
+		conf, err := google.JWTConfigFromJSON(data, storage.DevstorageReadWriteScope)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading output_store.account_file: %s", err)
+		}
+		client = conf.Client(ctx)
+	} else {
+		var err error
+		client, err = google.DefaultClient(ctx, storage.DevstorageReadWriteScope)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	service, err := storage.New(client)
+	if err != nil {
+		return nil, err
+	}
+	return &gcsStore{loc: loc, service: service, chunkSize: int(partSize)}, nil
+}
+
+func (s *gcsStore) Put(ctx context.Context, name string, r io.Reader, size int64) (string, error) {
+	key := s.loc.key(name)
+	_, err := s.service.Objects.Insert(s.loc.bucket, &storage.Object{Name: key}).
+		Media(r, googleapi.ChunkSize(s.chunkSize)).
+		Context(ctx).
+		Do()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("gs://%s/%s", s.loc.bucket, key), nil
+}
+
+func (s *gcsStore) Delete(ctx context.Context, name string) error {
+	return s.service.Objects.Delete(s.loc.bucket, s.loc.key(name)).Context(ctx).Do()
+}
@@ -0,0 +1,130 @@
+package outputstore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/hashicorp/packer/common"
+)
+
+// httpTries is how many times an upload that can be sent again is tried.
+const httpTries = 5
+
+// httpStore PUTs the files under a URL. Files of unknown size are sent with
+// the chunked transfer encoding.
+type httpStore struct {
+	base    *url.URL
+	headers map[string]string
+	client  *http.Client
+}
+
+func newHTTPStore(loc *location, headers map[string]string) *httpStore {
+	return &httpStore{
+		base:    loc.base,
+		headers: headers,
+		client:  http.DefaultClient,
+	}
+}
+
+func (s *httpStore) url(name string) string {
+	u := *s.base
+	u.Path = path.Join("/", u.Path, name)
+	return u.String()
+}
+
+func (s *httpStore) Put(ctx context.Context, name string, r io.Reader, size int64) (string, error) {
+	target := s.url(name)
+
+	// Only files that can be read again from the start are retried
+	seeker, ok := r.(io.Seeker)
+	if !ok {
+		if err := s.put(ctx, target, r, size); err != nil {
+			return "", err
+		}
+		return target, nil
+	}
+
+	var lastErr error
+	err := common.Retry(1, 16, httpTries, func(i uint) (bool, error) {
+		if i > 0 {
+			if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+				return false, err
+			}
+		}
+
+		lastErr = s.put(ctx, target, r, size)
+		if lastErr == nil {
+			return true, nil
+		}
+		if ctx.Err() != nil {
+			return false, lastErr
+		}
+		log.Printf("Error uploading %s, attempt %d: %s", target, i+1, lastErr)
+		return false, nil
+	})
+	if err == common.RetryExhaustedError {
+		err = lastErr
+	}
+	if err != nil {
+		return "", err
+	}
+	return target, nil
+}
+
+func (s *httpStore) put(ctx context.Context, target string, r io.Reader, size int64) error {
+	req, err := http.NewRequest("PUT", target, ioutil.NopCloser(r))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.ContentLength = size
+	if size == 0 {
+		req.Body = http.NoBody
+	}
+	for k, v := range s.headers {
+		req.Header.Set(k, v)
+	}
+	_, err = s.do(req)
+	return err
+}
+
+func (s *httpStore) Delete(ctx context.Context, name string) error {
+	req, err := http.NewRequest("DELETE", s.url(name), nil)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	for k, v := range s.headers {
+		req.Header.Set(k, v)
+	}
+	status, err := s.do(req)
+	if status == http.StatusNotFound {
+		// Already gone
+		return nil
+	}
+	return err
+}
+
+// do sends the request, and fails when the response isn't a success.
+func (s *httpStore) do(req *http.Request) (int, error) {
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return resp.StatusCode, fmt.Errorf("%s %s: %s %s",
+			req.Method, req.URL, resp.Status, strings.TrimSpace(string(body)))
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,136 @@
+package outputstore
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testServer is an HTTP server that stores the files PUT to it, and fails
+// the number of requests in fail first.
+type testServer struct {
+	l       sync.Mutex
+	files   map[string][]byte
+	headers http.Header
+	chunked bool
+	fail    int
+}
+
+func newTestServer(t *testing.T, fail int) (*testServer, *httptest.Server) {
+	s := &testServer{files: make(map[string][]byte), fail: fail}
+	return s, httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.l.Lock()
+		defer s.l.Unlock()
+
+		if s.fail > 0 {
+			s.fail--
+			ioutil.ReadAll(r.Body)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		switch r.Method {
+		case "PUT":
+			data, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				t.Errorf("err: %s", err)
+			}
+			s.files[r.URL.Path] = data
+			s.headers = r.Header
+			s.chunked = len(r.TransferEncoding) > 0
+			w.WriteHeader(http.StatusCreated)
+		case "DELETE":
+			if _, ok := s.files[r.URL.Path]; !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			delete(s.files, r.URL.Path)
+		}
+	}))
+}
+
+func testHTTPStore(t *testing.T, rawURL string) Store {
+	c := Config{URL: rawURL, Headers: map[string]string{"Authorization": "Bearer foo"}}
+	if errs := c.Prepare(); len(errs) > 0 {
+		t.Fatalf("bad: %#v", errs)
+	}
+	store, err := c.Store(context.Background())
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return store
+}
+
+func TestHTTPStore(t *testing.T) {
+	s, server := newTestServer(t, 0)
+	defer server.Close()
+	store := testHTTPStore(t, server.URL+"/images")
+
+	url, err := store.Put(context.Background(), "dir/disk.img", strings.NewReader("data"), 4)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if url != server.URL+"/images/dir/disk.img" {
+		t.Fatalf("bad: %s", url)
+	}
+	if string(s.files["/images/dir/disk.img"]) != "data" {
+		t.Fatalf("bad: %#v", s.files)
+	}
+	if s.headers.Get("Authorization") != "Bearer foo" {
+		t.Fatalf("bad: %#v", s.headers)
+	}
+	if s.chunked {
+		t.Fatal("should send the length")
+	}
+
+	if err := store.Delete(context.Background(), "dir/disk.img"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(s.files) > 0 {
+		t.Fatalf("bad: %#v", s.files)
+	}
+	if err := store.Delete(context.Background(), "dir/disk.img"); err != nil {
+		t.Fatalf("deleting a missing file should succeed: %s", err)
+	}
+}
+
+func TestHTTPStore_chunked(t *testing.T) {
+	s, server := newTestServer(t, 0)
+	defer server.Close()
+	store := testHTTPStore(t, server.URL)
+
+	// A reader that isn't a seeker, and has no known length
+	r := ioutil.NopCloser(bytes.NewBufferString("streamed"))
+	if _, err := store.Put(context.Background(), "disk.img", r, -1); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if string(s.files["/disk.img"]) != "streamed" || !s.chunked {
+		t.Fatalf("bad: %#v %v", s.files, s.chunked)
+	}
+}
+
+func TestHTTPStore_retry(t *testing.T) {
+	s, server := newTestServer(t, 1)
+	defer server.Close()
+	store := testHTTPStore(t, server.URL)
+
+	if _, err := store.Put(context.Background(), "disk.img", strings.NewReader("data"), 4); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if string(s.files["/disk.img"]) != "data" {
+		t.Fatalf("bad: %#v", s.files)
+	}
+
+	// Streams can't be sent again
+	s.fail = 1
+	r := ioutil.NopCloser(strings.NewReader("data"))
+	_, err := store.Put(context.Background(), "other.img", r, -1)
+	if err == nil || !strings.Contains(err.Error(), "500") {
+		t.Fatalf("bad: %v", err)
+	}
+}
@@ -0,0 +1,62 @@
+package outputstore
+
+import (
+	"context"
+	"io"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// s3Store uploads to S3 with multipart uploads, with the credentials of the
+// environment and the shared configuration files.
+type s3Store struct {
+	loc      *location
+	sess     *session.Session
+	partSize int64
+}
+
+func newS3Store(loc *location, region, endpoint string, partSize int64) (*s3Store, error) {
+	config := aws.NewConfig()
+	if region != "" {
+		config = config.WithRegion(region)
+	}
+	if endpoint != "" {
+		// S3 compatible stores don't have buckets as subdomains
+		config = config.WithEndpoint(endpoint).WithS3ForcePathStyle(true)
+	}
+
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            *config,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &s3Store{loc: loc, sess: sess, partSize: partSize}, nil
+}
+
+func (s *s3Store) Put(ctx context.Context, name string, r io.Reader, size int64) (string, error) {
+	uploader := s3manager.NewUploader(s.sess, func(u *s3manager.Uploader) {
+		u.PartSize = s.partSize
+	})
+	out, err := uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+		Bucket: aws.String(s.loc.bucket),
+		Key:    aws.String(s.loc.key(name)),
+		Body:   r,
+	})
+	if err != nil {
+		return "", err
+	}
+	return out.Location, nil
+}
+
+func (s *s3Store) Delete(ctx context.Context, name string) error {
+	_, err := s3.New(s.sess).DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.loc.bucket),
+		Key:    aws.String(s.loc.key(name)),
+	})
+	return err
+}
@@ -0,0 +1,61 @@
+package outputstore
+
+import (
+	"context"
+	"io"
+)
+
+// Store is where output files are uploaded.
+type Store interface {
+	// Put uploads the contents of r as the file of the name, and returns
+	// its URL. size is -1 when it isn't known, like when the file is
+	// streamed as it is written.
+	Put(ctx context.Context, name string, r io.Reader, size int64) (string, error)
+
+	// Delete removes the file of the name.
+	Delete(ctx context.Context, name string) error
+}
+
+// Writer streams what is written to it to a file of the store.
+type Writer struct {
+	pw   *io.PipeWriter
+	done chan struct{}
+	url  string
+	err  error
+}
+
+// NewWriter returns the writer of the file of the name. The upload is done
+// when Close returns.
+func NewWriter(ctx context.Context, store Store, name string) *Writer {
+	pr, pw := io.Pipe()
+	w := &Writer{pw: pw, done: make(chan struct{})}
+	go func() {
+		defer close(w.done)
+		w.url, w.err = store.Put(ctx, name, pr, -1)
+		// Stop the writes when the upload fails
+		pr.CloseWithError(w.err)
+	}()
+	return w
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	return w.pw.Write(p)
+}
+
+// Close ends the file, and waits for its upload.
+func (w *Writer) Close() error {
+	w.pw.Close()
+	<-w.done
+	return w.err
+}
+
+// Abort stops the upload with the error.
+func (w *Writer) Abort(err error) {
+	w.pw.CloseWithError(err)
+	<-w.done
+}
+
+// URL is the URL of the file once it is uploaded.
+func (w *Writer) URL() string {
+	return w.url
+}
@@ -0,0 +1,289 @@
+package ovf
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Apply sets the metadata in the OVF descriptor at path, or in the one of
+// the OVA archive at path, and updates its digest in the manifest. The
+// sections the metadata sets replace the ones the descriptor has, the
+// others are kept as they are. Signatures of the appliance aren't updated.
+func Apply(path string, m *Metadata) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ovf":
+		return applyOVF(path, m)
+	case ".ova":
+		return applyOVA(path, m)
+	default:
+		return fmt.Errorf("%s is not an OVF or OVA file", path)
+	}
+}
+
+func applyOVF(path string, m *Metadata) error {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	descriptor, err := m.edit(contents)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path, descriptor, 0644); err != nil {
+		return err
+	}
+
+	mfPath := strings.TrimSuffix(path, filepath.Ext(path)) + ".mf"
+	manifest, err := ioutil.ReadFile(mfPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	manifest, err = updateManifest(manifest, filepath.Base(path), descriptor)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(mfPath, manifest, 0644)
+}
+
+// applyOVA writes the archive again with the edited descriptor and
+// manifest. The descriptor comes first in an OVA, and the manifest after
+// it, so the disks are copied without being read into memory.
+func applyOVA(path string, m *Metadata) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(out.Name())
+	defer out.Close()
+
+	tr := tar.NewReader(in)
+	tw := tar.NewWriter(out)
+	var ovfName string
+	var descriptor []byte
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("Error reading %s: %s", path, err)
+		}
+
+		var contents []byte
+		switch strings.ToLower(filepath.Ext(hdr.Name)) {
+		case ".ovf":
+			if contents, err = ioutil.ReadAll(tr); err != nil {
+				return err
+			}
+			if contents, err = m.edit(contents); err != nil {
+				return err
+			}
+			ovfName, descriptor = hdr.Name, contents
+		case ".mf":
+			if descriptor == nil {
+				return fmt.Errorf("The manifest of %s comes before its OVF descriptor", path)
+			}
+			if contents, err = ioutil.ReadAll(tr); err != nil {
+				return err
+			}
+			if contents, err = updateManifest(contents, ovfName, descriptor); err != nil {
+				return err
+			}
+		}
+
+		if contents != nil {
+			hdr.Size = int64(len(contents))
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if contents != nil {
+			_, err = tw.Write(contents)
+		} else {
+			_, err = io.Copy(tw, tr)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if descriptor == nil {
+		return fmt.Errorf("%s has no OVF descriptor", path)
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	in.Close()
+	return os.Rename(out.Name(), path)
+}
+
+var virtualSystemRe = regexp.MustCompile(`<(\w+:)?VirtualSystem[\s>]`)
+
+// edit sets the sections of the metadata in the first virtual system of
+// the descriptor. The elements are in the namespace of the VirtualSystem
+// element, and the attributes in the ovf one.
+func (m *Metadata) edit(descriptor []byte) ([]byte, error) {
+	s := string(descriptor)
+	loc := virtualSystemRe.FindStringSubmatchIndex(s)
+	if loc == nil {
+		return nil, errors.New("The OVF descriptor has no VirtualSystem")
+	}
+	prefix := ""
+	if loc[2] >= 0 {
+		prefix = s[loc[2]:loc[3]]
+	}
+	end := strings.Index(s[loc[0]:], "</"+prefix+"VirtualSystem>")
+	if end < 0 {
+		return nil, errors.New("The VirtualSystem of the OVF descriptor isn't closed")
+	}
+	end += loc[0]
+	if !strings.Contains(s, `xmlns:ovf=`) {
+		return nil, errors.New("The OVF descriptor doesn't declare the ovf namespace")
+	}
+
+	system := s[loc[0]:end]
+	replaced := map[string]bool{
+		"ProductSection":    m.hasProduct(),
+		"AnnotationSection": m.Annotation != "",
+		"EulaSection":       m.EULA != "" || m.EULAFile != "",
+	}
+	for section, replace := range replaced {
+		if replace {
+			re := regexp.MustCompile(`(?s)\s*<` + prefix + section + `[\s>].*?</` + prefix + section + `>`)
+			system = re.ReplaceAllString(system, "")
+		}
+	}
+
+	sections, err := m.sections(prefix)
+	if err != nil {
+		return nil, err
+	}
+	base := s[strings.LastIndex(s[:loc[0]], "\n")+1 : loc[0]]
+	if strings.TrimSpace(base) != "" {
+		base = ""
+	}
+	indent := base + "  "
+	var b bytes.Buffer
+	b.WriteString(s[:loc[0]])
+	b.WriteString(strings.TrimRight(system, " \t\r\n"))
+	for _, line := range strings.Split(sections, "\n") {
+		if line != "" {
+			b.WriteString("\n" + indent + line)
+		}
+	}
+	b.WriteString("\n" + base)
+	b.WriteString(s[end:])
+	return b.Bytes(), nil
+}
+
+// sections are the XML of the sections of the metadata, indented in the
+// virtual system.
+func (m *Metadata) sections(prefix string) (string, error) {
+	var b bytes.Buffer
+	element := func(indent, name, value string) {
+		if value != "" {
+			fmt.Fprintf(&b, "%s<%s%s>%s</%s%s>\n", indent, prefix, name, escape(value), prefix, name)
+		}
+	}
+
+	if m.hasProduct() {
+		fmt.Fprintf(&b, "<%sProductSection>\n", prefix)
+		element("  ", "Info", "Information about the installed software")
+		element("  ", "Product", m.Product)
+		element("  ", "Vendor", m.Vendor)
+		element("  ", "Version", m.Version)
+		element("  ", "ProductUrl", m.ProductURL)
+		element("  ", "VendorUrl", m.VendorURL)
+		for _, key := range m.propertyKeys() {
+			fmt.Fprintf(&b, `  <%sProperty ovf:key="%s" ovf:type="string" ovf:value="%s"/>`+"\n",
+				prefix, escape(key), escape(m.Properties[key]))
+		}
+		fmt.Fprintf(&b, "</%sProductSection>\n", prefix)
+	}
+
+	if m.Annotation != "" {
+		fmt.Fprintf(&b, "<%sAnnotationSection>\n", prefix)
+		element("  ", "Info", "A human-readable annotation")
+		element("  ", "Annotation", m.Annotation)
+		fmt.Fprintf(&b, "</%sAnnotationSection>\n", prefix)
+	}
+
+	eula := m.EULA
+	if m.EULAFile != "" {
+		contents, err := ioutil.ReadFile(m.EULAFile)
+		if err != nil {
+			return "", fmt.Errorf("Error reading ovf_metadata.eula_file: %s", err)
+		}
+		eula = string(contents)
+	}
+	if eula != "" {
+		fmt.Fprintf(&b, "<%sEulaSection>\n", prefix)
+		element("  ", "Info", "License agreement for the virtual system")
+		element("  ", "License", eula)
+		fmt.Fprintf(&b, "</%sEulaSection>\n", prefix)
+	}
+
+	return b.String(), nil
+}
+
+func escape(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+var manifestLineRe = regexp.MustCompile(`^(\w+)\s*\((.+)\)\s*=\s*([0-9a-fA-F]+)\s*$`)
+
+// updateManifest sets the digest of the descriptor in the manifest, with
+// the algorithm it already uses and keeping its format, which differs
+// between VirtualBox and ovftool.
+func updateManifest(manifest []byte, name string, descriptor []byte) ([]byte, error) {
+	lines := strings.Split(string(manifest), "\n")
+	for i, line := range lines {
+		loc := manifestLineRe.FindStringSubmatchIndex(strings.TrimRight(line, "\r"))
+		if loc == nil || line[loc[4]:loc[5]] != filepath.Base(name) {
+			continue
+		}
+
+		var h hash.Hash
+		switch strings.ToUpper(line[loc[2]:loc[3]]) {
+		case "SHA1":
+			h = sha1.New()
+		case "SHA256":
+			h = sha256.New()
+		case "SHA512":
+			h = sha512.New()
+		default:
+			return nil, fmt.Errorf("Unknown digest algorithm in the manifest: %s", line[loc[2]:loc[3]])
+		}
+		h.Write(descriptor)
+		lines[i] = line[:loc[6]] + hex.EncodeToString(h.Sum(nil)) + line[loc[7]:]
+	}
+	return []byte(strings.Join(lines, "\n")), nil
+}
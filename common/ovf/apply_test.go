@@ -0,0 +1,187 @@
+package ovf
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testDescriptor = `<?xml version="1.0"?>
+<Envelope ovf:version="1.0" xmlns="http://schemas.dmtf.org/ovf/envelope/1" xmlns:ovf="http://schemas.dmtf.org/ovf/envelope/1">
+  <VirtualSystem ovf:id="packer">
+    <Info>A virtual machine</Info>
+    <ProductSection>
+      <Info>Old product</Info>
+      <Product>old</Product>
+    </ProductSection>
+    <VirtualHardwareSection>
+      <Info>Virtual hardware requirements</Info>
+    </VirtualHardwareSection>
+  </VirtualSystem>
+</Envelope>
+`
+
+func testMetadata() *Metadata {
+	return &Metadata{
+		Product:    "Packer & friends",
+		Vendor:     "HashiCorp",
+		Version:    "1.0",
+		Annotation: "Built by Packer",
+		EULA:       "Use <freely>",
+		Properties: map[string]string{"b": "2", "a": `"1"`},
+	}
+}
+
+func TestMetadataEdit(t *testing.T) {
+	edited, err := testMetadata().edit([]byte(testDescriptor))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	s := string(edited)
+
+	expected := []string{
+		"    <VirtualHardwareSection>",
+		"    <ProductSection>\n      <Info>Information about the installed software</Info>\n      <Product>Packer &amp; friends</Product>\n      <Vendor>HashiCorp</Vendor>\n      <Version>1.0</Version>",
+		`      <Property ovf:key="a" ovf:type="string" ovf:value="&#34;1&#34;"/>` + "\n" + `      <Property ovf:key="b"`,
+		"    <AnnotationSection>\n      <Info>A human-readable annotation</Info>\n      <Annotation>Built by Packer</Annotation>\n    </AnnotationSection>",
+		"<License>Use &lt;freely&gt;</License>",
+		"    </EulaSection>\n  </VirtualSystem>\n</Envelope>",
+	}
+	for _, e := range expected {
+		if !strings.Contains(s, e) {
+			t.Fatalf("should contain %q:\n%s", e, s)
+		}
+	}
+	if strings.Contains(s, "old") {
+		t.Fatalf("should replace the product section:\n%s", s)
+	}
+
+	// Only the annotation keeps the product section
+	edited, err = (&Metadata{Annotation: "note"}).edit([]byte(testDescriptor))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !strings.Contains(string(edited), "<Product>old</Product>") {
+		t.Fatalf("should keep the product section:\n%s", edited)
+	}
+
+	if _, err := testMetadata().edit([]byte("<Envelope/>")); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestUpdateManifest(t *testing.T) {
+	descriptor := []byte("descriptor")
+	sum1 := sha1.Sum(descriptor)
+	sum256 := sha256.Sum256(descriptor)
+
+	manifest := "SHA1 (packer.ovf) = 0000\nSHA1 (packer-disk1.vmdk) = 1111\n"
+	updated, err := updateManifest([]byte(manifest), "packer.ovf", descriptor)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := "SHA1 (packer.ovf) = " + hex.EncodeToString(sum1[:]) + "\nSHA1 (packer-disk1.vmdk) = 1111\n"
+	if string(updated) != expected {
+		t.Fatalf("bad: %s", updated)
+	}
+
+	manifest = "SHA256(packer.ovf)= 0000\r\n"
+	updated, err = updateManifest([]byte(manifest), "packer.ovf", descriptor)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if string(updated) != "SHA256(packer.ovf)= "+hex.EncodeToString(sum256[:])+"\r\n" {
+		t.Fatalf("bad: %q", updated)
+	}
+}
+
+func TestApply_ovf(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ovfPath := filepath.Join(dir, "packer.ovf")
+	ioutil.WriteFile(ovfPath, []byte(testDescriptor), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "packer.mf"), []byte("SHA1 (packer.ovf) = 0000\n"), 0644)
+
+	if err := Apply(ovfPath, testMetadata()); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	descriptor, _ := ioutil.ReadFile(ovfPath)
+	if !strings.Contains(string(descriptor), "<Vendor>HashiCorp</Vendor>") {
+		t.Fatalf("bad: %s", descriptor)
+	}
+	sum := sha1.Sum(descriptor)
+	manifest, _ := ioutil.ReadFile(filepath.Join(dir, "packer.mf"))
+	if string(manifest) != "SHA1 (packer.ovf) = "+hex.EncodeToString(sum[:])+"\n" {
+		t.Fatalf("bad: %s", manifest)
+	}
+}
+
+func TestApply_ova(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := []struct{ Name, Contents string }{
+		{"packer.ovf", testDescriptor},
+		{"packer.mf", "SHA1 (packer.ovf) = 0000\n"},
+		{"packer-disk1.vmdk", "disk"},
+	}
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, f := range files {
+		tw.WriteHeader(&tar.Header{Name: f.Name, Mode: 0644, Size: int64(len(f.Contents))})
+		tw.Write([]byte(f.Contents))
+	}
+	tw.Close()
+	ovaPath := filepath.Join(dir, "packer.ova")
+	ioutil.WriteFile(ovaPath, buf.Bytes(), 0644)
+
+	if err := Apply(ovaPath, testMetadata()); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	f, err := os.Open(ovaPath)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer f.Close()
+	tr := tar.NewReader(f)
+	var names []string
+	contents := make(map[string]string)
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			break
+		}
+		data, _ := ioutil.ReadAll(tr)
+		names = append(names, hdr.Name)
+		contents[hdr.Name] = string(data)
+	}
+
+	if strings.Join(names, ",") != "packer.ovf,packer.mf,packer-disk1.vmdk" {
+		t.Fatalf("bad: %#v", names)
+	}
+	if !strings.Contains(contents["packer.ovf"], "<Vendor>HashiCorp</Vendor>") {
+		t.Fatalf("bad: %s", contents["packer.ovf"])
+	}
+	sum := sha1.Sum([]byte(contents["packer.ovf"]))
+	if contents["packer.mf"] != "SHA1 (packer.ovf) = "+hex.EncodeToString(sum[:])+"\n" {
+		t.Fatalf("bad: %s", contents["packer.mf"])
+	}
+	if contents["packer-disk1.vmdk"] != "disk" {
+		t.Fatalf("bad: %s", contents["packer-disk1.vmdk"])
+	}
+}
@@ -0,0 +1,63 @@
+// Package ovf sets the metadata of the appliances builders export, like the
+// product, the annotation, and the properties, in their OVF descriptor.
+package ovf
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// Metadata is the metadata of an exported appliance, in the ovf_metadata
+// object of the builders that export OVF and OVA files.
+type Metadata struct {
+	Product    string            `mapstructure:"product"`
+	ProductURL string            `mapstructure:"product_url"`
+	Vendor     string            `mapstructure:"vendor"`
+	VendorURL  string            `mapstructure:"vendor_url"`
+	Version    string            `mapstructure:"version"`
+	Annotation string            `mapstructure:"annotation"`
+	EULA       string            `mapstructure:"eula"`
+	EULAFile   string            `mapstructure:"eula_file"`
+	Properties map[string]string `mapstructure:"properties"`
+}
+
+func (m *Metadata) Prepare() []error {
+	var errs []error
+
+	if m.EULA != "" && m.EULAFile != "" {
+		errs = append(errs, errors.New("Only one of ovf_metadata.eula or ovf_metadata.eula_file can be specified"))
+	}
+	if m.EULAFile != "" {
+		if _, err := os.Stat(m.EULAFile); err != nil {
+			errs = append(errs, fmt.Errorf("Bad ovf_metadata.eula_file: %s", err))
+		}
+	}
+	for key := range m.Properties {
+		if key == "" {
+			errs = append(errs, errors.New("ovf_metadata.properties can't have an empty key"))
+		}
+	}
+
+	return errs
+}
+
+// Empty says whether no metadata is set, so the OVF is left as exported.
+func (m *Metadata) Empty() bool {
+	return !m.hasProduct() && m.Annotation == "" && m.EULA == "" && m.EULAFile == ""
+}
+
+func (m *Metadata) hasProduct() bool {
+	return m.Product != "" || m.ProductURL != "" || m.Vendor != "" ||
+		m.VendorURL != "" || m.Version != "" || len(m.Properties) > 0
+}
+
+func (m *Metadata) propertyKeys() []string {
+	keys := make([]string, 0, len(m.Properties))
+	for key := range m.Properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,50 @@
+package ovf
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestMetadataPrepare(t *testing.T) {
+	m := Metadata{}
+	if errs := m.Prepare(); len(errs) > 0 {
+		t.Fatalf("err: %#v", errs)
+	}
+	if !m.Empty() {
+		t.Fatal("should be empty")
+	}
+
+	tf, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	tf.Close()
+	defer os.Remove(tf.Name())
+
+	m = Metadata{EULAFile: tf.Name()}
+	if errs := m.Prepare(); len(errs) > 0 {
+		t.Fatalf("err: %#v", errs)
+	}
+	if m.Empty() {
+		t.Fatal("should not be empty")
+	}
+
+	// Both EULAs
+	m = Metadata{EULA: "license", EULAFile: tf.Name()}
+	if errs := m.Prepare(); len(errs) != 1 {
+		t.Fatalf("bad: %#v", errs)
+	}
+
+	// Missing EULA file
+	m = Metadata{EULAFile: "i/dont/exist"}
+	if errs := m.Prepare(); len(errs) != 1 {
+		t.Fatalf("bad: %#v", errs)
+	}
+
+	// Empty property key
+	m = Metadata{Properties: map[string]string{"": "value"}}
+	if errs := m.Prepare(); len(errs) != 1 {
+		t.Fatalf("bad: %#v", errs)
+	}
+}
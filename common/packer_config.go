@@ -3,12 +3,19 @@ package common
 // PackerConfig is a struct that contains the configuration keys that
 // are sent by packer, properly tagged already so mapstructure can load
 // them. Embed this structure into your configuration class to get it.
+//
+// StepHooks is set in the template, for the runner of the steps.
 type PackerConfig struct {
 	PackerBuildName     string            `mapstructure:"packer_build_name"`
 	PackerBuilderType   string            `mapstructure:"packer_builder_type"`
 	PackerDebug         bool              `mapstructure:"packer_debug"`
+	PackerDebugSkip     []string          `mapstructure:"packer_debug_skip"`
+	PackerDebugTrace    string            `mapstructure:"packer_debug_trace"`
 	PackerForce         bool              `mapstructure:"packer_force"`
+	PackerForceDownload bool              `mapstructure:"packer_force_download"`
+	PackerOffline       bool              `mapstructure:"packer_offline"`
 	PackerOnError       string            `mapstructure:"packer_on_error"`
 	PackerUserVars      map[string]string `mapstructure:"packer_user_variables"`
 	PackerSensitiveVars []string          `mapstructure:"packer_sensitive_variables"`
+	StepHooks           []StepHook        `mapstructure:"step_hooks"`
 }
@@ -855,6 +855,65 @@ if ($switch -ne $null) {
 	return err
 }
 
+// CreateNATVirtualSwitch creates an internal switch whose network is
+// routed to the network of the host with NAT, and served by the DHCP server
+// of the host if dhcp is set, unless the switch already exists. It returns
+// whether it created the switch.
+func CreateNATVirtualSwitch(switchName string, prefix string, hostIP string, prefixLength int, dhcp bool,
+	networkID string, subnetMask string, dhcpStart string, dhcpEnd string) (bool, error) {
+
+	var script = `
+param([string]$switchName,[string]$prefix,[string]$hostIP,[int]$prefixLength,[string]$dhcp,[string]$networkID,[string]$subnetMask,[string]$dhcpStart,[string]$dhcpEnd)
+$ErrorActionPreference = "Stop"
+$switches = Hyper-V\Get-VMSwitch -Name $switchName -ErrorAction SilentlyContinue
+if ($switches.Count -ne 0) {
+  return $false
+}
+Hyper-V\New-VMSwitch -Name $switchName -SwitchType Internal | Out-Null
+$adapter = Get-NetAdapter -Name "vEthernet ($switchName)"
+New-NetIPAddress -IPAddress $hostIP -PrefixLength $prefixLength -InterfaceIndex $adapter.ifIndex | Out-Null
+New-NetNat -Name $switchName -InternalIPInterfaceAddressPrefix $prefix | Out-Null
+if ($dhcp -eq "True") {
+  Add-DhcpServerv4Scope -Name $switchName -StartRange $dhcpStart -EndRange $dhcpEnd -SubnetMask $subnetMask -State Active
+  $dnsServers = Get-DnsClientServerAddress -AddressFamily IPv4 | Select-Object -ExpandProperty ServerAddresses -Unique
+  if ($dnsServers) {
+    Set-DhcpServerv4OptionValue -ScopeId $networkID -Router $hostIP -DnsServer $dnsServers -Force
+  } else {
+    Set-DhcpServerv4OptionValue -ScopeId $networkID -Router $hostIP
+  }
+}
+return $true
+`
+
+	var ps powershell.PowerShellCmd
+	cmdOut, err := ps.Output(script, switchName, prefix, hostIP, strconv.Itoa(prefixLength),
+		strconv.FormatBool(dhcp), networkID, subnetMask, dhcpStart, dhcpEnd)
+	var created = strings.TrimSpace(cmdOut) == "True"
+	return created, err
+}
+
+// DeleteNATVirtualSwitch deletes a switch created by CreateNATVirtualSwitch,
+// with its NAT and its DHCP scope. What doesn't exist is skipped, so it also
+// cleans up after a switch that was partly created.
+func DeleteNATVirtualSwitch(switchName string, networkID string, dhcp bool) error {
+
+	var script = `
+param([string]$switchName,[string]$networkID,[string]$dhcp)
+if ($dhcp -eq "True") {
+  Remove-DhcpServerv4Scope -ScopeId $networkID -Force -ErrorAction SilentlyContinue
+}
+Get-NetNat -Name $switchName -ErrorAction SilentlyContinue | Remove-NetNat -Confirm:$false
+$switch = Hyper-V\Get-VMSwitch -Name $switchName -ErrorAction SilentlyContinue
+if ($switch -ne $null) {
+    $switch | Hyper-V\Remove-VMSwitch -Force -Confirm:$false
+}
+`
+
+	var ps powershell.PowerShellCmd
+	err := ps.Run(script, switchName, networkID, strconv.FormatBool(dhcp))
+	return err
+}
+
 func StartVirtualMachine(vmName string) error {
 
 	var script = `
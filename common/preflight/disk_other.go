@@ -0,0 +1,8 @@
+// +build !linux,!darwin,!freebsd
+
+package preflight
+
+// The free disk space isn't known on other platforms.
+func hostFreeDisk(dir string) (uint64, bool) {
+	return 0, false
+}
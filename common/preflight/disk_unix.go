@@ -0,0 +1,14 @@
+// +build linux darwin freebsd
+
+package preflight
+
+import "syscall"
+
+// hostFreeDisk is the space available in the directory, in megabytes.
+func hostFreeDisk(dir string) (uint64, bool) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return 0, false
+	}
+	return uint64(stat.Bavail) * uint64(stat.Bsize) / (1024 * 1024), true
+}
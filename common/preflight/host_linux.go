@@ -0,0 +1,64 @@
+package preflight
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// hostFreeMemory is the memory available to start programs, in megabytes.
+func hostFreeMemory() (uint64, bool) {
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "MemAvailable:" {
+			continue
+		}
+		kb, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0, false
+		}
+		return kb / 1024, true
+	}
+	return 0, false
+}
+
+// hostKVMError is why /dev/kvm can't be used, if it can't.
+func hostKVMError() error {
+	f, err := os.OpenFile("/dev/kvm", os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// hostHardwareVirt is whether the CPU has virtualization extensions.
+func hostHardwareVirt() (ok, known bool) {
+	f, err := os.Open("/proc/cpuinfo")
+	if err != nil {
+		return false, false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "flags") {
+			continue
+		}
+		for _, flag := range strings.Fields(line) {
+			if flag == "vmx" || flag == "svm" {
+				return true, true
+			}
+		}
+		return false, true
+	}
+	return false, false
+}
@@ -0,0 +1,21 @@
+// +build !linux
+
+package preflight
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// The free memory and the flags of the CPU aren't known on other platforms.
+func hostFreeMemory() (uint64, bool) {
+	return 0, false
+}
+
+func hostKVMError() error {
+	return fmt.Errorf("KVM is only available on Linux, not %s", runtime.GOOS)
+}
+
+func hostHardwareVirt() (ok, known bool) {
+	return false, false
+}
@@ -0,0 +1,152 @@
+// Package preflight checks that the host has what the build of a local
+// hypervisor needs, such as disk space, memory, and programs, before the
+// build starts. This way builds fail early with a message saying what to do
+// instead of failing on the way.
+package preflight
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+// Config is the configuration of the preflight checks of a builder.
+type Config struct {
+	// SkipPreflight skips the checks, for hosts they are wrong about.
+	SkipPreflight bool `mapstructure:"skip_preflight"`
+}
+
+// Checks are what a build needs from the host. The checks of the resources
+// the host can't tell about, like the free memory on some platforms, pass.
+type Checks struct {
+	// DiskSize is the size in megabytes of the disks the build creates,
+	// in DiskDir. It doesn't need to exist yet.
+	DiskDir  string
+	DiskSize uint64
+
+	// Memory is the memory of the VM, in megabytes.
+	Memory uint64
+
+	// KVM is whether the build needs KVM, and HardwareVirt whether it
+	// needs the virtualization extensions of the CPU.
+	KVM          bool
+	HardwareVirt bool
+
+	// Binaries are the programs the build runs.
+	Binaries []Binary
+}
+
+// Binary is a program a build runs.
+type Binary struct {
+	// Name is the program, it is looked for in the PATH unless Find is
+	// set.
+	Name string
+	Find func() (string, error)
+
+	// Hint says how to get the program.
+	Hint string
+}
+
+// For the tests, the hosts they run on can't be relied on.
+var (
+	freeDisk     = hostFreeDisk
+	freeMemory   = hostFreeMemory
+	kvmError     = hostKVMError
+	hardwareVirt = hostHardwareVirt
+)
+
+// Run runs the checks unless the configuration skips them, and returns
+// the problems it finds.
+func Run(ui packer.Ui, config *Config, checks *Checks) error {
+	if config.SkipPreflight {
+		log.Printf("[INFO] Skipping the preflight checks")
+		return nil
+	}
+
+	ui.Say("Running preflight checks...")
+	errs := checks.run()
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf(
+		"Preflight checks failed, set skip_preflight to true to skip them: %s",
+		&packer.MultiError{Errors: errs})
+}
+
+func (c *Checks) run() []error {
+	var errs []error
+
+	for _, b := range c.Binaries {
+		find := b.Find
+		if find == nil {
+			find = func() (string, error) { return exec.LookPath(b.Name) }
+		}
+		path, err := find()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s was not found: %s", b.Name, b.Hint))
+			continue
+		}
+		log.Printf("[DEBUG] preflight: %s is %s", b.Name, path)
+	}
+
+	if c.DiskSize > 0 {
+		dir := existingDir(c.DiskDir)
+		if free, ok := freeDisk(dir); ok && free < c.DiskSize {
+			errs = append(errs, fmt.Errorf(
+				"not enough disk space in %s: the disks need %d MB, %d MB are free. "+
+					"Free up space, build on a disk with more, or lower disk_size", dir, c.DiskSize, free))
+		}
+	}
+
+	if c.Memory > 0 {
+		if free, ok := freeMemory(); ok && free < c.Memory {
+			errs = append(errs, fmt.Errorf(
+				"not enough free memory: the VM needs %d MB, %d MB are available. "+
+					"Stop other VMs and programs, or lower the memory of the VM", c.Memory, free))
+		}
+	}
+
+	if c.KVM {
+		if err := kvmError(); err != nil {
+			errs = append(errs, fmt.Errorf(
+				"KVM can't be used: %s. Load the kvm module and make sure the user can open /dev/kvm, "+
+					"enable nested virtualization if the host is a VM, or set accelerator to tcg", err))
+		}
+	}
+
+	if c.HardwareVirt {
+		if ok, known := hardwareVirt(); known && !ok {
+			errs = append(errs, fmt.Errorf(
+				"the CPU has no virtualization extensions (VT-x or AMD-V), which 64-bit guests need. "+
+					"Enable them in the firmware, or nested virtualization if the host is a VM"))
+		}
+	}
+
+	return errs
+}
+
+// existingDir is the closest directory to path that exists, the directory
+// it will be created in.
+func existingDir(path string) string {
+	if path == "" {
+		path = "."
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "."
+	}
+	for {
+		if fi, err := os.Stat(path); err == nil && fi.IsDir() {
+			return path
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
+}
@@ -0,0 +1,91 @@
+package preflight
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+// testHost replaces what the checks know about the host, and returns the
+// function that restores it.
+func testHost(disk, memory uint64, kvm error, virt bool) func() {
+	oldDisk, oldMemory, oldKVM, oldVirt := freeDisk, freeMemory, kvmError, hardwareVirt
+	freeDisk = func(string) (uint64, bool) { return disk, true }
+	freeMemory = func() (uint64, bool) { return memory, true }
+	kvmError = func() error { return kvm }
+	hardwareVirt = func() (bool, bool) { return virt, true }
+	return func() {
+		freeDisk, freeMemory, kvmError, hardwareVirt = oldDisk, oldMemory, oldKVM, oldVirt
+	}
+}
+
+func TestRun(t *testing.T) {
+	defer testHost(1000, 1000, nil, true)()
+
+	checks := &Checks{
+		DiskSize:     1000,
+		Memory:       1000,
+		KVM:          true,
+		HardwareVirt: true,
+		Binaries: []Binary{
+			{Name: "found", Find: func() (string, error) { return "/bin/found", nil }},
+		},
+	}
+	if err := Run(packer.TestUi(t), new(Config), checks); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestRun_fail(t *testing.T) {
+	defer testHost(10, 10, errors.New("permission denied"), false)()
+
+	checks := &Checks{
+		DiskSize:     1000,
+		Memory:       1000,
+		KVM:          true,
+		HardwareVirt: true,
+		Binaries: []Binary{
+			{Name: "packer-test-nope", Hint: "install it"},
+		},
+	}
+	err := Run(packer.TestUi(t), new(Config), checks)
+	if err == nil {
+		t.Fatal("should fail")
+	}
+	for _, expected := range []string{
+		"skip_preflight",
+		"packer-test-nope was not found: install it",
+		"the disks need 1000 MB, 10 MB are free",
+		"the VM needs 1000 MB, 10 MB are available",
+		"KVM can't be used: permission denied",
+		"no virtualization extensions",
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Fatalf("no %q in: %s", expected, err)
+		}
+	}
+
+	if err := Run(packer.TestUi(t), &Config{SkipPreflight: true}, checks); err != nil {
+		t.Fatalf("should skip: %s", err)
+	}
+}
+
+func TestExistingDir(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	if actual := existingDir(filepath.Join(td, "output", "disks")); actual != td {
+		t.Fatalf("bad: %s", actual)
+	}
+	if actual := existingDir(td); actual != td {
+		t.Fatalf("bad: %s", actual)
+	}
+}
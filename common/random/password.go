@@ -0,0 +1,46 @@
+package random
+
+import (
+	"crypto/rand"
+	"math/big"
+)
+
+// PossibleSpecial are the special characters of passwords, ones that need no
+// quoting in XML answer files, cmd.exe, PowerShell or shell scripts.
+var PossibleSpecial = "-_.+@"
+
+// Password returns a password of the length from crypto/rand, with at least
+// one upper case letter, lower case letter, number, and special character,
+// so it meets the complexity rules of Windows and of the clouds.
+func Password(length int) string {
+	classes := []string{PossibleUpperCase, PossibleLowerCase, PossibleNumbers, PossibleSpecial}
+	if length < len(classes) {
+		length = len(classes)
+	}
+	all := PossibleAlphaNum + PossibleSpecial
+
+	password := make([]byte, length)
+	for i := range password {
+		chooseFrom := all
+		if i < len(classes) {
+			chooseFrom = classes[i]
+		}
+		password[i] = chooseFrom[cryptoIntn(len(chooseFrom))]
+	}
+
+	// Don't leave the characters of each class at the start
+	for i := len(password) - 1; i > 0; i-- {
+		j := cryptoIntn(i + 1)
+		password[i], password[j] = password[j], password[i]
+	}
+	return string(password)
+}
+
+func cryptoIntn(n int) int {
+	i, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		// The system has no source of randomness left
+		panic(err)
+	}
+	return int(i.Int64())
+}
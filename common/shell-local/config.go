@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 
 	"github.com/hashicorp/packer/common"
@@ -26,9 +27,17 @@ type Config struct {
 	// in the context of a single shell.
 	Inline []string
 
+	// Inline scripts keyed by runtime OS. The entry matching the OS Packer
+	// is running on is used as Inline; other OSs are skipped.
+	InlineByOS map[string][]string `mapstructure:"inline_by_os"`
+
 	// The shebang value used when running inline scripts.
 	InlineShebang string `mapstructure:"inline_shebang"`
 
+	// The interpreter used for scripts on Windows hosts, either "cmd" or
+	// "powershell". Defaults to "cmd".
+	WindowsInterpreter string `mapstructure:"windows_interpreter"`
+
 	// An array of multiple Runtime OSs to run on.
 	OnlyOn []string `mapstructure:"only_on"`
 
@@ -45,6 +54,10 @@ type Config struct {
 	// your command(s) are executed.
 	Vars []string `mapstructure:"environment_vars"`
 
+	// A map of environment variables that will be injected before your
+	// command(s) are executed, in addition to environment_vars.
+	Env map[string]string `mapstructure:"env"`
+
 	EnvVarFormat string `mapstructure:"env_var_format"`
 	// End dedupe with postprocessor
 
@@ -83,7 +96,31 @@ func Decode(config *Config, raws ...interface{}) error {
 func Validate(config *Config) error {
 	var errs *packer.MultiError
 
-	if runtime.GOOS == "windows" {
+	switch config.WindowsInterpreter {
+	case "":
+		config.WindowsInterpreter = "cmd"
+	case "cmd", "powershell":
+	default:
+		errs = packer.MultiErrorAppend(errs,
+			fmt.Errorf("windows_interpreter must be one of 'cmd' or 'powershell', got '%s'",
+				config.WindowsInterpreter))
+	}
+
+	if runtime.GOOS == "windows" && config.WindowsInterpreter == "powershell" {
+		if len(config.ExecuteCommand) == 0 {
+			config.ExecuteCommand = []string{
+				"powershell",
+				"-NoProfile",
+				"-ExecutionPolicy",
+				"Bypass",
+				"-Command",
+				"{{.Vars}}& '{{.Script}}'; exit $LASTEXITCODE",
+			}
+		}
+		if len(config.TempfileExtension) == 0 {
+			config.TempfileExtension = ".ps1"
+		}
+	} else if runtime.GOOS == "windows" {
 		if len(config.ExecuteCommand) == 0 {
 			config.ExecuteCommand = []string{
 				"cmd",
@@ -123,9 +160,34 @@ func Validate(config *Config) error {
 		config.Vars = make([]string, 0)
 	}
 
+	if config.Env == nil {
+		config.Env = make(map[string]string)
+	}
+
+	// Pick the inline commands for the runtime OS. Hosts without an entry
+	// are skipped at run time via only_on.
+	if len(config.InlineByOS) > 0 {
+		if config.Command != "" || len(config.Inline) != 0 ||
+			len(config.Scripts) != 0 || config.Script != "" {
+			errs = packer.MultiErrorAppend(errs,
+				errors.New("inline_by_os cannot be combined with Command, "+
+					"Inline, Script or Scripts."))
+		}
+		if len(config.OnlyOn) == 0 {
+			for goos := range config.InlineByOS {
+				config.OnlyOn = append(config.OnlyOn, goos)
+			}
+			sort.Strings(config.OnlyOn)
+		}
+		if inline, ok := config.InlineByOS[runtime.GOOS]; ok {
+			config.Inline = inline
+		}
+	}
+
 	// Verify that the user has given us a command to run
 	if config.Command == "" && len(config.Inline) == 0 &&
-		len(config.Scripts) == 0 && config.Script == "" {
+		len(config.Scripts) == 0 && config.Script == "" &&
+		len(config.InlineByOS) == 0 {
 		errs = packer.MultiErrorAppend(errs,
 			errors.New("Command, Inline, Script and Scripts options cannot all be empty."))
 	}
@@ -165,6 +227,13 @@ func Validate(config *Config) error {
 
 	// Check for properly formatted go os types
 	supportedSyslist := []string{"darwin", "freebsd", "linux", "openbsd", "solaris", "windows"}
+	for goos := range config.InlineByOS {
+		if !stringInSlice(supportedSyslist, goos) {
+			errs = packer.MultiErrorAppend(errs,
+				fmt.Errorf("Invalid OS specified in inline_by_os: '%s'\n"+
+					"Supported OS names: %s", goos, strings.Join(supportedSyslist, ", ")))
+		}
+	}
 	if len(config.OnlyOn) > 0 {
 		for _, provided_os := range config.OnlyOn {
 			supported_os := false
@@ -205,7 +274,9 @@ func Validate(config *Config) error {
 	// This is currently undocumented and not a feature users are expected to
 	// interact with.
 	if config.EnvVarFormat == "" {
-		if (runtime.GOOS == "windows") && !config.UseLinuxPathing {
+		if (runtime.GOOS == "windows") && config.WindowsInterpreter == "powershell" {
+			config.EnvVarFormat = "$env:%s='%s'; "
+		} else if (runtime.GOOS == "windows") && !config.UseLinuxPathing {
 			config.EnvVarFormat = "set %s=%s && "
 		} else {
 			config.EnvVarFormat = "%s='%s' "
@@ -227,6 +298,12 @@ func Validate(config *Config) error {
 				fmt.Errorf("Environment variable not in format 'key=value': %s", kv))
 		}
 	}
+	for k := range config.Env {
+		if k == "" || strings.Contains(k, "=") {
+			errs = packer.MultiErrorAppend(errs,
+				fmt.Errorf("Invalid environment variable name in env: '%s'", k))
+		}
+	}
 
 	if errs != nil && len(errs.Errors) > 0 {
 		return errs
@@ -235,6 +312,15 @@ func Validate(config *Config) error {
 	return nil
 }
 
+func stringInSlice(s []string, v string) bool {
+	for _, i := range s {
+		if i == v {
+			return true
+		}
+	}
+	return false
+}
+
 // C:/path/to/your/file becomes /mnt/c/path/to/your/file
 func ConvertToLinuxPath(winAbsPath string) (string, error) {
 	// get absolute path of script, and morph it into the bash path
@@ -1,6 +1,7 @@
 package shell_local
 
 import (
+	"runtime"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -14,3 +15,75 @@ func TestConvertToLinuxPath(t *testing.T) {
 		"Should have converted %s to %s -- not %s", winPath, winBashPath, converted)
 
 }
+
+func TestValidate_InlineByOS(t *testing.T) {
+	config := &Config{
+		InlineByOS: map[string][]string{
+			runtime.GOOS: {"echo foo"},
+		},
+	}
+	err := Validate(config)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"echo foo"}, config.Inline)
+	assert.Equal(t, []string{runtime.GOOS}, config.OnlyOn)
+
+	config = &Config{
+		InlineByOS: map[string][]string{
+			"plan9": {"echo foo"},
+		},
+	}
+	assert.Error(t, Validate(config), "should reject unsupported OS")
+
+	config = &Config{
+		Inline: []string{"echo bar"},
+		InlineByOS: map[string][]string{
+			runtime.GOOS: {"echo foo"},
+		},
+	}
+	assert.Error(t, Validate(config), "should reject inline_by_os with inline")
+}
+
+func TestValidate_Env(t *testing.T) {
+	config := &Config{
+		Inline: []string{"echo foo"},
+		Env:    map[string]string{"FOO": "bar"},
+	}
+	assert.NoError(t, Validate(config))
+
+	config = &Config{
+		Inline: []string{"echo foo"},
+		Env:    map[string]string{"FOO=BAR": "bar"},
+	}
+	assert.Error(t, Validate(config), "should reject bad env key")
+}
+
+func TestValidate_WindowsInterpreter(t *testing.T) {
+	config := &Config{Inline: []string{"echo foo"}}
+	assert.NoError(t, Validate(config))
+	assert.Equal(t, "cmd", config.WindowsInterpreter)
+
+	config = &Config{
+		Inline:             []string{"echo foo"},
+		WindowsInterpreter: "bash",
+	}
+	assert.Error(t, Validate(config), "should reject unknown interpreter")
+}
+
+func TestCreateFlattenedEnvVars_Env(t *testing.T) {
+	config := &Config{
+		Inline: []string{"echo foo"},
+		Vars:   []string{"A=1"},
+		Env:    map[string]string{"B": "it's"},
+	}
+	if err := Validate(config); err != nil {
+		t.Fatalf("bad: %s", err)
+	}
+	if runtime.GOOS == "windows" {
+		t.Skip("env var format differs on windows")
+	}
+	flattened, err := createFlattenedEnvVars(config, map[string]string{"A": "0", "C": "{{ it's }}"})
+	assert.NoError(t, err)
+	assert.Contains(t, flattened, "A='1' ")
+	assert.Contains(t, flattened, `B='it'"'"'s' `)
+	assert.Contains(t, flattened, `C='{{ it'"'"'s }}' `)
+}
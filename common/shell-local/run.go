@@ -28,6 +28,13 @@ type EnvVarsTemplate struct {
 }
 
 func Run(ui packer.Ui, config *Config) (bool, error) {
+	return RunWithEnv(ui, config, nil)
+}
+
+// RunWithEnv is Run, with env set in the environment of the scripts along
+// with the variables Packer always provides. The variables configured with
+// environment_vars and env take precedence.
+func RunWithEnv(ui packer.Ui, config *Config, env map[string]string) (bool, error) {
 	// Check if shell-local can even execute against this runtime OS
 	if len(config.OnlyOn) > 0 {
 		runCommand := false
@@ -65,7 +72,7 @@ func Run(ui packer.Ui, config *Config) (bool, error) {
 	}
 
 	// Create environment variables to set before executing the command
-	flattenedEnvVars, err := createFlattenedEnvVars(config)
+	flattenedEnvVars, err := createFlattenedEnvVars(config, env)
 	if err != nil {
 		return false, err
 	}
@@ -170,7 +177,7 @@ func createInterpolatedCommands(config *Config, script string, flattenedEnvVars
 	return interpolatedCmds, nil
 }
 
-func createFlattenedEnvVars(config *Config) (string, error) {
+func createFlattenedEnvVars(config *Config, extraEnv map[string]string) (string, error) {
 	flattened := ""
 	envVars := make(map[string]string)
 
@@ -196,6 +203,26 @@ func createFlattenedEnvVars(config *Config) (string, error) {
 	config.Ctx.Data = &EnvVarsTemplate{
 		WinRMPassword: getWinRMPassword(config.PackerBuildName),
 	}
+	// Powershell escapes a single quote by doubling it rather than by
+	// breaking out of the quoted string.
+	quoteEscape := `'"'"'`
+	if runtime.GOOS == "windows" && config.WindowsInterpreter == "powershell" {
+		quoteEscape = `''`
+	}
+
+	// expose the values the builder recorded about the build
+	buildEnv := commonhelper.BuildEnv(config.PackerBuildName)
+	packer.LogSecretFilter.Set(buildEnv["PACKER_BUILD_PASSWORD"])
+	for k, v := range buildEnv {
+		envVars[k] = strings.Replace(v, "'", quoteEscape, -1)
+	}
+
+	// Values from the caller aren't templates, only their quotes need
+	// escaping.
+	for k, v := range extraEnv {
+		envVars[k] = strings.Replace(v, "'", quoteEscape, -1)
+	}
+
 	// Split vars into key/value components
 	for _, envVar := range config.Vars {
 		envVar, err := interpolate.Render(envVar, &config.Ctx)
@@ -206,7 +233,15 @@ func createFlattenedEnvVars(config *Config) (string, error) {
 		keyValue := strings.SplitN(envVar, "=", 2)
 		// Store pair, replacing any single quotes in value so they parse
 		// correctly with required environment variable format
-		envVars[keyValue[0]] = strings.Replace(keyValue[1], "'", `'"'"'`, -1)
+		envVars[keyValue[0]] = strings.Replace(keyValue[1], "'", quoteEscape, -1)
+	}
+
+	for k, v := range config.Env {
+		v, err := interpolate.Render(v, &config.Ctx)
+		if err != nil {
+			return "", err
+		}
+		envVars[k] = strings.Replace(v, "'", quoteEscape, -1)
 	}
 
 	// Create a list of env var keys in sorted order
@@ -0,0 +1,50 @@
+package common
+
+import (
+	"context"
+	"log"
+
+	"github.com/hashicorp/packer/helper/communicator"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// StepCleanupTempFiles removes the temporary files of the provisioners from
+// the guest once they are done, with the cleanup command of the guest OS
+// family. Nothing is done when no family is set.
+//
+// Uses:
+//   communicator packer.Communicator
+//   ui           packer.Ui
+//
+// Produces:
+//   <nothing>
+type StepCleanupTempFiles struct {
+	Comm    *communicator.Config
+	GuestOS *GuestOSConfig
+}
+
+func (s *StepCleanupTempFiles) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	if s.Comm.Type == "none" {
+		return multistep.ActionContinue
+	}
+	command := s.GuestOS.CleanupCommand(s.Comm)
+	if command == "" {
+		return multistep.ActionContinue
+	}
+
+	comm := state.Get("communicator").(packer.Communicator)
+	ui := state.Get("ui").(packer.Ui)
+
+	// Like the removal of the temporary keys, this is cosmetic, so errors
+	// don't stop the build
+	ui.Say("Removing the temporary files of the provisioners...")
+	cmd := &packer.RemoteCmd{Command: command}
+	if err := cmd.StartWithUi(comm, ui); err != nil {
+		log.Printf("Error removing the temporary files; please clean them up manually: %s", err)
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *StepCleanupTempFiles) Cleanup(multistep.StateBag) {}
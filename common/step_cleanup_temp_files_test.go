@@ -0,0 +1,45 @@
+package common
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/packer/helper/communicator"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+func TestStepCleanupTempFiles_impl(t *testing.T) {
+	var _ multistep.Step = new(StepCleanupTempFiles)
+}
+
+func TestStepCleanupTempFiles(t *testing.T) {
+	comm := new(packer.MockCommunicator)
+	state := new(multistep.BasicStateBag)
+	state.Put("communicator", comm)
+	state.Put("ui", packer.TestUi(t))
+
+	// No family
+	commConfig := &communicator.Config{Type: "ssh", SSHUsername: "root"}
+	step := &StepCleanupTempFiles{Comm: commConfig, GuestOS: &GuestOSConfig{}}
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if comm.StartCalled {
+		t.Fatal("should not run the command")
+	}
+
+	step = &StepCleanupTempFiles{Comm: commConfig, GuestOS: &GuestOSConfig{GuestOSFamily: "linux-systemd"}}
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if comm.StartCmd.Command != "rm -rf /tmp/script_*.sh /tmp/packer-*" {
+		t.Fatalf("bad: %s", comm.StartCmd.Command)
+	}
+
+	// Failing commands don't stop the build
+	comm.StartExitStatus = 1
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+}
@@ -12,6 +12,10 @@ import (
 
 type StepCleanupTempKeys struct {
 	Comm *communicator.Config
+
+	// GuestOS gives the commands that remove the keys, the ones of Linux
+	// guests are used when it's nil or has no family.
+	GuestOS *GuestOSConfig
 }
 
 func (s *StepCleanupTempKeys) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
@@ -34,10 +38,19 @@ func (s *StepCleanupTempKeys) Run(_ context.Context, state multistep.StateBag) m
 	comm := state.Get("communicator").(packer.Communicator)
 	ui := state.Get("ui").(packer.Ui)
 
-	cmd := new(packer.RemoteCmd)
-
 	ui.Say("Trying to remove ephemeral keys from authorized_keys files")
 
+	if s.GuestOS != nil && s.GuestOS.GuestOSFamily != "" {
+		for _, command := range s.GuestOS.RemoveKeyCommands(s.Comm, s.Comm.SSHTemporaryKeyPairName) {
+			cmd := &packer.RemoteCmd{Command: command}
+			if err := cmd.StartWithUi(comm, ui); err != nil {
+				log.Printf("Error cleaning up authorized_keys; please clean up keys manually: %s", err)
+			}
+		}
+		return multistep.ActionContinue
+	}
+
+	cmd := new(packer.RemoteCmd)
 	cmd.Command = fmt.Sprintf("sed -i.bak '/ssh-rsa.*%s$/d' ~/.ssh/authorized_keys; rm ~/.ssh/authorized_keys.bak", s.Comm.SSHTemporaryKeyPairName)
 	if err := cmd.StartWithUi(comm, ui); err != nil {
 		log.Printf("Error cleaning up ~/.ssh/authorized_keys; please clean up keys manually: %s", err)
@@ -17,18 +17,21 @@ import (
 	"github.com/mitchellh/go-fs/fat"
 )
 
-// StepCreateFloppy will create a floppy disk with the given files.
+// StepCreateFloppy will create a floppy disk with the given files, and the
+// Windows answer file generated from Unattend if it is set.
 type StepCreateFloppy struct {
 	Files       []string
 	Directories []string
+	Unattend    *UnattendConfig
 
-	floppyPath string
+	floppyPath  string
+	unattendDir string
 
 	FilesAdded map[string]bool
 }
 
 func (s *StepCreateFloppy) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
-	if len(s.Files) == 0 && len(s.Directories) == 0 {
+	if len(s.Files) == 0 && len(s.Directories) == 0 && s.Unattend == nil {
 		log.Println("No floppy files specified. Floppy disk will not be made.")
 		return multistep.ActionContinue
 	}
@@ -208,6 +211,19 @@ func (s *StepCreateFloppy) Run(_ context.Context, state multistep.StateBag) mult
 	}
 	ui.Message("Done copying paths from floppy_dirs")
 
+	if s.Unattend != nil {
+		path, err := s.writeUnattend()
+		if err != nil {
+			state.Put("error", fmt.Errorf("Error creating %s: %s", UnattendFile, err))
+			return multistep.ActionHalt
+		}
+		ui.Message(fmt.Sprintf("Adding generated %s", UnattendFile))
+		if err := s.Add(cache, path); err != nil {
+			state.Put("error", fmt.Errorf("Error adding %s to floppy: %s", UnattendFile, err))
+			return multistep.ActionHalt
+		}
+	}
+
 	// Set the path to the floppy so it can be used later
 	state.Put("floppy_path", s.floppyPath)
 
@@ -298,11 +314,30 @@ func (s *StepCreateFloppy) Add(dircache directoryCache, src string) error {
 	return filepath.Walk(src, visit)
 }
 
+// writeUnattend writes the answer file to a temporary directory, under the
+// name it must have on the floppy.
+func (s *StepCreateFloppy) writeUnattend() (string, error) {
+	contents, err := s.Unattend.Render()
+	if err != nil {
+		return "", err
+	}
+
+	s.unattendDir, err = ioutil.TempDir("", "packer")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(s.unattendDir, UnattendFile)
+	return path, ioutil.WriteFile(path, contents, 0600)
+}
+
 func (s *StepCreateFloppy) Cleanup(multistep.StateBag) {
 	if s.floppyPath != "" {
 		log.Printf("Deleting floppy disk: %s", s.floppyPath)
 		os.Remove(s.floppyPath)
 	}
+	if s.unattendDir != "" {
+		os.RemoveAll(s.unattendDir)
+	}
 }
 
 // removeBase will take a regular os.PathSeparator-separated path and remove the
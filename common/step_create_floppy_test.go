@@ -116,6 +116,37 @@ func TestStepCreateFloppy(t *testing.T) {
 	}
 }
 
+func TestStepCreateFloppy_unattend(t *testing.T) {
+	state := testStepCreateFloppyState(t)
+	step := &StepCreateFloppy{
+		Unattend: &UnattendConfig{AdministratorPassword: "vagrant"},
+	}
+	step.Unattend.Prepare(nil)
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("error"); ok {
+		t.Fatalf("state should be ok")
+	}
+
+	floppyPath := state.Get("floppy_path").(string)
+	if _, err := os.Stat(floppyPath); err != nil {
+		t.Fatalf("file not found: %s for %v", floppyPath, step.Files)
+	}
+	unattendPath := filepath.Join(step.unattendDir, UnattendFile)
+	if !step.FilesAdded[unattendPath] {
+		t.Fatalf("%s not added: %v", UnattendFile, step.FilesAdded)
+	}
+
+	step.Cleanup(state)
+	if _, err := os.Stat(floppyPath); err == nil {
+		t.Fatalf("file found: %s", floppyPath)
+	}
+	if _, err := os.Stat(step.unattendDir); err == nil {
+		t.Fatalf("directory found: %s", step.unattendDir)
+	}
+}
 func xxxTestStepCreateFloppy_missing(t *testing.T) {
 	state := testStepCreateFloppyState(t)
 	step := new(StepCreateFloppy)
@@ -1,11 +1,17 @@
 package common
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha1"
 	"encoding/hex"
 	"fmt"
+	"io"
 	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/hashicorp/packer/helper/multistep"
@@ -18,13 +24,23 @@ import (
 // progress reporting, interrupt handling, etc.
 //
 // Uses:
-//   cache packer.Cache
-//   ui    packer.Ui
+//
+//	cache packer.Cache
+//	ui    packer.Ui
+//
+// Produces:
+//
+//	unverified_downloads []string - The URLs downloaded with a checksum type
+//	  of "none", appended to.
 type StepDownload struct {
 	// The checksum and the type of the checksum for the download
 	Checksum     string
 	ChecksumType string
 
+	// Size, if set, is the size in bytes the file must have. It's what
+	// files downloaded without a checksum are checked with.
+	Size int64
+
 	// A short description of the type of download being done. Example:
 	// "ISO" or "Guest Additions"
 	Description string
@@ -45,6 +61,31 @@ type StepDownload struct {
 	// extension on the URL is used. Otherwise, this will be forced
 	// on the downloaded file for every URL.
 	Extension string
+
+	// Extract, if true, extracts the file of the zip and tar.gz archives
+	// that are downloaded, and the file is the result. The checksum can be
+	// the one of the archive or the one of the file.
+	Extract bool
+
+	// Force, if true, downloads the file even if it is in the cache, as
+	// set by -force-download.
+	Force bool
+
+	// Offline, if true, only uses the files in the cache and the local
+	// ones, and fails instead of downloading the file, as set by -offline.
+	Offline bool
+}
+
+// DownloadStep is a step that downloads files. The runner sets its download
+// mode from -force-download and -offline.
+type DownloadStep interface {
+	SetDownloadMode(force, offline bool)
+}
+
+// SetDownloadMode sets Force and Offline from -force-download and -offline.
+func (s *StepDownload) SetDownloadMode(force, offline bool) {
+	s.Force = force
+	s.Offline = offline
 }
 
 func (s *StepDownload) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
@@ -67,9 +108,77 @@ func (s *StepDownload) Run(_ context.Context, state multistep.StateBag) multiste
 	// If it fails, proceed to regular download logic
 
 	var downloadConfigs = make([]*DownloadConfig, len(s.Url))
-	var finalPath string
+	var extractPaths = make([]string, len(s.Url))
+	var missReason string
+	var finalPath, finalURL string
+	if s.Force {
+		missReason = downloadForced
+	}
 	for i, url := range s.Url {
 		targetPath := s.TargetPath
+
+		// The name of the file decides where it goes when the target is a
+		// directory, and the extension it has in the cache when the URL
+		// doesn't say what the file is, like "download?id=123".
+		var name string
+		if isDirTarget(targetPath) {
+			name = s.filename(url)
+			targetPath = filepath.Join(targetPath, name)
+		} else if targetPath == "" && s.Extension == "" && !hasFilename(url) {
+			name = s.filename(url)
+		}
+
+		kind := archiveType(url)
+		if kind == "" && name != "" {
+			kind = archiveType(name)
+		}
+		if s.Extract && kind != "" {
+			// The archive is downloaded next to the file it is extracted
+			// to, which has the extension to force.
+			extractPath := targetPath
+			if extractPath == "" {
+				hash := sha1.Sum([]byte(url))
+				cacheKey := hex.EncodeToString(hash[:])
+				if s.Extension != "" {
+					cacheKey = fmt.Sprintf("%s.%s", cacheKey, s.Extension)
+				}
+
+				log.Printf("Acquiring lock to download: %s", url)
+				extractPath = cache.Lock(cacheKey)
+				defer cache.Unlock(cacheKey)
+			}
+			extractPaths[i] = extractPath
+
+			downloadConfigs[i] = &DownloadConfig{
+				Url:        url,
+				TargetPath: extractPath + "." + kind,
+				CopyFile:   false,
+				UserAgent:  useragent.String(),
+			}
+			if s.Force {
+				continue
+			}
+			if hit, _ := s.lookup(extractPath, checksum); hit {
+				s.reportHit(ui, url, extractPath)
+				finalPath = extractPath
+				finalURL = url
+				break
+			}
+			hit, reason := s.lookup(downloadConfigs[i].TargetPath, checksum)
+			if hit {
+				if path, err := s.extract(ui, downloadConfigs[i], downloadConfigs[i].TargetPath, extractPath, checksum); err == nil {
+					s.reportHit(ui, url, downloadConfigs[i].TargetPath)
+					finalPath = path
+					finalURL = url
+					break
+				}
+				reason = downloadChecksumMismatch
+			}
+			if i == 0 {
+				missReason = reason
+			}
+			continue
+		}
 		if targetPath == "" {
 			// Determine a cache key. This is normally just the URL but
 			// if we force a certain extension we hash the URL and add
@@ -79,6 +188,9 @@ func (s *StepDownload) Run(_ context.Context, state multistep.StateBag) multiste
 				hash := sha1.Sum([]byte(url))
 				cacheKey = fmt.Sprintf(
 					"%s.%s", hex.EncodeToString(hash[:]), s.Extension)
+			} else if ext := path.Ext(name); ext != "" {
+				hash := sha1.Sum([]byte(url))
+				cacheKey = hex.EncodeToString(hash[:]) + ext
 			}
 
 			log.Printf("Acquiring lock to download: %s", url)
@@ -95,19 +207,53 @@ func (s *StepDownload) Run(_ context.Context, state multistep.StateBag) multiste
 			UserAgent:  useragent.String(),
 		}
 		downloadConfigs[i] = config
+		if s.Force {
+			continue
+		}
 
-		if match, _ := NewDownloadClient(config, ui).VerifyChecksum(config.TargetPath); match {
-			ui.Message(fmt.Sprintf("Found already downloaded, initial checksum matched, no download needed: %s", url))
+		hit, reason := s.lookup(config.TargetPath, checksum)
+		if hit {
+			s.reportHit(ui, url, config.TargetPath)
 			finalPath = config.TargetPath
+			finalURL = url
 			break
 		}
+		if i == 0 {
+			missReason = reason
+		}
+	}
+
+	if finalPath == "" && s.Offline {
+		// Only the local files can be used
+		for i, u := range s.Url {
+			if !isLocalURL(u) {
+				downloadConfigs[i] = nil
+			}
+		}
+	}
+
+	if finalPath == "" && len(s.Url) > 0 {
+		s.reportMiss(ui, s.Url[0], missReason)
 	}
 
 	if finalPath == "" {
 		for i := range s.Url {
 			config := downloadConfigs[i]
+			if config == nil {
+				continue
+			}
+			if s.Force {
+				// The download client reuses the file if it matches
+				os.Remove(config.TargetPath)
+			}
 
 			path, err, retry := s.download(config, state)
+			if err == nil && retry {
+				err = s.checkSize(path)
+			}
+			if err == nil && retry && extractPaths[i] != "" {
+				path, err = s.extract(ui, config, path, extractPaths[i], checksum)
+			}
 			if err != nil {
 				ui.Message(fmt.Sprintf("Error downloading: %s", err))
 			}
@@ -118,11 +264,24 @@ func (s *StepDownload) Run(_ context.Context, state multistep.StateBag) multiste
 
 			if err == nil {
 				finalPath = path
+				finalURL = s.Url[i]
 				break
 			}
+			if i < len(s.Url)-1 {
+				ui.Machine("retry", "download", s.Url[i+1])
+			}
 		}
 	}
 
+	if finalPath == "" && s.Offline {
+		err := fmt.Errorf(
+			"The %s isn't in the cache, and can't be downloaded in offline mode: %s",
+			s.Description, strings.Join(s.Url, ", "))
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
 	if finalPath == "" {
 		err := fmt.Errorf("%s download failed.", s.Description)
 		state.Put("error", err)
@@ -130,12 +289,149 @@ func (s *StepDownload) Run(_ context.Context, state multistep.StateBag) multiste
 		return multistep.ActionHalt
 	}
 
+	if s.ChecksumType == "none" {
+		s.reportUnverified(ui, state, finalURL, finalPath)
+	}
+
 	state.Put(s.ResultKey, finalPath)
 	return multistep.ActionContinue
 }
 
 func (s *StepDownload) Cleanup(multistep.StateBag) {}
 
+// The reasons a file is downloaded instead of reusing the one in the cache,
+// in the "download-cache" machine-readable messages.
+const (
+	downloadNotCached        = "not-cached"
+	downloadChecksumMismatch = "checksum-mismatch"
+	downloadNoChecksum       = "no-checksum"
+	downloadForced           = "forced"
+	downloadSizeMismatch     = "size-mismatch"
+)
+
+var downloadReasons = map[string]string{
+	downloadNotCached:        "the file isn't in the cache yet",
+	downloadChecksumMismatch: "the checksum of the cached file doesn't match",
+	downloadNoChecksum:       "there is no checksum to check the cached file with",
+	downloadForced:           "-force-download is set",
+	downloadSizeMismatch:     "the size of the cached file doesn't match",
+}
+
+// lookup says whether the file at path can be used instead of downloading
+// it, and if not, why.
+// Without a checksum, the file is used if it has the size to check it with,
+// or in offline mode.
+func (s *StepDownload) lookup(path string, checksum []byte) (bool, string) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return false, downloadNotCached
+	}
+	if checksum == nil || HashForType(s.ChecksumType) == nil {
+		if s.Size > 0 {
+			if fi.Size() != s.Size {
+				return false, downloadSizeMismatch
+			}
+			return true, ""
+		}
+		return s.Offline, downloadNoChecksum
+	}
+	if !s.verify(path, checksum) {
+		return false, downloadChecksumMismatch
+	}
+	return true, ""
+}
+
+func (s *StepDownload) reportHit(ui packer.Ui, url, path string) {
+	if s.Checksum == "" || HashForType(s.ChecksumType) == nil {
+		ui.Message(fmt.Sprintf("Using the cached %s, without a checksum to check it: %s", s.Description, path))
+	} else {
+		ui.Message(fmt.Sprintf("Using the cached %s, its checksum matched: %s", s.Description, path))
+	}
+	ui.Machine("download-cache", "hit", url)
+}
+
+// checkSize checks that the downloaded file has the size it must have.
+func (s *StepDownload) checkSize(path string) error {
+	if s.Size <= 0 {
+		return nil
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if fi.Size() != s.Size {
+		return fmt.Errorf("the file is %d bytes, expected %d", fi.Size(), s.Size)
+	}
+	return nil
+}
+
+// reportUnverified warns that the file wasn't verified with a checksum, and
+// records it so the artifact, and the manifest, say it.
+func (s *StepDownload) reportUnverified(ui packer.Ui, state multistep.StateBag, url, path string) {
+	checked := "the size the server announced"
+	if s.Size > 0 {
+		checked = fmt.Sprintf("its size of %d bytes", s.Size)
+	}
+	ui.Error(fmt.Sprintf(
+		"Warning: the %s wasn't verified with a checksum, the checksum type is none.\n"+
+			"Only %s was checked: %s", s.Description, checked, path))
+
+	var urls []string
+	if v, ok := state.GetOk("unverified_downloads"); ok {
+		urls = v.([]string)
+	}
+	ui.Machine("download-unverified", url)
+	state.Put("unverified_downloads", append(urls, url))
+}
+
+func (s *StepDownload) reportMiss(ui packer.Ui, url, reason string) {
+	ui.Message(fmt.Sprintf("Downloading %s, %s", s.Description, downloadReasons[reason]))
+	ui.Machine("download-cache", "miss", reason, url)
+}
+
+// verify says whether the file at path has the checksum.
+func (s *StepDownload) verify(path string, checksum []byte) bool {
+	h := HashForType(s.ChecksumType)
+	if checksum == nil || h == nil {
+		return false
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	log.Printf("Verifying checksum of %s", path)
+	if _, err := io.Copy(h, f); err != nil {
+		return false
+	}
+	return bytes.Equal(h.Sum(nil), checksum)
+}
+
+// extract extracts the downloaded archive to dst, and checks that the
+// archive or its file has the checksum.
+func (s *StepDownload) extract(
+	ui packer.Ui, config *DownloadConfig, archive, dst string, checksum []byte) (string, error) {
+	archiveMatch := s.verify(archive, checksum)
+
+	ui.Message(fmt.Sprintf("Extracting: %s", config.Url))
+	if err := extractArchive(archive, dst); err != nil {
+		return "", fmt.Errorf("Error extracting %s: %s", archive, err)
+	}
+
+	if checksum != nil && !archiveMatch && !s.verify(dst, checksum) {
+		// Only delete the archive if we made a copy or downloaded it
+		if config.CopyFile {
+			os.Remove(archive)
+		}
+		os.Remove(dst)
+		return "", fmt.Errorf(
+			"checksums of the archive and of its file didn't match expected: %s",
+			hex.EncodeToString(checksum))
+	}
+	return dst, nil
+}
+
 func (s *StepDownload) download(config *DownloadConfig, state multistep.StateBag) (string, error, bool) {
 	var path string
 	ui := state.Get("ui").(packer.Ui)
@@ -173,3 +469,22 @@ func (s *StepDownload) download(config *DownloadConfig, state multistep.StateBag
 		}
 	}
 }
+
+// filename is the name of the file at the URL, as the server names it. It's
+// the last segment of the URL in offline mode, or if the server can't say.
+func (s *StepDownload) filename(url string) string {
+	if !s.Offline && !isLocalURL(url) {
+		name, err := RemoteFilename(url, useragent.String())
+		if err == nil && name != "" {
+			log.Printf("The file at %s is named %s", url, name)
+			return name
+		}
+		log.Printf("Error getting the name of the file at %s: %s", url, err)
+	}
+
+	if name := urlBase(url); name != "" {
+		return name
+	}
+	hash := sha1.Sum([]byte(url))
+	return hex.EncodeToString(hash[:])
+}
@@ -1,9 +1,20 @@
 package common
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
 )
 
 func TestStepDownload_Impl(t *testing.T) {
@@ -13,3 +24,289 @@ func TestStepDownload_Impl(t *testing.T) {
 		t.Fatalf("download should be a step")
 	}
 }
+
+func TestStepDownload_extract(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	archive := filepath.Join(td, "image.zip")
+	writeZip(t, archive, map[string]string{"image.iso": "image"})
+	contents, err := ioutil.ReadFile(archive)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	archiveSum := sha256.Sum256(contents)
+	imageSum := sha256.Sum256([]byte("image"))
+
+	cases := []struct {
+		Checksum string
+		Action   multistep.StepAction
+	}{
+		{hex.EncodeToString(archiveSum[:]), multistep.ActionContinue},
+		{hex.EncodeToString(imageSum[:]), multistep.ActionContinue},
+		{hex.EncodeToString(make([]byte, sha256.Size)), multistep.ActionHalt},
+	}
+	for _, tc := range cases {
+		state := new(multistep.BasicStateBag)
+		state.Put("cache", &packer.FileCache{CacheDir: filepath.Join(td, "cache")})
+		state.Put("ui", packer.TestUi(t))
+
+		step := &StepDownload{
+			Checksum:     tc.Checksum,
+			ChecksumType: "sha256",
+			Description:  "ISO",
+			Extension:    "iso",
+			Extract:      true,
+			ResultKey:    "iso_path",
+			Url:          []string{"file://" + filepath.ToSlash(archive)},
+		}
+		if action := step.Run(context.Background(), state); action != tc.Action {
+			t.Fatalf("%s: bad: %#v %v", tc.Checksum, action, state.Get("error"))
+		}
+		if tc.Action == multistep.ActionHalt {
+			continue
+		}
+
+		path := state.Get("iso_path").(string)
+		if filepath.Ext(path) != ".iso" {
+			t.Fatalf("bad: %s", path)
+		}
+		if result, _ := ioutil.ReadFile(path); string(result) != "image" {
+			t.Fatalf("bad: %q", result)
+		}
+		os.Remove(path)
+	}
+}
+
+func TestStepDownload_cacheDecisions(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("image"))
+	}))
+	defer server.Close()
+	sum := sha256.Sum256([]byte("image"))
+	cache := &packer.FileCache{CacheDir: filepath.Join(td, "cache")}
+
+	run := func(checksumType, checksum string) string {
+		var out bytes.Buffer
+		state := new(multistep.BasicStateBag)
+		state.Put("cache", cache)
+		state.Put("ui", &packer.MachineReadableUi{Writer: &out})
+
+		step := &StepDownload{
+			Checksum:     checksum,
+			ChecksumType: checksumType,
+			Description:  "ISO",
+			ResultKey:    "iso_path",
+			Url:          []string{server.URL + "/image.iso"},
+		}
+		step.Run(context.Background(), state)
+		return out.String()
+	}
+
+	cases := []struct {
+		ChecksumType string
+		Checksum     string
+		Expected     string
+	}{
+		{"sha256", hex.EncodeToString(sum[:]), "download-cache,miss,not-cached"},
+		{"sha256", hex.EncodeToString(sum[:]), "download-cache,hit"},
+		{"none", "", "download-cache,miss,no-checksum"},
+		{"sha256", hex.EncodeToString(make([]byte, sha256.Size)), "download-cache,miss,checksum-mismatch"},
+	}
+	for i, tc := range cases {
+		if out := run(tc.ChecksumType, tc.Checksum); !strings.Contains(out, tc.Expected) {
+			t.Fatalf("%d: bad: %s", i, out)
+		}
+	}
+}
+
+func TestStepDownload_downloadMode(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			requests++
+		}
+		w.Write([]byte("image"))
+	}))
+	defer server.Close()
+	sum := sha256.Sum256([]byte("image"))
+	cache := &packer.FileCache{CacheDir: filepath.Join(td, "cache")}
+
+	run := func(url string, force, offline bool) (multistep.StepAction, string) {
+		var out bytes.Buffer
+		state := new(multistep.BasicStateBag)
+		state.Put("cache", cache)
+		state.Put("ui", &packer.MachineReadableUi{Writer: &out})
+
+		step := &StepDownload{
+			Checksum:     hex.EncodeToString(sum[:]),
+			ChecksumType: "sha256",
+			Description:  "ISO",
+			ResultKey:    "iso_path",
+			Url:          []string{url},
+		}
+		var _ DownloadStep = step
+		step.SetDownloadMode(force, offline)
+		action := step.Run(context.Background(), state)
+		return action, out.String()
+	}
+
+	// Offline, nothing is in the cache yet
+	if action, out := run(server.URL+"/image.iso", false, true); action != multistep.ActionHalt || requests != 0 {
+		t.Fatalf("bad: %d %s", requests, out)
+	}
+
+	// Offline, local files are used in place
+	local := filepath.Join(td, "image.iso")
+	if err := ioutil.WriteFile(local, []byte("image"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if action, out := run("file://"+filepath.ToSlash(local), false, true); action != multistep.ActionContinue {
+		t.Fatalf("bad: %s", out)
+	}
+
+	if action, out := run(server.URL+"/image.iso", false, false); action != multistep.ActionContinue || requests != 1 {
+		t.Fatalf("bad: %d %s", requests, out)
+	}
+
+	// Offline, the cached file is used
+	if action, out := run(server.URL+"/image.iso", false, true); action != multistep.ActionContinue || requests != 1 {
+		t.Fatalf("bad: %d %s", requests, out)
+	}
+
+	// Forced, the cached file is downloaded again
+	action, out := run(server.URL+"/image.iso", true, false)
+	if action != multistep.ActionContinue || requests != 2 {
+		t.Fatalf("bad: %d %s", requests, out)
+	}
+	if !strings.Contains(out, "download-cache,miss,forced") {
+		t.Fatalf("bad: %s", out)
+	}
+}
+
+func TestStepDownload_noneChecksum(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			requests++
+		}
+		w.Write([]byte("image"))
+	}))
+	defer server.Close()
+	cache := &packer.FileCache{CacheDir: filepath.Join(td, "cache")}
+
+	run := func(size int64) (multistep.StepAction, multistep.StateBag, string) {
+		var out bytes.Buffer
+		state := new(multistep.BasicStateBag)
+		state.Put("cache", cache)
+		state.Put("ui", &packer.MachineReadableUi{Writer: &out})
+
+		step := &StepDownload{
+			ChecksumType: "none",
+			Size:         size,
+			Description:  "ISO",
+			ResultKey:    "iso_path",
+			Url:          []string{server.URL + "/image.iso"},
+		}
+		action := step.Run(context.Background(), state)
+		return action, state, out.String()
+	}
+
+	action, state, out := run(5)
+	if action != multistep.ActionContinue || requests != 1 {
+		t.Fatalf("bad: %d %s", requests, out)
+	}
+	if !strings.Contains(out, "download-unverified,"+server.URL+"/image.iso") {
+		t.Fatalf("bad: %s", out)
+	}
+	urls, _ := state.Get("unverified_downloads").([]string)
+	if len(urls) != 1 || urls[0] != server.URL+"/image.iso" {
+		t.Fatalf("bad: %#v", urls)
+	}
+
+	// The cached file has the size, it's used
+	if action, _, out := run(5); action != multistep.ActionContinue || requests != 1 {
+		t.Fatalf("bad: %d %s", requests, out)
+	}
+
+	// The cached file doesn't have the size, and neither does the download
+	action, _, out = run(6)
+	if action != multistep.ActionHalt || requests != 2 {
+		t.Fatalf("bad: %d %s", requests, out)
+	}
+	if !strings.Contains(out, "download-cache,miss,size-mismatch") {
+		t.Fatalf("bad: %s", out)
+	}
+}
+
+func TestStepDownload_filename(t *testing.T) {
+	td, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(td)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/download" {
+			http.Redirect(w, r, "/releases/os-1.2.iso", http.StatusFound)
+			return
+		}
+		w.Write([]byte("image"))
+	}))
+	defer server.Close()
+	sum := sha256.Sum256([]byte("image"))
+
+	run := func(targetPath string) string {
+		state := new(multistep.BasicStateBag)
+		state.Put("cache", &packer.FileCache{CacheDir: filepath.Join(td, "cache")})
+		state.Put("ui", packer.TestUi(t))
+
+		step := &StepDownload{
+			Checksum:     hex.EncodeToString(sum[:]),
+			ChecksumType: "sha256",
+			Description:  "ISO",
+			ResultKey:    "iso_path",
+			TargetPath:   targetPath,
+			Url:          []string{server.URL + "/download?id=123"},
+		}
+		if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+			t.Fatalf("bad action: %#v", action)
+		}
+		return state.Get("iso_path").(string)
+	}
+
+	// The cache has the extension of the file redirected to
+	if path := run(""); filepath.Ext(path) != ".iso" {
+		t.Fatalf("bad: %s", path)
+	}
+
+	// The file goes in the directory, with the name of the file redirected to
+	dir := filepath.Join(td, "isos")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if path := run(dir); path != filepath.Join(dir, "os-1.2.iso") {
+		t.Fatalf("bad: %s", path)
+	}
+}
@@ -0,0 +1,170 @@
+package common
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	commonhelper "github.com/hashicorp/packer/helper/common"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+// StepHook waits, and runs commands on the host, before or after a step of
+// the builder, like after the instance is created, so templates can work
+// around what a builder doesn't do without forking it. The step is named
+// like its type, StepCreateInstance, or step_create_instance.
+type StepHook struct {
+	Before string `mapstructure:"before"`
+	After  string `mapstructure:"after"`
+
+	// Wait is how long to wait before the commands.
+	Wait   time.Duration `mapstructure:"wait"`
+	Inline []string      `mapstructure:"inline"`
+}
+
+// stepNameMatches says whether the step of the type name is the one of the
+// name.
+func stepNameMatches(typeName, name string) bool {
+	return strings.EqualFold(typeName, strings.Replace(name, "_", "", -1))
+}
+
+// addStepHooks wraps the steps that have hooks, and returns warnings about
+// the hooks no step has and the ones that aren't valid.
+func addStepHooks(steps []multistep.Step, config PackerConfig, ui packer.Ui) []string {
+	var warnings []string
+	used := make([]bool, len(config.StepHooks))
+	for i, step := range steps {
+		hooked := &hookedStep{step: step, config: config, ui: ui}
+		for j, hook := range config.StepHooks {
+			if hook.Before != "" && stepNameMatches(typeName(step), hook.Before) {
+				hooked.before = append(hooked.before, hook)
+				used[j] = true
+			}
+			if hook.After != "" && stepNameMatches(typeName(step), hook.After) {
+				hooked.after = append(hooked.after, hook)
+				used[j] = true
+			}
+		}
+		if len(hooked.before) > 0 || len(hooked.after) > 0 {
+			steps[i] = hooked
+		}
+	}
+
+	for j, hook := range config.StepHooks {
+		switch {
+		case hook.Before == "" && hook.After == "":
+			warnings = append(warnings, fmt.Sprintf("step hook %d has neither before nor after", j+1))
+		case !used[j]:
+			warnings = append(warnings, fmt.Sprintf("there is no step %q for step hook %d", hook.Before+hook.After, j+1))
+		}
+	}
+	return warnings
+}
+
+type hookedStep struct {
+	step   multistep.Step
+	before []StepHook
+	after  []StepHook
+	config PackerConfig
+	ui     packer.Ui
+}
+
+func (s *hookedStep) InnerStepName() string {
+	return typeName(s.step)
+}
+
+func (s *hookedStep) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	if err := s.runHooks(ctx, "before", s.before); err != nil {
+		state.Put("error", err)
+		s.ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	action := s.step.Run(ctx, state)
+	if action != multistep.ActionContinue {
+		return action
+	}
+
+	if err := s.runHooks(ctx, "after", s.after); err != nil {
+		state.Put("error", err)
+		s.ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	return action
+}
+
+func (s *hookedStep) Cleanup(state multistep.StateBag) {
+	s.step.Cleanup(state)
+}
+
+func (s *hookedStep) runHooks(ctx context.Context, when string, hooks []StepHook) error {
+	name := typeName(s.step)
+	for _, hook := range hooks {
+		s.ui.Say(fmt.Sprintf("Running the step hook %s %s...", when, name))
+		start := time.Now()
+
+		if hook.Wait > 0 {
+			s.ui.Message(fmt.Sprintf("Waiting %s...", hook.Wait))
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("Step hook %s %s cancelled", when, name)
+			case <-time.After(hook.Wait):
+			}
+		}
+
+		env := []string{
+			"PACKER_BUILD_NAME=" + s.config.PackerBuildName,
+			"PACKER_BUILDER_TYPE=" + s.config.PackerBuilderType,
+			"PACKER_STEP=" + name,
+			"PACKER_STEP_HOOK=" + when,
+		}
+		for k, v := range commonhelper.BuildEnv(s.config.PackerBuildName) {
+			env = append(env, k+"="+v)
+		}
+
+		// Calls like {{build `ID`}} are only known now
+		ictx := &interpolate.Context{
+			BuildName: s.config.PackerBuildName,
+			BuildType: s.config.PackerBuilderType,
+		}
+		for _, raw := range hook.Inline {
+			command, err := interpolate.Render(raw, ictx)
+			if err != nil {
+				return fmt.Errorf("Error interpolating the step hook %s %s: %s", when, name, err)
+			}
+			if err := runHookCommand(ctx, s.ui, command, env); err != nil {
+				return fmt.Errorf("Error running the step hook %s %s: %s", when, name, err)
+			}
+		}
+
+		log.Printf("The step hook %s %s ran in %s", when, name, time.Since(start))
+	}
+	return nil
+}
+
+func runHookCommand(ctx context.Context, ui packer.Ui, command string, env []string) error {
+	shell := []string{"/bin/sh", "-c"}
+	if runtime.GOOS == "windows" {
+		shell = []string{"cmd", "/C"}
+	}
+
+	ui.Message(fmt.Sprintf("Executing: %s", command))
+	cmd := exec.CommandContext(ctx, shell[0], append(shell[1:], command)...)
+	cmd.Env = append(os.Environ(), env...)
+	output, err := cmd.CombinedOutput()
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		ui.Message(scanner.Text())
+	}
+	return err
+}
@@ -0,0 +1,129 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+type StepHookTest struct {
+	action multistep.StepAction
+	log    string
+}
+
+func (s *StepHookTest) Run(context.Context, multistep.StateBag) multistep.StepAction {
+	f, _ := os.OpenFile(s.log, os.O_APPEND|os.O_WRONLY, 0600)
+	f.WriteString("step\n")
+	f.Close()
+	return s.action
+}
+
+func (s *StepHookTest) Cleanup(multistep.StateBag) {}
+
+func TestStepNameMatches(t *testing.T) {
+	cases := []struct {
+		Name     string
+		Expected bool
+	}{
+		{"StepCreateInstance", true},
+		{"step_create_instance", true},
+		{"stepcreateinstance", true},
+		{"StepCreate", false},
+	}
+	for _, tc := range cases {
+		if actual := stepNameMatches("StepCreateInstance", tc.Name); actual != tc.Expected {
+			t.Fatalf("%s: expected %t", tc.Name, tc.Expected)
+		}
+	}
+}
+
+func TestAddStepHooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the hooks run sh commands")
+	}
+
+	dir, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	log := filepath.Join(dir, "log")
+	ioutil.WriteFile(log, nil, 0600)
+
+	config := PackerConfig{
+		PackerBuildName: "foo",
+		StepHooks: []StepHook{
+			{Before: "step_hook_test", Inline: []string{"echo before-$PACKER_STEP >> " + log}},
+			{After: "StepHookTest", Inline: []string{"echo after-{{build `Name`}} >> " + log}},
+			{After: "StepNope"},
+			{Inline: []string{"true"}},
+		},
+	}
+	ui := &packer.BasicUi{Reader: new(bytes.Buffer), Writer: new(bytes.Buffer)}
+	steps := []multistep.Step{&StepHookTest{action: multistep.ActionContinue, log: log}}
+
+	warnings := addStepHooks(steps, config, ui)
+	if len(warnings) != 2 || !strings.Contains(warnings[0], "StepNope") {
+		t.Fatalf("bad: %#v", warnings)
+	}
+
+	state := new(multistep.BasicStateBag)
+	if action := steps[0].Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	output, _ := ioutil.ReadFile(log)
+	if string(output) != "before-StepHookTest\nstep\nafter-foo\n" {
+		t.Fatalf("bad: %q", output)
+	}
+	if typeName(steps[0]) != "StepHookTest" {
+		t.Fatalf("bad: %s", typeName(steps[0]))
+	}
+}
+
+func TestAddStepHooks_halt(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the hooks run sh commands")
+	}
+
+	config := PackerConfig{
+		StepHooks: []StepHook{
+			{After: "StepHookTest", Inline: []string{"exit 1"}},
+		},
+	}
+	ui := &packer.BasicUi{Reader: new(bytes.Buffer), Writer: new(bytes.Buffer)}
+
+	// The hook after a step that halted doesn't run
+	log, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	log.Close()
+	defer os.Remove(log.Name())
+	steps := []multistep.Step{&StepHookTest{action: multistep.ActionHalt, log: log.Name()}}
+	addStepHooks(steps, config, ui)
+	state := new(multistep.BasicStateBag)
+	if action := steps[0].Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("error"); ok {
+		t.Fatal("the hook should not run")
+	}
+
+	// A hook that fails halts the build
+	steps = []multistep.Step{&StepHookTest{action: multistep.ActionContinue, log: log.Name()}}
+	addStepHooks(steps, config, ui)
+	if action := steps[0].Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("error"); !ok {
+		t.Fatal("should have an error")
+	}
+}
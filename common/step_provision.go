@@ -3,7 +3,6 @@ package common
 import (
 	"context"
 	"log"
-	"time"
 
 	"github.com/hashicorp/packer/helper/multistep"
 	"github.com/hashicorp/packer/packer"
@@ -18,11 +17,14 @@ import (
 //
 // Produces:
 //   <nothing>
+//
+// With -on-error=ask, "provision_start" can hold the index of the
+// provisioner to resume from when the step is retried.
 type StepProvision struct {
 	Comm packer.Communicator
 }
 
-func (s *StepProvision) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+func (s *StepProvision) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
 	comm := s.Comm
 	if comm == nil {
 		raw, ok := state.Get("communicator").(packer.Communicator)
@@ -33,30 +35,32 @@ func (s *StepProvision) Run(_ context.Context, state multistep.StateBag) multist
 	hook := state.Get("hook").(packer.Hook)
 	ui := state.Get("ui").(packer.Ui)
 
-	// Run the provisioner in a goroutine so we can continually check
-	// for cancellations...
+	// Run the provisioner in a goroutine so it can be cancelled with the
+	// build
+	var data interface{}
+	if start, ok := state.GetOk(stateProvisionStart); ok {
+		data = start
+		state.Remove(stateProvisionStart)
+	}
+
 	log.Println("Running the provision hook")
 	errCh := make(chan error, 1)
 	go func() {
-		errCh <- hook.Run(packer.HookProvision, ui, comm, nil)
+		errCh <- hook.Run(packer.HookProvision, ui, comm, data)
 	}()
 
-	for {
-		select {
-		case err := <-errCh:
-			if err != nil {
-				state.Put("error", err)
-				return multistep.ActionHalt
-			}
-
-			return multistep.ActionContinue
-		case <-time.After(1 * time.Second):
-			if _, ok := state.GetOk(multistep.StateCancelled); ok {
-				log.Println("Cancelling provisioning due to interrupt...")
-				hook.Cancel()
-				return multistep.ActionHalt
-			}
+	select {
+	case err := <-errCh:
+		if err != nil {
+			state.Put("error", err)
+			return multistep.ActionHalt
 		}
+
+		return multistep.ActionContinue
+	case <-ctx.Done():
+		log.Println("Cancelling provisioning due to interrupt...")
+		hook.Cancel()
+		return multistep.ActionHalt
 	}
 }
 
@@ -1,9 +1,12 @@
 package common
 
 import (
+	"bytes"
+	"context"
 	"testing"
 
 	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
 )
 
 func TestStepProvision_Impl(t *testing.T) {
@@ -13,3 +16,29 @@ func TestStepProvision_Impl(t *testing.T) {
 		t.Fatalf("provision should be a step")
 	}
 }
+
+func TestStepProvision_cancel(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	hook := &packer.MockHook{RunFunc: func() error {
+		<-block
+		return nil
+	}}
+
+	state := new(multistep.BasicStateBag)
+	state.Put("hook", hook)
+	state.Put("ui", &packer.BasicUi{
+		Reader: new(bytes.Buffer),
+		Writer: new(bytes.Buffer),
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	step := new(StepProvision)
+	if action := step.Run(ctx, state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if !hook.CancelCalled {
+		t.Fatal("the hook should be cancelled")
+	}
+}
@@ -0,0 +1,62 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// StepScreenshotOnFailure saves a screenshot of the console of the VM when
+// the build fails before the communicator connects, such as when the boot
+// command doesn't get the installer going. It goes right after the step
+// that starts the VM, so that its cleanup runs while the VM still does.
+//
+// Uses:
+//   ui packer.Ui
+//   error error
+//   communicator packer.Communicator - The screenshot is only taken when
+//     the build didn't connect.
+type StepScreenshotOnFailure struct {
+	// BuildName names the screenshot, packer-BUILDNAME-failure.png in the
+	// current directory.
+	BuildName string
+
+	// Screenshot saves the screen of the VM to the path, as a PNG image.
+	// Nothing is saved when it is nil, like when the console can't be
+	// reached.
+	Screenshot func(state multistep.StateBag, path string) error
+}
+
+func (s *StepScreenshotOnFailure) Run(context.Context, multistep.StateBag) multistep.StepAction {
+	return multistep.ActionContinue
+}
+
+func (s *StepScreenshotOnFailure) Cleanup(state multistep.StateBag) {
+	if s.Screenshot == nil {
+		return
+	}
+	if _, ok := state.GetOk("error"); !ok {
+		return
+	}
+	if _, ok := state.GetOk("communicator"); ok {
+		return
+	}
+
+	ui := state.Get("ui").(packer.Ui)
+	path, err := filepath.Abs(fmt.Sprintf("packer-%s-failure.png", s.BuildName))
+	if err != nil {
+		log.Printf("Error finding the screenshot path: %s", err)
+		return
+	}
+
+	log.Printf("Saving a screenshot of the console to %s", path)
+	if err := s.Screenshot(state, path); err != nil {
+		ui.Error(fmt.Sprintf("Error saving a screenshot of the console: %s", err))
+		return
+	}
+	ui.Say(fmt.Sprintf("Saved a screenshot of the console to %s", path))
+}
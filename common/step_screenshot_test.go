@@ -0,0 +1,66 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+func TestStepScreenshotOnFailure_impl(t *testing.T) {
+	var _ multistep.Step = new(StepScreenshotOnFailure)
+}
+
+func TestStepScreenshotOnFailure(t *testing.T) {
+	cases := []struct {
+		Error        bool
+		Communicator bool
+		Taken        bool
+	}{
+		{false, false, false},
+		{true, true, false},
+		{true, false, true},
+	}
+
+	for _, tc := range cases {
+		state := new(multistep.BasicStateBag)
+		state.Put("ui", packer.TestUi(t))
+		if tc.Error {
+			state.Put("error", errors.New("boot failed"))
+		}
+		if tc.Communicator {
+			state.Put("communicator", new(packer.MockCommunicator))
+		}
+
+		var path string
+		step := &StepScreenshotOnFailure{
+			BuildName: "foo",
+			Screenshot: func(_ multistep.StateBag, p string) error {
+				path = p
+				return nil
+			},
+		}
+		if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+			t.Fatalf("bad action: %#v", action)
+		}
+		step.Cleanup(state)
+
+		if (path != "") != tc.Taken {
+			t.Fatalf("%#v: bad: %q", tc, path)
+		}
+		if tc.Taken && filepath.Base(path) != "packer-foo-failure.png" {
+			t.Fatalf("bad: %q", path)
+		}
+	}
+}
+
+func TestStepScreenshotOnFailure_nil(t *testing.T) {
+	state := new(multistep.BasicStateBag)
+	state.Put("ui", packer.TestUi(t))
+	state.Put("error", errors.New("boot failed"))
+	step := &StepScreenshotOnFailure{BuildName: "foo"}
+	step.Cleanup(state)
+}
@@ -0,0 +1,80 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hashicorp/packer/helper/communicator"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// StepWaitCloudInit waits for the agent of wait_for_cloud_init to be done
+// with the user data of the instance, if it is set. The cloud builders run
+// it once they are connected, before the provisioners.
+//
+// Uses:
+//   communicator packer.Communicator
+//   ui           packer.Ui
+//
+// Produces:
+//   <nothing>
+type StepWaitCloudInit struct {
+	Config *CloudInitConfig
+	Comm   *communicator.Config
+
+	// pollInterval is the time between the checks, 5 seconds by default.
+	pollInterval time.Duration
+}
+
+func (s *StepWaitCloudInit) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	if s.Config.WaitForCloudInit == "" || s.Comm.Type == "none" {
+		return multistep.ActionContinue
+	}
+
+	comm := state.Get("communicator").(packer.Communicator)
+	ui := state.Get("ui").(packer.Ui)
+
+	interval := s.pollInterval
+	if interval == 0 {
+		interval = 5 * time.Second
+	}
+
+	ui.Say(fmt.Sprintf("Waiting for %s to finish...", s.Config.WaitForCloudInit))
+	command := s.Config.checkCommand(s.Comm.Type)
+	timeout := time.After(s.Config.CloudInitTimeout)
+	for {
+		cmd := &packer.RemoteCmd{Command: command}
+		err := comm.Start(cmd)
+		if err == nil {
+			cmd.Wait()
+			switch cmd.ExitStatus {
+			case 0:
+				ui.Message(fmt.Sprintf("%s finished", s.Config.WaitForCloudInit))
+				return multistep.ActionContinue
+			case 2:
+				err := fmt.Errorf("%s failed, see its logs in the guest", s.Config.WaitForCloudInit)
+				state.Put("error", err)
+				ui.Error(err.Error())
+				return multistep.ActionHalt
+			}
+		} else {
+			log.Printf("Error checking if %s finished, retrying: %s", s.Config.WaitForCloudInit, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return multistep.ActionHalt
+		case <-timeout:
+			err := fmt.Errorf("Timeout waiting for %s to finish", s.Config.WaitForCloudInit)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		case <-time.After(interval):
+		}
+	}
+}
+
+func (s *StepWaitCloudInit) Cleanup(multistep.StateBag) {}
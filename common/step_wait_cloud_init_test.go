@@ -0,0 +1,72 @@
+package common
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer/helper/communicator"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+func TestStepWaitCloudInit_impl(t *testing.T) {
+	var _ multistep.Step = new(StepWaitCloudInit)
+}
+
+func testStepWaitCloudInit(t *testing.T, exitStatus int) (*StepWaitCloudInit, *packer.MockCommunicator, multistep.StateBag) {
+	comm := &packer.MockCommunicator{StartExitStatus: exitStatus}
+	state := new(multistep.BasicStateBag)
+	state.Put("communicator", comm)
+	state.Put("ui", packer.TestUi(t))
+
+	step := &StepWaitCloudInit{
+		Config: &CloudInitConfig{
+			WaitForCloudInit: "cloud-init",
+			CloudInitTimeout: 50 * time.Millisecond,
+		},
+		Comm:         &communicator.Config{Type: "ssh"},
+		pollInterval: 10 * time.Millisecond,
+	}
+	return step, comm, state
+}
+
+func TestStepWaitCloudInit(t *testing.T) {
+	step, comm, state := testStepWaitCloudInit(t, 0)
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if comm.StartCmd.Command != step.Config.checkCommand("ssh") {
+		t.Fatalf("bad: %s", comm.StartCmd.Command)
+	}
+
+	// Disabled
+	step, comm, state = testStepWaitCloudInit(t, 0)
+	step.Config.WaitForCloudInit = ""
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if comm.StartCalled {
+		t.Fatal("should not run the command")
+	}
+}
+
+func TestStepWaitCloudInit_failed(t *testing.T) {
+	step, _, state := testStepWaitCloudInit(t, 2)
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("error"); !ok {
+		t.Fatal("should have error")
+	}
+}
+
+func TestStepWaitCloudInit_timeout(t *testing.T) {
+	step, _, state := testStepWaitCloudInit(t, 1)
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("error"); !ok {
+		t.Fatal("should have error")
+	}
+}
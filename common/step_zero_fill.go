@@ -0,0 +1,51 @@
+package common
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// StepZeroFill runs the zero fill command in the guest when
+// zero_fill_free_space is set. The builders run it before the guest is
+// shut down, and compact the disk afterwards.
+//
+// Uses:
+//   communicator packer.Communicator
+//   ui           packer.Ui
+//
+// Produces:
+//   <nothing>
+type StepZeroFill struct {
+	Config *ZeroFillConfig
+}
+
+func (s *StepZeroFill) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	if !s.Config.ZeroFillFreeSpace {
+		return multistep.ActionContinue
+	}
+
+	comm := state.Get("communicator").(packer.Communicator)
+	ui := state.Get("ui").(packer.Ui)
+
+	ui.Say("Filling the free space of the disk with zeros...")
+	cmd := &packer.RemoteCmd{Command: s.Config.ZeroFillCommand}
+	if err := cmd.StartWithUi(comm, ui); err != nil {
+		err := fmt.Errorf("Error filling the free space with zeros: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	if cmd.ExitStatus != 0 {
+		err := fmt.Errorf("The zero fill command exited with status %d", cmd.ExitStatus)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *StepZeroFill) Cleanup(multistep.StateBag) {}
@@ -0,0 +1,49 @@
+package common
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+func TestStepZeroFill_impl(t *testing.T) {
+	var _ multistep.Step = new(StepZeroFill)
+}
+
+func TestStepZeroFill(t *testing.T) {
+	comm := new(packer.MockCommunicator)
+	state := new(multistep.BasicStateBag)
+	state.Put("communicator", comm)
+	state.Put("ui", packer.TestUi(t))
+
+	// Disabled
+	step := &StepZeroFill{Config: &ZeroFillConfig{}}
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if comm.StartCalled {
+		t.Fatal("should not run the command")
+	}
+
+	step = &StepZeroFill{Config: &ZeroFillConfig{
+		ZeroFillFreeSpace: true,
+		ZeroFillCommand:   "zero",
+	}}
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if comm.StartCmd.Command != "zero" {
+		t.Fatalf("bad: %s", comm.StartCmd.Command)
+	}
+
+	// Failing command
+	comm.StartExitStatus = 1
+	if action := step.Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("error"); !ok {
+		t.Fatal("should have error")
+	}
+}
@@ -0,0 +1,334 @@
+package common
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+// UnattendFile is the name Windows Setup looks for the answer file under
+// at the root of removable media.
+const UnattendFile = "Autounattend.xml"
+
+// UnattendConfig describes an unattended Windows installation. The answer
+// file is generated from it and put on the floppy disk, instead of having to
+// maintain one by hand.
+type UnattendConfig struct {
+	// Locale is used for the language of the installation, the system, and
+	// the user, and InputLocale for the keyboard.
+	Locale      string `mapstructure:"locale"`
+	InputLocale string `mapstructure:"input_locale"`
+	TimeZone    string `mapstructure:"time_zone"`
+
+	ProductKey   string `mapstructure:"product_key"`
+	ImageName    string `mapstructure:"image_name"`
+	ComputerName string `mapstructure:"computer_name"`
+	Architecture string `mapstructure:"architecture"`
+	Firmware     string `mapstructure:"firmware"`
+
+	AdministratorPassword string         `mapstructure:"administrator_password"`
+	Users                 []UnattendUser `mapstructure:"users"`
+
+	// Commands are run in order at the first logon, after WinRM is set up
+	// if EnableWinRM is.
+	Commands    []string `mapstructure:"commands"`
+	EnableWinRM bool     `mapstructure:"enable_winrm"`
+}
+
+// UnattendUser is a local account created by the installation.
+type UnattendUser struct {
+	Name      string `mapstructure:"name"`
+	Password  string `mapstructure:"password"`
+	Group     string `mapstructure:"group"`
+	AutoLogon bool   `mapstructure:"auto_logon"`
+}
+
+// unattendWinRMCommands set up WinRM for the communicator, over HTTP with
+// basic authentication.
+var unattendWinRMCommands = []string{
+	`cmd.exe /c winrm quickconfig -q`,
+	`cmd.exe /c winrm set winrm/config/service @{AllowUnencrypted="true"}`,
+	`cmd.exe /c winrm set winrm/config/service/auth @{Basic="true"}`,
+	`cmd.exe /c netsh advfirewall firewall set rule group="Windows Remote Management" new enable=yes`,
+	`cmd.exe /c sc config winrm start= auto`,
+}
+
+func (c *UnattendConfig) Prepare(ctx *interpolate.Context) []error {
+	var errs []error
+
+	if c.Locale == "" {
+		c.Locale = "en-US"
+	}
+	if c.InputLocale == "" {
+		c.InputLocale = c.Locale
+	}
+	if c.TimeZone == "" {
+		c.TimeZone = "UTC"
+	}
+	if c.ComputerName == "" {
+		c.ComputerName = "*"
+	}
+
+	switch c.Architecture {
+	case "":
+		c.Architecture = "amd64"
+	case "amd64", "x86":
+	default:
+		errs = append(errs, fmt.Errorf("floppy_unattend: architecture must be amd64 or x86"))
+	}
+	switch c.Firmware {
+	case "":
+		c.Firmware = "bios"
+	case "bios", "efi":
+	default:
+		errs = append(errs, fmt.Errorf("floppy_unattend: firmware must be bios or efi"))
+	}
+
+	autoLogon := 0
+	for i := range c.Users {
+		u := &c.Users[i]
+		if u.Name == "" {
+			errs = append(errs, fmt.Errorf("floppy_unattend: user %d: name must be specified", i+1))
+		}
+		if u.Group == "" {
+			u.Group = "Administrators"
+		}
+		if u.AutoLogon {
+			autoLogon++
+		}
+		packer.LogSecretFilter.Set(u.Password)
+	}
+	if autoLogon > 1 {
+		errs = append(errs, fmt.Errorf("floppy_unattend: only one user can log on automatically"))
+	}
+	if c.AdministratorPassword == "" && len(c.Users) == 0 {
+		errs = append(errs, fmt.Errorf(
+			"floppy_unattend: administrator_password or users must be specified to log on"))
+	}
+	packer.LogSecretFilter.Set(c.AdministratorPassword, c.ProductKey)
+
+	return errs
+}
+
+// AutoLogonUser is the user that logs on automatically, if any.
+func (c *UnattendConfig) AutoLogonUser() *UnattendUser {
+	for i := range c.Users {
+		if c.Users[i].AutoLogon {
+			return &c.Users[i]
+		}
+	}
+	return nil
+}
+
+// FirstLogonCommands are the commands run at the first logon, in order.
+func (c *UnattendConfig) FirstLogonCommands() []string {
+	var commands []string
+	if c.EnableWinRM {
+		commands = append(commands, unattendWinRMCommands...)
+	}
+	return append(commands, c.Commands...)
+}
+
+// Render returns the answer file.
+func (c *UnattendConfig) Render() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := unattendTemplate.Execute(&buf, c); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+var unattendTemplate = template.Must(template.New("unattend").Funcs(template.FuncMap{
+	"xml": func(s string) string {
+		var b strings.Builder
+		xml.EscapeText(&b, []byte(s))
+		return b.String()
+	},
+	"inc": func(i int) int { return i + 1 },
+}).Parse(`<?xml version="1.0" encoding="utf-8"?>
+{{- $arch := .Architecture}}
+<unattend xmlns="urn:schemas-microsoft-com:unattend" xmlns:wcm="http://schemas.microsoft.com/WMIConfig/2002/State">
+  <settings pass="windowsPE">
+    <component name="Microsoft-Windows-International-Core-WinPE" processorArchitecture="{{$arch}}" publicKeyToken="31bf3856ad364e35" language="neutral" versionScope="nonSxS">
+      <SetupUILanguage>
+        <UILanguage>{{xml .Locale}}</UILanguage>
+      </SetupUILanguage>
+      <InputLocale>{{xml .InputLocale}}</InputLocale>
+      <SystemLocale>{{xml .Locale}}</SystemLocale>
+      <UILanguage>{{xml .Locale}}</UILanguage>
+      <UserLocale>{{xml .Locale}}</UserLocale>
+    </component>
+    <component name="Microsoft-Windows-Setup" processorArchitecture="{{$arch}}" publicKeyToken="31bf3856ad364e35" language="neutral" versionScope="nonSxS">
+      <DiskConfiguration>
+        <Disk wcm:action="add">
+          <DiskID>0</DiskID>
+          <WillWipeDisk>true</WillWipeDisk>
+          <CreatePartitions>
+{{- if eq .Firmware "efi"}}
+            <CreatePartition wcm:action="add">
+              <Order>1</Order>
+              <Type>EFI</Type>
+              <Size>100</Size>
+            </CreatePartition>
+            <CreatePartition wcm:action="add">
+              <Order>2</Order>
+              <Type>MSR</Type>
+              <Size>16</Size>
+            </CreatePartition>
+            <CreatePartition wcm:action="add">
+              <Order>3</Order>
+              <Type>Primary</Type>
+              <Extend>true</Extend>
+            </CreatePartition>
+          </CreatePartitions>
+          <ModifyPartitions>
+            <ModifyPartition wcm:action="add">
+              <Order>1</Order>
+              <PartitionID>1</PartitionID>
+              <Format>FAT32</Format>
+              <Label>System</Label>
+            </ModifyPartition>
+            <ModifyPartition wcm:action="add">
+              <Order>2</Order>
+              <PartitionID>3</PartitionID>
+              <Format>NTFS</Format>
+              <Label>Windows</Label>
+              <Letter>C</Letter>
+            </ModifyPartition>
+          </ModifyPartitions>
+{{- else}}
+            <CreatePartition wcm:action="add">
+              <Order>1</Order>
+              <Type>Primary</Type>
+              <Extend>true</Extend>
+            </CreatePartition>
+          </CreatePartitions>
+          <ModifyPartitions>
+            <ModifyPartition wcm:action="add">
+              <Order>1</Order>
+              <PartitionID>1</PartitionID>
+              <Format>NTFS</Format>
+              <Label>Windows</Label>
+              <Letter>C</Letter>
+              <Active>true</Active>
+            </ModifyPartition>
+          </ModifyPartitions>
+{{- end}}
+        </Disk>
+      </DiskConfiguration>
+      <ImageInstall>
+        <OSImage>
+{{- if .ImageName}}
+          <InstallFrom>
+            <MetaData wcm:action="add">
+              <Key>/IMAGE/NAME</Key>
+              <Value>{{xml .ImageName}}</Value>
+            </MetaData>
+          </InstallFrom>
+{{- end}}
+          <InstallTo>
+            <DiskID>0</DiskID>
+            <PartitionID>{{if eq .Firmware "efi"}}3{{else}}1{{end}}</PartitionID>
+          </InstallTo>
+        </OSImage>
+      </ImageInstall>
+      <UserData>
+{{- if .ProductKey}}
+        <ProductKey>
+          <Key>{{xml .ProductKey}}</Key>
+          <WillShowUI>OnError</WillShowUI>
+        </ProductKey>
+{{- end}}
+        <AcceptEula>true</AcceptEula>
+      </UserData>
+    </component>
+  </settings>
+  <settings pass="specialize">
+    <component name="Microsoft-Windows-Shell-Setup" processorArchitecture="{{$arch}}" publicKeyToken="31bf3856ad364e35" language="neutral" versionScope="nonSxS">
+      <ComputerName>{{xml .ComputerName}}</ComputerName>
+      <TimeZone>{{xml .TimeZone}}</TimeZone>
+    </component>
+  </settings>
+  <settings pass="oobeSystem">
+    <component name="Microsoft-Windows-International-Core" processorArchitecture="{{$arch}}" publicKeyToken="31bf3856ad364e35" language="neutral" versionScope="nonSxS">
+      <InputLocale>{{xml .InputLocale}}</InputLocale>
+      <SystemLocale>{{xml .Locale}}</SystemLocale>
+      <UILanguage>{{xml .Locale}}</UILanguage>
+      <UserLocale>{{xml .Locale}}</UserLocale>
+    </component>
+    <component name="Microsoft-Windows-Shell-Setup" processorArchitecture="{{$arch}}" publicKeyToken="31bf3856ad364e35" language="neutral" versionScope="nonSxS">
+      <OOBE>
+        <HideEULAPage>true</HideEULAPage>
+        <HideLocalAccountScreen>true</HideLocalAccountScreen>
+        <HideOEMRegistrationScreen>true</HideOEMRegistrationScreen>
+        <HideOnlineAccountScreens>true</HideOnlineAccountScreens>
+        <HideWirelessSetupInOOBE>true</HideWirelessSetupInOOBE>
+        <NetworkLocation>Work</NetworkLocation>
+        <ProtectYourPC>3</ProtectYourPC>
+        <SkipMachineOOBE>true</SkipMachineOOBE>
+        <SkipUserOOBE>true</SkipUserOOBE>
+      </OOBE>
+      <UserAccounts>
+{{- if .AdministratorPassword}}
+        <AdministratorPassword>
+          <Value>{{xml .AdministratorPassword}}</Value>
+          <PlainText>true</PlainText>
+        </AdministratorPassword>
+{{- end}}
+{{- if .Users}}
+        <LocalAccounts>
+{{- range .Users}}
+          <LocalAccount wcm:action="add">
+            <Name>{{xml .Name}}</Name>
+            <Group>{{xml .Group}}</Group>
+            <Password>
+              <Value>{{xml .Password}}</Value>
+              <PlainText>true</PlainText>
+            </Password>
+          </LocalAccount>
+{{- end}}
+        </LocalAccounts>
+{{- end}}
+      </UserAccounts>
+{{- with .AutoLogonUser}}
+      <AutoLogon>
+        <Enabled>true</Enabled>
+        <Username>{{xml .Name}}</Username>
+        <Password>
+          <Value>{{xml .Password}}</Value>
+          <PlainText>true</PlainText>
+        </Password>
+        <LogonCount>1</LogonCount>
+      </AutoLogon>
+{{- else}}
+{{- if .AdministratorPassword}}
+      <AutoLogon>
+        <Enabled>true</Enabled>
+        <Username>Administrator</Username>
+        <Password>
+          <Value>{{xml .AdministratorPassword}}</Value>
+          <PlainText>true</PlainText>
+        </Password>
+        <LogonCount>1</LogonCount>
+      </AutoLogon>
+{{- end}}
+{{- end}}
+{{- with .FirstLogonCommands}}
+      <FirstLogonCommands>
+{{- range $i, $cmd := .}}
+        <SynchronousCommand wcm:action="add">
+          <Order>{{inc $i}}</Order>
+          <CommandLine>{{xml $cmd}}</CommandLine>
+        </SynchronousCommand>
+{{- end}}
+      </FirstLogonCommands>
+{{- end}}
+    </component>
+  </settings>
+</unattend>
+`))
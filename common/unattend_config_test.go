@@ -0,0 +1,118 @@
+package common
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+// wellFormed checks the answer file is well-formed XML.
+func wellFormed(t *testing.T, contents []byte) {
+	dec := xml.NewDecoder(strings.NewReader(string(contents)))
+	for {
+		_, err := dec.Token()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			t.Fatalf("err: %s\n\n%s", err, contents)
+		}
+	}
+}
+
+func TestUnattendConfigPrepare(t *testing.T) {
+	c := &UnattendConfig{AdministratorPassword: "vagrant"}
+	if errs := c.Prepare(nil); len(errs) > 0 {
+		t.Fatalf("err: %#v", errs)
+	}
+	if c.Locale != "en-US" || c.InputLocale != "en-US" || c.TimeZone != "UTC" {
+		t.Fatalf("bad: %#v", c)
+	}
+	if c.Architecture != "amd64" || c.Firmware != "bios" || c.ComputerName != "*" {
+		t.Fatalf("bad: %#v", c)
+	}
+
+	c = &UnattendConfig{Locale: "fr-FR", Users: []UnattendUser{{Name: "packer"}}}
+	if errs := c.Prepare(nil); len(errs) > 0 {
+		t.Fatalf("err: %#v", errs)
+	}
+	if c.InputLocale != "fr-FR" || c.Users[0].Group != "Administrators" {
+		t.Fatalf("bad: %#v", c)
+	}
+
+	for _, c := range []*UnattendConfig{
+		{},
+		{AdministratorPassword: "a", Architecture: "arm"},
+		{AdministratorPassword: "a", Firmware: "uefi"},
+		{Users: []UnattendUser{{Password: "a"}}},
+		{Users: []UnattendUser{{Name: "a", AutoLogon: true}, {Name: "b", AutoLogon: true}}},
+	} {
+		if errs := c.Prepare(nil); len(errs) == 0 {
+			t.Fatalf("should error: %#v", c)
+		}
+	}
+}
+
+func TestUnattendConfigRender(t *testing.T) {
+	c := &UnattendConfig{
+		ProductKey: "AAAAA-BBBBB",
+		ImageName:  "Windows Server 2016 SERVERSTANDARD",
+		Users: []UnattendUser{
+			{Name: "packer", Password: "p<&>ss", AutoLogon: true},
+		},
+		Commands:    []string{`cmd.exe /c echo "done"`},
+		EnableWinRM: true,
+	}
+	if errs := c.Prepare(nil); len(errs) > 0 {
+		t.Fatalf("err: %#v", errs)
+	}
+	contents, err := c.Render()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	wellFormed(t, contents)
+
+	s := string(contents)
+	for _, expected := range []string{
+		`<UILanguage>en-US</UILanguage>`,
+		`<Key>AAAAA-BBBBB</Key>`,
+		`<Value>Windows Server 2016 SERVERSTANDARD</Value>`,
+		`<Value>p&lt;&amp;&gt;ss</Value>`,
+		`<Username>packer</Username>`,
+		`<Order>1</Order>
+          <CommandLine>cmd.exe /c winrm quickconfig -q</CommandLine>`,
+		`<Order>6</Order>
+          <CommandLine>cmd.exe /c echo &#34;done&#34;</CommandLine>`,
+	} {
+		if !strings.Contains(s, expected) {
+			t.Fatalf("no %q in:\n%s", expected, s)
+		}
+	}
+	if strings.Contains(s, "AdministratorPassword") || strings.Contains(s, "<Type>EFI</Type>") {
+		t.Fatalf("bad:\n%s", s)
+	}
+
+	c = &UnattendConfig{AdministratorPassword: "vagrant", Firmware: "efi"}
+	c.Prepare(nil)
+	contents, err = c.Render()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	wellFormed(t, contents)
+
+	s = string(contents)
+	for _, expected := range []string{
+		`<Type>EFI</Type>`,
+		`<PartitionID>3</PartitionID>
+          </InstallTo>`,
+		`<Username>Administrator</Username>`,
+	} {
+		if !strings.Contains(s, expected) {
+			t.Fatalf("no %q in:\n%s", expected, s)
+		}
+	}
+	if strings.Contains(s, "FirstLogonCommands") || strings.Contains(s, "ProductKey") {
+		t.Fatalf("bad:\n%s", s)
+	}
+}
@@ -0,0 +1,32 @@
+package common
+
+import (
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// unverifiedArtifact is an artifact built from files downloaded without a
+// checksum, whose URLs are its "unverified_downloads" state.
+type unverifiedArtifact struct {
+	packer.Artifact
+	urls []string
+}
+
+// WithUnverifiedDownloads returns the artifact with the URLs StepDownload
+// downloaded without a checksum in its "unverified_downloads" state, for
+// the manifest post-processor to record them. The artifact is returned as
+// is if there are none.
+func WithUnverifiedDownloads(artifact packer.Artifact, state multistep.StateBag) packer.Artifact {
+	v, ok := state.GetOk("unverified_downloads")
+	if !ok || artifact == nil {
+		return artifact
+	}
+	return &unverifiedArtifact{Artifact: artifact, urls: v.([]string)}
+}
+
+func (a *unverifiedArtifact) State(name string) interface{} {
+	if name == "unverified_downloads" {
+		return a.urls
+	}
+	return a.Artifact.State(name)
+}
@@ -0,0 +1,27 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+func TestWithUnverifiedDownloads(t *testing.T) {
+	artifact := &packer.MockArtifact{}
+	state := new(multistep.BasicStateBag)
+	if WithUnverifiedDownloads(artifact, state) != artifact {
+		t.Fatal("the artifact should be returned as is")
+	}
+
+	urls := []string{"http://example.com/os.iso"}
+	state.Put("unverified_downloads", urls)
+	result := WithUnverifiedDownloads(artifact, state)
+	if !reflect.DeepEqual(result.State("unverified_downloads"), urls) {
+		t.Fatalf("bad: %#v", result.State("unverified_downloads"))
+	}
+	if result.Id() != artifact.Id() {
+		t.Fatalf("bad: %s", result.Id())
+	}
+}
@@ -0,0 +1,46 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+)
+
+// DefaultUploadRetries is how many times a chunk of an upload is sent
+// again before the upload fails.
+const DefaultUploadRetries = 10
+
+// UploadConfig contains the configuration of the chunked uploads of the
+// post-processors that upload images to a cloud, in which a failed chunk is
+// sent again on its own instead of the whole file.
+type UploadConfig struct {
+	// UploadChunkSize is the size of the chunks in megabytes.
+	UploadChunkSize int `mapstructure:"upload_chunk_size"`
+	UploadRetries   int `mapstructure:"upload_retries"`
+}
+
+// Prepare sets the defaults, the chunk size must be at least minChunkSize
+// megabytes.
+func (c *UploadConfig) Prepare(defaultChunkSize, minChunkSize int) []error {
+	var errs []error
+
+	if c.UploadChunkSize == 0 {
+		c.UploadChunkSize = defaultChunkSize
+	}
+	if c.UploadChunkSize < minChunkSize {
+		errs = append(errs, fmt.Errorf("upload_chunk_size must be at least %d (MB)", minChunkSize))
+	}
+
+	if c.UploadRetries == 0 {
+		c.UploadRetries = DefaultUploadRetries
+	}
+	if c.UploadRetries < 0 {
+		errs = append(errs, errors.New("upload_retries can't be negative"))
+	}
+
+	return errs
+}
+
+// ChunkBytes is the size of the chunks in bytes.
+func (c *UploadConfig) ChunkBytes() int64 {
+	return int64(c.UploadChunkSize) * 1024 * 1024
+}
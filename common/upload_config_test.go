@@ -0,0 +1,23 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestUploadConfigPrepare(t *testing.T) {
+	var c UploadConfig
+	if errs := c.Prepare(64, 5); len(errs) > 0 {
+		t.Fatalf("bad: %#v", errs)
+	}
+	if c.UploadChunkSize != 64 || c.UploadRetries != DefaultUploadRetries {
+		t.Fatalf("bad: %#v", c)
+	}
+	if c.ChunkBytes() != 64*1024*1024 {
+		t.Fatalf("bad: %d", c.ChunkBytes())
+	}
+
+	c = UploadConfig{UploadChunkSize: 1, UploadRetries: -1}
+	if errs := c.Prepare(64, 5); len(errs) != 2 {
+		t.Fatalf("bad: %#v", errs)
+	}
+}
@@ -0,0 +1,104 @@
+package common
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"net"
+	"os"
+	"time"
+
+	"github.com/mitchellh/go-vnc"
+)
+
+// VNCScreenshot saves the screen of the VNC server at address to path as a
+// PNG image.
+func VNCScreenshot(address string, auth []vnc.ClientAuth, path string, timeout time.Duration) error {
+	nc, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return fmt.Errorf("Error connecting to VNC: %s", err)
+	}
+	defer nc.Close()
+	nc.SetDeadline(time.Now().Add(timeout))
+
+	// The messages are buffered so the client doesn't block on the ones
+	// after the update we wait for.
+	messages := make(chan vnc.ServerMessage, 8)
+	c, err := vnc.Client(nc, &vnc.ClientConfig{
+		Auth:            auth,
+		Exclusive:       false,
+		ServerMessageCh: messages,
+	})
+	if err != nil {
+		return fmt.Errorf("Error handshaking with VNC: %s", err)
+	}
+	defer c.Close()
+
+	if err := c.FramebufferUpdateRequest(false, 0, 0, c.FrameBufferWidth, c.FrameBufferHeight); err != nil {
+		return fmt.Errorf("Error requesting the VNC screen: %s", err)
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, int(c.FrameBufferWidth), int(c.FrameBufferHeight)))
+	timer := time.After(timeout)
+	for {
+		select {
+		case msg := <-messages:
+			update, ok := msg.(*vnc.FramebufferUpdateMessage)
+			if !ok {
+				continue
+			}
+			for _, rect := range update.Rectangles {
+				drawVNCRect(img, c.PixelFormat, rect)
+			}
+			return writePNG(path, img)
+		case <-timer:
+			return fmt.Errorf("Timeout waiting for the VNC screen")
+		}
+	}
+}
+
+// drawVNCRect draws the raw pixels of the rectangle on img.
+func drawVNCRect(img *image.RGBA, format vnc.PixelFormat, rect vnc.Rectangle) {
+	raw, ok := rect.Enc.(*vnc.RawEncoding)
+	if !ok {
+		return
+	}
+	for i, c := range raw.Colors {
+		x := int(rect.X) + i%int(rect.Width)
+		y := int(rect.Y) + i/int(rect.Width)
+		img.Set(x, y, vncColor(format, c))
+	}
+}
+
+// vncColor converts a color of the server, which is scaled to the maximums
+// of the pixel format for true color, or 16 bits from the color map.
+func vncColor(format vnc.PixelFormat, c vnc.Color) color.RGBA {
+	if !format.TrueColor {
+		return color.RGBA{uint8(c.R >> 8), uint8(c.G >> 8), uint8(c.B >> 8), 0xff}
+	}
+	scale := func(v, max uint16) uint8 {
+		if max == 0 {
+			return 0
+		}
+		return uint8(uint32(v) * 0xff / uint32(max))
+	}
+	return color.RGBA{
+		scale(c.R, format.RedMax),
+		scale(c.G, format.GreenMax),
+		scale(c.B, format.BlueMax),
+		0xff,
+	}
+}
+
+func writePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
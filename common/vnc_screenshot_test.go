@@ -0,0 +1,120 @@
+package common
+
+import (
+	"encoding/binary"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testVNCServer is a VNC server without authentication, with a 2x1 screen
+// of a red and a blue pixel.
+func testVNCServer(t *testing.T) (net.Listener, string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var version [12]byte
+		conn.Write([]byte("RFB 003.008\n"))
+		io.ReadFull(conn, version[:])
+
+		// The security types, only none, and the result
+		var securityType [1]byte
+		conn.Write([]byte{1, 1})
+		io.ReadFull(conn, securityType[:])
+		binary.Write(conn, binary.BigEndian, uint32(0))
+
+		// ClientInit, then ServerInit with 32 bits true color
+		var shared [1]byte
+		io.ReadFull(conn, shared[:])
+		write(conn, binary.BigEndian,
+			uint16(2), uint16(1),
+			uint8(32), uint8(24), uint8(0), uint8(1),
+			uint16(255), uint16(255), uint16(255),
+			uint8(16), uint8(8), uint8(0),
+			[3]byte{},
+			uint32(4), []byte("test"))
+
+		// FramebufferUpdateRequest
+		var request [10]byte
+		if _, err := io.ReadFull(conn, request[:]); err != nil {
+			return
+		}
+		write(conn, binary.BigEndian,
+			uint8(0), uint8(0), uint16(1),
+			uint16(0), uint16(0), uint16(2), uint16(1), int32(0))
+		write(conn, binary.LittleEndian, uint32(0xff0000), uint32(0x0000ff))
+
+		// Wait for the client to close
+		ioutil.ReadAll(conn)
+	}()
+
+	return l, l.Addr().String()
+}
+
+func write(w io.Writer, order binary.ByteOrder, values ...interface{}) {
+	for _, v := range values {
+		binary.Write(w, order, v)
+	}
+}
+
+func TestVNCScreenshot(t *testing.T) {
+	l, address := testVNCServer(t)
+	defer l.Close()
+
+	dir, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "screen.png")
+
+	if err := VNCScreenshot(address, nil, path, 5*time.Second); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if size := img.Bounds().Size(); size.X != 2 || size.Y != 1 {
+		t.Fatalf("bad size: %v", size)
+	}
+	if r, _, b, _ := img.At(0, 0).RGBA(); r != 0xffff || b != 0 {
+		t.Fatalf("bad color: %v", img.At(0, 0))
+	}
+	if r, _, b, _ := img.At(1, 0).RGBA(); r != 0 || b != 0xffff {
+		t.Fatalf("bad color: %v", img.At(1, 0))
+	}
+}
+
+func TestVNCScreenshot_noServer(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	address := l.Addr().String()
+	l.Close()
+
+	if err := VNCScreenshot(address, nil, "screen.png", time.Second); err == nil {
+		t.Fatal("should error")
+	}
+}
@@ -0,0 +1,41 @@
+package common
+
+import (
+	"errors"
+)
+
+// DefaultZeroFillCommand fills the free space of a Unix guest with zeros,
+// dd stops with an error once the disk is full, which is expected.
+const DefaultZeroFillCommand = "dd if=/dev/zero of=/var/tmp/packer-zero-fill bs=1M 2>/dev/null; rm -f /var/tmp/packer-zero-fill; sync"
+
+// ZeroFillConfig contains the configuration for filling the free space of
+// the disk of the guest with zeros before it is shut down, so that the
+// compacted disk and the exported image leave it out.
+type ZeroFillConfig struct {
+	ZeroFillFreeSpace bool   `mapstructure:"zero_fill_free_space"`
+	ZeroFillCommand   string `mapstructure:"zero_fill_command"`
+}
+
+// Prepare sets the default command for the communicator type. Only SSH
+// guests have a default, the command for Windows depends on the tools it
+// has, like sdelete.
+func (c *ZeroFillConfig) Prepare(commType string) []error {
+	if !c.ZeroFillFreeSpace {
+		return nil
+	}
+
+	var errs []error
+	switch commType {
+	case "none":
+		errs = append(errs, errors.New("zero_fill_free_space needs a communicator"))
+	case "winrm":
+		if c.ZeroFillCommand == "" {
+			errs = append(errs, errors.New("zero_fill_command must be set to use zero_fill_free_space with WinRM"))
+		}
+	default:
+		if c.ZeroFillCommand == "" {
+			c.ZeroFillCommand = DefaultZeroFillCommand
+		}
+	}
+	return errs
+}
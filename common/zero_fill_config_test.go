@@ -0,0 +1,41 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestZeroFillConfigPrepare(t *testing.T) {
+	// Disabled
+	c := ZeroFillConfig{}
+	if errs := c.Prepare("none"); len(errs) > 0 {
+		t.Fatalf("err: %#v", errs)
+	}
+	if c.ZeroFillCommand != "" {
+		t.Fatalf("bad: %s", c.ZeroFillCommand)
+	}
+
+	// Default command
+	c = ZeroFillConfig{ZeroFillFreeSpace: true}
+	if errs := c.Prepare("ssh"); len(errs) > 0 {
+		t.Fatalf("err: %#v", errs)
+	}
+	if c.ZeroFillCommand != DefaultZeroFillCommand {
+		t.Fatalf("bad: %s", c.ZeroFillCommand)
+	}
+
+	// No default for WinRM
+	c = ZeroFillConfig{ZeroFillFreeSpace: true}
+	if errs := c.Prepare("winrm"); len(errs) != 1 {
+		t.Fatalf("bad: %#v", errs)
+	}
+	c = ZeroFillConfig{ZeroFillFreeSpace: true, ZeroFillCommand: "sdelete -z c:"}
+	if errs := c.Prepare("winrm"); len(errs) > 0 {
+		t.Fatalf("err: %#v", errs)
+	}
+
+	// No communicator
+	c = ZeroFillConfig{ZeroFillFreeSpace: true}
+	if errs := c.Prepare("none"); len(errs) != 1 {
+		t.Fatalf("bad: %#v", errs)
+	}
+}
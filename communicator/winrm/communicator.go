@@ -13,7 +13,6 @@ import (
 
 	"github.com/hashicorp/packer/packer"
 	"github.com/masterzen/winrm"
-	"github.com/packer-community/winrmcp/winrmcp"
 )
 
 // Communicator represents the WinRM communicator
@@ -26,17 +25,13 @@ type Communicator struct {
 // New creates a new communicator implementation over WinRM.
 func New(config *Config) (*Communicator, error) {
 	endpoint := &winrm.Endpoint{
-		Host:     config.Host,
-		Port:     config.Port,
-		HTTPS:    config.Https,
-		Insecure: config.Insecure,
-
-		/*
-			TODO
-			HTTPS:    connInfo.HTTPS,
-			Insecure: connInfo.Insecure,
-			CACert:   connInfo.CACert,
-		*/
+		Host:          config.Host,
+		Port:          config.Port,
+		HTTPS:         config.Https,
+		Insecure:      config.Insecure,
+		TLSServerName: config.TLSServerName,
+		CACert:        config.CACert,
+		Timeout:       config.ConnectTimeout,
 	}
 
 	// Create the client
@@ -73,6 +68,19 @@ func New(config *Config) (*Communicator, error) {
 	}, nil
 }
 
+// Reconnect returns a communicator to the same host with another password,
+// for once the password of the user was changed.
+func (c *Communicator) Reconnect(password string) (*Communicator, error) {
+	config := *c.config
+	config.Password = password
+	return New(&config)
+}
+
+// Username is the user the communicator is connected as.
+func (c *Communicator) Username() string {
+	return c.config.Username
+}
+
 // Start implementation of communicator.Communicator interface
 func (c *Communicator) Start(rc *packer.RemoteCmd) error {
 	shell, err := c.client.CreateShell()
@@ -123,7 +131,7 @@ func runCommand(shell *winrm.Shell, cmd *winrm.Command, rc *packer.RemoteCmd) {
 
 // Upload implementation of communicator.Communicator interface
 func (c *Communicator) Upload(path string, input io.Reader, fi *os.FileInfo) error {
-	wcp, err := c.newCopyClient()
+	client, err := c.newWinRMClient()
 	if err != nil {
 		return fmt.Errorf("Was unable to create winrm client: %s", err)
 	}
@@ -132,7 +140,10 @@ func (c *Communicator) Upload(path string, input io.Reader, fi *os.FileInfo) err
 		path += filepath.Base((*fi).Name())
 	}
 	log.Printf("Uploading file to '%s'", path)
-	return wcp.Write(path, input)
+
+	u := &uploader{client: client}
+	defer u.Close()
+	return u.upload(winPath(path), input)
 }
 
 // UploadDir implementation of communicator.Communicator interface
@@ -141,11 +152,33 @@ func (c *Communicator) UploadDir(dst string, src string, exclude []string) error
 		dst = fmt.Sprintf("%s\\%s", dst, filepath.Base(src))
 	}
 	log.Printf("Uploading dir '%s' to '%s'", src, dst)
-	wcp, err := c.newCopyClient()
+	client, err := c.newWinRMClient()
 	if err != nil {
 		return err
 	}
-	return wcp.Copy(src, dst)
+
+	u := &uploader{client: client}
+	defer u.Close()
+	return filepath.Walk(src, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		// Ignore dir entries and OS X special hidden file
+		if fi.IsDir() || fi.Name() == ".DS_Store" {
+			return nil
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("Couldn't read file %s: %s", path, err)
+		}
+		defer f.Close()
+		return u.upload(winPath(dst+"\\"+rel), f)
+	})
 }
 
 func (c *Communicator) Download(src string, dst io.Writer) error {
@@ -168,56 +201,24 @@ func (c *Communicator) DownloadDir(src string, dst string, exclude []string) err
 	return fmt.Errorf("WinRM doesn't support download dir.")
 }
 
-func (c *Communicator) getClientConfig() *winrmcp.Config {
-	return &winrmcp.Config{
-		Auth: winrmcp.Auth{
-			User:     c.config.Username,
-			Password: c.config.Password,
-		},
-		Https:                 c.config.Https,
-		Insecure:              c.config.Insecure,
-		OperationTimeout:      c.config.Timeout,
-		MaxOperationsPerShell: 15, // lowest common denominator
-		TransportDecorator:    c.config.TransportDecorator,
-	}
-}
-
-func (c *Communicator) newCopyClient() (*winrmcp.Winrmcp, error) {
-	addr := fmt.Sprintf("%s:%d", c.endpoint.Host, c.endpoint.Port)
-	clientConfig := c.getClientConfig()
-	return winrmcp.New(addr, clientConfig)
-}
-
+// newWinRMClient returns a client for the transfers, with a longer timeout
+// than the one of the commands since each message carries a large part of
+// the file. It connects like the commands do, with the CA certificate, TLS
+// server name and connect timeout of the endpoint.
 func (c *Communicator) newWinRMClient() (*winrm.Client, error) {
-	conf := c.getClientConfig()
-
-	// Shamelessly borrowed from the winrmcp client to ensure
-	// that the client is configured using the same defaulting behaviors that
-	// winrmcp uses even we we aren't using winrmcp. This ensures similar
-	// behavior between upload, download, and copy functions. We can't use the
-	// one generated by winrmcp because it isn't exported.
-	var endpoint *winrm.Endpoint
-	endpoint = &winrm.Endpoint{
-		Host:          c.endpoint.Host,
-		Port:          c.endpoint.Port,
-		HTTPS:         conf.Https,
-		Insecure:      conf.Insecure,
-		TLSServerName: conf.TLSServerName,
-		CACert:        conf.CACertBytes,
-		Timeout:       conf.ConnectTimeout,
-	}
 	params := winrm.NewParameters(
-		winrm.DefaultParameters.Timeout,
+		"PT3M",
 		winrm.DefaultParameters.Locale,
 		winrm.DefaultParameters.EnvelopeSize,
 	)
+	if c.config.MaxEnvelopeSize > 0 {
+		params.EnvelopeSize = c.config.MaxEnvelopeSize * 1024
+	}
+	params.TransportDecorator = c.config.TransportDecorator
 
-	params.TransportDecorator = conf.TransportDecorator
-	params.Timeout = "PT3M"
-
-	client, err := winrm.NewClientWithParameters(
-		endpoint, conf.Auth.User, conf.Auth.Password, params)
-	return client, err
+	endpoint := *c.endpoint
+	return winrm.NewClientWithParameters(
+		&endpoint, c.config.Username, c.config.Password, params)
 }
 
 type Base64Pipe struct {
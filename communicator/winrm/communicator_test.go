@@ -3,7 +3,6 @@ package winrm
 import (
 	"bytes"
 	"io"
-	"strings"
 	"testing"
 	"time"
 
@@ -24,18 +23,6 @@ func newMockWinRMServer(t *testing.T) *winrmtest.Remote {
 			return 0
 		})
 
-	wrm.CommandFunc(
-		winrmtest.MatchPattern(`^echo c29tZXRoaW5n >> ".*"$`),
-		func(out, err io.Writer) int {
-			return 0
-		})
-
-	wrm.CommandFunc(
-		winrmtest.MatchPattern(`^echo `+BASE64_ENCODED_PAYLOAD+` >> ".*"$`),
-		func(out, err io.Writer) int {
-			return 0
-		})
-
 	wrm.CommandFunc(
 		winrmtest.MatchPattern(`^powershell.exe -EncodedCommand .*$`),
 		func(out, err io.Writer) int {
@@ -89,7 +76,7 @@ func TestStart(t *testing.T) {
 	}
 }
 
-func TestUpload(t *testing.T) {
+func TestDownload(t *testing.T) {
 	wrm := newMockWinRMServer(t)
 	defer wrm.Close()
 
@@ -104,11 +91,6 @@ func TestUpload(t *testing.T) {
 		t.Fatalf("error creating communicator: %s", err)
 	}
 	file := "C:/Temp/packer.cmd"
-	err = c.Upload(file, strings.NewReader(PAYLOAD), nil)
-	if err != nil {
-		t.Fatalf("error uploading file: %s", err)
-	}
-
 	dest := new(bytes.Buffer)
 	err = c.Download(file, dest)
 	if err != nil {
@@ -8,12 +8,24 @@ import (
 
 // Config is used to configure the WinRM connection
 type Config struct {
-	Host               string
-	Port               int
-	Username           string
-	Password           string
-	Timeout            time.Duration
-	Https              bool
-	Insecure           bool
+	Host     string
+	Port     int
+	Username string
+	Password string
+	Timeout  time.Duration
+	Https    bool
+	Insecure bool
+
+	// TLSServerName, if set, is the name checked against the certificate
+	// of the host, and CACert the PEM certificates that sign it.
+	TLSServerName string
+	CACert        []byte
+
+	// ConnectTimeout is how long to wait for the TCP connection.
+	ConnectTimeout time.Duration
+
+	// MaxEnvelopeSize is the largest message the host accepts, in KB, its
+	// MaxEnvelopeSizekb. Uploads send as much of the file in each message.
+	MaxEnvelopeSize    int
 	TransportDecorator func() winrm.Transporter
 }
@@ -0,0 +1,182 @@
+package winrm
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/masterzen/winrm"
+)
+
+// uploadScript writes the lines of base64 it reads from stdin to the file,
+// until an empty one, and says how many bytes it wrote. The file is written
+// next to the destination first so a failed upload doesn't leave half of it.
+const uploadScript = `
+$ErrorActionPreference = "Stop"
+$dest_file_path = [System.IO.Path]::GetFullPath(%s)
+if (Test-Path -Path $dest_file_path -PathType container) {
+	[Console]::Error.WriteLine("$dest_file_path is a directory")
+	Exit 1
+}
+$dest_dir = [System.IO.Path]::GetDirectoryName($dest_file_path)
+New-Item -ItemType directory -Force -ErrorAction SilentlyContinue -Path $dest_dir | Out-Null
+
+$tmp_file_path = $dest_file_path + ".packer-upload"
+$writer = [System.IO.File]::Create($tmp_file_path)
+$written = 0
+try {
+	for (;;) {
+		$line = [Console]::In.ReadLine()
+		if ([string]::IsNullOrEmpty($line)) { break }
+		$bytes = [System.Convert]::FromBase64String($line)
+		$writer.Write($bytes, 0, $bytes.Length)
+		$written += $bytes.Length
+	}
+} finally {
+	$writer.Close()
+}
+Move-Item -Force -Path $tmp_file_path -Destination $dest_file_path
+Write-Output $written
+`
+
+// maxOperationsPerShell is how many files are uploaded in a shell before
+// another one is opened. It is the lowest quota of commands per shell of
+// the hosts, the one of Windows Server 2008 R2.
+const maxOperationsPerShell = 15
+
+// uploader uploads files with the client, in the same shell for up to
+// maxOperationsPerShell of them.
+type uploader struct {
+	client     *winrm.Client
+	shell      *winrm.Shell
+	operations int
+}
+
+// Close closes the shell of the uploads, if there is one.
+func (u *uploader) Close() error {
+	if u.shell == nil {
+		return nil
+	}
+	err := u.shell.Close()
+	u.shell = nil
+	return err
+}
+
+// upload streams the file to the stdin of a single PowerShell process on
+// the host. Each message carries as much of the file as the envelope size
+// allows instead of a command line of a few KB, so there is one round trip
+// per chunk and no command to start for each of them.
+func (u *uploader) upload(dst string, input io.Reader) error {
+	start := time.Now()
+	if u.shell != nil && u.operations >= maxOperationsPerShell {
+		if err := u.Close(); err != nil {
+			log.Printf("[WARN] Error closing the shell of the uploads: %s", err)
+		}
+	}
+	if u.shell == nil {
+		shell, err := u.client.CreateShell()
+		if err != nil {
+			return fmt.Errorf("Couldn't create shell: %s", err)
+		}
+		u.shell = shell
+		u.operations = 0
+	}
+	u.operations++
+
+	cmd, err := u.shell.Execute(winrm.Powershell(fmt.Sprintf(uploadScript, psQuote(dst))))
+	if err != nil {
+		return err
+	}
+	defer cmd.Close()
+
+	var stdout, stderr bytes.Buffer
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		io.Copy(&stdout, cmd.Stdout)
+	}()
+	go func() {
+		defer wg.Done()
+		io.Copy(&stderr, cmd.Stderr)
+	}()
+
+	written, writeErr := writeUploadStream(cmd.Stdin, input, uploadChunkSize(u.client.Parameters.EnvelopeSize))
+	cmd.Wait()
+	wg.Wait()
+
+	if cmd.ExitCode() != 0 {
+		return fmt.Errorf("Error uploading %s, exit status %d: %s",
+			dst, cmd.ExitCode(), strings.TrimSpace(stderr.String()))
+	}
+	if writeErr != nil {
+		return fmt.Errorf("Error uploading %s: %s", dst, writeErr)
+	}
+	if received := strings.TrimSpace(stdout.String()); received != strconv.FormatInt(written, 10) {
+		return fmt.Errorf("Error uploading %s: sent %d bytes but %q were written", dst, written, received)
+	}
+
+	log.Printf("Uploaded %d bytes to '%s' in %s", written, dst, time.Since(start))
+	return nil
+}
+
+// uploadChunkSize is how many bytes of the file fit in a message of the
+// envelope size. The chunk is sent as a line of base64, which is encoded
+// in base64 again in the message, and some room is left for the rest of
+// the message.
+func uploadChunkSize(envelopeSize int) int {
+	line := (envelopeSize-2000)/4*3 - 1
+	return line / 4 * 3
+}
+
+// writeUploadStream writes the input to the stdin of the upload script, a
+// line of base64 by chunk, then the empty line that ends it. It returns how
+// many bytes of the input were written.
+func writeUploadStream(w io.Writer, input io.Reader, chunkSize int) (int64, error) {
+	var written int64
+	chunk := make([]byte, chunkSize)
+	for {
+		n, err := io.ReadFull(input, chunk)
+		if n > 0 {
+			line := base64.StdEncoding.EncodeToString(chunk[:n]) + "\n"
+			if err := writeAll(w, line); err != nil {
+				return written, err
+			}
+			written += int64(n)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, writeAll(w, "\n")
+}
+
+// writeAll also fails when the stdin of the command takes less than all of
+// the data without an error, which it does once the command exited.
+func writeAll(w io.Writer, data string) error {
+	n, err := io.WriteString(w, data)
+	if err == nil && n < len(data) {
+		err = io.ErrShortWrite
+	}
+	return err
+}
+
+// winPath is the path on the host, with backslashes.
+func winPath(path string) string {
+	return strings.Replace(path, "/", "\\", -1)
+}
+
+// psQuote quotes the path as a literal string of PowerShell, in which
+// nothing is expanded.
+func psQuote(path string) string {
+	return "'" + strings.Replace(path, "'", "''", -1) + "'"
+}
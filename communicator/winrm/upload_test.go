@@ -0,0 +1,340 @@
+package winrm
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/antchfx/xquery/xml"
+)
+
+// uploadServer is a WinRM host that runs the upload script: it keeps what
+// each command gets on stdin until the empty line, and says how many bytes
+// that is.
+type uploadServer struct {
+	*httptest.Server
+
+	lock     sync.Mutex
+	shells   int
+	commands []*uploadCommand
+}
+
+type uploadCommand struct {
+	script string
+	stdin  bytes.Buffer
+	data   []byte
+	done   chan struct{}
+}
+
+func newUploadServer() *uploadServer {
+	s := new(uploadServer)
+	s.Server = httptest.NewServer(s)
+	return s
+}
+
+func (s *uploadServer) config() *Config {
+	u, _ := url.Parse(s.URL)
+	port, _ := strconv.Atoi(u.Port())
+	return &Config{
+		Host:            u.Hostname(),
+		Port:            port,
+		Username:        "user",
+		Password:        "pass",
+		Timeout:         30 * time.Second,
+		MaxEnvelopeSize: 32,
+	}
+}
+
+// files are the destinations of the uploads and what they received.
+func (s *uploadServer) files() map[string]string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	files := make(map[string]string)
+	for _, c := range s.commands {
+		files[c.script] = string(c.data)
+	}
+	return files
+}
+
+func (s *uploadServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/soap+xml")
+	env, err := xmlquery.Parse(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	text := func(xpath string) string {
+		if n := xmlquery.FindOne(env, xpath); n != nil {
+			return n.InnerText()
+		}
+		return ""
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	action := text("//a:Action")
+	switch {
+	case strings.HasSuffix(action, "transfer/Create"):
+		s.shells++
+		fmt.Fprint(w, `<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope" xmlns:rsp="http://schemas.microsoft.com/wbem/wsman/1/windows/shell">
+			<rsp:ShellId>shell</rsp:ShellId></env:Envelope>`)
+
+	case strings.HasSuffix(action, "shell/Command"):
+		s.commands = append(s.commands, &uploadCommand{
+			script: decodePowershell(text("//rsp:Command")),
+			done:   make(chan struct{}),
+		})
+		fmt.Fprintf(w, `<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope" xmlns:rsp="http://schemas.microsoft.com/wbem/wsman/1/windows/shell">
+			<rsp:CommandId>%d</rsp:CommandId></env:Envelope>`, len(s.commands)-1)
+
+	case strings.HasSuffix(action, "shell/Send"):
+		stream := xmlquery.FindOne(env, "//rsp:Stream")
+		c := s.command(stream.SelectAttr("CommandId"))
+		input, err := base64.StdEncoding.DecodeString(stream.InnerText())
+		if c == nil || err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		c.stdin.Write(input)
+
+		// The upload script: lines of base64 until an empty one
+		lines := strings.Split(c.stdin.String(), "\n")
+		for i, line := range lines[:len(lines)-1] {
+			if line == "" {
+				for _, line := range lines[:i] {
+					b, _ := base64.StdEncoding.DecodeString(line)
+					c.data = append(c.data, b...)
+				}
+				close(c.done)
+				break
+			}
+		}
+
+	case strings.HasSuffix(action, "shell/Receive"):
+		id := xmlquery.FindOne(env, "//rsp:DesiredStream").SelectAttr("CommandId")
+		c := s.command(id)
+		if c == nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		// Wait for stdin to end like the script, without holding the lock
+		s.lock.Unlock()
+		select {
+		case <-c.done:
+		case <-time.After(50 * time.Millisecond):
+		}
+		s.lock.Lock()
+
+		select {
+		case <-c.done:
+			fmt.Fprintf(w, `<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope" xmlns:rsp="http://schemas.microsoft.com/wbem/wsman/1/windows/shell">
+				<rsp:ReceiveResponse>
+					<rsp:Stream Name="stdout" CommandId="%s">%s</rsp:Stream>
+					<rsp:Stream Name="stdout" CommandId="%s" End="true"></rsp:Stream>
+					<rsp:Stream Name="stderr" CommandId="%s" End="true"></rsp:Stream>
+					<rsp:CommandState State="http://schemas.microsoft.com/wbem/wsman/1/windows/shell/CommandState/Done">
+						<rsp:ExitCode>0</rsp:ExitCode>
+					</rsp:CommandState>
+				</rsp:ReceiveResponse></env:Envelope>`,
+				id, base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%d\r\n", len(c.data)))), id, id)
+		default:
+			fmt.Fprint(w, `<env:Envelope xmlns:env="http://www.w3.org/2003/05/soap-envelope" xmlns:rsp="http://schemas.microsoft.com/wbem/wsman/1/windows/shell">
+				<rsp:ReceiveResponse></rsp:ReceiveResponse></env:Envelope>`)
+		}
+
+	case strings.HasSuffix(action, "shell/Signal"), strings.HasSuffix(action, "transfer/Delete"):
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func (s *uploadServer) command(id string) *uploadCommand {
+	i, err := strconv.Atoi(id)
+	if err != nil || i < 0 || i >= len(s.commands) {
+		return nil
+	}
+	return s.commands[i]
+}
+
+// decodePowershell returns the script of a winrm.Powershell command.
+func decodePowershell(command string) string {
+	wide, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(command, "powershell.exe -EncodedCommand "))
+	script := make([]byte, 0, len(wide)/2)
+	for i := 0; i < len(wide); i += 2 {
+		script = append(script, wide[i])
+	}
+	return string(script)
+}
+
+// uploadScriptTo is the upload script for the destination.
+func uploadScriptTo(dst string) string {
+	return fmt.Sprintf(uploadScript, psQuote(dst))
+}
+
+func TestUploadChunkSize(t *testing.T) {
+	for _, envelopeSize := range []int{32 * 1024, 150 * 1024, 500 * 1024} {
+		size := uploadChunkSize(envelopeSize)
+		if size%3 != 0 {
+			t.Fatalf("%d: chunks of %d bytes would be padded", envelopeSize, size)
+		}
+
+		// The line of base64 is encoded again in the message
+		line := base64.StdEncoding.EncodedLen(size) + 1
+		if base64.StdEncoding.EncodedLen(line) > envelopeSize-2000 {
+			t.Fatalf("%d: chunks of %d bytes don't fit", envelopeSize, size)
+		}
+	}
+}
+
+func TestWriteUploadStream(t *testing.T) {
+	data := strings.Repeat("packer", 10)
+
+	var stdin bytes.Buffer
+	written, err := writeUploadStream(&stdin, strings.NewReader(data), 9)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if written != int64(len(data)) {
+		t.Fatalf("bad: %d", written)
+	}
+
+	// What the upload script does with the lines
+	lines := strings.Split(stdin.String(), "\n")
+	if len(lines) != 9 || lines[7] != "" || lines[8] != "" {
+		t.Fatalf("bad: %#v", lines)
+	}
+	var received []byte
+	for _, line := range lines[:7] {
+		b, err := base64.StdEncoding.DecodeString(line)
+		if err != nil {
+			t.Fatalf("bad line %q: %s", line, err)
+		}
+		received = append(received, b...)
+	}
+	if string(received) != data {
+		t.Fatalf("bad: %s", received)
+	}
+}
+
+func TestWriteUploadStream_empty(t *testing.T) {
+	var stdin bytes.Buffer
+	written, err := writeUploadStream(&stdin, strings.NewReader(""), 9)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if written != 0 || stdin.String() != "\n" {
+		t.Fatalf("bad: %d %#v", written, stdin.String())
+	}
+}
+
+func TestWinPath(t *testing.T) {
+	cases := map[string]string{
+		"C:/Temp/packer.cmd":      `C:\Temp\packer.cmd`,
+		`C:\Program Files\packer`: `C:\Program Files\packer`,
+		"":                        "",
+	}
+	for path, expected := range cases {
+		if actual := winPath(path); actual != expected {
+			t.Fatalf("%s: bad: %s", path, actual)
+		}
+	}
+}
+
+func TestPsQuote(t *testing.T) {
+	cases := map[string]string{
+		`C:\Temp\packer.cmd`:      `'C:\Temp\packer.cmd'`,
+		`C:\Temp\it's $env:x.txt`: `'C:\Temp\it''s $env:x.txt'`,
+	}
+	for path, expected := range cases {
+		if actual := psQuote(path); actual != expected {
+			t.Fatalf("%s: bad: %s", path, actual)
+		}
+	}
+}
+
+func TestCommunicatorUpload(t *testing.T) {
+	s := newUploadServer()
+	defer s.Close()
+
+	c, err := New(s.config())
+	if err != nil {
+		t.Fatalf("error creating communicator: %s", err)
+	}
+
+	// Larger than a chunk, and not a multiple of one
+	data := strings.Repeat("0123456789", uploadChunkSize(32*1024)/4)
+	if err := c.Upload("C:/Temp/it's.bin", strings.NewReader(data), nil); err != nil {
+		t.Fatalf("error uploading file: %s", err)
+	}
+
+	files := s.files()
+	received, ok := files[uploadScriptTo(`C:\Temp\it's.bin`)]
+	if len(files) != 1 || !ok {
+		t.Fatalf("bad: %#v", files)
+	}
+	if received != data {
+		t.Fatalf("received %d bytes instead of %d", len(received), len(data))
+	}
+}
+
+func TestCommunicatorUploadDir(t *testing.T) {
+	src, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(src)
+
+	contents := map[string]string{
+		"a.txt":       "a",
+		"empty.txt":   "",
+		"dir/big.txt": strings.Repeat("packer", uploadChunkSize(32*1024)),
+	}
+	for name, content := range contents {
+		path := filepath.Join(src, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	s := newUploadServer()
+	defer s.Close()
+
+	c, err := New(s.config())
+	if err != nil {
+		t.Fatalf("error creating communicator: %s", err)
+	}
+	shells := s.shells
+
+	if err := c.UploadDir(`C:\Temp`, src+"/", nil); err != nil {
+		t.Fatalf("error uploading dir: %s", err)
+	}
+
+	files := s.files()
+	if len(files) != len(contents) {
+		t.Fatalf("bad: %#v", files)
+	}
+	for name, content := range contents {
+		dst := `C:\Temp\` + winPath(filepath.FromSlash(name))
+		if received, ok := files[uploadScriptTo(dst)]; !ok || received != content {
+			t.Fatalf("%s: received %d bytes instead of %d", dst, len(received), len(content))
+		}
+	}
+	if s.shells != shells+1 {
+		t.Fatalf("the files were uploaded in %d shells", s.shells-shells)
+	}
+}
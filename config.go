@@ -9,11 +9,14 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 
 	"github.com/hashicorp/packer/command"
+	"github.com/hashicorp/packer/helper/pluginlock"
 	"github.com/hashicorp/packer/packer"
 	"github.com/hashicorp/packer/packer/plugin"
+	"github.com/hashicorp/packer/version"
 	"github.com/kardianos/osext"
 )
 
@@ -24,12 +27,40 @@ const PACKERSPACE = "-PACKERSPACE-"
 type config struct {
 	DisableCheckpoint          bool `json:"disable_checkpoint"`
 	DisableCheckpointSignature bool `json:"disable_checkpoint_signature"`
+	VersionCheck               bool `json:"version_check"`
 	PluginMinPort              uint
 	PluginMaxPort              uint
 
 	Builders       map[string]string
 	PostProcessors map[string]string `json:"post-processors"`
 	Provisioners   map[string]string
+
+	Notifications []*packer.Notification `json:"notifications"`
+
+	// CacheDir is the cache directory when PACKER_CACHE_DIR isn't set.
+	CacheDir string `json:"cache_dir"`
+
+	// PluginDirectories are searched for plugins after the plugins
+	// directory in the config directory.
+	PluginDirectories []string `json:"plugin_directories"`
+
+	// The proxies and CA bundle of the downloads, unless the environment
+	// has its own.
+	HTTPProxy  string `json:"http_proxy"`
+	HTTPSProxy string `json:"https_proxy"`
+	NoProxy    string `json:"no_proxy"`
+	CAFile     string `json:"ca_file"`
+
+	// OnError is the default of -on-error.
+	OnError string `json:"on_error"`
+
+	// VarFiles are loaded before the variable files of the template and
+	// the command line, in order.
+	VarFiles []string `json:"var_files"`
+
+	// insecurePlugins runs the external plugins whether they match the
+	// plugin lockfile or not.
+	insecurePlugins bool
 }
 
 // Decodes configuration in JSON format from the given io.Reader into
@@ -39,10 +70,62 @@ func decodeConfig(r io.Reader, c *config) error {
 	return decoder.Decode(c)
 }
 
+// prepare validates the config decoded from a file in dir, and makes the
+// paths in it relative to dir absolute.
+func (c *config) prepare(dir string) error {
+	switch c.OnError {
+	case "", "cleanup", "abort", "ask":
+	default:
+		return fmt.Errorf("on_error must be one of 'cleanup', 'abort', or 'ask'")
+	}
+
+	for i, n := range c.Notifications {
+		if err := n.Validate(); err != nil {
+			return fmt.Errorf("notification %d: %s", i+1, err)
+		}
+	}
+
+	abs := func(path *string) {
+		if *path != "" && !filepath.IsAbs(*path) {
+			*path = filepath.Join(dir, *path)
+		}
+	}
+	abs(&c.CacheDir)
+	abs(&c.CAFile)
+	for i := range c.PluginDirectories {
+		abs(&c.PluginDirectories[i])
+	}
+	for i := range c.VarFiles {
+		abs(&c.VarFiles[i])
+	}
+	return nil
+}
+
+// setEnvironment sets the proxy and CA environment variables of the
+// config that aren't set yet, for the downloads of Packer and its
+// plugins, which inherit them.
+func (c *config) setEnvironment() {
+	set := func(value string, keys ...string) {
+		if value == "" {
+			return
+		}
+		for _, k := range keys {
+			if os.Getenv(k) != "" {
+				return
+			}
+		}
+		os.Setenv(keys[0], value)
+	}
+	set(c.HTTPProxy, "HTTP_PROXY", "http_proxy")
+	set(c.HTTPSProxy, "HTTPS_PROXY", "https_proxy")
+	set(c.NoProxy, "NO_PROXY", "no_proxy")
+	set(c.CAFile, "SSL_CERT_FILE")
+}
+
 // Discover discovers plugins.
 //
-// Search the directory of the executable, then the plugins directory, and
-// finally the CWD, in that order. Any conflicts will overwrite previously
+// Search the directory of the executable, then the plugins directory, the
+// plugin directories of the config file, and finally the CWD, in that order. Any conflicts will overwrite previously
 // found plugins, in that order.
 // Hence, the priority order is the reverse of the search order - i.e., the
 // CWD has the highest priority.
@@ -73,6 +156,13 @@ func (c *config) Discover() error {
 		}
 	}
 
+	// Next, look in the plugin directories of the config file.
+	for _, dir := range c.PluginDirectories {
+		if err := c.discover(dir); err != nil {
+			return err
+		}
+	}
+
 	// Next, look in the CWD.
 	if err := c.discover("."); err != nil {
 		return err
@@ -97,14 +187,22 @@ func (c *config) LoadBuilder(name string) (packer.Builder, error) {
 		return nil, nil
 	}
 
-	return c.pluginClient(bin).Builder()
+	client, err := c.pluginClient(bin)
+	if err != nil {
+		return nil, err
+	}
+	return client.Builder()
 }
 
 // This is a proper implementation of packer.HookFunc that can be used
 // to load packer.Hook implementations from the defined plugins.
 func (c *config) LoadHook(name string) (packer.Hook, error) {
 	log.Printf("Loading hook: %s\n", name)
-	return c.pluginClient(name).Hook()
+	client, err := c.pluginClient(name)
+	if err != nil {
+		return nil, err
+	}
+	return client.Hook()
 }
 
 // This is a proper packer.PostProcessorFunc that can be used to load
@@ -117,7 +215,11 @@ func (c *config) LoadPostProcessor(name string) (packer.PostProcessor, error) {
 		return nil, nil
 	}
 
-	return c.pluginClient(bin).PostProcessor()
+	client, err := c.pluginClient(bin)
+	if err != nil {
+		return nil, err
+	}
+	return client.PostProcessor()
 }
 
 // This is a proper packer.ProvisionerFunc that can be used to load
@@ -130,7 +232,11 @@ func (c *config) LoadProvisioner(name string) (packer.Provisioner, error) {
 		return nil, nil
 	}
 
-	return c.pluginClient(bin).Provisioner()
+	client, err := c.pluginClient(bin)
+	if err != nil {
+		return nil, err
+	}
+	return client.Provisioner()
 }
 
 func (c *config) discover(path string) error {
@@ -237,7 +343,24 @@ func (c *config) discoverInternal() error {
 	return nil
 }
 
-func (c *config) pluginClient(path string) *plugin.Client {
+func (c *config) pluginClient(path string) (*plugin.Client, error) {
+	path, args, internal := c.pluginPath(path)
+	if err := c.verifyPlugin(path, internal); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Creating plugin client for path: %s", path)
+	var config plugin.ClientConfig
+	config.Cmd = exec.Command(path, args...)
+	config.Managed = true
+	config.MinPort = c.PluginMinPort
+	config.MaxPort = c.PluginMaxPort
+	return plugin.NewClient(&config), nil
+}
+
+// pluginPath returns the binary to run for the plugin at path and its
+// args, and whether it's an internal plugin of this binary.
+func (c *config) pluginPath(path string) (string, []string, bool) {
 	originalPath := path
 
 	// First attempt to find the executable by consulting the PATH.
@@ -257,7 +380,8 @@ func (c *config) pluginClient(path string) *plugin.Client {
 
 	// Check for special case using `packer plugin PLUGIN`
 	args := []string{}
-	if strings.Contains(path, PACKERSPACE) {
+	internal := strings.Contains(path, PACKERSPACE)
+	if internal {
 		parts := strings.Split(path, PACKERSPACE)
 		path = parts[0]
 		args = parts[1:]
@@ -269,11 +393,55 @@ func (c *config) pluginClient(path string) *plugin.Client {
 		path = originalPath
 	}
 
-	log.Printf("Creating plugin client for path: %s", path)
-	var config plugin.ClientConfig
-	config.Cmd = exec.Command(path, args...)
-	config.Managed = true
-	config.MinPort = c.PluginMinPort
-	config.MaxPort = c.PluginMaxPort
-	return plugin.NewClient(&config)
+	return path, args, internal
+}
+
+// externalPlugins returns the binaries of the external plugins that were
+// discovered.
+func (c *config) externalPlugins() []string {
+	seen := make(map[string]bool)
+	var paths []string
+	for _, m := range []map[string]string{c.Builders, c.PostProcessors, c.Provisioners} {
+		for _, p := range m {
+			path, _, internal := c.pluginPath(p)
+			if internal || seen[path] {
+				continue
+			}
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// verifyPlugin checks the plugin binary at path against the plugin
+// lockfile, and refuses to run it if it doesn't match unless the plugins
+// are allowed to be insecure. The internal plugins are this binary, only
+// its version is checked for them.
+func (c *config) verifyPlugin(path string, internal bool) error {
+	lockPath, err := pluginlock.Path()
+	if err != nil {
+		return fmt.Errorf("Error finding the plugin lockfile: %s", err)
+	}
+	lock, err := pluginlock.Load(lockPath)
+	if err != nil {
+		return err
+	}
+
+	err = lock.CheckVersion(version.Version)
+	if err == nil && !internal {
+		err = lock.Verify(path)
+	}
+	if err == nil {
+		log.Printf("Plugin %s matches the plugin lockfile", path)
+		return nil
+	}
+	if c.insecurePlugins {
+		log.Printf("[WARN] Running unverified plugin: %s", err)
+		return nil
+	}
+	return fmt.Errorf("Refusing to run an unverified plugin. %s\n\n"+
+		"Update the lockfile with `packer plugins lock` if the plugins are the ones "+
+		"you expect, or pass -insecure-plugins to run them anyway.", err)
 }
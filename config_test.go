@@ -0,0 +1,62 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigPrepare(t *testing.T) {
+	var c config
+	err := decodeConfig(strings.NewReader(`{
+		"cache_dir": "cache",
+		"plugin_directories": ["/opt/packer/plugins", "plugins"],
+		"var_files": ["org.json"],
+		"on_error": "abort"
+	}`), &c)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	dir := filepath.FromSlash("/etc/packer")
+	if err := c.prepare(dir); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if c.CacheDir != filepath.Join(dir, "cache") {
+		t.Fatalf("bad: %s", c.CacheDir)
+	}
+	if c.PluginDirectories[0] != "/opt/packer/plugins" || c.PluginDirectories[1] != filepath.Join(dir, "plugins") {
+		t.Fatalf("bad: %#v", c.PluginDirectories)
+	}
+	if c.VarFiles[0] != filepath.Join(dir, "org.json") {
+		t.Fatalf("bad: %#v", c.VarFiles)
+	}
+
+	c.OnError = "retry"
+	if err := c.prepare(dir); err == nil {
+		t.Fatal("should error on a bad on_error")
+	}
+}
+
+func TestConfigSetEnvironment(t *testing.T) {
+	for _, k := range []string{"HTTP_PROXY", "http_proxy", "HTTPS_PROXY", "https_proxy"} {
+		defer os.Setenv(k, os.Getenv(k))
+		os.Unsetenv(k)
+	}
+	os.Setenv("https_proxy", "http://env:3128")
+
+	c := &config{
+		HTTPProxy:  "http://config:3128",
+		HTTPSProxy: "http://config:3128",
+	}
+	c.setEnvironment()
+
+	if v := os.Getenv("HTTP_PROXY"); v != "http://config:3128" {
+		t.Fatalf("bad: %s", v)
+	}
+	// The environment wins over the config
+	if v := os.Getenv("HTTPS_PROXY"); v != "" {
+		t.Fatalf("bad: %s", v)
+	}
+}
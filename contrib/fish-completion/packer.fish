@@ -0,0 +1,6 @@
+# Completions for packer. Copy this file to ~/.config/fish/completions/.
+#
+# Packer completes its own command line, like it does for bash and zsh with
+# `packer -autocomplete-install`: it prints the candidates for the line in
+# COMP_LINE. This includes the builds of the template for -only and -except.
+complete -c packer -f -a '(env COMP_LINE=(commandline -cp) packer)'
@@ -6,8 +6,6 @@ import (
 )
 
 func init() {
-	testTesting = true
-
 	if err := os.Setenv(TestEnvVar, "1"); err != nil {
 		panic(err)
 	}
@@ -5,6 +5,10 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"time"
+	"unicode"
+
+	"github.com/hashicorp/packer/common/random"
 )
 
 // Used to set variables which we need to access later in the build, where
@@ -29,3 +33,131 @@ func RetrieveSharedState(key string, buildName string) (string, error) {
 func RemoveSharedStateFile(key string, buildName string) {
 	os.Remove(sharedStateFilename(key, buildName))
 }
+
+// BuildValues are the values about a build that builders record once they
+// know them: the ID of the instance or VM, the Host and the Password the
+// communicator connected with, and the SourceImage it was started from.
+// Provisioners get them in PACKER_BUILD_ environment variables, and
+// templates read them, with the name and type of the build, with
+// {{build `ID`}}.
+var BuildValues = []string{"ID", "Host", "Password", "SourceImage"}
+
+// SetBuildValue records one of the BuildValues of a build.
+func SetBuildValue(key string, value string, buildName string) error {
+	return SetSharedState("build_"+key, value, buildName)
+}
+
+// BuildValue returns one of the BuildValues of a build, and whether it was
+// recorded.
+func BuildValue(key string, buildName string) (string, bool) {
+	value, err := RetrieveSharedState("build_"+key, buildName)
+	return value, err == nil
+}
+
+// RemoveBuildValue removes one of the BuildValues of a build, as a builder
+// does for the Password once it no longer needs it.
+func RemoveBuildValue(key string, buildName string) {
+	RemoveSharedStateFile("build_"+key, buildName)
+}
+
+// RecordedBuildValues returns the BuildValues recorded for a build so far.
+func RecordedBuildValues(buildName string) map[string]string {
+	values := make(map[string]string)
+	for _, key := range BuildValues {
+		if value, ok := BuildValue(key, buildName); ok {
+			values[key] = value
+		}
+	}
+	return values
+}
+
+// SetBuildStart records when the build started to run, for the
+// post-processors that report how long it took, which may run in other
+// processes.
+func SetBuildStart(buildName string, start time.Time) error {
+	return SetSharedState("started_at", start.Format(time.RFC3339Nano), buildName)
+}
+
+// BuildStart returns when the build started to run, and whether it was
+// recorded.
+func BuildStart(buildName string) (time.Time, bool) {
+	value, err := RetrieveSharedState("started_at", buildName)
+	if err != nil {
+		return time.Time{}, false
+	}
+	start, err := time.Parse(time.RFC3339Nano, value)
+	return start, err == nil
+}
+
+// generatedPasswordKey is the shared state of BuildPassword. It must not be
+// "build_password": on case insensitive file systems it is the same file as
+// the Password of the BuildValues, which builders set and remove.
+const generatedPasswordKey = "generated_password"
+
+// BuildPassword returns the password generated for a build, the same in all
+// the processes of the run, so that the answer file, the communicator, and
+// the provisioners of a build agree on it without a template hardcoding one.
+func BuildPassword(buildName string) (string, error) {
+	path := sharedStateFilename(generatedPasswordKey, buildName)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if os.IsExist(err) {
+		// Another process generated it, and may still be writing it
+		for i := 0; i < 10; i++ {
+			password, err := ioutil.ReadFile(path)
+			if err != nil {
+				return "", err
+			}
+			if len(password) > 0 {
+				return string(password), nil
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+		return "", fmt.Errorf("the password of build %s is empty", buildName)
+	}
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	password := random.Password(24)
+	if _, err := f.WriteString(password); err != nil {
+		return "", err
+	}
+	return password, nil
+}
+
+// BuildEnv returns the environment variables of the BuildValues recorded
+// for a build, such as PACKER_BUILD_ID and PACKER_BUILD_SOURCE_IMAGE.
+func BuildEnv(buildName string) map[string]string {
+	env := make(map[string]string)
+	for key, value := range RecordedBuildValues(buildName) {
+		env["PACKER_BUILD_"+buildEnvName(key)] = value
+	}
+	return env
+}
+
+func buildEnvName(key string) string {
+	var name []rune
+	prev := ' '
+	for _, r := range key {
+		if unicode.IsUpper(r) && unicode.IsLower(prev) {
+			name = append(name, '_')
+		}
+		name = append(name, unicode.ToUpper(r))
+		prev = r
+	}
+	return string(name)
+}
+
+// RemoveSharedState removes the shared state of the run, once Packer is
+// done with it.
+func RemoveSharedState() {
+	uuid := os.Getenv("PACKER_RUN_UUID")
+	if uuid == "" {
+		return
+	}
+	files, _ := filepath.Glob(filepath.Join(os.TempDir(), fmt.Sprintf("packer-%s-*", uuid)))
+	for _, f := range files {
+		os.Remove(f)
+	}
+}
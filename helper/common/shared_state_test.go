@@ -0,0 +1,81 @@
+package common
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuildEnv(t *testing.T) {
+	defer os.Setenv("PACKER_RUN_UUID", os.Getenv("PACKER_RUN_UUID"))
+	os.Setenv("PACKER_RUN_UUID", "shared-state-test")
+	defer RemoveSharedState()
+
+	SetBuildValue("ID", "i-1234", "foo")
+	SetBuildValue("SourceImage", "ami-5678", "foo")
+	SetBuildValue("ID", "i-other", "bar")
+
+	expected := map[string]string{
+		"PACKER_BUILD_ID":           "i-1234",
+		"PACKER_BUILD_SOURCE_IMAGE": "ami-5678",
+	}
+	if env := BuildEnv("foo"); !reflect.DeepEqual(env, expected) {
+		t.Fatalf("bad: %#v", env)
+	}
+
+	RemoveSharedState()
+	if _, ok := BuildValue("ID", "foo"); ok {
+		t.Fatal("should be removed")
+	}
+}
+
+func TestBuildPassword(t *testing.T) {
+	defer os.Setenv("PACKER_RUN_UUID", os.Getenv("PACKER_RUN_UUID"))
+	os.Setenv("PACKER_RUN_UUID", "build-password-test")
+	defer RemoveSharedState()
+
+	foo, err := BuildPassword("foo")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(foo) != 24 {
+		t.Fatalf("bad: %q", foo)
+	}
+	if again, _ := BuildPassword("foo"); again != foo {
+		t.Fatalf("the password of a build should not change: %q, %q", foo, again)
+	}
+	if bar, _ := BuildPassword("bar"); bar == foo {
+		t.Fatalf("builds should have their own password: %q", bar)
+	}
+}
+
+func TestBuildPassword_buildValue(t *testing.T) {
+	defer os.Setenv("PACKER_RUN_UUID", os.Getenv("PACKER_RUN_UUID"))
+	os.Setenv("PACKER_RUN_UUID", "build-password-value-test")
+	defer RemoveSharedState()
+
+	generated, err := BuildPassword("foo")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := SetBuildValue("Password", "connected-with", "foo"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if password, _ := BuildValue("Password", "foo"); password != "connected-with" {
+		t.Fatalf("bad build value: %q", password)
+	}
+	if again, _ := BuildPassword("foo"); again != generated {
+		t.Fatalf("the build value should not change the generated password: %q", again)
+	}
+
+	RemoveBuildValue("Password", "foo")
+	if again, _ := BuildPassword("foo"); again != generated {
+		t.Fatalf("removing the build value should keep the generated password: %q", again)
+	}
+
+	// They would be the same file on case insensitive file systems
+	if strings.EqualFold(sharedStateFilename(generatedPasswordKey, "foo"), sharedStateFilename("build_Password", "foo")) {
+		t.Fatal("the generated password and the build value should not share a file")
+	}
+}
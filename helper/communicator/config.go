@@ -65,6 +65,8 @@ type Config struct {
 	WinRMUseSSL             bool          `mapstructure:"winrm_use_ssl"`
 	WinRMInsecure           bool          `mapstructure:"winrm_insecure"`
 	WinRMUseNTLM            bool          `mapstructure:"winrm_use_ntlm"`
+	WinRMScramblePassword   bool          `mapstructure:"winrm_scramble_password"`
+	WinRMMaxEnvelopeSize    int           `mapstructure:"winrm_max_envelope_size"`
 	WinRMTransportDecorator func() winrm.Transporter
 }
 
@@ -209,7 +211,7 @@ func (c *Config) Prepare(ctx *interpolate.Context) []error {
 		if es := c.prepareWinRM(ctx); len(es) > 0 {
 			errs = append(errs, es...)
 		}
-	case "docker", "none":
+	case "docker", "none", "qemu-agent":
 		break
 	default:
 		return []error{fmt.Errorf("Communicator type %s is invalid", c.Type)}
@@ -318,6 +320,10 @@ func (c *Config) prepareWinRM(ctx *interpolate.Context) []error {
 		c.WinRMTimeout = 30 * time.Minute
 	}
 
+	if c.WinRMMaxEnvelopeSize == 0 {
+		c.WinRMMaxEnvelopeSize = 150
+	}
+
 	if c.WinRMUseNTLM == true {
 		c.WinRMTransportDecorator = func() winrm.Transporter { return &winrm.ClientNTLM{} }
 	}
@@ -326,6 +332,10 @@ func (c *Config) prepareWinRM(ctx *interpolate.Context) []error {
 	if c.WinRMUser == "" {
 		errs = append(errs, errors.New("winrm_username must be specified."))
 	}
+	if c.WinRMMaxEnvelopeSize < 32 {
+		errs = append(errs, errors.New("winrm_max_envelope_size must be at least 32 (KB)."))
+	}
+	packer.LogSecretFilter.Set(c.WinRMPassword)
 
 	return errs
 }
@@ -54,6 +54,24 @@ func TestConfig_winrm_noport(t *testing.T) {
 
 }
 
+func TestConfig_winrm_max_envelope_size(t *testing.T) {
+	c := &Config{
+		Type:      "winrm",
+		WinRMUser: "admin",
+	}
+	if err := c.Prepare(testContext(t)); len(err) > 0 {
+		t.Fatalf("bad: %#v", err)
+	}
+	if c.WinRMMaxEnvelopeSize != 150 {
+		t.Fatalf("bad: %d", c.WinRMMaxEnvelopeSize)
+	}
+
+	c.WinRMMaxEnvelopeSize = 16
+	if err := c.Prepare(testContext(t)); len(err) != 1 {
+		t.Fatalf("bad: %#v", err)
+	}
+}
+
 func TestConfig_winrm_noport_ssl(t *testing.T) {
 	c := &Config{
 		Type:        "winrm",
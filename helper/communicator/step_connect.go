@@ -6,6 +6,7 @@ import (
 	"log"
 
 	"github.com/hashicorp/packer/communicator/none"
+	commonhelper "github.com/hashicorp/packer/helper/common"
 	"github.com/hashicorp/packer/helper/multistep"
 	"github.com/hashicorp/packer/packer"
 	gossh "golang.org/x/crypto/ssh"
@@ -41,6 +42,11 @@ type StepConnect struct {
 	// existing types.
 	CustomConnect map[string]multistep.Step
 
+	// BuildName is the name of the build. If it's set, the host and the
+	// password that were connected with are recorded as build values for
+	// the provisioners and post-processors.
+	BuildName string
+
 	substep multistep.Step
 }
 
@@ -94,7 +100,16 @@ func (s *StepConnect) Run(ctx context.Context, state multistep.StateBag) multist
 	}
 
 	s.substep = step
-	return s.substep.Run(ctx, state)
+	action := s.substep.Run(ctx, state)
+	if action == multistep.ActionContinue && s.BuildName != "" {
+		if host, err := s.Host(state); err == nil {
+			commonhelper.SetBuildValue("Host", host, s.BuildName)
+		}
+		if password := s.Config.Password(); password != "" {
+			commonhelper.SetBuildValue("Password", password, s.BuildName)
+		}
+	}
+	return action
 }
 
 func (s *StepConnect) Cleanup(state multistep.StateBag) {
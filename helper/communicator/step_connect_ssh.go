@@ -85,6 +85,8 @@ func (s *StepConnectSSH) Cleanup(multistep.StateBag) {
 }
 
 func (s *StepConnectSSH) waitForSSH(state multistep.StateBag, cancel <-chan struct{}) (packer.Communicator, error) {
+	ui := state.Get("ui").(packer.Ui)
+
 	// Determine if we're using a bastion host, and if so, retrieve
 	// that configuration. This configuration doesn't change so we
 	// do this one before entering the retry loop.
@@ -134,14 +136,14 @@ func (s *StepConnectSSH) waitForSSH(state multistep.StateBag, cancel <-chan stru
 		// First we request the TCP connection information
 		host, err := s.Host(state)
 		if err != nil {
-			log.Printf("[DEBUG] Error getting SSH address: %s", err)
+			packer.UiLog(ui, packer.UiLevelDebug, fmt.Sprintf("Error getting SSH address: %s", err))
 			continue
 		}
 		port := s.Config.SSHPort
 		if s.SSHPort != nil {
 			port, err = s.SSHPort(state)
 			if err != nil {
-				log.Printf("[DEBUG] Error getting SSH port: %s", err)
+				packer.UiLog(ui, packer.UiLevelDebug, fmt.Sprintf("Error getting SSH port: %s", err))
 				continue
 			}
 		}
@@ -149,7 +151,7 @@ func (s *StepConnectSSH) waitForSSH(state multistep.StateBag, cancel <-chan stru
 		// Retrieve the SSH configuration
 		sshConfig, err := s.SSHConfig(state)
 		if err != nil {
-			log.Printf("[DEBUG] Error getting SSH config: %s", err)
+			packer.UiLog(ui, packer.UiLevelDebug, fmt.Sprintf("Error getting SSH config: %s", err))
 			continue
 		}
 
@@ -170,7 +172,7 @@ func (s *StepConnectSSH) waitForSSH(state multistep.StateBag, cancel <-chan stru
 
 		nc, err := connFunc()
 		if err != nil {
-			log.Printf("[DEBUG] TCP connection to SSH ip/port failed: %s", err)
+			packer.UiLog(ui, packer.UiLevelDebug, fmt.Sprintf("TCP connection to SSH ip/port failed: %s", err))
 			continue
 		}
 		nc.Close()
@@ -186,10 +188,10 @@ func (s *StepConnectSSH) waitForSSH(state multistep.StateBag, cancel <-chan stru
 			Timeout:                s.Config.SSHReadWriteTimeout,
 		}
 
-		log.Println("[INFO] Attempting SSH connection...")
+		packer.UiLog(ui, packer.UiLevelDebug, fmt.Sprintf("Attempting SSH connection to %s...", address))
 		comm, err = ssh.New(address, config)
 		if err != nil {
-			log.Printf("[DEBUG] SSH handshake err: %s", err)
+			packer.UiLog(ui, packer.UiLevelDebug, fmt.Sprintf("SSH handshake err: %s", err))
 
 			// Only count this as an attempt if we were able to attempt
 			// to authenticate. Note this is very brittle since it depends
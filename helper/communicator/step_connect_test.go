@@ -3,8 +3,10 @@ package communicator
 import (
 	"bytes"
 	"context"
+	"os"
 	"testing"
 
+	commonhelper "github.com/hashicorp/packer/helper/common"
 	"github.com/hashicorp/packer/helper/multistep"
 	"github.com/hashicorp/packer/packer"
 )
@@ -29,6 +31,47 @@ func TestStepConnect_none(t *testing.T) {
 	}
 }
 
+func TestStepConnect_buildValues(t *testing.T) {
+	defer os.Setenv("PACKER_RUN_UUID", os.Getenv("PACKER_RUN_UUID"))
+	os.Setenv("PACKER_RUN_UUID", "step-connect-test")
+	defer commonhelper.RemoveSharedState()
+
+	state := testState(t)
+	step := &StepConnect{
+		Config: &Config{
+			Type:        "ssh",
+			SSHPassword: "secret",
+		},
+		Host: func(multistep.StateBag) (string, error) {
+			return "10.0.0.1", nil
+		},
+		CustomConnect: map[string]multistep.Step{
+			"ssh": new(testStepConnected),
+		},
+		BuildName: "test",
+	}
+	defer step.Cleanup(state)
+
+	if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if host, _ := commonhelper.BuildValue("Host", "test"); host != "10.0.0.1" {
+		t.Fatalf("bad: %s", host)
+	}
+	if password, _ := commonhelper.BuildValue("Password", "test"); password != "secret" {
+		t.Fatalf("bad: %s", password)
+	}
+}
+
+// testStepConnected stands in for a communicator that connects right away.
+type testStepConnected struct{}
+
+func (*testStepConnected) Run(context.Context, multistep.StateBag) multistep.StepAction {
+	return multistep.ActionContinue
+}
+
+func (*testStepConnected) Cleanup(multistep.StateBag) {}
+
 func testState(t *testing.T) multistep.StateBag {
 	state := new(multistep.BasicStateBag)
 	state.Put("hook", &packer.MockHook{})
@@ -87,6 +87,8 @@ func (s *StepConnectWinRM) Cleanup(multistep.StateBag) {
 }
 
 func (s *StepConnectWinRM) waitForWinRM(state multistep.StateBag, cancel <-chan struct{}) (packer.Communicator, error) {
+	ui := state.Get("ui").(packer.Ui)
+
 	var comm packer.Communicator
 	for {
 		select {
@@ -98,7 +100,7 @@ func (s *StepConnectWinRM) waitForWinRM(state multistep.StateBag, cancel <-chan
 
 		host, err := s.Host(state)
 		if err != nil {
-			log.Printf("[DEBUG] Error getting WinRM host: %s", err)
+			packer.UiLog(ui, packer.UiLevelDebug, fmt.Sprintf("Error getting WinRM host: %s", err))
 			continue
 		}
 
@@ -106,7 +108,7 @@ func (s *StepConnectWinRM) waitForWinRM(state multistep.StateBag, cancel <-chan
 		if s.WinRMPort != nil {
 			port, err = s.WinRMPort(state)
 			if err != nil {
-				log.Printf("[DEBUG] Error getting WinRM port: %s", err)
+				packer.UiLog(ui, packer.UiLevelDebug, fmt.Sprintf("Error getting WinRM port: %s", err))
 				continue
 			}
 		}
@@ -116,7 +118,7 @@ func (s *StepConnectWinRM) waitForWinRM(state multistep.StateBag, cancel <-chan
 		if s.WinRMConfig != nil {
 			config, err := s.WinRMConfig(state)
 			if err != nil {
-				log.Printf("[DEBUG] Error getting WinRM config: %s", err)
+				packer.UiLog(ui, packer.UiLevelDebug, fmt.Sprintf("Error getting WinRM config: %s", err))
 				continue
 			}
 
@@ -128,7 +130,7 @@ func (s *StepConnectWinRM) waitForWinRM(state multistep.StateBag, cancel <-chan
 			}
 		}
 
-		log.Println("[INFO] Attempting WinRM connection...")
+		packer.UiLog(ui, packer.UiLevelDebug, fmt.Sprintf("Attempting WinRM connection to %s:%d...", host, port))
 		comm, err = winrm.New(&winrm.Config{
 			Host:               host,
 			Port:               port,
@@ -137,10 +139,11 @@ func (s *StepConnectWinRM) waitForWinRM(state multistep.StateBag, cancel <-chan
 			Timeout:            s.Config.WinRMTimeout,
 			Https:              s.Config.WinRMUseSSL,
 			Insecure:           s.Config.WinRMInsecure,
+			MaxEnvelopeSize:    s.Config.WinRMMaxEnvelopeSize,
 			TransportDecorator: s.Config.WinRMTransportDecorator,
 		})
 		if err != nil {
-			log.Printf("[ERROR] WinRM connection err: %s", err)
+			packer.UiLog(ui, packer.UiLevelDebug, fmt.Sprintf("WinRM connection err: %s", err))
 			continue
 		}
 
@@ -163,11 +166,10 @@ func (s *StepConnectWinRM) waitForWinRM(state multistep.StateBag, cancel <-chan
 		}
 
 		log.Printf("Checking that WinRM is connected with: '%s'", connectCheckCommand)
-		ui := state.Get("ui").(packer.Ui)
 		err := cmd.StartWithUi(comm, ui)
 
 		if err != nil {
-			log.Printf("Communication connection err: %s", err)
+			packer.UiLog(ui, packer.UiLevelDebug, fmt.Sprintf("Communication connection err: %s", err))
 			continue
 		}
 
@@ -0,0 +1,101 @@
+package communicator
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hashicorp/packer/common/random"
+	"github.com/hashicorp/packer/communicator/winrm"
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+// StepScramblePassword changes the password of the WinRM user to a random
+// one that isn't recorded anywhere, when winrm_scramble_password is set, so
+// the password of the build doesn't work on the machines of the image. The
+// communicator is connected again with it for the steps after this one, like
+// the shutdown.
+//
+// Uses:
+//   communicator packer.Communicator
+//   ui           packer.Ui
+//
+// Produces:
+//   communicator packer.Communicator - Connected with the new password.
+type StepScramblePassword struct {
+	Config *Config
+
+	// retryInterval is how often the new password is tried until it works.
+	retryInterval time.Duration
+}
+
+func (s *StepScramblePassword) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	if s.Config.Type != "winrm" || !s.Config.WinRMScramblePassword {
+		return multistep.ActionContinue
+	}
+
+	ui := state.Get("ui").(packer.Ui)
+	comm, ok := state.Get("communicator").(*winrm.Communicator)
+	if !ok {
+		log.Printf("Not scrambling the password, the communicator isn't a WinRM one")
+		return multistep.ActionContinue
+	}
+
+	password := random.Password(32)
+	packer.LogSecretFilter.Set(password)
+
+	// The password is changed by a process of its own a few seconds later,
+	// so the command can return with the current one
+	ui.Say(fmt.Sprintf("Scrambling the password of %s...", comm.Username()))
+	cmd := &packer.RemoteCmd{Command: scramblePasswordCommand(comm.Username(), password)}
+	if err := cmd.StartWithUi(comm, ui); err != nil {
+		err := fmt.Errorf("Error scrambling the password: %s", err)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+	if cmd.ExitStatus != 0 {
+		err := fmt.Errorf("Error scrambling the password: exit status %d", cmd.ExitStatus)
+		state.Put("error", err)
+		ui.Error(err.Error())
+		return multistep.ActionHalt
+	}
+
+	retryInterval := s.retryInterval
+	if retryInterval == 0 {
+		retryInterval = 5 * time.Second
+	}
+	timeout := time.After(s.Config.WinRMTimeout)
+	for {
+		select {
+		case <-ctx.Done():
+			return multistep.ActionHalt
+		case <-timeout:
+			err := fmt.Errorf("Timeout waiting for the scrambled password of %s to work", comm.Username())
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		case <-time.After(retryInterval):
+		}
+
+		newComm, err := comm.Reconnect(password)
+		if err != nil {
+			log.Printf("The scrambled password doesn't work yet: %s", err)
+			continue
+		}
+		state.Put("communicator", newComm)
+		return multistep.ActionContinue
+	}
+}
+
+func (s *StepScramblePassword) Cleanup(multistep.StateBag) {}
+
+// scramblePasswordCommand changes the password of the user from a process
+// that isn't a child of the WinRM shell, so it is not killed with it.
+func scramblePasswordCommand(user, password string) string {
+	return fmt.Sprintf(`powershell -NoProfile -Command "`+
+		`$p = Invoke-WmiMethod -Class Win32_Process -Name Create -ArgumentList 'cmd.exe /c ping -n 6 127.0.0.1 >nul & net user %s %s'; `+
+		`exit $p.ReturnValue"`, user, password)
+}
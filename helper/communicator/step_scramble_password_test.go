@@ -0,0 +1,41 @@
+package communicator
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer/helper/multistep"
+	"github.com/hashicorp/packer/packer"
+)
+
+func TestStepScramblePassword_impl(t *testing.T) {
+	var _ multistep.Step = new(StepScramblePassword)
+}
+
+func TestStepScramblePassword_disabled(t *testing.T) {
+	comm := new(packer.MockCommunicator)
+	cases := []*Config{
+		{Type: "winrm"},
+		{Type: "ssh", WinRMScramblePassword: true},
+	}
+	for _, config := range cases {
+		state := testState(t)
+		state.Put("communicator", comm)
+
+		step := &StepScramblePassword{Config: config}
+		if action := step.Run(context.Background(), state); action != multistep.ActionContinue {
+			t.Fatalf("bad action: %#v", action)
+		}
+		if comm.StartCalled {
+			t.Fatalf("should not run a command: %#v", config)
+		}
+	}
+}
+
+func TestScramblePasswordCommand(t *testing.T) {
+	command := scramblePasswordCommand("packer", "Secret-1")
+	if !strings.Contains(command, "net user packer Secret-1'") {
+		t.Fatalf("bad: %s", command)
+	}
+}
@@ -1,7 +1,7 @@
 package config
 
 import (
-	"fmt"
+	"encoding/hex"
 	"reflect"
 	"sort"
 	"strings"
@@ -46,6 +46,8 @@ func Decode(target interface{}, config *DecodeOpts, raws ...interface{}) error {
 			config.InterpolateContext.BuildType = ctx.BuildType
 			config.InterpolateContext.TemplatePath = ctx.TemplatePath
 			config.InterpolateContext.UserVariables = ctx.UserVariables
+			config.InterpolateContext.BuildValues = ctx.BuildValues
+			config.InterpolateContext.RandomSeed = ctx.RandomSeed
 		}
 		ctx = config.InterpolateContext
 
@@ -88,12 +90,13 @@ func Decode(target interface{}, config *DecodeOpts, raws ...interface{}) error {
 
 	// If we have unused keys, it is an error
 	if len(md.Unused) > 0 {
+		c := detectComponent(raws...)
+
 		var err error
 		sort.Strings(md.Unused)
 		for _, unused := range md.Unused {
 			if unused != "type" && !strings.HasPrefix(unused, "packer_") {
-				err = multierror.Append(err, fmt.Errorf(
-					"unknown configuration key: %q", unused))
+				err = multierror.Append(err, unknownKeyError(unused, target, c))
 			}
 		}
 		if err != nil {
@@ -113,6 +116,8 @@ func DetectContext(raws ...interface{}) (*interpolate.Context, error) {
 		TemplatePath  string            `mapstructure:"packer_template_path"`
 		Vars          map[string]string `mapstructure:"packer_user_variables"`
 		SensitiveVars []string          `mapstructure:"packer_sensitive_variables"`
+		BuildValues   map[string]string `mapstructure:"packer_build_values"`
+		RandomSeed    string            `mapstructure:"packer_random_seed"`
 	}
 
 	for _, r := range raws {
@@ -121,12 +126,17 @@ func DetectContext(raws ...interface{}) (*interpolate.Context, error) {
 		}
 	}
 
+	// The seed is hex encoded, an invalid one leaves the package seed.
+	seed, _ := hex.DecodeString(s.RandomSeed)
+
 	return &interpolate.Context{
+		RandomSeed:         seed,
 		BuildName:          s.BuildName,
 		BuildType:          s.BuildType,
 		TemplatePath:       s.TemplatePath,
 		UserVariables:      s.Vars,
 		SensitiveVariables: s.SensitiveVars,
+		BuildValues:        s.BuildValues,
 	}, nil
 }
 
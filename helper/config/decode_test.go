@@ -1,7 +1,9 @@
 package config
 
 import (
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -118,3 +120,100 @@ func TestDecode(t *testing.T) {
 		}
 	}
 }
+
+func TestDecode_unknownKeys(t *testing.T) {
+	type Comm struct {
+		SSHUsername string `mapstructure:"ssh_username"`
+	}
+	type Target struct {
+		Comm `mapstructure:",squash"`
+
+		Name string
+		Disk struct {
+			Size int
+		} `mapstructure:"disk"`
+	}
+
+	path, err := filepath.Abs(filepath.Join("test-fixtures", "unknown-keys.json"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var result Target
+	err = Decode(&result, nil, map[string]interface{}{
+		"ssh_usernmae":          "packer",
+		"nmae":                  "foo",
+		"disk":                  map[string]interface{}{"sizee": 10},
+		"completely_different":  true,
+		"packer_component_file": path,
+		"packer_component_path": "builders[0]",
+	})
+	if err == nil {
+		t.Fatal("should error")
+	}
+
+	for _, expected := range []string{
+		`unknown configuration key: "ssh_usernmae" at unknown-keys.json:5; did you mean "ssh_username"?`,
+		`unknown configuration key: "nmae" at unknown-keys.json:6; did you mean "name"?`,
+		`unknown configuration key: "disk.sizee" at unknown-keys.json:8`,
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Fatalf("missing %q:\n\n%s", expected, err)
+		}
+	}
+	if !strings.Contains(err.Error(), `unknown configuration key: "completely_different"`) ||
+		strings.Contains(err.Error(), `"completely_different" at`) ||
+		strings.Contains(err.Error(), `"completely_different"; did you mean`) {
+		t.Fatalf("bad:\n\n%s", err)
+	}
+}
+
+func TestDecode_unknownKeysOverride(t *testing.T) {
+	type Target struct {
+		Name string
+	}
+
+	path, err := filepath.Abs(filepath.Join("test-fixtures", "unknown-keys.json"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var result Target
+	err = Decode(&result, nil, map[string]interface{}{
+		"ssh_usernmae":          "packer",
+		"nmae":                  "foo",
+		"packer_build_name":     "web",
+		"packer_component_file": path,
+		"packer_component_path": "provisioners[0]",
+	})
+	if err == nil {
+		t.Fatal("should error")
+	}
+
+	for _, expected := range []string{
+		`unknown configuration key: "nmae" at unknown-keys.json:15; did you mean "name"?`,
+		`unknown configuration key: "ssh_usernmae" at unknown-keys.json:18`,
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Fatalf("missing %q:\n\n%s", expected, err)
+		}
+	}
+}
+
+func TestSuggestKey(t *testing.T) {
+	keys := []string{"ssh_username", "ssh_password", "vm_name", "disk_size"}
+	cases := map[string]string{
+		"ssh_usernmae": "ssh_username",
+		"ssh_pasword":  "ssh_password",
+		"SSH_USERNAME": "ssh_username",
+		"vmname":       "vm_name",
+		"disksize":     "disk_size",
+		"foo":          "",
+		"iso_url":      "",
+	}
+	for unknown, expected := range cases {
+		if actual := suggestKey(unknown, keys); actual != expected {
+			t.Fatalf("%s: expected %q, got %q", unknown, expected, actual)
+		}
+	}
+}
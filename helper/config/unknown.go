@@ -0,0 +1,176 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/packer/template"
+	"github.com/mitchellh/mapstructure"
+)
+
+// component is where the configuration is in the template, as the core
+// sends it.
+type component struct {
+	BuildName string `mapstructure:"packer_build_name"`
+	File      string `mapstructure:"packer_component_file"`
+	Path      string `mapstructure:"packer_component_path"`
+}
+
+func detectComponent(raws ...interface{}) component {
+	var c component
+	for _, r := range raws {
+		mapstructure.Decode(r, &c)
+	}
+	return c
+}
+
+// unknownKeyError is the error of a key the configuration doesn't have,
+// with where it is in the template and the key that was likely meant.
+func unknownKeyError(key string, target interface{}, c component) error {
+	msg := fmt.Sprintf("unknown configuration key: %q", key)
+
+	var positions []string
+	for _, line := range c.keyLines(key) {
+		positions = append(positions, fmt.Sprintf("%s:%d", filepath.Base(c.File), line))
+	}
+	if len(positions) > 0 {
+		msg += " at " + strings.Join(positions, ", ")
+	}
+
+	if !strings.ContainsAny(key, ".[") {
+		if suggestion := suggestKey(key, configKeys(reflect.TypeOf(target))); suggestion != "" {
+			msg += fmt.Sprintf("; did you mean %q?", suggestion)
+		}
+	}
+
+	return fmt.Errorf("%s", msg)
+}
+
+// configKeys are the keys the configuration of type t has, following the
+// squashed structs like mapstructure does.
+func configKeys(t reflect.Type) []string {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+
+		tag := strings.Split(f.Tag.Get("mapstructure"), ",")
+		squash := false
+		for _, opt := range tag[1:] {
+			if opt == "squash" {
+				squash = true
+			}
+		}
+		if squash {
+			keys = append(keys, configKeys(f.Type)...)
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+
+		name := tag[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		if !strings.HasPrefix(name, "packer_") {
+			keys = append(keys, name)
+		}
+	}
+	return keys
+}
+
+// suggestKey returns the key closest to the unknown one, if one is close
+// enough that it was likely meant, like ssh_username for ssh_usernmae.
+func suggestKey(unknown string, keys []string) string {
+	sort.Strings(keys)
+	best, bestDistance := "", len(unknown)/4+2
+	for _, key := range keys {
+		d := editDistance(strings.ToLower(unknown), key)
+		if d < bestDistance {
+			best, bestDistance = key, d
+		}
+	}
+	return best
+}
+
+// editDistance is the number of insertions, deletions, substitutions, and
+// transpositions of adjacent characters to turn a into b.
+func editDistance(a, b string) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = minInt(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = minInt(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
+
+// keyLines are the lines of the file where the key of the component is
+// set, in the component or in its override for the build. Keys in nested
+// configuration are like "parent.key", as they are in template.Positions.
+// They aren't known for templates that aren't JSON files.
+func (c component) keyLines(key string) []int {
+	if c.File == "" || c.Path == "" {
+		return nil
+	}
+	contents, err := ioutil.ReadFile(c.File)
+	if err != nil {
+		return nil
+	}
+
+	positions := template.NewPositions(contents)
+	paths := []string{c.Path + "." + key}
+	if c.BuildName != "" {
+		paths = append(paths, fmt.Sprintf("%s.override.%s.%s", c.Path, c.BuildName, key))
+	}
+
+	var lines []int
+	for _, path := range paths {
+		if line, _ := positions.Position(path); line > 0 {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
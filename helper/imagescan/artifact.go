@@ -0,0 +1,49 @@
+package imagescan
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+// Artifact is a scanned artifact with the documents written by the scan
+// added to its files. It keeps the builder ID and ID of the scanned artifact
+// so post-processors further down the chain, like docker-push, still accept
+// it.
+type Artifact struct {
+	Parent  packer.Artifact
+	Reports []string
+}
+
+func (a *Artifact) BuilderId() string {
+	return a.Parent.BuilderId()
+}
+
+func (a *Artifact) Files() []string {
+	return append(append([]string{}, a.Parent.Files()...), a.Reports...)
+}
+
+func (a *Artifact) Id() string {
+	return a.Parent.Id()
+}
+
+func (a *Artifact) String() string {
+	return fmt.Sprintf("%s\nReports: %s", a.Parent.String(), strings.Join(a.Reports, ", "))
+}
+
+func (a *Artifact) State(name string) interface{} {
+	return a.Parent.State(name)
+}
+
+// Destroy only removes the reports, the scanned artifact is kept as the
+// input artifact of the post-processor.
+func (a *Artifact) Destroy() error {
+	for _, f := range a.Reports {
+		if err := os.RemoveAll(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
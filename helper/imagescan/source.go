@@ -0,0 +1,109 @@
+// Package imagescan has the helpers shared by the post-processors that scan
+// the contents of an image with tools from the syft and grype family.
+package imagescan
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/post-processor/docker-import"
+	"github.com/hashicorp/packer/post-processor/docker-save"
+	"github.com/hashicorp/packer/post-processor/docker-tag"
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+const (
+	DefaultMountCommand   = "guestmount -a {{.Disk}} -i --ro {{.MountPath}}"
+	DefaultUnmountCommand = "guestunmount {{.MountPath}}"
+)
+
+type mountCommandTemplate struct {
+	Disk      string
+	MountPath string
+}
+
+// Source returns the scanner source for an artifact, like
+// "docker:image:tag". Disk images have to be mounted first, so for them the
+// disk is returned instead.
+func Source(artifact packer.Artifact) (string, string, error) {
+	switch artifact.BuilderId() {
+	case dockerimport.BuilderId, dockertag.BuilderId:
+		return "docker:" + artifact.Id(), "", nil
+	case dockersave.BuilderId:
+		for _, path := range artifact.Files() {
+			return "docker-archive:" + path, "", nil
+		}
+	}
+
+	// The qemu builder records which of its files is the disk.
+	if name, ok := artifact.State("diskName").(string); ok && name != "" {
+		for _, path := range artifact.Files() {
+			if filepath.Base(path) == name {
+				return "", path, nil
+			}
+		}
+	}
+
+	for _, path := range artifact.Files() {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".raw", ".img", ".qcow2", ".vmdk", ".vhd", ".vhdx", ".vdi":
+			return "", path, nil
+		}
+	}
+
+	return "", "", fmt.Errorf(
+		"Can't scan artifact from %s. Only docker images and disk images can be scanned",
+		artifact.BuilderId())
+}
+
+// Mount mounts disk read-only on a new temporary directory with the mount
+// command template and returns the "dir:" source for it. The returned
+// function unmounts the disk and removes the directory.
+func Mount(ctx interpolate.Context, mountCommand, unmountCommand, disk string) (string, func() error, error) {
+	mountPath, err := ioutil.TempDir("", "packer-scan")
+	if err != nil {
+		return "", nil, err
+	}
+
+	ctx.Data = &mountCommandTemplate{Disk: disk, MountPath: mountPath}
+	if err := runCommand(mountCommand, &ctx); err != nil {
+		os.RemoveAll(mountPath)
+		return "", nil, fmt.Errorf("Error mounting %s: %s", disk, err)
+	}
+
+	unmount := func() error {
+		if err := runCommand(unmountCommand, &ctx); err != nil {
+			return fmt.Errorf("Error unmounting %s: %s", mountPath, err)
+		}
+		return os.RemoveAll(mountPath)
+	}
+	return "dir:" + mountPath, unmount, nil
+}
+
+func runCommand(command string, ctx *interpolate.Context) error {
+	rendered, err := interpolate.Render(command, ctx)
+	if err != nil {
+		return err
+	}
+
+	fields := strings.Fields(rendered)
+	if len(fields) == 0 {
+		return fmt.Errorf("empty command")
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(fields[0], fields[1:]...)
+	cmd.Stderr = &stderr
+	log.Printf("Executing: %s", rendered)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s\n\nStderr: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package imagescan
+
+import (
+	"testing"
+
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/post-processor/docker-save"
+	"github.com/hashicorp/packer/post-processor/docker-tag"
+)
+
+func TestArtifact_ImplementsArtifact(t *testing.T) {
+	var _ packer.Artifact = new(Artifact)
+}
+
+func TestSource(t *testing.T) {
+	cases := []struct {
+		artifact *packer.MockArtifact
+		source   string
+		disk     string
+		err      bool
+	}{
+		{
+			artifact: &packer.MockArtifact{BuilderIdValue: dockertag.BuilderId, IdValue: "app:1.0"},
+			source:   "docker:app:1.0",
+		},
+		{
+			artifact: &packer.MockArtifact{BuilderIdValue: dockersave.BuilderId, FilesValue: []string{"app.tar"}},
+			source:   "docker-archive:app.tar",
+		},
+		{
+			artifact: &packer.MockArtifact{
+				BuilderIdValue: "transcend.qemu",
+				FilesValue:     []string{"out/packer-vm"},
+				StateValues:    map[string]interface{}{"diskName": "packer-vm"},
+			},
+			disk: "out/packer-vm",
+		},
+		{
+			artifact: &packer.MockArtifact{FilesValue: []string{"out/disk.vmdk", "out/disk.vmx"}},
+			disk:     "out/disk.vmdk",
+		},
+		{
+			artifact: &packer.MockArtifact{FilesValue: []string{"out/notes.txt"}},
+			err:      true,
+		},
+	}
+
+	for i, tc := range cases {
+		source, disk, err := Source(tc.artifact)
+		if (err != nil) != tc.err {
+			t.Errorf("%d: expected error %t, got %v", i, tc.err, err)
+			continue
+		}
+		if source != tc.source || disk != tc.disk {
+			t.Errorf("%d: got %q %q", i, source, disk)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+// Package ledger keeps track on disk of the cloud resources that builds
+// create, so the ones left behind by a build that crashed or was killed
+// can be found and deleted later with `packer cleanup`.
+//
+// Each resource is one file in the ledger directory, written as soon as
+// the resource exists and removed once it is deleted. Files are written
+// by the builder plugins themselves, so they are there whatever happens to
+// the process.
+package ledger
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/packer/common/random"
+	"github.com/hashicorp/packer/packer"
+)
+
+// Resource is a cloud resource created by a build.
+type Resource struct {
+	// Provider is the cloud the resource is in, such as "amazon", and
+	// says who can delete it.
+	Provider string `json:"provider"`
+
+	// Type is the kind of resource for the provider, such as "instance".
+	Type string `json:"type"`
+
+	// ID is what identifies the resource for the provider.
+	ID string `json:"id"`
+
+	// Region is where the resource is, if the provider has regions.
+	Region string `json:"region,omitempty"`
+
+	// Created, PID, Host, and Run are set by Track. A resource is an orphan
+	// once the process that created it is gone.
+	Created time.Time `json:"created"`
+	PID     int       `json:"pid"`
+	Host    string    `json:"host"`
+
+	// Run is the ID of the run of Packer that created the resource.
+	Run string `json:"run,omitempty"`
+
+	path string
+}
+
+// Deleter deletes a resource of its provider. A resource that doesn't
+// exist anymore is not an error.
+type Deleter func(*Resource) error
+
+func (r *Resource) String() string {
+	s := fmt.Sprintf("%s %s %s", r.Provider, r.Type, r.ID)
+	if r.Region != "" {
+		s += fmt.Sprintf(" (%s)", r.Region)
+	}
+	return s
+}
+
+// Orphaned says whether the process that created the resource is gone.
+// Resources created on another host can't be checked and are not
+// orphans.
+func (r *Resource) Orphaned() bool {
+	host, _ := os.Hostname()
+	if r.Host != host {
+		return false
+	}
+	return !processAlive(r.PID)
+}
+
+// Dir returns the directory of the ledger, which is PACKER_LEDGER_DIR or
+// "ledger" in the configuration directory of Packer.
+func Dir() (string, error) {
+	if dir := os.Getenv("PACKER_LEDGER_DIR"); dir != "" {
+		return filepath.Abs(dir)
+	}
+
+	dir, err := packer.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "ledger"), nil
+}
+
+var unsafeChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// Track records the resource in the ledger. Failing to do so doesn't
+// stop the build, so the error is only logged.
+func Track(r *Resource) {
+	if err := track(r); err != nil {
+		log.Printf("[WARN] Failed to record %s in the ledger: %s", r, err)
+	}
+}
+
+func track(r *Resource) error {
+	dir, err := Dir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	r.Created = time.Now().UTC()
+	r.PID = os.Getpid()
+	r.Host, _ = os.Hostname()
+	r.Run = os.Getenv("PACKER_RUN_UUID")
+
+	raw, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write it whole or not at all
+	name := unsafeChars.ReplaceAllString(
+		strings.Join([]string{r.Provider, r.Type, r.ID, random.AlphaNumLower(8)}, "-"), "_")
+	path := filepath.Join(dir, name+".json")
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, raw, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	r.path = path
+	log.Printf("Recorded %s in the ledger: %s", r, path)
+	return nil
+}
+
+// Release removes the resource from the ledger once it is deleted.
+func Release(r *Resource) {
+	if r == nil || r.path == "" {
+		return
+	}
+
+	if err := os.Remove(r.path); err != nil && !os.IsNotExist(err) {
+		log.Printf("[WARN] Failed to remove %s from the ledger: %s", r, err)
+		return
+	}
+	r.path = ""
+}
+
+// ListRun returns the resources in the ledger created by the run of
+// Packer with the ID, such as the ones its builds couldn't delete.
+func ListRun(run string) ([]*Resource, error) {
+	resources, err := List()
+	if err != nil || run == "" {
+		return nil, err
+	}
+
+	var result []*Resource
+	for _, r := range resources {
+		if r.Run == run {
+			result = append(result, r)
+		}
+	}
+	return result, nil
+}
+
+// List returns the resources in the ledger, oldest first.
+func List() ([]*Resource, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*Resource
+	for _, path := range paths {
+		raw, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		var r Resource
+		if err := json.Unmarshal(raw, &r); err != nil {
+			return nil, fmt.Errorf("Error reading %s: %s", path, err)
+		}
+		r.path = path
+		result = append(result, &r)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Created.Before(result[j].Created)
+	})
+
+	return result, nil
+}
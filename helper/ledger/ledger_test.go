@@ -0,0 +1,106 @@
+package ledger
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func testLedgerDir(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "packer-ledger")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	old := os.Getenv("PACKER_LEDGER_DIR")
+	os.Setenv("PACKER_LEDGER_DIR", dir)
+	return func() {
+		os.Setenv("PACKER_LEDGER_DIR", old)
+		os.RemoveAll(dir)
+	}
+}
+
+func TestTrack(t *testing.T) {
+	defer testLedgerDir(t)()
+
+	r := &Resource{Provider: "amazon", Type: "instance", ID: "i-123", Region: "us-east-1"}
+	Track(r)
+	Track(&Resource{Provider: "amazon", Type: "key_pair", ID: "packer 5c2f/key"})
+
+	resources, err := List()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(resources) != 2 {
+		t.Fatalf("bad: %#v", resources)
+	}
+
+	got := resources[0]
+	if got.String() != "amazon instance i-123 (us-east-1)" {
+		t.Fatalf("bad: %s", got)
+	}
+	if got.PID != os.Getpid() || got.Created.IsZero() {
+		t.Fatalf("bad: %#v", got)
+	}
+	if got.Orphaned() {
+		t.Fatal("the resources of this process are not orphans")
+	}
+	if resources[1].ID != "packer 5c2f/key" {
+		t.Fatalf("bad: %#v", resources[1])
+	}
+
+	Release(r)
+	resources, err = List()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(resources) != 1 || resources[0].Type != "key_pair" {
+		t.Fatalf("bad: %#v", resources)
+	}
+
+	// Releasing what List returns works too, and twice is fine
+	Release(resources[0])
+	Release(resources[0])
+	dir, _ := Dir()
+	if files, _ := filepath.Glob(filepath.Join(dir, "*")); len(files) != 0 {
+		t.Fatalf("bad: %#v", files)
+	}
+}
+
+func TestList_empty(t *testing.T) {
+	defer testLedgerDir(t)()
+
+	os.Setenv("PACKER_LEDGER_DIR", filepath.Join(os.Getenv("PACKER_LEDGER_DIR"), "missing"))
+	resources, err := List()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(resources) != 0 {
+		t.Fatalf("bad: %#v", resources)
+	}
+}
+
+func TestResourceOrphaned(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a command that exits")
+	}
+
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	host, _ := os.Hostname()
+
+	r := &Resource{PID: cmd.Process.Pid, Host: host}
+	if !r.Orphaned() {
+		t.Fatal("should be orphaned")
+	}
+
+	r.Host = "elsewhere"
+	if r.Orphaned() {
+		t.Fatal("can't know about other hosts")
+	}
+}
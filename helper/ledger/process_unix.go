@@ -0,0 +1,15 @@
+// +build darwin freebsd linux netbsd openbsd solaris
+
+package ledger
+
+import "syscall"
+
+// processAlive says whether the process exists. A process we may not
+// signal still exists.
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
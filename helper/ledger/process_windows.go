@@ -0,0 +1,26 @@
+// +build windows
+
+package ledger
+
+import "syscall"
+
+const processQueryLimitedInformation = 0x1000
+
+// processAlive says whether the process exists.
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	h, err := syscall.OpenProcess(processQueryLimitedInformation, false, uint32(pid))
+	if err != nil {
+		return false
+	}
+	defer syscall.CloseHandle(h)
+
+	var code uint32
+	if err := syscall.GetExitCodeProcess(h, &code); err != nil {
+		return false
+	}
+	// STILL_ACTIVE
+	return code == 259
+}
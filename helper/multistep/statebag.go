@@ -10,6 +10,7 @@ type StateBag interface {
 	Get(string) interface{}
 	GetOk(string) (interface{}, bool)
 	Put(string, interface{})
+	Remove(string)
 }
 
 // BasicStateBag implements StateBag by using a normal map underneath
@@ -45,3 +46,22 @@ func (b *BasicStateBag) Put(k string, v interface{}) {
 	// Write the data
 	b.data[k] = v
 }
+
+// Keys returns the keys of the state, in no particular order.
+func (b *BasicStateBag) Keys() []string {
+	b.l.RLock()
+	defer b.l.RUnlock()
+
+	keys := make([]string, 0, len(b.data))
+	for k := range b.data {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func (b *BasicStateBag) Remove(k string) {
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	delete(b.data, k)
+}
@@ -1,6 +1,8 @@
 package multistep
 
 import (
+	"reflect"
+	"sort"
 	"testing"
 )
 
@@ -28,3 +30,31 @@ func TestBasicStateBag(t *testing.T) {
 		t.Fatalf("bad")
 	}
 }
+
+func TestBasicStateBag_Remove(t *testing.T) {
+	b := new(BasicStateBag)
+	b.Remove("foo")
+
+	b.Put("foo", "bar")
+	b.Remove("foo")
+
+	if _, ok := b.GetOk("foo"); ok {
+		t.Fatal("should not have foo")
+	}
+}
+
+func TestBasicStateBag_Keys(t *testing.T) {
+	b := new(BasicStateBag)
+	if keys := b.Keys(); len(keys) != 0 {
+		t.Fatalf("bad: %#v", keys)
+	}
+
+	b.Put("foo", "bar")
+	b.Put("bar", 1)
+
+	keys := b.Keys()
+	sort.Strings(keys)
+	if !reflect.DeepEqual(keys, []string{"bar", "foo"}) {
+		t.Fatalf("bad: %#v", keys)
+	}
+}
@@ -0,0 +1,213 @@
+// Package pluginlock checks the external plugin binaries against a lockfile
+// of the SHA256 sums, and optionally the signatures, they are expected to
+// have, before Packer runs them.
+//
+// A project can commit a packer.lock written by `packer plugins lock`, so
+// the builds in CI use exactly the plugins, and the version of Packer with
+// its builtin plugins, that were tested locally.
+package pluginlock
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/packer/helper/signing"
+	"github.com/hashicorp/packer/packer"
+)
+
+// EnvLockfile is the environment variable with the path to the lockfile,
+// when it isn't the project or the user one.
+const EnvLockfile = "PACKER_PLUGIN_LOCKFILE"
+
+// ProjectLockfile is the lockfile of the project in the current directory,
+// used instead of the one in the config directory when it exists.
+const ProjectLockfile = "packer.lock"
+
+var sha256Re = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// Lockfile lists the plugins that may run, keyed by the file name of
+// their binary, such as "packer-builder-foo".
+type Lockfile struct {
+	// PackerVersion is the version of Packer the plugins were locked
+	// with, the only one that may run them when set.
+	PackerVersion string `json:"packer_version,omitempty"`
+
+	Plugins map[string]*Plugin `json:"plugins"`
+
+	path string
+}
+
+// Plugin is what a plugin binary is expected to be.
+type Plugin struct {
+	// SHA256 is the hex encoded SHA256 sum of the binary.
+	SHA256 string `json:"sha256"`
+
+	// VerifyWith is "gpg" or "cosign" to check the release signature of
+	// the binary too. Signature is the detached signature, the binary
+	// with .asc or .sig appended by default, and CosignKey the public key
+	// cosign checks it with. Relative paths are relative to the lockfile.
+	VerifyWith string `json:"verify_with,omitempty"`
+	Signature  string `json:"signature,omitempty"`
+	CosignKey  string `json:"cosign_key,omitempty"`
+}
+
+// Path returns the path to the lockfile: the one EnvLockfile points to,
+// the project lockfile if there is one, or the one in the config directory.
+func Path() (string, error) {
+	if path := os.Getenv(EnvLockfile); path != "" {
+		return path, nil
+	}
+	if _, err := os.Stat(ProjectLockfile); err == nil {
+		return ProjectLockfile, nil
+	}
+
+	dir, err := packer.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "plugins.lock.json"), nil
+}
+
+// Load reads the lockfile at path. A lockfile that doesn't exist lists no
+// plugins.
+func Load(path string) (*Lockfile, error) {
+	l := &Lockfile{
+		Plugins: make(map[string]*Plugin),
+		path:    path,
+	}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if err := json.NewDecoder(f).Decode(l); err != nil {
+		return nil, fmt.Errorf("Error parsing the plugin lockfile %s: %s", path, err)
+	}
+
+	for name, p := range l.Plugins {
+		p.SHA256 = strings.ToLower(p.SHA256)
+		if !sha256Re.MatchString(p.SHA256) {
+			return nil, fmt.Errorf("Plugin lockfile %s: %s: sha256 must be 64 hex characters", path, name)
+		}
+		switch p.VerifyWith {
+		case "", "gpg":
+		case "cosign":
+			if p.CosignKey == "" {
+				return nil, fmt.Errorf("Plugin lockfile %s: %s: cosign_key must be set to verify with cosign", path, name)
+			}
+		default:
+			return nil, fmt.Errorf("Plugin lockfile %s: %s: verify_with must be one of 'gpg' or 'cosign'", path, name)
+		}
+	}
+
+	return l, nil
+}
+
+// Save writes the lockfile back to where it was loaded from.
+func (l *Lockfile) Save() error {
+	raw, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(l.path, append(raw, '\n'), 0644)
+}
+
+// Lock records the SHA256 sum of the plugin binary at path, keeping how
+// its signature is verified if it was already in the lockfile.
+func (l *Lockfile) Lock(path string) (*Plugin, error) {
+	sum, err := Sum(path)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading plugin %s: %s", path, err)
+	}
+
+	name := filepath.Base(path)
+	p, ok := l.Plugins[name]
+	if !ok {
+		p = &Plugin{}
+		l.Plugins[name] = p
+	}
+	p.SHA256 = sum
+	return p, nil
+}
+
+// CheckVersion checks that the plugins were locked with this version of
+// Packer, if the lockfile says which.
+func (l *Lockfile) CheckVersion(version string) error {
+	if l.PackerVersion == "" || l.PackerVersion == version {
+		return nil
+	}
+	return fmt.Errorf("The plugin lockfile %s was written by Packer %s, this is Packer %s",
+		l.path, l.PackerVersion, version)
+}
+
+// Verify checks that the plugin binary at path is in the lockfile with
+// the SHA256 sum it has, and that its signature is valid if it must have
+// one.
+func (l *Lockfile) Verify(path string) error {
+	name := filepath.Base(path)
+	p, ok := l.Plugins[name]
+	if !ok {
+		return fmt.Errorf("Plugin %s is not in the plugin lockfile %s", path, l.path)
+	}
+
+	sum, err := Sum(path)
+	if err != nil {
+		return fmt.Errorf("Error reading plugin %s: %s", path, err)
+	}
+	if sum != p.SHA256 {
+		return fmt.Errorf("The SHA256 sum of plugin %s is %s, the plugin lockfile %s expects %s",
+			path, sum, l.path, p.SHA256)
+	}
+
+	if p.VerifyWith == "" {
+		return nil
+	}
+	signature := l.relative(p.Signature)
+	if signature == "" {
+		signature = path + ".asc"
+		if p.VerifyWith == "cosign" {
+			signature = path + ".sig"
+		}
+	}
+	c := &signing.Config{
+		SignWith:  p.VerifyWith,
+		CosignKey: l.relative(p.CosignKey),
+	}
+	return c.VerifyFile(path, signature)
+}
+
+// relative makes a path relative to the lockfile absolute.
+func (l *Lockfile) relative(path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(filepath.Dir(l.path), path)
+}
+
+// Sum returns the hex encoded SHA256 sum of the file at path.
+func Sum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,160 @@
+package pluginlock
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// sha256 of "plugin"
+const pluginSum = "5e689e2b01672bf33996e75d5e372ff60c536ce1599a1458e867cd8f4bef5160"
+
+func testLockfile(t *testing.T, contents string) (string, string) {
+	dir, err := ioutil.TempDir("", "packer-pluginlock")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	bin := filepath.Join(dir, "packer-builder-foo")
+	if err := ioutil.WriteFile(bin, []byte("plugin"), 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	lock := filepath.Join(dir, "plugins.lock.json")
+	if err := ioutil.WriteFile(lock, []byte(contents), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return dir, bin
+}
+
+func TestLockfileVerify(t *testing.T) {
+	dir, bin := testLockfile(t, `{"plugins": {"packer-builder-foo": {"sha256": "`+strings.ToUpper(pluginSum)+`"}}}`)
+	defer os.RemoveAll(dir)
+
+	l, err := Load(filepath.Join(dir, "plugins.lock.json"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := l.Verify(bin); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if err := ioutil.WriteFile(bin, []byte("changed"), 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	err = l.Verify(bin)
+	if err == nil || !strings.Contains(err.Error(), "expects "+pluginSum) {
+		t.Fatalf("bad: %s", err)
+	}
+
+	other := filepath.Join(dir, "packer-builder-bar")
+	err = l.Verify(other)
+	if err == nil || !strings.Contains(err.Error(), "is not in the plugin lockfile") {
+		t.Fatalf("bad: %s", err)
+	}
+}
+
+func TestLockfileVerify_signature(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as cosign")
+	}
+
+	dir, bin := testLockfile(t, `{"plugins": {"packer-builder-foo": {
+		"sha256": "`+pluginSum+`",
+		"verify_with": "cosign",
+		"cosign_key": "cosign.pub"
+	}}}`)
+	defer os.RemoveAll(dir)
+
+	// A stand-in for cosign that accepts the signature if it says "good"
+	// and the key is the one relative to the lockfile.
+	cosign := `#!/bin/sh
+[ "$3" = "` + filepath.Join(dir, "cosign.pub") + `" ] && grep -q good "$5"
+`
+	if err := ioutil.WriteFile(filepath.Join(dir, "cosign"), []byte(cosign), 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	l, err := Load(filepath.Join(dir, "plugins.lock.json"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if err := ioutil.WriteFile(bin+".sig", []byte("bad"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := l.Verify(bin); err == nil {
+		t.Fatal("should fail with a bad signature")
+	}
+
+	if err := ioutil.WriteFile(bin+".sig", []byte("good"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := l.Verify(bin); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	l, err := Load(filepath.Join(os.TempDir(), "packer-missing.lock.json"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(l.Plugins) != 0 {
+		t.Fatalf("bad: %#v", l.Plugins)
+	}
+
+	cases := []string{
+		`{"plugins": {"packer-builder-foo": {"sha256": "abc"}}}`,
+		`{"plugins": {"packer-builder-foo": {"sha256": "` + pluginSum + `", "verify_with": "pgp"}}}`,
+		`{"plugins": {"packer-builder-foo": {"sha256": "` + pluginSum + `", "verify_with": "cosign"}}}`,
+		`{"plugins": [`,
+	}
+	for i, tc := range cases {
+		dir, _ := testLockfile(t, tc)
+		if _, err := Load(filepath.Join(dir, "plugins.lock.json")); err == nil {
+			t.Fatalf("%d: should error", i)
+		}
+		os.RemoveAll(dir)
+	}
+}
+
+func TestLockfileLock(t *testing.T) {
+	dir, bin := testLockfile(t, `{"packer_version": "1.0.0", "plugins": {"packer-builder-foo": {
+		"sha256": "`+strings.Repeat("0", 64)+`",
+		"verify_with": "gpg"
+	}}}`)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "plugins.lock.json")
+	l, err := Load(path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := l.CheckVersion("1.0.0"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := l.CheckVersion("1.1.0"); err == nil {
+		t.Fatal("should error on another version")
+	}
+
+	if _, err := l.Lock(bin); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := l.Save(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	l, err = Load(path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	p := l.Plugins["packer-builder-foo"]
+	if p.SHA256 != pluginSum || p.VerifyWith != "gpg" {
+		t.Fatalf("bad: %#v", p)
+	}
+}
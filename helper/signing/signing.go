@@ -0,0 +1,141 @@
+// Package signing creates signatures for the files and images built by
+// Packer with gpg or cosign.
+package signing
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os/exec"
+	"strings"
+)
+
+// Config is the signing configuration shared by the post-processors that
+// sign what they produce.
+type Config struct {
+	SignWith  string `mapstructure:"sign_with"`
+	GPGKeyID  string `mapstructure:"gpg_key_id"`
+	CosignKey string `mapstructure:"cosign_key"`
+}
+
+// Prepare validates sign_with. Signing is disabled when it is empty. Without
+// a cosign_key, cosign signs keyless with a short-lived certificate from
+// Fulcio and records the signature in the Rekor transparency log.
+func (c *Config) Prepare() []error {
+	switch c.SignWith {
+	case "", "gpg", "cosign":
+		return nil
+	default:
+		return []error{fmt.Errorf("sign_with must be one of 'gpg' or 'cosign'")}
+	}
+}
+
+// Keyless reports whether cosign signs without a key.
+func (c *Config) Keyless() bool {
+	return c.SignWith == "cosign" && c.CosignKey == ""
+}
+
+// SignFile creates a detached signature for path and returns the files
+// written: the signature, and the certificate when signing keyless.
+func (c *Config) SignFile(path string) ([]string, error) {
+	var files []string
+	var args []string
+	switch c.SignWith {
+	case "gpg":
+		files = []string{path + ".asc"}
+		args = []string{"gpg", "--batch", "--yes", "--armor", "--detach-sign", "--output", files[0]}
+		if c.GPGKeyID != "" {
+			args = append(args, "--local-user", c.GPGKeyID)
+		}
+	case "cosign":
+		files = []string{path + ".sig"}
+		args = append([]string{"cosign", "sign-blob"}, c.cosignKeyArgs()...)
+		args = append(args, "--output-signature", files[0])
+		if c.Keyless() {
+			files = append(files, path+".pem")
+			args = append(args, "--output-certificate", files[1])
+		}
+	default:
+		return nil, fmt.Errorf("Unknown signing tool %q", c.SignWith)
+	}
+	args = append(args, path)
+
+	if err := run(args); err != nil {
+		return nil, fmt.Errorf("Error signing %s with %s: %s", path, c.SignWith, err)
+	}
+	return files, nil
+}
+
+// VerifyFile checks the detached signature of path. gpg checks it against
+// the keys in its keyring, cosign against the public key in CosignKey.
+// Keyless signatures can't be verified this way, as it would take knowing
+// who is expected to have signed.
+func (c *Config) VerifyFile(path, signature string) error {
+	var args []string
+	switch c.SignWith {
+	case "gpg":
+		args = []string{"gpg", "--batch", "--verify", signature, path}
+	case "cosign":
+		if c.Keyless() {
+			return fmt.Errorf("Verifying with cosign needs a cosign_key")
+		}
+		args = []string{"cosign", "verify-blob", "--key", c.CosignKey, "--signature", signature, path}
+	default:
+		return fmt.Errorf("Unknown signing tool %q", c.SignWith)
+	}
+
+	if err := run(args); err != nil {
+		return fmt.Errorf("Error verifying the signature of %s with %s: %s", path, c.SignWith, err)
+	}
+	return nil
+}
+
+// SignImage signs the image ref in its registry with cosign. ref should
+// name the image by digest, a tag may be moved to another image later.
+func (c *Config) SignImage(ref string) error {
+	if c.SignWith != "cosign" {
+		return fmt.Errorf("Images can only be signed with cosign")
+	}
+	args := append([]string{"cosign", "sign"}, c.cosignKeyArgs()...)
+	args = append(args, ref)
+	if err := run(args); err != nil {
+		return fmt.Errorf("Error signing %s: %s", ref, err)
+	}
+	return nil
+}
+
+// AttestImage attaches the predicate in the file predicate as a signed
+// in-toto attestation of type predicateType, like "spdxjson" or
+// "cyclonedx", to the image ref.
+func (c *Config) AttestImage(ref, predicate, predicateType string) error {
+	if c.SignWith != "cosign" {
+		return fmt.Errorf("Attestations can only be signed with cosign")
+	}
+	args := append([]string{"cosign", "attest"}, c.cosignKeyArgs()...)
+	args = append(args, "--type", predicateType, "--predicate", predicate, ref)
+	if err := run(args); err != nil {
+		return fmt.Errorf("Error attesting %s for %s: %s", predicate, ref, err)
+	}
+	return nil
+}
+
+// cosignKeyArgs returns the arguments selecting the key cosign signs with.
+// --yes skips the confirmation cosign asks for before uploading to the
+// transparency log, there's no one to answer it during a build.
+func (c *Config) cosignKeyArgs() []string {
+	if c.Keyless() {
+		return []string{"--yes"}
+	}
+	return []string{"--yes", "--key", c.CosignKey}
+}
+
+func run(args []string) error {
+	var stderr bytes.Buffer
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stderr = &stderr
+	log.Printf("Executing: %s", strings.Join(args, " "))
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s\n\nStderr: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package signing
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+// A stand-in for cosign that writes the files it's asked to output.
+const fakeCosign = `#!/bin/sh
+while [ $# -gt 0 ]; do
+	case "$1" in
+	--output-signature|--output-certificate)
+		echo "$1" > "$2"
+		shift
+		;;
+	esac
+	shift
+done
+`
+
+func TestConfigPrepare(t *testing.T) {
+	for _, with := range []string{"", "gpg", "cosign"} {
+		c := &Config{SignWith: with}
+		if errs := c.Prepare(); len(errs) > 0 {
+			t.Fatalf("%q: %s", with, errs)
+		}
+	}
+
+	c := &Config{SignWith: "pgp"}
+	if errs := c.Prepare(); len(errs) == 0 {
+		t.Fatal("should error on unknown tool")
+	}
+}
+
+func TestConfigSignFile_keyless(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as cosign")
+	}
+
+	dir, err := ioutil.TempDir("", "packer-signing")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "cosign"), []byte(fakeCosign), 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	path := filepath.Join(dir, "disk.raw")
+	c := &Config{SignWith: "cosign"}
+	files, err := c.SignFile(path)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []string{path + ".sig", path + ".pem"}
+	if !reflect.DeepEqual(files, expected) {
+		t.Fatalf("bad files: %#v", files)
+	}
+	for _, f := range expected {
+		if _, err := os.Stat(f); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+}
@@ -5,6 +5,7 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -13,14 +14,17 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
 
 	"github.com/hashicorp/go-uuid"
 	"github.com/hashicorp/packer/command"
+	commonhelper "github.com/hashicorp/packer/helper/common"
 	"github.com/hashicorp/packer/packer"
 	"github.com/hashicorp/packer/packer/plugin"
+	"github.com/hashicorp/packer/template/interpolate"
 	"github.com/hashicorp/packer/version"
 	"github.com/mitchellh/cli"
 	"github.com/mitchellh/panicwrap"
@@ -155,6 +159,7 @@ func wrappedMain() int {
 		return 1
 	}
 	log.Printf("Packer config: %+v", config)
+	loadedConfig = config
 
 	// Fire off the checkpoint.
 	go runCheckpoint(config)
@@ -164,7 +169,29 @@ func wrappedMain() int {
 		)
 	}
 
+	// The plugins use the time the core started at for the templates, so
+	// it's the same everywhere in the run.
+	if inPlugin {
+		if t, err := time.Parse(time.RFC3339Nano, os.Getenv(interpolate.EnvInitTime)); err == nil {
+			interpolate.InitTime = t
+		}
+	} else {
+		os.Setenv(interpolate.EnvInitTime, interpolate.InitTime.Format(time.RFC3339Nano))
+	}
+
+	// Export traces and metrics if an OpenTelemetry collector is set up.
+	if !inPlugin {
+		packer.Tracing = packer.NewOTLPExporter()
+	}
+
+	if !inPlugin {
+		config.setEnvironment()
+	}
+
 	cacheDir := os.Getenv("PACKER_CACHE_DIR")
+	if cacheDir == "" {
+		cacheDir = config.CacheDir
+	}
 	if cacheDir == "" {
 		cacheDir = "packer_cache"
 	}
@@ -181,6 +208,14 @@ func wrappedMain() int {
 	// Determine if we're in machine-readable mode by mucking around with
 	// the arguments...
 	args, machineReadable := extractMachineReadable(os.Args[1:])
+	args, insecurePlugins := extractInsecurePlugins(args)
+	config.insecurePlugins = insecurePlugins
+	args, verbosity := extractVerbosity(args)
+	level, err := packer.ParseUiLevel(verbosity)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid -verbosity: %s\n", err)
+		return 1
+	}
 
 	defer plugin.CleanupClients()
 
@@ -189,10 +224,12 @@ func wrappedMain() int {
 		Reader:      os.Stdin,
 		Writer:      os.Stdout,
 		ErrorWriter: os.Stdout,
+		Level:       level,
 	}
 	if machineReadable {
 		ui = &packer.MachineReadableUi{
 			Writer: os.Stdout,
+			Level:  level,
 		}
 
 		// Set this so that we don't get colored output in our machine-
@@ -203,6 +240,10 @@ func wrappedMain() int {
 		}
 	}
 
+	if insecurePlugins {
+		packer.UiLog(ui, packer.UiLevelWarn, "Warning: -insecure-plugins is set, plugins that don't match the plugin lockfile will run.")
+	}
+
 	// Create the CLI meta
 	CommandMeta = &command.Meta{
 		CoreConfig: &packer.CoreConfig{
@@ -212,10 +253,14 @@ func wrappedMain() int {
 				PostProcessor: config.LoadPostProcessor,
 				Provisioner:   config.LoadProvisioner,
 			},
-			Version: version.Version,
+			Version:       version.Version,
+			Notifications: config.Notifications,
 		},
-		Cache: cache,
-		Ui:    ui,
+		Cache:    cache,
+		Ui:       ui,
+		Plugins:  config.externalPlugins(),
+		OnError:  config.OnError,
+		VarFiles: config.VarFiles,
 	}
 
 	cli := &cli.CLI{
@@ -233,6 +278,13 @@ func wrappedMain() int {
 		if err := packer.CheckpointReporter.Finalize(cli.Subcommand(), exitCode, err); err != nil {
 			log.Printf("[WARN] (telemetry) Error finalizing report. This is safe to ignore. %s", err.Error())
 		}
+		if err := packer.Tracing.Finalize(cli.Subcommand(), exitCode, err); err != nil {
+			log.Printf("[WARN] (otlp) %s", err)
+		}
+
+		// The values the builds shared with the plugins, like the build
+		// values, are only needed while the builds run.
+		commonhelper.RemoveSharedState()
 	}
 
 	if err != nil {
@@ -268,8 +320,48 @@ func excludeHelpFunc(commands map[string]cli.CommandFactory, exclude []string) c
 // flag and returns whether or not it is on. It modifies the args
 // to remove this flag.
 func extractMachineReadable(args []string) ([]string, bool) {
+	return extractFlag(args, "-machine-readable")
+}
+
+// extractInsecurePlugins checks the args for the flag allowing plugins
+// that don't match the plugin lockfile to run, and removes it.
+func extractInsecurePlugins(args []string) ([]string, bool) {
+	return extractFlag(args, "-insecure-plugins")
+}
+
+// extractVerbosity checks the args for the level of the messages to show,
+// -verbosity=LEVEL or -verbosity LEVEL, with one or two dashes, and removes
+// it. The level is info by default, and empty when the flag has no value.
+func extractVerbosity(args []string) ([]string, string) {
+	for i, arg := range args {
+		name := strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-")
+		if name == arg {
+			continue
+		}
+
+		if strings.HasPrefix(name, "verbosity=") {
+			result := make([]string, 0, len(args)-1)
+			result = append(result, args[:i]...)
+			result = append(result, args[i+1:]...)
+			return result, strings.TrimPrefix(name, "verbosity=")
+		}
+		if name == "verbosity" {
+			result := make([]string, 0, len(args)-1)
+			result = append(result, args[:i]...)
+			if i+1 == len(args) {
+				return result, ""
+			}
+			result = append(result, args[i+2:]...)
+			return result, args[i+1]
+		}
+	}
+
+	return args, packer.UiLevelInfo.String()
+}
+
+func extractFlag(args []string, flag string) ([]string, bool) {
 	for i, arg := range args {
-		if arg == "-machine-readable" {
+		if arg == flag {
 			// We found it. Slice it out.
 			result := make([]string, len(args)-1)
 			copy(result, args[:i])
@@ -306,7 +398,7 @@ func loadConfig() (*config, error) {
 	}
 
 	log.Printf("Attempting to open config file: %s", configFilePath)
-	f, err := os.Open(configFilePath)
+	raw, err := ioutil.ReadFile(configFilePath)
 	if err != nil {
 		if !os.IsNotExist(err) {
 			return nil, err
@@ -315,11 +407,28 @@ func loadConfig() (*config, error) {
 		log.Printf("[WARN] Config file doesn't exist: %s", configFilePath)
 		return &config, nil
 	}
-	defer f.Close()
 
-	if err := decodeConfig(f, &config); err != nil {
+	if err := decodeConfig(bytes.NewReader(raw), &config); err != nil {
 		return nil, err
 	}
+	if err := config.prepare(filepath.Dir(configFilePath)); err != nil {
+		return nil, err
+	}
+
+	// The plugin directories of the config file are searched too, so
+	// discover again, and decode again for the plugins the config file
+	// sets to still take precedence over the discovered ones.
+	if len(config.PluginDirectories) > 0 {
+		if err := config.Discover(); err != nil {
+			return nil, err
+		}
+		if err := decodeConfig(bytes.NewReader(raw), &config); err != nil {
+			return nil, err
+		}
+		if err := config.prepare(filepath.Dir(configFilePath)); err != nil {
+			return nil, err
+		}
+	}
 
 	return &config, nil
 }
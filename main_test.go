@@ -62,6 +62,66 @@ func TestExtractMachineReadable(t *testing.T) {
 	}
 }
 
+func TestExtractInsecurePlugins(t *testing.T) {
+	result, insecure := extractInsecurePlugins([]string{"build", "-insecure-plugins", "template.json"})
+	expected := []string{"build", "template.json"}
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("bad: %#v", result)
+	}
+	if !insecure {
+		t.Fatal("should be insecure")
+	}
+
+	result, insecure = extractInsecurePlugins(expected)
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("bad: %#v", result)
+	}
+	if insecure {
+		t.Fatal("should not be insecure")
+	}
+}
+
+func TestExtractVerbosity(t *testing.T) {
+	result, verbosity := extractVerbosity([]string{"build", "-verbosity=debug", "template.json"})
+	expected := []string{"build", "template.json"}
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("bad: %#v", result)
+	}
+	if verbosity != "debug" {
+		t.Fatalf("bad: %s", verbosity)
+	}
+
+	result, verbosity = extractVerbosity(expected)
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("bad: %#v", result)
+	}
+	if verbosity != "info" {
+		t.Fatalf("bad: %s", verbosity)
+	}
+
+	for _, args := range [][]string{
+		{"build", "-verbosity", "warn", "template.json"},
+		{"build", "--verbosity", "warn", "template.json"},
+		{"build", "--verbosity=warn", "template.json"},
+	} {
+		result, verbosity = extractVerbosity(args)
+		if !reflect.DeepEqual(result, expected) {
+			t.Fatalf("%v: bad: %#v", args, result)
+		}
+		if verbosity != "warn" {
+			t.Fatalf("%v: bad: %s", args, verbosity)
+		}
+	}
+
+	result, verbosity = extractVerbosity([]string{"build", "template.json", "-verbosity"})
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("bad: %#v", result)
+	}
+	if verbosity != "" {
+		t.Fatalf("bad: %s", verbosity)
+	}
+}
+
 func TestRandom(t *testing.T) {
 	if rand.Intn(9999999) == 8498210 {
 		t.Fatal("math.rand is not seeded properly")
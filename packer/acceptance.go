@@ -0,0 +1,377 @@
+package packer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer/template"
+)
+
+// AcceptanceEnvVar must be set to a non-empty value for acceptance tests to
+// run.
+const AcceptanceEnvVar = "PACKER_ACC"
+
+// AcceptanceTestCase is a build to run against the real core, with the
+// components under test. It is meant for the acceptance tests of builders,
+// provisioners, and post-processors, including the ones of plugins.
+type AcceptanceTestCase struct {
+	// RequiredEnv are the environment variables the test needs, such as
+	// credentials. The test is skipped when one of them isn't set.
+	RequiredEnv []string
+
+	// PreCheck, if non-nil, will be called once before the test case runs
+	// at all. This can be used for some validation prior to the test
+	// running.
+	PreCheck func()
+
+	// Builder is available as the "test" builder in the template.
+	Builder Builder
+
+	// Builders, Provisioners, and PostProcessors are available in the
+	// template under their names.
+	Builders       map[string]Builder
+	Provisioners   map[string]Provisioner
+	PostProcessors map[string]PostProcessor
+
+	// Template is the template contents to use, see AcceptanceTemplate.
+	Template string
+
+	// Variables are the user variables of the template, like with -var.
+	Variables map[string]string
+
+	// Build is the name of the build to run, "test" by default.
+	Build string
+
+	// ExpectError, if set, is matched against the error of the build
+	// instead of failing the test. Nothing is checked when it matches.
+	ExpectError *regexp.Regexp
+
+	// Check is called with the artifacts of the build, to test that the
+	// build did what it should. See CheckArtifacts.
+	Check AcceptanceCheckFunc
+
+	// Teardown will be called before the test case is over regardless of
+	// if the test succeeded or failed. This should return an error in the
+	// case that the test can't guarantee all resources were properly
+	// cleaned up.
+	Teardown func() error
+
+	// If SkipArtifactTeardown is true, we will not attempt to destroy the
+	// artifacts created in this test run.
+	SkipArtifactTeardown bool
+}
+
+// AcceptanceCheckFunc is the callback used for Check in AcceptanceTestCase.
+type AcceptanceCheckFunc func([]Artifact) error
+
+// AcceptanceT is the interface used to handle the test lifecycle of a test.
+//
+// Users should just use a *testing.T object, which implements this.
+type AcceptanceT interface {
+	Error(args ...interface{})
+	Fatal(args ...interface{})
+	Skip(args ...interface{})
+}
+
+// AcceptanceTest runs the build of the test case, checks its artifacts, and
+// destroys them.
+//
+// Tests are not run unless the environment variable "PACKER_ACC" is set to
+// some non-empty value, and the ones of RequiredEnv are set. This is to
+// avoid test cases surprising a user by creating real resources.
+//
+// Tests will fail unless the verbose flag (`go test -v`, or explicitly the
+// "-test.v" flag) is set. Because some acceptance tests take quite long, we
+// require the verbose flag so users are able to see progress output.
+//
+// The artifacts are destroyed and Teardown is called however the test ends,
+// including when t.Fatal stops it or a check panics.
+func AcceptanceTest(t AcceptanceT, c AcceptanceTestCase) {
+	// We only run acceptance tests if an env var is set because they're
+	// slow and generally require some outside configuration.
+	if os.Getenv(AcceptanceEnvVar) == "" {
+		t.Skip(fmt.Sprintf(
+			"Acceptance tests skipped unless env '%s' set",
+			AcceptanceEnvVar))
+		return
+	}
+	var missing []string
+	for _, name := range c.RequiredEnv {
+		if os.Getenv(name) == "" {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		t.Skip(fmt.Sprintf(
+			"Acceptance test skipped unless env %s set",
+			strings.Join(missing, ", ")))
+		return
+	}
+
+	// We require verbose mode so that the user knows what is going on.
+	if _, ok := t.(*testing.T); ok && !testing.Verbose() {
+		t.Fatal("Acceptance tests must be run with the -v flag on tests")
+		return
+	}
+
+	// Run the PreCheck if we have it
+	if c.PreCheck != nil {
+		c.PreCheck()
+	}
+
+	var artifacts []Artifact
+	defer func() {
+		if !c.SkipArtifactTeardown {
+			for _, a := range artifacts {
+				if a == nil {
+					continue
+				}
+				if err := a.Destroy(); err != nil {
+					t.Error(fmt.Sprintf(
+						"!!! ERROR REMOVING ARTIFACT '%s': %s !!!",
+						a.String(), err))
+				}
+			}
+		}
+
+		if c.Teardown != nil {
+			log.Printf("[DEBUG] Running teardown function")
+			if err := c.Teardown(); err != nil {
+				t.Error(fmt.Sprintf("Teardown failure:\n\n%s", err))
+			}
+		}
+	}()
+
+	build, err := acceptanceBuild(c)
+	if err != nil {
+		t.Fatal(err.Error())
+		return
+	}
+
+	// Prepare it
+	log.Printf("[DEBUG] Preparing '%s' build", build.Name())
+	warnings, err := build.Prepare()
+	if err == nil && len(warnings) > 0 {
+		t.Fatal(fmt.Sprintf(
+			"Prepare warnings:\n\n%s",
+			strings.Join(warnings, "\n")))
+		return
+	}
+
+	// Run it! We use a temporary directory for caching and discard any UI
+	// output. We discard since it shows up in logs anyways.
+	if err == nil {
+		log.Printf("[DEBUG] Running '%s' build", build.Name())
+		cache := &FileCache{CacheDir: os.TempDir()}
+		ui := &BasicUi{
+			Reader:      new(bytes.Buffer),
+			Writer:      ioutil.Discard,
+			ErrorWriter: ioutil.Discard,
+		}
+		artifacts, err = build.Run(ui, cache)
+	}
+
+	if c.ExpectError != nil {
+		switch {
+		case err == nil:
+			t.Fatal(fmt.Sprintf("Expected an error matching %s", c.ExpectError))
+		case !c.ExpectError.MatchString(err.Error()):
+			t.Fatal(fmt.Sprintf(
+				"Expected an error matching %s, got:\n\n%s", c.ExpectError, err))
+		}
+		return
+	}
+	if err != nil {
+		t.Fatal(fmt.Sprintf("Build error:\n\n%s", err))
+		return
+	}
+
+	if c.Check != nil {
+		log.Printf("[DEBUG] Running check function")
+		if err := c.Check(artifacts); err != nil {
+			t.Fatal(fmt.Sprintf("Check error:\n\n%s", err))
+			return
+		}
+	}
+}
+
+// acceptanceBuild returns the build of the test case, from the real core.
+func acceptanceBuild(c AcceptanceTestCase) (Build, error) {
+	log.Printf("[DEBUG] Parsing template...")
+	tpl, err := template.Parse(strings.NewReader(c.Template))
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse template: %s", err)
+	}
+
+	log.Printf("[DEBUG] Initializing core...")
+	core, err := NewCore(&CoreConfig{
+		Components: ComponentFinder{
+			Builder: func(n string) (Builder, error) {
+				if n == "test" && c.Builder != nil {
+					return c.Builder, nil
+				}
+				return c.Builders[n], nil
+			},
+			Provisioner: func(n string) (Provisioner, error) {
+				return c.Provisioners[n], nil
+			},
+			PostProcessor: func(n string) (PostProcessor, error) {
+				return c.PostProcessors[n], nil
+			},
+		},
+		Template:  tpl,
+		Variables: c.Variables,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to init core: %s", err)
+	}
+
+	name := c.Build
+	if name == "" {
+		name = "test"
+	}
+	log.Printf("[DEBUG] Retrieving '%s' build", name)
+	build, err := core.Build(name)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get '%s' build: %s", name, err)
+	}
+	return build, nil
+}
+
+// AcceptanceTemplate returns a template that builds with the builder, and
+// runs the provisioners in order. The builder is the "test" one unless it
+// has a "type".
+func AcceptanceTemplate(builder map[string]interface{}, provisioners ...map[string]interface{}) string {
+	b := make(map[string]interface{}, len(builder)+1)
+	b["type"] = "test"
+	for k, v := range builder {
+		b[k] = v
+	}
+
+	tpl := map[string]interface{}{
+		"builders": []interface{}{b},
+	}
+	if len(provisioners) > 0 {
+		tpl["provisioners"] = provisioners
+	}
+
+	contents, err := json.MarshalIndent(tpl, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	return string(contents)
+}
+
+// CheckArtifacts runs all the checks, and fails with the first one that
+// fails.
+func CheckArtifacts(checks ...AcceptanceCheckFunc) AcceptanceCheckFunc {
+	return func(artifacts []Artifact) error {
+		for _, check := range checks {
+			if err := check(artifacts); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// CheckArtifactCount checks that there are n artifacts.
+func CheckArtifactCount(n int) AcceptanceCheckFunc {
+	return func(artifacts []Artifact) error {
+		if len(artifacts) != n {
+			return fmt.Errorf("expected %d artifacts, got %d", n, len(artifacts))
+		}
+		return nil
+	}
+}
+
+// CheckArtifactBuilderId checks that the first artifact is from the builder
+// or post-processor with the ID.
+func CheckArtifactBuilderId(id string) AcceptanceCheckFunc {
+	return func(artifacts []Artifact) error {
+		a, err := firstArtifact(artifacts)
+		if err != nil {
+			return err
+		}
+		if a.BuilderId() != id {
+			return fmt.Errorf("expected builder ID %q, got %q", id, a.BuilderId())
+		}
+		return nil
+	}
+}
+
+// CheckArtifactId checks that the ID of the first artifact matches the
+// regular expression.
+func CheckArtifactId(pattern string) AcceptanceCheckFunc {
+	re := regexp.MustCompile(pattern)
+	return func(artifacts []Artifact) error {
+		a, err := firstArtifact(artifacts)
+		if err != nil {
+			return err
+		}
+		if !re.MatchString(a.Id()) {
+			return fmt.Errorf("expected an ID matching %s, got %q", pattern, a.Id())
+		}
+		return nil
+	}
+}
+
+// CheckArtifactFiles checks that the first artifact has files matching each
+// of the regular expressions, and that they exist.
+func CheckArtifactFiles(patterns ...string) AcceptanceCheckFunc {
+	return func(artifacts []Artifact) error {
+		a, err := firstArtifact(artifacts)
+		if err != nil {
+			return err
+		}
+		for _, f := range a.Files() {
+			if _, err := os.Stat(f); err != nil {
+				return fmt.Errorf("artifact file: %s", err)
+			}
+		}
+		for _, pattern := range patterns {
+			re := regexp.MustCompile(pattern)
+			found := false
+			for _, f := range a.Files() {
+				if re.MatchString(f) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return fmt.Errorf("expected a file matching %s, got %q", pattern, a.Files())
+			}
+		}
+		return nil
+	}
+}
+
+// CheckArtifactState checks that the first artifact has the state value
+// under the name.
+func CheckArtifactState(name string, value interface{}) AcceptanceCheckFunc {
+	return func(artifacts []Artifact) error {
+		a, err := firstArtifact(artifacts)
+		if err != nil {
+			return err
+		}
+		if actual := a.State(name); !reflect.DeepEqual(actual, value) {
+			return fmt.Errorf("expected state %q to be %#v, got %#v", name, value, actual)
+		}
+		return nil
+	}
+}
+
+func firstArtifact(artifacts []Artifact) (Artifact, error) {
+	if len(artifacts) == 0 || artifacts[0] == nil {
+		return nil, fmt.Errorf("expected an artifact, got none")
+	}
+	return artifacts[0], nil
+}
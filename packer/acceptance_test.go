@@ -0,0 +1,199 @@
+package packer
+
+import (
+	"errors"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// acceptanceT implements AcceptanceT for testing.
+type acceptanceT struct {
+	errors []string
+	fatal  string
+	skip   string
+}
+
+func (t *acceptanceT) Error(args ...interface{}) {
+	t.errors = append(t.errors, args[0].(string))
+}
+
+func (t *acceptanceT) Fatal(args ...interface{}) {
+	t.fatal = args[0].(string)
+}
+
+func (t *acceptanceT) Skip(args ...interface{}) {
+	t.skip = args[0].(string)
+}
+
+// setAcceptanceEnv sets the environment variable, and returns the function
+// that restores it.
+func setAcceptanceEnv(t *testing.T, value string) func() {
+	old := os.Getenv(AcceptanceEnvVar)
+	if err := os.Setenv(AcceptanceEnvVar, value); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	return func() { os.Setenv(AcceptanceEnvVar, old) }
+}
+
+func TestAcceptanceTest_env(t *testing.T) {
+	restore := setAcceptanceEnv(t, "")
+	mt := new(acceptanceT)
+	AcceptanceTest(mt, AcceptanceTestCase{})
+	restore()
+	if mt.skip == "" {
+		t.Fatal("should skip")
+	}
+
+	defer setAcceptanceEnv(t, "1")()
+	mt = new(acceptanceT)
+	AcceptanceTest(mt, AcceptanceTestCase{
+		RequiredEnv: []string{"PACKER_TEST_NOPE"},
+	})
+	if !strings.Contains(mt.skip, "PACKER_TEST_NOPE") {
+		t.Fatalf("bad: %q", mt.skip)
+	}
+}
+
+func TestAcceptanceTest(t *testing.T) {
+	defer setAcceptanceEnv(t, "1")()
+
+	builder := &MockBuilder{ArtifactId: "b-1"}
+	prov := new(MockProvisioner)
+	var artifacts []Artifact
+	tornDown := false
+	mt := new(acceptanceT)
+	AcceptanceTest(mt, AcceptanceTestCase{
+		Builder:      builder,
+		Provisioners: map[string]Provisioner{"shell": prov},
+		Template: AcceptanceTemplate(
+			map[string]interface{}{"value": "{{user `v`}}"},
+			map[string]interface{}{"type": "shell"},
+		),
+		Variables: map[string]string{"v": "foo"},
+		Check: CheckArtifacts(
+			CheckArtifactCount(1),
+			CheckArtifactBuilderId("bid"),
+			CheckArtifactId("^b-"),
+			func(a []Artifact) error {
+				artifacts = a
+				return nil
+			},
+		),
+		Teardown: func() error {
+			tornDown = true
+			return nil
+		},
+	})
+
+	if mt.fatal != "" || len(mt.errors) > 0 {
+		t.Fatalf("bad: %q %q", mt.fatal, mt.errors)
+	}
+	if !prov.ProvCalled {
+		t.Fatal("should provision")
+	}
+	vars := builder.PrepareConfig[1].(map[string]interface{})[UserVariablesConfigKey]
+	if vars.(map[string]string)["v"] != "foo" {
+		t.Fatalf("bad: %#v", vars)
+	}
+	if len(artifacts) != 1 || !artifacts[0].(*MockArtifact).DestroyCalled {
+		t.Fatal("should destroy the artifact")
+	}
+	if !tornDown {
+		t.Fatal("should tear down")
+	}
+}
+
+func TestAcceptanceTest_teardown(t *testing.T) {
+	defer setAcceptanceEnv(t, "1")()
+
+	var artifacts []Artifact
+	tornDown := false
+	mt := new(acceptanceT)
+	AcceptanceTest(mt, AcceptanceTestCase{
+		Builder:  new(MockBuilder),
+		Template: AcceptanceTemplate(nil),
+		Check: func(a []Artifact) error {
+			artifacts = a
+			return errors.New("check failed")
+		},
+		Teardown: func() error {
+			tornDown = true
+			return errors.New("teardown failed")
+		},
+	})
+
+	if !strings.Contains(mt.fatal, "check failed") {
+		t.Fatalf("bad: %q", mt.fatal)
+	}
+	if len(artifacts) != 1 || !artifacts[0].(*MockArtifact).DestroyCalled {
+		t.Fatal("should destroy the artifact")
+	}
+	if !tornDown || len(mt.errors) != 1 || !strings.Contains(mt.errors[0], "teardown failed") {
+		t.Fatalf("bad: %v %q", tornDown, mt.errors)
+	}
+}
+
+func TestAcceptanceTest_expectError(t *testing.T) {
+	defer setAcceptanceEnv(t, "1")()
+
+	mt := new(acceptanceT)
+	AcceptanceTest(mt, AcceptanceTestCase{
+		Builder:     &MockBuilder{RunErrResult: true},
+		Template:    AcceptanceTemplate(nil),
+		ExpectError: regexp.MustCompile("foo"),
+	})
+	if mt.fatal != "" {
+		t.Fatalf("bad: %q", mt.fatal)
+	}
+
+	mt = new(acceptanceT)
+	AcceptanceTest(mt, AcceptanceTestCase{
+		Builder:     new(MockBuilder),
+		Template:    AcceptanceTemplate(nil),
+		ExpectError: regexp.MustCompile("foo"),
+	})
+	if !strings.Contains(mt.fatal, "Expected an error") {
+		t.Fatalf("bad: %q", mt.fatal)
+	}
+}
+
+func TestCheckArtifacts(t *testing.T) {
+	artifacts := []Artifact{&MockArtifact{
+		IdValue:     "ami-1234",
+		FilesValue:  []string{"acceptance_test.go"},
+		StateValues: map[string]interface{}{"size": 10},
+	}}
+
+	cases := []struct {
+		Check AcceptanceCheckFunc
+		Err   bool
+	}{
+		{CheckArtifactCount(1), false},
+		{CheckArtifactCount(2), true},
+		{CheckArtifactBuilderId("bid"), false},
+		{CheckArtifactBuilderId("other"), true},
+		{CheckArtifactId("^ami-"), false},
+		{CheckArtifactId("^i-"), true},
+		{CheckArtifactFiles(`_test\.go$`), false},
+		{CheckArtifactFiles(`\.vmdk$`), true},
+		{CheckArtifactState("size", 10), false},
+		{CheckArtifactState("size", 11), true},
+		{CheckArtifacts(CheckArtifactCount(1), CheckArtifactId("^i-")), true},
+	}
+	for i, tc := range cases {
+		err := tc.Check(artifacts)
+		if (err != nil) != tc.Err {
+			t.Fatalf("%d: bad: %v", i, err)
+		}
+	}
+
+	if err := CheckArtifactId(".")(nil); err == nil {
+		t.Fatal("should fail without artifacts")
+	}
+	missing := []Artifact{&MockArtifact{FilesValue: []string{"nope"}}}
+	if err := CheckArtifactFiles()(missing); err == nil {
+		t.Fatal("should fail with missing files")
+	}
+}
@@ -1,9 +1,16 @@
 package packer
 
 import (
+	"encoding/hex"
 	"fmt"
 	"log"
+	"regexp"
 	"sync"
+	"time"
+
+	commonhelper "github.com/hashicorp/packer/helper/common"
+	"github.com/hashicorp/packer/template"
+	"github.com/hashicorp/packer/template/interpolate"
 )
 
 const (
@@ -20,10 +27,28 @@ const (
 	// debugging is enabled.
 	DebugConfigKey = "packer_debug"
 
+	// This is the key in configurations that is set to the names of the
+	// steps of the builder to skip.
+	DebugSkipConfigKey = "packer_debug_skip"
+
+	// This is the key in configurations that is set to the file to write
+	// the trace of the steps of the builder to.
+	DebugTraceConfigKey = "packer_debug_trace"
+
 	// This is the key in configurations that is set to "true" when Packer
 	// force build is enabled.
 	ForceConfigKey = "packer_force"
 
+	// This is the key in configurations that is set to "true" when the
+	// files the builder downloads must be downloaded again, even if they
+	// are in the cache.
+	ForceDownloadConfigKey = "packer_force_download"
+
+	// This is the key in configurations that is set to "true" when the
+	// builder must not download anything, and only use the files in the
+	// cache.
+	OfflineConfigKey = "packer_offline"
+
 	// This key determines what to do when a normal multistep step fails
 	// - "cleanup" - run cleanup steps
 	// - "abort" - exit without cleanup
@@ -33,11 +58,29 @@ const (
 	// TemplatePathKey is the path to the template that configured this build
 	TemplatePathKey = "packer_template_path"
 
+	// These keys are the file the component is defined in and where it is
+	// in the file, like "builders[1]", so errors about its configuration
+	// can point to the keys. See template.Pos.
+	ComponentFileConfigKey = "packer_component_file"
+	ComponentPathConfigKey = "packer_component_path"
+
 	// This key contains a map[string]string of the user variables for
 	// template processing.
 	UserVariablesConfigKey = "packer_user_variables"
+
+	// This key contains the seed of the random functions of the run, hex
+	// encoded, so they return the same values in the plugins.
+	RandomSeedConfigKey = "packer_random_seed"
+
+	// This key contains a map[string]string of the values the builder
+	// recorded about the build, for the post-processors that are
+	// configured again once the builder ran.
+	BuildValuesConfigKey = "packer_build_values"
 )
 
+// buildCall matches the calls to the build function in a configuration.
+var buildCall = regexp.MustCompile(`{{-?\s*build\s`)
+
 // A Build represents a single job within Packer that is responsible for
 // building some machine image artifact. Builds are meant to be parallelized.
 type Build interface {
@@ -68,17 +111,38 @@ type Build interface {
 	// strictly prohibited.
 	SetDebug(bool)
 
+	// SetDebugSkip sets the names of the steps of the builder to skip,
+	// such as StepShutdown, to debug builders and templates.
+	SetDebugSkip([]string)
+
+	// SetDebugTrace sets the file the builder appends a trace of its steps
+	// to, as JSON lines, when they start and end.
+	SetDebugTrace(string)
+
 	// SetForce will enable/disable forcing a build when artifacts exist.
 	//
 	// When SetForce is set to true, existing artifacts from the build are
 	// deleted prior to the build.
 	SetForce(bool)
 
+	// SetForceDownload will enable/disable downloading the files the
+	// builder needs again, even if they are in the cache.
+	SetForceDownload(bool)
+
+	// SetOffline will enable/disable the offline mode, where the builder
+	// fails instead of downloading files that aren't in the cache.
+	SetOffline(bool)
+
 	// SetOnError will determine what to do when a normal multistep step fails
 	// - "cleanup" - run cleanup steps
 	// - "abort" - exit without cleanup
 	// - "ask" - ask the user
 	SetOnError(string)
+
+	// SetParallelPostProcessors will enable/disable running the
+	// post-processor chains of the build concurrently. The chains still
+	// run one after the other in debug mode.
+	SetParallelPostProcessors(bool)
 }
 
 // A build struct represents a single build job, the result of which should
@@ -89,6 +153,7 @@ type coreBuild struct {
 	name           string
 	builder        Builder
 	builderConfig  interface{}
+	builderPos     template.Pos
 	builderType    string
 	hooks          map[string][]Hook
 	postProcessors [][]coreBuildPostProcessor
@@ -96,11 +161,22 @@ type coreBuild struct {
 	templatePath   string
 	variables      map[string]string
 
-	debug         bool
-	force         bool
-	onError       string
-	l             sync.Mutex
-	prepareCalled bool
+	debug                  bool
+	debugSkip              []string
+	debugTrace             string
+	force                  bool
+	forceDownload          bool
+	offline                bool
+	onError                string
+	parallelPostProcessors bool
+	packerConfig           map[string]interface{}
+	l                      sync.Mutex
+	prepareCalled          bool
+
+	// The post-processors that are running, to cancel them with the build.
+	ppLock     sync.Mutex
+	cancelled  bool
+	runningPPs map[PostProcessor]struct{}
 }
 
 // Keeps track of the post-processor and the configuration of the
@@ -108,8 +184,9 @@ type coreBuild struct {
 type coreBuildPostProcessor struct {
 	processor         PostProcessor
 	processorType     string
-	config            map[string]interface{}
+	config            []interface{}
 	keepInputArtifact bool
+	pos               template.Pos
 }
 
 // Keeps track of the provisioner and the configuration of the provisioner
@@ -118,6 +195,7 @@ type coreBuildProvisioner struct {
 	pType       string
 	provisioner Provisioner
 	config      []interface{}
+	pos         template.Pos
 }
 
 // Returns the name of the build.
@@ -142,16 +220,23 @@ func (b *coreBuild) Prepare() (warn []string, err error) {
 		BuildNameConfigKey:     b.name,
 		BuilderTypeConfigKey:   b.builderType,
 		DebugConfigKey:         b.debug,
+		DebugSkipConfigKey:     b.debugSkip,
+		DebugTraceConfigKey:    b.debugTrace,
 		ForceConfigKey:         b.force,
+		ForceDownloadConfigKey: b.forceDownload,
+		OfflineConfigKey:       b.offline,
 		OnErrorConfigKey:       b.onError,
+		RandomSeedConfigKey:    hex.EncodeToString(interpolate.RandomSeed),
 		TemplatePathKey:        b.templatePath,
 		UserVariablesConfigKey: b.variables,
 	}
+	b.packerConfig = packerConfig
 
 	// Prepare the builder
-	warn, err = b.builder.Prepare(b.builderConfig, packerConfig)
+	warn, err = b.builder.Prepare(b.builderConfig, componentConfig(packerConfig, b.builderPos))
 	if err != nil {
 		log.Printf("Build '%s' prepare failure: %s\n", b.name, err)
+		err = newPrepareError(b.builderPos, b.builderType, err)
 		return
 	}
 
@@ -159,9 +244,10 @@ func (b *coreBuild) Prepare() (warn []string, err error) {
 	for _, coreProv := range b.provisioners {
 		configs := make([]interface{}, len(coreProv.config), len(coreProv.config)+1)
 		copy(configs, coreProv.config)
-		configs = append(configs, packerConfig)
+		configs = append(configs, componentConfig(packerConfig, coreProv.pos))
 
 		if err = coreProv.provisioner.Prepare(configs...); err != nil {
+			err = newPrepareError(coreProv.pos, coreProv.pType, err)
 			return
 		}
 	}
@@ -169,8 +255,13 @@ func (b *coreBuild) Prepare() (warn []string, err error) {
 	// Prepare the post-processors
 	for _, ppSeq := range b.postProcessors {
 		for _, corePP := range ppSeq {
-			err = corePP.processor.Configure(corePP.config, packerConfig)
+			configs := make([]interface{}, len(corePP.config), len(corePP.config)+1)
+			copy(configs, corePP.config)
+			configs = append(configs, componentConfig(packerConfig, corePP.pos))
+
+			err = corePP.processor.Configure(configs...)
 			if err != nil {
+				err = newPrepareError(corePP.pos, corePP.processorType, err)
 				return
 			}
 		}
@@ -185,6 +276,14 @@ func (b *coreBuild) Run(originalUi Ui, cache Cache) ([]Artifact, error) {
 		panic("Prepare must be called first")
 	}
 
+	buildSpan := Tracing.StartSpan("build "+b.Name(), nil, map[string]string{
+		"packer.build.name": b.Name(),
+		"packer.build.type": b.builderType,
+	})
+	builderSpan := Tracing.StartSpan("builder "+b.builderType, buildSpan, map[string]string{
+		"packer.builder.type": b.builderType,
+	})
+
 	// Copy the hooks
 	hooks := make(map[string][]Hook)
 	for hookName, hookList := range b.hooks {
@@ -221,6 +320,7 @@ func (b *coreBuild) Run(originalUi Ui, cache Cache) ([]Artifact, error) {
 
 		hooks[HookProvision] = append(hooks[HookProvision], &ProvisionHook{
 			Provisioners: hookedProvisioners,
+			span:         builderSpan,
 		})
 	}
 
@@ -233,77 +333,67 @@ func (b *coreBuild) Run(originalUi Ui, cache Cache) ([]Artifact, error) {
 		Ui:     originalUi,
 	}
 
+	// Record how long the steps, provisioners, and post-processors take
+	// to show where the time went once the build is done.
+	start := time.Now()
+	if err := commonhelper.SetBuildStart(b.name, start); err != nil {
+		log.Printf("Error recording the start of build %s: %s", b.name, err)
+	}
+	durations := &durationsUi{Ui: builderUi, span: builderSpan}
+	defer func() {
+		builderUi.Say("Durations:")
+		builderUi.Message(durations.Summary(time.Since(start)))
+	}()
+
 	log.Printf("Running builder: %s", b.builderType)
 	ts := CheckpointReporter.AddSpan(b.builderType, "builder", b.builderConfig)
-	builderArtifact, err := b.builder.Run(builderUi, hook, cache)
+	builderArtifact, err := b.builder.Run(durations, hook, cache)
 	ts.End(err)
+	builderSpan.End(err)
 	if err != nil {
+		buildSpan.End(err)
 		return nil, err
 	}
 
 	// If there was no result, don't worry about running post-processors
 	// because there is nothing they can do, just return.
 	if builderArtifact == nil {
+		buildSpan.End(nil)
 		return nil, nil
 	}
 
 	errors := make([]error, 0)
 	keepOriginalArtifact := len(b.postProcessors) == 0
 
-	// Run the post-processors
-PostProcessorRunSeqLoop:
-	for _, ppSeq := range b.postProcessors {
-		priorArtifact := builderArtifact
-		for i, corePP := range ppSeq {
-			ppUi := &TargetedUI{
-				Target: fmt.Sprintf("%s (%s)", b.Name(), corePP.processorType),
-				Ui:     originalUi,
-			}
-
-			builderUi.Say(fmt.Sprintf("Running post-processor: %s", corePP.processorType))
-			ts := CheckpointReporter.AddSpan(corePP.processorType, "post-processor", corePP.config)
-			artifact, keep, err := corePP.processor.PostProcess(ppUi, priorArtifact)
-			ts.End(err)
-			if err != nil {
-				errors = append(errors, fmt.Errorf("Post-processor failed: %s", err))
-				continue PostProcessorRunSeqLoop
-			}
-
-			if artifact == nil {
-				log.Println("Nil artifact, halting post-processor chain.")
-				continue PostProcessorRunSeqLoop
-			}
-
-			keep = keep || corePP.keepInputArtifact
-			if i == 0 {
-				// This is the first post-processor. We handle deleting
-				// previous artifacts a bit different because multiple
-				// post-processors may be using the original and need it.
-				if !keepOriginalArtifact && keep {
-					log.Printf(
-						"Flagging to keep original artifact from post-processor '%s'",
-						corePP.processorType)
-					keepOriginalArtifact = true
-				}
-			} else {
-				// We have a prior artifact. If we want to keep it, we append
-				// it to the results list. Otherwise, we destroy it.
-				if keep {
-					artifacts = append(artifacts, priorArtifact)
-				} else {
-					log.Printf("Deleting prior artifact from post-processor '%s'", corePP.processorType)
-					if err := priorArtifact.Destroy(); err != nil {
-						errors = append(errors, fmt.Errorf("Failed cleaning up prior artifact: %s", err))
-					}
-				}
-			}
-
-			priorArtifact = artifact
+	// The values of the build the post-processors interpolate are the ones
+	// the builder recorded while it ran.
+	buildValues := commonhelper.RecordedBuildValues(b.name)
+
+	// Run the post-processors. Each chain starts from the builder artifact,
+	// so they don't depend on each other and can run concurrently. Their
+	// results are collected in the order of the chains either way.
+	results := make([]postProcessorChainResult, len(b.postProcessors))
+	if b.parallelPostProcessors && !b.debug {
+		var wg sync.WaitGroup
+		for i, ppSeq := range b.postProcessors {
+			wg.Add(1)
+			go func(i int, ppSeq []coreBuildPostProcessor) {
+				defer wg.Done()
+				results[i] = b.runPostProcessorChain(durations, originalUi, ppSeq, builderArtifact, buildValues, buildSpan)
+			}(i, ppSeq)
 		}
+		wg.Wait()
+	} else {
+		for i, ppSeq := range b.postProcessors {
+			results[i] = b.runPostProcessorChain(durations, originalUi, ppSeq, builderArtifact, buildValues, buildSpan)
+		}
+	}
 
-		// Add on the last artifact to the results
-		if priorArtifact != nil {
-			artifacts = append(artifacts, priorArtifact)
+	for _, result := range results {
+		artifacts = append(artifacts, result.artifacts...)
+		errors = append(errors, result.errors...)
+		if result.keepOriginalArtifact {
+			keepOriginalArtifact = true
 		}
 	}
 
@@ -321,10 +411,105 @@ PostProcessorRunSeqLoop:
 	if len(errors) > 0 {
 		err = &MultiError{errors}
 	}
+	buildSpan.End(err)
 
 	return artifacts, err
 }
 
+// The outcome of running one post-processor chain.
+type postProcessorChainResult struct {
+	// The artifacts of the chain to keep, in the order they were made.
+	artifacts []Artifact
+
+	// Whether the first post-processor wants the builder artifact kept.
+	keepOriginalArtifact bool
+
+	errors []error
+}
+
+// runPostProcessorChain runs the post-processors of one chain on the
+// builder artifact. Artifacts in between that the next post-processor
+// doesn't want kept are destroyed, but the builder artifact is left to the
+// caller since all the chains use it.
+func (b *coreBuild) runPostProcessorChain(builderUi *durationsUi, originalUi Ui, ppSeq []coreBuildPostProcessor, builderArtifact Artifact, buildValues map[string]string, buildSpan *OTLPSpan) postProcessorChainResult {
+	var result postProcessorChainResult
+
+	priorArtifact := builderArtifact
+	for i, corePP := range ppSeq {
+		ppUi := &TargetedUI{
+			Target: fmt.Sprintf("%s (%s)", b.Name(), corePP.processorType),
+			Ui:     originalUi,
+		}
+
+		// A cancelled build doesn't start any more post-processors
+		if !b.startPostProcessor(corePP.processor) {
+			result.errors = append(result.errors, fmt.Errorf("Post-processor %s cancelled", corePP.processorType))
+			return result
+		}
+
+		builderUi.Say(fmt.Sprintf("Running post-processor: %s", corePP.processorType))
+		if callsBuild(corePP.config) {
+			if err := b.configureWithBuildValues(corePP, buildValues); err != nil {
+				b.donePostProcessor(corePP.processor)
+				result.errors = append(result.errors, fmt.Errorf("Post-processor failed: %s", err))
+				return result
+			}
+		}
+		ts := CheckpointReporter.AddSpan(corePP.processorType, "post-processor", corePP.config)
+		span := Tracing.StartSpan("post-processor "+corePP.processorType, buildSpan, map[string]string{
+			"packer.post_processor.type": corePP.processorType,
+		})
+		ppStart := time.Now()
+		artifact, keep, err := corePP.processor.PostProcess(ppUi, priorArtifact)
+		b.donePostProcessor(corePP.processor)
+		builderUi.record("post-processor", corePP.processorType, time.Since(ppStart))
+		ts.End(err)
+		span.End(err)
+		if err != nil {
+			result.errors = append(result.errors, fmt.Errorf("Post-processor failed: %s", err))
+			return result
+		}
+
+		if artifact == nil {
+			log.Println("Nil artifact, halting post-processor chain.")
+			return result
+		}
+
+		keep = keep || corePP.keepInputArtifact
+		if i == 0 {
+			// This is the first post-processor. We handle deleting
+			// previous artifacts a bit different because multiple
+			// post-processors may be using the original and need it.
+			if keep {
+				log.Printf(
+					"Flagging to keep original artifact from post-processor '%s'",
+					corePP.processorType)
+				result.keepOriginalArtifact = true
+			}
+		} else {
+			// We have a prior artifact. If we want to keep it, we append
+			// it to the results list. Otherwise, we destroy it.
+			if keep {
+				result.artifacts = append(result.artifacts, priorArtifact)
+			} else {
+				log.Printf("Deleting prior artifact from post-processor '%s'", corePP.processorType)
+				if err := priorArtifact.Destroy(); err != nil {
+					result.errors = append(result.errors, fmt.Errorf("Failed cleaning up prior artifact: %s", err))
+				}
+			}
+		}
+
+		priorArtifact = artifact
+	}
+
+	// Add on the last artifact to the results
+	if priorArtifact != nil {
+		result.artifacts = append(result.artifacts, priorArtifact)
+	}
+
+	return result
+}
+
 func (b *coreBuild) SetDebug(val bool) {
 	if b.prepareCalled {
 		panic("prepare has already been called")
@@ -333,6 +518,22 @@ func (b *coreBuild) SetDebug(val bool) {
 	b.debug = val
 }
 
+func (b *coreBuild) SetDebugSkip(val []string) {
+	if b.prepareCalled {
+		panic("prepare has already been called")
+	}
+
+	b.debugSkip = val
+}
+
+func (b *coreBuild) SetDebugTrace(val string) {
+	if b.prepareCalled {
+		panic("prepare has already been called")
+	}
+
+	b.debugTrace = val
+}
+
 func (b *coreBuild) SetForce(val bool) {
 	if b.prepareCalled {
 		panic("prepare has already been called")
@@ -341,6 +542,22 @@ func (b *coreBuild) SetForce(val bool) {
 	b.force = val
 }
 
+func (b *coreBuild) SetForceDownload(val bool) {
+	if b.prepareCalled {
+		panic("prepare has already been called")
+	}
+
+	b.forceDownload = val
+}
+
+func (b *coreBuild) SetOffline(val bool) {
+	if b.prepareCalled {
+		panic("prepare has already been called")
+	}
+
+	b.offline = val
+}
+
 func (b *coreBuild) SetOnError(val string) {
 	if b.prepareCalled {
 		panic("prepare has already been called")
@@ -349,7 +566,113 @@ func (b *coreBuild) SetOnError(val string) {
 	b.onError = val
 }
 
-// Cancels the build if it is running.
+func (b *coreBuild) SetParallelPostProcessors(val bool) {
+	if b.prepareCalled {
+		panic("prepare has already been called")
+	}
+
+	b.parallelPostProcessors = val
+}
+
+// Cancels the build if it is running: the builder, and the post-processors
+// that are running and can be cancelled. The post-processors that didn't
+// start yet aren't run.
 func (b *coreBuild) Cancel() {
+	b.ppLock.Lock()
+	b.cancelled = true
+	running := make([]PostProcessor, 0, len(b.runningPPs))
+	for pp := range b.runningPPs {
+		running = append(running, pp)
+	}
+	b.ppLock.Unlock()
+
+	for _, pp := range running {
+		if cp, ok := pp.(CancellablePostProcessor); ok {
+			cp.Cancel()
+		}
+	}
 	b.builder.Cancel()
 }
+
+// startPostProcessor records that the post-processor runs, unless the build
+// was cancelled.
+func (b *coreBuild) startPostProcessor(pp PostProcessor) bool {
+	b.ppLock.Lock()
+	defer b.ppLock.Unlock()
+
+	if b.cancelled {
+		return false
+	}
+	if b.runningPPs == nil {
+		b.runningPPs = make(map[PostProcessor]struct{})
+	}
+	b.runningPPs[pp] = struct{}{}
+	return true
+}
+
+func (b *coreBuild) donePostProcessor(pp PostProcessor) {
+	b.ppLock.Lock()
+	defer b.ppLock.Unlock()
+
+	delete(b.runningPPs, pp)
+}
+
+// configureWithBuildValues configures the post-processor again with the
+// values the builder recorded, for the build calls of its configuration
+// that were left as they were when the build was prepared.
+func (b *coreBuild) configureWithBuildValues(corePP coreBuildPostProcessor, buildValues map[string]string) error {
+	packerConfig := make(map[string]interface{}, len(b.packerConfig)+3)
+	for k, v := range componentConfig(b.packerConfig, corePP.pos) {
+		packerConfig[k] = v
+	}
+	packerConfig[BuildValuesConfigKey] = buildValues
+
+	configs := make([]interface{}, len(corePP.config), len(corePP.config)+1)
+	copy(configs, corePP.config)
+	configs = append(configs, packerConfig)
+	return corePP.processor.Configure(configs...)
+}
+
+// componentConfig is the packer configuration with the position of the
+// component, when it is known.
+func componentConfig(packerConfig map[string]interface{}, pos template.Pos) map[string]interface{} {
+	if pos.Path == "" {
+		return packerConfig
+	}
+
+	result := make(map[string]interface{}, len(packerConfig)+2)
+	for k, v := range packerConfig {
+		result[k] = v
+	}
+	result[ComponentFileConfigKey] = pos.Filename
+	result[ComponentPathConfigKey] = pos.Path
+	return result
+}
+
+// callsBuild says whether a string of the configuration calls the build
+// function.
+func callsBuild(v interface{}) bool {
+	switch v := v.(type) {
+	case string:
+		return buildCall.MatchString(v)
+	case []interface{}:
+		for _, e := range v {
+			if callsBuild(e) {
+				return true
+			}
+		}
+	case []string:
+		for _, e := range v {
+			if callsBuild(e) {
+				return true
+			}
+		}
+	case map[string]interface{}:
+		for _, e := range v {
+			if callsBuild(e) {
+				return true
+			}
+		}
+	}
+	return false
+}
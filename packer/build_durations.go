@@ -0,0 +1,122 @@
+package packer
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// buildDuration is how long a part of the build took.
+type buildDuration struct {
+	Kind     string
+	Name     string
+	Duration time.Duration
+}
+
+// durationsUi records the "step-duration" and "provisioner-duration"
+// machine readable messages that go through it. The steps run in the
+// builder plugin, so the UI is the way the timings come back to the core.
+// The steps, downloads, and retries are also traced under span.
+type durationsUi struct {
+	Ui
+
+	span *OTLPSpan
+
+	lock      sync.Mutex
+	durations []buildDuration
+}
+
+func (u *durationsUi) Machine(t string, args ...string) {
+	switch t {
+	case "step-duration":
+		if len(args) == 2 {
+			u.add("step", args[0], args[1])
+			u.traceStep(args[0], args[1])
+		}
+	case "download-bytes":
+		if len(args) == 2 {
+			if n, err := strconv.ParseFloat(args[1], 64); err == nil {
+				Tracing.Count("packer.download.bytes", "By", n, map[string]string{"packer.download.scheme": args[0]})
+			}
+		}
+	case "retry":
+		if len(args) >= 1 {
+			Tracing.Count("packer.retries", "1", 1, map[string]string{"packer.retry.kind": args[0]})
+		}
+	case "provisioner-duration":
+		if len(args) == 3 {
+			u.add("provisioner", fmt.Sprintf("%s (%s)", args[0], args[1]), args[2])
+		}
+	}
+
+	u.Ui.Machine(t, args...)
+}
+
+func (u *durationsUi) Log(level UiLevel, message string) {
+	UiLog(u.Ui, level, message)
+}
+
+func (u *durationsUi) Output(stream, line string) {
+	UiOutput(u.Ui, stream, line)
+}
+
+func (u *durationsUi) add(kind, name, seconds string) {
+	s, err := strconv.ParseFloat(seconds, 64)
+	if err != nil {
+		return
+	}
+
+	u.record(kind, name, time.Duration(s*float64(time.Second)))
+}
+
+// traceStep adds the span of a step that just ended, and counts it.
+func (u *durationsUi) traceStep(name, seconds string) {
+	if Tracing == nil {
+		return
+	}
+	s, err := strconv.ParseFloat(seconds, 64)
+	if err != nil {
+		return
+	}
+
+	end := time.Now()
+	attrs := map[string]string{"packer.step": name}
+	Tracing.AddSpan("step "+name, u.span, end.Add(-time.Duration(s*float64(time.Second))), end, attrs)
+	Tracing.Count("packer.step.count", "1", 1, attrs)
+	Tracing.Count("packer.step.duration", "s", s, attrs)
+}
+
+func (u *durationsUi) record(kind, name string, d time.Duration) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	u.durations = append(u.durations, buildDuration{kind, name, d})
+}
+
+// Summary returns the table of the durations recorded, in the order the
+// parts of the build ran, and the total time of the build.
+func (u *durationsUi) Summary(total time.Duration) string {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 8, 2, ' ', 0)
+	for _, d := range u.durations {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", d.Kind, d.Name, formatDuration(d.Duration))
+	}
+	fmt.Fprintf(w, "total\t\t%s\n", formatDuration(total))
+	w.Flush()
+
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+func formatDuration(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(time.Second).String()
+}
@@ -0,0 +1,32 @@
+package packer
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDurationsUi(t *testing.T) {
+	ui := &durationsUi{Ui: TestUi(t)}
+	ui.Machine("provisioner-duration", "1", "shell", "61.2")
+	ui.Machine("step-duration", "StepProvision", "62.5")
+	ui.Machine("step-duration", "bad")
+	ui.Machine("artifact", "0", "id", "foo")
+	ui.record("post-processor", "compress", 1500*time.Millisecond)
+
+	expected := []string{
+		"provisioner     1 (shell)      1m1s",
+		"step            StepProvision  1m3s",
+		"post-processor  compress       2s",
+		"total                          1h0m0s",
+	}
+	actual := strings.Split(ui.Summary(time.Hour), "\n")
+	if len(actual) != len(expected) {
+		t.Fatalf("bad: %#v", actual)
+	}
+	for i := range expected {
+		if strings.TrimSpace(actual[i]) != expected[i] {
+			t.Fatalf("line %d: expected %q, got %q", i, expected[i], actual[i])
+		}
+	}
+}
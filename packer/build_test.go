@@ -1,8 +1,17 @@
 package packer
 
 import (
+	"encoding/hex"
+	"errors"
+	"os"
 	"reflect"
+	"sync"
 	"testing"
+	"time"
+
+	commonhelper "github.com/hashicorp/packer/helper/common"
+	"github.com/hashicorp/packer/template"
+	"github.com/hashicorp/packer/template/interpolate"
 )
 
 func testBuild() *coreBuild {
@@ -15,11 +24,11 @@ func testBuild() *coreBuild {
 			"foo": {&MockHook{}},
 		},
 		provisioners: []coreBuildProvisioner{
-			{"mock-provisioner", &MockProvisioner{}, []interface{}{42}},
+			{"mock-provisioner", &MockProvisioner{}, []interface{}{42}, template.Pos{}},
 		},
 		postProcessors: [][]coreBuildPostProcessor{
 			{
-				{&MockPostProcessor{ArtifactId: "pp"}, "testPP", make(map[string]interface{}), true},
+				{&MockPostProcessor{ArtifactId: "pp"}, "testPP", []interface{}{make(map[string]interface{})}, true, template.Pos{}},
 			},
 		},
 		variables: make(map[string]string),
@@ -32,8 +41,13 @@ func testDefaultPackerConfig() map[string]interface{} {
 		BuildNameConfigKey:     "test",
 		BuilderTypeConfigKey:   "foo",
 		DebugConfigKey:         false,
+		DebugSkipConfigKey:     []string(nil),
+		DebugTraceConfigKey:    "",
 		ForceConfigKey:         false,
+		ForceDownloadConfigKey: false,
+		OfflineConfigKey:       false,
 		OnErrorConfigKey:       "cleanup",
+		RandomSeedConfigKey:    hex.EncodeToString(interpolate.RandomSeed),
 		TemplatePathKey:        "",
 		UserVariablesConfigKey: make(map[string]string),
 	}
@@ -78,6 +92,33 @@ func TestBuild_Prepare(t *testing.T) {
 	}
 }
 
+func TestBuild_Prepare_ComponentPos(t *testing.T) {
+	build := testBuild()
+	build.builderPos = template.Pos{Filename: "web.json", Path: "builders[0]"}
+	build.provisioners[0].pos = template.Pos{Filename: "web.json", Path: "provisioners[0]"}
+	build.Prepare()
+
+	packerConfig := testDefaultPackerConfig()
+	packerConfig[ComponentFileConfigKey] = "web.json"
+	packerConfig[ComponentPathConfigKey] = "builders[0]"
+	builder := build.builder.(*MockBuilder)
+	if !reflect.DeepEqual(builder.PrepareConfig, []interface{}{42, packerConfig}) {
+		t.Fatalf("bad: %#v", builder.PrepareConfig)
+	}
+
+	packerConfig[ComponentPathConfigKey] = "provisioners[0]"
+	prov := build.provisioners[0].provisioner.(*MockProvisioner)
+	if !reflect.DeepEqual(prov.PrepConfigs, []interface{}{42, packerConfig}) {
+		t.Fatalf("bad: %#v", prov.PrepConfigs)
+	}
+
+	// The position isn't sent when it isn't known
+	pp := build.postProcessors[0][0].processor.(*MockPostProcessor)
+	if !reflect.DeepEqual(pp.ConfigureConfigs, []interface{}{make(map[string]interface{}), testDefaultPackerConfig()}) {
+		t.Fatalf("bad: %#v", pp.ConfigureConfigs)
+	}
+}
+
 func TestBuild_Prepare_Twice(t *testing.T) {
 	build := testBuild()
 	warn, err := build.Prepare()
@@ -144,6 +185,75 @@ func TestBuild_Prepare_Debug(t *testing.T) {
 	}
 }
 
+func TestBuild_Prepare_DebugSkipTrace(t *testing.T) {
+	packerConfig := testDefaultPackerConfig()
+	packerConfig[DebugSkipConfigKey] = []string{"StepShutdown"}
+	packerConfig[DebugTraceConfigKey] = "trace.json"
+
+	build := testBuild()
+	builder := build.builder.(*MockBuilder)
+
+	build.SetDebugSkip([]string{"StepShutdown"})
+	build.SetDebugTrace("trace.json")
+	build.Prepare()
+	if !reflect.DeepEqual(builder.PrepareConfig, []interface{}{42, packerConfig}) {
+		t.Fatalf("bad: %#v", builder.PrepareConfig)
+	}
+}
+
+func TestBuild_Prepare_Downloads(t *testing.T) {
+	packerConfig := testDefaultPackerConfig()
+	packerConfig[ForceDownloadConfigKey] = true
+	packerConfig[OfflineConfigKey] = true
+
+	build := testBuild()
+	builder := build.builder.(*MockBuilder)
+
+	build.SetForceDownload(true)
+	build.SetOffline(true)
+	build.Prepare()
+	if !reflect.DeepEqual(builder.PrepareConfig, []interface{}{42, packerConfig}) {
+		t.Fatalf("bad: %#v", builder.PrepareConfig)
+	}
+}
+
+func TestBuild_Prepare_ErrorPosition(t *testing.T) {
+	build := testBuild()
+	prov := build.provisioners[0].provisioner.(*MockProvisioner)
+	prov.PrepErr = MultiErrorAppend(nil, errors.New("invalid 'inline[0]'"), errors.New("bad"))
+	build.provisioners[0].pos = template.Pos{
+		Filename: "/tmp/template.json",
+		Path:     "provisioners[0]",
+		Line:     12,
+		Column:   5,
+	}
+
+	_, err := build.Prepare()
+	merr, ok := err.(*MultiError)
+	if !ok || len(merr.Errors) != 2 {
+		t.Fatalf("bad: %#v", err)
+	}
+	perr, ok := merr.Errors[0].(*PrepareError)
+	if !ok {
+		t.Fatalf("bad: %#v", merr.Errors[0])
+	}
+	expected := "template.json:12:5: provisioners[0] (mock-provisioner): invalid 'inline[0]'"
+	if perr.Error() != expected {
+		t.Fatalf("bad: %s", perr)
+	}
+	if perr.Summary() != "provisioners[0] (mock-provisioner): invalid 'inline[0]'" {
+		t.Fatalf("bad: %s", perr.Summary())
+	}
+
+	// Without a position, the error is the one of the provisioner.
+	build = testBuild()
+	prov = build.provisioners[0].provisioner.(*MockProvisioner)
+	prov.PrepErr = errors.New("bad")
+	if _, err := build.Prepare(); err == nil || err.Error() != "bad" {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
 func TestBuildPrepare_variables_default(t *testing.T) {
 	packerConfig := testDefaultPackerConfig()
 	packerConfig[UserVariablesConfigKey] = map[string]string{
@@ -190,6 +300,9 @@ func TestBuild_Run(t *testing.T) {
 	if !builder.RunCalled {
 		t.Fatal("should be called")
 	}
+	if _, ok := commonhelper.BuildStart(build.Name()); !ok {
+		t.Fatal("should record the start of the build")
+	}
 
 	// Verify hooks are dispatchable
 	dispatchHook := builder.RunHook
@@ -247,7 +360,7 @@ func TestBuild_Run_Artifacts(t *testing.T) {
 	build = testBuild()
 	build.postProcessors = [][]coreBuildPostProcessor{
 		{
-			{&MockPostProcessor{ArtifactId: "pp"}, "pp", make(map[string]interface{}), false},
+			{&MockPostProcessor{ArtifactId: "pp"}, "pp", []interface{}{make(map[string]interface{})}, false, template.Pos{}},
 		},
 	}
 
@@ -272,10 +385,10 @@ func TestBuild_Run_Artifacts(t *testing.T) {
 	build = testBuild()
 	build.postProcessors = [][]coreBuildPostProcessor{
 		{
-			{&MockPostProcessor{ArtifactId: "pp1"}, "pp", make(map[string]interface{}), false},
+			{&MockPostProcessor{ArtifactId: "pp1"}, "pp", []interface{}{make(map[string]interface{})}, false, template.Pos{}},
 		},
 		{
-			{&MockPostProcessor{ArtifactId: "pp2"}, "pp", make(map[string]interface{}), true},
+			{&MockPostProcessor{ArtifactId: "pp2"}, "pp", []interface{}{make(map[string]interface{})}, true, template.Pos{}},
 		},
 	}
 
@@ -300,12 +413,12 @@ func TestBuild_Run_Artifacts(t *testing.T) {
 	build = testBuild()
 	build.postProcessors = [][]coreBuildPostProcessor{
 		{
-			{&MockPostProcessor{ArtifactId: "pp1a"}, "pp", make(map[string]interface{}), false},
-			{&MockPostProcessor{ArtifactId: "pp1b"}, "pp", make(map[string]interface{}), true},
+			{&MockPostProcessor{ArtifactId: "pp1a"}, "pp", []interface{}{make(map[string]interface{})}, false, template.Pos{}},
+			{&MockPostProcessor{ArtifactId: "pp1b"}, "pp", []interface{}{make(map[string]interface{})}, true, template.Pos{}},
 		},
 		{
-			{&MockPostProcessor{ArtifactId: "pp2a"}, "pp", make(map[string]interface{}), false},
-			{&MockPostProcessor{ArtifactId: "pp2b"}, "pp", make(map[string]interface{}), false},
+			{&MockPostProcessor{ArtifactId: "pp2a"}, "pp", []interface{}{make(map[string]interface{})}, false, template.Pos{}},
+			{&MockPostProcessor{ArtifactId: "pp2b"}, "pp", []interface{}{make(map[string]interface{})}, false, template.Pos{}},
 		},
 	}
 
@@ -331,7 +444,7 @@ func TestBuild_Run_Artifacts(t *testing.T) {
 	build.postProcessors = [][]coreBuildPostProcessor{
 		{
 			{
-				&MockPostProcessor{ArtifactId: "pp", Keep: true}, "pp", make(map[string]interface{}), false,
+				&MockPostProcessor{ArtifactId: "pp", Keep: true}, "pp", []interface{}{make(map[string]interface{})}, false, template.Pos{},
 			},
 		},
 	}
@@ -353,6 +466,102 @@ func TestBuild_Run_Artifacts(t *testing.T) {
 	}
 }
 
+// barrierPostProcessor waits in PostProcess until all the post-processors
+// sharing its barrier are running.
+type barrierPostProcessor struct {
+	MockPostProcessor
+	barrier *sync.WaitGroup
+}
+
+func (p *barrierPostProcessor) PostProcess(ui Ui, a Artifact) (Artifact, bool, error) {
+	p.barrier.Done()
+
+	done := make(chan struct{})
+	go func() {
+		p.barrier.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return p.MockPostProcessor.PostProcess(ui, a)
+	case <-time.After(5 * time.Second):
+		return nil, false, errors.New("post-processors didn't run concurrently")
+	}
+}
+
+func TestBuild_Run_ParallelPostProcessors(t *testing.T) {
+	barrier := new(sync.WaitGroup)
+	barrier.Add(2)
+
+	build := testBuild()
+	build.postProcessors = [][]coreBuildPostProcessor{
+		{
+			{&barrierPostProcessor{MockPostProcessor{ArtifactId: "pp1a"}, barrier}, "pp", []interface{}{make(map[string]interface{})}, false, template.Pos{}},
+			{&MockPostProcessor{ArtifactId: "pp1b"}, "pp", []interface{}{make(map[string]interface{})}, false, template.Pos{}},
+		},
+		{
+			{&barrierPostProcessor{MockPostProcessor{ArtifactId: "pp2"}, barrier}, "pp", []interface{}{make(map[string]interface{})}, true, template.Pos{}},
+		},
+	}
+	build.SetParallelPostProcessors(true)
+
+	build.Prepare()
+	artifacts, err := build.Run(testUi(), &TestCache{})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The artifacts are in the order of the chains, and the second chain
+	// keeping its input keeps the builder artifact for both.
+	expectedIds := []string{"b", "pp1b", "pp2"}
+	artifactIds := make([]string, len(artifacts))
+	for i, artifact := range artifacts {
+		artifactIds[i] = artifact.Id()
+	}
+
+	if !reflect.DeepEqual(artifactIds, expectedIds) {
+		t.Fatalf("unexpected ids: %#v", artifactIds)
+	}
+}
+
+func TestBuild_Run_BuildValues(t *testing.T) {
+	defer os.Setenv("PACKER_RUN_UUID", os.Getenv("PACKER_RUN_UUID"))
+	os.Setenv("PACKER_RUN_UUID", "build-test")
+	defer commonhelper.RemoveSharedState()
+
+	build := testBuild()
+	build.postProcessors = [][]coreBuildPostProcessor{
+		{
+			{&MockPostProcessor{ArtifactId: "pp1"}, "pp", []interface{}{map[string]interface{}{"output": "{{build `ID`}}.box"}}, false, template.Pos{}},
+			{&MockPostProcessor{ArtifactId: "pp2"}, "pp", []interface{}{map[string]interface{}{"output": "out.box"}}, false, template.Pos{}},
+		},
+	}
+	build.Prepare()
+
+	if err := commonhelper.SetBuildValue("ID", "i-1234", "test"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := build.Run(testUi(), &TestCache{}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The post-processor calling build is configured again with the
+	// values, the other one isn't.
+	pp := build.postProcessors[0][0].processor.(*MockPostProcessor)
+	packerConfig := pp.ConfigureConfigs[len(pp.ConfigureConfigs)-1].(map[string]interface{})
+	expected := map[string]string{"ID": "i-1234"}
+	if !reflect.DeepEqual(packerConfig[BuildValuesConfigKey], expected) {
+		t.Fatalf("bad: %#v", packerConfig[BuildValuesConfigKey])
+	}
+
+	pp = build.postProcessors[0][1].processor.(*MockPostProcessor)
+	packerConfig = pp.ConfigureConfigs[len(pp.ConfigureConfigs)-1].(map[string]interface{})
+	if _, ok := packerConfig[BuildValuesConfigKey]; ok {
+		t.Fatal("should not be configured again")
+	}
+}
+
 func TestBuild_RunBeforePrepare(t *testing.T) {
 	defer func() {
 		p := recover()
@@ -377,3 +586,64 @@ func TestBuild_Cancel(t *testing.T) {
 		t.Fatal("cancel should be called")
 	}
 }
+
+// cancelPostProcessor blocks in PostProcess until it is cancelled.
+type cancelPostProcessor struct {
+	MockPostProcessor
+	started  chan struct{}
+	cancelCh chan struct{}
+}
+
+func (p *cancelPostProcessor) PostProcess(ui Ui, a Artifact) (Artifact, bool, error) {
+	close(p.started)
+	<-p.cancelCh
+	return nil, false, errors.New("cancelled")
+}
+
+func (p *cancelPostProcessor) Cancel() {
+	close(p.cancelCh)
+}
+
+func TestBuild_Cancel_PostProcessors(t *testing.T) {
+	pp := &cancelPostProcessor{started: make(chan struct{}), cancelCh: make(chan struct{})}
+	next := &MockPostProcessor{}
+
+	build := testBuild()
+	build.postProcessors = [][]coreBuildPostProcessor{
+		{
+			{pp, "pp", []interface{}{make(map[string]interface{})}, false, template.Pos{}},
+			{next, "pp", []interface{}{make(map[string]interface{})}, false, template.Pos{}},
+		},
+	}
+	build.Prepare()
+
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := build.Run(testUi(), &TestCache{})
+		errCh <- err
+	}()
+
+	<-pp.started
+	build.Cancel()
+	select {
+	case err := <-errCh:
+		if err == nil {
+			t.Fatal("should error")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the post-processor should be cancelled")
+	}
+
+	if next.PostProcessCalled {
+		t.Fatal("the next post-processor should not run")
+	}
+
+	// Post-processors don't start once the build is cancelled.
+	build = testBuild()
+	build.Prepare()
+	build.Cancel()
+	build.Run(testUi(), &TestCache{})
+	if build.postProcessors[0][0].processor.(*MockPostProcessor).PostProcessCalled {
+		t.Fatal("should not run")
+	}
+}
@@ -32,6 +32,14 @@ type RemoteCmd struct {
 	Stdout io.Writer
 	Stderr io.Writer
 
+	// User, Workdir, and Env are the user to run the command as, its
+	// working directory, and extra environment variables, for the
+	// communicators that support them, like the one of the docker
+	// builder. The others ignore them.
+	User    string
+	Workdir string
+	Env     map[string]string
+
 	// This will be set to true when the remote command has exited. It
 	// shouldn't be set manually by the user, but there is no harm in
 	// doing so.
@@ -86,7 +94,7 @@ type Communicator interface {
 
 // StartWithUi runs the remote command and streams the output to any
 // configured Writers for stdout/stderr, while also writing each line
-// as it comes to a Ui.
+// as it comes to the Output of a Ui, with its stream.
 func (r *RemoteCmd) StartWithUi(c Communicator, ui Ui) error {
 	stdout_r, stdout_w := io.Pipe()
 	stderr_r, stderr_w := io.Pipe()
@@ -141,11 +149,11 @@ OutputLoop:
 		select {
 		case output := <-stderrCh:
 			if output != "" {
-				ui.Message(r.cleanOutputLine(output))
+				UiOutput(ui, UiStreamStderr, r.cleanOutputLine(output))
 			}
 		case output := <-stdoutCh:
 			if output != "" {
-				ui.Message(r.cleanOutputLine(output))
+				UiOutput(ui, UiStreamStdout, r.cleanOutputLine(output))
 			}
 		case <-exitCh:
 			break OutputLoop
@@ -155,11 +163,11 @@ OutputLoop:
 	// Make sure we finish off stdout/stderr because we may have gotten
 	// a message from the exit channel before finishing these first.
 	for output := range stdoutCh {
-		ui.Message(r.cleanOutputLine(output))
+		UiOutput(ui, UiStreamStdout, r.cleanOutputLine(output))
 	}
 
 	for output := range stderrCh {
-		ui.Message(r.cleanOutputLine(output))
+		UiOutput(ui, UiStreamStderr, r.cleanOutputLine(output))
 	}
 
 	return nil
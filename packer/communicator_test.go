@@ -42,6 +42,33 @@ func TestRemoteCmd_StartWithUi(t *testing.T) {
 	}
 }
 
+func TestRemoteCmd_StartWithUi_stderr(t *testing.T) {
+	uiOutput := new(bytes.Buffer)
+	uiErrorOutput := new(bytes.Buffer)
+
+	testComm := new(MockCommunicator)
+	testComm.StartStdout = "hello\n"
+	testComm.StartStderr = "world\n"
+	testUi := &BasicUi{
+		Reader:      new(bytes.Buffer),
+		Writer:      uiOutput,
+		ErrorWriter: uiErrorOutput,
+	}
+
+	rc := &RemoteCmd{Command: "test"}
+	if err := rc.StartWithUi(testComm, testUi); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	rc.Wait()
+
+	if uiOutput.String() != "hello\n" {
+		t.Fatalf("bad output: %#v", uiOutput.String())
+	}
+	if uiErrorOutput.String() != "world\n" {
+		t.Fatalf("bad error output: %#v", uiErrorOutput.String())
+	}
+}
+
 func TestRemoteCmd_Wait(t *testing.T) {
 	var cmd RemoteCmd
 
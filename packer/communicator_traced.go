@@ -0,0 +1,104 @@
+package packer
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+// tracedCommunicator records the commands, uploads, and downloads of the
+// provisioners as spans and metrics of the run.
+type tracedCommunicator struct {
+	Communicator
+
+	span *OTLPSpan
+}
+
+func (c *tracedCommunicator) Start(cmd *RemoteCmd) error {
+	span := Tracing.StartSpan("communicator.command", c.span, map[string]string{
+		"packer.command": cmd.Command,
+	})
+	Tracing.Count("packer.communicator.commands", "1", 1, nil)
+
+	if err := c.Communicator.Start(cmd); err != nil {
+		span.End(err)
+		return err
+	}
+
+	go func() {
+		cmd.Wait()
+		var err error
+		if cmd.ExitStatus != 0 {
+			err = fmt.Errorf("exit status %d", cmd.ExitStatus)
+		}
+		span.SetAttribute("packer.exit_status", strconv.Itoa(cmd.ExitStatus))
+		span.End(err)
+	}()
+
+	return nil
+}
+
+func (c *tracedCommunicator) Upload(dst string, r io.Reader, fi *os.FileInfo) error {
+	span := Tracing.StartSpan("communicator.upload", c.span, map[string]string{
+		"packer.destination": dst,
+	})
+	counter := &countingReader{r: r}
+	err := c.Communicator.Upload(dst, counter, fi)
+	Tracing.Count("packer.communicator.upload.bytes", "By", float64(counter.n), nil)
+	span.End(err)
+	return err
+}
+
+func (c *tracedCommunicator) UploadDir(dst string, src string, exclude []string) error {
+	span := Tracing.StartSpan("communicator.upload_dir", c.span, map[string]string{
+		"packer.source":      src,
+		"packer.destination": dst,
+	})
+	err := c.Communicator.UploadDir(dst, src, exclude)
+	span.End(err)
+	return err
+}
+
+func (c *tracedCommunicator) Download(src string, w io.Writer) error {
+	span := Tracing.StartSpan("communicator.download", c.span, map[string]string{
+		"packer.source": src,
+	})
+	counter := &countingWriter{w: w}
+	err := c.Communicator.Download(src, counter)
+	Tracing.Count("packer.communicator.download.bytes", "By", float64(counter.n), nil)
+	span.End(err)
+	return err
+}
+
+func (c *tracedCommunicator) DownloadDir(src string, dst string, exclude []string) error {
+	span := Tracing.StartSpan("communicator.download_dir", c.span, map[string]string{
+		"packer.source":      src,
+		"packer.destination": dst,
+	})
+	err := c.Communicator.DownloadDir(src, dst, exclude)
+	span.End(err)
+	return err
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
@@ -20,6 +20,8 @@ type Core struct {
 	builds     map[string]*template.Builder
 	version    string
 	secrets    []string
+
+	skipDatasources bool
 }
 
 // CoreConfig is the structure for initializing a new Core. Once a CoreConfig
@@ -30,6 +32,14 @@ type CoreConfig struct {
 	Variables          map[string]string
 	SensitiveVariables []string
 	Version            string
+
+	// SkipDatasources replaces the template functions that read from
+	// remote services, consul_key and vault, with placeholders. This lets
+	// a template be checked without access to those services.
+	SkipDatasources bool
+
+	// Notifications are sent when builds are done.
+	Notifications []*Notification
 }
 
 // The function type used to lookup Builder implementations.
@@ -61,6 +71,8 @@ func NewCore(c *CoreConfig) (*Core, error) {
 		components: c.Components,
 		variables:  c.Variables,
 		version:    c.Version,
+
+		skipDatasources: c.SkipDatasources,
 	}
 
 	if err := result.validate(); err != nil {
@@ -151,6 +163,17 @@ func (c *Core) Build(n string) (Build, error) {
 			}
 		}
 
+		// The user, working directory, and environment of the commands
+		// of the provisioner go with each command.
+		if rawP.ExecUser != "" || rawP.ExecWorkdir != "" || len(rawP.ExecEnv) > 0 {
+			provisioner = &ExecProvisioner{
+				User:        rawP.ExecUser,
+				Workdir:     rawP.ExecWorkdir,
+				Env:         rawP.ExecEnv,
+				Provisioner: provisioner,
+			}
+		}
+
 		// If we're pausing, we wrap the provisioner in a special pauser.
 		if rawP.PauseBefore > 0 {
 			provisioner = &PausedProvisioner{
@@ -163,6 +186,23 @@ func (c *Core) Build(n string) (Build, error) {
 			pType:       rawP.Type,
 			provisioner: provisioner,
 			config:      config,
+			pos:         rawP.Pos,
+		})
+	}
+
+	// The image metadata is written after the provisioners, to hash them
+	if m := c.Template.ImageMetadata; m != nil && !m.Skip(rawName) {
+		ctx := c.Context()
+		ctx.BuildName = n
+		ctx.BuildType = configBuilder.Type
+		provisioner, err := newImageMetadataProvisioner(m, ctx, n, configBuilder.Type, provisioners)
+		if err != nil {
+			return nil, fmt.Errorf("image_metadata: %s", err)
+		}
+		provisioners = append(provisioners, coreBuildProvisioner{
+			pType:       "image-metadata",
+			provisioner: provisioner,
+			pos:         m.Pos,
 		})
 	}
 
@@ -188,11 +228,21 @@ func (c *Core) Build(n string) (Build, error) {
 					"post-processor type not found: %s", rawP.Type)
 			}
 
+			// Get the configuration
+			config := make([]interface{}, 1, 2)
+			config[0] = rawP.Config
+			if rawP.Override != nil {
+				if override, ok := rawP.Override[rawName]; ok {
+					config = append(config, override)
+				}
+			}
+
 			current = append(current, coreBuildPostProcessor{
 				processor:         postProcessor,
 				processorType:     rawP.Type,
-				config:            rawP.Config,
+				config:            config,
 				keepInputArtifact: rawP.KeepInputArtifact,
+				pos:               rawP.Pos,
 			})
 		}
 
@@ -210,6 +260,7 @@ func (c *Core) Build(n string) (Build, error) {
 		name:           n,
 		builder:        builder,
 		builderConfig:  configBuilder.Config,
+		builderPos:     configBuilder.Pos,
 		builderType:    configBuilder.Type,
 		postProcessors: postProcessors,
 		provisioners:   provisioners,
@@ -286,6 +337,16 @@ func (c *Core) init() error {
 	ctx := c.Context()
 	ctx.EnableEnv = true
 	ctx.UserVariables = nil
+	if c.skipDatasources {
+		ctx.Funcs = map[string]interface{}{
+			"consul_key": func(k string) string {
+				return fmt.Sprintf("<consul_key %s>", k)
+			},
+			"vault": func(path, key string) string {
+				return fmt.Sprintf("<vault %s %s>", path, key)
+			},
+		}
+	}
 	for k, v := range c.Template.Variables {
 		// Ignore variables that are required
 		if v.Required {
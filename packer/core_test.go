@@ -67,7 +67,7 @@ func TestCoreBuild_basic(t *testing.T) {
 		t.Fatalf("err: %s", err)
 	}
 
-	artifact, err := build.Run(nil, nil)
+	artifact, err := build.Run(TestUi(t), nil)
 	if err != nil {
 		t.Fatalf("err: %s", err)
 	}
@@ -97,7 +97,7 @@ func TestCoreBuild_basicInterpolated(t *testing.T) {
 		t.Fatalf("err: %s", err)
 	}
 
-	artifact, err := build.Run(nil, nil)
+	artifact, err := build.Run(TestUi(t), nil)
 	if err != nil {
 		t.Fatalf("err: %s", err)
 	}
@@ -142,6 +142,34 @@ func TestCoreBuild_env(t *testing.T) {
 	}
 }
 
+func TestCoreBuild_skipDatasources(t *testing.T) {
+	config := TestCoreConfig(t)
+	config.SkipDatasources = true
+	testCoreTemplate(t, config, fixtureDir("build-datasources.json"))
+	b := TestBuilder(t, config, "test")
+	core := TestCore(t, config)
+
+	build, err := core.Build("test")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if _, err := build.Prepare(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Interpolate the config
+	var result map[string]interface{}
+	err = configHelper.Decode(&result, nil, b.PrepareConfig...)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if result["value"] != "<consul_key packer/test>" {
+		t.Fatalf("bad: %#v", result)
+	}
+}
+
 func TestCoreBuild_buildNameVar(t *testing.T) {
 	config := TestCoreConfig(t)
 	testCoreTemplate(t, config, fixtureDir("build-var-build-name.json"))
@@ -230,7 +258,7 @@ func TestCoreBuild_prov(t *testing.T) {
 		t.Fatalf("err: %s", err)
 	}
 
-	artifact, err := build.Run(nil, nil)
+	artifact, err := build.Run(TestUi(t), nil)
 	if err != nil {
 		t.Fatalf("err: %s", err)
 	}
@@ -264,7 +292,7 @@ func TestCoreBuild_provSkip(t *testing.T) {
 		t.Fatalf("err: %s", err)
 	}
 
-	artifact, err := build.Run(nil, nil)
+	artifact, err := build.Run(TestUi(t), nil)
 	if err != nil {
 		t.Fatalf("err: %s", err)
 	}
@@ -298,7 +326,7 @@ func TestCoreBuild_provSkipInclude(t *testing.T) {
 		t.Fatalf("err: %s", err)
 	}
 
-	artifact, err := build.Run(nil, nil)
+	artifact, err := build.Run(TestUi(t), nil)
 	if err != nil {
 		t.Fatalf("err: %s", err)
 	}
@@ -332,7 +360,7 @@ func TestCoreBuild_provOverride(t *testing.T) {
 		t.Fatalf("err: %s", err)
 	}
 
-	artifact, err := build.Run(nil, nil)
+	artifact, err := build.Run(TestUi(t), nil)
 	if err != nil {
 		t.Fatalf("err: %s", err)
 	}
@@ -397,6 +425,36 @@ func TestCoreBuild_postProcess(t *testing.T) {
 	}
 }
 
+func TestCoreBuild_postProcessOverride(t *testing.T) {
+	config := TestCoreConfig(t)
+	testCoreTemplate(t, config, fixtureDir("build-pp-override.json"))
+	TestBuilder(t, config, "test")
+	p := TestPostProcessor(t, config, "test")
+	core := TestCore(t, config)
+
+	build, err := core.Build("test")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if _, err := build.Prepare(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	found := false
+	for _, raw := range p.ConfigureConfigs {
+		if m, ok := raw.(map[string]interface{}); ok {
+			if _, ok := m["foo"]; ok {
+				found = true
+				break
+			}
+		}
+	}
+	if !found {
+		t.Fatal("override not called")
+	}
+}
+
 func TestCoreBuild_templatePath(t *testing.T) {
 	config := TestCoreConfig(t)
 	testCoreTemplate(t, config, fixtureDir("build-template-path.json"))
@@ -0,0 +1,185 @@
+package packer
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/packer/template"
+	"github.com/hashicorp/packer/template/interpolate"
+	packerVersion "github.com/hashicorp/packer/version"
+)
+
+// imageMetadataStagingPath is where the image metadata file is uploaded
+// before the move command moves it.
+const imageMetadataStagingPath = "/tmp/packer-image.json"
+
+// ImageMetadata is the content of the image metadata file, which says how
+// the image was built.
+type ImageMetadata struct {
+	Template         string   `json:"template"`
+	Version          string   `json:"version,omitempty"`
+	GitCommit        string   `json:"git_commit,omitempty"`
+	BuildName        string   `json:"build_name"`
+	BuilderType      string   `json:"builder_type"`
+	BuildTime        string   `json:"build_time"`
+	PackerVersion    string   `json:"packer_version"`
+	PackerRunUUID    string   `json:"packer_run_uuid,omitempty"`
+	Provisioners     []string `json:"provisioners"`
+	ProvisionersHash string   `json:"provisioners_hash"`
+}
+
+// imageMetadataMoveTemplate is the data of the move command.
+type imageMetadataMoveTemplate struct {
+	Source      string
+	Destination string
+}
+
+// imageMetadataProvisioner writes the image metadata file. The core adds it
+// after the provisioners of the template, for the builds it isn't skipped
+// for.
+type imageMetadataProvisioner struct {
+	path        string
+	moveCommand string
+	metadata    ImageMetadata
+}
+
+// newImageMetadataProvisioner returns the provisioner writing the metadata
+// of the build to run after the provisioners.
+func newImageMetadataProvisioner(m *template.ImageMetadata, ctx *interpolate.Context, buildName, builderType string, provisioners []coreBuildProvisioner) (*imageMetadataProvisioner, error) {
+	name, err := interpolate.Render(m.Name, ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Error interpolating name: %s", err)
+	}
+	if name == "" && ctx.TemplatePath != "" {
+		base := filepath.Base(ctx.TemplatePath)
+		name = strings.TrimSuffix(base, filepath.Ext(base))
+	}
+	version, err := interpolate.Render(m.Version, ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Error interpolating version: %s", err)
+	}
+	path, err := interpolate.Render(m.Path, ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Error interpolating path: %s", err)
+	}
+	if m.MoveCommand != "" {
+		if _, err := interpolate.Render(m.MoveCommand, &interpolate.Context{Data: &imageMetadataMoveTemplate{}}); err != nil {
+			return nil, fmt.Errorf("Error parsing move_command: %s", err)
+		}
+	}
+
+	hash, err := provisionersHash(provisioners)
+	if err != nil {
+		return nil, err
+	}
+	types := make([]string, len(provisioners))
+	for i, p := range provisioners {
+		types[i] = p.pType
+	}
+
+	return &imageMetadataProvisioner{
+		path:        path,
+		moveCommand: m.MoveCommand,
+		metadata: ImageMetadata{
+			Template:         name,
+			Version:          version,
+			GitCommit:        GitCommit(ctx.TemplatePath),
+			BuildName:        buildName,
+			BuilderType:      builderType,
+			PackerVersion:    packerVersion.FormattedVersion(),
+			PackerRunUUID:    os.Getenv("PACKER_RUN_UUID"),
+			Provisioners:     types,
+			ProvisionersHash: hash,
+		},
+	}, nil
+}
+
+// provisionersHash is the hash of the types and the configurations of the
+// provisioners, so images provisioned the same way have the same one.
+func provisionersHash(provisioners []coreBuildProvisioner) (string, error) {
+	type hashed struct {
+		Type   string        `json:"type"`
+		Config []interface{} `json:"config"`
+	}
+	list := make([]hashed, len(provisioners))
+	for i, p := range provisioners {
+		list[i] = hashed{Type: p.pType, Config: p.config}
+	}
+	raw, err := json.Marshal(list)
+	if err != nil {
+		return "", fmt.Errorf("Error hashing the provisioners: %s", err)
+	}
+	sum := sha256.Sum256(raw)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
+}
+
+func (p *imageMetadataProvisioner) Prepare(...interface{}) error {
+	return nil
+}
+
+func (p *imageMetadataProvisioner) Provision(ui Ui, comm Communicator) error {
+	metadata := p.metadata
+	metadata.BuildTime = time.Now().UTC().Format(time.RFC3339)
+	raw, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return err
+	}
+	raw = append(raw, '\n')
+
+	ui.Say(fmt.Sprintf("Writing the image metadata to %s...", p.path))
+	if p.moveCommand == "" {
+		if err := comm.Upload(p.path, bytes.NewReader(raw), nil); err != nil {
+			return fmt.Errorf("Error uploading the image metadata: %s", err)
+		}
+		return nil
+	}
+
+	if err := comm.Upload(imageMetadataStagingPath, bytes.NewReader(raw), nil); err != nil {
+		return fmt.Errorf("Error uploading the image metadata: %s", err)
+	}
+	command, err := interpolate.Render(p.moveCommand, &interpolate.Context{
+		Data: &imageMetadataMoveTemplate{
+			Source:      imageMetadataStagingPath,
+			Destination: p.path,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("Error interpolating move_command: %s", err)
+	}
+	cmd := &RemoteCmd{Command: command}
+	if err := cmd.StartWithUi(comm, ui); err != nil {
+		return fmt.Errorf("Error moving the image metadata: %s", err)
+	}
+	if cmd.ExitStatus != 0 {
+		return fmt.Errorf("Error moving the image metadata: exit status %d", cmd.ExitStatus)
+	}
+	return nil
+}
+
+func (p *imageMetadataProvisioner) Cancel() {}
+
+// GitCommit returns the commit checked out in the repository containing
+// the template, or "" if it isn't in one or git isn't installed.
+func GitCommit(templatePath string) string {
+	if templatePath == "" {
+		return ""
+	}
+
+	cmd := exec.Command("git", "rev-parse", "HEAD")
+	cmd.Dir = filepath.Dir(templatePath)
+	out, err := cmd.Output()
+	if err != nil {
+		log.Printf("Unable to determine git commit for %s: %s", templatePath, err)
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
@@ -0,0 +1,129 @@
+package packer
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/packer/template"
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+func TestCoreBuild_imageMetadata(t *testing.T) {
+	config := TestCoreConfig(t)
+	testCoreTemplate(t, config, fixtureDir("build-image-metadata.json"))
+	TestBuilder(t, config, "test")
+	TestProvisioner(t, config, "test")
+	core := TestCore(t, config)
+
+	build, err := core.Build("test")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	provisioners := build.(*coreBuild).provisioners
+	if len(provisioners) != 2 || provisioners[1].pType != "image-metadata" {
+		t.Fatalf("bad: %#v", provisioners)
+	}
+	p := provisioners[1].provisioner.(*imageMetadataProvisioner)
+	if p.path != template.DefaultImageMetadataPath {
+		t.Fatalf("bad: %s", p.path)
+	}
+	if p.metadata.Template != "build-image-metadata" || p.metadata.Version != "1.2.0" {
+		t.Fatalf("bad: %#v", p.metadata)
+	}
+	if !reflect.DeepEqual(p.metadata.Provisioners, []string{"test"}) {
+		t.Fatalf("bad: %#v", p.metadata.Provisioners)
+	}
+
+	// Skipped with except
+	build, err = core.Build("foo")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if provisioners := build.(*coreBuild).provisioners; len(provisioners) != 1 {
+		t.Fatalf("bad: %#v", provisioners)
+	}
+}
+
+func TestImageMetadataProvisioner(t *testing.T) {
+	provisioners := []coreBuildProvisioner{
+		{pType: "shell", config: []interface{}{map[string]interface{}{"inline": []interface{}{"true"}}}},
+	}
+	ctx := &interpolate.Context{TemplatePath: filepath.Join("foo", "base.json")}
+	p, err := newImageMetadataProvisioner(&template.ImageMetadata{Path: "/etc/image.json"}, ctx, "amazon", "amazon-ebs", provisioners)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	comm := new(MockCommunicator)
+	if err := p.Provision(TestUi(t), comm); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if comm.UploadPath != "/etc/image.json" {
+		t.Fatalf("bad: %s", comm.UploadPath)
+	}
+	if comm.StartCalled {
+		t.Fatal("should not run a command")
+	}
+
+	var metadata ImageMetadata
+	if err := json.Unmarshal([]byte(comm.UploadData), &metadata); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if metadata.Template != "base" || metadata.BuildName != "amazon" || metadata.BuilderType != "amazon-ebs" {
+		t.Fatalf("bad: %#v", metadata)
+	}
+	if metadata.BuildTime == "" || metadata.PackerVersion == "" {
+		t.Fatalf("bad: %#v", metadata)
+	}
+	if !reflect.DeepEqual(metadata.Provisioners, []string{"shell"}) {
+		t.Fatalf("bad: %#v", metadata.Provisioners)
+	}
+
+	// The hash changes with the configuration of the provisioners
+	hash := metadata.ProvisionersHash
+	provisioners[0].config = []interface{}{map[string]interface{}{"inline": []interface{}{"false"}}}
+	p, err = newImageMetadataProvisioner(&template.ImageMetadata{Path: "/etc/image.json"}, ctx, "amazon", "amazon-ebs", provisioners)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if p.metadata.ProvisionersHash == hash {
+		t.Fatalf("bad: %s", hash)
+	}
+}
+
+func TestImageMetadataProvisioner_moveCommand(t *testing.T) {
+	m := &template.ImageMetadata{
+		Path:        "/etc/image.json",
+		MoveCommand: "sudo mv {{.Source}} {{.Destination}}",
+	}
+	p, err := newImageMetadataProvisioner(m, &interpolate.Context{}, "test", "test", nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	comm := new(MockCommunicator)
+	if err := p.Provision(TestUi(t), comm); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if comm.UploadPath != imageMetadataStagingPath {
+		t.Fatalf("bad: %s", comm.UploadPath)
+	}
+	expected := "sudo mv " + imageMetadataStagingPath + " /etc/image.json"
+	if comm.StartCmd == nil || comm.StartCmd.Command != expected {
+		t.Fatalf("bad: %#v", comm.StartCmd)
+	}
+
+	comm = &MockCommunicator{StartExitStatus: 1}
+	if err := p.Provision(TestUi(t), comm); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+func TestImageMetadataProvisioner_badMoveCommand(t *testing.T) {
+	m := &template.ImageMetadata{MoveCommand: "{{.Nope}}"}
+	if _, err := newImageMetadataProvisioner(m, &interpolate.Context{}, "test", "test", nil); err == nil {
+		t.Fatal("should error")
+	}
+}
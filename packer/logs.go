@@ -3,6 +3,7 @@ package packer
 import (
 	"bytes"
 	"io"
+	"strings"
 	"sync"
 )
 
@@ -35,6 +36,16 @@ func (l *secretFilter) Write(p []byte) (n int, err error) {
 	return l.w.Write(p)
 }
 
+// Filter removes the secrets from s, like from the logs.
+func (l *secretFilter) Filter(s string) string {
+	for _, secret := range l.get() {
+		if secret != "" {
+			s = strings.Replace(s, secret, "<sensitive>", -1)
+		}
+	}
+	return s
+}
+
 func (l *secretFilter) get() (s []string) {
 	l.m.Lock()
 	defer l.m.Unlock()
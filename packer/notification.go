@@ -0,0 +1,217 @@
+package packer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+// The statuses of a finished build that notifications are sent for.
+const (
+	BuildStatusSuccess   = "success"
+	BuildStatusFailure   = "failure"
+	BuildStatusCancelled = "cancelled"
+)
+
+// notificationTimeout is how long a webhook has to answer.
+const notificationTimeout = 30 * time.Second
+
+// Notification is a webhook that is called when a build is done. They are
+// set in the "notifications" of the Packer configuration file.
+type Notification struct {
+	// URL is called with the payload. It can use the env function, to
+	// keep the secret part of the URL out of the file.
+	URL string `json:"url"`
+
+	// Method defaults to POST.
+	Method string `json:"method"`
+
+	// Headers are added to the request, with env available in the values.
+	Headers map[string]string `json:"headers"`
+
+	// Payload is a template for the body of the request, which gets a
+	// BuildResult as data. It defaults to a JSON object with a Slack
+	// compatible "text".
+	Payload string `json:"payload"`
+
+	// On are the statuses to notify, all of them by default.
+	On []string `json:"on"`
+}
+
+// BuildResult is what notifications are told about a finished build.
+type BuildResult struct {
+	BuildName string   `json:"build_name"`
+	BuildType string   `json:"build_type"`
+	Status    string   `json:"status"`
+	Duration  string   `json:"duration"`
+	Seconds   float64  `json:"duration_seconds"`
+	Error     string   `json:"error,omitempty"`
+	Artifacts []string `json:"artifacts"`
+
+	// Text is a one line summary of the result.
+	Text string `json:"text"`
+}
+
+// NewBuildResult returns the result of a build to notify.
+func NewBuildResult(name, builderType, status string, duration time.Duration, artifacts []Artifact, err error) *BuildResult {
+	r := &BuildResult{
+		BuildName: name,
+		BuildType: builderType,
+		Status:    status,
+		Duration:  duration.Round(time.Second).String(),
+		Seconds:   duration.Seconds(),
+		Artifacts: []string{},
+	}
+	for _, a := range artifacts {
+		if a != nil {
+			r.Artifacts = append(r.Artifacts, a.Id())
+		}
+	}
+	if err != nil {
+		r.Error = err.Error()
+	}
+
+	switch status {
+	case BuildStatusSuccess:
+		r.Text = fmt.Sprintf("Packer build '%s' succeeded in %s", name, r.Duration)
+		if len(r.Artifacts) > 0 {
+			r.Text += ": " + strings.Join(r.Artifacts, ", ")
+		}
+	case BuildStatusCancelled:
+		r.Text = fmt.Sprintf("Packer build '%s' was cancelled after %s", name, r.Duration)
+	default:
+		r.Text = fmt.Sprintf("Packer build '%s' failed after %s: %s", name, r.Duration, r.Error)
+	}
+
+	return r
+}
+
+// Validate checks the notification can be sent.
+func (n *Notification) Validate() error {
+	var errs error
+	if n.URL == "" {
+		errs = multierror.Append(errs, fmt.Errorf("url must be specified"))
+	}
+	for _, s := range n.On {
+		switch s {
+		case BuildStatusSuccess, BuildStatusFailure, BuildStatusCancelled:
+		default:
+			errs = multierror.Append(errs, fmt.Errorf(
+				"on: unknown status '%s', must be one of success, failure, or cancelled", s))
+		}
+	}
+	if n.Payload != "" {
+		if err := interpolate.Validate(n.Payload, n.payloadContext(nil)); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("payload: %s", err))
+		}
+	}
+
+	return errs
+}
+
+// Wants says whether the notification is sent for builds with the status.
+func (n *Notification) Wants(status string) bool {
+	if len(n.On) == 0 {
+		return true
+	}
+	for _, s := range n.On {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// Send calls the webhook with the result of the build.
+func (n *Notification) Send(r *BuildResult) error {
+	body, err := n.payload(r)
+	if err != nil {
+		return err
+	}
+
+	envCtx := &interpolate.Context{EnableEnv: true}
+	url, err := interpolate.Render(n.URL, envCtx)
+	if err != nil {
+		return fmt.Errorf("Error rendering url: %s", err)
+	}
+
+	method := n.Method
+	if method == "" {
+		method = "POST"
+	}
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range n.Headers {
+		value, err := interpolate.Render(v, envCtx)
+		if err != nil {
+			return fmt.Errorf("Error rendering header %s: %s", k, err)
+		}
+		req.Header.Set(k, value)
+	}
+
+	client := &http.Client{Timeout: notificationTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s returned %s: %s", req.URL.Host, resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	return nil
+}
+
+func (n *Notification) payload(r *BuildResult) ([]byte, error) {
+	if n.Payload == "" {
+		return json.Marshal(r)
+	}
+
+	payload, err := interpolate.Render(n.Payload, n.payloadContext(r))
+	if err != nil {
+		return nil, fmt.Errorf("Error rendering payload: %s", err)
+	}
+	return []byte(payload), nil
+}
+
+// payloadContext makes the result available to the payload template, with
+// a json function to quote the values.
+func (n *Notification) payloadContext(r *BuildResult) *interpolate.Context {
+	return &interpolate.Context{
+		Data:      r,
+		EnableEnv: true,
+		Funcs: map[string]interface{}{
+			"json": func(v interface{}) (string, error) {
+				raw, err := json.Marshal(v)
+				return string(raw), err
+			},
+		},
+	}
+}
+
+// SendNotifications sends the notifications that want the result, and
+// returns the errors of the ones that failed.
+func SendNotifications(ns []*Notification, r *BuildResult) error {
+	var errs error
+	for _, n := range ns {
+		if !n.Wants(r.Status) {
+			continue
+		}
+		if err := n.Send(r); err != nil {
+			errs = multierror.Append(errs, err)
+		}
+	}
+	return errs
+}
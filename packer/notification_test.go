@@ -0,0 +1,126 @@
+package packer
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNotificationValidate(t *testing.T) {
+	cases := []struct {
+		Notification Notification
+		Err          bool
+	}{
+		{Notification{URL: "https://example.com"}, false},
+		{Notification{URL: "https://example.com", On: []string{"failure", "cancelled"}}, false},
+		{Notification{}, true},
+		{Notification{URL: "https://example.com", On: []string{"done"}}, true},
+		{Notification{URL: "https://example.com", Payload: `{"text": {{json .Text}}}`}, false},
+		{Notification{URL: "https://example.com", Payload: `{{.Text`}, true},
+	}
+
+	for _, tc := range cases {
+		err := tc.Notification.Validate()
+		if (err != nil) != tc.Err {
+			t.Fatalf("%#v: %s", tc.Notification, err)
+		}
+	}
+}
+
+func TestNotificationSend(t *testing.T) {
+	var body []byte
+	var header http.Header
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = ioutil.ReadAll(r.Body)
+		header = r.Header
+	}))
+	defer ts.Close()
+
+	os.Setenv("PACKER_TEST_NOTIFICATION_TOKEN", "secret")
+	defer os.Unsetenv("PACKER_TEST_NOTIFICATION_TOKEN")
+
+	n := &Notification{
+		URL:     ts.URL,
+		Headers: map[string]string{"Authorization": `Bearer {{env "PACKER_TEST_NOTIFICATION_TOKEN"}}`},
+	}
+	artifact := &MockArtifact{IdValue: "ami-1234"}
+	r := NewBuildResult("amazon-ebs", "amazon-ebs", BuildStatusSuccess, 90*time.Second, []Artifact{artifact, nil}, nil)
+	if err := n.Send(r); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if header.Get("Authorization") != "Bearer secret" {
+		t.Fatalf("bad: %#v", header)
+	}
+	var payload map[string]interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatalf("err: %s: %s", err, body)
+	}
+	if payload["text"] != "Packer build 'amazon-ebs' succeeded in 1m30s: ami-1234" {
+		t.Fatalf("bad: %s", body)
+	}
+	if payload["status"] != "success" || payload["duration_seconds"] != 90.0 {
+		t.Fatalf("bad: %s", body)
+	}
+}
+
+func TestNotificationSend_payload(t *testing.T) {
+	var body []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = ioutil.ReadAll(r.Body)
+	}))
+	defer ts.Close()
+
+	n := &Notification{
+		URL:     ts.URL,
+		Payload: `{"content": {{json .Text}}, "name": "{{.BuildName}}"}`,
+	}
+	r := NewBuildResult("docker", "docker", BuildStatusFailure, time.Second, nil, errors.New(`exit "1"`))
+	if err := n.Send(r); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := `{"content": "Packer build 'docker' failed after 1s: exit \"1\"", "name": "docker"}`
+	if string(body) != expected {
+		t.Fatalf("bad: %s", body)
+	}
+}
+
+func TestNotificationSend_errorStatus(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no_service", http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	n := &Notification{URL: ts.URL}
+	err := n.Send(NewBuildResult("foo", "foo", BuildStatusCancelled, time.Second, nil, nil))
+	if err == nil || !strings.Contains(err.Error(), "no_service") {
+		t.Fatalf("bad: %s", err)
+	}
+}
+
+func TestSendNotifications_on(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+	defer ts.Close()
+
+	ns := []*Notification{
+		{URL: ts.URL, On: []string{BuildStatusFailure}},
+		{URL: ts.URL},
+	}
+	r := NewBuildResult("foo", "foo", BuildStatusSuccess, time.Second, nil, nil)
+	if err := SendNotifications(ns, r); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if calls != 1 {
+		t.Fatalf("bad: %d", calls)
+	}
+}
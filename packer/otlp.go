@@ -0,0 +1,431 @@
+package packer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	packerVersion "github.com/hashicorp/packer/version"
+)
+
+// Tracing exports the spans and metrics of the run to an OpenTelemetry
+// collector. It is nil, and does nothing, unless an OTLP endpoint is set
+// in the environment.
+var Tracing *OTLPExporter
+
+// OTLPExporter collects the spans and the metrics of a run of Packer and
+// sends them with OTLP over HTTP, in JSON, when the run is done. It is
+// configured with the standard OTEL_EXPORTER_OTLP_* environment variables.
+type OTLPExporter struct {
+	tracesURL  string
+	metricsURL string
+	headers    map[string]string
+	resource   map[string]string
+
+	traceID string
+	root    *OTLPSpan
+	start   time.Time
+
+	lock    sync.Mutex
+	spans   []*OTLPSpan
+	metrics map[string]*otlpMetric
+}
+
+// OTLPSpan is an operation of the run, such as a build or a step.
+type OTLPSpan struct {
+	exporter *OTLPExporter
+
+	Name       string
+	SpanID     string
+	ParentID   string
+	StartTime  time.Time
+	EndTime    time.Time
+	Attributes map[string]string
+	Error      string
+}
+
+type otlpMetric struct {
+	name  string
+	unit  string
+	attrs map[string]string
+	value float64
+}
+
+// NewOTLPExporter returns the exporter configured by the environment, or
+// nil if no endpoint is set. The trace continues the one in TRACEPARENT
+// if it is set, for example by the CI system that runs Packer.
+func NewOTLPExporter() *OTLPExporter {
+	base := strings.TrimSuffix(os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"), "/")
+	e := &OTLPExporter{
+		tracesURL:  os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"),
+		metricsURL: os.Getenv("OTEL_EXPORTER_OTLP_METRICS_ENDPOINT"),
+		headers:    parseOTLPList(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS")),
+		resource:   parseOTLPList(os.Getenv("OTEL_RESOURCE_ATTRIBUTES")),
+		start:      time.Now(),
+		metrics:    make(map[string]*otlpMetric),
+	}
+	if e.tracesURL == "" && base != "" {
+		e.tracesURL = base + "/v1/traces"
+	}
+	if e.metricsURL == "" && base != "" {
+		e.metricsURL = base + "/v1/metrics"
+	}
+	if e.tracesURL == "" && e.metricsURL == "" {
+		return nil
+	}
+
+	if name := os.Getenv("OTEL_SERVICE_NAME"); name != "" {
+		e.resource["service.name"] = name
+	}
+	if _, ok := e.resource["service.name"]; !ok {
+		e.resource["service.name"] = "packer"
+	}
+	e.resource["service.version"] = packerVersion.FormattedVersion()
+
+	e.traceID = randomID(16)
+	parentID := ""
+	if traceID, spanID, ok := parseTraceparent(os.Getenv("TRACEPARENT")); ok {
+		e.traceID = traceID
+		parentID = spanID
+	}
+
+	e.root = &OTLPSpan{
+		exporter:   e,
+		Name:       "packer",
+		SpanID:     randomID(8),
+		ParentID:   parentID,
+		StartTime:  e.start,
+		Attributes: map[string]string{},
+	}
+
+	log.Printf("[INFO] (otlp) exporting traces to %q and metrics to %q", e.tracesURL, e.metricsURL)
+	return e
+}
+
+// StartSpan starts a span under the parent, or under the span of the run
+// when parent is nil.
+func (e *OTLPExporter) StartSpan(name string, parent *OTLPSpan, attrs map[string]string) *OTLPSpan {
+	return e.AddSpan(name, parent, time.Now(), time.Time{}, attrs)
+}
+
+// AddSpan adds a span that started, and maybe ended, earlier. The steps
+// run in the builder plugins and are only known once they are done.
+func (e *OTLPExporter) AddSpan(name string, parent *OTLPSpan, start, end time.Time, attrs map[string]string) *OTLPSpan {
+	if e == nil {
+		return nil
+	}
+	if parent == nil {
+		parent = e.root
+	}
+	if attrs == nil {
+		attrs = map[string]string{}
+	}
+
+	s := &OTLPSpan{
+		exporter:   e,
+		Name:       name,
+		SpanID:     randomID(8),
+		ParentID:   parent.SpanID,
+		StartTime:  start,
+		EndTime:    end,
+		Attributes: attrs,
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.spans = append(e.spans, s)
+	return s
+}
+
+// End ends the span, failed if err is set.
+func (s *OTLPSpan) End(err error) {
+	if s == nil {
+		return
+	}
+
+	s.exporter.lock.Lock()
+	defer s.exporter.lock.Unlock()
+
+	s.EndTime = time.Now()
+	if err != nil {
+		s.Error = err.Error()
+	}
+}
+
+// SetAttribute sets an attribute of the span.
+func (s *OTLPSpan) SetAttribute(key, value string) {
+	if s == nil {
+		return
+	}
+
+	s.exporter.lock.Lock()
+	defer s.exporter.lock.Unlock()
+
+	s.Attributes[key] = value
+}
+
+// Count adds value to the counter with the name and attributes.
+func (e *OTLPExporter) Count(name, unit string, value float64, attrs map[string]string) {
+	if e == nil {
+		return
+	}
+
+	key := name
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		key += "," + k + "=" + attrs[k]
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	m, ok := e.metrics[key]
+	if !ok {
+		m = &otlpMetric{name: name, unit: unit, attrs: attrs}
+		e.metrics[key] = m
+	}
+	m.value += value
+}
+
+// Finalize ends the span of the run and sends everything to the collector.
+func (e *OTLPExporter) Finalize(command string, exitCode int, err error) error {
+	if e == nil {
+		return nil
+	}
+
+	e.root.Name = strings.TrimSpace("packer " + command)
+	e.root.Attributes["packer.exit_code"] = strconv.Itoa(exitCode)
+	e.root.End(err)
+	if err == nil && exitCode != 0 {
+		e.root.Error = fmt.Sprintf("exit status %d", exitCode)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var errs []string
+	if e.tracesURL != "" {
+		if err := e.post(ctx, e.tracesURL, e.tracesPayload()); err != nil {
+			errs = append(errs, fmt.Sprintf("traces: %s", err))
+		}
+	}
+	if e.metricsURL != "" {
+		if err := e.post(ctx, e.metricsURL, e.metricsPayload()); err != nil {
+			errs = append(errs, fmt.Sprintf("metrics: %s", err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("Error exporting to OTLP: %s", strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+func (e *OTLPExporter) post(ctx context.Context, url string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return nil
+}
+
+// The OTLP JSON encoding, see
+// https://github.com/open-telemetry/opentelemetry-proto
+
+type otlpKeyValue struct {
+	Key   string            `json:"key"`
+	Value map[string]string `json:"value"`
+}
+
+func otlpAttributes(attrs map[string]string) []otlpKeyValue {
+	keys := make([]string, 0, len(attrs))
+	for k := range attrs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	result := make([]otlpKeyValue, 0, len(keys))
+	for _, k := range keys {
+		result = append(result, otlpKeyValue{k, map[string]string{"stringValue": attrs[k]}})
+	}
+	return result
+}
+
+func otlpTime(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+func (e *OTLPExporter) scope() map[string]string {
+	return map[string]string{"name": "packer", "version": packerVersion.FormattedVersion()}
+}
+
+func (e *OTLPExporter) tracesPayload() interface{} {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	var spans []interface{}
+	for _, s := range append([]*OTLPSpan{e.root}, e.spans...) {
+		end := s.EndTime
+		if end.IsZero() {
+			// Still running when Packer exits, such as on a panic
+			end = time.Now()
+		}
+
+		status := map[string]interface{}{"code": 1}
+		if s.Error != "" {
+			status = map[string]interface{}{"code": 2, "message": s.Error}
+		}
+
+		span := map[string]interface{}{
+			"traceId":           e.traceID,
+			"spanId":            s.SpanID,
+			"name":              s.Name,
+			"kind":              1,
+			"startTimeUnixNano": otlpTime(s.StartTime),
+			"endTimeUnixNano":   otlpTime(end),
+			"attributes":        otlpAttributes(s.Attributes),
+			"status":            status,
+		}
+		if s.ParentID != "" {
+			span["parentSpanId"] = s.ParentID
+		}
+		spans = append(spans, span)
+	}
+
+	return map[string]interface{}{
+		"resourceSpans": []interface{}{
+			map[string]interface{}{
+				"resource": map[string]interface{}{"attributes": otlpAttributes(e.resource)},
+				"scopeSpans": []interface{}{
+					map[string]interface{}{"scope": e.scope(), "spans": spans},
+				},
+			},
+		},
+	}
+}
+
+func (e *OTLPExporter) metricsPayload() interface{} {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	// One metric per name with a data point per set of attributes
+	now := time.Now()
+	byName := make(map[string]map[string]interface{})
+	var names []string
+	keys := make([]string, 0, len(e.metrics))
+	for k := range e.metrics {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		m := e.metrics[k]
+		metric, ok := byName[m.name]
+		if !ok {
+			metric = map[string]interface{}{
+				"name": m.name,
+				"unit": m.unit,
+				"sum": map[string]interface{}{
+					"aggregationTemporality": 2,
+					"isMonotonic":            true,
+					"dataPoints":             []interface{}{},
+				},
+			}
+			byName[m.name] = metric
+			names = append(names, m.name)
+		}
+
+		sum := metric["sum"].(map[string]interface{})
+		sum["dataPoints"] = append(sum["dataPoints"].([]interface{}), map[string]interface{}{
+			"attributes":        otlpAttributes(m.attrs),
+			"startTimeUnixNano": otlpTime(e.start),
+			"timeUnixNano":      otlpTime(now),
+			"asDouble":          m.value,
+		})
+	}
+
+	metrics := []interface{}{}
+	for _, n := range names {
+		metrics = append(metrics, byName[n])
+	}
+
+	return map[string]interface{}{
+		"resourceMetrics": []interface{}{
+			map[string]interface{}{
+				"resource": map[string]interface{}{"attributes": otlpAttributes(e.resource)},
+				"scopeMetrics": []interface{}{
+					map[string]interface{}{"scope": e.scope(), "metrics": metrics},
+				},
+			},
+		},
+	}
+}
+
+// parseOTLPList parses the "key1=value1,key2=value2" lists of the OTEL
+// environment variables.
+func parseOTLPList(s string) map[string]string {
+	result := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			continue
+		}
+		result[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	return result
+}
+
+// parseTraceparent parses a W3C trace context header, such as
+// 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01.
+func parseTraceparent(s string) (traceID, spanID string, ok bool) {
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return "", "", false
+	}
+	if _, err := hex.DecodeString(parts[1] + parts[2]); err != nil {
+		return "", "", false
+	}
+	return parts[1], parts[2], true
+}
+
+func randomID(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		// Unique enough for a trace
+		return fmt.Sprintf("%0*x", n*2, time.Now().UnixNano())[:n*2]
+	}
+	return hex.EncodeToString(b)
+}
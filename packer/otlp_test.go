@@ -0,0 +1,345 @@
+package packer
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testOTLPCollector records the requests sent to it by path.
+type testOTLPCollector struct {
+	*httptest.Server
+
+	lock     sync.Mutex
+	requests map[string]map[string]interface{}
+	headers  map[string]http.Header
+}
+
+func newTestOTLPCollector(t *testing.T) *testOTLPCollector {
+	c := &testOTLPCollector{
+		requests: make(map[string]map[string]interface{}),
+		headers:  make(map[string]http.Header),
+	}
+	c.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("bad body: %s", err)
+		}
+
+		c.lock.Lock()
+		defer c.lock.Unlock()
+		c.requests[r.URL.Path] = body
+		c.headers[r.URL.Path] = r.Header
+	}))
+	return c
+}
+
+func setOTLPEnv(t *testing.T, env map[string]string) func() {
+	keys := []string{
+		"OTEL_EXPORTER_OTLP_ENDPOINT",
+		"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT",
+		"OTEL_EXPORTER_OTLP_METRICS_ENDPOINT",
+		"OTEL_EXPORTER_OTLP_HEADERS",
+		"OTEL_RESOURCE_ATTRIBUTES",
+		"OTEL_SERVICE_NAME",
+		"TRACEPARENT",
+	}
+	old := make(map[string]string)
+	for _, k := range keys {
+		old[k] = os.Getenv(k)
+		os.Setenv(k, env[k])
+	}
+	return func() {
+		for k, v := range old {
+			os.Setenv(k, v)
+		}
+	}
+}
+
+func otlpSpans(body map[string]interface{}) []map[string]interface{} {
+	var result []map[string]interface{}
+	for _, rs := range body["resourceSpans"].([]interface{}) {
+		for _, ss := range rs.(map[string]interface{})["scopeSpans"].([]interface{}) {
+			for _, s := range ss.(map[string]interface{})["spans"].([]interface{}) {
+				result = append(result, s.(map[string]interface{}))
+			}
+		}
+	}
+	return result
+}
+
+func otlpMetrics(body map[string]interface{}) map[string]float64 {
+	result := make(map[string]float64)
+	for _, rm := range body["resourceMetrics"].([]interface{}) {
+		for _, sm := range rm.(map[string]interface{})["scopeMetrics"].([]interface{}) {
+			for _, m := range sm.(map[string]interface{})["metrics"].([]interface{}) {
+				metric := m.(map[string]interface{})
+				sum := metric["sum"].(map[string]interface{})
+				for _, dp := range sum["dataPoints"].([]interface{}) {
+					result[metric["name"].(string)] += dp.(map[string]interface{})["asDouble"].(float64)
+				}
+			}
+		}
+	}
+	return result
+}
+
+func TestNewOTLPExporter_disabled(t *testing.T) {
+	defer setOTLPEnv(t, nil)()
+
+	e := NewOTLPExporter()
+	if e != nil {
+		t.Fatalf("should be disabled: %#v", e)
+	}
+
+	// A nil exporter does nothing
+	span := e.StartSpan("foo", nil, nil)
+	span.SetAttribute("foo", "bar")
+	span.End(nil)
+	e.Count("foo", "1", 1, nil)
+	if err := e.Finalize("build", 0, nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	c := newTestOTLPCollector(t)
+	defer c.Close()
+	defer setOTLPEnv(t, map[string]string{
+		"OTEL_EXPORTER_OTLP_ENDPOINT": c.URL + "/",
+		"OTEL_EXPORTER_OTLP_HEADERS":  "Authorization=Bearer secret, X-Team=images",
+		"OTEL_SERVICE_NAME":           "images",
+		"TRACEPARENT":                 "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	})()
+
+	e := NewOTLPExporter()
+	if e == nil {
+		t.Fatal("should be enabled")
+	}
+
+	build := e.StartSpan("build foo", nil, map[string]string{"packer.build.name": "foo"})
+	step := e.StartSpan("step StepCreate", build, nil)
+	step.End(errors.New("boom"))
+	build.End(nil)
+	e.Count("packer.download.bytes", "By", 100, map[string]string{"packer.download.scheme": "http"})
+	e.Count("packer.download.bytes", "By", 50, map[string]string{"packer.download.scheme": "http"})
+	e.Count("packer.download.bytes", "By", 25, map[string]string{"packer.download.scheme": "file"})
+
+	if err := e.Finalize("build", 1, nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if h := c.headers["/v1/traces"].Get("Authorization"); h != "Bearer secret" {
+		t.Fatalf("bad header: %q", h)
+	}
+	if h := c.headers["/v1/metrics"].Get("X-Team"); h != "images" {
+		t.Fatalf("bad header: %q", h)
+	}
+
+	spans := otlpSpans(c.requests["/v1/traces"])
+	if len(spans) != 3 {
+		t.Fatalf("bad spans: %#v", spans)
+	}
+	byName := make(map[string]map[string]interface{})
+	for _, s := range spans {
+		if s["traceId"] != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Fatalf("bad trace: %#v", s)
+		}
+		byName[s["name"].(string)] = s
+	}
+
+	root := byName["packer build"]
+	if root == nil || root["parentSpanId"] != "00f067aa0ba902b7" {
+		t.Fatalf("bad root: %#v", root)
+	}
+	if status := root["status"].(map[string]interface{}); status["code"].(float64) != 2 {
+		t.Fatalf("bad root status: %#v", status)
+	}
+	if byName["build foo"]["parentSpanId"] != root["spanId"] {
+		t.Fatalf("bad build parent: %#v", byName["build foo"])
+	}
+	stepSpan := byName["step StepCreate"]
+	if stepSpan["parentSpanId"] != byName["build foo"]["spanId"] {
+		t.Fatalf("bad step parent: %#v", stepSpan)
+	}
+	if status := stepSpan["status"].(map[string]interface{}); status["message"] != "boom" {
+		t.Fatalf("bad step status: %#v", status)
+	}
+
+	resource := c.requests["/v1/traces"]["resourceSpans"].([]interface{})[0].(map[string]interface{})["resource"]
+	raw, _ := json.Marshal(resource)
+	if !strings.Contains(string(raw), `{"key":"service.name","value":{"stringValue":"images"}}`) {
+		t.Fatalf("bad resource: %s", raw)
+	}
+
+	metrics := otlpMetrics(c.requests["/v1/metrics"])
+	if metrics["packer.download.bytes"] != 175 {
+		t.Fatalf("bad metrics: %#v", metrics)
+	}
+}
+
+func TestOTLPExporter_signalEndpoints(t *testing.T) {
+	c := newTestOTLPCollector(t)
+	defer c.Close()
+	defer setOTLPEnv(t, map[string]string{
+		"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT": c.URL + "/traces",
+	})()
+
+	e := NewOTLPExporter()
+	if err := e.Finalize("validate", 0, nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if _, ok := c.requests["/traces"]; !ok {
+		t.Fatalf("no traces: %#v", c.requests)
+	}
+	if len(c.requests) != 1 {
+		t.Fatalf("metrics should not be sent: %#v", c.requests)
+	}
+}
+
+func TestOTLPExporter_error(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+	defer setOTLPEnv(t, map[string]string{
+		"OTEL_EXPORTER_OTLP_ENDPOINT": ts.URL,
+	})()
+
+	e := NewOTLPExporter()
+	if err := e.Finalize("build", 0, nil); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+func TestParseTraceparent(t *testing.T) {
+	cases := []struct {
+		Input string
+		Trace string
+		Span  string
+		Ok    bool
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7", true},
+		{"", "", "", false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-01", "", "", false},
+		{"00-zzf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", "", "", false},
+	}
+
+	for _, tc := range cases {
+		trace, span, ok := parseTraceparent(tc.Input)
+		if trace != tc.Trace || span != tc.Span || ok != tc.Ok {
+			t.Fatalf("%q: bad: %q %q %t", tc.Input, trace, span, ok)
+		}
+	}
+}
+
+func TestCoreBuild_tracing(t *testing.T) {
+	c := newTestOTLPCollector(t)
+	defer c.Close()
+	defer setOTLPEnv(t, map[string]string{
+		"OTEL_EXPORTER_OTLP_ENDPOINT": c.URL,
+	})()
+
+	Tracing = NewOTLPExporter()
+	defer func() { Tracing = nil }()
+
+	build := testBuild()
+	if _, err := build.Prepare(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := build.Run(TestUi(t), nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := Tracing.Finalize("build", 0, nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	names := make(map[string]bool)
+	for _, s := range otlpSpans(c.requests["/v1/traces"]) {
+		names[s["name"].(string)] = true
+	}
+	for _, n := range []string{"packer build", "build test", "builder foo", "post-processor testPP"} {
+		if !names[n] {
+			t.Fatalf("missing span %q: %#v", n, names)
+		}
+	}
+}
+
+func TestDurationsUi_tracing(t *testing.T) {
+	c := newTestOTLPCollector(t)
+	defer c.Close()
+	defer setOTLPEnv(t, map[string]string{
+		"OTEL_EXPORTER_OTLP_ENDPOINT": c.URL,
+	})()
+
+	Tracing = NewOTLPExporter()
+	defer func() { Tracing = nil }()
+
+	ui := &durationsUi{Ui: TestUi(t)}
+	ui.Machine("step-duration", "StepCreateVM", "1.500")
+	ui.Machine("download-bytes", "https", "1024")
+	ui.Machine("retry", "download", "https://mirror")
+	ui.Machine("retry", "step", "StepCreateVM")
+	if err := Tracing.Finalize("build", 0, nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	metrics := otlpMetrics(c.requests["/v1/metrics"])
+	expected := map[string]float64{
+		"packer.step.count":     1,
+		"packer.step.duration":  1.5,
+		"packer.download.bytes": 1024,
+		"packer.retries":        2,
+	}
+	for k, v := range expected {
+		if metrics[k] != v {
+			t.Fatalf("bad %s: %#v", k, metrics)
+		}
+	}
+}
+
+func TestTracedCommunicator(t *testing.T) {
+	c := newTestOTLPCollector(t)
+	defer c.Close()
+	defer setOTLPEnv(t, map[string]string{
+		"OTEL_EXPORTER_OTLP_ENDPOINT": c.URL,
+	})()
+
+	Tracing = NewOTLPExporter()
+	defer func() { Tracing = nil }()
+
+	comm := &tracedCommunicator{
+		Communicator: &MockCommunicator{StartExitStatus: 2},
+		span:         Tracing.StartSpan("provisioner shell", nil, nil),
+	}
+	cmd := &RemoteCmd{Command: "false"}
+	if err := comm.Start(cmd); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	cmd.Wait()
+	if err := comm.Upload("/tmp/foo", strings.NewReader("hello"), nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := Tracing.Finalize("build", 0, nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	metrics := otlpMetrics(c.requests["/v1/metrics"])
+	if metrics["packer.communicator.commands"] != 1 || metrics["packer.communicator.upload.bytes"] != 5 {
+		t.Fatalf("bad metrics: %#v", metrics)
+	}
+
+	names := make(map[string]bool)
+	for _, s := range otlpSpans(c.requests["/v1/traces"]) {
+		names[s["name"].(string)] = true
+	}
+	if !names["communicator.command"] || !names["communicator.upload"] {
+		t.Fatalf("bad spans: %#v", names)
+	}
+}
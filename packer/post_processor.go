@@ -16,3 +16,12 @@ type PostProcessor interface {
 	// is to true, then the previous artifact is forcibly kept.
 	PostProcess(Ui, Artifact) (a Artifact, keep bool, err error)
 }
+
+// A CancellablePostProcessor is a PostProcessor that can be cancelled while
+// it runs, as when Packer is interrupted. Cancel should make PostProcess
+// clean up what it started and return with an error soon.
+type CancellablePostProcessor interface {
+	PostProcessor
+
+	Cancel()
+}
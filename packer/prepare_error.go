@@ -0,0 +1,58 @@
+package packer
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/packer/template"
+)
+
+// PrepareError is an error preparing a builder, provisioner, or
+// post-processor of a build, with where the component is in the template.
+type PrepareError struct {
+	// Pos is the position of the component, it is empty when the template
+	// didn't come from a file.
+	Pos template.Pos
+
+	// Type is the type of the component, like "shell".
+	Type string
+
+	Err error
+}
+
+func (e *PrepareError) Error() string {
+	if pos := e.Pos.String(); pos != "" {
+		return fmt.Sprintf("%s: %s", pos, e.Summary())
+	}
+	return e.Summary()
+}
+
+// Summary is the error with the component it is about, but not its
+// position in the file.
+func (e *PrepareError) Summary() string {
+	if e.Pos.Path == "" {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%s (%s): %s", e.Pos.Path, e.Type, e.Err)
+}
+
+// newPrepareError wraps the errors of the component at pos in
+// PrepareErrors. Each of multiple errors is wrapped on its own, in a
+// MultiError.
+func newPrepareError(pos template.Pos, typ string, err error) error {
+	var errs []error
+	switch err := err.(type) {
+	case *MultiError:
+		errs = err.Errors
+	case *multierror.Error:
+		errs = err.Errors
+	default:
+		return &PrepareError{Pos: pos, Type: typ, Err: err}
+	}
+
+	result := new(MultiError)
+	for _, e := range errs {
+		result.Errors = append(result.Errors, &PrepareError{Pos: pos, Type: typ, Err: e})
+	}
+	return result
+}
@@ -3,6 +3,7 @@ package packer
 import (
 	"fmt"
 	"log"
+	"strconv"
 	"sync"
 	"time"
 )
@@ -40,11 +41,16 @@ type ProvisionHook struct {
 	// be prepared (by calling Prepare) at some earlier stage.
 	Provisioners []*HookedProvisioner
 
+	// span is the span of the build the provisioners run in.
+	span *OTLPSpan
+
 	lock               sync.Mutex
 	runningProvisioner Provisioner
 }
 
-// Runs the provisioners in order.
+// Runs the provisioners in order. If data is an integer, provisioning
+// starts from the provisioner with that index, to resume it after a
+// failure.
 func (h *ProvisionHook) Run(name string, ui Ui, comm Communicator, data interface{}) error {
 	// Shortcut
 	if len(h.Provisioners) == 0 {
@@ -65,17 +71,41 @@ func (h *ProvisionHook) Run(name string, ui Ui, comm Communicator, data interfac
 		h.runningProvisioner = nil
 	}()
 
-	for _, p := range h.Provisioners {
+	start := provisionStart(data)
+	if start >= len(h.Provisioners) {
+		return fmt.Errorf("Can't start from provisioner %d, there are %d", start+1, len(h.Provisioners))
+	}
+	if start > 0 {
+		ui.Say(fmt.Sprintf("Resuming provisioning from provisioner %d...", start+1))
+	}
+
+	for i, p := range h.Provisioners[start:] {
 		h.lock.Lock()
 		h.runningProvisioner = p.Provisioner
 		h.lock.Unlock()
 
 		ts := CheckpointReporter.AddSpan(p.TypeName, "provisioner", p.Config)
+		span := Tracing.StartSpan("provisioner "+p.TypeName, h.span, map[string]string{
+			"packer.provisioner.type":  p.TypeName,
+			"packer.provisioner.index": strconv.Itoa(start + i + 1),
+		})
+		provisionerComm := comm
+		if span != nil {
+			provisionerComm = &tracedCommunicator{Communicator: comm, span: span}
+		}
 
-		err := p.Provisioner.Provision(ui, comm)
+		provisionerStart := time.Now()
+		err := p.Provisioner.Provision(ui, provisionerComm)
+		span.End(err)
+		ui.Machine("provisioner-duration", strconv.Itoa(start+i+1), p.TypeName,
+			strconv.FormatFloat(time.Since(provisionerStart).Seconds(), 'f', 3, 64))
 
 		ts.End(err)
 		if err != nil {
+			if len(h.Provisioners) > 1 {
+				ui.Error(fmt.Sprintf("Provisioner %d of %d (%s) failed",
+					start+i+1, len(h.Provisioners), p.TypeName))
+			}
 			return err
 		}
 	}
@@ -83,6 +113,21 @@ func (h *ProvisionHook) Run(name string, ui Ui, comm Communicator, data interfac
 	return nil
 }
 
+// provisionStart returns the index of the first provisioner to run from
+// the data given to the hook. Over RPC the int can come back as another
+// integer type.
+func provisionStart(data interface{}) int {
+	switch v := data.(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case uint64:
+		return int(v)
+	}
+	return 0
+}
+
 // Cancels the provisioners that are still running.
 func (h *ProvisionHook) Cancel() {
 	h.lock.Lock()
@@ -170,6 +215,55 @@ func (p *PausedProvisioner) provision(result chan<- error, ui Ui, comm Communica
 	result <- p.Provisioner.Provision(ui, comm)
 }
 
+// ExecProvisioner is a Provisioner implementation that runs the commands of
+// the provisioner as the user, in the working directory, and with the
+// environment variables given, for the communicators that support them.
+type ExecProvisioner struct {
+	User        string
+	Workdir     string
+	Env         map[string]string
+	Provisioner Provisioner
+}
+
+func (p *ExecProvisioner) Prepare(raws ...interface{}) error {
+	return p.Provisioner.Prepare(raws...)
+}
+
+func (p *ExecProvisioner) Provision(ui Ui, comm Communicator) error {
+	return p.Provisioner.Provision(ui, &execCommunicator{comm, p})
+}
+
+func (p *ExecProvisioner) Cancel() {
+	p.Provisioner.Cancel()
+}
+
+// execCommunicator sets the exec settings of the provisioner on the
+// commands that don't have their own.
+type execCommunicator struct {
+	Communicator
+	p *ExecProvisioner
+}
+
+func (c *execCommunicator) Start(cmd *RemoteCmd) error {
+	if cmd.User == "" {
+		cmd.User = c.p.User
+	}
+	if cmd.Workdir == "" {
+		cmd.Workdir = c.p.Workdir
+	}
+	if len(c.p.Env) > 0 {
+		env := make(map[string]string, len(c.p.Env)+len(cmd.Env))
+		for k, v := range c.p.Env {
+			env[k] = v
+		}
+		for k, v := range cmd.Env {
+			env[k] = v
+		}
+		cmd.Env = env
+	}
+	return c.Communicator.Start(cmd)
+}
+
 // DebuggedProvisioner is a Provisioner implementation that waits until a key
 // press before the provisioner is actually run.
 type DebuggedProvisioner struct {
@@ -4,6 +4,7 @@ package packer
 // used for tests.
 type MockProvisioner struct {
 	ProvFunc func() error
+	PrepErr  error
 
 	PrepCalled       bool
 	PrepConfigs      []interface{}
@@ -16,7 +17,7 @@ type MockProvisioner struct {
 func (t *MockProvisioner) Prepare(configs ...interface{}) error {
 	t.PrepCalled = true
 	t.PrepConfigs = configs
-	return nil
+	return t.PrepErr
 }
 
 func (t *MockProvisioner) Provision(ui Ui, comm Communicator) error {
@@ -1,6 +1,7 @@
 package packer
 
 import (
+	"reflect"
 	"sync"
 	"testing"
 	"time"
@@ -40,6 +41,37 @@ func TestProvisionHook(t *testing.T) {
 	}
 }
 
+func TestProvisionHook_start(t *testing.T) {
+	pA := &MockProvisioner{}
+	pB := &MockProvisioner{}
+
+	ui := testUi()
+	var comm Communicator = new(MockCommunicator)
+
+	hook := &ProvisionHook{
+		Provisioners: []*HookedProvisioner{
+			{pA, nil, ""},
+			{pB, nil, ""},
+		},
+	}
+
+	if err := hook.Run("foo", ui, comm, int64(1)); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if pA.ProvCalled {
+		t.Error("provision should not be called on pA")
+	}
+
+	if !pB.ProvCalled {
+		t.Error("provision should be called on pB")
+	}
+
+	if err := hook.Run("foo", ui, comm, 2); err == nil {
+		t.Fatal("should error when starting past the last provisioner")
+	}
+}
+
 func TestProvisionHook_nilComm(t *testing.T) {
 	pA := &MockProvisioner{}
 	pB := &MockProvisioner{}
@@ -85,7 +117,7 @@ func TestProvisionHook_cancel(t *testing.T) {
 
 	finished := make(chan struct{})
 	go func() {
-		hook.Run("foo", nil, new(MockCommunicator), nil)
+		hook.Run("foo", testUi(), new(MockCommunicator), nil)
 		close(finished)
 	}()
 
@@ -261,3 +293,44 @@ func TestDebuggedProvisionerCancel(t *testing.T) {
 		t.Fatal("cancel should be called")
 	}
 }
+
+func TestExecProvisioner_impl(t *testing.T) {
+	var _ Provisioner = new(ExecProvisioner)
+}
+
+func TestExecProvisionerProvision(t *testing.T) {
+	mock := new(MockProvisioner)
+	prov := &ExecProvisioner{
+		User:        "app",
+		Workdir:     "/srv",
+		Env:         map[string]string{"A": "1", "B": "2"},
+		Provisioner: mock,
+	}
+
+	comm := new(MockCommunicator)
+	if err := prov.Provision(testUi(), comm); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !mock.ProvCalled {
+		t.Fatal("prov should be called")
+	}
+
+	// The settings of the command win over the ones of the provisioner
+	cmd := &RemoteCmd{
+		Command: "foo",
+		Workdir: "/tmp",
+		Env:     map[string]string{"B": "3"},
+	}
+	if err := mock.ProvCommunicator.Start(cmd); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if comm.StartCmd != cmd {
+		t.Fatal("command should be started")
+	}
+	if cmd.User != "app" || cmd.Workdir != "/tmp" {
+		t.Fatalf("bad: %#v", cmd)
+	}
+	if !reflect.DeepEqual(cmd.Env, map[string]string{"A": "1", "B": "3"}) {
+		t.Fatalf("bad: %#v", cmd.Env)
+	}
+}
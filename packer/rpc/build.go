@@ -74,18 +74,48 @@ func (b *build) SetDebug(val bool) {
 	}
 }
 
+func (b *build) SetDebugSkip(val []string) {
+	if err := b.client.Call("Build.SetDebugSkip", val, new(interface{})); err != nil {
+		panic(err)
+	}
+}
+
+func (b *build) SetDebugTrace(val string) {
+	if err := b.client.Call("Build.SetDebugTrace", val, new(interface{})); err != nil {
+		panic(err)
+	}
+}
+
 func (b *build) SetForce(val bool) {
 	if err := b.client.Call("Build.SetForce", val, new(interface{})); err != nil {
 		panic(err)
 	}
 }
 
+func (b *build) SetForceDownload(val bool) {
+	if err := b.client.Call("Build.SetForceDownload", val, new(interface{})); err != nil {
+		panic(err)
+	}
+}
+
+func (b *build) SetOffline(val bool) {
+	if err := b.client.Call("Build.SetOffline", val, new(interface{})); err != nil {
+		panic(err)
+	}
+}
+
 func (b *build) SetOnError(val string) {
 	if err := b.client.Call("Build.SetOnError", val, new(interface{})); err != nil {
 		panic(err)
 	}
 }
 
+func (b *build) SetParallelPostProcessors(val bool) {
+	if err := b.client.Call("Build.SetParallelPostProcessors", val, new(interface{})); err != nil {
+		panic(err)
+	}
+}
+
 func (b *build) Cancel() {
 	if err := b.client.Call("Build.Cancel", new(interface{}), new(interface{})); err != nil {
 		panic(err)
@@ -136,16 +166,41 @@ func (b *BuildServer) SetDebug(val *bool, reply *interface{}) error {
 	return nil
 }
 
+func (b *BuildServer) SetDebugSkip(val *[]string, reply *interface{}) error {
+	b.build.SetDebugSkip(*val)
+	return nil
+}
+
+func (b *BuildServer) SetDebugTrace(val *string, reply *interface{}) error {
+	b.build.SetDebugTrace(*val)
+	return nil
+}
+
 func (b *BuildServer) SetForce(val *bool, reply *interface{}) error {
 	b.build.SetForce(*val)
 	return nil
 }
 
+func (b *BuildServer) SetForceDownload(val *bool, reply *interface{}) error {
+	b.build.SetForceDownload(*val)
+	return nil
+}
+
+func (b *BuildServer) SetOffline(val *bool, reply *interface{}) error {
+	b.build.SetOffline(*val)
+	return nil
+}
+
 func (b *BuildServer) SetOnError(val *string, reply *interface{}) error {
 	b.build.SetOnError(*val)
 	return nil
 }
 
+func (b *BuildServer) SetParallelPostProcessors(val *bool, reply *interface{}) error {
+	b.build.SetParallelPostProcessors(*val)
+	return nil
+}
+
 func (b *BuildServer) Cancel(args *interface{}, reply *interface{}) error {
 	b.build.Cancel()
 	return nil
@@ -11,16 +11,21 @@ import (
 var testBuildArtifact = &packer.MockArtifact{}
 
 type testBuild struct {
-	nameCalled       bool
-	prepareCalled    bool
-	prepareWarnings  []string
-	runCalled        bool
-	runCache         packer.Cache
-	runUi            packer.Ui
-	setDebugCalled   bool
-	setForceCalled   bool
-	setOnErrorCalled bool
-	cancelCalled     bool
+	nameCalled                      bool
+	prepareCalled                   bool
+	prepareWarnings                 []string
+	runCalled                       bool
+	runCache                        packer.Cache
+	runUi                           packer.Ui
+	setDebugCalled                  bool
+	setDebugSkip                    []string
+	setDebugTrace                   string
+	setForceCalled                  bool
+	setForceDownloadCalled          bool
+	setOfflineCalled                bool
+	setOnErrorCalled                bool
+	setParallelPostProcessorsCalled bool
+	cancelCalled                    bool
 
 	errRunResult bool
 }
@@ -51,14 +56,34 @@ func (b *testBuild) SetDebug(bool) {
 	b.setDebugCalled = true
 }
 
+func (b *testBuild) SetDebugSkip(val []string) {
+	b.setDebugSkip = val
+}
+
+func (b *testBuild) SetDebugTrace(val string) {
+	b.setDebugTrace = val
+}
+
 func (b *testBuild) SetForce(bool) {
 	b.setForceCalled = true
 }
 
+func (b *testBuild) SetForceDownload(bool) {
+	b.setForceDownloadCalled = true
+}
+
+func (b *testBuild) SetOffline(bool) {
+	b.setOfflineCalled = true
+}
+
 func (b *testBuild) SetOnError(string) {
 	b.setOnErrorCalled = true
 }
 
+func (b *testBuild) SetParallelPostProcessors(bool) {
+	b.setParallelPostProcessorsCalled = true
+}
+
 func (b *testBuild) Cancel() {
 	b.cancelCalled = true
 }
@@ -116,18 +141,48 @@ func TestBuild(t *testing.T) {
 		t.Fatal("should be called")
 	}
 
+	// Test SetDebugSkip
+	bClient.SetDebugSkip([]string{"StepShutdown"})
+	if !reflect.DeepEqual(b.setDebugSkip, []string{"StepShutdown"}) {
+		t.Fatalf("bad: %#v", b.setDebugSkip)
+	}
+
+	// Test SetDebugTrace
+	bClient.SetDebugTrace("trace.json")
+	if b.setDebugTrace != "trace.json" {
+		t.Fatalf("bad: %#v", b.setDebugTrace)
+	}
+
 	// Test SetForce
 	bClient.SetForce(true)
 	if !b.setForceCalled {
 		t.Fatal("should be called")
 	}
 
+	// Test SetForceDownload
+	bClient.SetForceDownload(true)
+	if !b.setForceDownloadCalled {
+		t.Fatal("should be called")
+	}
+
+	// Test SetOffline
+	bClient.SetOffline(true)
+	if !b.setOfflineCalled {
+		t.Fatal("should be called")
+	}
+
 	// Test SetOnError
 	bClient.SetOnError("ask")
 	if !b.setOnErrorCalled {
 		t.Fatal("should be called")
 	}
 
+	// Test SetParallelPostProcessors
+	bClient.SetParallelPostProcessors(true)
+	if !b.setParallelPostProcessorsCalled {
+		t.Fatal("should be called")
+	}
+
 	// Test Cancel
 	bClient.Cancel()
 	if !b.cancelCalled {
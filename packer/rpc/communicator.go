@@ -31,6 +31,9 @@ type CommandFinished struct {
 
 type CommunicatorStartArgs struct {
 	Command          string
+	User             string
+	Workdir          string
+	Env              map[string]string
 	StdinStreamId    uint32
 	StdoutStreamId   uint32
 	StderrStreamId   uint32
@@ -67,6 +70,9 @@ func Communicator(client *rpc.Client) *communicator {
 func (c *communicator) Start(cmd *packer.RemoteCmd) (err error) {
 	var args CommunicatorStartArgs
 	args.Command = cmd.Command
+	args.User = cmd.User
+	args.Workdir = cmd.Workdir
+	args.Env = cmd.Env
 
 	var wg sync.WaitGroup
 
@@ -205,6 +211,9 @@ func (c *CommunicatorServer) Start(args *CommunicatorStartArgs, reply *interface
 	// to the remote side.
 	var cmd packer.RemoteCmd
 	cmd.Command = args.Command
+	cmd.User = args.User
+	cmd.Workdir = args.Workdir
+	cmd.Env = args.Env
 
 	// Create a channel to signal we're done so that we can close
 	// our stdin/stdout/stderr streams
@@ -27,6 +27,9 @@ func TestCommunicatorRPC(t *testing.T) {
 
 	var cmd packer.RemoteCmd
 	cmd.Command = "foo"
+	cmd.User = "app"
+	cmd.Workdir = "/srv"
+	cmd.Env = map[string]string{"FOO": "bar"}
 	cmd.Stdin = stdin_r
 	cmd.Stdout = stdout_w
 	cmd.Stderr = stderr_w
@@ -77,6 +80,11 @@ func TestCommunicatorRPC(t *testing.T) {
 		t.Fatalf("bad exit: %d", cmd.ExitStatus)
 	}
 
+	// The exec settings make it to the other side
+	if c.StartCmd.User != "app" || c.StartCmd.Workdir != "/srv" || c.StartCmd.Env["FOO"] != "bar" {
+		t.Fatalf("bad: %#v", c.StartCmd)
+	}
+
 	// Test that we can upload things
 	uploadR, uploadW := io.Pipe()
 	go func() {
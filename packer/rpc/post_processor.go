@@ -1,6 +1,7 @@
 package rpc
 
 import (
+	"log"
 	"net/rpc"
 
 	"github.com/hashicorp/packer/packer"
@@ -67,6 +68,13 @@ func (p *postProcessor) PostProcess(ui packer.Ui, a packer.Artifact) (packer.Art
 	return client.Artifact(), response.Keep, nil
 }
 
+func (p *postProcessor) Cancel() {
+	err := p.client.Call("PostProcessor.Cancel", new(interface{}), new(interface{}))
+	if err != nil {
+		log.Printf("PostProcessor.Cancel err: %s", err)
+	}
+}
+
 func (p *PostProcessorServer) Configure(args *PostProcessorConfigureArgs, reply *interface{}) error {
 	err := p.p.Configure(args.Configs...)
 	return err
@@ -96,3 +104,10 @@ func (p *PostProcessorServer) PostProcess(streamId uint32, reply *PostProcessorP
 
 	return nil
 }
+
+func (p *PostProcessorServer) Cancel(args *interface{}, reply *interface{}) error {
+	if cp, ok := p.p.(packer.CancellablePostProcessor); ok {
+		cp.Cancel()
+	}
+	return nil
+}
@@ -15,7 +15,8 @@ type Ui struct {
 	endpoint string
 }
 
-var _ packer.Ui = new(Ui)
+var _ packer.LevelUi = new(Ui)
+var _ packer.OutputUi = new(Ui)
 
 // UiServer wraps a packer.Ui implementation and makes it exportable
 // as part of a Golang RPC server.
@@ -24,6 +25,18 @@ type UiServer struct {
 	register func(name string, rcvr interface{}) error
 }
 
+// The arguments sent to Ui.Log
+type UiLogArgs struct {
+	Level   packer.UiLevel
+	Message string
+}
+
+// The arguments sent to Ui.Output
+type UiOutputArgs struct {
+	Stream string
+	Line   string
+}
+
 // The arguments sent to Ui.Machine
 type UiMachineArgs struct {
 	Category string
@@ -41,6 +54,35 @@ func (u *Ui) Error(message string) {
 	}
 }
 
+func (u *Ui) Log(level packer.UiLevel, message string) {
+	rpcArgs := &UiLogArgs{
+		Level:   level,
+		Message: message,
+	}
+
+	if err := u.client.Call("Ui.Log", rpcArgs, new(interface{})); err != nil {
+		log.Printf("Error in Ui.Log RPC call: %s", err)
+
+		// The core might be older than the plugin and not have Ui.Log;
+		// hide the method so UiLog says it the other way.
+		packer.UiLog(struct{ packer.Ui }{u}, level, message)
+	}
+}
+
+func (u *Ui) Output(stream, line string) {
+	rpcArgs := &UiOutputArgs{
+		Stream: stream,
+		Line:   line,
+	}
+
+	if err := u.client.Call("Ui.Output", rpcArgs, new(interface{})); err != nil {
+		log.Printf("Error in Ui.Output RPC call: %s", err)
+
+		// Like Ui.Log, the core might not have Ui.Output.
+		packer.UiOutput(struct{ packer.Ui }{u}, stream, line)
+	}
+}
+
 func (u *Ui) Machine(t string, args ...string) {
 	rpcArgs := &UiMachineArgs{
 		Category: t,
@@ -101,6 +143,20 @@ func (u *UiServer) Error(message *string, reply *interface{}) error {
 	return nil
 }
 
+func (u *UiServer) Log(args *UiLogArgs, reply *interface{}) error {
+	packer.UiLog(u.ui, args.Level, args.Message)
+
+	*reply = nil
+	return nil
+}
+
+func (u *UiServer) Output(args *UiOutputArgs, reply *interface{}) error {
+	packer.UiOutput(u.ui, args.Stream, args.Line)
+
+	*reply = nil
+	return nil
+}
+
 func (u *UiServer) Machine(args *UiMachineArgs, reply *interface{}) error {
 	u.ui.Machine(args.Category, args.Args...)
 
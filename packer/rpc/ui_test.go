@@ -13,6 +13,12 @@ type testUi struct {
 	askQuery       string
 	errorCalled    bool
 	errorMessage   string
+	logCalled      bool
+	logLevel       packer.UiLevel
+	logMessage     string
+	outputCalled   bool
+	outputStream   string
+	outputLine     string
 	machineCalled  bool
 	machineType    string
 	machineArgs    []string
@@ -39,6 +45,18 @@ func (u *testUi) Error(message string) {
 	u.errorMessage = message
 }
 
+func (u *testUi) Log(level packer.UiLevel, message string) {
+	u.logCalled = true
+	u.logLevel = level
+	u.logMessage = message
+}
+
+func (u *testUi) Output(stream, line string) {
+	u.outputCalled = true
+	u.outputStream = stream
+	u.outputLine = line
+}
+
 func (u *testUi) Machine(t string, args ...string) {
 	u.machineCalled = true
 	u.machineType = t
@@ -109,6 +127,22 @@ func TestUiRPC(t *testing.T) {
 		t.Fatalf("bad: %#v", ui.errorMessage)
 	}
 
+	uiClient.(packer.LevelUi).Log(packer.UiLevelWarn, "message")
+	if !ui.logCalled {
+		t.Fatal("log should be called")
+	}
+	if ui.logLevel != packer.UiLevelWarn || ui.logMessage != "message" {
+		t.Fatalf("bad: %s %#v", ui.logLevel, ui.logMessage)
+	}
+
+	uiClient.(packer.OutputUi).Output(packer.UiStreamStderr, "line")
+	if !ui.outputCalled {
+		t.Fatal("output should be called")
+	}
+	if ui.outputStream != packer.UiStreamStderr || ui.outputLine != "line" {
+		t.Fatalf("bad: %s %#v", ui.outputStream, ui.outputLine)
+	}
+
 	uiClient.Message("message")
 	if ui.messageMessage != "message" {
 		t.Fatalf("bad: %#v", ui.errorMessage)
@@ -0,0 +1,212 @@
+package packer
+
+import (
+	"github.com/hashicorp/packer/sdk/packer"
+)
+
+// The SDK, the packages under sdk, has types of its own so that they don't
+// change with the core. The components of plugins built against it are
+// adapted to the types of the core here, and the values the core gives
+// them are adapted back to the ones of the SDK.
+
+// SDKBuilder adapts the builder of the SDK to the core.
+func SDKBuilder(b packer.Builder) Builder {
+	return &sdkBuilder{b}
+}
+
+// SDKProvisioner adapts the provisioner of the SDK to the core.
+func SDKProvisioner(p packer.Provisioner) Provisioner {
+	return &sdkProvisioner{p}
+}
+
+// SDKPostProcessor adapts the post-processor of the SDK to the core. It is
+// a CancellablePostProcessor when the post-processor can be cancelled.
+func SDKPostProcessor(p packer.PostProcessor) PostProcessor {
+	if _, ok := p.(packer.CancellablePostProcessor); ok {
+		return &sdkCancellablePostProcessor{sdkPostProcessor{p}}
+	}
+	return &sdkPostProcessor{p}
+}
+
+// SDKUi adapts the Ui of the SDK to the core.
+func SDKUi(ui packer.Ui) Ui {
+	switch ui := ui.(type) {
+	case nil:
+		return nil
+	case *toSDKUi:
+		return ui.Ui
+	}
+	return &sdkUi{ui}
+}
+
+// SDKCommunicator adapts the communicator of the SDK to the core.
+func SDKCommunicator(c packer.Communicator) Communicator {
+	switch c := c.(type) {
+	case nil:
+		return nil
+	case *toSDKCommunicator:
+		return c.Communicator
+	}
+	return &sdkCommunicator{c}
+}
+
+// SDKError returns the error of a component of the SDK, with its
+// MultiError turned into the one of the core.
+func SDKError(err error) error {
+	if multi, ok := err.(*packer.MultiError); ok && multi != nil {
+		return &MultiError{Errors: multi.Errors}
+	}
+	return err
+}
+
+func toSDKUiOf(ui Ui) packer.Ui {
+	switch ui := ui.(type) {
+	case nil:
+		return nil
+	case *sdkUi:
+		return ui.Ui
+	}
+	return &toSDKUi{ui}
+}
+
+func toSDKCommunicatorOf(c Communicator) packer.Communicator {
+	switch c := c.(type) {
+	case nil:
+		return nil
+	case *sdkCommunicator:
+		return c.Communicator
+	}
+	return &toSDKCommunicator{c}
+}
+
+type sdkBuilder struct {
+	packer.Builder
+}
+
+func (b *sdkBuilder) Prepare(raws ...interface{}) ([]string, error) {
+	warnings, err := b.Builder.Prepare(raws...)
+	return warnings, SDKError(err)
+}
+
+func (b *sdkBuilder) Run(ui Ui, hook Hook, cache Cache) (Artifact, error) {
+	artifact, err := b.Builder.Run(toSDKUiOf(ui), &toSDKHook{hook}, cache)
+	return artifact, SDKError(err)
+}
+
+type sdkProvisioner struct {
+	packer.Provisioner
+}
+
+func (p *sdkProvisioner) Prepare(raws ...interface{}) error {
+	return SDKError(p.Provisioner.Prepare(raws...))
+}
+
+func (p *sdkProvisioner) Provision(ui Ui, comm Communicator) error {
+	return SDKError(p.Provisioner.Provision(toSDKUiOf(ui), toSDKCommunicatorOf(comm)))
+}
+
+type sdkPostProcessor struct {
+	packer.PostProcessor
+}
+
+func (p *sdkPostProcessor) Configure(raws ...interface{}) error {
+	return SDKError(p.PostProcessor.Configure(raws...))
+}
+
+func (p *sdkPostProcessor) PostProcess(ui Ui, artifact Artifact) (Artifact, bool, error) {
+	result, keep, err := p.PostProcessor.PostProcess(toSDKUiOf(ui), artifact)
+	return result, keep, SDKError(err)
+}
+
+type sdkCancellablePostProcessor struct {
+	sdkPostProcessor
+}
+
+func (p *sdkCancellablePostProcessor) Cancel() {
+	p.PostProcessor.(packer.CancellablePostProcessor).Cancel()
+}
+
+// toSDKHook is the hook of the core given to the builders of the SDK.
+type toSDKHook struct {
+	Hook
+}
+
+func (h *toSDKHook) Run(name string, ui packer.Ui, comm packer.Communicator, data interface{}) error {
+	return h.Hook.Run(name, SDKUi(ui), SDKCommunicator(comm), data)
+}
+
+// sdkUi is the Ui of the SDK, as one of the core.
+type sdkUi struct {
+	packer.Ui
+}
+
+func (u *sdkUi) ProgressBar() ProgressBar {
+	return u.Ui.ProgressBar()
+}
+
+func (u *sdkUi) Output(stream, line string) {
+	packer.UiOutput(u.Ui, stream, line)
+}
+
+// toSDKUi is the Ui of the core, as one of the SDK.
+type toSDKUi struct {
+	Ui
+}
+
+func (u *toSDKUi) ProgressBar() packer.ProgressBar {
+	return u.Ui.ProgressBar()
+}
+
+func (u *toSDKUi) Output(stream, line string) {
+	UiOutput(u.Ui, stream, line)
+}
+
+// sdkCommunicator is the communicator of the SDK, as one of the core.
+type sdkCommunicator struct {
+	packer.Communicator
+}
+
+func (c *sdkCommunicator) Start(cmd *RemoteCmd) error {
+	remote := &packer.RemoteCmd{
+		Command: cmd.Command,
+		Stdin:   cmd.Stdin,
+		Stdout:  cmd.Stdout,
+		Stderr:  cmd.Stderr,
+		User:    cmd.User,
+		Workdir: cmd.Workdir,
+		Env:     cmd.Env,
+	}
+	if err := c.Communicator.Start(remote); err != nil {
+		return err
+	}
+	go func() {
+		remote.Wait()
+		cmd.SetExited(remote.ExitStatus)
+	}()
+	return nil
+}
+
+// toSDKCommunicator is the communicator of the core, as one of the SDK.
+type toSDKCommunicator struct {
+	Communicator
+}
+
+func (c *toSDKCommunicator) Start(cmd *packer.RemoteCmd) error {
+	remote := &RemoteCmd{
+		Command: cmd.Command,
+		Stdin:   cmd.Stdin,
+		Stdout:  cmd.Stdout,
+		Stderr:  cmd.Stderr,
+		User:    cmd.User,
+		Workdir: cmd.Workdir,
+		Env:     cmd.Env,
+	}
+	if err := c.Communicator.Start(remote); err != nil {
+		return err
+	}
+	go func() {
+		remote.Wait()
+		cmd.SetExited(remote.ExitStatus)
+	}()
+	return nil
+}
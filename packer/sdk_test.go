@@ -0,0 +1,101 @@
+package packer
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/packer/sdk/packer"
+)
+
+// testSDKBuilder is a builder written only against the SDK.
+type testSDKBuilder struct {
+	comm packer.Communicator
+}
+
+func (b *testSDKBuilder) Prepare(...interface{}) ([]string, error) {
+	return nil, packer.MultiErrorAppend(nil, errors.New("bad"))
+}
+
+func (b *testSDKBuilder) Run(ui packer.Ui, hook packer.Hook, cache packer.Cache) (packer.Artifact, error) {
+	return nil, hook.Run(packer.HookProvision, ui, b.comm, nil)
+}
+
+func (b *testSDKBuilder) Cancel() {}
+
+// testSDKProvisioner is a provisioner written only against the SDK.
+type testSDKProvisioner struct {
+	cmd *packer.RemoteCmd
+}
+
+func (p *testSDKProvisioner) Prepare(...interface{}) error { return nil }
+
+func (p *testSDKProvisioner) Provision(ui packer.Ui, comm packer.Communicator) error {
+	p.cmd = &packer.RemoteCmd{Command: "hostname", User: "root"}
+	return p.cmd.StartWithUi(comm, ui)
+}
+
+func (p *testSDKProvisioner) Cancel() {}
+
+func TestSDKBuilder(t *testing.T) {
+	comm := &MockCommunicator{}
+	builder := SDKBuilder(&testSDKBuilder{comm: toSDKCommunicatorOf(comm)})
+
+	_, err := builder.Prepare()
+	if multi, ok := err.(*MultiError); !ok || len(multi.Errors) != 1 {
+		t.Fatalf("should return a MultiError of the core: %#v", err)
+	}
+
+	hook := &MockHook{}
+	ui := TestUi(t)
+	if _, err := builder.Run(ui, hook, &FileCache{}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if hook.RunName != HookProvision {
+		t.Fatalf("bad hook: %s", hook.RunName)
+	}
+	if hook.RunUi != ui {
+		t.Fatalf("should give back the Ui of the core: %#v", hook.RunUi)
+	}
+	if hook.RunComm != comm {
+		t.Fatalf("should give back the communicator of the core: %#v", hook.RunComm)
+	}
+}
+
+func TestSDKProvisioner(t *testing.T) {
+	var stdout bytes.Buffer
+	ui := &BasicUi{Reader: new(bytes.Buffer), Writer: &stdout}
+	comm := &MockCommunicator{StartStdout: "web-1\n", StartExitStatus: 2}
+	p := &testSDKProvisioner{}
+
+	if err := SDKProvisioner(p).Provision(ui, comm); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if comm.StartCmd.Command != "hostname" || comm.StartCmd.User != "root" {
+		t.Fatalf("bad command: %#v", comm.StartCmd)
+	}
+	if !p.cmd.Exited || p.cmd.ExitStatus != 2 {
+		t.Fatalf("should have the exit status of the command: %#v", p.cmd)
+	}
+	if stdout.String() != "web-1\n" {
+		t.Fatalf("bad output: %q", stdout.String())
+	}
+}
+
+func TestSDKConfigKeys(t *testing.T) {
+	keys := map[string]string{
+		packer.BuildNameConfigKey:     BuildNameConfigKey,
+		packer.BuilderTypeConfigKey:   BuilderTypeConfigKey,
+		packer.DebugConfigKey:         DebugConfigKey,
+		packer.ForceConfigKey:         ForceConfigKey,
+		packer.OnErrorConfigKey:       OnErrorConfigKey,
+		packer.TemplatePathKey:        TemplatePathKey,
+		packer.UserVariablesConfigKey: UserVariablesConfigKey,
+		packer.HookProvision:          HookProvision,
+	}
+	for sdk, core := range keys {
+		if sdk != core {
+			t.Errorf("the SDK has %q instead of %q", sdk, core)
+		}
+	}
+}
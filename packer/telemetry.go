@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"sync"
 	"time"
 
 	checkpoint "github.com/hashicorp/go-checkpoint"
@@ -26,6 +27,7 @@ type PackerReport struct {
 
 type CheckpointTelemetry struct {
 	spans         []*TelemetrySpan
+	spansLock     sync.Mutex
 	signatureFile string
 	startTime     time.Time
 }
@@ -98,7 +100,9 @@ func (c *CheckpointTelemetry) AddSpan(name, pluginType string, options interface
 		StartTime: time.Now().UTC(),
 		Type:      pluginType,
 	}
+	c.spansLock.Lock()
 	c.spans = append(c.spans, ts)
+	c.spansLock.Unlock()
 	return ts
 }
 
@@ -19,6 +19,39 @@ import (
 
 type UiColor uint
 
+// The streams of the output of commands.
+const (
+	UiStreamStdout = "stdout"
+	UiStreamStderr = "stderr"
+)
+
+// OutputUi is a Ui that tells the streams of the output of commands apart.
+// It is optional so the Uis outside of Packer keep working; UiOutput falls
+// back to the methods of the Ui for the others.
+type OutputUi interface {
+	Ui
+
+	// Output says a line that a command, like the script of a provisioner,
+	// wrote to the stream, UiStreamStdout or UiStreamStderr.
+	Output(stream, line string)
+}
+
+// UiOutput says the line a command wrote to the stream with the Ui. The
+// Uis that aren't an OutputUi show stderr with Error and stdout with
+// Message.
+func UiOutput(ui Ui, stream, line string) {
+	if oui, ok := ui.(OutputUi); ok {
+		oui.Output(stream, line)
+		return
+	}
+
+	if stream == UiStreamStderr {
+		ui.Error(line)
+	} else {
+		ui.Message(line)
+	}
+}
+
 const (
 	UiColorRed     UiColor = 31
 	UiColorGreen           = 32
@@ -30,24 +63,30 @@ const (
 
 // The Ui interface handles all communication for Packer with the outside
 // world. This sort of control allows us to strictly control how output
-// is formatted and various levels of output.
+// is formatted and various levels of output. Say and Message are messages
+// of the info level and Error of the error level; the Uis that are a
+// LevelUi say messages of the other levels too, see UiLog.
 type Ui interface {
 	Ask(string) (string, error)
 	Say(string)
 	Message(string)
 	Error(string)
+
 	Machine(string, ...string)
 	ProgressBar() ProgressBar
 }
 
 type NoopUi struct{}
 
-var _ Ui = new(NoopUi)
+var _ LevelUi = new(NoopUi)
+var _ OutputUi = new(NoopUi)
 
 func (*NoopUi) Ask(string) (string, error) { return "", errors.New("this is a noop ui") }
 func (*NoopUi) Say(string)                 { return }
 func (*NoopUi) Message(string)             { return }
 func (*NoopUi) Error(string)               { return }
+func (*NoopUi) Log(UiLevel, string)        { return }
+func (*NoopUi) Output(string, string)      { return }
 func (*NoopUi) Machine(string, ...string)  { return }
 func (*NoopUi) ProgressBar() ProgressBar   { return new(NoopProgressBar) }
 
@@ -58,7 +97,8 @@ type ColoredUi struct {
 	Ui         Ui
 }
 
-var _ Ui = new(ColoredUi)
+var _ LevelUi = new(ColoredUi)
+var _ OutputUi = new(ColoredUi)
 
 // TargetedUI is a UI that wraps another UI implementation and modifies
 // the output to indicate a specific target. Specifically, all Say output
@@ -70,34 +110,40 @@ type TargetedUI struct {
 	Ui     Ui
 }
 
-var _ Ui = new(TargetedUI)
+var _ LevelUi = new(TargetedUI)
+var _ OutputUi = new(TargetedUI)
 
 // The BasicUI is a UI that reads and writes from a standard Go reader
 // and writer. It is safe to be called from multiple goroutines. Machine
-// readable output is simply logged for this UI.
+// readable output is simply logged for this UI. The messages below Level
+// are only logged.
 type BasicUi struct {
 	Reader      io.Reader
 	Writer      io.Writer
 	ErrorWriter io.Writer
+	Level       UiLevel
 	l           sync.Mutex
 	interrupted bool
 	scanner     *bufio.Scanner
 	StackableProgressBar
 }
 
-var _ Ui = new(BasicUi)
+var _ LevelUi = new(BasicUi)
+var _ OutputUi = new(BasicUi)
 
 func (bu *BasicUi) ProgressBar() ProgressBar {
 	return &bu.StackableProgressBar
 }
 
 // MachineReadableUi is a UI that only outputs machine-readable output
-// to the given Writer.
+// to the given Writer. The ui messages below Level are dropped.
 type MachineReadableUi struct {
 	Writer io.Writer
+	Level  UiLevel
 }
 
-var _ Ui = new(MachineReadableUi)
+var _ LevelUi = new(MachineReadableUi)
+var _ OutputUi = new(MachineReadableUi)
 
 func (u *ColoredUi) Ask(query string) (string, error) {
 	return u.Ui.Ask(u.colorize(query, u.Color, true))
@@ -120,6 +166,40 @@ func (u *ColoredUi) Error(message string) {
 	u.Ui.Error(u.colorize(message, color, true))
 }
 
+// Log colors errors like Error, warnings in yellow and debug and trace
+// messages like Message.
+func (u *ColoredUi) Log(level UiLevel, message string) {
+	switch level {
+	case UiLevelError:
+		color := u.ErrorColor
+		if color == 0 {
+			color = UiColorRed
+		}
+		message = u.colorize(message, color, true)
+	case UiLevelWarn:
+		message = u.colorize(message, UiColorYellow, true)
+	case UiLevelInfo:
+		message = u.colorize(message, u.Color, true)
+	default:
+		message = u.colorize(message, u.Color, false)
+	}
+
+	UiLog(u.Ui, level, message)
+}
+
+// Output colors stdout like Message and stderr like Error, not bold.
+func (u *ColoredUi) Output(stream, line string) {
+	color := u.Color
+	if stream == UiStreamStderr {
+		color = u.ErrorColor
+		if color == 0 {
+			color = UiColorRed
+		}
+	}
+
+	UiOutput(u.Ui, stream, u.colorize(line, color, false))
+}
+
 func (u *ColoredUi) Machine(t string, args ...string) {
 	// Don't colorize machine-readable output
 	u.Ui.Machine(t, args...)
@@ -177,6 +257,23 @@ func (u *TargetedUI) Error(message string) {
 	u.Ui.Error(u.prefixLines(true, message))
 }
 
+func (u *TargetedUI) Log(level UiLevel, message string) {
+	UiLog(u.Ui, level, u.prefixLines(level >= UiLevelInfo, message))
+}
+
+// Output is lined up like Message, with the lines of stderr tagged.
+func (u *TargetedUI) Output(stream, line string) {
+	if stream == UiStreamStderr {
+		lines := strings.Split(line, "\n")
+		for i := range lines {
+			lines[i] = "stderr: " + lines[i]
+		}
+		line = strings.Join(lines, "\n")
+	}
+
+	UiOutput(u.Ui, stream, u.prefixLines(false, line))
+}
+
 func (u *TargetedUI) Machine(t string, args ...string) {
 	// Prefix in the target, then pass through
 	u.Ui.Machine(fmt.Sprintf("%s,%s", u.Target, t), args...)
@@ -252,38 +349,60 @@ func (rw *BasicUi) Ask(query string) (string, error) {
 }
 
 func (rw *BasicUi) Say(message string) {
-	rw.l.Lock()
-	defer rw.l.Unlock()
-
-	log.Printf("ui: %s", message)
-	_, err := fmt.Fprint(rw.Writer, message+"\n")
-	if err != nil {
-		log.Printf("[ERR] Failed to write to UI: %s", err)
-	}
+	rw.Log(UiLevelInfo, message)
 }
 
 func (rw *BasicUi) Message(message string) {
+	rw.Log(UiLevelInfo, message)
+}
+
+func (rw *BasicUi) Error(message string) {
+	rw.Log(UiLevelError, message)
+}
+
+// Log writes the messages the Level shows, errors to the ErrorWriter. All
+// of them are logged.
+func (rw *BasicUi) Log(level UiLevel, message string) {
 	rw.l.Lock()
 	defer rw.l.Unlock()
 
-	log.Printf("ui: %s", message)
-	_, err := fmt.Fprint(rw.Writer, message+"\n")
+	switch level {
+	case UiLevelInfo:
+		log.Printf("ui: %s", message)
+	case UiLevelError:
+		log.Printf("ui error: %s", message)
+	default:
+		log.Printf("ui %s: %s", level, message)
+	}
+	if !rw.Level.Shows(level) {
+		return
+	}
+
+	writer := rw.Writer
+	if level == UiLevelError && rw.ErrorWriter != nil {
+		writer = rw.ErrorWriter
+	}
+	_, err := fmt.Fprint(writer, message+"\n")
 	if err != nil {
 		log.Printf("[ERR] Failed to write to UI: %s", err)
 	}
 }
 
-func (rw *BasicUi) Error(message string) {
+// Output writes stderr to the ErrorWriter, and stdout to the Writer.
+func (rw *BasicUi) Output(stream, line string) {
 	rw.l.Lock()
 	defer rw.l.Unlock()
 
-	writer := rw.ErrorWriter
-	if writer == nil {
-		writer = rw.Writer
+	log.Printf("ui %s: %s", stream, line)
+	if !rw.Level.Shows(UiLevelInfo) {
+		return
 	}
 
-	log.Printf("ui error: %s", message)
-	_, err := fmt.Fprint(writer, message+"\n")
+	writer := rw.Writer
+	if stream == UiStreamStderr && rw.ErrorWriter != nil {
+		writer = rw.ErrorWriter
+	}
+	_, err := fmt.Fprint(writer, line+"\n")
 	if err != nil {
 		log.Printf("[ERR] Failed to write to UI: %s", err)
 	}
@@ -298,17 +417,33 @@ func (u *MachineReadableUi) Ask(query string) (string, error) {
 }
 
 func (u *MachineReadableUi) Say(message string) {
-	u.Machine("ui", "say", message)
+	if u.Level.Shows(UiLevelInfo) {
+		u.Machine("ui", "say", message)
+	}
 }
 
 func (u *MachineReadableUi) Message(message string) {
-	u.Machine("ui", "message", message)
+	if u.Level.Shows(UiLevelInfo) {
+		u.Machine("ui", "message", message)
+	}
 }
 
 func (u *MachineReadableUi) Error(message string) {
 	u.Machine("ui", "error", message)
 }
 
+func (u *MachineReadableUi) Log(level UiLevel, message string) {
+	if u.Level.Shows(level) {
+		u.Machine("ui", level.String(), message)
+	}
+}
+
+func (u *MachineReadableUi) Output(stream, line string) {
+	if u.Level.Shows(UiLevelInfo) {
+		u.Machine("ui", stream, line)
+	}
+}
+
 func (u *MachineReadableUi) Machine(category string, args ...string) {
 	now := time.Now().UTC()
 
@@ -344,12 +479,13 @@ func (u *MachineReadableUi) ProgressBar() ProgressBar {
 }
 
 // TimestampedUi is a UI that wraps another UI implementation and prefixes
-// prefixes each message with an RFC3339 timestamp
+// each line of the messages with an RFC3339 timestamp
 type TimestampedUi struct {
 	Ui Ui
 }
 
-var _ Ui = new(TimestampedUi)
+var _ LevelUi = new(TimestampedUi)
+var _ OutputUi = new(TimestampedUi)
 
 func (u *TimestampedUi) Ask(query string) (string, error) {
 	return u.Ui.Ask(query)
@@ -367,12 +503,25 @@ func (u *TimestampedUi) Error(message string) {
 	u.Ui.Error(u.timestampLine(message))
 }
 
+func (u *TimestampedUi) Log(level UiLevel, message string) {
+	UiLog(u.Ui, level, u.timestampLine(message))
+}
+
+func (u *TimestampedUi) Output(stream, line string) {
+	UiOutput(u.Ui, stream, u.timestampLine(line))
+}
+
 func (u *TimestampedUi) Machine(message string, args ...string) {
 	u.Ui.Machine(message, args...)
 }
 
 func (u *TimestampedUi) ProgressBar() ProgressBar { return u.Ui.ProgressBar() }
 
-func (u *TimestampedUi) timestampLine(string string) string {
-	return fmt.Sprintf("%v: %v", time.Now().Format(time.RFC3339), string)
+func (u *TimestampedUi) timestampLine(message string) string {
+	now := time.Now().Format(time.RFC3339)
+	lines := strings.Split(message, "\n")
+	for i, line := range lines {
+		lines[i] = fmt.Sprintf("%v: %v", now, line)
+	}
+	return strings.Join(lines, "\n")
 }
@@ -0,0 +1,76 @@
+package packer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// UiLevel is how important a message of the Ui is. Say and Message are
+// info messages and Error is an error one; Log says the level explicitly,
+// so users can choose how much they see with -verbosity. The zero value,
+// trace, shows everything.
+type UiLevel uint
+
+const (
+	UiLevelTrace UiLevel = iota
+	UiLevelDebug
+	UiLevelInfo
+	UiLevelWarn
+	UiLevelError
+)
+
+var uiLevelNames = []string{"trace", "debug", "info", "warn", "error"}
+
+func (l UiLevel) String() string {
+	if int(l) < len(uiLevelNames) {
+		return uiLevelNames[l]
+	}
+	return fmt.Sprintf("UiLevel(%d)", uint(l))
+}
+
+// ParseUiLevel returns the level of the name, like "debug".
+func ParseUiLevel(name string) (UiLevel, error) {
+	for i, n := range uiLevelNames {
+		if strings.EqualFold(name, n) {
+			return UiLevel(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown level %q, must be one of: %s",
+		name, strings.Join(uiLevelNames, ", "))
+}
+
+// Shows says whether the messages of the level are shown with the level as
+// verbosity. Errors are always shown.
+func (l UiLevel) Shows(level UiLevel) bool {
+	return level >= l || level == UiLevelError
+}
+
+// LevelUi is a Ui that can say messages of any level. It is optional so
+// the Uis outside of Packer keep working; UiLog falls back to the methods
+// of the Ui for the others.
+type LevelUi interface {
+	Ui
+
+	// Log says a message of the level, so it can be filtered and shown
+	// like the level.
+	Log(UiLevel, string)
+}
+
+// UiLog says the message of the level with the Ui. The Uis that aren't a
+// LevelUi show errors with Error, warnings and info with Say, and debug and
+// trace messages with Message.
+func UiLog(ui Ui, level UiLevel, message string) {
+	if lui, ok := ui.(LevelUi); ok {
+		lui.Log(level, message)
+		return
+	}
+
+	switch {
+	case level >= UiLevelError:
+		ui.Error(message)
+	case level >= UiLevelInfo:
+		ui.Say(message)
+	default:
+		ui.Message(message)
+	}
+}
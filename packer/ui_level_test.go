@@ -0,0 +1,102 @@
+package packer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseUiLevel(t *testing.T) {
+	cases := map[string]UiLevel{
+		"trace": UiLevelTrace,
+		"debug": UiLevelDebug,
+		"INFO":  UiLevelInfo,
+		"warn":  UiLevelWarn,
+		"error": UiLevelError,
+	}
+	for name, expected := range cases {
+		level, err := ParseUiLevel(name)
+		if err != nil {
+			t.Fatalf("%s: err: %s", name, err)
+		}
+		if level != expected {
+			t.Fatalf("%s: bad: %s", name, level)
+		}
+	}
+
+	if _, err := ParseUiLevel("verbose"); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+func TestBasicUi_Level(t *testing.T) {
+	bufferUi := testUi()
+	bufferUi.Level = UiLevelWarn
+
+	bufferUi.Say("foo")
+	bufferUi.Message("foo")
+	bufferUi.Log(UiLevelDebug, "foo")
+	bufferUi.Log(UiLevelInfo, "foo")
+	if actual := readWriter(bufferUi); actual != "" {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	bufferUi.Log(UiLevelWarn, "bar")
+	if actual := readWriter(bufferUi); actual != "bar\n" {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	bufferUi.Error("baz")
+	bufferUi.Log(UiLevelError, "baz")
+	if actual := readErrorWriter(bufferUi); actual != "baz\nbaz\n" {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	bufferUi.Level = UiLevelTrace
+	bufferUi.Message("foo")
+	bufferUi.Log(UiLevelTrace, "foo")
+	if actual := readWriter(bufferUi); actual != "foo\nfoo\n" {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestMachineReadableUi_Level(t *testing.T) {
+	buf := new(bytes.Buffer)
+	ui := &MachineReadableUi{Writer: buf, Level: UiLevelError}
+
+	ui.Say("foo")
+	ui.Log(UiLevelWarn, "foo")
+	if buf.Len() != 0 {
+		t.Fatalf("bad: %s", buf.String())
+	}
+
+	ui.Log(UiLevelError, "bar")
+	data := strings.SplitN(buf.String(), ",", 2)[1]
+	if data != ",ui,error,bar\n" {
+		t.Fatalf("bad: %s", data)
+	}
+
+	buf.Reset()
+	ui.Level = UiLevelInfo
+	ui.Output(UiStreamStderr, "baz")
+	data = strings.SplitN(buf.String(), ",", 2)[1]
+	if data != ",ui,stderr,baz\n" {
+		t.Fatalf("bad: %s", data)
+	}
+}
+
+func TestUiLog_notLevelUi(t *testing.T) {
+	bufferUi := testUi()
+	ui := struct{ Ui }{bufferUi}
+
+	UiLog(ui, UiLevelDebug, "foo")
+	UiLog(ui, UiLevelWarn, "bar")
+	if actual := readWriter(bufferUi); actual != "foo\nbar\n" {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	UiLog(ui, UiLevelError, "baz")
+	if actual := readErrorWriter(bufferUi); actual != "baz\n" {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
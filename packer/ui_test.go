@@ -5,6 +5,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 )
 
 // This reads the output from the bytes.Buffer in our test object
@@ -126,6 +127,34 @@ func TestTargetedUI(t *testing.T) {
 		t.Fatalf("bad: %#v", actual)
 	}
 
+	targetedUi.Log(UiLevelWarn, "foo")
+	actual = readWriter(bufferUi)
+	expected = "==> foo: foo\n"
+	if actual != expected {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	targetedUi.Log(UiLevelDebug, "foo")
+	actual = readWriter(bufferUi)
+	expected = "    foo: foo\n"
+	if actual != expected {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	targetedUi.Output(UiStreamStdout, "foo")
+	actual = readWriter(bufferUi)
+	expected = "    foo: foo\n"
+	if actual != expected {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	targetedUi.Output(UiStreamStderr, "bar")
+	actual = readErrorWriter(bufferUi)
+	expected = "    foo: stderr: bar\n"
+	if actual != expected {
+		t.Fatalf("bad: %#v", actual)
+	}
+
 	targetedUi.Say("foo\nbar")
 	actual = readWriter(bufferUi)
 	expected = "==> foo: foo\n==> foo: bar\n"
@@ -134,6 +163,43 @@ func TestTargetedUI(t *testing.T) {
 	}
 }
 
+func TestUiOutput_notOutputUi(t *testing.T) {
+	bufferUi := testUi()
+	ui := struct{ Ui }{bufferUi}
+
+	UiOutput(ui, UiStreamStdout, "foo")
+	if actual := readWriter(bufferUi); actual != "foo\n" {
+		t.Fatalf("bad: %#v", actual)
+	}
+
+	UiOutput(ui, UiStreamStderr, "bar")
+	if actual := readErrorWriter(bufferUi); actual != "bar\n" {
+		t.Fatalf("bad: %#v", actual)
+	}
+}
+
+func TestTimestampedUi(t *testing.T) {
+	bufferUi := testUi()
+	timestampedUi := &TimestampedUi{
+		Ui: bufferUi,
+	}
+
+	timestampedUi.Say("foo\nbar")
+	lines := strings.Split(strings.TrimSuffix(readWriter(bufferUi), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("bad: %#v", lines)
+	}
+	for i, expected := range []string{"foo", "bar"} {
+		parts := strings.SplitN(lines[i], ": ", 2)
+		if len(parts) != 2 || parts[1] != expected {
+			t.Fatalf("bad: %#v", lines[i])
+		}
+		if _, err := time.Parse(time.RFC3339, parts[0]); err != nil {
+			t.Fatalf("bad timestamp %q: %s", parts[0], err)
+		}
+	}
+}
+
 func TestColoredUi_ImplUi(t *testing.T) {
 	var raw interface{}
 	raw = &ColoredUi{}
@@ -0,0 +1,82 @@
+package amazonamicopy
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/hashicorp/packer/packer"
+)
+
+const BuilderId = "packer.post-processor.amazon-ami-copy"
+
+// Artifact is the copies of the AMIs in the target accounts.
+type Artifact struct {
+	// A map of account IDs to maps of regions to AMI IDs.
+	Amis map[string]map[string]string
+
+	// connect returns an EC2 connection to a region of a target account.
+	connect func(account, region string) *ec2.EC2
+}
+
+func (*Artifact) BuilderId() string {
+	return BuilderId
+}
+
+func (*Artifact) Files() []string {
+	return nil
+}
+
+func (a *Artifact) Id() string {
+	parts := make([]string, 0, len(a.Amis))
+	for account, amis := range a.Amis {
+		for region, ami := range amis {
+			parts = append(parts, fmt.Sprintf("%s:%s:%s", account, region, ami))
+		}
+	}
+
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+func (a *Artifact) String() string {
+	amiStrings := make([]string, 0, len(a.Amis))
+	for account, amis := range a.Amis {
+		for region, ami := range amis {
+			amiStrings = append(amiStrings, fmt.Sprintf("%s %s: %s", account, region, ami))
+		}
+	}
+
+	sort.Strings(amiStrings)
+	return fmt.Sprintf("AMIs were copied:\n%s\n", strings.Join(amiStrings, "\n"))
+}
+
+func (*Artifact) State(name string) interface{} {
+	return nil
+}
+
+func (a *Artifact) Destroy() error {
+	errs := new(packer.MultiError)
+	for account, amis := range a.Amis {
+		for region, ami := range amis {
+			log.Printf("Deregistering image ID (%s) from region (%s) of account (%s)", ami, region, account)
+			_, err := a.connect(account, region).DeregisterImage(&ec2.DeregisterImageInput{
+				ImageId: aws.String(ami),
+			})
+			if err != nil {
+				errs = packer.MultiErrorAppend(errs, err)
+			}
+		}
+	}
+
+	if len(errs.Errors) > 0 {
+		if len(errs.Errors) == 1 {
+			return errs.Errors[0]
+		}
+		return errs
+	}
+	return nil
+}
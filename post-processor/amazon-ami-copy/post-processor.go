@@ -0,0 +1,375 @@
+package amazonamicopy
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	awscommon "github.com/hashicorp/packer/builder/amazon/common"
+	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/helper/config"
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+var accountIdRe = regexp.MustCompile(`^\d{12}$`)
+
+// Target is an account the AMIs are copied to.
+type Target struct {
+	AccountId  string            `mapstructure:"account_id"`
+	RoleArn    string            `mapstructure:"role_arn"`
+	ExternalId string            `mapstructure:"external_id"`
+	KmsKeyId   string            `mapstructure:"kms_key_id"`
+	Tags       map[string]string `mapstructure:"tags"`
+}
+
+type Config struct {
+	common.PackerConfig    `mapstructure:",squash"`
+	awscommon.AccessConfig `mapstructure:",squash"`
+
+	Targets               []Target          `mapstructure:"targets"`
+	RoleName              string            `mapstructure:"role_name"`
+	Name                  string            `mapstructure:"ami_name"`
+	EncryptBootVolume     bool              `mapstructure:"encrypt_boot"`
+	Tags                  map[string]string `mapstructure:"tags"`
+	KeepLaunchPermissions bool              `mapstructure:"keep_launch_permissions"`
+	Keep                  bool              `mapstructure:"keep_input_artifact"`
+
+	ctx interpolate.Context
+}
+
+type PostProcessor struct {
+	config Config
+}
+
+// A source AMI in one region, and the snapshots backing it.
+type sourceImage struct {
+	region    string
+	id        string
+	name      string
+	snapshots []string
+}
+
+func (p *PostProcessor) Configure(raws ...interface{}) error {
+	p.config.ctx.Funcs = awscommon.TemplateFuncs
+	err := config.Decode(&p.config, &config.DecodeOpts{
+		Interpolate:        true,
+		InterpolateContext: &p.config.ctx,
+	}, raws...)
+	if err != nil {
+		return err
+	}
+
+	if p.config.RoleName == "" {
+		p.config.RoleName = "OrganizationAccountAccessRole"
+	}
+
+	errs := new(packer.MultiError)
+	errs = packer.MultiErrorAppend(errs, p.config.AccessConfig.Prepare(&p.config.ctx)...)
+
+	if len(p.config.Targets) == 0 {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("targets must be set"))
+	}
+	seen := make(map[string]bool)
+	for i := range p.config.Targets {
+		t := &p.config.Targets[i]
+		if !accountIdRe.MatchString(t.AccountId) {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf(
+				"targets[%d]: account_id must be a 12 digit AWS account ID", i))
+			continue
+		}
+		if seen[t.AccountId] {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf(
+				"targets[%d]: account %s is listed more than once", i, t.AccountId))
+		}
+		seen[t.AccountId] = true
+
+		if t.RoleArn == "" {
+			t.RoleArn = fmt.Sprintf("arn:aws:iam::%s:role/%s", t.AccountId, p.config.RoleName)
+		}
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (packer.Artifact, bool, error) {
+	keep := p.config.Keep
+	ctx := context.TODO()
+
+	amis, err := parseAmis(artifact.Id())
+	if err != nil {
+		return nil, keep, fmt.Errorf(
+			"Can't copy artifact from %s: %s", artifact.BuilderId(), err)
+	}
+
+	session, err := p.config.Session()
+	if err != nil {
+		return nil, keep, err
+	}
+
+	var accounts []string
+	for _, t := range p.config.Targets {
+		accounts = append(accounts, t.AccountId)
+	}
+
+	// The target accounts copy the AMIs with their own credentials, so they
+	// have to be allowed to launch the AMIs and read their snapshots.
+	sources := make([]*sourceImage, 0, len(amis))
+	for region, ami := range amis {
+		conn := ec2.New(session, aws.NewConfig().WithRegion(region))
+		source, err := describeSource(conn, region, ami)
+		if err != nil {
+			return nil, keep, err
+		}
+		sources = append(sources, source)
+
+		ui.Message(fmt.Sprintf("Sharing %s in %s with %s", ami, region, strings.Join(accounts, ", ")))
+		if err := modifyPermissions(conn, source, accounts, true); err != nil {
+			return nil, keep, err
+		}
+	}
+	sort.Slice(sources, func(i, j int) bool { return sources[i].region < sources[j].region })
+
+	if !p.config.KeepLaunchPermissions {
+		defer func() {
+			for _, source := range sources {
+				ui.Message(fmt.Sprintf("Removing the launch permissions of %s in %s", source.id, source.region))
+				conn := ec2.New(session, aws.NewConfig().WithRegion(source.region))
+				if err := modifyPermissions(conn, source, accounts, false); err != nil {
+					ui.Error(err.Error())
+				}
+			}
+		}()
+	}
+
+	creds := make(map[string]*credentials.Credentials)
+	for _, t := range p.config.Targets {
+		t := t
+		creds[t.AccountId] = stscreds.NewCredentials(session, t.RoleArn, func(arp *stscreds.AssumeRoleProvider) {
+			if t.ExternalId != "" {
+				arp.ExternalID = aws.String(t.ExternalId)
+			}
+		})
+	}
+	connect := func(account, region string) *ec2.EC2 {
+		return ec2.New(session, aws.NewConfig().
+			WithRegion(region).
+			WithCredentials(creds[account]))
+	}
+
+	copies := &Artifact{
+		Amis:    make(map[string]map[string]string),
+		connect: connect,
+	}
+	for _, account := range accounts {
+		copies.Amis[account] = make(map[string]string)
+	}
+
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	errs := new(packer.MultiError)
+	for _, t := range p.config.Targets {
+		for _, source := range sources {
+			ui.Message(fmt.Sprintf("Copying %s to %s in %s", source.id, t.AccountId, source.region))
+
+			wg.Add(1)
+			go func(t Target, source *sourceImage) {
+				defer wg.Done()
+				id, err := p.copyImage(ctx, connect(t.AccountId, source.region), t, source)
+				lock.Lock()
+				defer lock.Unlock()
+				if id != "" {
+					copies.Amis[t.AccountId][source.region] = id
+				}
+				if err != nil {
+					errs = packer.MultiErrorAppend(errs, fmt.Errorf(
+						"Error copying %s to %s in %s: %s", source.id, t.AccountId, source.region, err))
+				}
+			}(t, source)
+		}
+	}
+
+	ui.Message("Waiting for all copies to complete...")
+	wg.Wait()
+
+	if len(errs.Errors) > 0 {
+		// Don't leave the copies that did succeed behind.
+		if err := copies.Destroy(); err != nil {
+			ui.Error(fmt.Sprintf("Error deregistering copied AMIs: %s", err))
+		}
+		return nil, keep, errs
+	}
+
+	return copies, keep, nil
+}
+
+// copyImage copies source into the account of conn, waits for the copy to
+// become available and tags it and its snapshots.
+func (p *PostProcessor) copyImage(ctx context.Context, conn *ec2.EC2, t Target, source *sourceImage) (string, error) {
+	name := p.config.Name
+	if name == "" {
+		name = source.name
+	}
+
+	input := &ec2.CopyImageInput{
+		Name:          aws.String(name),
+		SourceImageId: aws.String(source.id),
+		SourceRegion:  aws.String(source.region),
+	}
+	if p.config.EncryptBootVolume || t.KmsKeyId != "" {
+		input.Encrypted = aws.Bool(true)
+	}
+	if t.KmsKeyId != "" {
+		input.KmsKeyId = aws.String(t.KmsKeyId)
+	}
+
+	resp, err := conn.CopyImage(input)
+	if err != nil {
+		return "", err
+	}
+	id := *resp.ImageId
+	log.Printf("Copy of %s to %s in %s is %s", source.id, t.AccountId, source.region, id)
+
+	if err := awscommon.WaitUntilAMIAvailable(ctx, conn, id); err != nil {
+		return id, fmt.Errorf("Error waiting for AMI (%s): %s", id, err)
+	}
+
+	tags := ec2Tags(p.config.Tags, t.Tags)
+	if len(tags) == 0 {
+		return id, nil
+	}
+
+	copied, err := describeSource(conn, source.region, id)
+	if err != nil {
+		return id, err
+	}
+	resources := []*string{aws.String(id)}
+	for _, snapshot := range copied.snapshots {
+		resources = append(resources, aws.String(snapshot))
+	}
+	_, err = conn.CreateTags(&ec2.CreateTagsInput{
+		Resources: resources,
+		Tags:      tags,
+	})
+	if err != nil {
+		return id, fmt.Errorf("Error tagging AMI (%s): %s", id, err)
+	}
+
+	return id, nil
+}
+
+func describeSource(conn *ec2.EC2, region, ami string) (*sourceImage, error) {
+	resp, err := conn.DescribeImages(&ec2.DescribeImagesInput{
+		ImageIds: []*string{aws.String(ami)},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error describing AMI (%s) in %s: %s", ami, region, err)
+	}
+	if len(resp.Images) == 0 {
+		return nil, fmt.Errorf("AMI (%s) not found in %s", ami, region)
+	}
+
+	image := resp.Images[0]
+	source := &sourceImage{
+		region: region,
+		id:     ami,
+		name:   aws.StringValue(image.Name),
+	}
+	for _, bdm := range image.BlockDeviceMappings {
+		if bdm.Ebs != nil && bdm.Ebs.SnapshotId != nil {
+			source.snapshots = append(source.snapshots, *bdm.Ebs.SnapshotId)
+		}
+	}
+	return source, nil
+}
+
+// modifyPermissions adds or removes the permissions of accounts to launch
+// source and create volumes from its snapshots.
+func modifyPermissions(conn *ec2.EC2, source *sourceImage, accounts []string, add bool) error {
+	var launch []*ec2.LaunchPermission
+	var volume []*ec2.CreateVolumePermission
+	for _, account := range accounts {
+		launch = append(launch, &ec2.LaunchPermission{UserId: aws.String(account)})
+		volume = append(volume, &ec2.CreateVolumePermission{UserId: aws.String(account)})
+	}
+
+	launchModifications := &ec2.LaunchPermissionModifications{}
+	volumeModifications := &ec2.CreateVolumePermissionModifications{}
+	if add {
+		launchModifications.Add = launch
+		volumeModifications.Add = volume
+	} else {
+		launchModifications.Remove = launch
+		volumeModifications.Remove = volume
+	}
+
+	_, err := conn.ModifyImageAttribute(&ec2.ModifyImageAttributeInput{
+		ImageId:          aws.String(source.id),
+		LaunchPermission: launchModifications,
+	})
+	if err != nil {
+		return fmt.Errorf("Error modifying launch permissions of AMI (%s): %s", source.id, err)
+	}
+
+	for _, snapshot := range source.snapshots {
+		_, err := conn.ModifySnapshotAttribute(&ec2.ModifySnapshotAttributeInput{
+			SnapshotId:             aws.String(snapshot),
+			Attribute:              aws.String("createVolumePermission"),
+			CreateVolumePermission: volumeModifications,
+		})
+		if err != nil {
+			return fmt.Errorf("Error modifying volume permissions of snapshot (%s): %s", snapshot, err)
+		}
+	}
+	return nil
+}
+
+// parseAmis parses the ID of an AMI artifact, like
+// "us-east-1:ami-1234,eu-west-1:ami-5678", into a map of regions to AMIs.
+func parseAmis(id string) (map[string]string, error) {
+	amis := make(map[string]string)
+	for _, part := range strings.Split(id, ",") {
+		parts := strings.Split(part, ":")
+		if len(parts) != 2 || parts[0] == "" || !strings.HasPrefix(parts[1], "ami-") {
+			return nil, fmt.Errorf("%q is not a list of region:ami-id pairs", id)
+		}
+		amis[parts[0]] = parts[1]
+	}
+	return amis, nil
+}
+
+// ec2Tags merges the tags of all targets with the tags of one, which take
+// precedence.
+func ec2Tags(all, target map[string]string) []*ec2.Tag {
+	merged := make(map[string]string)
+	for k, v := range all {
+		merged[k] = v
+	}
+	for k, v := range target {
+		merged[k] = v
+	}
+
+	keys := make([]string, 0, len(merged))
+	for k := range merged {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	tags := make([]*ec2.Tag, 0, len(keys))
+	for _, k := range keys {
+		tags = append(tags, &ec2.Tag{Key: aws.String(k), Value: aws.String(merged[k])})
+	}
+	return tags
+}
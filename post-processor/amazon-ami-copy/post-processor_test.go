@@ -0,0 +1,96 @@
+package amazonamicopy
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/hashicorp/packer/packer"
+)
+
+func testConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"access_key": "foo",
+		"secret_key": "bar",
+		"region":     "us-east-1",
+		"targets": []map[string]interface{}{
+			{"account_id": "111111111111"},
+			{"account_id": "222222222222", "role_arn": "arn:aws:iam::222222222222:role/promote"},
+		},
+	}
+}
+
+func TestPostProcessor_ImplementsPostProcessor(t *testing.T) {
+	var _ packer.PostProcessor = new(PostProcessor)
+}
+
+func TestArtifact_ImplementsArtifact(t *testing.T) {
+	var _ packer.Artifact = new(Artifact)
+}
+
+func TestPostProcessorConfigure(t *testing.T) {
+	var p PostProcessor
+	if err := p.Configure(testConfig()); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if p.config.Targets[0].RoleArn != "arn:aws:iam::111111111111:role/OrganizationAccountAccessRole" {
+		t.Fatalf("bad role: %s", p.config.Targets[0].RoleArn)
+	}
+	if p.config.Targets[1].RoleArn != "arn:aws:iam::222222222222:role/promote" {
+		t.Fatalf("bad role: %s", p.config.Targets[1].RoleArn)
+	}
+
+	c := testConfig()
+	c["targets"] = []map[string]interface{}{{"account_id": "1234"}}
+	p = PostProcessor{}
+	if err := p.Configure(c); err == nil {
+		t.Fatal("should error on bad account ID")
+	}
+
+	c = testConfig()
+	delete(c, "targets")
+	p = PostProcessor{}
+	if err := p.Configure(c); err == nil {
+		t.Fatal("should require targets")
+	}
+}
+
+func TestParseAmis(t *testing.T) {
+	amis, err := parseAmis("us-east-1:ami-1234,eu-west-1:ami-5678")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := map[string]string{"us-east-1": "ami-1234", "eu-west-1": "ami-5678"}
+	if !reflect.DeepEqual(amis, expected) {
+		t.Fatalf("bad: %#v", amis)
+	}
+
+	if _, err := parseAmis("packer-vm"); err == nil {
+		t.Fatal("should error on non-AMI artifact")
+	}
+}
+
+func TestEC2Tags(t *testing.T) {
+	tags := ec2Tags(
+		map[string]string{"stage": "prod", "team": "infra"},
+		map[string]string{"stage": "staging"})
+	expected := []*ec2.Tag{
+		{Key: aws.String("stage"), Value: aws.String("staging")},
+		{Key: aws.String("team"), Value: aws.String("infra")},
+	}
+	if !reflect.DeepEqual(tags, expected) {
+		t.Fatalf("bad: %#v", tags)
+	}
+}
+
+func TestArtifactId(t *testing.T) {
+	a := &Artifact{Amis: map[string]map[string]string{
+		"222222222222": {"us-east-1": "ami-2"},
+		"111111111111": {"us-east-1": "ami-1", "eu-west-1": "ami-3"},
+	}}
+	expected := "111111111111:eu-west-1:ami-3,111111111111:us-east-1:ami-1,222222222222:us-east-1:ami-2"
+	if a.Id() != expected {
+		t.Fatalf("bad: %s", a.Id())
+	}
+}
@@ -2,11 +2,16 @@ package amazonimport
 
 import (
 	"fmt"
+	"io/ioutil"
 	"log"
+	"net/url"
 	"os"
+	"path"
+	"regexp"
 	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/ec2"
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
@@ -23,6 +28,7 @@ const BuilderId = "packer.post-processor.amazon-import"
 type Config struct {
 	common.PackerConfig    `mapstructure:",squash"`
 	awscommon.AccessConfig `mapstructure:",squash"`
+	common.UploadConfig    `mapstructure:",squash"`
 
 	// Variables specific to this post processor
 	S3Bucket    string            `mapstructure:"s3_bucket_name"`
@@ -36,6 +42,7 @@ type Config struct {
 	LicenseType string            `mapstructure:"license_type"`
 	RoleName    string            `mapstructure:"role_name"`
 	Format      string            `mapstructure:"format"`
+	BootMode    string            `mapstructure:"boot_mode"`
 
 	ctx interpolate.Context
 }
@@ -80,6 +87,9 @@ func (p *PostProcessor) Configure(raws ...interface{}) error {
 	// Check we have AWS access variables defined somewhere
 	errs = packer.MultiErrorAppend(errs, p.config.AccessConfig.Prepare(&p.config.ctx)...)
 
+	// The parts of multipart uploads are at least 5MB
+	errs = packer.MultiErrorAppend(errs, p.config.UploadConfig.Prepare(64, 5)...)
+
 	// define all our required parameters
 	templates := map[string]*string{
 		"s3_bucket_name": &p.config.S3Bucket,
@@ -92,6 +102,20 @@ func (p *PostProcessor) Configure(raws ...interface{}) error {
 		}
 	}
 
+	switch p.config.LicenseType {
+	case "", "AWS", "BYOL":
+	default:
+		errs = packer.MultiErrorAppend(
+			errs, fmt.Errorf("invalid license_type '%s'. Only 'AWS' or 'BYOL' are allowed", p.config.LicenseType))
+	}
+
+	switch p.config.BootMode {
+	case "", "legacy-bios", "uefi":
+	default:
+		errs = packer.MultiErrorAppend(
+			errs, fmt.Errorf("invalid boot_mode '%s'. Only 'legacy-bios' or 'uefi' are allowed", p.config.BootMode))
+	}
+
 	switch p.config.Format {
 	case "ova", "raw", "vmdk", "vhd", "vhdx":
 	default:
@@ -126,59 +150,62 @@ func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (pac
 	log.Printf("Rendered s3_key_name as %s", p.config.S3Key)
 
 	log.Println("Looking for image in artifact")
-	// Locate the files output from the builder
-	source := ""
-	for _, path := range artifact.Files() {
-		if strings.HasSuffix(path, "."+p.config.Format) {
-			source = path
-			break
-		}
-	}
+	sources := imageFiles(artifact.Files(), p.config.Format)
 
 	// Hope we found something useful
-	if source == "" {
+	if len(sources) == 0 {
 		return nil, false, fmt.Errorf("No %s image file found in artifact from builder", p.config.Format)
 	}
 
-	// open the source file
-	log.Printf("Opening file %s to upload", source)
-	file, err := os.Open(source)
-	if err != nil {
-		return nil, false, fmt.Errorf("Failed to open %s: %s", source, err)
-	}
+	// Copy the image files into the S3 bucket specified
+	// Each part is sent again on its own when it fails, the upload fails
+	// once a part has failed all of its retries.
+	uploader := s3manager.NewUploader(
+		session.Copy(&aws.Config{MaxRetries: aws.Int(p.config.UploadRetries)}),
+		func(u *s3manager.Uploader) {
+			u.PartSize = p.config.ChunkBytes()
+		})
+	keys := make([]string, len(sources))
+	var diskContainers []*ec2.ImageDiskContainer
+	for i, source := range sources {
+		keys[i] = diskKey(p.config.S3Key, i)
 
-	ui.Message(fmt.Sprintf("Uploading %s to s3://%s/%s", source, p.config.S3Bucket, p.config.S3Key))
+		log.Printf("Opening file %s to upload", source)
+		file, err := os.Open(source)
+		if err != nil {
+			return nil, false, fmt.Errorf("Failed to open %s: %s", source, err)
+		}
 
-	// Copy the image file into the S3 bucket specified
-	uploader := s3manager.NewUploader(session)
-	_, err = uploader.Upload(&s3manager.UploadInput{
-		Body:   file,
-		Bucket: &p.config.S3Bucket,
-		Key:    &p.config.S3Key,
-	})
-	if err != nil {
-		return nil, false, fmt.Errorf("Failed to upload %s: %s", source, err)
-	}
+		ui.Message(fmt.Sprintf("Uploading %s to s3://%s/%s", source, p.config.S3Bucket, keys[i]))
+
+		_, err = uploader.Upload(&s3manager.UploadInput{
+			Body:   file,
+			Bucket: &p.config.S3Bucket,
+			Key:    &keys[i],
+		})
+		// May as well stop holding this open now
+		file.Close()
+		if err != nil {
+			return nil, false, fmt.Errorf("Failed to upload %s: %s", source, err)
+		}
 
-	// May as well stop holding this open now
-	file.Close()
+		ui.Message(fmt.Sprintf("Completed upload of %s to s3://%s/%s", source, p.config.S3Bucket, keys[i]))
 
-	ui.Message(fmt.Sprintf("Completed upload of %s to s3://%s/%s", source, p.config.S3Bucket, p.config.S3Key))
+		diskContainers = append(diskContainers, &ec2.ImageDiskContainer{
+			Format: &p.config.Format,
+			UserBucket: &ec2.UserBucket{
+				S3Bucket: &p.config.S3Bucket,
+				S3Key:    &keys[i],
+			},
+		})
+	}
 
 	// Call EC2 image import process
-	log.Printf("Calling EC2 to import from s3://%s/%s", p.config.S3Bucket, p.config.S3Key)
+	log.Printf("Calling EC2 to import from s3://%s/%s", p.config.S3Bucket, strings.Join(keys, ", "))
 
 	ec2conn := ec2.New(session)
 	params := &ec2.ImportImageInput{
-		DiskContainers: []*ec2.ImageDiskContainer{
-			{
-				Format: &p.config.Format,
-				UserBucket: &ec2.UserBucket{
-					S3Bucket: &p.config.S3Bucket,
-					S3Key:    &p.config.S3Key,
-				},
-			},
-		},
+		DiskContainers: diskContainers,
 	}
 
 	if p.config.RoleName != "" {
@@ -190,7 +217,12 @@ func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (pac
 		params.LicenseType = &p.config.LicenseType
 	}
 
-	import_start, err := ec2conn.ImportImage(params)
+	req, import_start := ec2conn.ImportImageRequest(params)
+	if p.config.BootMode != "" {
+		ui.Message(fmt.Sprintf("Setting boot mode to '%s'", p.config.BootMode))
+		req.Handlers.Build.PushBack(addQueryParameter("BootMode", p.config.BootMode))
+	}
+	err = req.Send()
 
 	if err != nil {
 		return nil, false, fmt.Errorf("Failed to start import from s3://%s/%s: %s", p.config.S3Bucket, p.config.S3Key, err)
@@ -200,7 +232,8 @@ func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (pac
 
 	// Wait for import process to complete, this takes a while
 	ui.Message(fmt.Sprintf("Waiting for task %s to complete (may take a while)", *import_start.ImportTaskId))
-	err = awscommon.WaitUntilImageImported(aws.BackgroundContext(), ec2conn, *import_start.ImportTaskId)
+	err = awscommon.WaitUntilImageImported(aws.BackgroundContext(), ec2conn, *import_start.ImportTaskId,
+		request.WithWaiterRequestOptions(reportImportProgress(ui)))
 	if err != nil {
 		return nil, false, fmt.Errorf("Import task %s failed with error: %s", *import_start.ImportTaskId, err)
 	}
@@ -380,16 +413,106 @@ func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (pac
 	}
 
 	if !p.config.SkipClean {
-		ui.Message(fmt.Sprintf("Deleting import source s3://%s/%s", p.config.S3Bucket, p.config.S3Key))
 		s3conn := s3.New(session)
-		_, err = s3conn.DeleteObject(&s3.DeleteObjectInput{
-			Bucket: &p.config.S3Bucket,
-			Key:    &p.config.S3Key,
-		})
-		if err != nil {
-			return nil, false, fmt.Errorf("Failed to delete s3://%s/%s: %s", p.config.S3Bucket, p.config.S3Key, err)
+		for i := range keys {
+			ui.Message(fmt.Sprintf("Deleting import source s3://%s/%s", p.config.S3Bucket, keys[i]))
+			_, err = s3conn.DeleteObject(&s3.DeleteObjectInput{
+				Bucket: &p.config.S3Bucket,
+				Key:    &keys[i],
+			})
+			if err != nil {
+				return nil, false, fmt.Errorf("Failed to delete s3://%s/%s: %s", p.config.S3Bucket, keys[i], err)
+			}
 		}
 	}
 
 	return artifact, false, nil
 }
+
+// vmdkExtent matches the extents of split and flat vmdk disks, which are
+// part of the disk of their descriptor.
+var vmdkExtent = regexp.MustCompile(`-(s|f)[0-9]{3}\.vmdk$|-flat\.vmdk$`)
+
+// imageFiles returns the files of the artifact to import, one per disk. An
+// OVA describes all of the disks of the machine, other formats have one
+// file per disk, and the extents of vmdk disks are left out.
+func imageFiles(files []string, format string) []string {
+	var sources []string
+	for _, path := range files {
+		if !strings.HasSuffix(path, "."+format) {
+			continue
+		}
+		if format == "vmdk" && vmdkExtent.MatchString(path) {
+			log.Printf("Skipping %s, an extent of a vmdk disk", path)
+			continue
+		}
+		sources = append(sources, path)
+		if format == "ova" {
+			break
+		}
+	}
+	return sources
+}
+
+// diskKey returns the S3 key of the nth disk. The first disk uses the
+// configured key and the others get a suffix before the extension.
+func diskKey(key string, n int) string {
+	if n == 0 {
+		return key
+	}
+	ext := path.Ext(key)
+	return fmt.Sprintf("%s-%d%s", strings.TrimSuffix(key, ext), n, ext)
+}
+
+// addQueryParameter returns a build handler adding a parameter to an EC2
+// query request. It is used for parameters that the vendored SDK doesn't
+// know about yet, and must run after the request body has been built.
+func addQueryParameter(name, value string) func(*request.Request) {
+	return func(r *request.Request) {
+		if r.Error != nil {
+			return
+		}
+		body, err := ioutil.ReadAll(r.GetBody())
+		if err != nil {
+			r.Error = err
+			return
+		}
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			r.Error = err
+			return
+		}
+		values.Set(name, value)
+		r.SetBufferBody([]byte(values.Encode()))
+	}
+}
+
+// reportImportProgress returns a request option that reports the status
+// of the import task every time it changes while it is being waited for.
+func reportImportProgress(ui packer.Ui) request.Option {
+	last := ""
+	return func(r *request.Request) {
+		r.Handlers.Complete.PushBack(func(r *request.Request) {
+			out, ok := r.Data.(*ec2.DescribeImportImageTasksOutput)
+			if r.Error != nil || !ok || len(out.ImportImageTasks) == 0 {
+				return
+			}
+			status := importStatus(out.ImportImageTasks[0])
+			if status != last {
+				ui.Message(status)
+				last = status
+			}
+		})
+	}
+}
+
+func importStatus(task *ec2.ImportImageTask) string {
+	status := fmt.Sprintf("Import task %s: %s", aws.StringValue(task.ImportTaskId), aws.StringValue(task.Status))
+	if task.Progress != nil {
+		status += fmt.Sprintf(" (%s%%)", *task.Progress)
+	}
+	if task.StatusMessage != nil {
+		status += ", " + *task.StatusMessage
+	}
+	return status
+}
@@ -0,0 +1,159 @@
+package amazonimport
+
+import (
+	"io/ioutil"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+	"github.com/hashicorp/packer/packer"
+)
+
+func testConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"access_key":     "foo",
+		"secret_key":     "bar",
+		"region":         "us-east-1",
+		"s3_bucket_name": "bucket",
+	}
+}
+
+func TestPostProcessor_ImplementsPostProcessor(t *testing.T) {
+	var _ packer.PostProcessor = new(PostProcessor)
+}
+
+func TestPostProcessorConfigure(t *testing.T) {
+	cases := []struct {
+		key   string
+		value string
+		err   bool
+	}{
+		{"boot_mode", "uefi", false},
+		{"boot_mode", "legacy-bios", false},
+		{"boot_mode", "bios", true},
+		{"license_type", "BYOL", false},
+		{"license_type", "byol", true},
+		{"format", "vhdx", false},
+		{"format", "qcow2", true},
+		{"upload_chunk_size", "16", false},
+		{"upload_chunk_size", "4", true},
+	}
+
+	for _, tc := range cases {
+		c := testConfig()
+		c[tc.key] = tc.value
+
+		var p PostProcessor
+		err := p.Configure(c)
+		if (err != nil) != tc.err {
+			t.Errorf("%s=%s: expected error %t, got %v", tc.key, tc.value, tc.err, err)
+		}
+	}
+}
+
+func TestImageFiles(t *testing.T) {
+	cases := []struct {
+		files    []string
+		format   string
+		expected []string
+	}{
+		{
+			[]string{"a.ova", "b.ova", "c.mf"},
+			"ova",
+			[]string{"a.ova"},
+		},
+		{
+			[]string{"disk.vmdk", "disk-s001.vmdk", "disk-s002.vmdk", "disk-1.vmdk", "disk-1-flat.vmdk", "disk.vmx"},
+			"vmdk",
+			[]string{"disk.vmdk", "disk-1.vmdk"},
+		},
+		{
+			[]string{"disk.raw", "disk-s001.raw"},
+			"raw",
+			[]string{"disk.raw", "disk-s001.raw"},
+		},
+	}
+
+	for _, tc := range cases {
+		if actual := imageFiles(tc.files, tc.format); !reflect.DeepEqual(actual, tc.expected) {
+			t.Errorf("%v: expected %v, got %v", tc.files, tc.expected, actual)
+		}
+	}
+}
+
+func TestDiskKey(t *testing.T) {
+	cases := []struct {
+		key      string
+		n        int
+		expected string
+	}{
+		{"packer-import-1.vmdk", 0, "packer-import-1.vmdk"},
+		{"packer-import-1.vmdk", 1, "packer-import-1-1.vmdk"},
+		{"images/disk", 2, "images/disk-2"},
+	}
+
+	for _, tc := range cases {
+		if actual := diskKey(tc.key, tc.n); actual != tc.expected {
+			t.Errorf("%s %d: expected %s, got %s", tc.key, tc.n, tc.expected, actual)
+		}
+	}
+}
+
+func TestAddQueryParameter(t *testing.T) {
+	sess, err := session.NewSession(&aws.Config{
+		Region:      aws.String("us-east-1"),
+		Credentials: credentials.NewStaticCredentials("foo", "bar", ""),
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	req, _ := ec2.New(sess).ImportImageRequest(&ec2.ImportImageInput{
+		LicenseType: aws.String("BYOL"),
+	})
+	req.Handlers.Build.PushBack(addQueryParameter("BootMode", "uefi"))
+	if err := req.Build(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	body, err := ioutil.ReadAll(req.GetBody())
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if values.Get("Action") != "ImportImage" || values.Get("LicenseType") != "BYOL" {
+		t.Fatalf("bad body: %s", body)
+	}
+	if values.Get("BootMode") != "uefi" {
+		t.Fatalf("boot mode not set: %s", body)
+	}
+}
+
+func TestImportStatus(t *testing.T) {
+	task := &ec2.ImportImageTask{
+		ImportTaskId:  aws.String("import-ami-1"),
+		Status:        aws.String("active"),
+		Progress:      aws.String("28"),
+		StatusMessage: aws.String("converting"),
+	}
+	expected := "Import task import-ami-1: active (28%), converting"
+	if actual := importStatus(task); actual != expected {
+		t.Fatalf("expected %q, got %q", expected, actual)
+	}
+
+	task = &ec2.ImportImageTask{
+		ImportTaskId: aws.String("import-ami-1"),
+		Status:       aws.String("completed"),
+	}
+	expected = "Import task import-ami-1: completed"
+	if actual := importStatus(task); actual != expected {
+		t.Fatalf("expected %q, got %q", expected, actual)
+	}
+}
@@ -10,9 +10,13 @@ import (
 const BuilderId = "packer.post-processor.artifice"
 
 type Artifact struct {
-	files []string
+	files     []string
+	buildName string
+	metadata  map[string]string
 }
 
+// NewArtifact creates an artifact from files, which may be glob patterns or
+// directories. Directories are replaced by the files in them.
 func NewArtifact(files []string) (*Artifact, error) {
 	artifact := &Artifact{}
 	for _, f := range files {
@@ -20,11 +24,31 @@ func NewArtifact(files []string) (*Artifact, error) {
 		if err != nil {
 			return nil, err
 		}
+		if len(globfiles) == 0 {
+			return nil, fmt.Errorf("No files match %s", f)
+		}
 		for _, gf := range globfiles {
-			if _, err := os.Stat(gf); err != nil {
+			info, err := os.Stat(gf)
+			if err != nil {
+				return nil, err
+			}
+			if !info.IsDir() {
+				artifact.files = append(artifact.files, gf)
+				continue
+			}
+
+			err = filepath.Walk(gf, func(path string, info os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+				if !info.IsDir() {
+					artifact.files = append(artifact.files, path)
+				}
+				return nil
+			})
+			if err != nil {
 				return nil, err
 			}
-			artifact.files = append(artifact.files, gf)
 		}
 	}
 	return artifact, nil
@@ -44,10 +68,22 @@ func (a *Artifact) Id() string {
 
 func (a *Artifact) String() string {
 	files := strings.Join(a.files, ", ")
+	if a.buildName != "" {
+		return fmt.Sprintf("Created artifact from files of build '%s': %s", a.buildName, files)
+	}
 	return fmt.Sprintf("Created artifact from files: %s", files)
 }
 
+// State returns the metadata value name, so that post-processors that look
+// for state set by builders, like "diskName", can be given it. "build_name"
+// is the name of the build the files came from.
 func (a *Artifact) State(name string) interface{} {
+	if v, ok := a.metadata[name]; ok {
+		return v
+	}
+	if name == "build_name" && a.buildName != "" {
+		return a.buildName
+	}
 	return nil
 }
 
@@ -19,8 +19,9 @@ import (
 type Config struct {
 	common.PackerConfig `mapstructure:",squash"`
 
-	Files []string `mapstructure:"files"`
-	Keep  bool     `mapstructure:"keep_input_artifact"`
+	Files    []string          `mapstructure:"files"`
+	Metadata map[string]string `mapstructure:"metadata"`
+	Keep     bool              `mapstructure:"keep_input_artifact"`
 
 	ctx interpolate.Context
 }
@@ -53,8 +54,13 @@ func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (pac
 		ui.Say(fmt.Sprintf("Discarding artifact files: %s", strings.Join(artifact.Files(), ", ")))
 	}
 
-	artifact, err := NewArtifact(p.config.Files)
-	ui.Say(fmt.Sprintf("Using these artifact files: %s", strings.Join(artifact.Files(), ", ")))
+	newArtifact, err := NewArtifact(p.config.Files)
+	if err != nil {
+		return nil, true, err
+	}
+	newArtifact.buildName = p.config.PackerBuildName
+	newArtifact.metadata = p.config.Metadata
+	ui.Say(fmt.Sprintf("Using these artifact files: %s", strings.Join(newArtifact.Files(), ", ")))
 
-	return artifact, true, err
+	return newArtifact, true, nil
 }
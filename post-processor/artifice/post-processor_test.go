@@ -1 +1,108 @@
 package artifice
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+func testUi() *packer.BasicUi {
+	return &packer.BasicUi{
+		Reader: new(bytes.Buffer),
+		Writer: new(bytes.Buffer),
+	}
+}
+
+func testFiles(t *testing.T, names ...string) string {
+	dir, err := ioutil.TempDir("", "packer-artifice")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	for _, name := range names {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := ioutil.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+	return dir
+}
+
+func TestPostProcessor_ImplementsPostProcessor(t *testing.T) {
+	var _ packer.PostProcessor = new(PostProcessor)
+}
+
+func TestArtifact_ImplementsArtifact(t *testing.T) {
+	var _ packer.Artifact = new(Artifact)
+}
+
+func TestNewArtifact(t *testing.T) {
+	dir := testFiles(t, "app.jar", "notes.txt", "lib/a.jar", "lib/sub/b.jar")
+	defer os.RemoveAll(dir)
+
+	artifact, err := NewArtifact([]string{
+		filepath.Join(dir, "*.jar"),
+		filepath.Join(dir, "lib"),
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []string{
+		filepath.Join(dir, "app.jar"),
+		filepath.Join(dir, "lib", "a.jar"),
+		filepath.Join(dir, "lib", "sub", "b.jar"),
+	}
+	if !reflect.DeepEqual(artifact.Files(), expected) {
+		t.Fatalf("bad files: %#v", artifact.Files())
+	}
+
+	if _, err := NewArtifact([]string{filepath.Join(dir, "*.war")}); err == nil {
+		t.Fatal("should error when nothing matches")
+	}
+}
+
+func TestPostProcessor_PostProcess(t *testing.T) {
+	dir := testFiles(t, "disk.qcow2")
+	defer os.RemoveAll(dir)
+
+	var p PostProcessor
+	err := p.Configure(map[string]interface{}{
+		"files": []string{filepath.Join(dir, "disk.qcow2")},
+		"metadata": map[string]string{
+			"diskName": "disk.qcow2",
+			"diskType": "{{ build_name }}",
+		},
+	}, map[string]interface{}{
+		"packer_build_name": "qcow2",
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	artifact, keep, err := p.PostProcess(testUi(), &packer.MockArtifact{})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !keep {
+		t.Fatal("should keep the input artifact")
+	}
+
+	if v := artifact.State("diskName"); v != "disk.qcow2" {
+		t.Fatalf("bad diskName: %#v", v)
+	}
+	if v := artifact.State("diskType"); v != "qcow2" {
+		t.Fatalf("bad diskType: %#v", v)
+	}
+	if v := artifact.State("build_name"); v != "qcow2" {
+		t.Fatalf("bad build_name: %#v", v)
+	}
+	if v := artifact.State("missing"); v != nil {
+		t.Fatalf("bad missing: %#v", v)
+	}
+}
@@ -0,0 +1,199 @@
+package azureimport
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/storage"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/hashicorp/packer/helper/useragent"
+)
+
+// computeAPIVersion is the version of the compute API for the uploads to
+// managed disks and the galleries, which the vendored SDK doesn't have.
+const computeAPIVersion = "2019-07-01"
+
+// armClient makes the requests to Azure Resource Manager that the vendored
+// SDK can't.
+type armClient struct {
+	autorest.Client
+	baseURI        string
+	subscriptionID string
+}
+
+func newARMClient(baseURI, subscriptionID string, authorizer autorest.Authorizer) *armClient {
+	c := &armClient{
+		Client:         autorest.NewClientWithUserAgent(useragent.String()),
+		baseURI:        baseURI,
+		subscriptionID: subscriptionID,
+	}
+	c.Authorizer = authorizer
+	return c
+}
+
+// do sends the request for the resource at path, in the resource group of
+// the subscription, and waits for it to complete if it is a long running
+// operation. The response is unmarshalled into result if it isn't nil.
+func (c *armClient) do(ctx context.Context, method, resourceGroup, path string, body, result interface{}) error {
+	decorators := []autorest.PrepareDecorator{
+		autorest.AsContentType("application/json; charset=utf-8"),
+		autorest.WithMethod(method),
+		autorest.WithBaseURL(c.baseURI),
+		autorest.WithPath(fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Compute/%s",
+			c.subscriptionID, resourceGroup, path)),
+		autorest.WithQueryParameters(map[string]interface{}{"api-version": computeAPIVersion}),
+	}
+	if body != nil {
+		decorators = append(decorators, autorest.WithJSON(body))
+	}
+	req, err := autorest.Prepare((&http.Request{}).WithContext(ctx), decorators...)
+	if err != nil {
+		return err
+	}
+
+	resp, err := autorest.SendWithSender(c, req, azure.DoRetryWithRegistration(c.Client))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusAccepted || resp.StatusCode == http.StatusCreated {
+		future, err := azure.NewFutureFromResponse(resp)
+		if err != nil {
+			return err
+		}
+		if err := future.WaitForCompletionRef(ctx, c.Client); err != nil {
+			return err
+		}
+		if resp, err = future.GetResult(c); err != nil {
+			return err
+		}
+	}
+
+	responders := []autorest.RespondDecorator{
+		c.ByInspecting(),
+		azure.WithErrorUnlessStatusCode(http.StatusOK, http.StatusCreated, http.StatusAccepted, http.StatusNoContent),
+	}
+	if result != nil {
+		responders = append(responders, autorest.ByUnmarshallingJSON(result))
+	}
+	return autorest.Respond(resp, append(responders, autorest.ByClosing())...)
+}
+
+// managedDiskID is the resource ID of the managed disk.
+func (c *armClient) managedDiskID(resourceGroup, name string) string {
+	return fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Compute/disks/%s",
+		c.subscriptionID, resourceGroup, name)
+}
+
+// createUploadDisk creates an empty managed disk to upload a VHD of the size
+// to.
+func (c *armClient) createUploadDisk(ctx context.Context, resourceGroup, name, location, osType string, size int64) error {
+	disk := map[string]interface{}{
+		"location": location,
+		"sku":      map[string]string{"name": "Standard_LRS"},
+		"properties": map[string]interface{}{
+			"osType": osType,
+			"creationData": map[string]interface{}{
+				"createOption":    "Upload",
+				"uploadSizeBytes": size,
+			},
+		},
+	}
+	return c.do(ctx, http.MethodPut, resourceGroup, "disks/"+name, disk, nil)
+}
+
+// grantDiskWrite returns a SAS URL to write to the managed disk with.
+func (c *armClient) grantDiskWrite(ctx context.Context, resourceGroup, name string) (string, error) {
+	var result struct {
+		AccessSAS string `json:"accessSAS"`
+	}
+	access := map[string]interface{}{
+		"access":            "Write",
+		"durationInSeconds": 24 * 60 * 60,
+	}
+	err := c.do(ctx, http.MethodPost, resourceGroup, "disks/"+name+"/beginGetAccess", access, &result)
+	if err == nil && result.AccessSAS == "" {
+		err = fmt.Errorf("no SAS URL returned")
+	}
+	return result.AccessSAS, err
+}
+
+// revokeDiskAccess revokes the SAS URL of the managed disk, which makes
+// the disk usable once it is uploaded.
+func (c *armClient) revokeDiskAccess(ctx context.Context, resourceGroup, name string) error {
+	return c.do(ctx, http.MethodPost, resourceGroup, "disks/"+name+"/endGetAccess", nil, nil)
+}
+
+func (c *armClient) deleteDisk(ctx context.Context, resourceGroup, name string) error {
+	return c.do(ctx, http.MethodDelete, resourceGroup, "disks/"+name, nil, nil)
+}
+
+// createGalleryImageVersion creates the version of the gallery image from
+// the managed image and returns its ID.
+func (c *armClient) createGalleryImageVersion(ctx context.Context, d SharedImageGalleryDestination, location, managedImageID string) (string, error) {
+	regions := []map[string]string{{"name": location}}
+	for _, r := range d.ReplicationRegions {
+		if !strings.EqualFold(r, location) {
+			regions = append(regions, map[string]string{"name": r})
+		}
+	}
+	version := map[string]interface{}{
+		"location": location,
+		"properties": map[string]interface{}{
+			"publishingProfile": map[string]interface{}{
+				"targetRegions": regions,
+			},
+			"storageProfile": map[string]interface{}{
+				"source": map[string]string{"id": managedImageID},
+			},
+		},
+	}
+
+	var result struct {
+		ID string `json:"id"`
+	}
+	path := fmt.Sprintf("galleries/%s/images/%s/versions/%s", d.GalleryName, d.ImageName, d.ImageVersion)
+	err := c.do(ctx, http.MethodPut, d.ResourceGroup, path, version, &result)
+	return result.ID, err
+}
+
+// sasPageWriter writes the pages of a page blob, like a managed disk being
+// uploaded, with its SAS URL.
+type sasPageWriter struct {
+	url    string
+	client *http.Client
+}
+
+func (w *sasPageWriter) WriteRange(r storage.BlobRange, body io.Reader, _ *storage.PutPageOptions) error {
+	u, err := url.Parse(w.url)
+	if err != nil {
+		return err
+	}
+	q := u.Query()
+	q.Set("comp", "page")
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequest(http.MethodPut, u.String(), body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = int64(r.End - r.Start + 1)
+	req.Header.Set("x-ms-page-write", "update")
+	req.Header.Set("x-ms-range", fmt.Sprintf("bytes=%d-%d", r.Start, r.End))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package azureimport
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/storage"
+	"github.com/Azure/go-autorest/autorest"
+)
+
+func TestSASPageWriter(t *testing.T) {
+	var req *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req = r
+		body, _ = ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	w := &sasPageWriter{url: server.URL + "/disk/abcd?sv=2018-03-28&sig=foo", client: server.Client()}
+	data := make([]byte, 1024)
+	data[0] = 1
+	if err := w.WriteRange(storage.BlobRange{Start: 512, End: 1535}, bytes.NewReader(data), nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if req.Method != http.MethodPut || req.URL.Path != "/disk/abcd" {
+		t.Fatalf("bad request: %s %s", req.Method, req.URL)
+	}
+	q := req.URL.Query()
+	if q.Get("comp") != "page" || q.Get("sig") != "foo" {
+		t.Fatalf("bad query: %s", req.URL.RawQuery)
+	}
+	if req.Header.Get("x-ms-page-write") != "update" || req.Header.Get("x-ms-range") != "bytes=512-1535" {
+		t.Fatalf("bad headers: %#v", req.Header)
+	}
+	if !bytes.Equal(body, data) {
+		t.Fatal("bad body")
+	}
+
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "AuthenticationFailed", http.StatusForbidden)
+	})
+	if err := w.WriteRange(storage.BlobRange{Start: 0, End: 511}, bytes.NewReader(data[:512]), nil); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+func TestARMClient_createGalleryImageVersion(t *testing.T) {
+	var path, apiVersion string
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		apiVersion = r.URL.Query().Get("api-version")
+		json.NewDecoder(r.Body).Decode(&body)
+		w.Write([]byte(`{"id": "/versions/1.0.0"}`))
+	}))
+	defer server.Close()
+
+	c := newARMClient(server.URL, "sub", autorest.NullAuthorizer{})
+	sig := SharedImageGalleryDestination{
+		ResourceGroup:      "rg",
+		GalleryName:        "gallery",
+		ImageName:          "ubuntu",
+		ImageVersion:       "1.0.0",
+		ReplicationRegions: []string{"westeurope", "northeurope"},
+	}
+	id, err := c.createGalleryImageVersion(context.Background(), sig, "westeurope", "/images/ubuntu")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if id != "/versions/1.0.0" {
+		t.Fatalf("bad id: %s", id)
+	}
+	if path != "/subscriptions/sub/resourceGroups/rg/providers/Microsoft.Compute/galleries/gallery/images/ubuntu/versions/1.0.0" {
+		t.Fatalf("bad path: %s", path)
+	}
+	if apiVersion != computeAPIVersion {
+		t.Fatalf("bad api version: %s", apiVersion)
+	}
+
+	properties := body["properties"].(map[string]interface{})
+	regions := properties["publishingProfile"].(map[string]interface{})["targetRegions"]
+	expected := []interface{}{
+		map[string]interface{}{"name": "westeurope"},
+		map[string]interface{}{"name": "northeurope"},
+	}
+	if !reflect.DeepEqual(regions, expected) {
+		t.Fatalf("bad regions: %#v", regions)
+	}
+	source := properties["storageProfile"].(map[string]interface{})["source"]
+	if !reflect.DeepEqual(source, map[string]interface{}{"id": "/images/ubuntu"}) {
+		t.Fatalf("bad source: %#v", source)
+	}
+}
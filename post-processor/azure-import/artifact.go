@@ -0,0 +1,64 @@
+package azureimport
+
+import (
+	"fmt"
+)
+
+const BuilderId = "packer.post-processor.azure-import"
+
+// Artifact is the uploaded VHD, in a blob or a managed disk, and the managed
+// image and gallery image version created from it, if any.
+type Artifact struct {
+	BlobURI               string
+	ManagedDiskID         string
+	ManagedImageID        string
+	ManagedImageName      string
+	GalleryImageVersionID string
+	Location              string
+}
+
+func (*Artifact) BuilderId() string {
+	return BuilderId
+}
+
+func (*Artifact) Files() []string {
+	return nil
+}
+
+func (a *Artifact) Id() string {
+	switch {
+	case a.GalleryImageVersionID != "":
+		return a.GalleryImageVersionID
+	case a.ManagedImageID != "":
+		return a.ManagedImageID
+	}
+	return a.source()
+}
+
+func (a *Artifact) String() string {
+	switch {
+	case a.GalleryImageVersionID != "":
+		return fmt.Sprintf("Gallery image version %s created from managed image %s in %s",
+			a.GalleryImageVersionID, a.ManagedImageName, a.Location)
+	case a.ManagedImageID != "":
+		return fmt.Sprintf("Managed image %s in %s created from %s",
+			a.ManagedImageName, a.Location, a.source())
+	}
+	return fmt.Sprintf("VHD uploaded to %s", a.source())
+}
+
+// source is where the VHD was uploaded to.
+func (a *Artifact) source() string {
+	if a.ManagedDiskID != "" {
+		return a.ManagedDiskID
+	}
+	return a.BlobURI
+}
+
+func (*Artifact) State(name string) interface{} {
+	return nil
+}
+
+func (*Artifact) Destroy() error {
+	return nil
+}
@@ -0,0 +1,479 @@
+package azureimport
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/services/compute/mgmt/2018-04-01/compute"
+	armStorage "github.com/Azure/azure-sdk-for-go/services/storage/mgmt/2017-10-01/storage"
+	"github.com/Azure/azure-sdk-for-go/storage"
+	"github.com/Azure/go-autorest/autorest"
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/helper/config"
+	"github.com/hashicorp/packer/helper/useragent"
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+// SharedImageGalleryDestination is the gallery image a version is created
+// of from the managed image.
+type SharedImageGalleryDestination struct {
+	ResourceGroup      string   `mapstructure:"resource_group"`
+	GalleryName        string   `mapstructure:"gallery_name"`
+	ImageName          string   `mapstructure:"image_name"`
+	ImageVersion       string   `mapstructure:"image_version"`
+	ReplicationRegions []string `mapstructure:"replication_regions"`
+}
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+
+	// Authentication
+	SubscriptionID       string `mapstructure:"subscription_id"`
+	ClientID             string `mapstructure:"client_id"`
+	ClientSecret         string `mapstructure:"client_secret"`
+	TenantID             string `mapstructure:"tenant_id"`
+	CloudEnvironmentName string `mapstructure:"cloud_environment_name"`
+
+	// Where the VHD is uploaded
+	ResourceGroupName string `mapstructure:"resource_group_name"`
+	StorageAccount    string `mapstructure:"storage_account"`
+	StorageContainer  string `mapstructure:"storage_container"`
+	BlobName          string `mapstructure:"blob_name"`
+
+	// The managed disk the VHD is uploaded to instead of a storage account
+	ManagedDiskName string `mapstructure:"managed_disk_name"`
+
+	// The managed image created from the VHD
+	ManagedImageName              string `mapstructure:"managed_image_name"`
+	ManagedImageResourceGroupName string `mapstructure:"managed_image_resource_group_name"`
+	Location                      string `mapstructure:"location"`
+	OSType                        string `mapstructure:"os_type"`
+
+	// The gallery image version created from the managed image
+	SharedImageGalleryDestination SharedImageGalleryDestination `mapstructure:"shared_image_gallery_destination"`
+
+	DiskFormat string `mapstructure:"disk_format"`
+	Keep       bool   `mapstructure:"keep_input_artifact"`
+
+	cloud *azure.Environment
+	ctx   interpolate.Context
+}
+
+type PostProcessor struct {
+	config Config
+}
+
+func (p *PostProcessor) Configure(raws ...interface{}) error {
+	err := config.Decode(&p.config, &config.DecodeOpts{
+		Interpolate:        true,
+		InterpolateContext: &p.config.ctx,
+		InterpolateFilter: &interpolate.RenderFilter{
+			Exclude: []string{
+				"blob_name",
+			},
+		},
+	}, raws...)
+	if err != nil {
+		return err
+	}
+
+	// Set defaults
+	if p.config.CloudEnvironmentName == "" {
+		p.config.CloudEnvironmentName = "Public"
+	}
+	if p.config.StorageContainer == "" {
+		p.config.StorageContainer = "images"
+	}
+	if p.config.BlobName == "" {
+		p.config.BlobName = "packer-import-{{timestamp}}.vhd"
+	}
+	if p.config.ManagedImageResourceGroupName == "" {
+		p.config.ManagedImageResourceGroupName = p.config.ResourceGroupName
+	}
+
+	errs := new(packer.MultiError)
+
+	if err = interpolate.Validate(p.config.BlobName, &p.config.ctx); err != nil {
+		errs = packer.MultiErrorAppend(
+			errs, fmt.Errorf("Error parsing blob_name template: %s", err))
+	}
+
+	templates := map[string]*string{
+		"subscription_id":     &p.config.SubscriptionID,
+		"resource_group_name": &p.config.ResourceGroupName,
+	}
+	for key, ptr := range templates {
+		if *ptr == "" {
+			errs = packer.MultiErrorAppend(
+				errs, fmt.Errorf("%s must be set", key))
+		}
+	}
+
+	if (p.config.StorageAccount == "") == (p.config.ManagedDiskName == "") {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf(
+			"one of storage_account or managed_disk_name must be set"))
+	}
+
+	if p.config.ClientID != "" && (p.config.ClientSecret == "" || p.config.TenantID == "") {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf(
+			"client_secret and tenant_id must be set when client_id is set"))
+	}
+
+	for key, set := range map[string]bool{
+		"managed_image_name": p.config.ManagedImageName != "",
+		"managed_disk_name":  p.config.ManagedDiskName != "",
+	} {
+		if !set {
+			continue
+		}
+		if p.config.Location == "" {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf(
+				"location must be set when %s is set", key))
+		}
+		switch compute.OperatingSystemTypes(p.config.OSType) {
+		case compute.Linux, compute.Windows:
+		default:
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf(
+				"os_type must be 'Linux' or 'Windows' when %s is set", key))
+		}
+	}
+
+	if sig := p.config.SharedImageGalleryDestination; sig.GalleryName != "" {
+		if p.config.ManagedImageName == "" {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf(
+				"managed_image_name must be set when shared_image_gallery_destination is set"))
+		}
+		for key, value := range map[string]string{
+			"resource_group": sig.ResourceGroup,
+			"image_name":     sig.ImageName,
+			"image_version":  sig.ImageVersion,
+		} {
+			if value == "" {
+				errs = packer.MultiErrorAppend(errs, fmt.Errorf(
+					"shared_image_gallery_destination.%s must be set", key))
+			}
+		}
+	}
+
+	switch p.config.DiskFormat {
+	case "", "raw", "qcow2", "vmdk", "vpc", "vhdx":
+	default:
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf(
+			"invalid disk_format '%s'. Only 'raw', 'qcow2', 'vmdk', 'vpc' or 'vhdx' are allowed",
+			p.config.DiskFormat))
+	}
+
+	env, err := cloudEnvironment(p.config.CloudEnvironmentName)
+	if err != nil {
+		errs = packer.MultiErrorAppend(errs, err)
+	}
+	p.config.cloud = env
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	packer.LogSecretFilter.Set(p.config.ClientSecret)
+	return nil
+}
+
+func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (packer.Artifact, bool, error) {
+	blobName, err := interpolate.Render(p.config.BlobName, &p.config.ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("Error rendering blob_name template: %s", err)
+	}
+
+	source, format, err := findDisk(artifact, p.config.DiskFormat)
+	if err != nil {
+		return nil, false, err
+	}
+
+	vhd := source
+	if format != "" {
+		vhd = strings.TrimSuffix(source, filepath.Ext(source)) + "-fixed.vhd"
+		ui.Message(fmt.Sprintf("Converting %s to a fixed size VHD...", source))
+		if err := convertToFixedVHD(source, format, vhd); err != nil {
+			return nil, false, fmt.Errorf("Error converting %s: %s", source, err)
+		}
+		defer os.Remove(vhd)
+	}
+
+	disk, err := openFixedVHD(vhd)
+	if err != nil {
+		return nil, false, err
+	}
+	defer disk.Close()
+	if disk.Padded() {
+		ui.Message(fmt.Sprintf("Padding %s to a multiple of 1 MiB, as Azure requires", vhd))
+	}
+
+	spt, err := p.servicePrincipalToken()
+	if err != nil {
+		return nil, false, err
+	}
+	authorizer := autorest.NewBearerAuthorizer(spt)
+	arm := newARMClient(p.config.cloud.ResourceManagerEndpoint, p.config.SubscriptionID, authorizer)
+	ctx := context.TODO()
+
+	result := &Artifact{}
+	osDisk := &compute.ImageOSDisk{
+		OsType:  compute.OperatingSystemTypes(p.config.OSType),
+		OsState: compute.Generalized,
+	}
+	if p.config.ManagedDiskName != "" {
+		result.ManagedDiskID, err = p.uploadToManagedDisk(ctx, ui, arm, disk, vhd)
+		if err != nil {
+			return nil, false, err
+		}
+		osDisk.ManagedDisk = &compute.SubResource{ID: to.StringPtr(result.ManagedDiskID)}
+	} else {
+		blob, err := p.blobReference(authorizer, blobName)
+		if err != nil {
+			return nil, false, err
+		}
+
+		ui.Message(fmt.Sprintf("Uploading %s to %s...", vhd, blob.GetURL()))
+		blob.Properties.ContentLength = disk.Size()
+		if err := blob.PutPageBlob(nil); err != nil {
+			return nil, false, fmt.Errorf("Error creating page blob: %s", err)
+		}
+		if err := uploadVHD(ui, blob, disk, vhd); err != nil {
+			return nil, false, err
+		}
+		result.BlobURI = blob.GetURL()
+		osDisk.BlobURI = to.StringPtr(result.BlobURI)
+	}
+
+	if p.config.ManagedImageName == "" {
+		return result, p.config.Keep, nil
+	}
+
+	ui.Message(fmt.Sprintf("Creating managed image %s in %s...",
+		p.config.ManagedImageName, p.config.ManagedImageResourceGroupName))
+	client := compute.NewImagesClientWithBaseURI(p.config.cloud.ResourceManagerEndpoint, p.config.SubscriptionID)
+	client.Authorizer = authorizer
+	client.UserAgent = fmt.Sprintf("%s %s", useragent.String(), client.UserAgent)
+
+	image := compute.Image{
+		Location: to.StringPtr(p.config.Location),
+		ImageProperties: &compute.ImageProperties{
+			StorageProfile: &compute.ImageStorageProfile{
+				OsDisk: osDisk,
+			},
+		},
+	}
+	future, err := client.CreateOrUpdate(ctx, p.config.ManagedImageResourceGroupName, p.config.ManagedImageName, image)
+	if err == nil {
+		err = future.WaitForCompletionRef(ctx, client.Client)
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("Error creating managed image %s: %s", p.config.ManagedImageName, err)
+	}
+
+	created, err := future.Result(client)
+	if err != nil {
+		return nil, false, fmt.Errorf("Error creating managed image %s: %s", p.config.ManagedImageName, err)
+	}
+
+	result.ManagedImageID = to.String(created.ID)
+	result.ManagedImageName = p.config.ManagedImageName
+	result.Location = p.config.Location
+
+	if sig := p.config.SharedImageGalleryDestination; sig.GalleryName != "" {
+		ui.Message(fmt.Sprintf("Creating version %s of gallery image %s in %s...",
+			sig.ImageVersion, sig.ImageName, sig.GalleryName))
+		result.GalleryImageVersionID, err = arm.createGalleryImageVersion(ctx, sig, p.config.Location, result.ManagedImageID)
+		if err != nil {
+			return nil, false, fmt.Errorf("Error creating version %s of gallery image %s: %s",
+				sig.ImageVersion, sig.ImageName, err)
+		}
+	}
+	return result, p.config.Keep, nil
+}
+
+// uploadToManagedDisk uploads the VHD to a new managed disk through the SAS
+// URL of the disk, and returns the ID of the disk. The disk is deleted if
+// the upload fails.
+func (p *PostProcessor) uploadToManagedDisk(ctx context.Context, ui packer.Ui, arm *armClient, disk *fixedVHD, path string) (string, error) {
+	rg, name := p.config.ResourceGroupName, p.config.ManagedDiskName
+
+	ui.Message(fmt.Sprintf("Creating managed disk %s in %s...", name, rg))
+	if err := arm.createUploadDisk(ctx, rg, name, p.config.Location, p.config.OSType, disk.Size()); err != nil {
+		return "", fmt.Errorf("Error creating managed disk %s: %s", name, err)
+	}
+
+	err := func() error {
+		sas, err := arm.grantDiskWrite(ctx, rg, name)
+		if err != nil {
+			return fmt.Errorf("Error getting the SAS URL of managed disk %s: %s", name, err)
+		}
+
+		ui.Message(fmt.Sprintf("Uploading %s to managed disk %s...", path, name))
+		uploadErr := uploadVHD(ui, &sasPageWriter{url: sas, client: http.DefaultClient}, disk, path)
+
+		// The disk can't be used, or deleted, until its SAS URL is revoked
+		if err := arm.revokeDiskAccess(ctx, rg, name); err != nil && uploadErr == nil {
+			return fmt.Errorf("Error revoking the SAS URL of managed disk %s: %s", name, err)
+		}
+		return uploadErr
+	}()
+	if err != nil {
+		ui.Message(fmt.Sprintf("Deleting managed disk %s...", name))
+		if derr := arm.deleteDisk(ctx, rg, name); derr != nil {
+			ui.Error(fmt.Sprintf("Error deleting managed disk %s: %s", name, derr))
+		}
+		return "", err
+	}
+
+	return arm.managedDiskID(rg, name), nil
+}
+
+// servicePrincipalToken authenticates with the configured client or,
+// without one, with the managed identity of the machine.
+func (p *PostProcessor) servicePrincipalToken() (*adal.ServicePrincipalToken, error) {
+	resource := p.config.cloud.ResourceManagerEndpoint
+
+	var spt *adal.ServicePrincipalToken
+	var err error
+	if p.config.ClientID == "" {
+		spt, err = adal.NewServicePrincipalTokenFromMSI(
+			"http://169.254.169.254/metadata/identity/oauth2/token", resource)
+	} else {
+		var oauthConfig *adal.OAuthConfig
+		oauthConfig, err = adal.NewOAuthConfig(p.config.cloud.ActiveDirectoryEndpoint, p.config.TenantID)
+		if err == nil {
+			spt, err = adal.NewServicePrincipalToken(
+				*oauthConfig, p.config.ClientID, p.config.ClientSecret, resource)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error authenticating to Azure: %s", err)
+	}
+
+	if err := spt.EnsureFresh(); err != nil {
+		return nil, fmt.Errorf("Error authenticating to Azure: %s", err)
+	}
+	return spt, nil
+}
+
+func (p *PostProcessor) blobReference(authorizer autorest.Authorizer, name string) (*storage.Blob, error) {
+	accounts := armStorage.NewAccountsClientWithBaseURI(p.config.cloud.ResourceManagerEndpoint, p.config.SubscriptionID)
+	accounts.Authorizer = authorizer
+	accounts.UserAgent = fmt.Sprintf("%s %s", useragent.String(), accounts.UserAgent)
+
+	keys, err := accounts.ListKeys(context.TODO(), p.config.ResourceGroupName, p.config.StorageAccount)
+	if err != nil {
+		return nil, fmt.Errorf("Error getting the keys of storage account %s: %s", p.config.StorageAccount, err)
+	}
+	if keys.Keys == nil || len(*keys.Keys) == 0 {
+		return nil, fmt.Errorf("Storage account %s has no keys", p.config.StorageAccount)
+	}
+
+	client, err := storage.NewClient(
+		p.config.StorageAccount,
+		to.String((*keys.Keys)[0].Value),
+		p.config.cloud.StorageEndpointSuffix,
+		storage.DefaultAPIVersion,
+		true /*useHttps*/)
+	if err != nil {
+		return nil, err
+	}
+
+	blobService := client.GetBlobService()
+	container := blobService.GetContainerReference(p.config.StorageContainer)
+	if _, err := container.CreateIfNotExists(nil); err != nil {
+		return nil, fmt.Errorf("Error creating container %s: %s", p.config.StorageContainer, err)
+	}
+
+	return container.GetBlobReference(name), nil
+}
+
+// uploadVHD uploads the padded VHD with w, to a page blob of its size.
+func uploadVHD(ui packer.Ui, w pageWriter, disk *fixedVHD, path string) error {
+	lastPercent := -1
+	err := uploadPages(w, disk.Reader(), func(written, skipped int64) {
+		percent := int((written + skipped) * 100 / disk.Size())
+		if percent/10 != lastPercent/10 {
+			log.Printf("Uploaded %d%% of %s, %d bytes skipped as empty", percent, path, skipped)
+			lastPercent = percent
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("Error uploading %s: %s", path, err)
+	}
+
+	ui.Message(fmt.Sprintf("Completed upload of %s", path))
+	return nil
+}
+
+// findDisk returns the disk of the artifact and the qemu-img format it has
+// to be converted from, or "" if it already is a fixed VHD.
+func findDisk(artifact packer.Artifact, format string) (string, string, error) {
+	// The qemu builder records which of its files is the disk.
+	if name, ok := artifact.State("diskName").(string); ok && name != "" {
+		for _, path := range artifact.Files() {
+			if filepath.Base(path) == name {
+				if format == "" {
+					format, _ = artifact.State("diskType").(string)
+				}
+				return path, format, nil
+			}
+		}
+		return "", "", fmt.Errorf("Disk %s not found in artifact", name)
+	}
+
+	for _, path := range artifact.Files() {
+		detected := ""
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".vhd":
+			fixed, err := isFixedVHD(path)
+			if err != nil {
+				return "", "", err
+			}
+			if !fixed {
+				detected = "vpc"
+			}
+		case ".vhdx":
+			detected = "vhdx"
+		case ".vmdk":
+			detected = "vmdk"
+		case ".qcow2":
+			detected = "qcow2"
+		case ".raw", ".img":
+			detected = "raw"
+		default:
+			continue
+		}
+		if format == "" {
+			format = detected
+		}
+		return path, format, nil
+	}
+
+	return "", "", fmt.Errorf("No disk image found in artifact from %s", artifact.BuilderId())
+}
+
+func cloudEnvironment(name string) (*azure.Environment, error) {
+	envName := strings.ToUpper(name)
+	if !strings.HasPrefix(envName, "AZURE") {
+		envName = "AZURE" + envName
+	}
+	if !strings.HasSuffix(envName, "CLOUD") {
+		envName = envName + "CLOUD"
+	}
+
+	env, err := azure.EnvironmentFromName(envName)
+	if err != nil {
+		return nil, fmt.Errorf("There is no cloud environment matching the name '%s'!", name)
+	}
+	return &env, nil
+}
@@ -0,0 +1,140 @@
+package azureimport
+
+import (
+	"testing"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+func testConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"subscription_id":     "00000000-0000-0000-0000-000000000000",
+		"resource_group_name": "packer",
+		"storage_account":     "packerimages",
+	}
+}
+
+func TestPostProcessor_ImplementsPostProcessor(t *testing.T) {
+	var _ packer.PostProcessor = new(PostProcessor)
+}
+
+func TestArtifact_ImplementsArtifact(t *testing.T) {
+	var _ packer.Artifact = new(Artifact)
+}
+
+func TestPostProcessorConfigure(t *testing.T) {
+	var p PostProcessor
+	if err := p.Configure(testConfig()); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if p.config.StorageContainer != "images" {
+		t.Errorf("bad container: %s", p.config.StorageContainer)
+	}
+	if p.config.ManagedImageResourceGroupName != "packer" {
+		t.Errorf("bad managed image resource group: %s", p.config.ManagedImageResourceGroupName)
+	}
+	if p.config.cloud == nil || p.config.cloud.Name != "AzurePublicCloud" {
+		t.Errorf("bad cloud: %#v", p.config.cloud)
+	}
+}
+
+func TestPostProcessorConfigure_managedDisk(t *testing.T) {
+	c := testConfig()
+	delete(c, "storage_account")
+	c["managed_disk_name"] = "disk"
+	c["location"] = "westus"
+	c["os_type"] = "Linux"
+	c["managed_image_name"] = "foo"
+	c["shared_image_gallery_destination"] = map[string]interface{}{
+		"resource_group":      "rg",
+		"gallery_name":        "gallery",
+		"image_name":          "ubuntu",
+		"image_version":       "1.0.0",
+		"replication_regions": []string{"eastus"},
+	}
+
+	var p PostProcessor
+	if err := p.Configure(c); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if p.config.SharedImageGalleryDestination.ReplicationRegions[0] != "eastus" {
+		t.Fatalf("bad: %#v", p.config.SharedImageGalleryDestination)
+	}
+}
+
+func TestPostProcessorConfigure_invalid(t *testing.T) {
+	cases := []map[string]interface{}{
+		{"storage_account": ""},
+		{"client_id": "foo"},
+		{"managed_image_name": "foo", "os_type": "Linux"},
+		{"managed_image_name": "foo", "location": "westus", "os_type": "BSD"},
+		{"disk_format": "iso"},
+		{"cloud_environment_name": "Moon"},
+		{"managed_disk_name": "disk", "location": "westus", "os_type": "Linux"},
+		{"storage_account": "", "managed_disk_name": "disk", "os_type": "Linux"},
+		{"shared_image_gallery_destination": map[string]interface{}{
+			"resource_group": "rg",
+			"gallery_name":   "gallery",
+			"image_name":     "ubuntu",
+			"image_version":  "1.0.0",
+		}},
+		{"managed_image_name": "foo", "location": "westus", "os_type": "Linux",
+			"shared_image_gallery_destination": map[string]interface{}{
+				"gallery_name": "gallery",
+			}},
+	}
+
+	for _, tc := range cases {
+		c := testConfig()
+		for k, v := range tc {
+			c[k] = v
+		}
+
+		var p PostProcessor
+		if err := p.Configure(c); err == nil {
+			t.Errorf("%#v: should error", tc)
+		}
+	}
+}
+
+func TestFindDisk(t *testing.T) {
+	cases := []struct {
+		artifact *packer.MockArtifact
+		path     string
+		format   string
+	}{
+		{
+			artifact: &packer.MockArtifact{
+				FilesValue: []string{"out/disk.vhdx"},
+			},
+			path:   "out/disk.vhdx",
+			format: "vhdx",
+		},
+		{
+			artifact: &packer.MockArtifact{
+				FilesValue: []string{"out/packer-vm"},
+				StateValues: map[string]interface{}{
+					"diskName": "packer-vm",
+					"diskType": "qcow2",
+				},
+			},
+			path:   "out/packer-vm",
+			format: "qcow2",
+		},
+	}
+
+	for i, tc := range cases {
+		path, format, err := findDisk(tc.artifact, "")
+		if err != nil {
+			t.Fatalf("%d: %s", i, err)
+		}
+		if path != tc.path || format != tc.format {
+			t.Errorf("%d: got %s %s", i, path, format)
+		}
+	}
+
+	if _, _, err := findDisk(&packer.MockArtifact{FilesValue: []string{"foo.iso"}}, ""); err == nil {
+		t.Fatal("should error without a disk")
+	}
+}
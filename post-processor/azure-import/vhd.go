@@ -0,0 +1,271 @@
+package azureimport
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/storage"
+)
+
+// The size of the chunks written to the page blob. Put Page accepts at most
+// 4 MiB per request.
+const pageChunkSize = 4 * 1024 * 1024
+
+const (
+	vhdFooterSize   = 512
+	vhdFooterCookie = "conectix"
+	vhdTypeFixed    = 2
+)
+
+// vhdAlignment is what the virtual size of the VHDs Azure accepts must be
+// a multiple of.
+const vhdAlignment = 1024 * 1024
+
+// isFixedVHD reports whether the file at path is a fixed size VHD, which is
+// the only kind of VHD Azure accepts.
+func isFixedVHD(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+	if fi.Size() < vhdFooterSize {
+		return false, nil
+	}
+
+	footer := make([]byte, vhdFooterSize)
+	if _, err := f.ReadAt(footer, fi.Size()-vhdFooterSize); err != nil {
+		return false, err
+	}
+
+	if string(footer[:8]) != vhdFooterCookie {
+		return false, nil
+	}
+	return binary.BigEndian.Uint32(footer[60:64]) == vhdTypeFixed, nil
+}
+
+// fixedVHD is a fixed size VHD to upload, padded with zeros to the size
+// Azure accepts.
+type fixedVHD struct {
+	f *os.File
+
+	// data is the virtual size of the VHD in the file, size the padded
+	// one, and footer the footer for the padded size.
+	data   int64
+	size   int64
+	footer []byte
+}
+
+// openFixedVHD opens the fixed size VHD at path.
+func openFixedVHD(path string) (*fixedVHD, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if fi.Size() < vhdFooterSize || fi.Size()%512 != 0 {
+		f.Close()
+		return nil, fmt.Errorf("%s is not a fixed size VHD", path)
+	}
+
+	footer := make([]byte, vhdFooterSize)
+	if _, err := f.ReadAt(footer, fi.Size()-vhdFooterSize); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if string(footer[:8]) != vhdFooterCookie || binary.BigEndian.Uint32(footer[60:64]) != vhdTypeFixed {
+		f.Close()
+		return nil, fmt.Errorf("%s is not a fixed size VHD", path)
+	}
+
+	v := &fixedVHD{
+		f:      f,
+		data:   fi.Size() - vhdFooterSize,
+		footer: footer,
+	}
+	v.size = (v.data + vhdAlignment - 1) / vhdAlignment * vhdAlignment
+	if v.size != v.data {
+		setVHDSize(v.footer, v.size)
+	}
+	return v, nil
+}
+
+// Size is the size of the padded VHD, with its footer.
+func (v *fixedVHD) Size() int64 {
+	return v.size + vhdFooterSize
+}
+
+// Padded says whether the VHD is padded.
+func (v *fixedVHD) Padded() bool {
+	return v.size != v.data
+}
+
+// Reader returns the padded VHD.
+func (v *fixedVHD) Reader() io.Reader {
+	return io.MultiReader(
+		io.NewSectionReader(v.f, 0, v.data),
+		io.LimitReader(zeroReader{}, v.size-v.data),
+		bytes.NewReader(v.footer))
+}
+
+func (v *fixedVHD) Close() error {
+	return v.f.Close()
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
+
+// setVHDSize sets the size of the disk in the footer, with its geometry
+// and checksum.
+func setVHDSize(footer []byte, size int64) {
+	binary.BigEndian.PutUint64(footer[40:48], uint64(size))
+	binary.BigEndian.PutUint64(footer[48:56], uint64(size))
+
+	cylinders, heads, sectors := vhdGeometry(size)
+	binary.BigEndian.PutUint16(footer[56:58], cylinders)
+	footer[58] = heads
+	footer[59] = sectors
+
+	var sum uint32
+	for i, b := range footer {
+		if i < 64 || i >= 68 {
+			sum += uint32(b)
+		}
+	}
+	binary.BigEndian.PutUint32(footer[64:68], ^sum)
+}
+
+// vhdGeometry is the CHS geometry of a disk of the size, as the VHD
+// specification computes it.
+func vhdGeometry(size int64) (cylinders uint16, heads, sectors uint8) {
+	totalSectors := size / 512
+	if totalSectors > 65535*16*255 {
+		totalSectors = 65535 * 16 * 255
+	}
+
+	var sectorsPerTrack, headCount, cylinderTimesHeads int64
+	if totalSectors >= 65535*16*63 {
+		sectorsPerTrack = 255
+		headCount = 16
+		cylinderTimesHeads = totalSectors / sectorsPerTrack
+	} else {
+		sectorsPerTrack = 17
+		cylinderTimesHeads = totalSectors / sectorsPerTrack
+		headCount = (cylinderTimesHeads + 1023) / 1024
+		if headCount < 4 {
+			headCount = 4
+		}
+		if cylinderTimesHeads >= headCount*1024 || headCount > 16 {
+			sectorsPerTrack = 31
+			headCount = 16
+			cylinderTimesHeads = totalSectors / sectorsPerTrack
+		}
+		if cylinderTimesHeads >= headCount*1024 {
+			sectorsPerTrack = 63
+			headCount = 16
+			cylinderTimesHeads = totalSectors / sectorsPerTrack
+		}
+	}
+
+	return uint16(cylinderTimesHeads / headCount), uint8(headCount), uint8(sectorsPerTrack)
+}
+
+// convertToFixedVHD converts src to a fixed size VHD at dst using qemu-img.
+func convertToFixedVHD(src, srcFormat, dst string) error {
+	args := []string{"convert", "-f", srcFormat, "-O", "vpc", "-o", "subformat=fixed,force_size", src, dst}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("qemu-img", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	log.Printf("Executing qemu-img: %#v", args)
+	err := cmd.Run()
+
+	stdoutString := strings.TrimSpace(stdout.String())
+	stderrString := strings.TrimSpace(stderr.String())
+
+	if _, ok := err.(*exec.ExitError); ok {
+		err = fmt.Errorf("qemu-img error: %s", stderrString)
+	}
+
+	log.Printf("stdout: %s", stdoutString)
+	log.Printf("stderr: %s", stderrString)
+
+	return err
+}
+
+type pageWriter interface {
+	WriteRange(storage.BlobRange, io.Reader, *storage.PutPageOptions) error
+}
+
+// uploadPages writes r to a page blob that was created with the size of r.
+// Chunks that are all zeros are skipped since a new page blob reads as
+// zeros, which keeps the upload of a mostly empty fixed VHD small.
+func uploadPages(w pageWriter, r io.Reader, progress func(written, skipped int64)) error {
+	buf := make([]byte, pageChunkSize)
+	var offset, written, skipped int64
+
+	for {
+		n, err := io.ReadFull(r, buf)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		if n%512 != 0 {
+			return fmt.Errorf("VHD size is not a multiple of 512 bytes")
+		}
+
+		chunk := buf[:n]
+		if isZero(chunk) {
+			skipped += int64(n)
+		} else {
+			blobRange := storage.BlobRange{
+				Start: uint64(offset),
+				End:   uint64(offset + int64(n) - 1),
+			}
+			if err := w.WriteRange(blobRange, bytes.NewReader(chunk), nil); err != nil {
+				return fmt.Errorf("Error writing bytes %d-%d: %s", blobRange.Start, blobRange.End, err)
+			}
+			written += int64(n)
+		}
+		offset += int64(n)
+
+		if progress != nil {
+			progress(written, skipped)
+		}
+	}
+}
+
+func isZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,188 @@
+package azureimport
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/storage"
+)
+
+type mockPageWriter struct {
+	ranges []storage.BlobRange
+	data   []byte
+}
+
+func (w *mockPageWriter) WriteRange(r storage.BlobRange, b io.Reader, _ *storage.PutPageOptions) error {
+	w.ranges = append(w.ranges, r)
+	data, err := ioutil.ReadAll(b)
+	w.data = append(w.data, data...)
+	return err
+}
+
+func TestUploadPages(t *testing.T) {
+	// One empty chunk between two chunks with data, the last one short.
+	image := make([]byte, 2*pageChunkSize+1024)
+	image[0] = 1
+	image[2*pageChunkSize+10] = 2
+
+	w := new(mockPageWriter)
+	var written, skipped int64
+	err := uploadPages(w, bytes.NewReader(image), func(w, s int64) {
+		written, skipped = w, s
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []storage.BlobRange{
+		{Start: 0, End: pageChunkSize - 1},
+		{Start: 2 * pageChunkSize, End: 2*pageChunkSize + 1023},
+	}
+	if !reflect.DeepEqual(w.ranges, expected) {
+		t.Fatalf("bad ranges: %#v", w.ranges)
+	}
+	if written != pageChunkSize+1024 || skipped != pageChunkSize {
+		t.Fatalf("bad progress: %d written, %d skipped", written, skipped)
+	}
+	if len(w.data) != pageChunkSize+1024 || w.data[pageChunkSize+10] != 2 {
+		t.Fatal("bad data written")
+	}
+}
+
+func TestUploadPages_unaligned(t *testing.T) {
+	err := uploadPages(new(mockPageWriter), bytes.NewReader([]byte{1, 2, 3}), nil)
+	if err == nil {
+		t.Fatal("should error")
+	}
+}
+
+func TestIsFixedVHD(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer-azure-import")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeVHD := func(name string, diskType uint32) string {
+		footer := make([]byte, vhdFooterSize)
+		copy(footer, vhdFooterCookie)
+		binary.BigEndian.PutUint32(footer[60:64], diskType)
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, append(make([]byte, 1024), footer...), 0644); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		return path
+	}
+
+	cases := map[string]bool{
+		writeVHD("fixed.vhd", vhdTypeFixed): true,
+		writeVHD("dynamic.vhd", 3):          false,
+	}
+
+	raw := filepath.Join(dir, "disk.raw")
+	if err := ioutil.WriteFile(raw, make([]byte, 2048), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	cases[raw] = false
+
+	for path, expected := range cases {
+		fixed, err := isFixedVHD(path)
+		if err != nil {
+			t.Fatalf("%s: %s", path, err)
+		}
+		if fixed != expected {
+			t.Errorf("%s: expected %t", path, expected)
+		}
+	}
+}
+
+func TestOpenFixedVHD(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer-azure-import")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeVHD := func(name string, size int) string {
+		footer := make([]byte, vhdFooterSize)
+		copy(footer, vhdFooterCookie)
+		binary.BigEndian.PutUint32(footer[60:64], vhdTypeFixed)
+		setVHDSize(footer, int64(size))
+		data := make([]byte, size)
+		data[0] = 1
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, append(data, footer...), 0644); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		return path
+	}
+
+	// An aligned VHD is uploaded as it is
+	aligned := writeVHD("aligned.vhd", vhdAlignment)
+	disk, err := openFixedVHD(aligned)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer disk.Close()
+	expected, _ := ioutil.ReadFile(aligned)
+	actual, _ := ioutil.ReadAll(disk.Reader())
+	if disk.Padded() || disk.Size() != int64(len(expected)) || !bytes.Equal(actual, expected) {
+		t.Fatalf("the VHD should not change")
+	}
+
+	// Others are padded to the next MiB, with the footer for their size
+	disk, err = openFixedVHD(writeVHD("unaligned.vhd", 3*512))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer disk.Close()
+	actual, _ = ioutil.ReadAll(disk.Reader())
+	if !disk.Padded() || disk.Size() != vhdAlignment+vhdFooterSize || int64(len(actual)) != disk.Size() {
+		t.Fatalf("bad size: %d", len(actual))
+	}
+	if actual[0] != 1 || !isZero(actual[1:vhdAlignment]) {
+		t.Fatal("the data should be padded with zeros")
+	}
+	footer := actual[vhdAlignment:]
+	if size := binary.BigEndian.Uint64(footer[48:56]); size != vhdAlignment {
+		t.Fatalf("bad size in the footer: %d", size)
+	}
+	var sum uint32
+	for i, b := range footer {
+		if i < 64 || i >= 68 {
+			sum += uint32(b)
+		}
+	}
+	if binary.BigEndian.Uint32(footer[64:68]) != ^sum {
+		t.Fatal("bad checksum")
+	}
+
+	if _, err := openFixedVHD(filepath.Join(dir, "missing.vhd")); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+func TestVHDGeometry(t *testing.T) {
+	cases := []struct {
+		size      int64
+		cylinders uint16
+		heads     uint8
+		sectors   uint8
+	}{
+		{vhdAlignment, 30, 4, 17},
+		{1024 * vhdAlignment, 2080, 16, 63},
+		{1024 * 1024 * vhdAlignment, 65535, 16, 255},
+	}
+	for _, tc := range cases {
+		c, h, s := vhdGeometry(tc.size)
+		if c != tc.cylinders || h != tc.heads || s != tc.sectors {
+			t.Errorf("%d: bad geometry %d/%d/%d", tc.size, c, h, s)
+		}
+	}
+}
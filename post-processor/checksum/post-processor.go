@@ -10,9 +10,11 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/hashicorp/packer/common"
 	"github.com/hashicorp/packer/helper/config"
+	"github.com/hashicorp/packer/helper/signing"
 	"github.com/hashicorp/packer/packer"
 	"github.com/hashicorp/packer/template/interpolate"
 )
@@ -20,10 +22,15 @@ import (
 type Config struct {
 	common.PackerConfig `mapstructure:",squash"`
 
-	Keep          bool     `mapstructure:"keep_input_artifact"`
-	ChecksumTypes []string `mapstructure:"checksum_types"`
-	OutputPath    string   `mapstructure:"output"`
-	ctx           interpolate.Context
+	Keep           bool     `mapstructure:"keep_input_artifact"`
+	ChecksumTypes  []string `mapstructure:"checksum_types"`
+	ChecksumFormat string   `mapstructure:"checksum_format"`
+	OutputPath     string   `mapstructure:"output"`
+
+	// Detached signature over each checksum file
+	signing.Config `mapstructure:",squash"`
+
+	ctx interpolate.Context
 }
 
 type PostProcessor struct {
@@ -79,6 +86,21 @@ func (p *PostProcessor) Configure(raws ...interface{}) error {
 		}
 	}
 
+	switch p.config.ChecksumFormat {
+	case "":
+		p.config.ChecksumFormat = "default"
+	case "default", "coreutils", "bsd":
+	default:
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf(
+			"checksum_format must be one of 'default', 'coreutils' or 'bsd'"))
+	}
+
+	errs = packer.MultiErrorAppend(errs, p.config.Config.Prepare()...)
+	if p.config.Keyless() {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf(
+			"cosign_key must be set when sign_with is 'cosign'"))
+	}
+
 	if p.config.OutputPath == "" {
 		p.config.OutputPath = "packer_{{.BuildName}}_{{.BuilderType}}_{{.ChecksumType}}.checksum"
 	}
@@ -100,6 +122,10 @@ func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (pac
 	var h hash.Hash
 
 	newartifact := NewArtifact(artifact.Files())
+	// The checksum files written, in order, to sign each of them once,
+	// including the ones that existed before and were appended to
+	var checksumFiles []string
+	written := make(map[string]bool)
 	opTpl := &outputPathTemplate{
 		BuildName:   p.config.PackerBuildName,
 		BuilderType: p.config.PackerBuilderType,
@@ -119,6 +145,10 @@ func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (pac
 			if _, err := os.Stat(checksumFile); err != nil {
 				newartifact.files = append(newartifact.files, checksumFile)
 			}
+			if !written[checksumFile] {
+				written[checksumFile] = true
+				checksumFiles = append(checksumFiles, checksumFile)
+			}
 			if err := os.MkdirAll(filepath.Dir(checksumFile), os.FileMode(0755)); err != nil {
 				return nil, false, fmt.Errorf("unable to create dir: %s", err.Error())
 			}
@@ -138,11 +168,37 @@ func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (pac
 				return nil, false, fmt.Errorf("unable to compute %s hash for %s", ct, art)
 			}
 			fr.Close()
-			fw.WriteString(fmt.Sprintf("%x\t%s\n", h.Sum(nil), filepath.Base(art)))
+			fw.WriteString(formatChecksum(p.config.ChecksumFormat, ct, h.Sum(nil), filepath.Base(art)))
 			fw.Close()
 			h.Reset()
 		}
 	}
 
+	if p.config.SignWith != "" {
+		for _, checksumFile := range checksumFiles {
+			ui.Message(fmt.Sprintf("Signing %s with %s", checksumFile, p.config.SignWith))
+			sigs, err := p.config.SignFile(checksumFile)
+			if err != nil {
+				return nil, false, err
+			}
+			newartifact.files = append(newartifact.files, sigs...)
+		}
+	}
+
 	return newartifact, true, nil
 }
+
+// formatChecksum returns the line for one file in a checksum file.
+func formatChecksum(format string, checksumType string, sum []byte, name string) string {
+	switch format {
+	case "coreutils":
+		// As written by sha256sum and friends, and read by their --check
+		return fmt.Sprintf("%x  %s\n", sum, name)
+	case "bsd":
+		// As written by the BSD tools and by sha256sum --tag, which keeps
+		// several algorithms in one file unambiguous.
+		return fmt.Sprintf("%s (%s) = %x\n", strings.ToUpper(checksumType), name, sum)
+	default:
+		return fmt.Sprintf("%x\t%s\n", sum, name)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 
@@ -106,3 +108,133 @@ func testChecksum(t *testing.T, config string) packer.Artifact {
 
 	return artifactOut
 }
+
+func TestChecksumBSDFormat(t *testing.T) {
+	const config = `
+	{
+	    "post-processors": [
+	        {
+	            "type": "checksum",
+	            "checksum_types": ["sha1", "md5"],
+	            "checksum_format": "bsd",
+	            "output": "CHECKSUMS"
+	        }
+	    ]
+	}
+	`
+	artifact := testChecksum(t, config)
+	defer artifact.Destroy()
+
+	buf, err := ioutil.ReadFile("CHECKSUMS")
+	if err != nil {
+		t.Fatalf("Unable to read checksum file: %s", err)
+	}
+	expected := "SHA1 (package.txt) = d3486ae9136e7856bc42212385ea797094475802\n" +
+		"MD5 (package.txt) = 86fb269d190d2c85f6e0468ceca42a20\n"
+	if string(buf) != expected {
+		t.Errorf("Bad checksum file:\n%s", buf)
+	}
+}
+
+func TestFormatChecksum(t *testing.T) {
+	sum := []byte{0xab, 0xcd}
+	cases := map[string]string{
+		"default":   "abcd\tfoo\n",
+		"coreutils": "abcd  foo\n",
+		"bsd":       "SHA256 (foo) = abcd\n",
+	}
+	for format, expected := range cases {
+		if actual := formatChecksum(format, "sha256", sum, "foo"); actual != expected {
+			t.Errorf("%s: expected %q, got %q", format, expected, actual)
+		}
+	}
+}
+
+// fakeGPG signs a file by copying it to the signature, and logs the files
+// it signs.
+const fakeGPG = `#!/bin/sh
+while [ $# -gt 1 ]; do
+	case "$1" in
+	--output)
+		out="$2"
+		shift
+		;;
+	esac
+	shift
+done
+cat "$1" > "$out"
+echo "$1" >> "$(dirname "$0")/signed"
+`
+
+func TestChecksumSign_existingFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as gpg")
+	}
+
+	dir, err := ioutil.TempDir("", "packer-checksum")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "gpg"), []byte(fakeGPG), 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	// The checksums of a previous build, signed then
+	if err := ioutil.WriteFile("CHECKSUMS", []byte("old\tother.txt\n"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.Remove("CHECKSUMS")
+	defer os.Remove("CHECKSUMS.asc")
+
+	const config = `
+	{
+	    "post-processors": [
+	        {
+	            "type": "checksum",
+	            "checksum_types": ["sha1", "md5"],
+	            "output": "CHECKSUMS",
+	            "sign_with": "gpg"
+	        }
+	    ]
+	}
+	`
+	artifact := testChecksum(t, config)
+	defer artifact.Destroy()
+
+	sums, err := ioutil.ReadFile("CHECKSUMS")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	sig, err := ioutil.ReadFile("CHECKSUMS.asc")
+	if err != nil {
+		t.Fatalf("the existing checksum file should be signed: %s", err)
+	}
+	if !bytes.Equal(sig, sums) {
+		t.Fatalf("the signature is of an older checksum file:\n%s", sig)
+	}
+
+	signed, err := ioutil.ReadFile(filepath.Join(dir, "signed"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if string(signed) != "CHECKSUMS\n" {
+		t.Fatalf("should sign the checksum file once: %q", signed)
+	}
+}
+
+func TestConfigure_signWith(t *testing.T) {
+	var p PostProcessor
+	if err := p.Configure(map[string]interface{}{"sign_with": "pgp"}); err == nil {
+		t.Fatal("should error on unknown signer")
+	}
+	if err := p.Configure(map[string]interface{}{"sign_with": "cosign"}); err == nil {
+		t.Fatal("should require cosign_key")
+	}
+	if err := p.Configure(map[string]interface{}{"sign_with": "gpg"}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
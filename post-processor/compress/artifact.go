@@ -9,6 +9,9 @@ const BuilderId = "packer.post-processor.compress"
 
 type Artifact struct {
 	Path string
+
+	// Parts are the paths of the files the archive was split into, if any.
+	Parts []string
 }
 
 func (a *Artifact) BuilderId() string {
@@ -20,6 +23,9 @@ func (*Artifact) Id() string {
 }
 
 func (a *Artifact) Files() []string {
+	if len(a.Parts) > 0 {
+		return a.Parts
+	}
 	return []string{a.Path}
 }
 
@@ -32,5 +38,10 @@ func (*Artifact) State(name string) interface{} {
 }
 
 func (a *Artifact) Destroy() error {
-	return os.Remove(a.Path)
+	for _, path := range a.Files() {
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -0,0 +1,115 @@
+package compress
+
+import (
+	"bytes"
+	"io"
+)
+
+// parallelWriter compresses its input in fixed-size chunks, each chunk
+// independently and in parallel, and writes the compressed chunks to the
+// output in order. This is only valid for formats whose decoders accept a
+// concatenation of independently compressed streams, such as xz.
+type parallelWriter struct {
+	output    io.Writer
+	chunkSize int
+	newWriter func(io.Writer) (io.WriteCloser, error)
+
+	buf     []byte
+	pending chan chan parallelResult
+	done    chan error
+	closed  bool
+	err     error
+}
+
+type parallelResult struct {
+	data []byte
+	err  error
+}
+
+func newParallelWriter(output io.Writer, chunkSize, workers int,
+	newWriter func(io.Writer) (io.WriteCloser, error)) *parallelWriter {
+	if workers < 1 {
+		workers = 1
+	}
+	w := &parallelWriter{
+		output:    output,
+		chunkSize: chunkSize,
+		newWriter: newWriter,
+		buf:       make([]byte, 0, chunkSize),
+		// Bound the number of chunks in flight so memory use stays at
+		// roughly workers * chunkSize.
+		pending: make(chan chan parallelResult, workers),
+		done:    make(chan error, 1),
+	}
+	go w.drain()
+	return w
+}
+
+// drain writes compressed chunks to the output in the order they were
+// submitted.
+func (w *parallelWriter) drain() {
+	var err error
+	for result := range w.pending {
+		r := <-result
+		if err != nil {
+			continue
+		}
+		if r.err != nil {
+			err = r.err
+			continue
+		}
+		_, err = w.output.Write(r.data)
+	}
+	w.done <- err
+}
+
+func (w *parallelWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		space := w.chunkSize - len(w.buf)
+		if space > len(p) {
+			space = len(p)
+		}
+		w.buf = append(w.buf, p[:space]...)
+		p = p[space:]
+		if len(w.buf) == w.chunkSize {
+			w.flush()
+		}
+	}
+	return n, nil
+}
+
+func (w *parallelWriter) flush() {
+	chunk := w.buf
+	w.buf = make([]byte, 0, w.chunkSize)
+
+	result := make(chan parallelResult, 1)
+	w.pending <- result
+	go func() {
+		var out bytes.Buffer
+		cw, err := w.newWriter(&out)
+		if err == nil {
+			_, err = cw.Write(chunk)
+		}
+		if err == nil {
+			err = cw.Close()
+		}
+		result <- parallelResult{data: out.Bytes(), err: err}
+	}()
+}
+
+// Close compresses any buffered data and waits for all chunks to be
+// written. It does not close the output.
+func (w *parallelWriter) Close() error {
+	if w.closed {
+		return w.err
+	}
+	w.closed = true
+
+	if len(w.buf) > 0 {
+		w.flush()
+	}
+	close(w.pending)
+	w.err = <-w.done
+	return w.err
+}
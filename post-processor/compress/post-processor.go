@@ -4,6 +4,8 @@ import (
 	"archive/tar"
 	"archive/zip"
 	"compress/gzip"
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -12,10 +14,13 @@ import (
 	"runtime"
 
 	"github.com/biogo/hts/bgzf"
+	humanize "github.com/dustin/go-humanize"
 	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/common/outputstore"
 	"github.com/hashicorp/packer/helper/config"
 	"github.com/hashicorp/packer/packer"
 	"github.com/hashicorp/packer/template/interpolate"
+	"github.com/klauspost/compress/zstd"
 	"github.com/klauspost/pgzip"
 	"github.com/pierrec/lz4"
 	"github.com/ulikunitz/xz"
@@ -41,10 +46,15 @@ type Config struct {
 	Format            string `mapstructure:"format"`
 	CompressionLevel  int    `mapstructure:"compression_level"`
 	KeepInputArtifact bool   `mapstructure:"keep_input_artifact"`
+	Concurrency       int    `mapstructure:"concurrency"`
+	SplitSize         string `mapstructure:"split_size"`
+
+	OutputStore outputstore.Config `mapstructure:"output_store"`
 
 	// Derived fields
-	Archive   string
-	Algorithm string
+	Archive    string
+	Algorithm  string
+	splitBytes int64
 
 	ctx interpolate.Context
 }
@@ -76,14 +86,17 @@ func (p *PostProcessor) Configure(raws ...interface{}) error {
 		p.config.OutputPath = "packer_{{.BuildName}}_{{.BuilderType}}"
 	}
 
-	if p.config.CompressionLevel > pgzip.BestCompression {
-		p.config.CompressionLevel = pgzip.BestCompression
+	if p.config.Concurrency <= 0 {
+		p.config.Concurrency = runtime.GOMAXPROCS(-1)
 	}
-	// Technically 0 means "don't compress" but I don't know how to
-	// differentiate between "user entered zero" and "user entered nothing".
-	// Also, why bother creating a compressed file with zero compression?
-	if p.config.CompressionLevel == -1 || p.config.CompressionLevel == 0 {
-		p.config.CompressionLevel = pgzip.DefaultCompression
+
+	if p.config.SplitSize != "" {
+		size, err := humanize.ParseBytes(p.config.SplitSize)
+		if err != nil || size == 0 {
+			errs = packer.MultiErrorAppend(
+				errs, fmt.Errorf("Invalid split_size %q: expected a size such as 4GB", p.config.SplitSize))
+		}
+		p.config.splitBytes = int64(size)
 	}
 
 	if err = interpolate.Validate(p.config.OutputPath, &p.config.ctx); err != nil {
@@ -93,6 +106,25 @@ func (p *PostProcessor) Configure(raws ...interface{}) error {
 
 	p.config.detectFromFilename()
 
+	// The levels are on the scale of the algorithm, zstd has its own.
+	bestLevel, defaultLevel := pgzip.BestCompression, pgzip.DefaultCompression
+	if p.config.Algorithm == "zstd" {
+		bestLevel, defaultLevel = zstdBestCompression, zstdDefaultCompression
+	}
+	if p.config.CompressionLevel > bestLevel {
+		p.config.CompressionLevel = bestLevel
+	}
+	// Technically 0 means "don't compress" but I don't know how to
+	// differentiate between "user entered zero" and "user entered nothing".
+	// Also, why bother creating a compressed file with zero compression?
+	if p.config.CompressionLevel == -1 || p.config.CompressionLevel == 0 {
+		p.config.CompressionLevel = defaultLevel
+	}
+
+	for _, err := range p.config.OutputStore.Prepare() {
+		errs = packer.MultiErrorAppend(errs, err)
+	}
+
 	if len(errs.Errors) > 0 {
 		return errs
 	}
@@ -118,44 +150,99 @@ func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (pac
 	keep := p.config.KeepInputArtifact
 	newArtifact := &Artifact{Path: target}
 
-	if err = os.MkdirAll(filepath.Dir(target), os.FileMode(0755)); err != nil {
-		return nil, false, fmt.Errorf(
-			"Unable to create dir for archive %s: %s", target, err)
+	// The archive is streamed to the output store when it isn't kept on
+	// disk. Split parts are uploaded once they are all written.
+	store := &p.config.OutputStore
+	stream := !store.Empty() && !store.KeepLocal && p.config.splitBytes == 0
+
+	if !stream {
+		if err = os.MkdirAll(filepath.Dir(target), os.FileMode(0755)); err != nil {
+			return nil, false, fmt.Errorf(
+				"Unable to create dir for archive %s: %s", target, err)
+		}
 	}
-	outputFile, err := os.Create(target)
-	if err != nil {
-		return nil, false, fmt.Errorf(
-			"Unable to create archive %s: %s", target, err)
+
+	// Setup the file interface. If we're splitting, it writes each part to
+	// its own file, if we're streaming, it's the upload, otherwise it's just
+	// a file.
+	var outputFile io.WriteCloser
+	var splitter *splitWriter
+	var upload *outputstore.Writer
+	var uploadStore outputstore.Store
+	completed := false
+	switch {
+	case p.config.splitBytes > 0:
+		ui.Say(fmt.Sprintf("Splitting %s into parts of %s", target, p.config.SplitSize))
+		splitter = newSplitWriter(target, p.config.splitBytes)
+		outputFile = splitter
+	case stream:
+		ctx := context.TODO()
+		uploadStore, err = store.Store(ctx)
+		if err != nil {
+			return nil, false, fmt.Errorf("Error opening the output store: %s", err)
+		}
+		ui.Say(fmt.Sprintf("Streaming %s to %s", filepath.Base(target), store.URL))
+		upload = outputstore.NewWriter(ctx, uploadStore, filepath.Base(target))
+		outputFile = upload
+
+		// Don't finish the upload of an archive that failed
+		defer func() {
+			if !completed {
+				upload.Abort(errors.New("the archive failed"))
+			}
+		}()
+	default:
+		outputFile, err = os.Create(target)
+		if err != nil {
+			return nil, false, fmt.Errorf(
+				"Unable to create archive %s: %s", target, err)
+		}
 	}
-	defer outputFile.Close()
+	// The compressor and the file are closed once the archive is complete,
+	// to report their errors, and by the deferred calls otherwise.
+	closed := false
+	defer func() {
+		if !closed {
+			outputFile.Close()
+		}
+	}()
 
 	// Setup output interface. If we're using compression, output is a
 	// compression writer. Otherwise it's just a file.
 	var output io.WriteCloser
+	cores := p.config.Concurrency
 	switch p.config.Algorithm {
 	case "bgzf":
 		ui.Say(fmt.Sprintf("Using bgzf compression with %d cores for %s",
-			runtime.GOMAXPROCS(-1), target))
-		output, err = makeBGZFWriter(outputFile, p.config.CompressionLevel)
-		defer output.Close()
+			cores, target))
+		output, err = makeBGZFWriter(outputFile, p.config.CompressionLevel, cores)
 	case "lz4":
 		ui.Say(fmt.Sprintf("Using lz4 compression with %d cores for %s",
-			runtime.GOMAXPROCS(-1), target))
+			cores, target))
 		output, err = makeLZ4Writer(outputFile, p.config.CompressionLevel)
-		defer output.Close()
 	case "xz":
-		ui.Say(fmt.Sprintf("Using xz compression with 1 core for %s (library does not support MT)",
-			target))
-		output, err = makeXZWriter(outputFile)
-		defer output.Close()
+		ui.Say(fmt.Sprintf("Using xz compression with %d cores for %s",
+			cores, target))
+		output, err = makeXZWriter(outputFile, cores)
 	case "pgzip":
 		ui.Say(fmt.Sprintf("Using pgzip compression with %d cores for %s",
-			runtime.GOMAXPROCS(-1), target))
-		output, err = makePgzipWriter(outputFile, p.config.CompressionLevel)
-		defer output.Close()
+			cores, target))
+		output, err = makePgzipWriter(outputFile, p.config.CompressionLevel, cores)
+	case "zstd":
+		ui.Say(fmt.Sprintf("Using zstd compression with %d cores for %s",
+			cores, target))
+		output, err = makeZstdWriter(outputFile, p.config.CompressionLevel, cores)
 	default:
 		output = outputFile
 	}
+	if err != nil {
+		return nil, false, fmt.Errorf("Unable to create compressor for %s: %s", target, err)
+	}
+	defer func() {
+		if !closed && output != outputFile {
+			output.Close()
+		}
+	}()
 
 	compression := p.config.Algorithm
 	if compression == "" {
@@ -201,8 +288,41 @@ func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (pac
 		}
 	}
 
+	// Flush the compressor and file now so that errors are reported and all
+	// split parts are known.
+	completed = true
+	closed = true
+	if err := output.Close(); err != nil {
+		if output != outputFile {
+			outputFile.Close()
+		}
+		return nil, keep, fmt.Errorf("Failed to finish compressing %s: %s", target, err)
+	}
+	if output != outputFile {
+		if err := outputFile.Close(); err != nil {
+			return nil, keep, fmt.Errorf("Failed to close archive %s: %s", target, err)
+		}
+	}
+	if splitter != nil {
+		newArtifact.Parts = splitter.Parts()
+	}
+
+	if upload != nil {
+		ui.Say(fmt.Sprintf("Archive %s uploaded to %s", target, upload.URL()))
+		return outputstore.NewArtifact(newArtifact, uploadStore,
+			[]string{filepath.Base(target)}, []string{upload.URL()}, false), keep, nil
+	}
+
 	ui.Say(fmt.Sprintf("Archive %s completed", target))
 
+	if !store.Empty() {
+		uploaded, err := outputstore.Upload(ui, store, newArtifact)
+		if err != nil {
+			return nil, keep, err
+		}
+		return uploaded, keep, nil
+	}
+
 	return newArtifact, keep, nil
 }
 
@@ -216,6 +336,7 @@ func (config *Config) detectFromFilename() {
 		"lz4":  "lz4",
 		"bgzf": "bgzf",
 		"xz":   "xz",
+		"zst":  "zstd",
 	}
 
 	if config.Format == "" {
@@ -264,8 +385,8 @@ func (config *Config) detectFromFilename() {
 	return
 }
 
-func makeBGZFWriter(output io.WriteCloser, compressionLevel int) (io.WriteCloser, error) {
-	bgzfWriter, err := bgzf.NewWriterLevel(output, compressionLevel, runtime.GOMAXPROCS(-1))
+func makeBGZFWriter(output io.WriteCloser, compressionLevel int, cores int) (io.WriteCloser, error) {
+	bgzfWriter, err := bgzf.NewWriterLevel(output, compressionLevel, cores)
 	if err != nil {
 		return nil, ErrInvalidCompressionLevel
 	}
@@ -280,23 +401,46 @@ func makeLZ4Writer(output io.WriteCloser, compressionLevel int) (io.WriteCloser,
 	return lzwriter, nil
 }
 
-func makeXZWriter(output io.WriteCloser) (io.WriteCloser, error) {
-	xzwriter, err := xz.NewWriter(output)
-	if err != nil {
-		return nil, err
+// xzChunkSize is the amount of uncompressed data compressed as one xz stream
+// when compressing with more than one core.
+const xzChunkSize = 16 * 1024 * 1024
+
+func makeXZWriter(output io.WriteCloser, cores int) (io.WriteCloser, error) {
+	newWriter := func(w io.Writer) (io.WriteCloser, error) {
+		return xz.NewWriter(w)
 	}
-	return xzwriter, nil
+	if cores <= 1 {
+		return newWriter(output)
+	}
+
+	// The xz library is single threaded, so compress chunks as independent
+	// streams in parallel. xz decoders read concatenated streams as one file.
+	return newParallelWriter(output, xzChunkSize, cores, newWriter), nil
 }
 
-func makePgzipWriter(output io.WriteCloser, compressionLevel int) (io.WriteCloser, error) {
+func makePgzipWriter(output io.WriteCloser, compressionLevel int, cores int) (io.WriteCloser, error) {
 	gzipWriter, err := pgzip.NewWriterLevel(output, compressionLevel)
 	if err != nil {
 		return nil, ErrInvalidCompressionLevel
 	}
-	gzipWriter.SetConcurrency(500000, runtime.GOMAXPROCS(-1))
+	gzipWriter.SetConcurrency(500000, cores)
 	return gzipWriter, nil
 }
 
+// The levels of zstd run from 1 to 22. The encoder only has a handful of
+// speeds within that.
+const (
+	zstdBestCompression    = 22
+	zstdDefaultCompression = 3
+)
+
+func makeZstdWriter(output io.WriteCloser, compressionLevel int, cores int) (io.WriteCloser, error) {
+	level := zstd.EncoderLevelFromZstd(compressionLevel)
+	return zstd.NewWriter(output,
+		zstd.WithEncoderLevel(level),
+		zstd.WithEncoderConcurrency(cores))
+}
+
 func createTarArchive(files []string, output io.WriteCloser) error {
 	archive := tar.NewWriter(output)
 	defer archive.Close()
@@ -1,9 +1,14 @@
 package compress
 
 import (
+	"archive/tar"
+	"bytes"
 	"compress/gzip"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"strings"
 	"testing"
@@ -11,6 +16,8 @@ import (
 	"github.com/hashicorp/packer/builder/file"
 	"github.com/hashicorp/packer/packer"
 	"github.com/hashicorp/packer/template"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
 )
 
 func TestDetectFilename(t *testing.T) {
@@ -246,3 +253,188 @@ func testArchive(t *testing.T, config string) packer.Artifact {
 
 	return artifactOut
 }
+
+func TestCompressZstd(t *testing.T) {
+	const config = `
+	{
+	    "post-processors": [
+	        {
+	            "type": "compress",
+	            "output": "package.zst"
+	        }
+	    ]
+	}
+	`
+
+	artifact := testArchive(t, config)
+	defer artifact.Destroy()
+
+	archive, err := os.Open("package.zst")
+	if err != nil {
+		t.Fatalf("Unable to read archive: %s", err)
+	}
+	defer archive.Close()
+
+	zstdReader, err := zstd.NewReader(archive)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer zstdReader.Close()
+	data, _ := ioutil.ReadAll(zstdReader)
+
+	if string(data) != expectedFileContents {
+		t.Errorf("Expected:\n%s\nFound:\n%s\n", expectedFileContents, data)
+	}
+}
+
+func TestCompressLevel(t *testing.T) {
+	cases := []struct {
+		output   string
+		level    int
+		expected int
+	}{
+		{"package.tar.gz", 0, -1},
+		{"package.tar.gz", 19, 9},
+		{"package.zst", 0, 3},
+		{"package.zst", 19, 19},
+		{"package.zst", 30, 22},
+	}
+	for _, tc := range cases {
+		var p PostProcessor
+		err := p.Configure(map[string]interface{}{
+			"output":            tc.output,
+			"compression_level": tc.level,
+		})
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if p.config.CompressionLevel != tc.expected {
+			t.Fatalf("%s %d: expected %d, got %d", tc.output, tc.level, tc.expected, p.config.CompressionLevel)
+		}
+	}
+}
+
+func TestCompressSplit(t *testing.T) {
+	const config = `
+	{
+	    "post-processors": [
+	        {
+	            "type": "compress",
+	            "output": "package.txt",
+	            "format": "",
+	            "split_size": "5B"
+	        }
+	    ]
+	}
+	`
+
+	artifact := testArchive(t, config)
+	defer artifact.Destroy()
+
+	files := artifact.Files()
+	if len(files) < 2 {
+		t.Fatalf("Expected several parts, got: %v", files)
+	}
+	if files[0] != "package.txt.001" {
+		t.Fatalf("bad: %v", files)
+	}
+
+	var data []byte
+	for _, f := range files {
+		part, err := ioutil.ReadFile(f)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if len(part) > 5 {
+			t.Fatalf("part %s too large: %d", f, len(part))
+		}
+		data = append(data, part...)
+	}
+
+	// The parts are a tar.gz, since the output has no known extension
+	gzipReader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	tarReader := tar.NewReader(gzipReader)
+	if _, err := tarReader.Next(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	contents, _ := ioutil.ReadAll(tarReader)
+	if string(contents) != expectedFileContents {
+		t.Errorf("Expected:\n%s\nFound:\n%s\n", expectedFileContents, contents)
+	}
+}
+
+func TestCompressOutputStore(t *testing.T) {
+	var uploaded []byte
+	var chunked bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PUT" || r.URL.Path != "/out/package.gz" {
+			t.Errorf("bad: %s %s", r.Method, r.URL.Path)
+		}
+		uploaded, _ = ioutil.ReadAll(r.Body)
+		chunked = len(r.TransferEncoding) > 0
+	}))
+	defer server.Close()
+
+	config := fmt.Sprintf(`
+	{
+	    "post-processors": [
+	        {
+	            "type": "compress",
+	            "output": "package.gz",
+	            "output_store": {"url": "%s/out"}
+	        }
+	    ]
+	}
+	`, server.URL)
+
+	artifact := testArchive(t, config)
+	if files := artifact.Files(); len(files) != 1 || files[0] != server.URL+"/out/package.gz" {
+		t.Fatalf("bad: %v", files)
+	}
+	if _, err := os.Stat("package.gz"); err == nil {
+		os.Remove("package.gz")
+		t.Fatal("the archive should be streamed, not written to disk")
+	}
+	if !chunked {
+		t.Fatal("should stream the archive")
+	}
+
+	gzipReader, err := gzip.NewReader(bytes.NewReader(uploaded))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	contents, _ := ioutil.ReadAll(gzipReader)
+	if string(contents) != expectedFileContents {
+		t.Errorf("Expected:\n%s\nFound:\n%s\n", expectedFileContents, contents)
+	}
+}
+
+func TestParallelWriter_xz(t *testing.T) {
+	var out bytes.Buffer
+	input := bytes.Repeat([]byte("packer"), 1000)
+
+	w := newParallelWriter(&out, 100, 4, func(w io.Writer) (io.WriteCloser, error) {
+		return xz.NewWriter(w)
+	})
+	if _, err := w.Write(input); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	r, err := xz.NewReader(&out)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !bytes.Equal(data, input) {
+		t.Fatalf("roundtrip mismatch: got %d bytes", len(data))
+	}
+}
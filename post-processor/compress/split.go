@@ -0,0 +1,77 @@
+package compress
+
+import (
+	"fmt"
+	"os"
+)
+
+// splitWriter writes to a series of files of at most size bytes each,
+// named after base with a numeric suffix: base.001, base.002, and so on.
+type splitWriter struct {
+	base string
+	size int64
+
+	current *os.File
+	written int64
+	parts   []string
+}
+
+func newSplitWriter(base string, size int64) *splitWriter {
+	return &splitWriter{base: base, size: size}
+}
+
+func (w *splitWriter) Write(p []byte) (int, error) {
+	total := 0
+	for len(p) > 0 {
+		if w.current == nil || w.written == w.size {
+			if err := w.next(); err != nil {
+				return total, err
+			}
+		}
+
+		chunk := p
+		if remaining := w.size - w.written; int64(len(chunk)) > remaining {
+			chunk = chunk[:remaining]
+		}
+		n, err := w.current.Write(chunk)
+		total += n
+		w.written += int64(n)
+		if err != nil {
+			return total, err
+		}
+		p = p[n:]
+	}
+	return total, nil
+}
+
+func (w *splitWriter) next() error {
+	if w.current != nil {
+		if err := w.current.Close(); err != nil {
+			return err
+		}
+	}
+
+	name := fmt.Sprintf("%s.%03d", w.base, len(w.parts)+1)
+	f, err := os.Create(name)
+	if err != nil {
+		return fmt.Errorf("Unable to create archive part %s: %s", name, err)
+	}
+	w.current = f
+	w.written = 0
+	w.parts = append(w.parts, name)
+	return nil
+}
+
+// Parts returns the paths of the files written so far.
+func (w *splitWriter) Parts() []string {
+	return w.parts
+}
+
+func (w *splitWriter) Close() error {
+	if w.current == nil {
+		return nil
+	}
+	err := w.current.Close()
+	w.current = nil
+	return err
+}
@@ -0,0 +1,54 @@
+package digitaloceanimport
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/digitalocean/godo"
+)
+
+const BuilderId = "packer.post-processor.digitalocean-import"
+
+type Artifact struct {
+	// The name of the image
+	imageName string
+
+	// The ID of the image
+	imageId int
+
+	// The names of the regions the image is available in
+	regionNames []string
+
+	// The client for making API calls
+	client *godo.Client
+}
+
+func (*Artifact) BuilderId() string {
+	return BuilderId
+}
+
+func (*Artifact) Files() []string {
+	return nil
+}
+
+func (a *Artifact) Id() string {
+	return fmt.Sprintf("%s:%s", strings.Join(a.regionNames, ","), strconv.Itoa(a.imageId))
+}
+
+func (a *Artifact) String() string {
+	return fmt.Sprintf("A custom image was created: '%v' (ID: %v) in regions '%v'",
+		a.imageName, a.imageId, strings.Join(a.regionNames, ","))
+}
+
+func (*Artifact) State(name string) interface{} {
+	return nil
+}
+
+func (a *Artifact) Destroy() error {
+	log.Printf("Destroying image: %d (%s)", a.imageId, a.imageName)
+	_, err := a.client.Images.Delete(context.TODO(), a.imageId)
+	return err
+}
@@ -0,0 +1,107 @@
+package digitaloceanimport
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/digitalocean/godo"
+)
+
+// The version of godo that is vendored predates custom images, so the
+// requests for them are made directly.
+
+type customImageCreateRequest struct {
+	Name         string   `json:"name"`
+	Url          string   `json:"url"`
+	Region       string   `json:"region"`
+	Distribution string   `json:"distribution,omitempty"`
+	Description  string   `json:"description,omitempty"`
+	Tags         []string `json:"tags,omitempty"`
+}
+
+type customImage struct {
+	ID           int      `json:"id"`
+	Name         string   `json:"name"`
+	Status       string   `json:"status"`
+	ErrorMessage string   `json:"error_message"`
+	Regions      []string `json:"regions"`
+}
+
+type customImageRoot struct {
+	Image *customImage `json:"image"`
+}
+
+func createCustomImage(ctx context.Context, client *godo.Client, create *customImageCreateRequest) (*customImage, error) {
+	req, err := client.NewRequest(ctx, "POST", "v2/images", create)
+	if err != nil {
+		return nil, err
+	}
+	root := new(customImageRoot)
+	if _, err := client.Do(req, root); err != nil {
+		return nil, err
+	}
+	return root.Image, nil
+}
+
+func getCustomImage(ctx context.Context, client *godo.Client, id int) (*customImage, error) {
+	req, err := client.NewRequest(ctx, "GET", fmt.Sprintf("v2/images/%d", id), nil)
+	if err != nil {
+		return nil, err
+	}
+	root := new(customImageRoot)
+	if _, err := client.Do(req, root); err != nil {
+		return nil, err
+	}
+	return root.Image, nil
+}
+
+// waitForImageAvailable blocks until DigitalOcean has fetched and
+// imported the image, or it failed to, or timeout passed.
+func waitForImageAvailable(ctx context.Context, client *godo.Client, id int, timeout time.Duration, interval time.Duration) (*customImage, error) {
+	deadline := time.Now().Add(timeout)
+	for attempts := 1; ; attempts++ {
+		log.Printf("Checking image status... (attempt: %d)", attempts)
+		image, err := getCustomImage(ctx, client, id)
+		if err != nil {
+			return nil, err
+		}
+
+		switch image.Status {
+		case "available":
+			return image, nil
+		case "deleted":
+			return nil, fmt.Errorf("Image import failed: %s", image.ErrorMessage)
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("Timeout while waiting for image to become available")
+		}
+		time.Sleep(interval)
+	}
+}
+
+// waitForActionCompleted blocks until an image action, like a transfer to
+// another region, is done.
+func waitForActionCompleted(ctx context.Context, client *godo.Client, imageId, actionId int, timeout time.Duration, interval time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		action, _, err := client.ImageActions.Get(ctx, imageId, actionId)
+		if err != nil {
+			return err
+		}
+
+		switch action.Status {
+		case godo.ActionCompleted:
+			return nil
+		case "errored":
+			return fmt.Errorf("Image action %d failed", actionId)
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("Timeout while waiting for image action %d to complete", actionId)
+		}
+		time.Sleep(interval)
+	}
+}
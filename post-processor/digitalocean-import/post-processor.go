@@ -0,0 +1,331 @@
+package digitaloceanimport
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/digitalocean/godo"
+	"golang.org/x/oauth2"
+
+	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/helper/config"
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+// The formats DigitalOcean can import, optionally gzip or bzip2 compressed.
+var imageExtensions = []string{".raw", ".img", ".qcow2", ".vhdx", ".vdi", ".vmdk"}
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+
+	APIToken     string `mapstructure:"api_token"`
+	APIURL       string `mapstructure:"api_url"`
+	SpacesKey    string `mapstructure:"spaces_key"`
+	SpacesSecret string `mapstructure:"spaces_secret"`
+
+	SpacesRegion    string        `mapstructure:"spaces_region"`
+	SpaceName       string        `mapstructure:"space_name"`
+	ObjectName      string        `mapstructure:"space_object_name"`
+	SkipClean       bool          `mapstructure:"skip_clean"`
+	Tags            []string      `mapstructure:"image_tags"`
+	Name            string        `mapstructure:"image_name"`
+	Description     string        `mapstructure:"image_description"`
+	Distribution    string        `mapstructure:"image_distribution"`
+	ImageRegions    []string      `mapstructure:"image_regions"`
+	Timeout         time.Duration `mapstructure:"timeout"`
+	PollingInterval time.Duration `mapstructure:"polling_interval"`
+
+	ctx interpolate.Context
+}
+
+type PostProcessor struct {
+	config Config
+}
+
+type objectNameTemplate struct {
+	BuildName   string
+	BuilderType string
+}
+
+func (p *PostProcessor) Configure(raws ...interface{}) error {
+	err := config.Decode(&p.config, &config.DecodeOpts{
+		Interpolate:        true,
+		InterpolateContext: &p.config.ctx,
+		InterpolateFilter: &interpolate.RenderFilter{
+			Exclude: []string{"space_object_name"},
+		},
+	}, raws...)
+	if err != nil {
+		return err
+	}
+
+	if p.config.APIToken == "" {
+		p.config.APIToken = os.Getenv("DIGITALOCEAN_API_TOKEN")
+	}
+	if p.config.APIURL == "" {
+		p.config.APIURL = os.Getenv("DIGITALOCEAN_API_URL")
+	}
+	if p.config.SpacesKey == "" {
+		p.config.SpacesKey = os.Getenv("DIGITALOCEAN_SPACES_ACCESS_KEY")
+	}
+	if p.config.SpacesSecret == "" {
+		p.config.SpacesSecret = os.Getenv("DIGITALOCEAN_SPACES_SECRET_KEY")
+	}
+	if p.config.ObjectName == "" {
+		p.config.ObjectName = "packer-import-{{timestamp}}"
+	}
+	if p.config.Name == "" {
+		p.config.Name = "packer-import-" + interpolate.InitTime.Format("20060102150405")
+	}
+	if p.config.Distribution == "" {
+		p.config.Distribution = "Unknown"
+	}
+	if p.config.Timeout == 0 {
+		p.config.Timeout = 20 * time.Minute
+	}
+	if p.config.PollingInterval == 0 {
+		p.config.PollingInterval = 10 * time.Second
+	}
+
+	errs := new(packer.MultiError)
+
+	if err = interpolate.Validate(p.config.ObjectName, &p.config.ctx); err != nil {
+		errs = packer.MultiErrorAppend(
+			errs, fmt.Errorf("Error parsing space_object_name template: %s", err))
+	}
+
+	required := map[string]*string{
+		"api_token":     &p.config.APIToken,
+		"spaces_key":    &p.config.SpacesKey,
+		"spaces_secret": &p.config.SpacesSecret,
+		"spaces_region": &p.config.SpacesRegion,
+		"space_name":    &p.config.SpaceName,
+	}
+	for key, ptr := range required {
+		if *ptr == "" {
+			errs = packer.MultiErrorAppend(
+				errs, fmt.Errorf("%s must be set", key))
+		}
+	}
+
+	if len(p.config.ImageRegions) == 0 {
+		errs = packer.MultiErrorAppend(
+			errs, fmt.Errorf("image_regions must be set"))
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (packer.Artifact, bool, error) {
+	ctx := context.TODO()
+
+	source, err := findImageFile(artifact)
+	if err != nil {
+		return nil, false, err
+	}
+
+	p.config.ctx.Data = &objectNameTemplate{
+		BuildName:   p.config.PackerBuildName,
+		BuilderType: p.config.PackerBuilderType,
+	}
+	objectName, err := interpolate.Render(p.config.ObjectName, &p.config.ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("Error rendering space_object_name template: %s", err)
+	}
+	// Keep the extension, DigitalOcean detects the format from it.
+	if !strings.HasSuffix(objectName, imageExtension(source)) {
+		objectName += imageExtension(source)
+	}
+
+	sess, err := session.NewSession(&aws.Config{
+		Credentials: credentials.NewStaticCredentials(p.config.SpacesKey, p.config.SpacesSecret, ""),
+		Endpoint:    aws.String(fmt.Sprintf("https://%s.digitaloceanspaces.com", p.config.SpacesRegion)),
+		Region:      aws.String("us-east-1"),
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	file, err := os.Open(source)
+	if err != nil {
+		return nil, false, fmt.Errorf("Failed to open %s: %s", source, err)
+	}
+	defer file.Close()
+
+	ui.Message(fmt.Sprintf("Uploading %s to spaces://%s/%s", source, p.config.SpaceName, objectName))
+	_, err = s3manager.NewUploader(sess).Upload(&s3manager.UploadInput{
+		Body:   file,
+		Bucket: aws.String(p.config.SpaceName),
+		Key:    aws.String(objectName),
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("Failed to upload %s: %s", source, err)
+	}
+
+	s3conn := s3.New(sess)
+	if !p.config.SkipClean {
+		defer func() {
+			ui.Message(fmt.Sprintf("Deleting spaces://%s/%s", p.config.SpaceName, objectName))
+			_, err := s3conn.DeleteObject(&s3.DeleteObjectInput{
+				Bucket: aws.String(p.config.SpaceName),
+				Key:    aws.String(objectName),
+			})
+			if err != nil {
+				ui.Error(fmt.Sprintf("Failed to delete spaces://%s/%s: %s", p.config.SpaceName, objectName, err))
+			}
+		}()
+	}
+
+	// The object stays private, DigitalOcean fetches it from a presigned
+	// URL that expires once the import would have timed out anyway.
+	getReq, _ := s3conn.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(p.config.SpaceName),
+		Key:    aws.String(objectName),
+	})
+	imageURL, err := getReq.Presign(p.config.Timeout)
+	if err != nil {
+		return nil, false, fmt.Errorf("Failed to presign URL for %s: %s", objectName, err)
+	}
+
+	client := godo.NewClient(oauth2.NewClient(oauth2.NoContext, oauth2.StaticTokenSource(&oauth2.Token{
+		AccessToken: p.config.APIToken,
+	})))
+	if p.config.APIURL != "" {
+		u, err := url.Parse(p.config.APIURL)
+		if err != nil {
+			return nil, false, fmt.Errorf("DigitalOcean: Invalid API URL, %s.", err)
+		}
+		client.BaseURL = u
+	}
+
+	ui.Message(fmt.Sprintf("Creating image %s in %s", p.config.Name, p.config.ImageRegions[0]))
+	image, err := createCustomImage(ctx, client, &customImageCreateRequest{
+		Name:         p.config.Name,
+		Url:          imageURL,
+		Region:       p.config.ImageRegions[0],
+		Distribution: p.config.Distribution,
+		Description:  p.config.Description,
+		Tags:         p.config.Tags,
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("Failed to create image: %s", err)
+	}
+
+	ui.Message(fmt.Sprintf("Waiting for image %d to become available...", image.ID))
+	if _, err := waitForImageAvailable(ctx, client, image.ID, p.config.Timeout, p.config.PollingInterval); err != nil {
+		return nil, false, fmt.Errorf("Failed to import image %d: %s", image.ID, err)
+	}
+
+	result := &Artifact{
+		imageName:   p.config.Name,
+		imageId:     image.ID,
+		regionNames: []string{p.config.ImageRegions[0]},
+		client:      client,
+	}
+
+	if err := p.distribute(ctx, ui, client, image.ID); err != nil {
+		return result, false, err
+	}
+	result.regionNames = p.config.ImageRegions
+
+	return result, false, nil
+}
+
+// distribute transfers the image to the rest of image_regions, all at the
+// same time.
+func (p *PostProcessor) distribute(ctx context.Context, ui packer.Ui, client *godo.Client, imageId int) error {
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	errs := new(packer.MultiError)
+	for _, region := range p.config.ImageRegions[1:] {
+		ui.Message(fmt.Sprintf("Transferring image %d to %s", imageId, region))
+
+		wg.Add(1)
+		go func(region string) {
+			defer wg.Done()
+			err := p.transfer(ctx, client, imageId, region)
+			if err != nil {
+				lock.Lock()
+				errs = packer.MultiErrorAppend(errs, fmt.Errorf(
+					"Error transferring image %d to %s: %s", imageId, region, err))
+				lock.Unlock()
+			}
+		}(region)
+	}
+	wg.Wait()
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (p *PostProcessor) transfer(ctx context.Context, client *godo.Client, imageId int, region string) error {
+	action, _, err := client.ImageActions.Transfer(ctx, imageId, &godo.ActionRequest{
+		"type":   "transfer",
+		"region": region,
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("Transfer of image %d to %s is action %d", imageId, region, action.ID)
+	return waitForActionCompleted(ctx, client, imageId, action.ID, p.config.Timeout, p.config.PollingInterval)
+}
+
+// findImageFile returns the disk image of artifact.
+func findImageFile(artifact packer.Artifact) (string, error) {
+	// The qemu builder records which of its files is the disk.
+	if name, ok := artifact.State("diskName").(string); ok && name != "" {
+		for _, path := range artifact.Files() {
+			if filepath.Base(path) == name {
+				return path, nil
+			}
+		}
+	}
+
+	for _, path := range artifact.Files() {
+		if imageExtension(path) != "" {
+			return path, nil
+		}
+	}
+
+	return "", fmt.Errorf(
+		"No disk image found in artifact from %s. DigitalOcean can import raw, qcow2, vhdx, vdi and vmdk images",
+		artifact.BuilderId())
+}
+
+// imageExtension returns the extension of an image file DigitalOcean can
+// import, including a compression suffix, or "" for other files.
+func imageExtension(path string) string {
+	lower := strings.ToLower(path)
+	compression := ""
+	for _, c := range []string{".gz", ".bz2"} {
+		if strings.HasSuffix(lower, c) {
+			compression = c
+			lower = strings.TrimSuffix(lower, c)
+		}
+	}
+	for _, ext := range imageExtensions {
+		if strings.HasSuffix(lower, ext) {
+			return ext + compression
+		}
+	}
+	return ""
+}
@@ -0,0 +1,142 @@
+package digitaloceanimport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/hashicorp/packer/packer"
+)
+
+func testConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"api_token":     "token",
+		"spaces_key":    "key",
+		"spaces_secret": "secret",
+		"spaces_region": "nyc3",
+		"space_name":    "images",
+		"image_regions": []string{"nyc3", "ams3"},
+	}
+}
+
+func TestPostProcessor_ImplementsPostProcessor(t *testing.T) {
+	var _ packer.PostProcessor = new(PostProcessor)
+}
+
+func TestArtifact_ImplementsArtifact(t *testing.T) {
+	var _ packer.Artifact = new(Artifact)
+}
+
+func TestPostProcessorConfigure(t *testing.T) {
+	var p PostProcessor
+	if err := p.Configure(testConfig()); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if p.config.Timeout != 20*time.Minute {
+		t.Fatalf("bad timeout: %s", p.config.Timeout)
+	}
+	if p.config.Distribution != "Unknown" {
+		t.Fatalf("bad distribution: %s", p.config.Distribution)
+	}
+
+	for _, key := range []string{"api_token", "spaces_key", "spaces_secret", "spaces_region", "space_name", "image_regions"} {
+		c := testConfig()
+		delete(c, key)
+		p = PostProcessor{}
+		if err := p.Configure(c); err == nil {
+			t.Fatalf("should error without %s", key)
+		}
+	}
+}
+
+func TestFindImageFile(t *testing.T) {
+	cases := []struct {
+		artifact packer.Artifact
+		expected string
+	}{
+		{
+			&packer.MockArtifact{FilesValue: []string{"out/disk.qcow2", "out/other.txt"}},
+			"out/disk.qcow2",
+		},
+		{
+			&packer.MockArtifact{FilesValue: []string{"out/README", "out/disk.RAW.gz"}},
+			"out/disk.RAW.gz",
+		},
+		{
+			&packer.MockArtifact{
+				FilesValue:  []string{"out/seed.img", "out/packer-centos"},
+				StateValues: map[string]interface{}{"diskName": "packer-centos"},
+			},
+			"out/packer-centos",
+		},
+	}
+	for _, tc := range cases {
+		actual, err := findImageFile(tc.artifact)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if actual != tc.expected {
+			t.Fatalf("expected %s, got %s", tc.expected, actual)
+		}
+	}
+
+	if _, err := findImageFile(&packer.MockArtifact{FilesValue: []string{"a.ova"}}); err == nil {
+		t.Fatal("should error without a disk image")
+	}
+}
+
+func TestCreateCustomImage(t *testing.T) {
+	var polls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/v2/images":
+			var req customImageCreateRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+			if req.Region != "nyc3" || req.Url != "https://example.com/disk.qcow2" {
+				t.Fatalf("bad request: %#v", req)
+			}
+			fmt.Fprint(w, `{"image": {"id": 42, "status": "NEW"}}`)
+		case r.Method == "GET" && r.URL.Path == "/v2/images/42":
+			polls++
+			status := "pending"
+			if polls > 1 {
+				status = "available"
+			}
+			fmt.Fprintf(w, `{"image": {"id": 42, "status": "%s"}}`, status)
+		default:
+			t.Fatalf("unexpected request: %s %s", r.Method, r.URL)
+		}
+	}))
+	defer server.Close()
+
+	client := godo.NewClient(nil)
+	client.BaseURL, _ = url.Parse(server.URL + "/")
+
+	ctx := context.Background()
+	image, err := createCustomImage(ctx, client, &customImageCreateRequest{
+		Name:   "test",
+		Url:    "https://example.com/disk.qcow2",
+		Region: "nyc3",
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if image.ID != 42 {
+		t.Fatalf("bad id: %d", image.ID)
+	}
+
+	if _, err := waitForImageAvailable(ctx, client, image.ID, time.Minute, time.Millisecond); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if polls != 2 {
+		t.Fatalf("expected 2 polls, got %d", polls)
+	}
+}
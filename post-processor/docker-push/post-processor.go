@@ -2,6 +2,9 @@ package dockerpush
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/hashicorp/packer/builder/docker"
 	"github.com/hashicorp/packer/common"
@@ -10,6 +13,7 @@ import (
 	"github.com/hashicorp/packer/post-processor/docker-import"
 	"github.com/hashicorp/packer/post-processor/docker-tag"
 	"github.com/hashicorp/packer/template/interpolate"
+	"github.com/mitchellh/go-homedir"
 )
 
 const BuilderIdImport = "packer.post-processor.docker-import"
@@ -22,7 +26,13 @@ type Config struct {
 	LoginPassword          string `mapstructure:"login_password"`
 	LoginServer            string `mapstructure:"login_server"`
 	EcrLogin               bool   `mapstructure:"ecr_login"`
+	GcrLogin               bool   `mapstructure:"gcr_login"`
+	AcrLogin               bool   `mapstructure:"acr_login"`
 	docker.AwsAccessConfig `mapstructure:",squash"`
+	AzureAccessConfig      `mapstructure:",squash"`
+
+	Tags         []string `mapstructure:"tags"`
+	ManifestList string   `mapstructure:"manifest_list"`
 
 	ctx interpolate.Context
 }
@@ -45,8 +55,38 @@ func (p *PostProcessor) Configure(raws ...interface{}) error {
 		return err
 	}
 
+	var errs *packer.MultiError
+	logins := 0
+	for _, l := range []bool{p.config.EcrLogin, p.config.GcrLogin, p.config.AcrLogin} {
+		if l {
+			logins++
+		}
+	}
+	if logins > 1 {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf(
+			"Only one of ecr_login, gcr_login and acr_login can be set."))
+	}
 	if p.config.EcrLogin && p.config.LoginServer == "" {
-		return fmt.Errorf("ECR login requires login server to be provided.")
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf(
+			"ECR login requires login server to be provided."))
+	}
+	if p.config.GcrLogin && p.config.LoginServer == "" {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf(
+			"GCR login requires login server to be provided."))
+	}
+	if p.config.AcrLogin && p.config.LoginServer == "" {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf(
+			"ACR login requires login server to be provided."))
+	}
+	for _, tag := range p.config.Tags {
+		if tag == "" || strings.ContainsAny(tag, ":/@") {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf(
+				"Invalid tag %q: tags must not be empty or contain ':', '/' or '@'.", tag))
+		}
+	}
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return errs
 	}
 	return nil
 }
@@ -66,10 +106,25 @@ func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (pac
 		driver = &docker.DockerDriver{Ctx: &p.config.ctx, Ui: ui}
 	}
 
-	if p.config.EcrLogin {
+	var getLogin func() (string, string, error)
+	switch {
+	case p.config.EcrLogin:
 		ui.Message("Fetching ECR credentials...")
+		getLogin = func() (string, string, error) {
+			return p.config.EcrGetLogin(p.config.LoginServer)
+		}
+	case p.config.GcrLogin:
+		ui.Message("Fetching GCR credentials...")
+		getLogin = gcrGetLogin
+	case p.config.AcrLogin:
+		ui.Message("Fetching ACR credentials...")
+		getLogin = func() (string, string, error) {
+			return p.config.AcrGetLogin(p.config.LoginServer)
+		}
+	}
 
-		username, password, err := p.config.EcrGetLogin(p.config.LoginServer)
+	if getLogin != nil {
+		username, password, err := getLogin()
 		if err != nil {
 			return nil, false, err
 		}
@@ -78,7 +133,7 @@ func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (pac
 		p.config.LoginPassword = password
 	}
 
-	if p.config.Login || p.config.EcrLogin {
+	if p.config.Login || getLogin != nil {
 		ui.Message("Logging in...")
 		err := driver.Login(
 			p.config.LoginServer,
@@ -100,9 +155,32 @@ func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (pac
 	// Get the name.
 	name := artifact.Id()
 
-	ui.Message("Pushing: " + name)
-	if err := driver.Push(name); err != nil {
-		return nil, false, err
+	names := []string{name}
+	repo := repository(name)
+	for _, tag := range p.config.Tags {
+		tagged := repo + ":" + tag
+		if tagged == name {
+			continue
+		}
+
+		ui.Message("Tagging: " + tagged)
+		if err := driver.TagImage(name, tagged, false); err != nil {
+			return nil, false, err
+		}
+		names = append(names, tagged)
+	}
+
+	for _, n := range names {
+		ui.Message("Pushing: " + n)
+		if err := driver.Push(n); err != nil {
+			return nil, false, err
+		}
+	}
+
+	if p.config.ManifestList != "" {
+		if err := p.pushManifestList(ui, driver, name); err != nil {
+			return nil, false, err
+		}
 	}
 
 	artifact = &docker.ImportArtifact{
@@ -113,3 +191,59 @@ func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (pac
 
 	return artifact, true, nil
 }
+
+// pushManifestList adds the image to the manifest list and pushes it. Each
+// build of a multi-arch template adds its image to the same list, so the
+// list that is pushed last contains every architecture.
+func (p *PostProcessor) pushManifestList(ui packer.Ui, driver docker.Driver, name string) error {
+	list := p.config.ManifestList
+
+	// Builds running at the same time amend the list in turn, so none of
+	// their images is lost
+	path, err := manifestListPath(list)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	unlock, err := common.LockPath(path, func() {
+		ui.Message(fmt.Sprintf("Waiting for another build to push manifest list %s...", list))
+	})
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	ui.Message(fmt.Sprintf("Adding %s to manifest list %s", name, list))
+	if err := driver.ManifestCreate(list, []string{name}); err != nil {
+		return err
+	}
+
+	ui.Message("Pushing manifest list: " + list)
+	return driver.ManifestPush(list)
+}
+
+// manifestListPath is the file the Docker CLI keeps the local manifest list
+// in, under its configuration directory.
+func manifestListPath(list string) (string, error) {
+	dir := os.Getenv("DOCKER_CONFIG")
+	if dir == "" {
+		home, err := homedir.Dir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".docker")
+	}
+	name := strings.NewReplacer(":", "-", "/", "_").Replace(list)
+	return filepath.Join(dir, "manifests", name), nil
+}
+
+// repository returns the image name without its tag, taking care not to
+// mistake a registry port for one.
+func repository(name string) string {
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		return name[:i]
+	}
+	return name
+}
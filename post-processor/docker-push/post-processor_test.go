@@ -2,7 +2,16 @@ package dockerpush
 
 import (
 	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/packer/builder/docker"
 	"github.com/hashicorp/packer/packer"
@@ -122,3 +131,184 @@ func TestPostProcessor_PostProcess_tags(t *testing.T) {
 		t.Fatal("bad image id")
 	}
 }
+
+func TestPostProcessor_PostProcess_multipleTags(t *testing.T) {
+	driver := &docker.MockDriver{}
+	p := &PostProcessor{Driver: driver}
+	if err := p.Configure(map[string]interface{}{
+		"tags": []string{"latest", "precise", "12.04"},
+	}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	artifact := &packer.MockArtifact{
+		BuilderIdValue: dockerimport.BuilderId,
+		IdValue:        "localhost:5000/hashicorp/ubuntu:precise",
+	}
+
+	result, _, err := p.PostProcess(testUi(), artifact)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	expected := []string{
+		"localhost:5000/hashicorp/ubuntu:precise",
+		"localhost:5000/hashicorp/ubuntu:latest",
+		"localhost:5000/hashicorp/ubuntu:12.04",
+	}
+	if !reflect.DeepEqual(driver.PushNames, expected) {
+		t.Fatalf("bad pushes: %#v", driver.PushNames)
+	}
+	if result.Id() != "localhost:5000/hashicorp/ubuntu:precise" {
+		t.Fatal("bad image id")
+	}
+	if driver.ManifestCreateCalled {
+		t.Fatal("should not create a manifest list")
+	}
+}
+
+func TestPostProcessor_PostProcess_manifestList(t *testing.T) {
+	driver := &docker.MockDriver{}
+	p := &PostProcessor{Driver: driver}
+	if err := p.Configure(map[string]interface{}{
+		"manifest_list": "hashicorp/ubuntu:precise",
+	}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	artifact := &packer.MockArtifact{
+		BuilderIdValue: dockerimport.BuilderId,
+		IdValue:        "hashicorp/ubuntu:precise-arm64",
+	}
+
+	if _, _, err := p.PostProcess(testUi(), artifact); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if driver.ManifestCreateList != "hashicorp/ubuntu:precise" {
+		t.Fatalf("bad list: %s", driver.ManifestCreateList)
+	}
+	if !reflect.DeepEqual(driver.ManifestCreateImages, []string{"hashicorp/ubuntu:precise-arm64"}) {
+		t.Fatalf("bad images: %#v", driver.ManifestCreateImages)
+	}
+	if driver.ManifestPushList != "hashicorp/ubuntu:precise" {
+		t.Fatalf("bad push: %s", driver.ManifestPushList)
+	}
+}
+
+// manifestDriver fails the test when two builds amend the manifest list at
+// the same time.
+type manifestDriver struct {
+	*docker.MockDriver
+	t      *testing.T
+	active *int32
+}
+
+func (d *manifestDriver) ManifestCreate(list string, images []string) error {
+	if atomic.AddInt32(d.active, 1) != 1 {
+		d.t.Errorf("%s amended the manifest list during another build", images[0])
+	}
+	time.Sleep(10 * time.Millisecond)
+	return nil
+}
+
+func (d *manifestDriver) ManifestPush(list string) error {
+	atomic.AddInt32(d.active, -1)
+	return nil
+}
+
+func TestPostProcessor_PostProcess_manifestListParallel(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	defer os.Setenv("DOCKER_CONFIG", os.Getenv("DOCKER_CONFIG"))
+	os.Setenv("DOCKER_CONFIG", dir)
+
+	var active int32
+	var wg sync.WaitGroup
+	arches := []string{"amd64", "arm64", "arm", "ppc64le", "s390x"}
+	for _, arch := range arches {
+		driver := &manifestDriver{MockDriver: &docker.MockDriver{}, t: t, active: &active}
+		p := &PostProcessor{Driver: driver}
+		if err := p.Configure(map[string]interface{}{
+			"manifest_list": "hashicorp/ubuntu:precise",
+		}); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		artifact := &packer.MockArtifact{
+			BuilderIdValue: dockerimport.BuilderId,
+			IdValue:        "hashicorp/ubuntu:precise-" + arch,
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, _, err := p.PostProcess(testUi(), artifact); err != nil {
+				t.Errorf("err: %s", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if entries, _ := ioutil.ReadDir(filepath.Join(dir, "manifests")); len(entries) != 0 {
+		t.Fatalf("the lock was left: %d files", len(entries))
+	}
+}
+
+func TestPostProcessor_Configure_logins(t *testing.T) {
+	cases := []struct {
+		config map[string]interface{}
+		err    bool
+	}{
+		{map[string]interface{}{"gcr_login": true, "login_server": "https://gcr.io"}, false},
+		{map[string]interface{}{"acr_login": true, "login_server": "r.azurecr.io"}, false},
+		{map[string]interface{}{"gcr_login": true}, true},
+		{map[string]interface{}{"acr_login": true}, true},
+		{map[string]interface{}{"ecr_login": true, "gcr_login": true, "login_server": "x"}, true},
+		{map[string]interface{}{"tags": []string{"a:b"}}, true},
+	}
+
+	for _, tc := range cases {
+		var p PostProcessor
+		err := p.Configure(tc.config)
+		if (err != nil) != tc.err {
+			t.Errorf("%#v: expected error %t, got %v", tc.config, tc.err, err)
+		}
+	}
+}
+
+func TestRepository(t *testing.T) {
+	cases := map[string]string{
+		"foo":                    "foo",
+		"foo:bar":                "foo",
+		"localhost:5000/foo":     "localhost:5000/foo",
+		"localhost:5000/foo:bar": "localhost:5000/foo",
+	}
+	for name, expected := range cases {
+		if actual := repository(name); actual != expected {
+			t.Errorf("%s: expected %s, got %s", name, expected, actual)
+		}
+	}
+}
+
+func TestAcrExchange(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/oauth2/exchange" {
+			t.Errorf("bad path: %s", r.URL.Path)
+		}
+		r.ParseForm()
+		if r.Form.Get("access_token") != "aad" || r.Form.Get("tenant") != "tenant" {
+			t.Errorf("bad form: %#v", r.Form)
+		}
+		w.Write([]byte(`{"refresh_token": "acr"}`))
+	}))
+	defer ts.Close()
+
+	token, err := acrExchange(ts.Client(), ts.URL, "tenant", "aad")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if token != "acr" {
+		t.Fatalf("bad token: %s", token)
+	}
+}
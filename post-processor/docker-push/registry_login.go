@@ -0,0 +1,132 @@
+package dockerpush
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/Azure/go-autorest/autorest/adal"
+	"github.com/Azure/go-autorest/autorest/azure"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// GCR and Artifact Registry accept an OAuth2 access token as the password
+// of this user.
+const gcrUsername = "oauth2accesstoken"
+
+// ACR accepts a refresh token as the password of the null GUID user.
+const acrUsername = "00000000-0000-0000-0000-000000000000"
+
+// AzureAccessConfig holds the service principal used to log in to ACR.
+// When no client is set the environment is used, then the managed identity
+// of the machine packer runs on.
+type AzureAccessConfig struct {
+	AzureClientID     string `mapstructure:"azure_client_id"`
+	AzureClientSecret string `mapstructure:"azure_client_secret"`
+	AzureTenantID     string `mapstructure:"azure_tenant_id"`
+}
+
+// gcrGetLogin returns credentials for GCR or Artifact Registry using the
+// Google application default credentials.
+func gcrGetLogin() (string, string, error) {
+	ts, err := google.DefaultTokenSource(oauth2.NoContext,
+		"https://www.googleapis.com/auth/cloud-platform")
+	if err != nil {
+		return "", "", fmt.Errorf("Error finding Google credentials: %s", err)
+	}
+
+	token, err := ts.Token()
+	if err != nil {
+		return "", "", fmt.Errorf("Error getting Google access token: %s", err)
+	}
+
+	return gcrUsername, token.AccessToken, nil
+}
+
+// AcrGetLogin returns credentials for the given ACR registry by exchanging
+// an Azure AD token for a registry refresh token.
+func (c *AzureAccessConfig) AcrGetLogin(server string) (string, string, error) {
+	clientID := c.AzureClientID
+	clientSecret := c.AzureClientSecret
+	tenantID := c.AzureTenantID
+	if clientID == "" {
+		clientID = os.Getenv("AZURE_CLIENT_ID")
+		clientSecret = os.Getenv("AZURE_CLIENT_SECRET")
+	}
+	if tenantID == "" {
+		tenantID = os.Getenv("AZURE_TENANT_ID")
+	}
+
+	resource := azure.PublicCloud.ResourceManagerEndpoint
+	var spt *adal.ServicePrincipalToken
+	var err error
+	if clientID == "" {
+		log.Printf("Getting Azure AD token for %s from the managed identity", server)
+		spt, err = adal.NewServicePrincipalTokenFromMSI(
+			"http://169.254.169.254/metadata/identity/oauth2/token", resource)
+	} else {
+		log.Printf("Getting Azure AD token for %s for client %s", server, clientID)
+		var oauthConfig *adal.OAuthConfig
+		oauthConfig, err = adal.NewOAuthConfig(
+			azure.PublicCloud.ActiveDirectoryEndpoint, tenantID)
+		if err == nil {
+			spt, err = adal.NewServicePrincipalToken(
+				*oauthConfig, clientID, clientSecret, resource)
+		}
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("Error creating Azure AD token: %s", err)
+	}
+	if err := spt.Refresh(); err != nil {
+		return "", "", fmt.Errorf("Error getting Azure AD token: %s", err)
+	}
+
+	refreshToken, err := acrExchange(http.DefaultClient, server, tenantID, spt.OAuthToken())
+	if err != nil {
+		return "", "", err
+	}
+
+	return acrUsername, refreshToken, nil
+}
+
+// acrExchange trades an Azure AD access token for an ACR refresh token.
+func acrExchange(client *http.Client, server, tenantID, accessToken string) (string, error) {
+	host := strings.TrimPrefix(strings.TrimPrefix(server, "https://"), "http://")
+	host = strings.SplitN(host, "/", 2)[0]
+
+	form := url.Values{
+		"grant_type":   {"access_token"},
+		"service":      {host},
+		"access_token": {accessToken},
+	}
+	if tenantID != "" {
+		form.Set("tenant", tenantID)
+	}
+
+	resp, err := client.PostForm(fmt.Sprintf("https://%s/oauth2/exchange", host), form)
+	if err != nil {
+		return "", fmt.Errorf("Error exchanging Azure AD token for %s: %s", host, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Error exchanging Azure AD token for %s: %s", host, resp.Status)
+	}
+
+	var body struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("Error decoding ACR token response: %s", err)
+	}
+	if body.RefreshToken == "" {
+		return "", fmt.Errorf("ACR token response for %s had no refresh token", host)
+	}
+
+	return body.RefreshToken, nil
+}
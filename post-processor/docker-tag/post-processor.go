@@ -14,7 +14,8 @@ import (
 const BuilderId = "packer.post-processor.docker-tag"
 
 type Config struct {
-	common.PackerConfig `mapstructure:",squash"`
+	common.PackerConfig   `mapstructure:",squash"`
+	common.IfExistsConfig `mapstructure:",squash"`
 
 	Repository string `mapstructure:"repository"`
 	Tag        string `mapstructure:"tag"`
@@ -41,6 +42,13 @@ func (p *PostProcessor) Configure(raws ...interface{}) error {
 		return err
 	}
 
+	// Tagging replaces the tag unless if_exists says otherwise
+	if p.config.IfExists != "" {
+		if errs := p.config.IfExistsConfig.Prepare(); len(errs) > 0 {
+			return errs[0]
+		}
+	}
+
 	return nil
 
 }
@@ -65,9 +73,34 @@ func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (pac
 		importRepo += ":" + p.config.Tag
 	}
 
+	force := p.config.Force
+	if p.config.IfExists != "" && !p.config.PackerForce {
+		exists, err := driver.ImageExists(importRepo)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if exists {
+			switch p.config.IfExists {
+			case common.IfExistsFail:
+				return nil, false, fmt.Errorf("Image %s already exists", importRepo)
+			case common.IfExistsSkip:
+				ui.Message(fmt.Sprintf("Image %s already exists, keeping it", importRepo))
+				return &docker.ImportArtifact{
+					BuilderIdValue: BuilderId,
+					Driver:         driver,
+					IdValue:        importRepo,
+					Existing:       true,
+				}, true, nil
+			case common.IfExistsReplace:
+				force = true
+			}
+		}
+	}
+
 	ui.Message("Tagging image: " + artifact.Id())
 	ui.Message("Repository: " + importRepo)
-	err := driver.TagImage(artifact.Id(), importRepo, p.config.Force)
+	err := driver.TagImage(artifact.Id(), importRepo, force)
 	if err != nil {
 		return nil, false, err
 	}
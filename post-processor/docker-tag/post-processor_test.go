@@ -111,3 +111,63 @@ func TestPostProcessor_PostProcess_Force(t *testing.T) {
 		t.Fatal("bad force")
 	}
 }
+
+func TestPostProcessor_PostProcess_IfExists(t *testing.T) {
+	artifact := &packer.MockArtifact{
+		BuilderIdValue: dockerimport.BuilderId,
+		IdValue:        "1234567890abcdef",
+	}
+
+	cases := []struct {
+		ifExists string
+		exists   bool
+		tag      bool
+		force    bool
+		err      bool
+	}{
+		{"fail", false, true, false, false},
+		{"fail", true, false, false, true},
+		{"skip", true, false, false, false},
+		{"replace", true, true, true, false},
+	}
+	for _, tc := range cases {
+		driver := &docker.MockDriver{ImageExistsResult: tc.exists}
+		p := &PostProcessor{Driver: driver}
+		c := testConfig()
+		c["if_exists"] = tc.ifExists
+		if err := p.Configure(c); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		result, _, err := p.PostProcess(testUi(), artifact)
+		if (err != nil) != tc.err {
+			t.Fatalf("%s %t: err: %s", tc.ifExists, tc.exists, err)
+		}
+		if driver.ImageExistsName != "foo:bar" {
+			t.Fatalf("%s %t: bad name: %s", tc.ifExists, tc.exists, driver.ImageExistsName)
+		}
+		if driver.TagImageCalled != tc.tag || driver.TagImageForce != tc.force {
+			t.Fatalf("%s %t: bad tag: %t %t", tc.ifExists, tc.exists, driver.TagImageCalled, driver.TagImageForce)
+		}
+		if !tc.err && result.Id() != "foo:bar" {
+			t.Fatalf("%s %t: bad artifact: %s", tc.ifExists, tc.exists, result.Id())
+		}
+		if !tc.err {
+			if err := result.Destroy(); err != nil {
+				t.Fatalf("%s %t: err: %s", tc.ifExists, tc.exists, err)
+			}
+			if driver.DeleteImageCalled == (tc.ifExists == "skip") {
+				t.Fatalf("%s %t: bad delete: %t", tc.ifExists, tc.exists, driver.DeleteImageCalled)
+			}
+		}
+	}
+}
+
+func TestPostProcessor_Configure_IfExists(t *testing.T) {
+	var p PostProcessor
+	c := testConfig()
+	c["if_exists"] = "maybe"
+	if err := p.Configure(c); err == nil {
+		t.Fatal("should error")
+	}
+}
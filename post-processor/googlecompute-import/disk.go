@@ -0,0 +1,89 @@
+package googlecomputeimport
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+// GCE only imports tarballs containing a single raw disk named disk.raw.
+const gceDiskName = "disk.raw"
+
+// findRawDisk returns the raw disk image of an artifact.
+func findRawDisk(artifact packer.Artifact) (string, error) {
+	// The qemu builder records which of its files is the disk.
+	if name, ok := artifact.State("diskName").(string); ok && name != "" {
+		if format, _ := artifact.State("diskType").(string); format != "raw" {
+			return "", fmt.Errorf("Disk %s has format %s, only raw disks can be imported", name, format)
+		}
+		for _, path := range artifact.Files() {
+			if filepath.Base(path) == name {
+				return path, nil
+			}
+		}
+		return "", fmt.Errorf("Disk %s not found in artifact", name)
+	}
+
+	for _, path := range artifact.Files() {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".raw", ".img":
+			return path, nil
+		}
+	}
+
+	return "", fmt.Errorf(
+		"No raw disk found in artifact from %s. Can import raw disks and Compress post-processor artifacts",
+		artifact.BuilderId())
+}
+
+// createDiskTarball writes a gzipped tarball at dst containing the disk at
+// src as disk.raw.
+func createDiskTarball(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("Error opening %s: %s", src, err)
+	}
+	defer in.Close()
+
+	fi, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("Error creating %s: %s", dst, err)
+	}
+	defer out.Close()
+
+	gzw := gzip.NewWriter(out)
+	tw := tar.NewWriter(gzw)
+
+	header := &tar.Header{
+		Name:    gceDiskName,
+		Mode:    0644,
+		Size:    fi.Size(),
+		ModTime: fi.ModTime(),
+		Format:  tar.FormatGNU,
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	if _, err := io.Copy(tw, in); err != nil {
+		return fmt.Errorf("Error packing %s: %s", src, err)
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gzw.Close(); err != nil {
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,85 @@
+package googlecomputeimport
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+func TestFindRawDisk(t *testing.T) {
+	artifact := &packer.MockArtifact{
+		FilesValue: []string{"out/packer-vm"},
+		StateValues: map[string]interface{}{
+			"diskName": "packer-vm",
+			"diskType": "raw",
+		},
+	}
+	if path, err := findRawDisk(artifact); err != nil || path != "out/packer-vm" {
+		t.Fatalf("bad: %s %v", path, err)
+	}
+
+	artifact.StateValues["diskType"] = "qcow2"
+	if _, err := findRawDisk(artifact); err == nil {
+		t.Fatal("should error on qcow2 disks")
+	}
+
+	artifact = &packer.MockArtifact{FilesValue: []string{"out/disk.vmdk", "out/disk.img"}}
+	if path, err := findRawDisk(artifact); err != nil || path != "out/disk.img" {
+		t.Fatalf("bad: %s %v", path, err)
+	}
+}
+
+func TestCreateDiskTarball(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer-gce-import")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	disk := filepath.Join(dir, "packer-vm")
+	content := bytes.Repeat([]byte("disk"), 1024)
+	if err := ioutil.WriteFile(disk, content, 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	tarball := filepath.Join(dir, "disk.tar.gz")
+	if err := createDiskTarball(disk, tarball); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	f, err := os.Open(tarball)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer f.Close()
+
+	gzr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	tr := tar.NewReader(gzr)
+
+	header, err := tr.Next()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if header.Name != "disk.raw" {
+		t.Fatalf("bad name: %s", header.Name)
+	}
+	data, err := ioutil.ReadAll(tr)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !bytes.Equal(data, content) {
+		t.Fatal("bad content")
+	}
+	if _, err := tr.Next(); err == nil {
+		t.Fatal("should contain only one file")
+	}
+}
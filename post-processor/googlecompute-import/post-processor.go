@@ -2,6 +2,7 @@ package googlecomputeimport
 
 import (
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"os"
 	"strings"
@@ -24,17 +25,19 @@ import (
 
 type Config struct {
 	common.PackerConfig `mapstructure:",squash"`
-
-	Bucket            string            `mapstructure:"bucket"`
-	GCSObjectName     string            `mapstructure:"gcs_object_name"`
-	ImageDescription  string            `mapstructure:"image_description"`
-	ImageFamily       string            `mapstructure:"image_family"`
-	ImageLabels       map[string]string `mapstructure:"image_labels"`
-	ImageName         string            `mapstructure:"image_name"`
-	ProjectId         string            `mapstructure:"project_id"`
-	AccountFile       string            `mapstructure:"account_file"`
-	KeepOriginalImage bool              `mapstructure:"keep_input_artifact"`
-	SkipClean         bool              `mapstructure:"skip_clean"`
+	common.UploadConfig `mapstructure:",squash"`
+
+	Bucket               string            `mapstructure:"bucket"`
+	GCSObjectName        string            `mapstructure:"gcs_object_name"`
+	ImageDescription     string            `mapstructure:"image_description"`
+	ImageFamily          string            `mapstructure:"image_family"`
+	ImageGuestOsFeatures []string          `mapstructure:"image_guest_os_features"`
+	ImageLabels          map[string]string `mapstructure:"image_labels"`
+	ImageName            string            `mapstructure:"image_name"`
+	ProjectId            string            `mapstructure:"project_id"`
+	AccountFile          string            `mapstructure:"account_file"`
+	KeepOriginalImage    bool              `mapstructure:"keep_input_artifact"`
+	SkipClean            bool              `mapstructure:"skip_clean"`
 
 	ctx interpolate.Context
 }
@@ -84,6 +87,15 @@ func (p *PostProcessor) Configure(raws ...interface{}) error {
 		}
 	}
 
+	errs = packer.MultiErrorAppend(errs, p.config.UploadConfig.Prepare(16, 1)...)
+
+	for _, feature := range p.config.ImageGuestOsFeatures {
+		if feature == "" || strings.ToUpper(feature) != feature {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf(
+				"Invalid guest OS feature %q: features are upper case, like UEFI_COMPATIBLE", feature))
+		}
+	}
+
 	if len(errs.Errors) > 0 {
 		return errs
 	}
@@ -94,24 +106,53 @@ func (p *PostProcessor) Configure(raws ...interface{}) error {
 func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (packer.Artifact, bool, error) {
 	var err error
 
-	if artifact.BuilderId() != compress.BuilderId {
-		err = fmt.Errorf(
-			"incompatible artifact type: %s\nCan only import from Compress post-processor artifacts",
-			artifact.BuilderId())
-		return nil, false, err
-	}
-
 	p.config.GCSObjectName, err = interpolate.Render(p.config.GCSObjectName, &p.config.ctx)
 	if err != nil {
 		return nil, false, fmt.Errorf("Error rendering gcs_object_name template: %s", err)
 	}
 
-	rawImageGcsPath, err := UploadToBucket(p.config.AccountFile, ui, artifact, p.config.Bucket, p.config.GCSObjectName)
+	var source string
+	if artifact.BuilderId() == compress.BuilderId {
+		ui.Say("Looking for tar.gz file in list of artifacts...")
+		for _, path := range artifact.Files() {
+			ui.Say(fmt.Sprintf("Found artifact %v...", path))
+			if strings.HasSuffix(path, ".tar.gz") {
+				source = path
+				break
+			}
+		}
+
+		if source == "" {
+			return nil, false, fmt.Errorf("No tar.gz file found in list of articats")
+		}
+	} else {
+		// Anything else has to contain a raw disk, which is packed the way
+		// GCE expects it.
+		disk, err := findRawDisk(artifact)
+		if err != nil {
+			return nil, false, err
+		}
+
+		tarball, err := ioutil.TempFile("", "packer-gce-import")
+		if err != nil {
+			return nil, false, err
+		}
+		tarball.Close()
+		defer os.Remove(tarball.Name())
+
+		ui.Say(fmt.Sprintf("Packing %v as disk.raw into a tar.gz file...", disk))
+		if err := createDiskTarball(disk, tarball.Name()); err != nil {
+			return nil, false, err
+		}
+		source = tarball.Name()
+	}
+
+	rawImageGcsPath, err := UploadToBucket(p.config.AccountFile, ui, source, p.config.Bucket, p.config.GCSObjectName, &p.config.UploadConfig)
 	if err != nil {
 		return nil, p.config.KeepOriginalImage, err
 	}
 
-	gceImageArtifact, err := CreateGceImage(p.config.AccountFile, ui, p.config.ProjectId, rawImageGcsPath, p.config.ImageName, p.config.ImageDescription, p.config.ImageFamily, p.config.ImageLabels)
+	gceImageArtifact, err := CreateGceImage(p.config.AccountFile, ui, p.config.ProjectId, rawImageGcsPath, p.config.ImageName, p.config.ImageDescription, p.config.ImageFamily, p.config.ImageLabels, p.config.ImageGuestOsFeatures)
 	if err != nil {
 		return nil, p.config.KeepOriginalImage, err
 	}
@@ -126,7 +167,9 @@ func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (pac
 	return gceImageArtifact, p.config.KeepOriginalImage, nil
 }
 
-func UploadToBucket(accountFile string, ui packer.Ui, artifact packer.Artifact, bucket string, gcsObjectName string) (string, error) {
+// UploadToBucket uploads the file at source to the bucket, in chunks of a
+// resumable upload.
+func UploadToBucket(accountFile string, ui packer.Ui, source string, bucket string, gcsObjectName string, upload *common.UploadConfig) (string, error) {
 	var client *http.Client
 	var account googlecompute.AccountFile
 
@@ -144,42 +187,37 @@ func UploadToBucket(accountFile string, ui packer.Ui, artifact packer.Artifact,
 	}
 
 	client = conf.Client(oauth2.NoContext)
-	service, err := storage.New(client)
+
+	artifactFile, err := os.Open(source)
 	if err != nil {
+		err := fmt.Errorf("error opening %v", source)
 		return "", err
 	}
 
-	ui.Say("Looking for tar.gz file in list of artifacts...")
-	source := ""
-	for _, path := range artifact.Files() {
-		ui.Say(fmt.Sprintf("Found artifact %v...", path))
-		if strings.HasSuffix(path, ".tar.gz") {
-			source = path
-			break
-		}
-	}
-
-	if source == "" {
-		return "", fmt.Errorf("No tar.gz file found in list of articats")
-	}
+	defer artifactFile.Close()
 
-	artifactFile, err := os.Open(source)
+	fi, err := artifactFile.Stat()
 	if err != nil {
-		err := fmt.Errorf("error opening %v", source)
 		return "", err
 	}
 
 	ui.Say(fmt.Sprintf("Uploading file %v to GCS bucket %v/%v...", source, bucket, gcsObjectName))
-	storageObject, err := service.Objects.Insert(bucket, &storage.Object{Name: gcsObjectName}).Media(artifactFile).Do()
-	if err != nil {
-		ui.Say(fmt.Sprintf("Failed to upload: %v", storageObject))
+	u := &resumableUpload{
+		client:     client,
+		baseURL:    gcsUploadURL,
+		chunkSize:  upload.ChunkBytes(),
+		retries:    upload.UploadRetries,
+		retryDelay: time.Second,
+	}
+	if err := u.upload(bucket, gcsObjectName, artifactFile, fi.Size()); err != nil {
+		ui.Say(fmt.Sprintf("Failed to upload: %s", err))
 		return "", err
 	}
 
 	return "https://storage.googleapis.com/" + bucket + "/" + gcsObjectName, nil
 }
 
-func CreateGceImage(accountFile string, ui packer.Ui, project string, rawImageURL string, imageName string, imageDescription string, imageFamily string, imageLabels map[string]string) (packer.Artifact, error) {
+func CreateGceImage(accountFile string, ui packer.Ui, project string, rawImageURL string, imageName string, imageDescription string, imageFamily string, imageLabels map[string]string, imageGuestOsFeatures []string) (packer.Artifact, error) {
 	var client *http.Client
 	var account googlecompute.AccountFile
 
@@ -212,6 +250,12 @@ func CreateGceImage(accountFile string, ui packer.Ui, project string, rawImageUR
 		SourceType:  "RAW",
 	}
 
+	for _, feature := range imageGuestOsFeatures {
+		gceImage.GuestOsFeatures = append(gceImage.GuestOsFeatures, &compute.GuestOsFeature{
+			Type: feature,
+		})
+	}
+
 	ui.Say(fmt.Sprintf("Creating GCE image %v...", imageName))
 	op, err := service.Images.Insert(project, gceImage).Do()
 	if err != nil {
@@ -0,0 +1,214 @@
+package googlecomputeimport
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const gcsUploadURL = "https://www.googleapis.com/upload/storage/v1"
+
+// resumableUpload uploads a file to Cloud Storage in chunks, with the
+// resumable upload protocol. When a chunk fails, the server is asked how
+// much of the file it has, and the upload goes on from there instead of
+// starting over.
+type resumableUpload struct {
+	client    *http.Client
+	baseURL   string
+	chunkSize int64
+
+	// retries is how many times in a row a chunk can fail.
+	retries    int
+	retryDelay time.Duration
+}
+
+// chunkError is the error of a chunk, retryable if sending it again may
+// work, like with an error of the network or of the server.
+type chunkError struct {
+	err       error
+	retryable bool
+}
+
+func (e *chunkError) Error() string {
+	return e.err.Error()
+}
+
+func (u *resumableUpload) upload(bucket, name string, r io.ReaderAt, size int64) error {
+	session, err := u.start(bucket, name, size)
+	if err != nil {
+		return fmt.Errorf("Error starting the upload: %s", err)
+	}
+
+	var offset int64
+	failures := 0
+	for {
+		n := size - offset
+		if n > u.chunkSize {
+			n = u.chunkSize
+		}
+		done, next, err := u.putChunk(session, r, offset, n, size)
+		if err == nil {
+			if done {
+				return nil
+			}
+			offset, failures = next, 0
+			continue
+		}
+
+		failures++
+		if cerr, ok := err.(*chunkError); !ok || !cerr.retryable || failures > u.retries {
+			return err
+		}
+		log.Printf("Error uploading the chunk at %d, retrying: %s", offset, err)
+		time.Sleep(u.delay(failures))
+
+		// The server may have received part of the chunk
+		done, next, err = u.status(session, size)
+		if err != nil {
+			log.Printf("Error getting the status of the upload: %s", err)
+			continue
+		}
+		if done {
+			return nil
+		}
+		offset = next
+	}
+}
+
+// delay is the time to wait after the failures of a chunk, doubled at each
+// failure up to 30 seconds.
+func (u *resumableUpload) delay(failures int) time.Duration {
+	d := u.retryDelay
+	for i := 1; i < failures && d < 30*time.Second; i++ {
+		d *= 2
+	}
+	if d > 30*time.Second {
+		d = 30 * time.Second
+	}
+	return d
+}
+
+// start starts the upload session, and returns its URL.
+func (u *resumableUpload) start(bucket, name string, size int64) (string, error) {
+	query := url.Values{
+		"uploadType": {"resumable"},
+		"name":       {name},
+	}
+	target := fmt.Sprintf("%s/b/%s/o?%s", u.baseURL, url.PathEscape(bucket), query.Encode())
+	body, err := json.Marshal(map[string]string{"name": name})
+	if err != nil {
+		return "", err
+	}
+
+	var lastErr error
+	for i := 0; i <= u.retries; i++ {
+		if i > 0 {
+			time.Sleep(u.delay(i))
+		}
+
+		req, err := http.NewRequest("POST", target, bytes.NewReader(body))
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+		req.Header.Set("X-Upload-Content-Length", strconv.FormatInt(size, 10))
+
+		resp, err := u.client.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		resp.Body.Close()
+		switch {
+		case resp.StatusCode == http.StatusOK:
+			session := resp.Header.Get("Location")
+			if session == "" {
+				return "", fmt.Errorf("no upload session in the response")
+			}
+			return session, nil
+		case retryableStatus(resp.StatusCode):
+			lastErr = fmt.Errorf("unexpected status: %s", resp.Status)
+		default:
+			return "", fmt.Errorf("unexpected status: %s", resp.Status)
+		}
+	}
+	return "", lastErr
+}
+
+// putChunk sends the n bytes at offset. It returns whether the upload is
+// done, or the offset the next chunk starts at.
+func (u *resumableUpload) putChunk(session string, r io.ReaderAt, offset, n, size int64) (bool, int64, error) {
+	contentRange := fmt.Sprintf("bytes %d-%d/%d", offset, offset+n-1, size)
+	if n == 0 {
+		// Nothing is left to send, like for empty files
+		contentRange = fmt.Sprintf("bytes */%d", size)
+	}
+	return u.put(session, io.NewSectionReader(r, offset, n), n, contentRange)
+}
+
+// status asks how much of the file the server has.
+func (u *resumableUpload) status(session string, size int64) (bool, int64, error) {
+	return u.put(session, nil, 0, fmt.Sprintf("bytes */%d", size))
+}
+
+func (u *resumableUpload) put(session string, body io.Reader, n int64, contentRange string) (bool, int64, error) {
+	req, err := http.NewRequest("PUT", session, body)
+	if err != nil {
+		return false, 0, err
+	}
+	if body == nil || n == 0 {
+		req.Body = http.NoBody
+	}
+	req.ContentLength = n
+	req.Header.Set("Content-Range", contentRange)
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return false, 0, &chunkError{err: err, retryable: true}
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusCreated:
+		return true, 0, nil
+	case resp.StatusCode == 308:
+		// Resume Incomplete, the Range header is what the server has
+		next, err := rangeEnd(resp.Header.Get("Range"))
+		return false, next, err
+	default:
+		message, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return false, 0, &chunkError{
+			err:       fmt.Errorf("unexpected status: %s %s", resp.Status, strings.TrimSpace(string(message))),
+			retryable: retryableStatus(resp.StatusCode),
+		}
+	}
+}
+
+// rangeEnd is the offset after a Range header like "bytes=0-1048575", 0
+// when there is none.
+func rangeEnd(header string) (int64, error) {
+	if header == "" {
+		return 0, nil
+	}
+	i := strings.LastIndex(header, "-")
+	if i < 0 {
+		return 0, fmt.Errorf("bad Range header: %q", header)
+	}
+	end, err := strconv.ParseInt(header[i+1:], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("bad Range header: %q", header)
+	}
+	return end + 1, nil
+}
+
+func retryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
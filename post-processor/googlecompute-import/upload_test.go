@@ -0,0 +1,147 @@
+package googlecomputeimport
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// resumableServer implements the resumable upload protocol of Cloud
+// Storage. The chunk at failAt is received halfway and fails once.
+type resumableServer struct {
+	l       sync.Mutex
+	data    []byte
+	size    int64
+	failAt  int64
+	failed  bool
+	puts    int
+	queries int
+}
+
+func (s *resumableServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	if r.Method == "POST" {
+		s.size, _ = strconv.ParseInt(r.Header.Get("X-Upload-Content-Length"), 10, 64)
+		w.Header().Set("Location", "http://"+r.Host+"/session")
+		return
+	}
+
+	contentRange := strings.TrimPrefix(r.Header.Get("Content-Range"), "bytes ")
+	if strings.HasPrefix(contentRange, "*/") {
+		s.queries++
+		s.respond(w)
+		return
+	}
+
+	s.puts++
+	var start int64
+	fmt.Sscanf(contentRange, "%d-", &start)
+	if start != int64(len(s.data)) {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	body, _ := ioutil.ReadAll(r.Body)
+	if start == s.failAt && !s.failed {
+		s.failed = true
+		s.data = append(s.data, body[:len(body)/2]...)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	s.data = append(s.data, body...)
+	s.respond(w)
+}
+
+func (s *resumableServer) respond(w http.ResponseWriter) {
+	if int64(len(s.data)) == s.size {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if len(s.data) > 0 {
+		w.Header().Set("Range", fmt.Sprintf("bytes=0-%d", len(s.data)-1))
+	}
+	w.WriteHeader(308)
+}
+
+func testUpload(t *testing.T, s *resumableServer, contents []byte, retries int) error {
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	u := &resumableUpload{
+		client:    http.DefaultClient,
+		baseURL:   server.URL,
+		chunkSize: 10,
+		retries:   retries,
+	}
+	return u.upload("bucket", "image.tar.gz", bytes.NewReader(contents), int64(len(contents)))
+}
+
+func TestResumableUpload(t *testing.T) {
+	contents := []byte("0123456789abcdefghijklmnopqrstuvwxyz")
+	s := &resumableServer{failAt: -1}
+	if err := testUpload(t, s, contents, 3); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !bytes.Equal(s.data, contents) {
+		t.Fatalf("bad: %q", s.data)
+	}
+	if s.puts != 4 || s.queries != 0 {
+		t.Fatalf("bad: %d %d", s.puts, s.queries)
+	}
+}
+
+func TestResumableUpload_resume(t *testing.T) {
+	contents := []byte("0123456789abcdefghijklmnopqrstuvwxyz")
+	s := &resumableServer{failAt: 20}
+	if err := testUpload(t, s, contents, 3); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !bytes.Equal(s.data, contents) {
+		t.Fatalf("bad: %q", s.data)
+	}
+
+	// Only the rest of the failed chunk is sent again
+	if s.queries != 1 || s.puts != 5 {
+		t.Fatalf("bad: %d %d", s.puts, s.queries)
+	}
+}
+
+func TestResumableUpload_noRetries(t *testing.T) {
+	contents := []byte("0123456789abcdefghijklmnopqrstuvwxyz")
+	s := &resumableServer{failAt: 0}
+	err := testUpload(t, s, contents, 0)
+	if err == nil || !strings.Contains(err.Error(), "503") {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+func TestResumableUpload_empty(t *testing.T) {
+	s := &resumableServer{failAt: -1}
+	if err := testUpload(t, s, nil, 3); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestRangeEnd(t *testing.T) {
+	cases := map[string]int64{
+		"":                0,
+		"bytes=0-0":       1,
+		"bytes=0-1048575": 1048576,
+	}
+	for header, expected := range cases {
+		end, err := rangeEnd(header)
+		if err != nil || end != expected {
+			t.Fatalf("%q: bad: %d %v", header, end, err)
+		}
+	}
+	if _, err := rangeEnd("bytes"); err == nil {
+		t.Fatal("should have error")
+	}
+}
@@ -0,0 +1,36 @@
+package isoremaster
+
+import (
+	"fmt"
+	"os"
+)
+
+const BuilderId = "packer.post-processor.iso-remaster"
+
+type Artifact struct {
+	Path string
+}
+
+func (a *Artifact) BuilderId() string {
+	return BuilderId
+}
+
+func (*Artifact) Id() string {
+	return ""
+}
+
+func (a *Artifact) Files() []string {
+	return []string{a.Path}
+}
+
+func (a *Artifact) String() string {
+	return fmt.Sprintf("remastered ISO: %s", a.Path)
+}
+
+func (*Artifact) State(name string) interface{} {
+	return nil
+}
+
+func (a *Artifact) Destroy() error {
+	return os.Remove(a.Path)
+}
@@ -0,0 +1,168 @@
+package isoremaster
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The well known locations of the El Torito boot images of the install
+// media of the common distributions.
+var (
+	biosBootImages = []string{
+		"isolinux/isolinux.bin",
+		"syslinux/isolinux.bin",
+		"boot/grub/i386-pc/eltorito.img",
+	}
+	efiBootImages = []string{
+		"images/efiboot.img",
+		"boot/grub/efi.img",
+		"efi.img",
+		"EFI/BOOT/efiboot.img",
+		"boot/efiboot.img",
+	}
+)
+
+// The boot config files kernel_args is added to, and the commands in them
+// that load a kernel.
+var (
+	bootConfigDirs = []string{"isolinux", "syslinux", "boot/grub", "EFI/BOOT"}
+	kernelCommands = []string{"append", "linux", "linuxefi"}
+)
+
+// findBootImage returns the first of candidates that exists in dir.
+func findBootImage(dir string, candidates []string) string {
+	for _, c := range candidates {
+		if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(c))); err == nil {
+			return c
+		}
+	}
+	return ""
+}
+
+// volumeID reads the volume ID from the primary volume descriptor of an
+// ISO 9660 image. Installers often find their media by this label, so the
+// remastered ISO has to keep it.
+func volumeID(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	// The primary volume descriptor is the first one, in sector 16.
+	pvd := make([]byte, 72)
+	if _, err := f.ReadAt(pvd, 16*2048); err != nil {
+		return "", fmt.Errorf("Error reading volume descriptor of %s: %s", path, err)
+	}
+	if pvd[0] != 1 || string(pvd[1:6]) != "CD001" {
+		return "", fmt.Errorf("%s is not an ISO 9660 image", path)
+	}
+	return strings.TrimRight(string(pvd[40:72]), " \x00"), nil
+}
+
+// extractMBR writes the system area of an isohybrid ISO, the MBR that
+// makes it bootable from a USB stick, to dst. It returns false when the
+// ISO isn't isohybrid.
+func extractMBR(iso, dst string) (bool, error) {
+	f, err := os.Open(iso)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	mbr := make([]byte, 512)
+	if _, err := io.ReadFull(f, mbr); err != nil {
+		return false, err
+	}
+	if mbr[510] != 0x55 || mbr[511] != 0xaa {
+		return false, nil
+	}
+	// xorriso only takes the boot code, the partition table is its own.
+	return true, ioutil.WriteFile(dst, mbr[:432], 0644)
+}
+
+// addKernelArgs appends args to every kernel command line in the isolinux
+// and grub configs under dir.
+func addKernelArgs(dir, args string) error {
+	for _, d := range bootConfigDirs {
+		matches, err := filepath.Glob(filepath.Join(dir, filepath.FromSlash(d), "*.cfg"))
+		if err != nil {
+			return err
+		}
+		for _, path := range matches {
+			contents, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			updated := appendKernelArgs(contents, args)
+			if bytes.Equal(updated, contents) {
+				continue
+			}
+			if err := ioutil.WriteFile(path, updated, 0644); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func appendKernelArgs(config []byte, args string) []byte {
+	var buf bytes.Buffer
+	scanner := bufio.NewScanner(bytes.NewReader(config))
+	for scanner.Scan() {
+		line := scanner.Text()
+		fields := strings.Fields(line)
+		if len(fields) > 1 {
+			for _, c := range kernelCommands {
+				if strings.EqualFold(fields[0], c) {
+					line = strings.TrimRight(line, " \t") + " " + args
+					break
+				}
+			}
+		}
+		buf.WriteString(line)
+		buf.WriteString("\n")
+	}
+	return buf.Bytes()
+}
+
+// makeWritable adds owner write permission to everything under dir. Files
+// extracted from an ISO are read only, and have to be replaced and removed.
+func makeWritable(dir string) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return nil
+		}
+		return os.Chmod(path, info.Mode().Perm()|0200)
+	})
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
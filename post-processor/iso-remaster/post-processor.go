@@ -0,0 +1,291 @@
+package isoremaster
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/helper/config"
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+
+	Files         map[string]string `mapstructure:"files"`
+	KernelArgs    string            `mapstructure:"kernel_args"`
+	VolumeLabel   string            `mapstructure:"volume_label"`
+	BIOSBootImage string            `mapstructure:"bios_boot_image"`
+	BootCatalog   string            `mapstructure:"boot_catalog"`
+	EFIBootImage  string            `mapstructure:"efi_boot_image"`
+	IsohybridMBR  string            `mapstructure:"isohybrid_mbr"`
+	OutputPath    string            `mapstructure:"output"`
+	XorrisoPath   string            `mapstructure:"xorriso_path"`
+	Keep          bool              `mapstructure:"keep_input_artifact"`
+
+	ctx interpolate.Context
+}
+
+type PostProcessor struct {
+	config Config
+}
+
+type outputPathTemplate struct {
+	BuildName   string
+	BuilderType string
+}
+
+func (p *PostProcessor) Configure(raws ...interface{}) error {
+	err := config.Decode(&p.config, &config.DecodeOpts{
+		Interpolate:        true,
+		InterpolateContext: &p.config.ctx,
+		InterpolateFilter: &interpolate.RenderFilter{
+			Exclude: []string{"output"},
+		},
+	}, raws...)
+	if err != nil {
+		return err
+	}
+
+	if p.config.OutputPath == "" {
+		p.config.OutputPath = "packer_{{.BuildName}}_{{.BuilderType}}.iso"
+	}
+	if p.config.XorrisoPath == "" {
+		p.config.XorrisoPath = "xorriso"
+	}
+
+	errs := new(packer.MultiError)
+
+	if err = interpolate.Validate(p.config.OutputPath, &p.config.ctx); err != nil {
+		errs = packer.MultiErrorAppend(
+			errs, fmt.Errorf("Error parsing output template: %s", err))
+	}
+
+	for dst, src := range p.config.Files {
+		if _, err := os.Stat(src); err != nil {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf(
+				"Bad source for %s in files: %s", dst, err))
+		}
+	}
+
+	if p.config.IsohybridMBR != "" {
+		if _, err := os.Stat(p.config.IsohybridMBR); err != nil {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf(
+				"Bad isohybrid_mbr: %s", err))
+		}
+	}
+
+	if len(p.config.VolumeLabel) > 32 {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf(
+			"volume_label must be at most 32 characters"))
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (packer.Artifact, bool, error) {
+	keep := p.config.Keep
+
+	p.config.ctx.Data = &outputPathTemplate{
+		BuildName:   p.config.PackerBuildName,
+		BuilderType: p.config.PackerBuilderType,
+	}
+	output, err := interpolate.Render(p.config.OutputPath, &p.config.ctx)
+	if err != nil {
+		return nil, keep, fmt.Errorf("Error rendering output template: %s", err)
+	}
+
+	staging, err := ioutil.TempDir("", "packer-iso-remaster")
+	if err != nil {
+		return nil, keep, err
+	}
+	defer func() {
+		makeWritable(staging)
+		os.RemoveAll(staging)
+	}()
+	tree := filepath.Join(staging, "tree")
+
+	label := p.config.VolumeLabel
+	mbr := p.config.IsohybridMBR
+
+	if iso := findISO(artifact.Files()); iso != "" {
+		ui.Message(fmt.Sprintf("Extracting %s...", iso))
+		if err := p.xorriso("-osirrox", "on", "-indev", iso, "-extract", "/", tree); err != nil {
+			return nil, keep, fmt.Errorf("Error extracting %s: %s", iso, err)
+		}
+		if err := makeWritable(tree); err != nil {
+			return nil, keep, err
+		}
+
+		if label == "" {
+			if label, err = volumeID(iso); err != nil {
+				return nil, keep, err
+			}
+		}
+		if mbr == "" {
+			path := filepath.Join(staging, "isohybrid.mbr")
+			ok, err := extractMBR(iso, path)
+			if err != nil {
+				return nil, keep, fmt.Errorf("Error reading MBR of %s: %s", iso, err)
+			}
+			if ok {
+				mbr = path
+			}
+		}
+	} else {
+		files := artifact.Files()
+		if len(files) == 0 {
+			return nil, keep, fmt.Errorf(
+				"Artifact from %s has no files to make an ISO from", artifact.BuilderId())
+		}
+
+		root := commonDir(files)
+		ui.Message(fmt.Sprintf("Copying %d files from %s...", len(files), root))
+		for _, f := range files {
+			rel, err := filepath.Rel(root, f)
+			if err != nil {
+				return nil, keep, err
+			}
+			if err := copyFile(f, filepath.Join(tree, rel)); err != nil {
+				return nil, keep, fmt.Errorf("Error copying %s: %s", f, err)
+			}
+		}
+	}
+
+	if label == "" {
+		label = "PACKER"
+	}
+
+	dsts := make([]string, 0, len(p.config.Files))
+	for dst := range p.config.Files {
+		dsts = append(dsts, dst)
+	}
+	sort.Strings(dsts)
+	for _, dst := range dsts {
+		ui.Message(fmt.Sprintf("Adding %s", dst))
+		target := filepath.Join(tree, filepath.FromSlash(strings.TrimPrefix(dst, "/")))
+		if err := copyFile(p.config.Files[dst], target); err != nil {
+			return nil, keep, fmt.Errorf("Error adding %s: %s", dst, err)
+		}
+	}
+
+	if p.config.KernelArgs != "" {
+		ui.Message(fmt.Sprintf("Adding kernel arguments: %s", p.config.KernelArgs))
+		if err := addKernelArgs(tree, p.config.KernelArgs); err != nil {
+			return nil, keep, fmt.Errorf("Error updating boot configs: %s", err)
+		}
+	}
+
+	args, err := p.mkisofsArgs(tree, label, mbr, output)
+	if err != nil {
+		return nil, keep, err
+	}
+
+	if dir := filepath.Dir(output); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, keep, fmt.Errorf("Error creating %s: %s", dir, err)
+		}
+	}
+
+	ui.Message(fmt.Sprintf("Creating %s...", output))
+	if err := p.xorriso(args...); err != nil {
+		return nil, keep, fmt.Errorf("Error creating %s: %s", output, err)
+	}
+
+	return &Artifact{Path: output}, keep, nil
+}
+
+// mkisofsArgs returns the xorriso arguments that create a hybrid ISO,
+// bootable with BIOS and UEFI from optical media and USB sticks.
+func (p *PostProcessor) mkisofsArgs(tree, label, mbr, output string) ([]string, error) {
+	bios := p.config.BIOSBootImage
+	if bios == "" {
+		bios = findBootImage(tree, biosBootImages)
+	}
+	efi := p.config.EFIBootImage
+	if efi == "" {
+		efi = findBootImage(tree, efiBootImages)
+	}
+	if bios == "" && efi == "" {
+		return nil, fmt.Errorf(
+			"No boot images found, set bios_boot_image or efi_boot_image")
+	}
+
+	catalog := p.config.BootCatalog
+	if catalog == "" {
+		catalog = "boot.cat"
+		if bios != "" {
+			catalog = path.Join(path.Dir(bios), "boot.cat")
+		}
+	}
+
+	args := []string{
+		"-as", "mkisofs",
+		"-o", output,
+		"-V", label,
+		"-r", "-J", "-joliet-long",
+		"-c", catalog,
+	}
+	if mbr != "" {
+		args = append(args, "-isohybrid-mbr", mbr)
+	}
+	if bios != "" {
+		args = append(args,
+			"-b", bios,
+			"-no-emul-boot", "-boot-load-size", "4", "-boot-info-table")
+	}
+	if efi != "" {
+		if bios != "" {
+			args = append(args, "-eltorito-alt-boot")
+		}
+		args = append(args, "-e", efi, "-no-emul-boot")
+		if mbr != "" {
+			args = append(args, "-isohybrid-gpt-basdat")
+		}
+	}
+	return append(args, tree), nil
+}
+
+func (p *PostProcessor) xorriso(args ...string) error {
+	var stderr bytes.Buffer
+	cmd := exec.Command(p.config.XorrisoPath, args...)
+	cmd.Stderr = &stderr
+	log.Printf("Executing: %s %s", p.config.XorrisoPath, strings.Join(args, " "))
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s\n\nStderr: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+func findISO(files []string) string {
+	for _, f := range files {
+		if strings.EqualFold(filepath.Ext(f), ".iso") {
+			return f
+		}
+	}
+	return ""
+}
+
+// commonDir returns the deepest directory that contains all of files.
+func commonDir(files []string) string {
+	dir := filepath.Dir(files[0])
+	for _, f := range files[1:] {
+		for !strings.HasPrefix(f, dir+string(filepath.Separator)) && dir != filepath.Dir(dir) {
+			dir = filepath.Dir(dir)
+		}
+	}
+	return dir
+}
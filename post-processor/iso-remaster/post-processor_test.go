@@ -0,0 +1,185 @@
+package isoremaster
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+// A stand-in for xorriso that copies its arguments into the -o file.
+const fakeXorriso = `#!/bin/sh
+for arg in "$@"; do
+	if [ "$prev" = "-o" ]; then
+		out=$arg
+	fi
+	prev=$arg
+done
+echo "$@" > "$out"
+`
+
+func testUi() *packer.BasicUi {
+	return &packer.BasicUi{
+		Reader: new(bytes.Buffer),
+		Writer: new(bytes.Buffer),
+	}
+}
+
+func TestPostProcessor_ImplementsPostProcessor(t *testing.T) {
+	var _ packer.PostProcessor = new(PostProcessor)
+}
+
+func TestArtifact_ImplementsArtifact(t *testing.T) {
+	var _ packer.Artifact = new(Artifact)
+}
+
+func TestPostProcessorConfigure(t *testing.T) {
+	var p PostProcessor
+	if err := p.Configure(map[string]interface{}{}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	p = PostProcessor{}
+	err := p.Configure(map[string]interface{}{
+		"files": map[string]string{"ks.cfg": "/nonexistent/ks.cfg"},
+	})
+	if err == nil {
+		t.Fatal("should error on missing file")
+	}
+
+	p = PostProcessor{}
+	err = p.Configure(map[string]interface{}{
+		"volume_label": "THIS LABEL IS LONGER THAN 32 CHARS",
+	})
+	if err == nil {
+		t.Fatal("should error on long label")
+	}
+}
+
+func TestVolumeID(t *testing.T) {
+	f, err := ioutil.TempFile("", "packer-iso")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.Remove(f.Name())
+
+	pvd := make([]byte, 2048)
+	pvd[0] = 1
+	copy(pvd[1:], "CD001")
+	copy(pvd[40:72], "RHEL-8-0-0-BaseOS-x86_64        ")
+	if _, err := f.WriteAt(pvd, 16*2048); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	f.Close()
+
+	label, err := volumeID(f.Name())
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if label != "RHEL-8-0-0-BaseOS-x86_64" {
+		t.Fatalf("bad label: %q", label)
+	}
+}
+
+func TestAppendKernelArgs(t *testing.T) {
+	config := "label linux\n  kernel vmlinuz\n  append initrd=initrd.img quiet\n" +
+		"menuentry 'Install' {\n\tlinuxefi /images/pxeboot/vmlinuz quiet\n}\n"
+	expected := "label linux\n  kernel vmlinuz\n  append initrd=initrd.img quiet inst.ks=cdrom:/ks.cfg\n" +
+		"menuentry 'Install' {\n\tlinuxefi /images/pxeboot/vmlinuz quiet inst.ks=cdrom:/ks.cfg\n}\n"
+
+	actual := appendKernelArgs([]byte(config), "inst.ks=cdrom:/ks.cfg")
+	if string(actual) != expected {
+		t.Fatalf("bad config:\n%s", actual)
+	}
+}
+
+func TestCommonDir(t *testing.T) {
+	files := []string{
+		filepath.Join("out", "tree", "isolinux", "isolinux.cfg"),
+		filepath.Join("out", "tree", "images", "efiboot.img"),
+	}
+	if dir := commonDir(files); dir != filepath.Join("out", "tree") {
+		t.Fatalf("bad dir: %s", dir)
+	}
+}
+
+func TestPostProcessor_PostProcess(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as xorriso")
+	}
+
+	dir, err := ioutil.TempDir("", "packer-iso-remaster")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	xorriso := filepath.Join(dir, "xorriso")
+	if err := ioutil.WriteFile(xorriso, []byte(fakeXorriso), 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	tree := filepath.Join(dir, "tree")
+	files := map[string]string{
+		"isolinux/isolinux.bin": "",
+		"isolinux/isolinux.cfg": "  append initrd=initrd.img\n",
+		"images/efiboot.img":    "",
+	}
+	var artifactFiles []string
+	for name, contents := range files {
+		path := filepath.Join(tree, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := ioutil.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		artifactFiles = append(artifactFiles, path)
+	}
+	ks := filepath.Join(dir, "ks.cfg")
+	if err := ioutil.WriteFile(ks, []byte("text\n"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	output := filepath.Join(dir, "out", "install.iso")
+	var p PostProcessor
+	err = p.Configure(map[string]interface{}{
+		"xorriso_path": xorriso,
+		"files":        map[string]string{"ks.cfg": ks},
+		"kernel_args":  "inst.ks=cdrom:/ks.cfg",
+		"volume_label": "INSTALL",
+		"output":       output,
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	args, err := p.mkisofsArgs(tree, "INSTALL", "", output)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := []string{
+		"-as", "mkisofs", "-o", output, "-V", "INSTALL", "-r", "-J", "-joliet-long",
+		"-c", "isolinux/boot.cat",
+		"-b", "isolinux/isolinux.bin", "-no-emul-boot", "-boot-load-size", "4", "-boot-info-table",
+		"-eltorito-alt-boot", "-e", "images/efiboot.img", "-no-emul-boot",
+		tree,
+	}
+	if !reflect.DeepEqual(args, expected) {
+		t.Fatalf("bad args: %#v", args)
+	}
+
+	result, _, err := p.PostProcess(testUi(), &packer.MockArtifact{FilesValue: artifactFiles})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(result.Files(), []string{output}) {
+		t.Fatalf("bad files: %#v", result.Files())
+	}
+	if _, err := os.Stat(output); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
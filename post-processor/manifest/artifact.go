@@ -5,8 +5,9 @@ import "fmt"
 const BuilderId = "packer.post-processor.manifest"
 
 type ArtifactFile struct {
-	Name string `json:"name"`
-	Size int64  `json:"size"`
+	Name     string `json:"name"`
+	Size     int64  `json:"size"`
+	Checksum string `json:"checksum,omitempty"`
 }
 
 type Artifact struct {
@@ -16,6 +17,16 @@ type Artifact struct {
 	ArtifactFiles []ArtifactFile `json:"files"`
 	ArtifactId    string         `json:"artifact_id"`
 	PackerRunUUID string         `json:"packer_run_uuid"`
+
+	BuildDuration int64             `json:"build_duration"`
+	PackerVersion string            `json:"packer_version"`
+	TemplatePath  string            `json:"template_path,omitempty"`
+	GitCommit     string            `json:"git_commit,omitempty"`
+	CustomData    map[string]string `json:"custom_data,omitempty"`
+
+	// UnverifiedDownloads are the URLs the build downloaded without a
+	// checksum, with a checksum type of "none".
+	UnverifiedDownloads []string `json:"unverified_downloads,omitempty"`
 }
 
 func (a *Artifact) BuilderId() string {
@@ -1,30 +1,41 @@
 package manifest
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/hashicorp/packer/common"
+	commonhelper "github.com/hashicorp/packer/helper/common"
 	"github.com/hashicorp/packer/helper/config"
 	"github.com/hashicorp/packer/packer"
 	"github.com/hashicorp/packer/template/interpolate"
+	"github.com/hashicorp/packer/version"
 )
 
 type Config struct {
 	common.PackerConfig `mapstructure:",squash"`
 
-	OutputPath string `mapstructure:"output"`
-	StripPath  bool   `mapstructure:"strip_path"`
+	OutputPath string            `mapstructure:"output"`
+	StripPath  bool              `mapstructure:"strip_path"`
+	CustomData map[string]string `mapstructure:"custom_data"`
 	ctx        interpolate.Context
 }
 
 type PostProcessor struct {
 	config Config
+
+	// configuredAt is the start of the build when the core didn't record
+	// it, as when the post-processor is run on its own.
+	configuredAt time.Time
 }
 
 type ManifestFile struct {
@@ -52,6 +63,8 @@ func (p *PostProcessor) Configure(raws ...interface{}) error {
 		return fmt.Errorf("Error parsing target template: %s", err)
 	}
 
+	p.configuredAt = time.Now()
+
 	return nil
 }
 
@@ -66,6 +79,11 @@ func (p *PostProcessor) PostProcess(ui packer.Ui, source packer.Artifact) (packe
 		af := ArtifactFile{}
 		if fi, err = os.Stat(name); err == nil {
 			af.Size = fi.Size()
+			if fi.Mode().IsRegular() {
+				if af.Checksum, err = sha256File(name); err != nil {
+					return source, true, fmt.Errorf("Unable to checksum %s: %s", name, err)
+				}
+			}
 		}
 		if p.config.StripPath {
 			af.Name = filepath.Base(name)
@@ -78,6 +96,22 @@ func (p *PostProcessor) PostProcess(ui packer.Ui, source packer.Artifact) (packe
 	artifact.BuilderType = p.config.PackerBuilderType
 	artifact.BuildName = p.config.PackerBuildName
 	artifact.BuildTime = time.Now().Unix()
+	// The builds that wait for others ran long after they were configured
+	start, ok := commonhelper.BuildStart(p.config.PackerBuildName)
+	if !ok {
+		start = p.configuredAt
+	}
+	artifact.BuildDuration = int64(time.Since(start).Seconds())
+	artifact.PackerVersion = version.FormattedVersion()
+	artifact.TemplatePath = p.config.ctx.TemplatePath
+	artifact.GitCommit = packer.GitCommit(p.config.ctx.TemplatePath)
+	artifact.CustomData = p.config.CustomData
+	artifact.UnverifiedDownloads = unverifiedDownloads(source)
+	if len(artifact.UnverifiedDownloads) > 0 {
+		ui.Error(fmt.Sprintf(
+			"Warning: the build used downloads that weren't verified with a checksum, "+
+				"they are recorded in the manifest: %s", strings.Join(artifact.UnverifiedDownloads, ", ")))
+	}
 	// Since each post-processor runs in a different process we need a way to
 	// coordinate between various post-processors in a single packer run. We do
 	// this by setting a UUID per run and tracking this in the manifest file.
@@ -136,3 +170,35 @@ func (p *PostProcessor) PostProcess(ui packer.Ui, source packer.Artifact) (packe
 
 	return source, true, nil
 }
+
+func sha256File(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// unverifiedDownloads are the URLs of the "unverified_downloads" state of
+// the artifact. Artifacts of plugins have it as a []interface{}.
+func unverifiedDownloads(source packer.Artifact) []string {
+	switch v := source.State("unverified_downloads").(type) {
+	case []string:
+		return v
+	case []interface{}:
+		urls := make([]string, 0, len(v))
+		for _, url := range v {
+			if s, ok := url.(string); ok {
+				urls = append(urls, s)
+			}
+		}
+		return urls
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	commonhelper "github.com/hashicorp/packer/helper/common"
+	"github.com/hashicorp/packer/packer"
+)
+
+func TestPostProcessor_ImplementsPostProcessor(t *testing.T) {
+	var _ packer.PostProcessor = new(PostProcessor)
+}
+
+func TestPostProcessor_PostProcess(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer-manifest")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	artifactPath := filepath.Join(dir, "image.raw")
+	if err := ioutil.WriteFile(artifactPath, []byte("hello"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	output := filepath.Join(dir, "manifest.json")
+
+	var p PostProcessor
+	err = p.Configure(map[string]interface{}{
+		"output":      output,
+		"strip_path":  true,
+		"custom_data": map[string]string{"owner": "{{ build_name }}"},
+	}, map[string]interface{}{
+		"packer_build_name":    "vm",
+		"packer_builder_type":  "qemu",
+		"packer_template_path": filepath.Join(dir, "template.json"),
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	source := &packer.MockArtifact{
+		IdValue:    "image",
+		FilesValue: []string{artifactPath},
+	}
+	if _, _, err := p.PostProcess(testUi(), source); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	contents, err := ioutil.ReadFile(output)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	var manifest ManifestFile
+	if err := json.Unmarshal(contents, &manifest); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(manifest.Builds) != 1 {
+		t.Fatalf("bad: %#v", manifest.Builds)
+	}
+
+	build := manifest.Builds[0]
+	if build.CustomData["owner"] != "vm" {
+		t.Errorf("bad custom_data: %#v", build.CustomData)
+	}
+	if build.PackerVersion == "" {
+		t.Error("packer_version should be set")
+	}
+	if build.TemplatePath != filepath.Join(dir, "template.json") {
+		t.Errorf("bad template_path: %s", build.TemplatePath)
+	}
+	if build.GitCommit != "" {
+		t.Errorf("git_commit should be empty outside of a repository: %s", build.GitCommit)
+	}
+
+	expected := ArtifactFile{
+		Name:     "image.raw",
+		Size:     5,
+		Checksum: "sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+	}
+	if len(build.ArtifactFiles) != 1 || build.ArtifactFiles[0] != expected {
+		t.Errorf("bad files: %#v", build.ArtifactFiles)
+	}
+}
+
+func TestPostProcessor_PostProcess_buildDuration(t *testing.T) {
+	defer os.Setenv("PACKER_RUN_UUID", os.Getenv("PACKER_RUN_UUID"))
+	os.Setenv("PACKER_RUN_UUID", "manifest-duration-test")
+	defer commonhelper.RemoveSharedState()
+
+	dir, err := ioutil.TempDir("", "packer-manifest")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	output := filepath.Join(dir, "manifest.json")
+
+	var p PostProcessor
+	err = p.Configure(map[string]interface{}{
+		"output":            output,
+		"packer_build_name": "vm",
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The build waited for others before it ran for a minute
+	if err := commonhelper.SetBuildStart("vm", time.Now().Add(-time.Minute)); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	p.configuredAt = time.Now().Add(-time.Hour)
+
+	if _, _, err := p.PostProcess(testUi(), &packer.MockArtifact{IdValue: "image"}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	contents, err := ioutil.ReadFile(output)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	var manifest ManifestFile
+	if err := json.Unmarshal(contents, &manifest); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if d := manifest.Builds[0].BuildDuration; d < 60 || d > 120 {
+		t.Fatalf("should last from the start of the build, not its configuration: %d", d)
+	}
+}
+
+func TestPostProcessor_PostProcess_unverifiedDownloads(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer-manifest")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	output := filepath.Join(dir, "manifest.json")
+
+	var p PostProcessor
+	if err := p.Configure(map[string]interface{}{"output": output}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Artifacts of plugins have the URLs as a []interface{}
+	source := &packer.MockArtifact{
+		StateValues: map[string]interface{}{
+			"unverified_downloads": []interface{}{"http://example.com/os.iso"},
+		},
+	}
+	ui := testUi()
+	if _, _, err := p.PostProcess(ui, source); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !strings.Contains(ui.Writer.(*bytes.Buffer).String(), "http://example.com/os.iso") {
+		t.Errorf("the unverified downloads should be warned about: %q", ui.Writer.(*bytes.Buffer).String())
+	}
+
+	contents, err := ioutil.ReadFile(output)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	var manifest ManifestFile
+	if err := json.Unmarshal(contents, &manifest); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected := []string{"http://example.com/os.iso"}
+	if len(manifest.Builds) != 1 || !reflect.DeepEqual(manifest.Builds[0].UnverifiedDownloads, expected) {
+		t.Fatalf("bad: %#v", manifest.Builds)
+	}
+}
+
+func testUi() *packer.BasicUi {
+	return &packer.BasicUi{
+		Reader: new(bytes.Buffer),
+		Writer: new(bytes.Buffer),
+	}
+}
@@ -0,0 +1,42 @@
+package openstackimport
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack/imageservice/v2/images"
+)
+
+const BuilderId = "packer.post-processor.openstack-import"
+
+// Artifact is the Glance image uploaded by the post-processor.
+type Artifact struct {
+	ImageId string
+	Client  *gophercloud.ServiceClient
+}
+
+func (*Artifact) BuilderId() string {
+	return BuilderId
+}
+
+func (*Artifact) Files() []string {
+	return nil
+}
+
+func (a *Artifact) Id() string {
+	return a.ImageId
+}
+
+func (a *Artifact) String() string {
+	return fmt.Sprintf("A Glance image was created: %v", a.ImageId)
+}
+
+func (*Artifact) State(name string) interface{} {
+	return nil
+}
+
+func (a *Artifact) Destroy() error {
+	log.Printf("Destroying image: %s", a.ImageId)
+	return images.Delete(a.Client, a.ImageId).ExtractErr()
+}
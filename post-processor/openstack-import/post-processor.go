@@ -0,0 +1,294 @@
+package openstackimport
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack/imageservice/v2/images"
+	"github.com/hashicorp/packer/builder/openstack"
+	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/helper/config"
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+type Config struct {
+	common.PackerConfig    `mapstructure:",squash"`
+	openstack.AccessConfig `mapstructure:",squash"`
+
+	ImageName       string            `mapstructure:"image_name"`
+	DiskFormat      string            `mapstructure:"disk_format"`
+	ContainerFormat string            `mapstructure:"container_format"`
+	Visibility      string            `mapstructure:"image_visibility"`
+	Properties      map[string]string `mapstructure:"image_properties"`
+	Tags            []string          `mapstructure:"image_tags"`
+	MinDisk         int               `mapstructure:"image_min_disk"`
+	MinRAM          int               `mapstructure:"image_min_ram"`
+	Store           string            `mapstructure:"image_store"`
+	Keep            bool              `mapstructure:"keep_input_artifact"`
+	RawStateTimeout string            `mapstructure:"state_timeout"`
+
+	stateTimeout time.Duration
+	ctx          interpolate.Context
+}
+
+type PostProcessor struct {
+	config Config
+
+	cancelLock sync.Mutex
+	cancelCh   chan struct{}
+}
+
+// errCancelled is returned once the import is cancelled.
+var errCancelled = errors.New("Import cancelled")
+
+func (p *PostProcessor) Configure(raws ...interface{}) error {
+	err := config.Decode(&p.config, &config.DecodeOpts{
+		Interpolate:        true,
+		InterpolateContext: &p.config.ctx,
+		InterpolateFilter: &interpolate.RenderFilter{
+			Exclude: []string{},
+		},
+	}, raws...)
+	if err != nil {
+		return err
+	}
+
+	if p.config.ContainerFormat == "" {
+		p.config.ContainerFormat = "bare"
+	}
+
+	if p.config.RawStateTimeout == "" {
+		p.config.RawStateTimeout = "30m"
+	}
+
+	errs := new(packer.MultiError)
+
+	stateTimeout, err := time.ParseDuration(p.config.RawStateTimeout)
+	if err != nil {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("Failed parsing state_timeout: %s", err))
+	}
+	p.config.stateTimeout = stateTimeout
+
+	if p.config.ImageName == "" {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("image_name must be set"))
+	}
+
+	switch p.config.DiskFormat {
+	case "", "qcow2", "raw":
+	default:
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf(
+			"invalid disk_format '%s'. Only 'qcow2' or 'raw' are allowed", p.config.DiskFormat))
+	}
+
+	switch images.ImageVisibility(p.config.Visibility) {
+	case "", images.ImageVisibilityPublic, images.ImageVisibilityPrivate,
+		images.ImageVisibilityShared, images.ImageVisibilityCommunity:
+	default:
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf(
+			"invalid image_visibility '%s'. Only 'public', 'private', 'shared' or 'community' are allowed",
+			p.config.Visibility))
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	errs = packer.MultiErrorAppend(errs, p.config.AccessConfig.Prepare(&p.config.ctx)...)
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// Cancel stops the upload of the image or the wait for it, and the image is
+// deleted.
+func (p *PostProcessor) Cancel() {
+	p.cancelLock.Lock()
+	defer p.cancelLock.Unlock()
+
+	if p.cancelCh != nil {
+		close(p.cancelCh)
+		p.cancelCh = nil
+	}
+}
+
+func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (packer.Artifact, bool, error) {
+	cancel := make(chan struct{})
+	p.cancelLock.Lock()
+	p.cancelCh = cancel
+	p.cancelLock.Unlock()
+	defer p.Cancel()
+
+	source, diskFormat, err := findDisk(artifact, p.config.DiskFormat)
+	if err != nil {
+		return nil, false, err
+	}
+
+	client, err := p.config.ImageV2Client()
+	if err != nil {
+		return nil, false, fmt.Errorf("Error initializing image service client: %s", err)
+	}
+
+	opts := images.CreateOpts{
+		Name:            p.config.ImageName,
+		Tags:            p.config.Tags,
+		ContainerFormat: p.config.ContainerFormat,
+		DiskFormat:      diskFormat,
+		MinDisk:         p.config.MinDisk,
+		MinRAM:          p.config.MinRAM,
+		Properties:      p.config.Properties,
+	}
+	if p.config.Visibility != "" {
+		visibility := images.ImageVisibility(p.config.Visibility)
+		opts.Visibility = &visibility
+	}
+
+	ui.Message(fmt.Sprintf("Creating image %s...", p.config.ImageName))
+	image, err := images.Create(client, opts).Extract()
+	if err != nil {
+		return nil, false, fmt.Errorf("Error creating image: %s", err)
+	}
+
+	ui.Message(fmt.Sprintf("Uploading %s to image %s...", source, image.ID))
+	err = uploadImage(client, image.ID, source, p.config.Store, cancel)
+	if err == nil {
+		ui.Message("Waiting for image to become active...")
+		err = waitForImage(client, image.ID, p.config.stateTimeout, cancel)
+	}
+	if err != nil {
+		// Don't leave a half imported image behind
+		ui.Message(fmt.Sprintf("Deleting image %s...", image.ID))
+		if derr := images.Delete(client, image.ID).ExtractErr(); derr != nil {
+			ui.Error(fmt.Sprintf("Error deleting image %s: %s", image.ID, derr))
+		}
+		return nil, false, err
+	}
+
+	return &Artifact{ImageId: image.ID, Client: client}, p.config.Keep, nil
+}
+
+// findDisk returns the disk image of the artifact and its format. A format
+// set in the configuration wins over the one detected from the artifact.
+func findDisk(artifact packer.Artifact, format string) (string, string, error) {
+	// The qemu builder records which of its files is the disk.
+	if name, ok := artifact.State("diskName").(string); ok && name != "" {
+		for _, path := range artifact.Files() {
+			if filepath.Base(path) == name {
+				if format == "" {
+					format, _ = artifact.State("diskType").(string)
+				}
+				return path, format, checkFormat(path, format)
+			}
+		}
+		return "", "", fmt.Errorf("Disk %s not found in artifact", name)
+	}
+
+	for _, path := range artifact.Files() {
+		detected := ""
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".qcow2":
+			detected = "qcow2"
+		case ".raw", ".img":
+			detected = "raw"
+		default:
+			continue
+		}
+		if format == "" {
+			format = detected
+		}
+		return path, format, nil
+	}
+
+	return "", "", fmt.Errorf("No qcow2 or raw disk image found in artifact from %s", artifact.BuilderId())
+}
+
+func checkFormat(path, format string) error {
+	switch format {
+	case "qcow2", "raw":
+		return nil
+	default:
+		return fmt.Errorf("Unsupported disk format '%s' for %s, set disk_format", format, path)
+	}
+}
+
+func uploadImage(client *gophercloud.ServiceClient, id, path, store string, cancel <-chan struct{}) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("Error opening %s: %s", path, err)
+	}
+	defer f.Close()
+
+	headers := map[string]string{"Content-Type": "application/octet-stream"}
+	// Selects the backend of Glance multi-store deployments.
+	if store != "" {
+		headers["X-Image-Meta-Store"] = store
+	}
+
+	_, err = client.Request("PUT", client.ServiceURL("images", id, "file"), &gophercloud.RequestOpts{
+		RawBody:     &cancelReader{r: f, cancel: cancel},
+		MoreHeaders: headers,
+		OkCodes:     []int{204},
+	})
+	select {
+	case <-cancel:
+		return errCancelled
+	default:
+	}
+	if err != nil {
+		return fmt.Errorf("Error uploading %s: %s", path, err)
+	}
+	return nil
+}
+
+// cancelReader stops reading once cancel is closed, which aborts the
+// upload it is the body of.
+type cancelReader struct {
+	r      io.Reader
+	cancel <-chan struct{}
+}
+
+func (r *cancelReader) Read(p []byte) (int, error) {
+	select {
+	case <-r.cancel:
+		return 0, errCancelled
+	default:
+	}
+	return r.r.Read(p)
+}
+
+// waitForImage waits for the image to become active, for up to the timeout.
+func waitForImage(client *gophercloud.ServiceClient, id string, timeout time.Duration, cancel <-chan struct{}) error {
+	deadline := time.After(timeout)
+	for {
+		image, err := images.Get(client, id).Extract()
+		if err != nil {
+			return fmt.Errorf("Error getting image %s: %s", id, err)
+		}
+
+		switch image.Status {
+		case images.ImageStatusActive:
+			return nil
+		case images.ImageStatusKilled, images.ImageStatusDeleted:
+			return fmt.Errorf("Image %s is %s", id, image.Status)
+		}
+
+		log.Printf("Waiting for image status: %s", image.Status)
+		select {
+		case <-cancel:
+			return errCancelled
+		case <-deadline:
+			return fmt.Errorf("Timeout waiting for image %s to become active, it is %s", id, image.Status)
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package openstackimport
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/hashicorp/packer/packer"
+)
+
+func TestPostProcessor_ImplementsPostProcessor(t *testing.T) {
+	var _ packer.CancellablePostProcessor = new(PostProcessor)
+}
+
+func TestArtifact_ImplementsArtifact(t *testing.T) {
+	var _ packer.Artifact = new(Artifact)
+}
+
+func TestPostProcessorConfigure_invalid(t *testing.T) {
+	cases := []map[string]interface{}{
+		{},
+		{"image_name": "foo", "disk_format": "vmdk"},
+		{"image_name": "foo", "image_visibility": "hidden"},
+	}
+
+	for _, c := range cases {
+		var p PostProcessor
+		if err := p.Configure(c); err == nil {
+			t.Errorf("%#v: should error", c)
+		}
+	}
+}
+
+func TestFindDisk(t *testing.T) {
+	cases := []struct {
+		artifact *packer.MockArtifact
+		format   string
+		path     string
+		expected string
+		err      bool
+	}{
+		{
+			artifact: &packer.MockArtifact{
+				FilesValue: []string{"out/disk.qcow2"},
+			},
+			path:     "out/disk.qcow2",
+			expected: "qcow2",
+		},
+		{
+			artifact: &packer.MockArtifact{
+				FilesValue: []string{"out/disk.img"},
+			},
+			path:     "out/disk.img",
+			expected: "raw",
+		},
+		{
+			artifact: &packer.MockArtifact{
+				FilesValue: []string{"out/disk.img"},
+			},
+			format:   "qcow2",
+			path:     "out/disk.img",
+			expected: "qcow2",
+		},
+		{
+			artifact: &packer.MockArtifact{
+				FilesValue: []string{"out/packer-vm", "out/packer-vm.log"},
+				StateValues: map[string]interface{}{
+					"diskName": "packer-vm",
+					"diskType": "raw",
+				},
+			},
+			path:     "out/packer-vm",
+			expected: "raw",
+		},
+		{
+			artifact: &packer.MockArtifact{
+				FilesValue: []string{"out/packer-vm"},
+				StateValues: map[string]interface{}{
+					"diskName": "packer-vm",
+					"diskType": "vmdk",
+				},
+			},
+			err: true,
+		},
+		{
+			artifact: &packer.MockArtifact{
+				FilesValue: []string{"out/disk.vmdk"},
+			},
+			err: true,
+		},
+	}
+
+	for i, tc := range cases {
+		path, format, err := findDisk(tc.artifact, tc.format)
+		if (err != nil) != tc.err {
+			t.Errorf("%d: expected error %t, got %v", i, tc.err, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if path != tc.path || format != tc.expected {
+			t.Errorf("%d: got %s %s", i, path, format)
+		}
+	}
+}
+
+func TestPostProcessorConfigure_stateTimeout(t *testing.T) {
+	var p PostProcessor
+	if err := p.Configure(map[string]interface{}{"image_name": "foo", "state_timeout": "nope"}); err == nil {
+		t.Fatal("should error")
+	}
+}
+
+// testImageClient is a client of an image service whose images stay in
+// the status.
+func testImageClient(t *testing.T, status string) (*gophercloud.ServiceClient, func()) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"id": "image", "status": %q}`, status)
+	}))
+	client := &gophercloud.ServiceClient{
+		ProviderClient: &gophercloud.ProviderClient{HTTPClient: *server.Client()},
+		Endpoint:       server.URL + "/",
+	}
+	return client, server.Close
+}
+
+func TestWaitForImage(t *testing.T) {
+	client, done := testImageClient(t, "active")
+	defer done()
+	if err := waitForImage(client, "image", time.Minute, nil); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestWaitForImage_timeout(t *testing.T) {
+	client, done := testImageClient(t, "saving")
+	defer done()
+	if err := waitForImage(client, "image", 10*time.Millisecond, nil); err == nil {
+		t.Fatal("should time out")
+	}
+}
+
+func TestWaitForImage_cancel(t *testing.T) {
+	client, done := testImageClient(t, "saving")
+	defer done()
+	cancel := make(chan struct{})
+	close(cancel)
+	if err := waitForImage(client, "image", time.Minute, cancel); err != errCancelled {
+		t.Fatalf("should be cancelled: %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+package promote
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/common/uuid"
+)
+
+// lockRetryInterval is how often a lock somebody else has is tried again.
+var lockRetryInterval = time.Second
+
+// Pointer is the latest image of a family, recorded when it is promoted.
+type Pointer struct {
+	Family        string `json:"family"`
+	Version       string `json:"version,omitempty"`
+	ArtifactId    string `json:"artifact_id"`
+	BuilderId     string `json:"builder_id"`
+	BuildName     string `json:"build_name"`
+	BuilderType   string `json:"builder_type"`
+	PackerRunUUID string `json:"packer_run_uuid,omitempty"`
+	PromotedAt    string `json:"promoted_at"`
+}
+
+// Backend stores the pointers of the families, and their locks.
+type Backend interface {
+	// Lock waits up to the timeout for the lock of the family, and returns
+	// the function releasing it.
+	Lock(family string, timeout time.Duration) (func() error, error)
+
+	// Get returns the pointer of the family, nil if it has none yet.
+	Get(family string) (*Pointer, error)
+
+	// Put records the pointer of the family.
+	Put(family string, p *Pointer) error
+}
+
+// lockOwner is who has a lock, written in the locks that aren't released
+// when Packer dies, so the stale ones can be taken over once they expire.
+type lockOwner struct {
+	Token         string `json:"token"`
+	Host          string `json:"host"`
+	Pid           int    `json:"pid"`
+	PackerRunUUID string `json:"packer_run_uuid,omitempty"`
+	Expires       string `json:"expires"`
+}
+
+func newLockOwner(ttl time.Duration) *lockOwner {
+	host, _ := os.Hostname()
+	return &lockOwner{
+		Token:         uuid.TimeOrderedUUID(),
+		Host:          host,
+		Pid:           os.Getpid(),
+		PackerRunUUID: os.Getenv("PACKER_RUN_UUID"),
+		Expires:       time.Now().Add(ttl).UTC().Format(time.RFC3339),
+	}
+}
+
+// staleLock says whether the lock expired. Locks that can't be read, like
+// the ones of other tools, never do.
+func staleLock(raw []byte) bool {
+	var owner lockOwner
+	if err := json.Unmarshal(raw, &owner); err != nil {
+		return false
+	}
+	expires, err := time.Parse(time.RFC3339, owner.Expires)
+	return err == nil && time.Now().After(expires)
+}
+
+// localBackend keeps the pointers in a directory, in a JSON file per
+// family. The lock of a family is an advisory lock of the system on a file
+// next to it, so it is released when Packer dies.
+type localBackend struct {
+	dir string
+}
+
+func (b *localBackend) Lock(family string, timeout time.Duration) (func() error, error) {
+	if err := os.MkdirAll(b.dir, 0755); err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(b.dir, family)
+	deadline := time.Now().Add(timeout)
+	for {
+		unlock, ok, err := common.TryLockPath(path)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return func() error {
+				unlock()
+				return nil
+			}, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timeout waiting for the lock %s.lock", path)
+		}
+		log.Printf("The lock %s.lock is held, waiting...", path)
+		time.Sleep(lockRetryInterval)
+	}
+}
+
+func (b *localBackend) Get(family string) (*Pointer, error) {
+	raw, err := ioutil.ReadFile(filepath.Join(b.dir, family+".json"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodePointer(raw)
+}
+
+func (b *localBackend) Put(family string, p *Pointer) error {
+	raw, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Written next to it first, so the pointer is never half written
+	path := filepath.Join(b.dir, family+".json")
+	if err := ioutil.WriteFile(path+".tmp", append(raw, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+func decodePointer(raw []byte) (*Pointer, error) {
+	var p Pointer
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return nil, fmt.Errorf("Error parsing the pointer: %s", err)
+	}
+	return &p, nil
+}
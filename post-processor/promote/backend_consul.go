@@ -0,0 +1,69 @@
+package promote
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	consulapi "github.com/hashicorp/consul/api"
+)
+
+// consulBackend keeps the pointers in the key/value store of Consul, as JSON
+// under the prefix. The locks are Consul locks, released if Packer dies.
+type consulBackend struct {
+	client *consulapi.Client
+	prefix string
+}
+
+func newConsulBackend(address, prefix string) (*consulBackend, error) {
+	config := consulapi.DefaultConfig()
+	if address != "" {
+		config.Address = address
+	}
+	client, err := consulapi.NewClient(config)
+	if err != nil {
+		return nil, fmt.Errorf("Error getting the Consul client: %s", err)
+	}
+	return &consulBackend{client: client, prefix: prefix}, nil
+}
+
+func (b *consulBackend) Lock(family string, timeout time.Duration) (func() error, error) {
+	lock, err := b.client.LockOpts(&consulapi.LockOptions{
+		Key:          b.prefix + family + ".lock",
+		SessionName:  "packer promote " + family,
+		LockWaitTime: timeout,
+		LockTryOnce:  true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	lost, err := lock.Lock(nil)
+	if err != nil {
+		return nil, err
+	}
+	if lost == nil {
+		return nil, fmt.Errorf("timeout waiting for the lock %s", b.prefix+family+".lock")
+	}
+	return lock.Unlock, nil
+}
+
+func (b *consulBackend) Get(family string) (*Pointer, error) {
+	kv, _, err := b.client.KV().Get(b.prefix+family, nil)
+	if err != nil {
+		return nil, err
+	}
+	if kv == nil {
+		return nil, nil
+	}
+	return decodePointer(kv.Value)
+}
+
+func (b *consulBackend) Put(family string, p *Pointer) error {
+	raw, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	_, err = b.client.KV().Put(&consulapi.KVPair{Key: b.prefix + family, Value: raw}, nil)
+	return err
+}
@@ -0,0 +1,153 @@
+package promote
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// s3Backend keeps the pointers in a bucket, as JSON objects under the
+// prefix. The lock of a family is an object next to it, written only if it
+// doesn't exist yet, or if it is still the expired one, with the conditional
+// writes of S3. It expires after the ttl, since it is left behind if Packer
+// dies.
+type s3Backend struct {
+	conn   *s3.S3
+	bucket string
+	prefix string
+	ttl    time.Duration
+}
+
+func (b *s3Backend) Lock(family string, timeout time.Duration) (func() error, error) {
+	key := b.prefix + family + ".lock"
+	deadline := time.Now().Add(timeout)
+	for {
+		raw, err := json.Marshal(newLockOwner(b.ttl))
+		if err != nil {
+			return nil, err
+		}
+
+		current, etag, err := b.get(key)
+		if err != nil {
+			return nil, err
+		}
+
+		ok := false
+		switch {
+		case current == nil:
+			ok, err = b.putIf(key, raw, "If-None-Match", "*")
+		case staleLock(current):
+			log.Printf("Taking over the expired lock s3://%s/%s: %s", b.bucket, key, current)
+			ok, err = b.putIf(key, raw, "If-Match", etag)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return func() error { return b.unlock(key, raw) }, nil
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timeout waiting for the lock s3://%s/%s: %s", b.bucket, key, current)
+		}
+		log.Printf("The lock s3://%s/%s is held, waiting...", b.bucket, key)
+		time.Sleep(lockRetryInterval)
+	}
+}
+
+// unlock deletes the lock, unless it expired and was taken over.
+func (b *s3Backend) unlock(key string, raw []byte) error {
+	current, etag, err := b.get(key)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(current, raw) {
+		return fmt.Errorf("the lock s3://%s/%s expired and was taken over", b.bucket, key)
+	}
+
+	req, _ := b.conn.DeleteObjectRequest(&s3.DeleteObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(key),
+	})
+	req.HTTPRequest.Header.Set("If-Match", etag)
+	if err := req.Send(); err != nil {
+		return fmt.Errorf("Error deleting s3://%s/%s: %s", b.bucket, key, err)
+	}
+	return nil
+}
+
+func (b *s3Backend) Get(family string) (*Pointer, error) {
+	raw, _, err := b.get(b.prefix + family + ".json")
+	if err != nil || raw == nil {
+		return nil, err
+	}
+	return decodePointer(raw)
+}
+
+func (b *s3Backend) Put(family string, p *Pointer) error {
+	raw, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return b.put(b.prefix+family+".json", raw)
+}
+
+// get returns the content of the object and its ETag, nil if there is none.
+func (b *s3Backend) get(key string) ([]byte, string, error) {
+	resp, err := b.conn.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(key),
+	})
+	if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == s3.ErrCodeNoSuchKey {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("Error getting s3://%s/%s: %s", b.bucket, key, err)
+	}
+	defer resp.Body.Close()
+	raw, err := ioutil.ReadAll(resp.Body)
+	return raw, aws.StringValue(resp.ETag), err
+}
+
+// putIf writes the object if the condition, a header like If-None-Match,
+// holds, and says whether it did.
+func (b *s3Backend) putIf(key string, raw []byte, header, value string) (bool, error) {
+	req, _ := b.conn.PutObjectRequest(&s3.PutObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(raw),
+	})
+	req.HTTPRequest.Header.Set(header, value)
+	err := req.Send()
+	if reqErr, ok := err.(awserr.RequestFailure); ok {
+		switch reqErr.StatusCode() {
+		case http.StatusPreconditionFailed, http.StatusConflict:
+			// Somebody else wrote it first
+			return false, nil
+		}
+	}
+	if err != nil {
+		return false, fmt.Errorf("Error writing s3://%s/%s: %s", b.bucket, key, err)
+	}
+	return true, nil
+}
+
+func (b *s3Backend) put(key string, raw []byte) error {
+	_, err := b.conn.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(raw),
+	})
+	if err != nil {
+		return fmt.Errorf("Error writing s3://%s/%s: %s", b.bucket, key, err)
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package promote
+
+import (
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+func TestLocalBackend(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer-promote")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	b := &localBackend{dir: dir}
+
+	p, err := b.Get("web")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if p != nil {
+		t.Fatalf("bad: %#v", p)
+	}
+
+	expected := &Pointer{Family: "web", Version: "1.0.0", ArtifactId: "ami-1234"}
+	if err := b.Put("web", expected); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	p, err = b.Get("web")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(p, expected) {
+		t.Fatalf("bad: %#v", p)
+	}
+}
+
+func TestLocalBackend_Lock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer-promote")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	b := &localBackend{dir: dir}
+
+	interval := lockRetryInterval
+	lockRetryInterval = 10 * time.Millisecond
+	defer func() { lockRetryInterval = interval }()
+
+	unlock, err := b.Lock("web", time.Second)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := b.Lock("web", 50*time.Millisecond); err == nil {
+		t.Fatal("should time out")
+	}
+
+	// The other families aren't locked
+	unlockDb, err := b.Lock("db", 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	unlockDb()
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		unlock()
+	}()
+	unlock, err = b.Lock("web", time.Second)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := unlock(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+// fakeS3 is a bucket with the conditional writes of S3.
+type fakeS3 struct {
+	lock    sync.Mutex
+	objects map[string][]byte
+}
+
+func etagOf(raw []byte) string {
+	return fmt.Sprintf(`"%x"`, md5.Sum(raw))
+}
+
+func (s *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	current, exists := s.objects[r.URL.Path]
+	switch r.Method {
+	case "GET":
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `<Error><Code>NoSuchKey</Code><Message>not found</Message></Error>`)
+			return
+		}
+		w.Header().Set("ETag", etagOf(current))
+		w.Write(current)
+	case "PUT", "DELETE":
+		if (r.Header.Get("If-None-Match") == "*" && exists) ||
+			(r.Header.Get("If-Match") != "" && r.Header.Get("If-Match") != etagOf(current)) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			fmt.Fprint(w, `<Error><Code>PreconditionFailed</Code><Message>failed</Message></Error>`)
+			return
+		}
+		if r.Method == "DELETE" {
+			delete(s.objects, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		raw, _ := ioutil.ReadAll(r.Body)
+		s.objects[r.URL.Path] = raw
+		w.Header().Set("ETag", etagOf(raw))
+	}
+}
+
+func TestS3Backend_Lock(t *testing.T) {
+	store := &fakeS3{objects: make(map[string][]byte)}
+	srv := httptest.NewServer(store)
+	defer srv.Close()
+
+	interval := lockRetryInterval
+	lockRetryInterval = 10 * time.Millisecond
+	defer func() { lockRetryInterval = interval }()
+
+	conn := s3.New(session.New(&aws.Config{
+		Endpoint:         aws.String(srv.URL),
+		Region:           aws.String("us-east-1"),
+		S3ForcePathStyle: aws.Bool(true),
+		Credentials:      credentials.NewStaticCredentials("id", "secret", ""),
+	}))
+	b := &s3Backend{conn: conn, bucket: "state", prefix: "packer/", ttl: time.Hour}
+
+	unlock, err := b.Lock("web", time.Second)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if _, err := b.Lock("web", 50*time.Millisecond); err == nil {
+		t.Fatal("should time out")
+	}
+	if err := unlock(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(store.objects) != 0 {
+		t.Fatalf("the lock should be deleted: %#v", store.objects)
+	}
+
+	// The lock of a pipeline that died expires
+	b.ttl = -time.Minute
+	unlockDead, err := b.Lock("web", time.Second)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	b.ttl = time.Hour
+	unlock, err = b.Lock("web", time.Second)
+	if err != nil {
+		t.Fatalf("the expired lock should be taken over: %s", err)
+	}
+	if err := unlockDead(); err == nil {
+		t.Fatal("should not release the lock it lost")
+	}
+	if err := unlock(); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestStaleLock(t *testing.T) {
+	raw, _ := json.Marshal(newLockOwner(time.Hour))
+	if staleLock(raw) {
+		t.Fatal("should not be stale")
+	}
+	raw, _ = json.Marshal(newLockOwner(-time.Second))
+	if !staleLock(raw) {
+		t.Fatal("should be stale")
+	}
+	if staleLock([]byte("something else")) {
+		t.Fatal("unknown locks should never be stale")
+	}
+}
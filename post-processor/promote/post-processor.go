@@ -0,0 +1,205 @@
+package promote
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/hashicorp/go-version"
+	awscommon "github.com/hashicorp/packer/builder/amazon/common"
+	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/helper/config"
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+// The backends the pointers and the locks are kept in.
+const (
+	BackendLocal  = "local"
+	BackendS3     = "s3"
+	BackendConsul = "consul"
+)
+
+// familyRe are the names of families, which are part of file names and keys.
+var familyRe = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+type Config struct {
+	common.PackerConfig    `mapstructure:",squash"`
+	awscommon.AccessConfig `mapstructure:",squash"`
+
+	Backend string `mapstructure:"backend"`
+
+	// Path is the directory of the local backend, or the prefix of the keys
+	// of the other ones.
+	Path    string `mapstructure:"path"`
+	Bucket  string `mapstructure:"bucket"`
+	Address string `mapstructure:"address"`
+
+	Family      string        `mapstructure:"family"`
+	Version     string        `mapstructure:"version"`
+	LockTimeout time.Duration `mapstructure:"lock_timeout"`
+	LockTTL     time.Duration `mapstructure:"lock_ttl"`
+
+	ctx interpolate.Context
+}
+
+// PostProcessor records the artifact as the latest image of its family in
+// a state backend, with the family locked, so pipelines building the same
+// family don't promote conflicting images at the same time.
+type PostProcessor struct {
+	config Config
+}
+
+func (p *PostProcessor) Configure(raws ...interface{}) error {
+	err := config.Decode(&p.config, &config.DecodeOpts{
+		Interpolate:        true,
+		InterpolateContext: &p.config.ctx,
+	}, raws...)
+	if err != nil {
+		return err
+	}
+
+	errs := new(packer.MultiError)
+	if p.config.Backend == "" {
+		p.config.Backend = BackendLocal
+	}
+	if p.config.Path == "" {
+		p.config.Path = "packer-state"
+	}
+	if p.config.LockTimeout == 0 {
+		p.config.LockTimeout = 5 * time.Minute
+	}
+	if p.config.LockTTL == 0 {
+		p.config.LockTTL = time.Hour
+	}
+
+	switch p.config.Backend {
+	case BackendLocal, BackendConsul:
+	case BackendS3:
+		errs = packer.MultiErrorAppend(errs, p.config.AccessConfig.Prepare(&p.config.ctx)...)
+		if p.config.Bucket == "" {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf("bucket must be set for the s3 backend"))
+		}
+	default:
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("backend must be one of %s, %s or %s",
+			BackendLocal, BackendS3, BackendConsul))
+	}
+
+	if p.config.Family == "" {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("family must be set"))
+	} else if !familyRe.MatchString(p.config.Family) {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf(
+			"family can only have letters, digits, dots, dashes and underscores: %s", p.config.Family))
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	packer.LogSecretFilter.Set(p.config.AccessKey, p.config.SecretKey, p.config.Token)
+	return nil
+}
+
+func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (packer.Artifact, bool, error) {
+	backend, err := p.newBackend()
+	if err != nil {
+		return artifact, true, err
+	}
+
+	family := p.config.Family
+	ui.Say(fmt.Sprintf("Locking the image family %s...", family))
+	unlock, err := backend.Lock(family, p.config.LockTimeout)
+	if err != nil {
+		return artifact, true, fmt.Errorf("Error locking the image family %s: %s", family, err)
+	}
+	defer func() {
+		if err := unlock(); err != nil {
+			ui.Error(fmt.Sprintf("Error unlocking the image family %s: %s", family, err))
+		}
+	}()
+
+	current, err := backend.Get(family)
+	if err != nil {
+		return artifact, true, fmt.Errorf("Error getting the latest image of %s: %s", family, err)
+	}
+
+	pointer := &Pointer{
+		Family:        family,
+		Version:       p.config.Version,
+		ArtifactId:    artifact.Id(),
+		BuilderId:     artifact.BuilderId(),
+		BuildName:     p.config.PackerBuildName,
+		BuilderType:   p.config.PackerBuilderType,
+		PackerRunUUID: os.Getenv("PACKER_RUN_UUID"),
+		PromotedAt:    time.Now().UTC().Format(time.RFC3339),
+	}
+	if err := checkPromotion(current, pointer); err != nil {
+		if !p.config.PackerForce {
+			return artifact, true, fmt.Errorf("Error promoting %s: %s", family, err)
+		}
+		ui.Message(fmt.Sprintf("Promoting anyway with -force: %s", err))
+	}
+
+	if err := backend.Put(family, pointer); err != nil {
+		return artifact, true, fmt.Errorf("Error recording the latest image of %s: %s", family, err)
+	}
+	ui.Say(fmt.Sprintf("Promoted %s as the latest image of %s", pointer.ArtifactId, family))
+	return artifact, true, nil
+}
+
+func (p *PostProcessor) newBackend() (Backend, error) {
+	switch p.config.Backend {
+	case BackendS3:
+		session, err := p.config.Session()
+		if err != nil {
+			return nil, err
+		}
+		return &s3Backend{
+			conn:   s3.New(session),
+			bucket: p.config.Bucket,
+			prefix: keyPrefix(p.config.Path),
+			ttl:    p.config.LockTTL,
+		}, nil
+	case BackendConsul:
+		return newConsulBackend(p.config.Address, keyPrefix(p.config.Path))
+	}
+	return &localBackend{dir: p.config.Path}, nil
+}
+
+// keyPrefix is the prefix of the keys under the path.
+func keyPrefix(path string) string {
+	return strings.TrimSuffix(path, "/") + "/"
+}
+
+// checkPromotion says why the pointer can't replace the latest one: when
+// its version is older, or the same version of another image.
+func checkPromotion(current, next *Pointer) error {
+	if current == nil || current.Version == "" || next.Version == "" {
+		return nil
+	}
+	if current.ArtifactId == next.ArtifactId && current.BuilderId == next.BuilderId {
+		return nil
+	}
+
+	currentVersion, err1 := version.NewVersion(current.Version)
+	nextVersion, err2 := version.NewVersion(next.Version)
+	if err1 != nil || err2 != nil {
+		log.Printf("Not comparing the versions %q and %q, they aren't both versions", current.Version, next.Version)
+		if current.Version == next.Version {
+			return fmt.Errorf("version %s is already %s", current.Version, current.ArtifactId)
+		}
+		return nil
+	}
+
+	switch {
+	case nextVersion.LessThan(currentVersion):
+		return fmt.Errorf("the latest version is %s (%s), newer than %s", current.Version, current.ArtifactId, next.Version)
+	case nextVersion.Equal(currentVersion):
+		return fmt.Errorf("version %s is already %s", current.Version, current.ArtifactId)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package promote
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+func TestPostProcessor_ImplementsPostProcessor(t *testing.T) {
+	var _ packer.PostProcessor = new(PostProcessor)
+}
+
+func testUi() *packer.BasicUi {
+	return &packer.BasicUi{
+		Reader: new(bytes.Buffer),
+		Writer: new(bytes.Buffer),
+	}
+}
+
+func TestPostProcessorConfigure(t *testing.T) {
+	cases := []struct {
+		config map[string]interface{}
+		err    bool
+	}{
+		{map[string]interface{}{"family": "web"}, false},
+		{map[string]interface{}{}, true},
+		{map[string]interface{}{"family": "../web"}, true},
+		{map[string]interface{}{"family": "web", "backend": "etcd"}, true},
+		{map[string]interface{}{"family": "web", "backend": "s3"}, true},
+		{map[string]interface{}{"family": "web", "backend": "s3", "bucket": "images", "region": "us-east-1"}, false},
+		{map[string]interface{}{"family": "web", "backend": "consul"}, false},
+	}
+
+	for _, tc := range cases {
+		var p PostProcessor
+		if err := p.Configure(tc.config); (err != nil) != tc.err {
+			t.Fatalf("%#v: bad: %s", tc.config, err)
+		}
+	}
+}
+
+func TestPostProcessor_PostProcess(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer-promote")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	promote := func(version, id string, force bool) error {
+		var p PostProcessor
+		err := p.Configure(map[string]interface{}{
+			"family":  "web",
+			"version": version,
+			"path":    dir,
+		}, map[string]interface{}{
+			"packer_build_name":   "amazon",
+			"packer_builder_type": "amazon-ebs",
+			"packer_force":        force,
+		})
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		artifact := &packer.MockArtifact{IdValue: id}
+		result, keep, err := p.PostProcess(testUi(), artifact)
+		if result != artifact || !keep {
+			t.Fatalf("bad: %#v %t", result, keep)
+		}
+		return err
+	}
+
+	if err := promote("1.1.0", "ami-1", false); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := promote("1.0.0", "ami-2", false); err == nil {
+		t.Fatal("should not promote an older version")
+	}
+	if err := promote("1.1.0", "ami-2", false); err == nil {
+		t.Fatal("should not promote another image of the version")
+	}
+	if err := promote("1.1.0", "ami-1", false); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := promote("1.2.0", "ami-3", false); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := promote("1.0.0", "ami-2", true); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	p, err := (&localBackend{dir: dir}).Get("web")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if p.Version != "1.0.0" || p.ArtifactId != "ami-2" || p.BuildName != "amazon" || p.BuilderType != "amazon-ebs" {
+		t.Fatalf("bad: %#v", p)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "web.lock")); !os.IsNotExist(err) {
+		t.Fatalf("the lock should be released: %s", err)
+	}
+}
+
+func TestCheckPromotion(t *testing.T) {
+	cases := []struct {
+		current *Pointer
+		next    *Pointer
+		err     bool
+	}{
+		{nil, &Pointer{Version: "1.0.0"}, false},
+		{&Pointer{ArtifactId: "a"}, &Pointer{ArtifactId: "b"}, false},
+		{&Pointer{Version: "1.0.0", ArtifactId: "a"}, &Pointer{Version: "1.0.1", ArtifactId: "b"}, false},
+		{&Pointer{Version: "1.0.1", ArtifactId: "a"}, &Pointer{Version: "1.0.0", ArtifactId: "b"}, true},
+		{&Pointer{Version: "1.0", ArtifactId: "a"}, &Pointer{Version: "1.0.0", ArtifactId: "b"}, true},
+		{&Pointer{Version: "1.0.0", ArtifactId: "a"}, &Pointer{Version: "1.0.0", ArtifactId: "a"}, false},
+		{&Pointer{Version: "blue", ArtifactId: "a"}, &Pointer{Version: "green", ArtifactId: "b"}, false},
+		{&Pointer{Version: "blue", ArtifactId: "a"}, &Pointer{Version: "blue", ArtifactId: "b"}, true},
+	}
+
+	for _, tc := range cases {
+		if err := checkPromotion(tc.current, tc.next); (err != nil) != tc.err {
+			t.Fatalf("%#v %#v: bad: %s", tc.current, tc.next, err)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package sbom
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/helper/config"
+	"github.com/hashicorp/packer/helper/imagescan"
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+// The file extension of the documents for each syft output format.
+var formatExtensions = map[string]string{
+	"spdx-json":      ".spdx.json",
+	"spdx-tag-value": ".spdx",
+	"cyclonedx-json": ".cdx.json",
+	"cyclonedx-xml":  ".cdx.xml",
+	"syft-json":      ".syft.json",
+}
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+
+	Formats        []string `mapstructure:"formats"`
+	OutputPath     string   `mapstructure:"output"`
+	SyftPath       string   `mapstructure:"syft_path"`
+	MountCommand   string   `mapstructure:"mount_command"`
+	UnmountCommand string   `mapstructure:"unmount_command"`
+
+	ctx interpolate.Context
+}
+
+type PostProcessor struct {
+	config Config
+}
+
+type outputPathTemplate struct {
+	BuildName   string
+	BuilderType string
+	Format      string
+	Extension   string
+}
+
+func (p *PostProcessor) Configure(raws ...interface{}) error {
+	err := config.Decode(&p.config, &config.DecodeOpts{
+		Interpolate:        true,
+		InterpolateContext: &p.config.ctx,
+		InterpolateFilter: &interpolate.RenderFilter{
+			Exclude: []string{
+				"output",
+				"mount_command",
+				"unmount_command",
+			},
+		},
+	}, raws...)
+	if err != nil {
+		return err
+	}
+
+	if len(p.config.Formats) == 0 {
+		p.config.Formats = []string{"spdx-json"}
+	}
+	if p.config.OutputPath == "" {
+		p.config.OutputPath = "packer_{{.BuildName}}_{{.BuilderType}}{{.Extension}}"
+	}
+	if p.config.SyftPath == "" {
+		p.config.SyftPath = "syft"
+	}
+	if p.config.MountCommand == "" {
+		p.config.MountCommand = imagescan.DefaultMountCommand
+	}
+	if p.config.UnmountCommand == "" {
+		p.config.UnmountCommand = imagescan.DefaultUnmountCommand
+	}
+
+	errs := new(packer.MultiError)
+
+	for _, f := range p.config.Formats {
+		if _, ok := formatExtensions[f]; !ok {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf(
+				"Unrecognized SBOM format %q. Must be one of spdx-json, spdx-tag-value, "+
+					"cyclonedx-json, cyclonedx-xml or syft-json", f))
+		}
+	}
+
+	templates := map[string]*string{
+		"output":          &p.config.OutputPath,
+		"mount_command":   &p.config.MountCommand,
+		"unmount_command": &p.config.UnmountCommand,
+	}
+	for key, ptr := range templates {
+		if err = interpolate.Validate(*ptr, &p.config.ctx); err != nil {
+			errs = packer.MultiErrorAppend(
+				errs, fmt.Errorf("Error parsing %s template: %s", key, err))
+		}
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (packer.Artifact, bool, error) {
+	source, disk, err := imagescan.Source(artifact)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if disk != "" {
+		ui.Message(fmt.Sprintf("Mounting %s read-only...", disk))
+		var unmount func() error
+		source, unmount, err = imagescan.Mount(
+			p.config.ctx, p.config.MountCommand, p.config.UnmountCommand, disk)
+		if err != nil {
+			return nil, false, err
+		}
+		defer func() {
+			if err := unmount(); err != nil {
+				ui.Error(err.Error())
+			}
+		}()
+	}
+
+	args := []string{source}
+	var sboms []string
+	for _, format := range p.config.Formats {
+		p.config.ctx.Data = &outputPathTemplate{
+			BuildName:   p.config.PackerBuildName,
+			BuilderType: p.config.PackerBuilderType,
+			Format:      format,
+			Extension:   formatExtensions[format],
+		}
+		path, err := interpolate.Render(p.config.OutputPath, &p.config.ctx)
+		if err != nil {
+			return nil, false, fmt.Errorf("Error rendering output template: %s", err)
+		}
+		if dir := filepath.Dir(path); dir != "" {
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return nil, false, fmt.Errorf("Error creating %s: %s", dir, err)
+			}
+		}
+
+		args = append(args, "-o", format+"="+path)
+		sboms = append(sboms, path)
+	}
+
+	ui.Message(fmt.Sprintf("Generating SBOM for %s...", source))
+	var stderr bytes.Buffer
+	cmd := exec.Command(p.config.SyftPath, args...)
+	cmd.Stderr = &stderr
+	log.Printf("Executing: %s %s", p.config.SyftPath, strings.Join(args, " "))
+	if err := cmd.Run(); err != nil {
+		return nil, false, fmt.Errorf("Error running syft: %s\n\nStderr: %s",
+			err, strings.TrimSpace(stderr.String()))
+	}
+
+	for _, path := range sboms {
+		ui.Message(fmt.Sprintf("Wrote %s", path))
+	}
+
+	return &imagescan.Artifact{Parent: artifact, Reports: sboms}, true, nil
+}
@@ -0,0 +1,118 @@
+package sbom
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/post-processor/docker-tag"
+)
+
+// A stand-in for syft that writes its source into every -o file.
+const fakeSyft = `#!/bin/sh
+source=$1
+shift
+while [ $# -gt 0 ]; do
+	if [ "$1" = "-o" ]; then
+		echo "$source" > "${2#*=}"
+		shift
+	fi
+	shift
+done
+`
+
+func testUi() *packer.BasicUi {
+	return &packer.BasicUi{
+		Reader: new(bytes.Buffer),
+		Writer: new(bytes.Buffer),
+	}
+}
+
+func TestPostProcessor_ImplementsPostProcessor(t *testing.T) {
+	var _ packer.PostProcessor = new(PostProcessor)
+}
+
+func TestPostProcessorConfigure(t *testing.T) {
+	var p PostProcessor
+	if err := p.Configure(map[string]interface{}{}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !reflect.DeepEqual(p.config.Formats, []string{"spdx-json"}) {
+		t.Fatalf("bad formats: %#v", p.config.Formats)
+	}
+
+	p = PostProcessor{}
+	err := p.Configure(map[string]interface{}{
+		"formats": []string{"spdx-json", "rpm"},
+	})
+	if err == nil {
+		t.Fatal("should error on unknown format")
+	}
+}
+
+func TestPostProcessor_PostProcess(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as syft")
+	}
+
+	dir, err := ioutil.TempDir("", "packer-sbom")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	syft := filepath.Join(dir, "syft")
+	if err := ioutil.WriteFile(syft, []byte(fakeSyft), 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	var p PostProcessor
+	err = p.Configure(map[string]interface{}{
+		"syft_path": syft,
+		"formats":   []string{"spdx-json", "cyclonedx-json"},
+		"output":    filepath.Join(dir, "sbom", "{{.BuildName}}{{.Extension}}"),
+	}, map[string]interface{}{
+		"packer_build_name": "app",
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	source := &packer.MockArtifact{
+		BuilderIdValue: dockertag.BuilderId,
+		IdValue:        "app:1.0",
+		FilesValue:     []string{},
+	}
+	result, keep, err := p.PostProcess(testUi(), source)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !keep {
+		t.Fatal("should keep the input artifact")
+	}
+	if result.BuilderId() != dockertag.BuilderId || result.Id() != "app:1.0" {
+		t.Fatalf("should keep the builder ID and ID: %s %s", result.BuilderId(), result.Id())
+	}
+
+	expected := []string{
+		filepath.Join(dir, "sbom", "app.spdx.json"),
+		filepath.Join(dir, "sbom", "app.cdx.json"),
+	}
+	if !reflect.DeepEqual(result.Files(), expected) {
+		t.Fatalf("bad files: %#v", result.Files())
+	}
+	for _, path := range expected {
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if string(contents) != "docker:app:1.0\n" {
+			t.Fatalf("bad source scanned: %s", contents)
+		}
+	}
+}
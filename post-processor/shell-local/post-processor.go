@@ -1,6 +1,12 @@
 package shell_local
 
 import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
 	sl "github.com/hashicorp/packer/common/shell-local"
 	"github.com/hashicorp/packer/packer"
 )
@@ -14,6 +20,17 @@ type ExecuteCommandTemplate struct {
 	Script string
 }
 
+// artifactJSON is what scripts find in the file PACKER_ARTIFACT_JSON
+// points to.
+type artifactJSON struct {
+	BuildName   string   `json:"build_name"`
+	BuilderType string   `json:"builder_type"`
+	BuilderId   string   `json:"builder_id"`
+	Id          string   `json:"id"`
+	Files       []string `json:"files"`
+	String      string   `json:"string"`
+}
+
 func (p *PostProcessor) Configure(raws ...interface{}) error {
 	err := sl.Decode(&p.config, raws...)
 	if err != nil {
@@ -37,12 +54,59 @@ func (p *PostProcessor) Configure(raws ...interface{}) error {
 
 func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (packer.Artifact, bool, error) {
 	// this particular post-processor doesn't do anything with the artifact
-	// except to return it.
+	// except to tell the scripts about it and return it.
+	env, cleanup, err := p.artifactEnv(artifact)
+	if err != nil {
+		return nil, false, err
+	}
+	defer cleanup()
 
-	retBool, retErr := sl.Run(ui, &p.config)
+	retBool, retErr := sl.RunWithEnv(ui, &p.config, env)
 	if !retBool {
 		return nil, retBool, retErr
 	}
 
 	return artifact, retBool, retErr
 }
+
+// artifactEnv returns the environment variables describing artifact, and a
+// function removing the JSON file written for it.
+func (p *PostProcessor) artifactEnv(artifact packer.Artifact) (map[string]string, func(), error) {
+	files := artifact.Files()
+	if files == nil {
+		files = []string{}
+	}
+
+	data, err := json.Marshal(&artifactJSON{
+		BuildName:   p.config.PackerBuildName,
+		BuilderType: p.config.PackerBuilderType,
+		BuilderId:   artifact.BuilderId(),
+		Id:          artifact.Id(),
+		Files:       files,
+		String:      artifact.String(),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tf, err := ioutil.TempFile("", "packer-artifact")
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error writing artifact JSON: %s", err)
+	}
+	_, err = tf.Write(data)
+	if cerr := tf.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tf.Name())
+		return nil, nil, fmt.Errorf("Error writing artifact JSON: %s", err)
+	}
+
+	env := map[string]string{
+		"PACKER_ARTIFACT_BUILDER_ID": artifact.BuilderId(),
+		"PACKER_ARTIFACT_ID":         artifact.Id(),
+		"PACKER_ARTIFACT_FILES":      strings.Join(files, " "),
+		"PACKER_ARTIFACT_JSON":       tf.Name(),
+	}
+	return env, func() { os.Remove(tf.Name()) }, nil
+}
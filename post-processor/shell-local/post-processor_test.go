@@ -1,8 +1,12 @@
 package shell_local
 
 import (
+	"bytes"
+	"encoding/json"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"reflect"
 	"runtime"
 	"testing"
 
@@ -263,3 +267,61 @@ func TestPostProcessorPrepare_EnvironmentVars(t *testing.T) {
 		t.Fatalf("should not have error: %s", err)
 	}
 }
+
+func TestPostProcessor_PostProcessArtifactEnv(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script")
+	}
+
+	dir, err := ioutil.TempDir("", "packer-shell-local")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "out")
+
+	raws := map[string]interface{}{
+		"inline": []interface{}{
+			`echo "$PACKER_ARTIFACT_BUILDER_ID $PACKER_ARTIFACT_ID $PACKER_ARTIFACT_FILES" > ` + out,
+			`cp "$PACKER_ARTIFACT_JSON" ` + out + `.json`,
+		},
+	}
+	p := new(PostProcessor)
+	if err := p.Configure(raws, map[string]interface{}{"packer_build_name": "vm"}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	ui := &packer.BasicUi{
+		Reader: new(bytes.Buffer),
+		Writer: new(bytes.Buffer),
+	}
+	artifact := &packer.MockArtifact{
+		BuilderIdValue: "bid",
+		IdValue:        "us-east-1:ami-123",
+		FilesValue:     []string{"a.vmdk", "a.vmx"},
+	}
+	if _, _, err := p.PostProcess(ui, artifact); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	contents, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if string(contents) != "bid us-east-1:ami-123 a.vmdk a.vmx\n" {
+		t.Fatalf("bad env: %q", contents)
+	}
+
+	contents, err = ioutil.ReadFile(out + ".json")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	var data artifactJSON
+	if err := json.Unmarshal(contents, &data); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if data.BuildName != "vm" || data.Id != "us-east-1:ami-123" ||
+		!reflect.DeepEqual(data.Files, []string{"a.vmdk", "a.vmx"}) {
+		t.Fatalf("bad json: %#v", data)
+	}
+}
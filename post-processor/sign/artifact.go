@@ -0,0 +1,51 @@
+package sign
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+// Artifact is the signed artifact with the signatures added to its files.
+// It keeps the builder ID and ID of the signed artifact so post-processors
+// further down the chain still accept it.
+type Artifact struct {
+	parent     packer.Artifact
+	signatures []string
+}
+
+func (a *Artifact) BuilderId() string {
+	return a.parent.BuilderId()
+}
+
+func (a *Artifact) Files() []string {
+	return append(append([]string{}, a.parent.Files()...), a.signatures...)
+}
+
+func (a *Artifact) Id() string {
+	return a.parent.Id()
+}
+
+func (a *Artifact) String() string {
+	if len(a.signatures) == 0 {
+		return a.parent.String()
+	}
+	return fmt.Sprintf("%s\nSignatures: %s", a.parent.String(), strings.Join(a.signatures, ", "))
+}
+
+func (a *Artifact) State(name string) interface{} {
+	return a.parent.State(name)
+}
+
+// Destroy only removes the signatures, the signed artifact is kept as the
+// input artifact of the post-processor.
+func (a *Artifact) Destroy() error {
+	for _, f := range a.signatures {
+		if err := os.RemoveAll(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package sign
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os/exec"
+	"strings"
+
+	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/helper/config"
+	"github.com/hashicorp/packer/helper/signing"
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/post-processor/docker-import"
+	"github.com/hashicorp/packer/post-processor/docker-tag"
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+type Attestation struct {
+	Predicate string `mapstructure:"predicate"`
+	Type      string `mapstructure:"type"`
+}
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+	signing.Config      `mapstructure:",squash"`
+
+	Attestations []Attestation `mapstructure:"attestations"`
+
+	ctx interpolate.Context
+}
+
+type PostProcessor struct {
+	config Config
+}
+
+func (p *PostProcessor) Configure(raws ...interface{}) error {
+	err := config.Decode(&p.config, &config.DecodeOpts{
+		Interpolate:        true,
+		InterpolateContext: &p.config.ctx,
+	}, raws...)
+	if err != nil {
+		return err
+	}
+
+	errs := new(packer.MultiError)
+	errs = packer.MultiErrorAppend(errs, p.config.Config.Prepare()...)
+
+	if p.config.SignWith == "" {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("sign_with must be set"))
+	}
+
+	for i := range p.config.Attestations {
+		a := &p.config.Attestations[i]
+		if a.Predicate == "" {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf(
+				"attestations[%d]: predicate must be set", i))
+		}
+		if a.Type == "" {
+			a.Type = "custom"
+		}
+	}
+	if len(p.config.Attestations) > 0 && p.config.SignWith != "cosign" {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf(
+			"attestations can only be signed with cosign"))
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (packer.Artifact, bool, error) {
+	switch artifact.BuilderId() {
+	case dockerimport.BuilderId, dockertag.BuilderId:
+		return p.signImage(ui, artifact)
+	}
+
+	if len(p.config.Attestations) > 0 {
+		return nil, false, fmt.Errorf(
+			"Attestations can only be attached to docker images, not to artifacts from %s",
+			artifact.BuilderId())
+	}
+
+	var signatures []string
+	for _, path := range artifact.Files() {
+		ui.Message(fmt.Sprintf("Signing %s with %s", path, p.config.SignWith))
+		files, err := p.config.SignFile(path)
+		if err != nil {
+			return nil, false, err
+		}
+		signatures = append(signatures, files...)
+	}
+
+	return &Artifact{parent: artifact, signatures: signatures}, true, nil
+}
+
+// signImage signs a docker image that has been pushed to a registry, and
+// attaches the attestations to it.
+func (p *PostProcessor) signImage(ui packer.Ui, artifact packer.Artifact) (packer.Artifact, bool, error) {
+	if p.config.SignWith != "cosign" {
+		return nil, false, fmt.Errorf("Docker images can only be signed with cosign")
+	}
+
+	ref, err := imageDigest(artifact.Id())
+	if err != nil {
+		return nil, false, err
+	}
+
+	ui.Message(fmt.Sprintf("Signing %s", ref))
+	if err := p.config.SignImage(ref); err != nil {
+		return nil, false, err
+	}
+
+	for _, a := range p.config.Attestations {
+		ui.Message(fmt.Sprintf("Attesting %s (%s) for %s", a.Predicate, a.Type, ref))
+		if err := p.config.AttestImage(ref, a.Predicate, a.Type); err != nil {
+			return nil, false, err
+		}
+	}
+
+	return &Artifact{parent: artifact}, true, nil
+}
+
+// imageDigest returns the digest reference, like repo@sha256:..., the image
+// name was pushed as. Signing the digest rather than a tag makes sure the
+// signature is for the image that was built.
+func imageDigest(name string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("docker", "image", "inspect", "--format", "{{json .RepoDigests}}", name)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	log.Printf("Executing: %s", strings.Join(cmd.Args, " "))
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("Error inspecting %s: %s\n\nStderr: %s",
+			name, err, strings.TrimSpace(stderr.String()))
+	}
+
+	var digests []string
+	if err := json.Unmarshal(stdout.Bytes(), &digests); err != nil {
+		return "", fmt.Errorf("Error parsing digests of %s: %s", name, err)
+	}
+	return findDigest(name, digests)
+}
+
+func findDigest(name string, digests []string) (string, error) {
+	repo := name
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		repo = name[:i]
+	}
+
+	for _, d := range digests {
+		if strings.HasPrefix(d, repo+"@") {
+			return d, nil
+		}
+	}
+	return "", fmt.Errorf(
+		"%s has no digest in %s. Push it with the docker-push post-processor before signing it",
+		name, repo)
+}
@@ -0,0 +1,127 @@
+package sign
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+// A stand-in for gpg that writes the --output file.
+const fakeGPG = `#!/bin/sh
+while [ $# -gt 0 ]; do
+	if [ "$1" = "--output" ]; then
+		echo signature > "$2"
+		shift
+	fi
+	shift
+done
+`
+
+func testUi() *packer.BasicUi {
+	return &packer.BasicUi{
+		Reader: new(bytes.Buffer),
+		Writer: new(bytes.Buffer),
+	}
+}
+
+func TestPostProcessor_ImplementsPostProcessor(t *testing.T) {
+	var _ packer.PostProcessor = new(PostProcessor)
+}
+
+func TestArtifact_ImplementsArtifact(t *testing.T) {
+	var _ packer.Artifact = new(Artifact)
+}
+
+func TestPostProcessorConfigure(t *testing.T) {
+	var p PostProcessor
+	if err := p.Configure(map[string]interface{}{}); err == nil {
+		t.Fatal("should require sign_with")
+	}
+
+	p = PostProcessor{}
+	err := p.Configure(map[string]interface{}{
+		"sign_with":    "cosign",
+		"attestations": []map[string]interface{}{{"predicate": "sbom.spdx.json"}},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if p.config.Attestations[0].Type != "custom" {
+		t.Fatalf("bad type: %s", p.config.Attestations[0].Type)
+	}
+
+	p = PostProcessor{}
+	err = p.Configure(map[string]interface{}{
+		"sign_with":    "gpg",
+		"attestations": []map[string]interface{}{{"predicate": "sbom.spdx.json"}},
+	})
+	if err == nil {
+		t.Fatal("should require cosign for attestations")
+	}
+}
+
+func TestFindDigest(t *testing.T) {
+	digests := []string{
+		"registry.example.com:5000/other@sha256:aaa",
+		"registry.example.com:5000/app@sha256:bbb",
+	}
+
+	d, err := findDigest("registry.example.com:5000/app:1.0", digests)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if d != "registry.example.com:5000/app@sha256:bbb" {
+		t.Fatalf("bad digest: %s", d)
+	}
+
+	if _, err := findDigest("registry.example.com:5000/missing", digests); err == nil {
+		t.Fatal("should error when the image wasn't pushed")
+	}
+}
+
+func TestPostProcessor_PostProcessFiles(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as gpg")
+	}
+
+	dir, err := ioutil.TempDir("", "packer-sign")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "gpg"), []byte(fakeGPG), 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	var p PostProcessor
+	if err := p.Configure(map[string]interface{}{"sign_with": "gpg"}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	disk := filepath.Join(dir, "disk.raw")
+	source := &packer.MockArtifact{FilesValue: []string{disk}}
+	result, keep, err := p.PostProcess(testUi(), source)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !keep {
+		t.Fatal("should keep the input artifact")
+	}
+
+	expected := []string{disk, disk + ".asc"}
+	if !reflect.DeepEqual(result.Files(), expected) {
+		t.Fatalf("bad files: %#v", result.Files())
+	}
+	if _, err := os.Stat(disk + ".asc"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
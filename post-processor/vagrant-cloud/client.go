@@ -2,6 +2,7 @@ package vagrantcloud
 
 import (
 	"bytes"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -35,11 +36,14 @@ func (v VagrantCloudErrors) FormatErrors() string {
 	return strings.Join(errs, ". ")
 }
 
-func (v VagrantCloudClient) New(baseUrl string, token string) (*VagrantCloudClient, error) {
+func (v VagrantCloudClient) New(baseUrl string, token string, insecureSkipTLSVerify bool) (*VagrantCloudClient, error) {
 	c := &VagrantCloudClient{
 		client: &http.Client{
 			Transport: &http.Transport{
 				Proxy: http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{
+					InsecureSkipVerify: insecureSkipTLSVerify,
+				},
 			},
 		},
 		BaseURL:     baseUrl,
@@ -113,7 +117,20 @@ func (v *VagrantCloudClient) Delete(path string) (*http.Response, error) {
 	return resp, err
 }
 
+// Upload uploads the file at path to url, authenticating with the access
+// token.
 func (v *VagrantCloudClient) Upload(path string, url string) (*http.Response, error) {
+	return v.upload(path, url, true)
+}
+
+// DirectUpload uploads the file at path to a pre-signed url, such as one
+// pointing directly at object storage. The access token is not sent, since
+// the url carries its own authorization.
+func (v *VagrantCloudClient) DirectUpload(path string, url string) (*http.Response, error) {
+	return v.upload(path, url, false)
+}
+
+func (v *VagrantCloudClient) upload(path string, url string, auth bool) (*http.Response, error) {
 	file, err := os.Open(path)
 
 	if err != nil {
@@ -128,7 +145,12 @@ func (v *VagrantCloudClient) Upload(path string, url string) (*http.Response, er
 
 	defer file.Close()
 
-	request, err := v.newRequest("PUT", url, file)
+	var request *http.Request
+	if auth {
+		request, err = v.newRequest("PUT", url, file)
+	} else {
+		request, err = http.NewRequest("PUT", url, file)
+	}
 
 	if err != nil {
 		return nil, fmt.Errorf("Error preparing upload request: %s", err)
@@ -167,12 +189,37 @@ func (v *VagrantCloudClient) Post(path string, body interface{}) (*http.Response
 	return resp, err
 }
 
-func (v *VagrantCloudClient) Put(path string) (*http.Response, error) {
+func (v *VagrantCloudClient) Put(path string, body interface{}) (*http.Response, error) {
 	reqUrl := fmt.Sprintf("%s/%s", v.BaseURL, path)
 
+	var encBody io.Reader
+	if body != nil {
+		var err error
+		if encBody, err = encodeBody(body); err != nil {
+			return nil, fmt.Errorf("Error encoding body for request: %s", err)
+		}
+	}
+
 	log.Printf("Post-Processor Vagrant Cloud API PUT: %s", reqUrl)
 
-	req, err := v.newRequest("PUT", reqUrl, nil)
+	req, err := v.newRequest("PUT", reqUrl, encBody)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := v.client.Do(req)
+
+	log.Printf("Post-Processor Vagrant Cloud API Response: \n\n%+v", resp)
+
+	return resp, err
+}
+
+// Callback notifies the server that a direct upload has completed. The url
+// is absolute, as returned when preparing the upload.
+func (v *VagrantCloudClient) Callback(url string) (*http.Response, error) {
+	log.Printf("Post-Processor Vagrant Cloud API Callback: %s", url)
+
+	req, err := v.newRequest("PUT", url, nil)
 	if err != nil {
 		return nil, err
 	}
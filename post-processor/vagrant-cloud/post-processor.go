@@ -27,8 +27,15 @@ type Config struct {
 	VersionDescription string `mapstructure:"version_description"`
 	NoRelease          bool   `mapstructure:"no_release"`
 
-	AccessToken     string `mapstructure:"access_token"`
-	VagrantCloudUrl string `mapstructure:"vagrant_cloud_url"`
+	AccessToken           string `mapstructure:"access_token"`
+	VagrantCloudUrl       string `mapstructure:"vagrant_cloud_url"`
+	InsecureSkipTLSVerify bool   `mapstructure:"insecure_skip_tls_verify"`
+	DirectUpload          bool   `mapstructure:"direct_upload"`
+
+	// UploadRetries is how many times the box is uploaded again when the
+	// upload fails. Vagrant Cloud takes the box in one request, so it can't
+	// be sent in chunks.
+	UploadRetries int `mapstructure:"upload_retries"`
 
 	BoxDownloadUrl string `mapstructure:"box_download_url"`
 
@@ -77,9 +84,17 @@ func (p *PostProcessor) Configure(raws ...interface{}) error {
 		p.config.AccessToken = envToken
 	}
 
+	if p.config.UploadRetries == 0 {
+		p.config.UploadRetries = 3
+	}
+
 	// Accumulate any errors
 	errs := new(packer.MultiError)
 
+	if p.config.UploadRetries < 0 {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("upload_retries can't be negative"))
+	}
+
 	// required configuration
 	templates := map[string]*string{
 		"box_tag":      &p.config.Tag,
@@ -95,7 +110,7 @@ func (p *PostProcessor) Configure(raws ...interface{}) error {
 	}
 
 	// create the HTTP client
-	p.client, err = VagrantCloudClient{}.New(p.config.VagrantCloudUrl, p.config.AccessToken)
+	p.client, err = VagrantCloudClient{}.New(p.config.VagrantCloudUrl, p.config.AccessToken, p.config.InsecureSkipTLSVerify)
 	if err != nil {
 		errs = packer.MultiErrorAppend(
 			errs, fmt.Errorf("Failed to verify authentication token: %v", err))
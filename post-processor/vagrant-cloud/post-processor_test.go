@@ -2,12 +2,14 @@ package vagrantcloud
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"testing"
 
+	"github.com/hashicorp/packer/helper/multistep"
 	"github.com/hashicorp/packer/packer"
 )
 
@@ -111,6 +113,98 @@ func TestPostProcessor_Configure_Bad(t *testing.T) {
 	}
 }
 
+func TestPostProcessor_Configure_uploadRetries(t *testing.T) {
+	server := newSecureServer("foo", nil)
+	defer server.Close()
+
+	config := testGoodConfig()
+	config["vagrant_cloud_url"] = server.URL
+	var p PostProcessor
+	if err := p.Configure(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if p.config.UploadRetries != 3 {
+		t.Fatalf("bad: %d", p.config.UploadRetries)
+	}
+
+	config["upload_retries"] = -1
+	p = PostProcessor{}
+	if err := p.Configure(config); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestPostProcessor_Configure_insecureSkipTLSVerify(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("authorization") != "Bearer foo" {
+			http.Error(rw, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	config := testGoodConfig()
+	config["vagrant_cloud_url"] = server.URL
+
+	var p PostProcessor
+	if err := p.Configure(config); err == nil {
+		t.Fatal("should fail to verify self-signed certificate")
+	}
+
+	config["insecure_skip_tls_verify"] = true
+	if err := p.Configure(config); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+}
+
+func TestVersion_HasProvider(t *testing.T) {
+	v := &Version{
+		Version:   "1.0.0",
+		Status:    "active",
+		Providers: []*Provider{{Name: "virtualbox"}},
+	}
+
+	if ok, p := v.HasProvider("virtualbox"); !ok || p.Name != "virtualbox" {
+		t.Fatalf("bad: %#v", p)
+	}
+	if ok, _ := v.HasProvider("vmware_desktop"); ok {
+		t.Fatal("should not have provider")
+	}
+	if !v.IsReleased() {
+		t.Fatal("should be released")
+	}
+}
+
+func TestStepPrepareUpload_released(t *testing.T) {
+	state := new(multistep.BasicStateBag)
+	state.Put("client", (*VagrantCloudClient)(nil))
+	state.Put("ui", testUi())
+	state.Put("config", Config{})
+	state.Put("artifactFilePath", "package.box")
+	state.Put("box", &Box{Tag: "hashicorp/precise64"})
+	state.Put("provider", &Provider{Name: "virtualbox"})
+	state.Put("version", &Version{
+		Version:   "1.0.0",
+		Status:    "active",
+		Providers: []*Provider{{Name: "virtualbox"}},
+	})
+
+	// The box is already there, so neither step calls the API
+	if action := new(stepPrepareUpload).Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+	if _, ok := state.GetOk("upload"); ok {
+		t.Fatal("should not prepare an upload")
+	}
+	if action := new(stepUpload).Run(context.Background(), state); action != multistep.ActionContinue {
+		t.Fatalf("bad action: %#v", action)
+	}
+
+	state.Put("provider", &Provider{Name: "vmware_desktop"})
+	if action := new(stepPrepareUpload).Run(context.Background(), state); action != multistep.ActionHalt {
+		t.Fatalf("bad action: %#v", action)
+	}
+}
+
 func testUi() *packer.BasicUi {
 	return &packer.BasicUi{
 		Reader: new(bytes.Buffer),
@@ -27,8 +27,6 @@ func (s *stepCreateProvider) Run(_ context.Context, state multistep.StateBag) mu
 	providerName := state.Get("providerName").(string)
 	downloadUrl := state.Get("boxDownloadUrl").(string)
 
-	path := fmt.Sprintf("box/%s/version/%v/providers", box.Tag, version.Version)
-
 	provider := &Provider{Name: providerName}
 
 	if downloadUrl != "" {
@@ -41,6 +39,36 @@ func (s *stepCreateProvider) Run(_ context.Context, state multistep.StateBag) mu
 
 	ui.Say(fmt.Sprintf("Creating provider: %s", providerName))
 
+	if hasProvider, p := version.HasProvider(providerName); hasProvider {
+		// A re-run of the same build; reuse the provider rather than
+		// failing, but never delete it on cleanup since we didn't create it.
+		if version.IsReleased() || p.Url == provider.Url {
+			ui.Message("Provider exists, skipping creation")
+			state.Put("provider", p)
+			return multistep.ActionContinue
+		}
+
+		ui.Message("Provider exists, updating")
+		path := fmt.Sprintf("box/%s/version/%v/provider/%s", box.Tag, version.Version, providerName)
+		resp, err := client.Put(path, wrapper)
+		if err != nil || (resp.StatusCode != 200) {
+			cloudErrors := &VagrantCloudErrors{}
+			err = decodeBody(resp, cloudErrors)
+			state.Put("error", fmt.Errorf("Error updating provider: %s", cloudErrors.FormatErrors()))
+			return multistep.ActionHalt
+		}
+
+		if err = decodeBody(resp, provider); err != nil {
+			state.Put("error", fmt.Errorf("Error parsing provider response: %s", err))
+			return multistep.ActionHalt
+		}
+
+		state.Put("provider", provider)
+		return multistep.ActionContinue
+	}
+
+	path := fmt.Sprintf("box/%s/version/%v/providers", box.Tag, version.Version)
+
 	resp, err := client.Post(path, wrapper)
 
 	if err != nil || (resp.StatusCode != 200) {
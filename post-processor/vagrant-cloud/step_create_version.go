@@ -9,8 +9,25 @@ import (
 )
 
 type Version struct {
-	Version     string `json:"version"`
-	Description string `json:"description,omitempty"`
+	Version     string      `json:"version"`
+	Description string      `json:"description,omitempty"`
+	Status      string      `json:"status,omitempty"`
+	Providers   []*Provider `json:"providers,omitempty"`
+}
+
+// IsReleased returns whether the version has been released. The providers
+// of a released version can no longer be changed.
+func (v *Version) IsReleased() bool {
+	return v.Status == "active"
+}
+
+func (v *Version) HasProvider(name string) (bool, *Provider) {
+	for _, p := range v.Providers {
+		if p.Name == name {
+			return true, p
+		}
+	}
+	return false, nil
 }
 
 type stepCreateVersion struct {
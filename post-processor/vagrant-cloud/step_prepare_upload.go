@@ -10,6 +10,7 @@ import (
 
 type Upload struct {
 	UploadPath string `json:"upload_path"`
+	Callback   string `json:"callback,omitempty"`
 }
 
 type stepPrepareUpload struct {
@@ -23,11 +24,29 @@ func (s *stepPrepareUpload) Run(_ context.Context, state multistep.StateBag) mul
 	provider := state.Get("provider").(*Provider)
 	artifactFilePath := state.Get("artifactFilePath").(string)
 
+	config := state.Get("config").(Config)
+
 	path := fmt.Sprintf("box/%s/version/%v/provider/%s/upload", box.Tag, version.Version, provider.Name)
+	if config.DirectUpload {
+		path = path + "/direct"
+	}
 	upload := &Upload{}
 
 	ui.Say(fmt.Sprintf("Preparing upload of box: %s", artifactFilePath))
 
+	if version.IsReleased() {
+		// A re-run of a build that was released; the box is already there
+		if hasProvider, _ := version.HasProvider(provider.Name); hasProvider {
+			ui.Message(fmt.Sprintf(
+				"Version %s is already released with the provider, skipping the upload", version.Version))
+			return multistep.ActionContinue
+		}
+
+		state.Put("error", fmt.Errorf(
+			"Version %s is already released, a box can't be uploaded to it", version.Version))
+		return multistep.ActionHalt
+	}
+
 	resp, err := client.Get(path)
 
 	if err != nil || (resp.StatusCode != 200) {
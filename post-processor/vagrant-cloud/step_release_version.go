@@ -26,9 +26,14 @@ func (s *stepReleaseVersion) Run(_ context.Context, state multistep.StateBag) mu
 		return multistep.ActionContinue
 	}
 
+	if version.IsReleased() {
+		ui.Message("Not releasing version, already released")
+		return multistep.ActionContinue
+	}
+
 	path := fmt.Sprintf("box/%s/version/%v/release", box.Tag, version.Version)
 
-	resp, err := client.Put(path)
+	resp, err := client.Put(path, nil)
 
 	if err != nil || (resp.StatusCode != 200) {
 		cloudErrors := &VagrantCloudErrors{}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net/http"
 
 	"github.com/hashicorp/packer/common"
 	"github.com/hashicorp/packer/helper/multistep"
@@ -16,8 +17,15 @@ type stepUpload struct {
 func (s *stepUpload) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
 	client := state.Get("client").(*VagrantCloudClient)
 	ui := state.Get("ui").(packer.Ui)
-	upload := state.Get("upload").(*Upload)
+	config := state.Get("config").(Config)
 	artifactFilePath := state.Get("artifactFilePath").(string)
+
+	// There is no upload when the box was already released
+	rawUpload, ok := state.GetOk("upload")
+	if !ok {
+		return multistep.ActionContinue
+	}
+	upload := rawUpload.(*Upload)
 	url := upload.UploadPath
 
 	ui.Say(fmt.Sprintf("Uploading box: %s", artifactFilePath))
@@ -25,20 +33,26 @@ func (s *stepUpload) Run(_ context.Context, state multistep.StateBag) multistep.
 		"Depending on your internet connection and the size of the box,\n" +
 			"this may take some time")
 
-	err := common.Retry(10, 10, 3, func(i uint) (bool, error) {
-		ui.Message(fmt.Sprintf("Uploading box, attempt %d", i+1))
+	// The waits between the attempts double from 10 seconds, up to 2
+	// minutes.
+	tries := uint(config.UploadRetries) + 1
+	err := common.Retry(10, 120, tries, func(i uint) (bool, error) {
+		ui.Message(fmt.Sprintf("Uploading box, attempt %d of %d", i+1, tries))
 
-		resp, err := client.Upload(artifactFilePath, url)
+		var resp *http.Response
+		var err error
+		if upload.Callback != "" {
+			resp, err = client.DirectUpload(artifactFilePath, url)
+		} else {
+			resp, err = client.Upload(artifactFilePath, url)
+		}
 		if err != nil {
-			ui.Message(fmt.Sprintf(
-				"Error uploading box! Will retry in 10 seconds. Error: %s", err))
+			ui.Message(fmt.Sprintf("Error uploading box! Error: %s", err))
 			return false, nil
 		}
 		if resp.StatusCode != 200 {
 			log.Printf("bad HTTP status: %d", resp.StatusCode)
-			ui.Message(fmt.Sprintf(
-				"Error uploading box! Will retry in 10 seconds. Status: %d",
-				resp.StatusCode))
+			ui.Message(fmt.Sprintf("Error uploading box! Status: %d", resp.StatusCode))
 			return false, nil
 		}
 		return true, nil
@@ -49,6 +63,17 @@ func (s *stepUpload) Run(_ context.Context, state multistep.StateBag) multistep.
 		return multistep.ActionHalt
 	}
 
+	if upload.Callback != "" {
+		resp, err := client.Callback(upload.Callback)
+		if err != nil || resp.StatusCode != 200 {
+			if err == nil {
+				err = fmt.Errorf("bad HTTP status: %d", resp.StatusCode)
+			}
+			state.Put("error", fmt.Errorf("Error completing direct upload: %s", err))
+			return multistep.ActionHalt
+		}
+	}
+
 	ui.Message("Box successfully uploaded")
 
 	return multistep.ActionContinue
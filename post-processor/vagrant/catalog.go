@@ -0,0 +1,169 @@
+package vagrant
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+// boxCatalog is the metadata file Vagrant reads to discover the versions
+// and providers of a box that is not hosted on Vagrant Cloud.
+type boxCatalog struct {
+	Name        string               `json:"name"`
+	Description string               `json:"description,omitempty"`
+	Versions    []*boxCatalogVersion `json:"versions"`
+}
+
+type boxCatalogVersion struct {
+	Version   string                `json:"version"`
+	Providers []*boxCatalogProvider `json:"providers"`
+}
+
+type boxCatalogProvider struct {
+	Name         string `json:"name"`
+	URL          string `json:"url"`
+	ChecksumType string `json:"checksum_type"`
+	Checksum     string `json:"checksum"`
+}
+
+// writeCatalog adds the box at data.Path to the catalog metadata file,
+// replacing any existing entry for the same version and provider. Entries
+// for other versions and providers are kept, so several builds can populate
+// the same catalog.
+func (p *PostProcessor) writeCatalog(ui packer.Ui, config *Config, provider string, data *outputPathTemplate) error {
+	// By default the catalog sits next to the box
+	metadataPath := filepath.Join(filepath.Dir(data.Path), "metadata.json")
+	config.ctx.Data = data
+	if config.MetadataOutput != "" {
+		var err error
+		metadataPath, err = interpolate.Render(config.MetadataOutput, &config.ctx)
+		if err != nil {
+			return fmt.Errorf("Error rendering metadata_output: %s", err)
+		}
+	}
+
+	absPath, err := filepath.Abs(data.Path)
+	if err != nil {
+		return err
+	}
+	data.Path = filepath.ToSlash(absPath)
+	url, err := interpolate.Render(config.BoxURL, &config.ctx)
+	if err != nil {
+		return fmt.Errorf("Error rendering box_url: %s", err)
+	}
+
+	checksum, err := sha256File(absPath)
+	if err != nil {
+		return fmt.Errorf("Error computing box checksum: %s", err)
+	}
+
+	if dir := filepath.Dir(metadataPath); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+
+	// Builds running at the same time add their box in turn, so none of
+	// them is lost
+	unlock, err := common.LockPath(metadataPath, func() {
+		ui.Message(fmt.Sprintf("Waiting for another build to write %s...", metadataPath))
+	})
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	catalog, err := readCatalog(metadataPath)
+	if err != nil {
+		return err
+	}
+	if config.BoxName != "" {
+		catalog.Name = config.BoxName
+	}
+	if config.BoxDescription != "" {
+		catalog.Description = config.BoxDescription
+	}
+	catalog.addProvider(config.BoxVersion, &boxCatalogProvider{
+		Name:         provider,
+		URL:          url,
+		ChecksumType: "sha256",
+		Checksum:     checksum,
+	})
+
+	ui.Message(fmt.Sprintf("Writing box metadata: %s", metadataPath))
+	contents, err := json.MarshalIndent(catalog, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Vagrant never reads half of the catalog
+	tmpPath := metadataPath + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, contents, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, metadataPath); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+func readCatalog(path string) (*boxCatalog, error) {
+	catalog := &boxCatalog{}
+	contents, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return catalog, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(contents, catalog); err != nil {
+		return nil, fmt.Errorf("Error parsing existing box metadata %s: %s", path, err)
+	}
+	return catalog, nil
+}
+
+func (c *boxCatalog) addProvider(version string, provider *boxCatalogProvider) {
+	var v *boxCatalogVersion
+	for _, existing := range c.Versions {
+		if existing.Version == version {
+			v = existing
+			break
+		}
+	}
+	if v == nil {
+		v = &boxCatalogVersion{Version: version}
+		c.Versions = append(c.Versions, v)
+	}
+
+	for i, existing := range v.Providers {
+		if existing.Name == provider.Name {
+			v.Providers[i] = provider
+			return
+		}
+	}
+	v.Providers = append(v.Providers, provider)
+}
+
+func sha256File(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
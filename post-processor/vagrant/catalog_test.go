@@ -0,0 +1,125 @@
+package vagrant
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+func TestBoxCatalog_addProvider(t *testing.T) {
+	c := &boxCatalog{}
+	c.addProvider("1.0.0", &boxCatalogProvider{Name: "virtualbox", URL: "a"})
+	c.addProvider("1.0.0", &boxCatalogProvider{Name: "vmware", URL: "b"})
+	c.addProvider("1.0.0", &boxCatalogProvider{Name: "virtualbox", URL: "c"})
+	c.addProvider("1.1.0", &boxCatalogProvider{Name: "virtualbox", URL: "d"})
+
+	if len(c.Versions) != 2 {
+		t.Fatalf("bad: %#v", c.Versions)
+	}
+	v := c.Versions[0]
+	if len(v.Providers) != 2 {
+		t.Fatalf("bad: %#v", v.Providers)
+	}
+	if v.Providers[0].URL != "c" {
+		t.Fatalf("provider should be replaced: %#v", v.Providers[0])
+	}
+}
+
+func TestWriteCatalog_parallel(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	providers := []string{"virtualbox", "vmware", "parallels", "hyperv", "libvirt"}
+	var wg sync.WaitGroup
+	errs := make([]error, len(providers))
+	for i, provider := range providers {
+		path := filepath.Join(dir, provider+".box")
+		if err := ioutil.WriteFile(path, []byte(provider), 0644); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+
+		wg.Add(1)
+		go func(i int, provider, path string) {
+			defer wg.Done()
+			var p PostProcessor
+			config := &Config{BoxVersion: "1.0.0", BoxURL: "{{ .Path }}"}
+			errs[i] = p.writeCatalog(testUi(), config, provider, &outputPathTemplate{Path: path})
+		}(i, provider, path)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+	contents, err := ioutil.ReadFile(filepath.Join(dir, "metadata.json"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	var catalog boxCatalog
+	if err := json.Unmarshal(contents, &catalog); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(catalog.Versions) != 1 || len(catalog.Versions[0].Providers) != len(providers) {
+		t.Fatalf("a box was lost: %s", contents)
+	}
+	if entries, _ := ioutil.ReadDir(dir); len(entries) != len(providers)+1 {
+		t.Fatalf("the lock or a temporary file was left: %d files", len(entries))
+	}
+}
+
+func TestPostProcessorPostProcess_boxVersion(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	var p PostProcessor
+	c := map[string]interface{}{
+		"box_name":    "org/box",
+		"box_version": "1.2.3",
+		"box_url":     "https://example.com/{{ .Provider }}/{{ .Version }}.box",
+		"output":      filepath.Join(dir, "{{ .Provider }}-{{ .Version }}.box"),
+	}
+	if err := p.Configure(c); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	a := &packer.MockArtifact{BuilderIdValue: "packer.parallels"}
+	if _, _, err := p.PostProcess(testUi(), a); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "parallels-1.2.3.box")); err != nil {
+		t.Fatalf("box should exist: %s", err)
+	}
+
+	contents, err := ioutil.ReadFile(filepath.Join(dir, "metadata.json"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	var catalog boxCatalog
+	if err := json.Unmarshal(contents, &catalog); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if catalog.Name != "org/box" {
+		t.Fatalf("bad: %#v", catalog)
+	}
+	provider := catalog.Versions[0].Providers[0]
+	if provider.Name != "parallels" || provider.URL != "https://example.com/parallels/1.2.3.box" {
+		t.Fatalf("bad: %#v", provider)
+	}
+	if provider.ChecksumType != "sha256" || provider.Checksum == "" {
+		t.Fatalf("bad: %#v", provider)
+	}
+}
@@ -1,13 +1,19 @@
 package vagrant
 
 import (
+	"bytes"
 	"fmt"
+	"log"
+	"os/exec"
 	"path/filepath"
 	"strings"
 
 	"github.com/hashicorp/packer/packer"
 )
 
+// libvirtBoxFormat is the only disk format vagrant-libvirt can import.
+const libvirtBoxFormat = "qcow2"
+
 type LibVirtProvider struct{}
 
 func (p *LibVirtProvider) KeepInputArtifact() bool {
@@ -15,19 +21,36 @@ func (p *LibVirtProvider) KeepInputArtifact() bool {
 }
 func (p *LibVirtProvider) Process(ui packer.Ui, artifact packer.Artifact, dir string) (vagrantfile string, metadata map[string]interface{}, err error) {
 	diskName := artifact.State("diskName").(string)
+	format := artifact.State("diskType").(string)
 
-	// Copy the disk image into the temporary directory (as box.img)
+	// Find the disk image in the artifact
+	var diskPath string
 	for _, path := range artifact.Files() {
-		if strings.HasSuffix(path, "/"+diskName) {
-			ui.Message(fmt.Sprintf("Copying from artifact: %s", path))
-			dstPath := filepath.Join(dir, "box.img")
-			if err = CopyContents(dstPath, path); err != nil {
-				return
-			}
+		if filepath.Base(path) == diskName {
+			diskPath = path
+			break
 		}
 	}
+	if diskPath == "" {
+		return "", nil, fmt.Errorf("Disk image %s not found in artifact", diskName)
+	}
+
+	// Copy the disk image into the temporary directory (as box.img),
+	// converting it if vagrant-libvirt couldn't use it as is.
+	dstPath := filepath.Join(dir, "box.img")
+	if format == libvirtBoxFormat {
+		ui.Message(fmt.Sprintf("Copying from artifact: %s", diskPath))
+		if err = CopyContents(dstPath, diskPath); err != nil {
+			return
+		}
+	} else {
+		ui.Message(fmt.Sprintf("Converting %s disk to %s: %s", format, libvirtBoxFormat, diskPath))
+		if err = qemuImgConvert(diskPath, format, dstPath, libvirtBoxFormat); err != nil {
+			return
+		}
+		format = libvirtBoxFormat
+	}
 
-	format := artifact.State("diskType").(string)
 	origSize := artifact.State("diskSize").(uint64)
 	size := origSize / 1024 // In MB, want GB
 	if origSize%1024 > 0 {
@@ -58,6 +81,21 @@ func (p *LibVirtProvider) Process(ui packer.Ui, artifact packer.Artifact, dir st
 	return
 }
 
+// qemuImgConvert converts a disk image between formats using qemu-img.
+func qemuImgConvert(src, srcFormat, dst, dstFormat string) error {
+	var stderr bytes.Buffer
+	cmd := exec.Command("qemu-img", "convert", "-f", srcFormat, "-O", dstFormat, src, dst)
+	cmd.Stderr = &stderr
+
+	log.Printf("Executing: qemu-img %#v", cmd.Args[1:])
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("Error converting disk image with qemu-img: %s\n\nStderr: %s",
+			err, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
+
 var libvirtVagrantfile = `
 Vagrant.configure("2") do |config|
   config.vm.provider :libvirt do |libvirt|
@@ -0,0 +1,85 @@
+package vagrant
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/packer/packer"
+)
+
+func TestLibVirtProvider_impl(t *testing.T) {
+	var _ Provider = new(LibVirtProvider)
+}
+
+func TestLibVirtProvider_Process(t *testing.T) {
+	src, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(src)
+
+	dir, err := ioutil.TempDir("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	disk := filepath.Join(src, "packer-qemu")
+	if err := ioutil.WriteFile(disk, []byte("disk"), 0644); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	artifact := &packer.MockArtifact{
+		FilesValue: []string{disk},
+		StateValues: map[string]interface{}{
+			"diskName":   "packer-qemu",
+			"diskType":   "qcow2",
+			"diskSize":   uint64(1025),
+			"domainType": "kvm",
+		},
+	}
+
+	p := new(LibVirtProvider)
+	vagrantfile, metadata, err := p.Process(testUi(), artifact, dir)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "box.img")); err != nil {
+		t.Fatalf("box.img should exist: %s", err)
+	}
+
+	result, _ := json.Marshal(metadata)
+	expected := `{"format":"qcow2","provider":"libvirt","virtual_size":2}`
+	if string(result) != expected {
+		t.Fatalf("bad: %s", result)
+	}
+
+	if vagrantfile != `
+Vagrant.configure("2") do |config|
+  config.vm.provider :libvirt do |libvirt|
+    libvirt.driver = "kvm"
+  end
+end
+` {
+		t.Fatalf("bad: %s", vagrantfile)
+	}
+}
+
+func TestLibVirtProvider_ProcessMissingDisk(t *testing.T) {
+	artifact := &packer.MockArtifact{
+		FilesValue: []string{"/tmp/other"},
+		StateValues: map[string]interface{}{
+			"diskName": "packer-qemu",
+			"diskType": "qcow2",
+		},
+	}
+
+	p := new(LibVirtProvider)
+	if _, _, err := p.Process(testUi(), artifact, "/tmp"); err == nil {
+		t.Fatal("should have error")
+	}
+}
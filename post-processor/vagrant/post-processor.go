@@ -9,6 +9,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strconv"
 	"text/template"
 
 	"github.com/hashicorp/packer/common"
@@ -40,11 +41,17 @@ var builtins = map[string]string{
 type Config struct {
 	common.PackerConfig `mapstructure:",squash"`
 
-	CompressionLevel    int      `mapstructure:"compression_level"`
-	Include             []string `mapstructure:"include"`
-	OutputPath          string   `mapstructure:"output"`
-	Override            map[string]interface{}
-	VagrantfileTemplate string `mapstructure:"vagrantfile_template"`
+	BoxDescription       string   `mapstructure:"box_description"`
+	BoxName              string   `mapstructure:"box_name"`
+	BoxURL               string   `mapstructure:"box_url"`
+	BoxVersion           string   `mapstructure:"box_version"`
+	CompressionLevel     int      `mapstructure:"compression_level"`
+	Include              []string `mapstructure:"include"`
+	MetadataOutput       string   `mapstructure:"metadata_output"`
+	OutputPath           string   `mapstructure:"output"`
+	Override             map[string]interface{}
+	VagrantfileTemplate  string            `mapstructure:"vagrantfile_template"`
+	VagrantfileTemplates map[string]string `mapstructure:"vagrantfile_templates"`
 
 	ctx interpolate.Context
 }
@@ -84,11 +91,14 @@ func (p *PostProcessor) PostProcessProvider(name string, provider Provider, ui p
 
 	ui.Say(fmt.Sprintf("Creating Vagrant box for '%s' provider", name))
 
-	config.ctx.Data = &outputPathTemplate{
+	pathData := &outputPathTemplate{
 		ArtifactId: artifact.Id(),
 		BuildName:  config.PackerBuildName,
 		Provider:   name,
+		Timestamp:  strconv.FormatInt(interpolate.InitTime.Unix(), 10),
+		Version:    config.BoxVersion,
 	}
+	config.ctx.Data = pathData
 	outputPath, err := interpolate.Render(config.OutputPath, &config.ctx)
 	if err != nil {
 		return nil, false, err
@@ -122,11 +132,16 @@ func (p *PostProcessor) PostProcessProvider(name string, provider Provider, ui p
 		return nil, false, err
 	}
 
-	// Write our Vagrantfile
+	// Write our Vagrantfile, preferring a template specific to this provider
+	customVagrantfilePath := config.VagrantfileTemplate
+	if path, ok := config.VagrantfileTemplates[name]; ok {
+		customVagrantfilePath = path
+	}
+
 	var customVagrantfile string
-	if config.VagrantfileTemplate != "" {
-		ui.Message(fmt.Sprintf("Using custom Vagrantfile: %s", config.VagrantfileTemplate))
-		customBytes, err := ioutil.ReadFile(config.VagrantfileTemplate)
+	if customVagrantfilePath != "" {
+		ui.Message(fmt.Sprintf("Using custom Vagrantfile: %s", customVagrantfilePath))
+		customBytes, err := ioutil.ReadFile(customVagrantfilePath)
 		if err != nil {
 			return nil, false, err
 		}
@@ -154,6 +169,14 @@ func (p *PostProcessor) PostProcessProvider(name string, provider Provider, ui p
 		return nil, false, err
 	}
 
+	// Record the box in the catalog metadata if we're versioning
+	if config.BoxVersion != "" {
+		pathData.Path = outputPath
+		if err := p.writeCatalog(ui, config, name, pathData); err != nil {
+			return nil, false, err
+		}
+	}
+
 	return NewArtifact(name, outputPath), provider.KeepInputArtifact(), nil
 }
 
@@ -182,6 +205,8 @@ func (p *PostProcessor) configureSingle(c *Config, raws ...interface{}) error {
 		InterpolateContext: &c.ctx,
 		InterpolateFilter: &interpolate.RenderFilter{
 			Exclude: []string{
+				"box_url",
+				"metadata_output",
 				"output",
 			},
 		},
@@ -207,6 +232,12 @@ func (p *PostProcessor) configureSingle(c *Config, raws ...interface{}) error {
 		c.CompressionLevel = flate.DefaultCompression
 	}
 
+	if c.BoxVersion != "" {
+		if c.BoxURL == "" {
+			c.BoxURL = "file://{{ .Path }}"
+		}
+	}
+
 	var errs *packer.MultiError
 	if c.VagrantfileTemplate != "" {
 		_, err := os.Stat(c.VagrantfileTemplate)
@@ -216,6 +247,22 @@ func (p *PostProcessor) configureSingle(c *Config, raws ...interface{}) error {
 		}
 	}
 
+	for provider, path := range c.VagrantfileTemplates {
+		if providerForName(provider) == nil {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf(
+				"vagrantfile_templates: unknown provider '%s'", provider))
+		}
+		if _, err := os.Stat(path); err != nil {
+			errs = packer.MultiErrorAppend(errs, fmt.Errorf(
+				"vagrantfile_templates: '%s' does not exist", path))
+		}
+	}
+
+	if c.BoxVersion == "" && (c.BoxURL != "" || c.MetadataOutput != "") {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf(
+			"box_version is required when box_url or metadata_output is set"))
+	}
+
 	if errs != nil && len(errs.Errors) > 0 {
 		return errs
 	}
@@ -260,6 +307,12 @@ type outputPathTemplate struct {
 	ArtifactId string
 	BuildName  string
 	Provider   string
+	Timestamp  string
+	Version    string
+
+	// Path is the path of the created box. It is only available within
+	// box_url and metadata_output.
+	Path string
 }
 
 type vagrantfileTemplate struct {
@@ -146,6 +146,44 @@ func TestPostProcessorPrepare_vagrantfileTemplateExists(t *testing.T) {
 	}
 }
 
+func TestPostProcessorPrepare_vagrantfileTemplates(t *testing.T) {
+	f, err := ioutil.TempFile("", "packer")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	name := f.Name()
+	f.Close()
+	defer os.Remove(name)
+
+	var p PostProcessor
+	c := testConfig()
+	c["vagrantfile_templates"] = map[string]string{"virtualbox": name}
+	if err := p.Configure(c); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Unknown provider
+	c["vagrantfile_templates"] = map[string]string{"nope": name}
+	if err := p.Configure(c); err == nil {
+		t.Fatal("should have error")
+	}
+
+	// Missing file
+	c["vagrantfile_templates"] = map[string]string{"virtualbox": name + ".missing"}
+	if err := p.Configure(c); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
+func TestPostProcessorPrepare_boxURLWithoutVersion(t *testing.T) {
+	var p PostProcessor
+	c := testConfig()
+	c["box_url"] = "https://example.com/box"
+	if err := p.Configure(c); err == nil {
+		t.Fatal("should have error")
+	}
+}
+
 func TestPostProcessorPostProcess_badId(t *testing.T) {
 	artifact := &packer.MockArtifact{
 		BuilderIdValue: "invalid.packer",
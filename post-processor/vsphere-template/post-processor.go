@@ -31,6 +31,7 @@ type Config struct {
 	Password            string `mapstructure:"password"`
 	Datacenter          string `mapstructure:"datacenter"`
 	Folder              string `mapstructure:"folder"`
+	TemplateName        string `mapstructure:"template_name"`
 	SnapshotEnable      bool   `mapstructure:"snapshot_enable"`
 	SnapshotName        string `mapstructure:"snapshot_name"`
 	SnapshotDescription string `mapstructure:"snapshot_description"`
@@ -75,6 +76,11 @@ func (p *PostProcessor) Configure(raws ...interface{}) error {
 			errs, fmt.Errorf("Folder must be bound to the root"))
 	}
 
+	if p.config.SnapshotEnable && p.config.SnapshotName == "" {
+		errs = packer.MultiErrorAppend(
+			errs, fmt.Errorf("snapshot_name must be set when snapshot_enable is true"))
+	}
+
 	sdk, err := url.Parse(fmt.Sprintf("https://%v/sdk", p.config.Host))
 	if err != nil {
 		errs = packer.MultiErrorAppend(
@@ -130,7 +136,7 @@ func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (pac
 			Folder: p.config.Folder,
 		},
 		NewStepCreateSnapshot(artifact, p),
-		NewStepMarkAsTemplate(artifact),
+		NewStepMarkAsTemplate(artifact, p),
 	}
 	runner := common.NewRunnerWithPauseFn(steps, p.config.PackerConfig, ui, state)
 	runner.Run(state)
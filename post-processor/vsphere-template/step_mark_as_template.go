@@ -6,6 +6,7 @@ import (
 	"path"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/packer/helper/multistep"
 	"github.com/hashicorp/packer/packer"
@@ -18,9 +19,10 @@ import (
 type stepMarkAsTemplate struct {
 	VMName       string
 	RemoteFolder string
+	TemplateName string
 }
 
-func NewStepMarkAsTemplate(artifact packer.Artifact) *stepMarkAsTemplate {
+func NewStepMarkAsTemplate(artifact packer.Artifact, p *PostProcessor) *stepMarkAsTemplate {
 	remoteFolder := "Discovered virtual machine"
 	vmname := artifact.Id()
 
@@ -30,9 +32,15 @@ func NewStepMarkAsTemplate(artifact packer.Artifact) *stepMarkAsTemplate {
 		vmname = id[2]
 	}
 
+	templateName := p.config.TemplateName
+	if templateName == "" {
+		templateName = vmname
+	}
+
 	return &stepMarkAsTemplate{
 		VMName:       vmname,
 		RemoteFolder: remoteFolder,
+		TemplateName: templateName,
 	}
 }
 
@@ -65,31 +73,53 @@ func (s *stepMarkAsTemplate) Run(_ context.Context, state multistep.StateBag) mu
 		return multistep.ActionHalt
 	}
 
-	if err := vm.Unregister(context.Background()); err != nil {
+	previous, err := findPreviousVM(cli, folder, s.TemplateName)
+	if err != nil {
 		state.Put("error", err)
 		ui.Error(err.Error())
 		return multistep.ActionHalt
 	}
 
-	if err := unregisterPreviousVM(cli, folder, s.VMName); err != nil {
+	// If a template of the same name already exists the new one is
+	// registered next to it first, so the old one is only replaced once
+	// the new one is in place.
+	registerName := s.TemplateName
+	if previous != nil {
+		registerName = fmt.Sprintf("%s-packer-%d", s.TemplateName, time.Now().Unix())
+	}
+
+	if err := vm.Unregister(context.Background()); err != nil {
 		state.Put("error", err)
 		ui.Error(err.Error())
 		return multistep.ActionHalt
 	}
 
-	task, err := folder.RegisterVM(context.Background(), dsPath.String(), s.VMName, true, nil, host)
+	task, err := folder.RegisterVM(context.Background(), dsPath.String(), registerName, true, nil, host)
 	if err != nil {
 		state.Put("error", err)
 		ui.Error(err.Error())
 		return multistep.ActionHalt
 	}
 
-	if err = task.Wait(context.Background()); err != nil {
+	info, err := task.WaitForResult(context.Background(), nil)
+	if err != nil {
 		state.Put("error", err)
 		ui.Error(err.Error())
 		return multistep.ActionHalt
 	}
 
+	if previous != nil {
+		ui.Message(fmt.Sprintf("Replacing existing template %s...", s.TemplateName))
+		template := object.NewVirtualMachine(cli.Client, info.Result.(types.ManagedObjectReference))
+		if err := replaceVM(previous, template, s.TemplateName); err != nil {
+			err = fmt.Errorf("Error replacing template %s, the new template is %s: %s",
+				s.TemplateName, registerName, err)
+			state.Put("error", err)
+			ui.Error(err.Error())
+			return multistep.ActionHalt
+		}
+	}
+
 	return multistep.ActionContinue
 }
 
@@ -145,27 +175,53 @@ func findRuntimeVM(cli *govmomi.Client, dcPath, name, remoteFolder string) (*obj
 	return vm, nil
 }
 
-// If in the target folder a virtual machine or template already exists
-// it will be removed to maintain consistency
-func unregisterPreviousVM(cli *govmomi.Client, folder *object.Folder, name string) error {
+// findPreviousVM returns the virtual machine or template of the given name
+// in the target folder, if there is one.
+func findPreviousVM(cli *govmomi.Client, folder *object.Folder, name string) (*object.VirtualMachine, error) {
 	si := object.NewSearchIndex(cli.Client)
 	fullPath := path.Join(folder.InventoryPath, name)
 
 	ref, err := si.FindByInventoryPath(context.Background(), fullPath)
 	if err != nil {
+		return nil, err
+	}
+
+	if ref == nil {
+		return nil, nil
+	}
+
+	vm, ok := ref.(*object.VirtualMachine)
+	if !ok {
+		return nil, fmt.Errorf("an object name '%v' already exists", name)
+	}
+	return vm, nil
+}
+
+// replaceVM gives template the name of previous and unregisters previous
+// to maintain consistency. If template can't be renamed, previous gets
+// its name back.
+func replaceVM(previous, template *object.VirtualMachine, name string) error {
+	backup := fmt.Sprintf("%s-packer-old-%d", name, time.Now().Unix())
+	if err := renameVM(previous, backup); err != nil {
 		return err
 	}
 
-	if ref != nil {
-		if vm, ok := ref.(*object.VirtualMachine); ok {
-			return vm.Unregister(context.Background())
-		} else {
-			return fmt.Errorf("an object name '%v' already exists", name)
+	if err := renameVM(template, name); err != nil {
+		if rerr := renameVM(previous, name); rerr != nil {
+			return fmt.Errorf("%s; renaming the existing template back also failed: %s", err, rerr)
 		}
-
+		return err
 	}
 
-	return nil
+	return previous.Unregister(context.Background())
+}
+
+func renameVM(vm *object.VirtualMachine, name string) error {
+	task, err := vm.Rename(context.Background(), name)
+	if err != nil {
+		return err
+	}
+	return task.Wait(context.Background())
 }
 
 func (s *stepMarkAsTemplate) Cleanup(multistep.StateBag) {}
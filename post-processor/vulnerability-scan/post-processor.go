@@ -0,0 +1,231 @@
+package vulnerabilityscan
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/helper/config"
+	"github.com/hashicorp/packer/helper/imagescan"
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+// The severities grype reports, from least to most severe.
+var severities = []string{"negligible", "low", "medium", "high", "critical"}
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+
+	FailOnSeverity string   `mapstructure:"fail_on_severity"`
+	Ignore         []string `mapstructure:"ignore"`
+	OnlyFixed      bool     `mapstructure:"only_fixed"`
+	ReportPath     string   `mapstructure:"report"`
+	GrypePath      string   `mapstructure:"grype_path"`
+	MountCommand   string   `mapstructure:"mount_command"`
+	UnmountCommand string   `mapstructure:"unmount_command"`
+
+	ctx interpolate.Context
+}
+
+type PostProcessor struct {
+	config Config
+}
+
+type reportPathTemplate struct {
+	BuildName   string
+	BuilderType string
+}
+
+// report is the part of the grype JSON report the gate looks at.
+type report struct {
+	Matches []struct {
+		Vulnerability struct {
+			ID       string `json:"id"`
+			Severity string `json:"severity"`
+		} `json:"vulnerability"`
+		Artifact struct {
+			Name    string `json:"name"`
+			Version string `json:"version"`
+		} `json:"artifact"`
+	} `json:"matches"`
+}
+
+func (p *PostProcessor) Configure(raws ...interface{}) error {
+	err := config.Decode(&p.config, &config.DecodeOpts{
+		Interpolate:        true,
+		InterpolateContext: &p.config.ctx,
+		InterpolateFilter: &interpolate.RenderFilter{
+			Exclude: []string{
+				"report",
+				"mount_command",
+				"unmount_command",
+			},
+		},
+	}, raws...)
+	if err != nil {
+		return err
+	}
+
+	if p.config.FailOnSeverity == "" {
+		p.config.FailOnSeverity = "high"
+	}
+	p.config.FailOnSeverity = strings.ToLower(p.config.FailOnSeverity)
+	if p.config.ReportPath == "" {
+		p.config.ReportPath = "packer_{{.BuildName}}_{{.BuilderType}}_vulnerabilities.json"
+	}
+	if p.config.GrypePath == "" {
+		p.config.GrypePath = "grype"
+	}
+	if p.config.MountCommand == "" {
+		p.config.MountCommand = imagescan.DefaultMountCommand
+	}
+	if p.config.UnmountCommand == "" {
+		p.config.UnmountCommand = imagescan.DefaultUnmountCommand
+	}
+
+	errs := new(packer.MultiError)
+
+	if severityRank(p.config.FailOnSeverity) < 0 {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf(
+			"Unrecognized fail_on_severity %q. Must be one of negligible, low, "+
+				"medium, high or critical", p.config.FailOnSeverity))
+	}
+
+	templates := map[string]*string{
+		"report":          &p.config.ReportPath,
+		"mount_command":   &p.config.MountCommand,
+		"unmount_command": &p.config.UnmountCommand,
+	}
+	for key, ptr := range templates {
+		if err = interpolate.Validate(*ptr, &p.config.ctx); err != nil {
+			errs = packer.MultiErrorAppend(
+				errs, fmt.Errorf("Error parsing %s template: %s", key, err))
+		}
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (packer.Artifact, bool, error) {
+	source, disk, err := imagescan.Source(artifact)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if disk != "" {
+		ui.Message(fmt.Sprintf("Mounting %s read-only...", disk))
+		var unmount func() error
+		source, unmount, err = imagescan.Mount(
+			p.config.ctx, p.config.MountCommand, p.config.UnmountCommand, disk)
+		if err != nil {
+			return nil, false, err
+		}
+		defer func() {
+			if err := unmount(); err != nil {
+				ui.Error(err.Error())
+			}
+		}()
+	}
+
+	p.config.ctx.Data = &reportPathTemplate{
+		BuildName:   p.config.PackerBuildName,
+		BuilderType: p.config.PackerBuilderType,
+	}
+	path, err := interpolate.Render(p.config.ReportPath, &p.config.ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("Error rendering report template: %s", err)
+	}
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, false, fmt.Errorf("Error creating %s: %s", dir, err)
+		}
+	}
+
+	args := []string{source, "-o", "json", "--file", path}
+	if p.config.OnlyFixed {
+		args = append(args, "--only-fixed")
+	}
+
+	ui.Message(fmt.Sprintf("Scanning %s for vulnerabilities...", source))
+	var stderr bytes.Buffer
+	cmd := exec.Command(p.config.GrypePath, args...)
+	cmd.Stderr = &stderr
+	log.Printf("Executing: %s %s", p.config.GrypePath, strings.Join(args, " "))
+	if err := cmd.Run(); err != nil {
+		return nil, false, fmt.Errorf("Error running grype: %s\n\nStderr: %s",
+			err, strings.TrimSpace(stderr.String()))
+	}
+	ui.Message(fmt.Sprintf("Wrote %s", path))
+
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, false, fmt.Errorf("Error reading report: %s", err)
+	}
+	var r report
+	if err := json.Unmarshal(contents, &r); err != nil {
+		return nil, false, fmt.Errorf("Error parsing report %s: %s", path, err)
+	}
+
+	counts, failing := p.gate(&r)
+	var summary []string
+	for i := len(severities) - 1; i >= 0; i-- {
+		summary = append(summary, fmt.Sprintf("%s: %d", severities[i], counts[severities[i]]))
+	}
+	ui.Message(fmt.Sprintf("Found %d vulnerabilities (%s)", len(r.Matches), strings.Join(summary, ", ")))
+
+	if len(failing) > 0 {
+		sort.Strings(failing)
+		return nil, false, fmt.Errorf(
+			"Found %d vulnerabilities with severity %s or higher, see %s:\n\n%s",
+			len(failing), p.config.FailOnSeverity, path, strings.Join(failing, "\n"))
+	}
+
+	return &imagescan.Artifact{Parent: artifact, Reports: []string{path}}, true, nil
+}
+
+// gate counts the matches in the report by severity and returns the
+// matches at or above fail_on_severity that aren't ignored.
+func (p *PostProcessor) gate(r *report) (map[string]int, []string) {
+	ignored := make(map[string]bool)
+	for _, id := range p.config.Ignore {
+		ignored[id] = true
+	}
+	threshold := severityRank(p.config.FailOnSeverity)
+
+	counts := make(map[string]int)
+	var failing []string
+	for _, m := range r.Matches {
+		severity := strings.ToLower(m.Vulnerability.Severity)
+		counts[severity]++
+		if ignored[m.Vulnerability.ID] || severityRank(severity) < threshold {
+			continue
+		}
+		failing = append(failing, fmt.Sprintf("%s (%s) in %s %s",
+			m.Vulnerability.ID, severity, m.Artifact.Name, m.Artifact.Version))
+	}
+	return counts, failing
+}
+
+// severityRank returns the position of severity in severities, or -1 for
+// severities like "unknown" that never fail the build.
+func severityRank(severity string) int {
+	for i, s := range severities {
+		if s == severity {
+			return i
+		}
+	}
+	return -1
+}
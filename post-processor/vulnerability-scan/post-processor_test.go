@@ -0,0 +1,139 @@
+package vulnerabilityscan
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/post-processor/docker-tag"
+)
+
+// A stand-in for grype that writes a fixed report to the --file argument.
+const fakeGrype = `#!/bin/sh
+while [ $# -gt 0 ]; do
+	if [ "$1" = "--file" ]; then
+		cat > "$2" <<'REPORT'
+{
+  "matches": [
+    {"vulnerability": {"id": "CVE-2023-0001", "severity": "Critical"}, "artifact": {"name": "openssl", "version": "1.1.1"}},
+    {"vulnerability": {"id": "CVE-2023-0002", "severity": "Medium"}, "artifact": {"name": "curl", "version": "7.0"}},
+    {"vulnerability": {"id": "CVE-2023-0003", "severity": "Unknown"}, "artifact": {"name": "zlib", "version": "1.2"}}
+  ]
+}
+REPORT
+		shift
+	fi
+	shift
+done
+`
+
+func testUi() *packer.BasicUi {
+	return &packer.BasicUi{
+		Reader: new(bytes.Buffer),
+		Writer: new(bytes.Buffer),
+	}
+}
+
+func TestPostProcessor_ImplementsPostProcessor(t *testing.T) {
+	var _ packer.PostProcessor = new(PostProcessor)
+}
+
+func TestPostProcessorConfigure(t *testing.T) {
+	var p PostProcessor
+	if err := p.Configure(map[string]interface{}{}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if p.config.FailOnSeverity != "high" {
+		t.Fatalf("bad fail_on_severity: %s", p.config.FailOnSeverity)
+	}
+
+	p = PostProcessor{}
+	if err := p.Configure(map[string]interface{}{"fail_on_severity": "Critical"}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if p.config.FailOnSeverity != "critical" {
+		t.Fatalf("bad fail_on_severity: %s", p.config.FailOnSeverity)
+	}
+
+	p = PostProcessor{}
+	if err := p.Configure(map[string]interface{}{"fail_on_severity": "severe"}); err == nil {
+		t.Fatal("should error on unknown severity")
+	}
+}
+
+func testPostProcess(t *testing.T, config map[string]interface{}) (packer.Artifact, string, error) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as grype")
+	}
+
+	dir, err := ioutil.TempDir("", "packer-vulnerability-scan")
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	grype := filepath.Join(dir, "grype")
+	if err := ioutil.WriteFile(grype, []byte(fakeGrype), 0755); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	config["grype_path"] = grype
+	config["report"] = filepath.Join(dir, "reports", "{{.BuildName}}.json")
+
+	var p PostProcessor
+	if err := p.Configure(config, map[string]interface{}{"packer_build_name": "app"}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	source := &packer.MockArtifact{
+		BuilderIdValue: dockertag.BuilderId,
+		IdValue:        "app:1.0",
+		FilesValue:     []string{},
+	}
+	result, _, err := p.PostProcess(testUi(), source)
+	return result, dir, err
+}
+
+func TestPostProcessor_PostProcess(t *testing.T) {
+	result, dir, err := testPostProcess(t, map[string]interface{}{
+		"fail_on_severity": "critical",
+		"ignore":           []string{"CVE-2023-0001"},
+	})
+	defer os.RemoveAll(dir)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if result.BuilderId() != dockertag.BuilderId || result.Id() != "app:1.0" {
+		t.Fatalf("should keep the builder ID and ID: %s %s", result.BuilderId(), result.Id())
+	}
+
+	expected := []string{filepath.Join(dir, "reports", "app.json")}
+	if !reflect.DeepEqual(result.Files(), expected) {
+		t.Fatalf("bad files: %#v", result.Files())
+	}
+}
+
+func TestPostProcessor_PostProcessFails(t *testing.T) {
+	_, dir, err := testPostProcess(t, map[string]interface{}{
+		"fail_on_severity": "medium",
+		"ignore":           []string{"CVE-2023-0002"},
+	})
+	defer os.RemoveAll(dir)
+	if err == nil {
+		t.Fatal("should fail on the critical vulnerability")
+	}
+	if !strings.Contains(err.Error(), "CVE-2023-0001 (critical) in openssl 1.1.1") {
+		t.Fatalf("bad error: %s", err)
+	}
+	if strings.Contains(err.Error(), "CVE-2023-0002") || strings.Contains(err.Error(), "CVE-2023-0003") {
+		t.Fatalf("should not fail on ignored or unknown vulnerabilities: %s", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "reports", "app.json")); err != nil {
+		t.Fatalf("should still write the report: %s", err)
+	}
+}
@@ -393,13 +393,13 @@ func (p *Provisioner) executeAnsible(ui packer.Ui, comm packer.Communicator, pri
 	}
 
 	wg := sync.WaitGroup{}
-	repeat := func(r io.ReadCloser) {
+	repeat := func(r io.ReadCloser, stream string) {
 		reader := bufio.NewReader(r)
 		for {
 			line, err := reader.ReadString('\n')
 			if line != "" {
 				line = strings.TrimRightFunc(line, unicode.IsSpace)
-				ui.Message(line)
+				packer.UiOutput(ui, stream, line)
 			}
 			if err != nil {
 				if err == io.EOF {
@@ -413,8 +413,8 @@ func (p *Provisioner) executeAnsible(ui packer.Ui, comm packer.Communicator, pri
 		wg.Done()
 	}
 	wg.Add(2)
-	go repeat(stdout)
-	go repeat(stderr)
+	go repeat(stdout, packer.UiStreamStdout)
+	go repeat(stderr, packer.UiStreamStderr)
 
 	// remove winrm password from command, if it's been added
 	flattenedCmd := strings.Join(cmd.Args, " ")
@@ -593,6 +593,18 @@ func (ui *Ui) Error(s string) {
 	<-ui.sem
 }
 
+func (ui *Ui) Log(level packer.UiLevel, s string) {
+	ui.sem <- 1
+	packer.UiLog(ui.ui, level, s)
+	<-ui.sem
+}
+
+func (ui *Ui) Output(stream, line string) {
+	ui.sem <- 1
+	packer.UiOutput(ui.ui, stream, line)
+	<-ui.sem
+}
+
 func (ui *Ui) Machine(t string, args ...string) {
 	ui.sem <- 1
 	ui.ui.Machine(t, args...)
@@ -380,6 +380,13 @@ func (p *Provisioner) createFlattenedEnvVars(elevated bool) (flattened string) {
 	envVars["PACKER_BUILD_NAME"] = p.config.PackerBuildName
 	envVars["PACKER_BUILDER_TYPE"] = p.config.PackerBuilderType
 
+	// expose the values the builder recorded about the build
+	buildEnv := commonhelper.BuildEnv(p.config.PackerBuildName)
+	packer.LogSecretFilter.Set(buildEnv["PACKER_BUILD_PASSWORD"])
+	for k, v := range buildEnv {
+		envVars[k] = psEscape.Replace(v)
+	}
+
 	// expose ip address variables
 	httpAddr := common.GetHTTPAddr()
 	if httpAddr != "" {
@@ -16,6 +16,7 @@ import (
 	"time"
 
 	"github.com/hashicorp/packer/common"
+	commonhelper "github.com/hashicorp/packer/helper/common"
 	"github.com/hashicorp/packer/helper/config"
 	"github.com/hashicorp/packer/packer"
 	"github.com/hashicorp/packer/template/interpolate"
@@ -464,6 +465,13 @@ func (p *Provisioner) escapeEnvVars() ([]string, map[string]string) {
 	envVars["PACKER_BUILD_NAME"] = fmt.Sprintf("%s", p.config.PackerBuildName)
 	envVars["PACKER_BUILDER_TYPE"] = fmt.Sprintf("%s", p.config.PackerBuilderType)
 
+	// expose the values the builder recorded about the build
+	buildEnv := commonhelper.BuildEnv(p.config.PackerBuildName)
+	packer.LogSecretFilter.Set(buildEnv["PACKER_BUILD_PASSWORD"])
+	for k, v := range buildEnv {
+		envVars[k] = strings.Replace(v, "'", `'"'"'`, -1)
+	}
+
 	// expose ip address variables
 	httpAddr := common.GetHTTPAddr()
 	if httpAddr != "" {
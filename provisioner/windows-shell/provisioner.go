@@ -14,6 +14,7 @@ import (
 	"time"
 
 	"github.com/hashicorp/packer/common"
+	commonhelper "github.com/hashicorp/packer/helper/common"
 	"github.com/hashicorp/packer/helper/config"
 	"github.com/hashicorp/packer/packer"
 	"github.com/hashicorp/packer/template/interpolate"
@@ -290,6 +291,13 @@ func (p *Provisioner) createFlattenedEnvVars() (flattened string) {
 	envVars["PACKER_BUILD_NAME"] = p.config.PackerBuildName
 	envVars["PACKER_BUILDER_TYPE"] = p.config.PackerBuilderType
 
+	// expose the values the builder recorded about the build
+	buildEnv := commonhelper.BuildEnv(p.config.PackerBuildName)
+	packer.LogSecretFilter.Set(buildEnv["PACKER_BUILD_PASSWORD"])
+	for k, v := range buildEnv {
+		envVars[k] = v
+	}
+
 	// expose ip address variables
 	httpAddr := common.GetHTTPAddr()
 	if httpAddr != "" {
@@ -0,0 +1,177 @@
+// Package acctest runs acceptance tests of builders, provisioners, and
+// post-processors: real builds against the core, from Go tests.
+package acctest
+
+import (
+	"regexp"
+
+	corepacker "github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/sdk/packer"
+)
+
+// EnvVar must be set to a non-empty value for acceptance tests to run.
+const EnvVar = corepacker.AcceptanceEnvVar
+
+// TestCase is a build to run against the real core, with the components
+// under test.
+type TestCase struct {
+	// RequiredEnv are the environment variables the test needs, such as
+	// credentials. The test is skipped when one of them isn't set.
+	RequiredEnv []string
+
+	// PreCheck, if not nil, is called once before the test runs.
+	PreCheck func()
+
+	// Builder is available as the "test" builder in the template.
+	Builder packer.Builder
+
+	// Builders, Provisioners, and PostProcessors are available in the
+	// template under their names.
+	Builders       map[string]packer.Builder
+	Provisioners   map[string]packer.Provisioner
+	PostProcessors map[string]packer.PostProcessor
+
+	// Template is the template contents to use, see Template.
+	Template string
+
+	// Variables are the user variables of the template, like with -var.
+	Variables map[string]string
+
+	// Build is the name of the build to run, "test" by default.
+	Build string
+
+	// ExpectError, if set, is matched against the error of the build
+	// instead of failing the test. Nothing is checked when it matches.
+	ExpectError *regexp.Regexp
+
+	// Check is called with the artifacts of the build. See
+	// CheckArtifacts.
+	Check CheckFunc
+
+	// Teardown is called when the test is over, whether it succeeded or
+	// not. It returns an error when it couldn't clean everything up.
+	Teardown func() error
+
+	// SkipArtifactTeardown keeps the artifacts instead of destroying
+	// them.
+	SkipArtifactTeardown bool
+}
+
+// CheckFunc checks the artifacts of the build of a TestCase.
+type CheckFunc func([]packer.Artifact) error
+
+// T is the test the TestCase runs in, a *testing.T.
+type T interface {
+	Error(args ...interface{})
+	Fatal(args ...interface{})
+	Skip(args ...interface{})
+}
+
+// Test runs the build of the test case, checks its artifacts, and
+// destroys them. It only runs when EnvVar and the variables of RequiredEnv
+// are set, and with the verbose flag of go test, since builds create real
+// resources and take a while.
+func Test(t T, c TestCase) {
+	tc := corepacker.AcceptanceTestCase{
+		RequiredEnv:          c.RequiredEnv,
+		PreCheck:             c.PreCheck,
+		Template:             c.Template,
+		Variables:            c.Variables,
+		Build:                c.Build,
+		ExpectError:          c.ExpectError,
+		Teardown:             c.Teardown,
+		SkipArtifactTeardown: c.SkipArtifactTeardown,
+	}
+	if c.Builder != nil {
+		tc.Builder = corepacker.SDKBuilder(c.Builder)
+	}
+	if len(c.Builders) > 0 {
+		tc.Builders = make(map[string]corepacker.Builder)
+		for name, b := range c.Builders {
+			tc.Builders[name] = corepacker.SDKBuilder(b)
+		}
+	}
+	if len(c.Provisioners) > 0 {
+		tc.Provisioners = make(map[string]corepacker.Provisioner)
+		for name, p := range c.Provisioners {
+			tc.Provisioners[name] = corepacker.SDKProvisioner(p)
+		}
+	}
+	if len(c.PostProcessors) > 0 {
+		tc.PostProcessors = make(map[string]corepacker.PostProcessor)
+		for name, p := range c.PostProcessors {
+			tc.PostProcessors[name] = corepacker.SDKPostProcessor(p)
+		}
+	}
+	if c.Check != nil {
+		tc.Check = func(artifacts []corepacker.Artifact) error {
+			return c.Check(sdkArtifacts(artifacts))
+		}
+	}
+	corepacker.AcceptanceTest(t, tc)
+}
+
+// Template returns a template that builds with the builder, and runs the
+// provisioners in order.
+func Template(builder map[string]interface{}, provisioners ...map[string]interface{}) string {
+	return corepacker.AcceptanceTemplate(builder, provisioners...)
+}
+
+// CheckArtifacts runs the checks in order, until one fails.
+func CheckArtifacts(checks ...CheckFunc) CheckFunc {
+	return func(artifacts []packer.Artifact) error {
+		for _, check := range checks {
+			if err := check(artifacts); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// CheckArtifactCount checks that there are n artifacts.
+func CheckArtifactCount(n int) CheckFunc {
+	return check(corepacker.CheckArtifactCount(n))
+}
+
+// CheckArtifactBuilderId checks that the first artifact is from the builder
+// or post-processor with the ID.
+func CheckArtifactBuilderId(id string) CheckFunc {
+	return check(corepacker.CheckArtifactBuilderId(id))
+}
+
+// CheckArtifactId checks that the ID of the first artifact matches the
+// regular expression.
+func CheckArtifactId(pattern string) CheckFunc {
+	return check(corepacker.CheckArtifactId(pattern))
+}
+
+// CheckArtifactFiles checks that the first artifact has files matching each
+// of the regular expressions, and that they exist.
+func CheckArtifactFiles(patterns ...string) CheckFunc {
+	return check(corepacker.CheckArtifactFiles(patterns...))
+}
+
+// CheckArtifactState checks that the first artifact has the state value
+// under the name.
+func CheckArtifactState(name string, value interface{}) CheckFunc {
+	return check(corepacker.CheckArtifactState(name, value))
+}
+
+func check(f corepacker.AcceptanceCheckFunc) CheckFunc {
+	return func(artifacts []packer.Artifact) error {
+		core := make([]corepacker.Artifact, len(artifacts))
+		for i, a := range artifacts {
+			core[i] = a
+		}
+		return f(core)
+	}
+}
+
+func sdkArtifacts(artifacts []corepacker.Artifact) []packer.Artifact {
+	result := make([]packer.Artifact, len(artifacts))
+	for i, a := range artifacts {
+		result[i] = a
+	}
+	return result
+}
@@ -0,0 +1,146 @@
+// Package config decodes the configuration of components, and interpolates
+// the template functions in it.
+package config
+
+import (
+	"time"
+
+	"github.com/hashicorp/packer/helper/config"
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+// PackerConfig is the configuration the core adds for every component, to
+// embed in theirs with `mapstructure:",squash"`.
+type PackerConfig struct {
+	PackerBuildName     string            `mapstructure:"packer_build_name"`
+	PackerBuilderType   string            `mapstructure:"packer_builder_type"`
+	PackerDebug         bool              `mapstructure:"packer_debug"`
+	PackerDebugSkip     []string          `mapstructure:"packer_debug_skip"`
+	PackerDebugTrace    string            `mapstructure:"packer_debug_trace"`
+	PackerForce         bool              `mapstructure:"packer_force"`
+	PackerForceDownload bool              `mapstructure:"packer_force_download"`
+	PackerOffline       bool              `mapstructure:"packer_offline"`
+	PackerOnError       string            `mapstructure:"packer_on_error"`
+	PackerUserVars      map[string]string `mapstructure:"packer_user_variables"`
+	PackerSensitiveVars []string          `mapstructure:"packer_sensitive_variables"`
+	StepHooks           []StepHook        `mapstructure:"step_hooks"`
+}
+
+// StepHook runs commands on the machine before or after the step of a
+// name, like the step_hooks of the templates.
+type StepHook struct {
+	Before string        `mapstructure:"before"`
+	After  string        `mapstructure:"after"`
+	Wait   time.Duration `mapstructure:"wait"`
+	Inline []string      `mapstructure:"inline"`
+}
+
+// DecodeOpts are the options for decoding configuration.
+type DecodeOpts struct {
+	// Interpolate, if true, interpolates the configuration with the
+	// InterpolateContext, after adding to it the user variables and the
+	// other values the core adds to the configuration.
+	Interpolate        bool
+	InterpolateContext *InterpolateContext
+	InterpolateFilter  *RenderFilter
+}
+
+// InterpolateContext is what the template functions, like {{user}} and
+// {{.Data}}, are interpolated with.
+type InterpolateContext struct {
+	// Data is the data of the template, for {{.Name}}.
+	Data interface{}
+
+	// Funcs are extra functions available in the template.
+	Funcs map[string]interface{}
+
+	// UserVariables are the user variables, for {{user}}, and
+	// SensitiveVariables the names of the ones to mask.
+	UserVariables      map[string]string
+	SensitiveVariables []string
+
+	// EnableEnv enables {{env}}.
+	EnableEnv bool
+
+	// BuildName and BuildType are the name and builder type of the build,
+	// and TemplatePath the path of its template.
+	BuildName    string
+	BuildType    string
+	TemplatePath string
+
+	// BuildValues are the values the builder recorded about the build,
+	// for {{build}}.
+	BuildValues map[string]string
+
+	// RandomSeed is the seed of the random functions of the run.
+	RandomSeed []byte
+}
+
+// RenderFilter selects the keys of the configuration to interpolate: the
+// ones of Include if it isn't empty, and not the ones of Exclude.
+type RenderFilter struct {
+	Include []string
+	Exclude []string
+}
+
+// Decode decodes the configuration into the target and optionally
+// automatically interpolates all the configuration as it goes.
+func Decode(target interface{}, opts *DecodeOpts, raws ...interface{}) error {
+	if opts == nil {
+		opts = &DecodeOpts{Interpolate: true}
+	}
+
+	coreOpts := &config.DecodeOpts{
+		Interpolate:        opts.Interpolate,
+		InterpolateContext: opts.InterpolateContext.core(),
+	}
+	if f := opts.InterpolateFilter; f != nil {
+		coreOpts.InterpolateFilter = &interpolate.RenderFilter{Include: f.Include, Exclude: f.Exclude}
+	}
+
+	err := config.Decode(target, coreOpts, raws...)
+	if opts.InterpolateContext != nil {
+		opts.InterpolateContext.set(coreOpts.InterpolateContext)
+	}
+	return err
+}
+
+// Render interpolates the template functions of v.
+func Render(v string, ctx *InterpolateContext) (string, error) {
+	return interpolate.Render(v, ctx.core())
+}
+
+// core is the context of the core for ctx, empty if ctx is nil.
+func (ctx *InterpolateContext) core() *interpolate.Context {
+	if ctx == nil {
+		return &interpolate.Context{}
+	}
+	return &interpolate.Context{
+		Data:               ctx.Data,
+		Funcs:              ctx.Funcs,
+		UserVariables:      ctx.UserVariables,
+		SensitiveVariables: ctx.SensitiveVariables,
+		EnableEnv:          ctx.EnableEnv,
+		BuildName:          ctx.BuildName,
+		BuildType:          ctx.BuildType,
+		TemplatePath:       ctx.TemplatePath,
+		BuildValues:        ctx.BuildValues,
+		RandomSeed:         ctx.RandomSeed,
+	}
+}
+
+// set sets ctx to the context of the core, which Decode filled in.
+func (ctx *InterpolateContext) set(c *interpolate.Context) {
+	*ctx = InterpolateContext{
+		Data:               c.Data,
+		Funcs:              c.Funcs,
+		UserVariables:      c.UserVariables,
+		SensitiveVariables: c.SensitiveVariables,
+		EnableEnv:          c.EnableEnv,
+		BuildName:          c.BuildName,
+		BuildType:          c.BuildType,
+		TemplatePath:       c.TemplatePath,
+		BuildValues:        c.BuildValues,
+		RandomSeed:         c.RandomSeed,
+	}
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	var c struct {
+		PackerConfig `mapstructure:",squash"`
+
+		Name string `mapstructure:"name"`
+	}
+	var ctx InterpolateContext
+	err := Decode(&c, &DecodeOpts{
+		Interpolate:        true,
+		InterpolateContext: &ctx,
+	}, map[string]interface{}{
+		"name":                  "{{user `name`}}-{{build_name}}",
+		"packer_build_name":     "web",
+		"packer_user_variables": map[string]string{"name": "app"},
+	})
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if c.Name != "app-web" {
+		t.Fatalf("bad name: %s", c.Name)
+	}
+	if c.PackerBuildName != "web" {
+		t.Fatalf("bad build name: %s", c.PackerBuildName)
+	}
+	if ctx.BuildName != "web" || ctx.UserVariables["name"] != "app" {
+		t.Fatalf("should fill the context in: %#v", ctx)
+	}
+
+	result, err := Render("{{user `name`}}", &ctx)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if result != "app" {
+		t.Fatalf("bad: %s", result)
+	}
+}
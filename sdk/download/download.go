@@ -0,0 +1,72 @@
+// Package download downloads files from HTTP, SMB, and local paths, and
+// verifies their checksum.
+package download
+
+import (
+	"hash"
+
+	"github.com/hashicorp/packer/common"
+	corepacker "github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/sdk/packer"
+)
+
+// Config is the configuration of a download. Once a configuration is used
+// to instantiate a client, it must not be modified.
+type Config struct {
+	// Url is the URL to download.
+	Url string
+
+	// TargetPath is where to download the file to.
+	TargetPath string
+
+	// CopyFile, if true, copies a local file to TargetPath too, instead
+	// of using it where it is.
+	CopyFile bool
+
+	// Hash and Checksum are the hash to verify the file with, and the
+	// checksum it must have. The file isn't verified if Hash is nil.
+	Hash     hash.Hash
+	Checksum []byte
+
+	// UserAgent is the user agent of the HTTP requests.
+	UserAgent string
+}
+
+// Client downloads a file to its target path.
+type Client struct {
+	client *common.DownloadClient
+}
+
+// NewClient returns a client for the download, reporting progress to ui.
+func NewClient(c *Config, ui packer.Ui) *Client {
+	return &Client{common.NewDownloadClient(&common.DownloadConfig{
+		Url:        c.Url,
+		TargetPath: c.TargetPath,
+		CopyFile:   c.CopyFile,
+		Hash:       c.Hash,
+		Checksum:   c.Checksum,
+		UserAgent:  c.UserAgent,
+	}, corepacker.SDKUi(ui))}
+}
+
+// Get downloads the file and returns its path.
+func (c *Client) Get() (string, error) {
+	return c.client.Get()
+}
+
+// Cancel cancels the download while it runs.
+func (c *Client) Cancel() {
+	c.client.Cancel()
+}
+
+// VerifyChecksum says whether the file of the path has the checksum of the
+// configuration.
+func (c *Client) VerifyChecksum(path string) (bool, error) {
+	return c.client.VerifyChecksum(path)
+}
+
+// HashForType returns the hash for the checksum type, like "sha256", or nil
+// if there is none.
+func HashForType(t string) hash.Hash {
+	return common.HashForType(t)
+}
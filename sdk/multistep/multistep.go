@@ -0,0 +1,145 @@
+// Package multistep runs the steps builders are written as, one after
+// another, and cleans them up in reverse order.
+package multistep
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/helper/multistep"
+	corepacker "github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/sdk/config"
+	"github.com/hashicorp/packer/sdk/packer"
+)
+
+// StepAction says whether the runner goes on to the next step.
+type StepAction uint
+
+const (
+	ActionContinue StepAction = iota
+	ActionHalt
+)
+
+// StateCancelled and StateHalted are set in the state when the build is
+// cancelled, or a step halts.
+const (
+	StateCancelled = "cancelled"
+	StateHalted    = "halted"
+)
+
+// Step is one of the steps of a build.
+type Step interface {
+	// Run runs the step. The context is cancelled when the runner is. The
+	// state is shared by all the steps, check the types of its values.
+	Run(context.Context, StateBag) StepAction
+
+	// Cleanup cleans up after the step, in reverse order of the steps
+	// that ran, even when the build failed or was cancelled.
+	Cleanup(StateBag)
+}
+
+// StepWrapper is a step that wraps another one, and names the steps after
+// it, for -debug-skip and the other options that select steps by name.
+type StepWrapper interface {
+	InnerStepName() string
+}
+
+// Runner runs steps.
+type Runner interface {
+	// Run runs the steps with the state.
+	Run(StateBag)
+
+	// Cancel cancels the steps while they run.
+	Cancel()
+}
+
+// DebugLocation is where the steps are paused with -debug: after the run of
+// a step, or before its cleanup.
+type DebugLocation uint
+
+const (
+	DebugLocationAfterRun DebugLocation = iota
+	DebugLocationBeforeCleanup
+)
+
+// DebugPauseFn pauses the steps with -debug, at the location of the step of
+// the name.
+type DebugPauseFn func(DebugLocation, string, StateBag)
+
+// NewRunner returns a Runner that runs the steps with the support of
+// -debug, -on-error, and the other options of packer build for steps.
+func NewRunner(steps []Step, config config.PackerConfig, ui packer.Ui) Runner {
+	return &runner{common.NewRunner(coreSteps(steps), packerConfig(config), corepacker.SDKUi(ui))}
+}
+
+// NewRunnerWithPauseFn is NewRunner, and with -debug puts the DebugPauseFn
+// that pauses between steps into the state under the key "pauseFn".
+func NewRunnerWithPauseFn(steps []Step, config config.PackerConfig, ui packer.Ui, state StateBag) Runner {
+	r := common.NewRunnerWithPauseFn(coreSteps(steps), packerConfig(config), corepacker.SDKUi(ui), state)
+	if pause, ok := state.Get("pauseFn").(multistep.DebugPauseFn); ok {
+		state.Put("pauseFn", DebugPauseFn(func(loc DebugLocation, name string, state StateBag) {
+			pause(multistep.DebugLocation(loc), name, state)
+		}))
+	}
+	return &runner{r}
+}
+
+// runner is the runner of the core, running the steps of the SDK.
+type runner struct {
+	multistep.Runner
+}
+
+func (r *runner) Run(state StateBag) {
+	r.Runner.Run(state)
+}
+
+// coreStep is the step of the SDK, as one of the core.
+type coreStep struct {
+	step Step
+}
+
+func coreSteps(steps []Step) []multistep.Step {
+	result := make([]multistep.Step, len(steps))
+	for i, step := range steps {
+		result[i] = &coreStep{step}
+	}
+	return result
+}
+
+func (s *coreStep) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	return multistep.StepAction(s.step.Run(ctx, state))
+}
+
+func (s *coreStep) Cleanup(state multistep.StateBag) {
+	s.step.Cleanup(state)
+}
+
+func (s *coreStep) InnerStepName() string {
+	if wrapped, ok := s.step.(StepWrapper); ok {
+		return wrapped.InnerStepName()
+	}
+	return reflect.Indirect(reflect.ValueOf(s.step)).Type().Name()
+}
+
+// packerConfig is the configuration of the core for the one of the SDK.
+func packerConfig(c config.PackerConfig) common.PackerConfig {
+	hooks := make([]common.StepHook, len(c.StepHooks))
+	for i, h := range c.StepHooks {
+		hooks[i] = common.StepHook(h)
+	}
+	return common.PackerConfig{
+		PackerBuildName:     c.PackerBuildName,
+		PackerBuilderType:   c.PackerBuilderType,
+		PackerDebug:         c.PackerDebug,
+		PackerDebugSkip:     c.PackerDebugSkip,
+		PackerDebugTrace:    c.PackerDebugTrace,
+		PackerForce:         c.PackerForce,
+		PackerForceDownload: c.PackerForceDownload,
+		PackerOffline:       c.PackerOffline,
+		PackerOnError:       c.PackerOnError,
+		PackerUserVars:      c.PackerUserVars,
+		PackerSensitiveVars: c.PackerSensitiveVars,
+		StepHooks:           hooks,
+	}
+}
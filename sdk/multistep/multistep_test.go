@@ -0,0 +1,75 @@
+package multistep
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/hashicorp/packer/sdk/config"
+	"github.com/hashicorp/packer/sdk/packer"
+)
+
+// sdkStep is a step written only against the SDK.
+type sdkStep struct {
+	ran       bool
+	cleanedUp bool
+}
+
+func (s *sdkStep) Run(_ context.Context, state StateBag) StepAction {
+	s.ran = true
+	return ActionContinue
+}
+
+func (s *sdkStep) Cleanup(StateBag) {
+	s.cleanedUp = true
+}
+
+// testUi is a Ui of the SDK that shows nothing.
+type testUi struct{}
+
+func (testUi) Ask(string) (string, error)      { return "", nil }
+func (testUi) Say(string)                      {}
+func (testUi) Message(string)                  {}
+func (testUi) Error(string)                    {}
+func (testUi) Machine(string, ...string)       {}
+func (testUi) ProgressBar() packer.ProgressBar { return testProgressBar{} }
+
+type testProgressBar struct{}
+
+func (testProgressBar) Start(int64)                          {}
+func (testProgressBar) Add(int64)                            {}
+func (testProgressBar) NewProxyReader(r io.Reader) io.Reader { return r }
+func (testProgressBar) Finish()                              {}
+
+func TestNewRunner(t *testing.T) {
+	step := new(sdkStep)
+	state := new(BasicStateBag)
+
+	NewRunner([]Step{step}, config.PackerConfig{}, testUi{}).Run(state)
+
+	if !step.ran {
+		t.Fatal("should run the step")
+	}
+	if !step.cleanedUp {
+		t.Fatal("should clean up the step")
+	}
+}
+
+func TestNewRunner_debugSkip(t *testing.T) {
+	skipped, step := new(sdkStep), new(otherStep)
+	state := new(BasicStateBag)
+
+	config := config.PackerConfig{PackerDebugSkip: []string{"sdkStep"}}
+	NewRunner([]Step{skipped, step}, config, testUi{}).Run(state)
+
+	if skipped.ran {
+		t.Fatal("should skip the step by the name of its type")
+	}
+	if !step.ran {
+		t.Fatal("should run the other step")
+	}
+}
+
+type otherStep struct {
+	sdkStep
+}
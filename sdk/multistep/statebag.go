@@ -0,0 +1,60 @@
+package multistep
+
+import "sync"
+
+// StateBag holds the state the steps share. It must be safe to use
+// concurrently.
+type StateBag interface {
+	Get(string) interface{}
+	GetOk(string) (interface{}, bool)
+	Put(string, interface{})
+	Remove(string)
+}
+
+// BasicStateBag is a StateBag of a map protected by a RWMutex.
+type BasicStateBag struct {
+	data map[string]interface{}
+	l    sync.RWMutex
+}
+
+func (b *BasicStateBag) Get(k string) interface{} {
+	result, _ := b.GetOk(k)
+	return result
+}
+
+func (b *BasicStateBag) GetOk(k string) (interface{}, bool) {
+	b.l.RLock()
+	defer b.l.RUnlock()
+
+	result, ok := b.data[k]
+	return result, ok
+}
+
+func (b *BasicStateBag) Put(k string, v interface{}) {
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	if b.data == nil {
+		b.data = make(map[string]interface{})
+	}
+	b.data[k] = v
+}
+
+func (b *BasicStateBag) Remove(k string) {
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	delete(b.data, k)
+}
+
+// Keys returns the keys of the state, in no particular order.
+func (b *BasicStateBag) Keys() []string {
+	b.l.RLock()
+	defer b.l.RUnlock()
+
+	keys := make([]string, 0, len(b.data))
+	for k := range b.data {
+		keys = append(keys, k)
+	}
+	return keys
+}
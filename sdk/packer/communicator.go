@@ -0,0 +1,152 @@
+package packer
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// CmdDisconnect is the exit status of a RemoteCmd that exited because the
+// remote side disconnected.
+const CmdDisconnect int = 2300218
+
+// RemoteCmd is a command being prepared or run on the machine.
+type RemoteCmd struct {
+	// Command is the command to run, as a shell command, so it must be
+	// escaped as needed.
+	Command string
+
+	// Stdin is the standard input of the command, empty if nil.
+	Stdin io.Reader
+
+	// Stdout and Stderr are the standard output and error of the
+	// command, discarded if nil.
+	Stdout io.Writer
+	Stderr io.Writer
+
+	// User, Workdir, and Env are the user to run the command as, its
+	// working directory, and extra environment variables, for the
+	// communicators that support them. The others ignore them.
+	User    string
+	Workdir string
+	Env     map[string]string
+
+	// Exited is true once the command exited, with ExitStatus.
+	Exited     bool
+	ExitStatus int
+
+	exitCh chan struct{}
+	sync.Mutex
+}
+
+// Communicator runs commands on the machine being built and copies files
+// to and from it. It must be safe to use concurrently.
+type Communicator interface {
+	// Start starts the command and returns without waiting for it to
+	// exit. The command must not be modified or started again.
+	Start(*RemoteCmd) error
+
+	// Upload writes the contents of the reader to the path on the
+	// machine.
+	Upload(string, io.Reader, *os.FileInfo) error
+
+	// UploadDir uploads the directory recursively to the path on the
+	// machine, except the paths of exclude. Like rsync(1), the directory
+	// itself is created in the destination unless src ends with a slash.
+	UploadDir(dst string, src string, exclude []string) error
+
+	// Download writes the file of the path on the machine to the writer.
+	Download(string, io.Writer) error
+
+	// DownloadDir downloads the directory on the machine recursively to
+	// the local path, except the paths of exclude.
+	DownloadDir(src string, dst string, exclude []string) error
+}
+
+// SetExited says the command exited with the status. Communicators call it
+// once the command is done.
+func (r *RemoteCmd) SetExited(status int) {
+	r.Lock()
+	defer r.Unlock()
+
+	if r.exitCh == nil {
+		r.exitCh = make(chan struct{})
+	}
+
+	r.Exited = true
+	r.ExitStatus = status
+	close(r.exitCh)
+}
+
+// Wait waits for the command to exit.
+func (r *RemoteCmd) Wait() {
+	r.Lock()
+	if r.exitCh == nil {
+		r.exitCh = make(chan struct{})
+	}
+	r.Unlock()
+
+	<-r.exitCh
+}
+
+// StartWithUi runs the command with the communicator and waits for it to
+// exit. Each line it writes is said with the Ui, with UiOutput, on top of
+// being written to Stdout and Stderr.
+func (r *RemoteCmd) StartWithUi(c Communicator, ui Ui) error {
+	stdoutR, stdoutW := io.Pipe()
+	stderrR, stderrW := io.Pipe()
+
+	originalStdout, originalStderr := r.Stdout, r.Stderr
+	defer func() {
+		r.Lock()
+		defer r.Unlock()
+
+		r.Stdout = originalStdout
+		r.Stderr = originalStderr
+	}()
+
+	r.Stdout, r.Stderr = stdoutW, stderrW
+	if originalStdout != nil {
+		r.Stdout = io.MultiWriter(originalStdout, stdoutW)
+	}
+	if originalStderr != nil {
+		r.Stderr = io.MultiWriter(originalStderr, stderrW)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go outputLines(&wg, stdoutR, ui, UiStreamStdout)
+	go outputLines(&wg, stderrR, ui, UiStreamStderr)
+
+	err := c.Start(r)
+	if err == nil {
+		r.Wait()
+	}
+	stdoutW.Close()
+	stderrW.Close()
+	wg.Wait()
+	return err
+}
+
+// outputLines says the lines of the stream with the Ui until it is closed.
+// The text up to the last carriage return of a line is left out, since it
+// was overwritten.
+func outputLines(wg *sync.WaitGroup, r io.Reader, ui Ui, stream string) {
+	defer wg.Done()
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRightFunc(scanner.Text(), unicode.IsSpace)
+		if i := strings.LastIndex(line, "\r"); i > -1 {
+			line = line[i+1:]
+		}
+		if line != "" {
+			UiOutput(ui, stream, line)
+		}
+	}
+	io.Copy(ioutil.Discard, r)
+}
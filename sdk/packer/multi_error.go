@@ -0,0 +1,46 @@
+package packer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MultiError is the errors of a configuration, returned as one error.
+type MultiError struct {
+	Errors []error
+}
+
+func (e *MultiError) Error() string {
+	points := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		points[i] = fmt.Sprintf("* %s", err)
+	}
+
+	return fmt.Sprintf(
+		"%d error(s) occurred:\n\n%s",
+		len(e.Errors), strings.Join(points, "\n"))
+}
+
+// MultiErrorAppend appends the errors to err, flattening the MultiErrors,
+// as the Prepare methods return them. If err is not a MultiError, it is
+// turned into one.
+func MultiErrorAppend(err error, errs ...error) *MultiError {
+	multi, ok := err.(*MultiError)
+	if !ok || multi == nil {
+		multi = new(MultiError)
+		if err != nil && !ok {
+			multi.Errors = append(multi.Errors, err)
+		}
+	}
+
+	for _, err := range errs {
+		if rhs, ok := err.(*MultiError); ok {
+			if rhs != nil {
+				multi.Errors = append(multi.Errors, rhs.Errors...)
+			}
+			continue
+		}
+		multi.Errors = append(multi.Errors, err)
+	}
+	return multi
+}
@@ -0,0 +1,124 @@
+// Package packer holds the interfaces plugins implement and use to talk to
+// the core. It doesn't import the rest of Packer: the core adapts its own
+// types to these, so they only change with the major version of the SDK.
+package packer
+
+// Builder builds an image on some platform given some configuration.
+type Builder interface {
+	// Prepare configures the builder and validates the configuration,
+	// without side effects. The configurations, almost always of type
+	// map[string]interface{}, are merged in order. It returns the warnings
+	// along with any error.
+	Prepare(...interface{}) ([]string, error)
+
+	// Run builds the image and returns its artifact.
+	Run(ui Ui, hook Hook, cache Cache) (Artifact, error)
+
+	// Cancel cancels a possibly running build. It blocks until the
+	// builder cancelled and cleaned up after itself.
+	Cancel()
+}
+
+// Provisioner installs and configures software on the machine of a build
+// before the image is made from it.
+type Provisioner interface {
+	// Prepare configures the provisioner, merging the configurations in
+	// order.
+	Prepare(...interface{}) error
+
+	// Provision provisions the machine of the communicator, which is
+	// connected.
+	Provision(Ui, Communicator) error
+
+	// Cancel cancels the provisioning, usually while Provision runs. It
+	// must stop it as quickly as possible in a race-free way.
+	Cancel()
+}
+
+// PostProcessor turns the artifact of a build into another, like a
+// compressed or uploaded one.
+type PostProcessor interface {
+	// Configure configures the post-processor, merging the configurations
+	// in order.
+	Configure(...interface{}) error
+
+	// PostProcess returns the artifact made from the one given. If keep
+	// is true, the given artifact is kept even if the user didn't ask
+	// to.
+	PostProcess(Ui, Artifact) (a Artifact, keep bool, err error)
+}
+
+// CancellablePostProcessor is a PostProcessor that can be cancelled while
+// it runs, as when Packer is interrupted.
+type CancellablePostProcessor interface {
+	PostProcessor
+
+	Cancel()
+}
+
+// Artifact is the result of a build, or of a post-processor.
+type Artifact interface {
+	// BuilderId is the ID of the builder, or post-processor, that made
+	// the artifact, so the post-processors know if they can use it.
+	BuilderId() string
+
+	// Files are the local files of the artifact, if any.
+	Files() []string
+
+	// Id is the ID of the artifact, like the ID of an image. Its format
+	// is up to the builder.
+	Id() string
+
+	// String is a human readable description of the artifact.
+	String() string
+
+	// State is a value of the artifact, for the post-processors that know
+	// the builder. It is nil if there's none of the name.
+	State(name string) interface{}
+
+	// Destroy deletes the artifact.
+	Destroy() error
+}
+
+// HookProvision is the hook builders run to provision the machine.
+const HookProvision = "packer_provision"
+
+// Hook runs at named points of a build, with data that depends on the
+// hook, like HookProvision that runs the provisioners.
+type Hook interface {
+	Run(string, Ui, Communicator, interface{}) error
+
+	// Cancel cancels the hook, usually while Run runs.
+	Cancel()
+}
+
+// Cache stores files between runs of Packer. The keys are locked while
+// the files are written and read, so builds running at the same time
+// don't step on each other.
+type Cache interface {
+	// Lock locks the key for writing and returns the path to write the
+	// file of the key to.
+	Lock(string) string
+
+	// Unlock unlocks the key locked with Lock.
+	Unlock(string)
+
+	// RLock locks the key for reading, and returns the path of its file
+	// and whether it exists.
+	RLock(string) (string, bool)
+
+	// RUnlock unlocks the key locked with RLock.
+	RUnlock(string)
+}
+
+// The keys of the configuration the core adds for builders,
+// provisioners, and post-processors.
+const (
+	BuildNameConfigKey     = "packer_build_name"
+	BuilderTypeConfigKey   = "packer_builder_type"
+	DebugConfigKey         = "packer_debug"
+	ForceConfigKey         = "packer_force"
+	OnErrorConfigKey       = "packer_on_error"
+	TemplatePathKey        = "packer_template_path"
+	UserVariablesConfigKey = "packer_user_variables"
+)
@@ -0,0 +1,53 @@
+package packer
+
+import "io"
+
+// Ui is how components talk to the user.
+type Ui interface {
+	Ask(string) (string, error)
+	Say(string)
+	Message(string)
+	Error(string)
+	Machine(string, ...string)
+	ProgressBar() ProgressBar
+}
+
+// ProgressBar shows the progress of a transfer, like a download.
+type ProgressBar interface {
+	Start(total int64)
+	Add(current int64)
+	NewProxyReader(r io.Reader) (proxy io.Reader)
+	Finish()
+}
+
+// The streams of the output of commands.
+const (
+	UiStreamStdout = "stdout"
+	UiStreamStderr = "stderr"
+)
+
+// OutputUi is a Ui that tells the streams of the output of commands apart,
+// like the ones of the core do.
+type OutputUi interface {
+	Ui
+
+	// Output says a line that a command wrote to the stream,
+	// UiStreamStdout or UiStreamStderr.
+	Output(stream, line string)
+}
+
+// UiOutput says the line a command wrote to the stream with the Ui. The
+// Uis that aren't an OutputUi show stderr with Error and stdout with
+// Message.
+func UiOutput(ui Ui, stream, line string) {
+	if oui, ok := ui.(OutputUi); ok {
+		oui.Output(stream, line)
+		return
+	}
+
+	if stream == UiStreamStderr {
+		ui.Error(line)
+	} else {
+		ui.Message(line)
+	}
+}
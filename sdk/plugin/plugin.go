@@ -0,0 +1,79 @@
+// Package plugin serves a component from a plugin binary, for Packer to
+// run it.
+package plugin
+
+import (
+	corepacker "github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/packer/plugin"
+	"github.com/hashicorp/packer/packer/rpc"
+	"github.com/hashicorp/packer/sdk/packer"
+)
+
+// ComponentServer serves the components registered to it to Packer.
+type ComponentServer struct {
+	server *rpc.Server
+}
+
+// Server waits for Packer to connect to the plugin, and returns the server
+// to register the component to and serve it with.
+func Server() (*ComponentServer, error) {
+	server, err := plugin.Server()
+	if err != nil {
+		return nil, err
+	}
+	return &ComponentServer{server: server}, nil
+}
+
+// RegisterBuilder registers the builder to serve.
+func (s *ComponentServer) RegisterBuilder(b packer.Builder) {
+	s.server.RegisterBuilder(corepacker.SDKBuilder(b))
+}
+
+// RegisterProvisioner registers the provisioner to serve.
+func (s *ComponentServer) RegisterProvisioner(p packer.Provisioner) {
+	s.server.RegisterProvisioner(corepacker.SDKProvisioner(p))
+}
+
+// RegisterPostProcessor registers the post-processor to serve.
+func (s *ComponentServer) RegisterPostProcessor(p packer.PostProcessor) {
+	s.server.RegisterPostProcessor(corepacker.SDKPostProcessor(p))
+}
+
+// Serve serves the registered component until Packer is done with it.
+func (s *ComponentServer) Serve() {
+	s.server.Serve()
+}
+
+// ServeBuilder serves the builder, until Packer is done with it.
+func ServeBuilder(b packer.Builder) error {
+	server, err := Server()
+	if err != nil {
+		return err
+	}
+	server.RegisterBuilder(b)
+	server.Serve()
+	return nil
+}
+
+// ServeProvisioner serves the provisioner, until Packer is done with it.
+func ServeProvisioner(p packer.Provisioner) error {
+	server, err := Server()
+	if err != nil {
+		return err
+	}
+	server.RegisterProvisioner(p)
+	server.Serve()
+	return nil
+}
+
+// ServePostProcessor serves the post-processor, until Packer is done with
+// it.
+func ServePostProcessor(p packer.PostProcessor) error {
+	server, err := Server()
+	if err != nil {
+		return err
+	}
+	server.RegisterPostProcessor(p)
+	server.Serve()
+	return nil
+}
@@ -0,0 +1,26 @@
+// Package sdk is the surface of Packer that plugins are meant to build on.
+//
+// The packages under sdk only are covered by compatibility promises: within
+// a major Version, what they export is not removed nor changed in a way
+// that breaks plugins compiled against them. New fields, methods, and
+// functions may be added in minor versions. The other packages of Packer
+// change whenever Packer needs them to, and plugins importing them can
+// break with any release.
+//
+//   - sdk/packer: the interfaces of builders, provisioners, post-processors,
+//     and communicators, and the types they use.
+//   - sdk/multistep: the steps builders are written as, and the runner that
+//     adds -debug and -on-error to them.
+//   - sdk/config: decoding and interpolating the configuration.
+//   - sdk/download: downloading files, with checksums.
+//   - sdk/plugin: serving a component from a plugin binary.
+//   - sdk/acctest: the acceptance test harness.
+//
+// The SDK defines its types itself, and sdk/packer imports nothing else of
+// Packer, so changes to the internal packages don't reach plugins. The core
+// adapts the components of the SDK to its own types, see packer.SDKBuilder.
+package sdk
+
+// Version is the version of the SDK, following semantic versioning. It is
+// versioned separately from Packer.
+const Version = "1.0.0"
@@ -0,0 +1,367 @@
+package template
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/mitchellh/mapstructure"
+)
+
+// groupRef is a use of a provisioner or post-processor group, in place of
+// a provisioner or post-processor. Its only and except narrow down the
+// builds the members of the group run for.
+type groupRef struct {
+	OnlyExcept `mapstructure:",squash"`
+
+	Group string
+}
+
+// rawGroups are the provisioner and post-processor groups of a template or
+// of a file it includes.
+type rawGroups struct {
+	ProvisionerGroups   map[string][]map[string]interface{} `mapstructure:"provisioner-groups"`
+	PostProcessorGroups map[string][]interface{}            `mapstructure:"post-processor-groups"`
+}
+
+// isGroupRef says whether a provisioner or post-processor is the use of a
+// group.
+func isGroupRef(v interface{}) bool {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	_, ok = m["group"]
+	return ok
+}
+
+func (r *rawTemplate) parseGroupRef(v map[string]interface{}) (*groupRef, error) {
+	var ref groupRef
+	var md mapstructure.Metadata
+	if err := r.decoder(&ref, &md).Decode(v); err != nil {
+		return nil, err
+	}
+
+	var errs error
+	sort.Strings(md.Unused)
+	for _, unused := range md.Unused {
+		errs = multierror.Append(errs, fmt.Errorf(
+			"unknown key '%s', a group can only be used with 'only' or 'except'", unused))
+	}
+	if ref.Group == "" {
+		errs = multierror.Append(errs, fmt.Errorf("missing 'group'"))
+	}
+	if len(ref.Only) > 0 && len(ref.Except) > 0 {
+		errs = multierror.Append(errs, fmt.Errorf(
+			"only one of 'only' or 'except' may be specified"))
+	}
+	if errs != nil {
+		return nil, errs
+	}
+	return &ref, nil
+}
+
+// readIncludes adds the groups of the files the template includes to the
+// ones of the template. Relative paths are relative to the template.
+func (r *rawTemplate) readIncludes() error {
+	var errs error
+	for _, path := range r.Include {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(r.dir, path)
+		}
+
+		var groups rawGroups
+		src, err := readGroupsFile(path, &groups)
+		if err != nil {
+			for _, e := range multierror.Append(err).Errors {
+				errs = multierror.Append(errs, fmt.Errorf("include %s: %s", path, e))
+			}
+			continue
+		}
+
+		for name, g := range groups.ProvisionerGroups {
+			if _, ok := r.ProvisionerGroups[name]; ok {
+				errs = multierror.Append(errs, fmt.Errorf(
+					"include %s: provisioner group '%s' already exists", path, name))
+				continue
+			}
+			if r.ProvisionerGroups == nil {
+				r.ProvisionerGroups = make(map[string][]map[string]interface{})
+			}
+			r.ProvisionerGroups[name] = g
+			r.setGroupSource("provisioner-groups."+name, src)
+		}
+		for name, g := range groups.PostProcessorGroups {
+			if _, ok := r.PostProcessorGroups[name]; ok {
+				errs = multierror.Append(errs, fmt.Errorf(
+					"include %s: post-processor group '%s' already exists", path, name))
+				continue
+			}
+			if r.PostProcessorGroups == nil {
+				r.PostProcessorGroups = make(map[string][]interface{})
+			}
+			r.PostProcessorGroups[name] = g
+			r.setGroupSource("post-processor-groups."+name, src)
+		}
+	}
+	return errs
+}
+
+func (r *rawTemplate) setGroupSource(group string, src source) {
+	if r.groupSources == nil {
+		r.groupSources = make(map[string]source)
+	}
+	r.groupSources[group] = src
+}
+
+// groupSource is the file the group is defined in, like
+// "provisioner-groups.base".
+func (r *rawTemplate) groupSource(group string) source {
+	if src, ok := r.groupSources[group]; ok {
+		return src
+	}
+	return r.source
+}
+
+// readGroupsFile reads a file of groups, written in JSON or YAML, and
+// returns its source. It can't have anything else, and can't include other
+// files.
+func readGroupsFile(path string, groups *rawGroups) (source, error) {
+	src := source{filename: path}
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return src, err
+	}
+	if IsYAML(path) {
+		if contents, err = YAMLToJSON(contents); err != nil {
+			return src, err
+		}
+	} else {
+		src.positions = NewPositions(contents)
+	}
+
+	var raw interface{}
+	if err := json.Unmarshal(contents, &raw); err != nil {
+		return src, err
+	}
+	var md mapstructure.Metadata
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		Metadata: &md,
+		Result:   groups,
+	})
+	if err != nil {
+		return src, err
+	}
+	if err := decoder.Decode(raw); err != nil {
+		return src, err
+	}
+
+	var errs error
+	sort.Strings(md.Unused)
+	for _, unused := range md.Unused {
+		if unused[0] == '_' {
+			continue
+		}
+		errs = multierror.Append(errs, fmt.Errorf(
+			"unknown root level key '%s', included files can only have groups", unused))
+	}
+	return src, errs
+}
+
+// parseProvisionerGroups parses the provisioner groups, whether they are
+// used or not.
+func (r *rawTemplate) parseProvisionerGroups() (map[string][]*Provisioner, error) {
+	var errs error
+	result := make(map[string][]*Provisioner, len(r.ProvisionerGroups))
+	names := make([]string, 0, len(r.ProvisionerGroups))
+	for name := range r.ProvisionerGroups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		group := "provisioner-groups." + name
+		src := r.groupSource(group)
+		var ps []*Provisioner
+		for i, v := range r.ProvisionerGroups[name] {
+			if isGroupRef(v) {
+				errs = multierror.Append(errs, fmt.Errorf(
+					"provisioner group '%s': provisioner %d: groups can't be used in groups", name, i+1))
+				continue
+			}
+			p, err := r.parseProvisioner(v)
+			if err != nil {
+				errs = multierror.Append(errs, fmt.Errorf(
+					"provisioner group '%s': provisioner %d: %s", name, i+1, err))
+				continue
+			}
+			p.Pos = src.pos(fmt.Sprintf("%s[%d]", group, i))
+			ps = append(ps, p)
+		}
+		result[name] = ps
+	}
+	return result, errs
+}
+
+// parsePostProcessorGroups parses the post-processor groups, whether they
+// are used or not.
+func (r *rawTemplate) parsePostProcessorGroups() (map[string][][]*PostProcessor, error) {
+	var errs error
+	result := make(map[string][][]*PostProcessor, len(r.PostProcessorGroups))
+	names := make([]string, 0, len(r.PostProcessorGroups))
+	for name := range r.PostProcessorGroups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		group := "post-processor-groups." + name
+		var chains [][]*PostProcessor
+		for i, v := range r.PostProcessorGroups[name] {
+			if isGroupRef(v) {
+				errs = multierror.Append(errs, fmt.Errorf(
+					"post-processor group '%s': post-processor %d: groups can't be used in groups", name, i+1))
+				continue
+			}
+			chain, err := r.parsePostProcessorChain(r.groupSource(group), group, i, v)
+			if err != nil {
+				for _, e := range multierror.Append(err).Errors {
+					errs = multierror.Append(errs, fmt.Errorf(
+						"post-processor group '%s': %s", name, e))
+				}
+				continue
+			}
+			chains = append(chains, chain)
+		}
+		result[name] = chains
+	}
+	return result, errs
+}
+
+// expandProvisioners returns the provisioners, with the members of the
+// groups in place of their uses.
+// The provisioners are the list at path in the template.
+func (r *rawTemplate) expandProvisioners(
+	path string, raw []map[string]interface{}, groups map[string][]*Provisioner) ([]*Provisioner, error) {
+	var errs error
+	var result []*Provisioner
+	for i, v := range raw {
+		if !isGroupRef(v) {
+			p, err := r.parseProvisioner(v)
+			if err != nil {
+				errs = multierror.Append(errs, fmt.Errorf(
+					"provisioner %d: %s", i+1, err))
+				continue
+			}
+			p.Pos = r.source.pos(fmt.Sprintf("%s[%d]", path, i))
+			result = append(result, p)
+			continue
+		}
+
+		ref, err := r.parseGroupRef(v)
+		if err != nil {
+			for _, e := range multierror.Append(err).Errors {
+				errs = multierror.Append(errs, fmt.Errorf("provisioner %d: %s", i+1, e))
+			}
+			continue
+		}
+		group, ok := groups[ref.Group]
+		if !ok {
+			errs = multierror.Append(errs, fmt.Errorf(
+				"provisioner %d: provisioner group '%s' not found", i+1, ref.Group))
+			continue
+		}
+		for _, member := range group {
+			oe, ok := member.OnlyExcept.Within(ref.OnlyExcept)
+			if !ok {
+				continue
+			}
+			p := *member
+			p.OnlyExcept = oe
+			p.Config = copyConfig(member.Config)
+			p.Override = copyConfig(member.Override)
+			result = append(result, &p)
+		}
+	}
+	return result, errs
+}
+
+// expandPostProcessors returns the post-processors, with the sequences of
+// the groups in place of their uses.
+func (r *rawTemplate) expandPostProcessors(
+	raw []interface{}, groups map[string][][]*PostProcessor) ([][]*PostProcessor, error) {
+	var errs error
+	var result [][]*PostProcessor
+	for i, v := range raw {
+		if !isGroupRef(v) {
+			chain, err := r.parsePostProcessorChain(r.source, "post-processors", i, v)
+			if err != nil {
+				errs = multierror.Append(errs, err)
+				continue
+			}
+			result = append(result, chain)
+			continue
+		}
+
+		ref, err := r.parseGroupRef(v.(map[string]interface{}))
+		if err != nil {
+			for _, e := range multierror.Append(err).Errors {
+				errs = multierror.Append(errs, fmt.Errorf("post-processor %d: %s", i+1, e))
+			}
+			continue
+		}
+		group, ok := groups[ref.Group]
+		if !ok {
+			errs = multierror.Append(errs, fmt.Errorf(
+				"post-processor %d: post-processor group '%s' not found", i+1, ref.Group))
+			continue
+		}
+		for _, members := range group {
+			var chain []*PostProcessor
+			for _, member := range members {
+				oe, ok := member.OnlyExcept.Within(ref.OnlyExcept)
+				if !ok {
+					continue
+				}
+				pp := *member
+				pp.OnlyExcept = oe
+				pp.Config = copyConfig(member.Config)
+				pp.Override = copyConfig(member.Override)
+				chain = append(chain, &pp)
+			}
+			if len(chain) > 0 {
+				result = append(result, chain)
+			}
+		}
+	}
+	return result, errs
+}
+
+// copyConfig is a deep copy of a configuration, so the uses of a group
+// don't share it.
+func copyConfig(config map[string]interface{}) map[string]interface{} {
+	if config == nil {
+		return nil
+	}
+	return copyValue(config).(map[string]interface{})
+}
+
+func copyValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for k, value := range v {
+			result[k] = copyValue(value)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, value := range v {
+			result[i] = copyValue(value)
+		}
+		return result
+	default:
+		return v
+	}
+}
@@ -1,10 +1,17 @@
 package interpolate
 
 import (
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"hash"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 	"text/template"
@@ -12,9 +19,11 @@ import (
 
 	consulapi "github.com/hashicorp/consul/api"
 	"github.com/hashicorp/packer/common/uuid"
+	commonhelper "github.com/hashicorp/packer/helper/common"
 	"github.com/hashicorp/packer/version"
 	vaultapi "github.com/hashicorp/vault/api"
 	"github.com/rwtodd/Go.Sed/sed"
+	"golang.org/x/crypto/bcrypt"
 )
 
 // InitTime is the UTC time when this package was initialized. It is
@@ -22,26 +31,55 @@ import (
 // match for a single build.
 var InitTime time.Time
 
+// EnvInitTime is the environment variable the core passes its InitTime to
+// the plugins in, so the time is the same in all the processes of a run.
+const EnvInitTime = "PACKER_INIT_TIME"
+
 func init() {
 	InitTime = time.Now().UTC()
 }
 
 // Funcs are the interpolation funcs that are available within interpolations.
 var FuncGens = map[string]FuncGenerator{
+	"build":          funcGenBuild,
 	"build_name":     funcGenBuildName,
+	"build_password": funcGenBuildPassword,
 	"build_type":     funcGenBuildType,
 	"env":            funcGenEnv,
 	"isotime":        funcGenIsotime,
+	"strftime":       funcGenStrftime,
 	"pwd":            funcGenPwd,
 	"split":          funcGenSplitter,
 	"template_dir":   funcGenTemplateDir,
 	"timestamp":      funcGenTimestamp,
 	"uuid":           funcGenUuid,
+	"uuidv4":         funcGenUuidv4,
+	"random_string":  funcGenRandomString,
+	"random_int":     funcGenRandomInt,
 	"user":           funcGenUser,
 	"packer_version": funcGenPackerVersion,
 	"consul_key":     funcGenConsul,
 	"vault":          funcGenVault,
 	"sed":            funcGenSed,
+	"replace":        funcGenReplace,
+	"regex_replace":  funcGenRegexReplace,
+	"join":           funcGenJoin,
+	"trim":           funcGenTrim,
+	"add":            funcGenArithmetic("add"),
+	"sub":            funcGenArithmetic("sub"),
+	"mul":            funcGenArithmetic("mul"),
+	"div":            funcGenArithmetic("div"),
+	"mod":            funcGenArithmetic("mod"),
+	"min":            funcGenMinMax("min"),
+	"max":            funcGenMinMax("max"),
+	"int":            funcGenInt,
+	"ternary":        funcGenTernary,
+	"md5":            funcGenHash(md5.New),
+	"sha256":         funcGenHash(sha256.New),
+	"sha512":         funcGenHash(sha512.New),
+	"bcrypt":         funcGenBcrypt,
+	"base64_encode":  funcGenBase64Encode,
+	"base64_decode":  funcGenBase64Decode,
 
 	"upper": funcGenPrimitive(strings.ToUpper),
 	"lower": funcGenPrimitive(strings.ToLower),
@@ -78,6 +116,238 @@ func funcGenSplitter(ctx *Context) interface{} {
 	}
 }
 
+// The string functions take the string last, so they can be the target
+// of a pipe: {{user `version` | replace "." "-"}}.
+
+func funcGenReplace(ctx *Context) interface{} {
+	return func(old, new, s string) string {
+		return strings.Replace(s, old, new, -1)
+	}
+}
+
+func funcGenRegexReplace(ctx *Context) interface{} {
+	return func(pattern, repl, s string) (string, error) {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return "", fmt.Errorf("regex_replace: %s", err)
+		}
+		return re.ReplaceAllString(s, repl), nil
+	}
+}
+
+func funcGenJoin(ctx *Context) interface{} {
+	return func(sep string, elems ...string) string {
+		return strings.Join(elems, sep)
+	}
+}
+
+func funcGenTrim(ctx *Context) interface{} {
+	return func(args ...string) (string, error) {
+		switch len(args) {
+		case 1:
+			return strings.TrimSpace(args[0]), nil
+		case 2:
+			return strings.Trim(args[1], args[0]), nil
+		default:
+			return "", fmt.Errorf("trim takes a string, and optionally the characters to trim first")
+		}
+	}
+}
+
+// The arithmetic functions take numbers, or strings of numbers like user
+// variables. The result is an integer if all of them are integers.
+
+// number parses v as an int64 if it can, or a float64.
+func number(v interface{}) (int64, float64, bool, error) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), float64(n), true, nil
+	case int64:
+		return n, float64(n), true, nil
+	case float64:
+		return 0, n, false, nil
+	case string:
+		s := strings.TrimSpace(n)
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i, float64(i), true, nil
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return 0, f, false, nil
+		}
+	}
+	return 0, 0, false, fmt.Errorf("%#v is not a number", v)
+}
+
+func funcGenArithmetic(op string) FuncGenerator {
+	return func(ctx *Context) interface{} {
+		return func(a, b interface{}) (interface{}, error) {
+			ai, af, aInt, err := number(a)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", op, err)
+			}
+			bi, bf, bInt, err := number(b)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s", op, err)
+			}
+
+			if (op == "div" || op == "mod") && bf == 0 {
+				return nil, fmt.Errorf("%s: division by zero", op)
+			}
+			if aInt && bInt {
+				switch op {
+				case "add":
+					return ai + bi, nil
+				case "sub":
+					return ai - bi, nil
+				case "mul":
+					return ai * bi, nil
+				case "div":
+					return ai / bi, nil
+				default:
+					return ai % bi, nil
+				}
+			}
+
+			switch op {
+			case "add":
+				return af + bf, nil
+			case "sub":
+				return af - bf, nil
+			case "mul":
+				return af * bf, nil
+			case "div":
+				return af / bf, nil
+			default:
+				return nil, fmt.Errorf("mod: only works with integers")
+			}
+		}
+	}
+}
+
+func funcGenMinMax(op string) FuncGenerator {
+	return func(ctx *Context) interface{} {
+		return func(values ...interface{}) (interface{}, error) {
+			if len(values) == 0 {
+				return nil, fmt.Errorf("%s: needs at least one number", op)
+			}
+
+			var result interface{}
+			var resultF float64
+			for i, v := range values {
+				n, f, isInt, err := number(v)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %s", op, err)
+				}
+				if i == 0 || (op == "min" && f < resultF) || (op == "max" && f > resultF) {
+					resultF = f
+					result = f
+					if isInt {
+						result = n
+					}
+				}
+			}
+			return result, nil
+		}
+	}
+}
+
+func funcGenInt(ctx *Context) interface{} {
+	return func(v interface{}) (int64, error) {
+		n, f, isInt, err := number(v)
+		if err != nil {
+			return 0, fmt.Errorf("int: %s", err)
+		}
+		if !isInt {
+			return int64(f), nil
+		}
+		return n, nil
+	}
+}
+
+// funcGenTernary returns a if cond is true, b otherwise. cond is a bool or
+// a string like a user variable: "true", "1", "false", "0", or empty.
+func funcGenTernary(ctx *Context) interface{} {
+	return func(a, b, cond interface{}) (interface{}, error) {
+		var ok bool
+		switch c := cond.(type) {
+		case bool:
+			ok = c
+		case string:
+			if c != "" {
+				var err error
+				ok, err = strconv.ParseBool(c)
+				if err != nil {
+					return nil, fmt.Errorf("ternary: %q is not true or false", c)
+				}
+			}
+		default:
+			return nil, fmt.Errorf("ternary: %#v is not true or false", cond)
+		}
+
+		if ok {
+			return a, nil
+		}
+		return b, nil
+	}
+}
+
+// funcGenHash returns the hex encoded hash of the string.
+func funcGenHash(newHash func() hash.Hash) FuncGenerator {
+	return func(ctx *Context) interface{} {
+		return func(s string) string {
+			h := newHash()
+			h.Write([]byte(s))
+			return hex.EncodeToString(h.Sum(nil))
+		}
+	}
+}
+
+// funcGenBcrypt hashes the password with bcrypt, at the default cost
+// unless one is given first. The salt is random, so each call returns
+// another hash.
+func funcGenBcrypt(ctx *Context) interface{} {
+	return func(args ...interface{}) (string, error) {
+		cost := bcrypt.DefaultCost
+		switch len(args) {
+		case 1:
+		case 2:
+			c, _, isInt, err := number(args[0])
+			if err != nil || !isInt {
+				return "", fmt.Errorf("bcrypt: the cost must be an integer")
+			}
+			cost = int(c)
+		default:
+			return "", fmt.Errorf("bcrypt takes a password, and optionally the cost first")
+		}
+
+		password, ok := args[len(args)-1].(string)
+		if !ok {
+			return "", fmt.Errorf("bcrypt: the password must be a string")
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), cost)
+		if err != nil {
+			return "", fmt.Errorf("bcrypt: %s", err)
+		}
+		return string(hash), nil
+	}
+}
+
+func funcGenBase64Encode(ctx *Context) interface{} {
+	return func(s string) string {
+		return base64.StdEncoding.EncodeToString([]byte(s))
+	}
+}
+
+func funcGenBase64Decode(ctx *Context) interface{} {
+	return func(s string) (string, error) {
+		raw, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return "", fmt.Errorf("base64_decode: %s", err)
+		}
+		return string(raw), nil
+	}
+}
+
 func funcGenBuildName(ctx *Context) interface{} {
 	return func() (string, error) {
 		if ctx == nil || ctx.BuildName == "" {
@@ -88,6 +358,16 @@ func funcGenBuildName(ctx *Context) interface{} {
 	}
 }
 
+func funcGenBuildPassword(ctx *Context) interface{} {
+	return func() (string, error) {
+		if ctx == nil || ctx.BuildName == "" {
+			return "", errors.New("build_password not available")
+		}
+
+		return commonhelper.BuildPassword(ctx.BuildName)
+	}
+}
+
 func funcGenBuildType(ctx *Context) interface{} {
 	return func() (string, error) {
 		if ctx == nil || ctx.BuildType == "" {
@@ -98,6 +378,44 @@ func funcGenBuildType(ctx *Context) interface{} {
 	}
 }
 
+func funcGenBuild(ctx *Context) interface{} {
+	return func(key string) (string, error) {
+		if ctx == nil || ctx.BuildName == "" {
+			return "", errors.New("build not available")
+		}
+
+		switch key {
+		case "Name":
+			return ctx.BuildName, nil
+		case "Type":
+			return ctx.BuildType, nil
+		}
+		for _, k := range commonhelper.BuildValues {
+			if k != key {
+				continue
+			}
+			if ctx.BuildValues != nil {
+				// The builder ran, as for the post-processors
+				if value, ok := ctx.BuildValues[key]; ok {
+					return value, nil
+				}
+				return "", fmt.Errorf("the builder of %s didn't record the build %s", ctx.BuildName, key)
+			}
+			if value, ok := commonhelper.BuildValue(key, ctx.BuildName); ok {
+				return value, nil
+			}
+
+			// The builder hasn't recorded it yet, as when the configuration
+			// is decoded before the build. Leave the call in for when the
+			// setting is interpolated again while the build runs.
+			return fmt.Sprintf("{{build `%s`}}", key), nil
+		}
+
+		return "", fmt.Errorf("build has no %q, only Name, Type, %s",
+			key, strings.Join(commonhelper.BuildValues, ", "))
+	}
+}
+
 func funcGenEnv(ctx *Context) interface{} {
 	return func(k string) (string, error) {
 		if !ctx.EnableEnv {
@@ -116,12 +434,102 @@ func funcGenIsotime(ctx *Context) interface{} {
 			return InitTime.Format(time.RFC3339), nil
 		}
 
-		if len(format) > 1 {
-			return "", fmt.Errorf("too many values, 1 needed: %v", format)
+		if len(format) > 2 {
+			return "", fmt.Errorf("too many values, 1 or 2 needed: %v", format)
+		}
+
+		t, err := initTimeIn(format[1:])
+		if err != nil {
+			return "", err
+		}
+		return t.Format(format[0]), nil
+	}
+}
+
+// strftimeLayouts are the Go layouts of the strftime conversions that
+// have one.
+var strftimeLayouts = map[byte]string{
+	'a': "Mon",
+	'A': "Monday",
+	'b': "Jan",
+	'B': "January",
+	'd': "02",
+	'e': "_2",
+	'F': "2006-01-02",
+	'H': "15",
+	'I': "03",
+	'm': "01",
+	'M': "04",
+	'p': "PM",
+	'S': "05",
+	'T': "15:04:05",
+	'y': "06",
+	'Y': "2006",
+	'z': "-0700",
+	'Z': "MST",
+}
+
+func funcGenStrftime(ctx *Context) interface{} {
+	return func(format string, tz ...string) (string, error) {
+		if len(tz) > 1 {
+			return "", fmt.Errorf("too many values, 1 or 2 needed")
+		}
+		t, err := initTimeIn(tz)
+		if err != nil {
+			return "", err
+		}
+		return strftime(t, format)
+	}
+}
+
+// strftime formats t like strftime(3) does with format.
+func strftime(t time.Time, format string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			b.WriteByte(format[i])
+			continue
+		}
+		i++
+		if i == len(format) {
+			return "", fmt.Errorf("strftime: format ends with %%")
 		}
 
-		return InitTime.Format(format[0]), nil
+		c := format[i]
+		if layout, ok := strftimeLayouts[c]; ok {
+			b.WriteString(t.Format(layout))
+			continue
+		}
+		switch c {
+		case 'j':
+			fmt.Fprintf(&b, "%03d", t.YearDay())
+		case 's':
+			b.WriteString(strconv.FormatInt(t.Unix(), 10))
+		case 'u':
+			wd := int(t.Weekday())
+			if wd == 0 {
+				wd = 7
+			}
+			b.WriteString(strconv.Itoa(wd))
+		case '%':
+			b.WriteByte('%')
+		default:
+			return "", fmt.Errorf("strftime: unknown conversion %%%c", c)
+		}
+	}
+	return b.String(), nil
+}
+
+// initTimeIn returns InitTime in the time zone tz, UTC if there is none.
+func initTimeIn(tz []string) (time.Time, error) {
+	if len(tz) == 0 || tz[0] == "" {
+		return InitTime, nil
+	}
+	loc, err := time.LoadLocation(tz[0])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unknown time zone %q: %s", tz[0], err)
 	}
+	return InitTime.In(loc), nil
 }
 
 func funcGenPrimitive(value interface{}) FuncGenerator {
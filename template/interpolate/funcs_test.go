@@ -1,6 +1,9 @@
 package interpolate
 
 import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -8,7 +11,9 @@ import (
 	"testing"
 	"time"
 
+	commonhelper "github.com/hashicorp/packer/helper/common"
 	"github.com/hashicorp/packer/version"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func TestFuncBuildName(t *testing.T) {
@@ -36,6 +41,81 @@ func TestFuncBuildName(t *testing.T) {
 	}
 }
 
+func TestFuncBuild(t *testing.T) {
+	defer os.Setenv("PACKER_RUN_UUID", os.Getenv("PACKER_RUN_UUID"))
+	os.Setenv("PACKER_RUN_UUID", "interpolate-test")
+	defer commonhelper.RemoveSharedState()
+
+	if err := commonhelper.SetBuildValue("ID", "i-1234", "foo"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	cases := []struct {
+		Input         string
+		Output        string
+		ErrorExpected bool
+	}{
+		{`{{build "Name"}}`, "foo", false},
+		{`{{build "Type"}}`, "bar", false},
+		{`{{build "ID"}}`, "i-1234", false},
+		// Not recorded yet, left for a later interpolation.
+		{`{{build "Host"}}`, "{{build `Host`}}", false},
+		{`{{build "Nope"}}`, "", true},
+	}
+
+	ctx := &Context{BuildName: "foo", BuildType: "bar"}
+	for _, tc := range cases {
+		i := &I{Value: tc.Input}
+		result, err := i.Render(ctx)
+		if (err != nil) != tc.ErrorExpected {
+			t.Fatalf("Input: %s\n\nerr: %s", tc.Input, err)
+		}
+
+		if result != tc.Output {
+			t.Fatalf("Input: %s\n\nGot: %s", tc.Input, result)
+		}
+	}
+
+	// The call that was left renders once the value is recorded.
+	if err := commonhelper.SetBuildValue("Host", "10.0.0.1", "foo"); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if result, err := Render("{{build `Host`}}", ctx); err != nil || result != "10.0.0.1" {
+		t.Fatalf("bad: %s %s", result, err)
+	}
+
+	// Once the builder ran, only the values it recorded are available.
+	ctx.BuildValues = map[string]string{"ID": "i-5678"}
+	if result, err := Render("{{build `ID`}}", ctx); err != nil || result != "i-5678" {
+		t.Fatalf("bad: %s %s", result, err)
+	}
+	if _, err := Render("{{build `Password`}}", ctx); err == nil {
+		t.Fatal("should error for a value that wasn't recorded")
+	}
+}
+
+func TestFuncBuildPassword(t *testing.T) {
+	defer os.Setenv("PACKER_RUN_UUID", os.Getenv("PACKER_RUN_UUID"))
+	os.Setenv("PACKER_RUN_UUID", "interpolate-test")
+	defer commonhelper.RemoveSharedState()
+
+	ctx := &Context{BuildName: "foo"}
+	first, err := Render("{{build_password}}", ctx)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if first == "" {
+		t.Fatal("should have a password")
+	}
+	if second, err := Render("{{build_password}}", ctx); err != nil || second != first {
+		t.Fatalf("should be the same password: %s %s", second, err)
+	}
+
+	if _, err := Render("{{build_password}}", &Context{}); err == nil {
+		t.Fatal("should error without a build")
+	}
+}
+
 func TestFuncBuildType(t *testing.T) {
 	cases := []struct {
 		Input  string
@@ -140,6 +220,38 @@ func TestFuncIsotime(t *testing.T) {
 	}
 }
 
+func TestFuncStrftime(t *testing.T) {
+	defer func(old time.Time) { InitTime = old }(InitTime)
+	InitTime = time.Date(2018, 3, 4, 23, 5, 9, 0, time.UTC)
+
+	cases := []struct {
+		Input         string
+		Output        string
+		ErrorExpected bool
+	}{
+		{`{{strftime "%Y%m%d-%H%M%S"}}`, "20180304-230509", false},
+		{`{{strftime "%a %d %b %Y, day %j, %u of the week, %s"}}`, "Sun 04 Mar 2018, day 063, 7 of the week, 1520204709", false},
+		{`{{strftime "%F %T %Z 100%%"}}`, "2018-03-04 23:05:09 UTC 100%", false},
+		{`{{strftime "%F %T %z" "Europe/Berlin"}}`, "2018-03-05 00:05:09 +0100", false},
+		{`{{isotime "2006-01-02 15:04 MST" "America/New_York"}}`, "2018-03-04 18:05 EST", false},
+		{`{{strftime "%F" "Nowhere/Special"}}`, "", true},
+		{`{{strftime "%Q"}}`, "", true},
+		{`{{strftime "100%"}}`, "", true},
+	}
+
+	for _, tc := range cases {
+		i := &I{Value: tc.Input}
+		result, err := i.Render(&Context{})
+		if (err == nil) == tc.ErrorExpected {
+			t.Fatalf("Input: %s\n\nerr: %s", tc.Input, err)
+		}
+
+		if result != tc.Output {
+			t.Fatalf("Input: %s\n\nGot: %s", tc.Input, result)
+		}
+	}
+}
+
 func TestFuncPwd(t *testing.T) {
 	wd, err := os.Getwd()
 	if err != nil {
@@ -354,3 +466,194 @@ func TestFuncSed(t *testing.T) {
 		}
 	}
 }
+
+func TestFuncStrings(t *testing.T) {
+	cases := []struct {
+		Input         string
+		Output        string
+		ErrorExpected bool
+	}{
+		{`{{user "version" | replace "." "-"}}`, "1-2-3", false},
+		{`{{replace "-" "" "a-b-c"}}`, "abc", false},
+		{`{{user "branch" | regex_replace "[^a-z0-9]+" "-"}}`, "feature-ami-names", false},
+		{`{{regex_replace "^v(\\d+)\\..*$" "$1" "v12.4"}}`, "12", false},
+		{`{{regex_replace "(" "" "a"}}`, "", true},
+		{`{{user "version" | join "-" "app"}}`, "app-1.2.3", false},
+		{`{{join "," "a" "b" "c"}}`, "a,b,c", false},
+		{`{{user "padded" | trim}}`, "v1.2.3", false},
+		{`{{user "padded" | trim | trim "v"}}`, "1.2.3", false},
+		{`{{trim "a" "b" "c"}}`, "", true},
+	}
+
+	ctx := &Context{
+		UserVariables: map[string]string{
+			"version": "1.2.3",
+			"branch":  "feature/ami_names",
+			"padded":  "  v1.2.3\n",
+		},
+	}
+	for _, tc := range cases {
+		i := &I{Value: tc.Input}
+		result, err := i.Render(ctx)
+		if (err == nil) == tc.ErrorExpected {
+			t.Fatalf("Input: %s\n\nerr: %s", tc.Input, err)
+		}
+
+		if result != tc.Output {
+			t.Fatalf("Input: %s\n\nGot: %s", tc.Input, result)
+		}
+	}
+}
+
+func TestFuncArithmetic(t *testing.T) {
+	cases := []struct {
+		Input         string
+		Output        string
+		ErrorExpected bool
+	}{
+		{`{{mul (user "disk_gb") 1024}}`, "40960", false},
+		{`{{user "port" | add 1}}`, "8081", false},
+		{`{{sub (user "memory") 512}}`, "3584", false},
+		{`{{div (user "memory") 3}}`, "1365", false},
+		{`{{div (user "memory") 1.5}}`, "2730.6666666666665", false},
+		{`{{mod (user "port") 7}}`, "2", false},
+		{`{{add "0.5" 1}}`, "1.5", false},
+		{`{{div 1 0}}`, "", true},
+		{`{{mod 1.5 1}}`, "", true},
+		{`{{add (user "name") 1}}`, "", true},
+		{`{{min (user "memory") 2048 "8192"}}`, "2048", false},
+		{`{{max (user "memory") 2048 "8192"}}`, "8192", false},
+		{`{{max}}`, "", true},
+		{`{{if gt (int (user "disk_gb")) 32}}big{{else}}small{{end}}`, "big", false},
+		{`{{user "gui" | ternary "gui" "headless"}}`, "headless", false},
+		{`{{ternary "on" "off" (eq (user "name") "web")}}`, "on", false},
+		{`{{ternary "on" "off" "maybe"}}`, "", true},
+	}
+
+	ctx := &Context{
+		UserVariables: map[string]string{
+			"disk_gb": "40",
+			"port":    "8080",
+			"memory":  "4096",
+			"name":    "web",
+			"gui":     "false",
+		},
+	}
+	for _, tc := range cases {
+		i := &I{Value: tc.Input}
+		result, err := i.Render(ctx)
+		if (err == nil) == tc.ErrorExpected {
+			t.Fatalf("Input: %s\n\nerr: %s", tc.Input, err)
+		}
+
+		if result != tc.Output {
+			t.Fatalf("Input: %s\n\nGot: %s", tc.Input, result)
+		}
+	}
+}
+
+func TestFuncHashes(t *testing.T) {
+	cases := []struct {
+		Input         string
+		Output        string
+		ErrorExpected bool
+	}{
+		{`{{md5 "packer"}}`, "0b0f137f17ac10944716020b018f8126", false},
+		{`{{user "name" | sha256}}`, "131db0b57a618771d4d791b8e065c3286ff3b0fd92afb2dcdd6119256688f94e", false},
+		{`{{sha512 ""}}`, "cf83e1357eefb8bdf1542850d66d8007d620e4050b5715dc83f4a921d36ce9ce47d0d13c5d85f2b0ff8318d2877eec2f63b931bd47417a81a538327af927da3e", false},
+		{`{{user "name" | base64_encode}}`, "cGFja2Vy", false},
+		{`{{base64_decode "cGFja2Vy"}}`, "packer", false},
+		{`{{base64_decode "!"}}`, "", true},
+		{`{{bcrypt 40 "password"}}`, "", true},
+		{`{{bcrypt "a" "b" "c"}}`, "", true},
+	}
+
+	ctx := &Context{
+		UserVariables: map[string]string{
+			"name": "packer",
+		},
+	}
+	for _, tc := range cases {
+		i := &I{Value: tc.Input}
+		result, err := i.Render(ctx)
+		if (err == nil) == tc.ErrorExpected {
+			t.Fatalf("Input: %s\n\nerr: %s", tc.Input, err)
+		}
+
+		if result != tc.Output {
+			t.Fatalf("Input: %s\n\nGot: %s", tc.Input, result)
+		}
+	}
+}
+
+func TestFuncBcrypt(t *testing.T) {
+	i := &I{Value: `{{user "password" | bcrypt}}`}
+	ctx := &Context{
+		UserVariables: map[string]string{
+			"password": "hunter2",
+		},
+	}
+	result, err := i.Render(ctx)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(result), []byte("hunter2")); err != nil {
+		t.Fatalf("bad: %s: %s", result, err)
+	}
+}
+
+func TestFuncConsul(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Consul-Token") != "secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/kv/packer/mirror":
+			fmt.Fprint(w, `[{"Key": "packer/mirror", "Value": "aHR0cDovL21pcnJvci5leGFtcGxl"}]`)
+		case "/v1/kv/packer/empty":
+			fmt.Fprint(w, `[{"Key": "packer/empty", "Value": null}]`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	for k, v := range map[string]string{
+		"CONSUL_HTTP_ADDR":  strings.TrimPrefix(server.URL, "http://"),
+		"CONSUL_HTTP_TOKEN": "secret",
+	} {
+		defer os.Setenv(k, os.Getenv(k))
+		os.Setenv(k, v)
+	}
+
+	cases := []struct {
+		Input         string
+		Output        string
+		ErrorExpected bool
+	}{
+		{"{{consul_key `packer/mirror`}}", "http://mirror.example", false},
+		{"{{consul_key `packer/empty`}}", "", true},
+		{"{{consul_key `packer/missing`}}", "", true},
+	}
+
+	ctx := &Context{EnableEnv: true}
+	for _, tc := range cases {
+		i := &I{Value: tc.Input}
+		result, err := i.Render(ctx)
+		if (err == nil) == tc.ErrorExpected {
+			t.Fatalf("Input: %s\n\nerr: %s", tc.Input, err)
+		}
+
+		if result != tc.Output {
+			t.Fatalf("Input: %s\n\nGot: %s", tc.Input, result)
+		}
+	}
+
+	// Only where env is allowed
+	i := &I{Value: "{{consul_key `packer/mirror`}}"}
+	if _, err := i.Render(&Context{}); err == nil {
+		t.Fatal("should error")
+	}
+}
@@ -34,6 +34,15 @@ type Context struct {
 	BuildName    string
 	BuildType    string
 	TemplatePath string
+
+	// BuildValues are the values the builder recorded about the build,
+	// once it ran, for the "build" function. When they're nil the build
+	// hasn't run yet, and the function reads the ones recorded so far.
+	BuildValues map[string]string
+
+	// RandomSeed is the seed of the random functions of the run, the
+	// package RandomSeed is used when it is empty.
+	RandomSeed []byte
 }
 
 // Render is shorthand for constructing an I and calling Render.
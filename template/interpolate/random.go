@@ -0,0 +1,142 @@
+package interpolate
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// RandomSeed is the secret the values of uuidv4, random_string, and
+// random_int are derived from, unless the Context has its own. The same
+// call in the same build always returns the same value for a given seed,
+// so values are stable when a template is interpolated again, in any
+// process of the run. The core passes it to the plugins in their
+// configuration.
+var RandomSeed []byte
+
+const defaultCharset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+func init() {
+	RandomSeed = make([]byte, 32)
+	if _, err := rand.Read(RandomSeed); err != nil {
+		panic(err)
+	}
+}
+
+// randomStream is an endless stream of bytes derived from RandomSeed and
+// what a call is: its build, function, and arguments.
+type randomStream struct {
+	key     []byte
+	block   []byte
+	counter uint64
+}
+
+func newRandomStream(ctx *Context, call ...interface{}) *randomStream {
+	var build string
+	seed := RandomSeed
+	if ctx != nil {
+		build = ctx.BuildName
+		if len(ctx.RandomSeed) > 0 {
+			seed = ctx.RandomSeed
+		}
+	}
+
+	mac := hmac.New(sha256.New, seed)
+	fmt.Fprintf(mac, "%q %#v", build, call)
+	return &randomStream{key: mac.Sum(nil)}
+}
+
+func (s *randomStream) Read(p []byte) (int, error) {
+	for i := range p {
+		if len(s.block) == 0 {
+			mac := hmac.New(sha256.New, s.key)
+			binary.Write(mac, binary.BigEndian, s.counter)
+			s.counter++
+			s.block = mac.Sum(nil)
+		}
+		p[i] = s.block[0]
+		s.block = s.block[1:]
+	}
+	return len(p), nil
+}
+
+// uint64n returns a uniform number in [0, n), or any if n is 0.
+func (s *randomStream) uint64n(n uint64) uint64 {
+	// Reject the values past the last whole multiple of n, which would
+	// make the small results more likely.
+	var max uint64
+	if n > 0 {
+		max = ^uint64(0) - ^uint64(0)%n
+	}
+	for {
+		var v uint64
+		binary.Read(s, binary.BigEndian, &v)
+		if n == 0 {
+			return v
+		}
+		if v < max {
+			return v % n
+		}
+	}
+}
+
+func funcGenUuidv4(ctx *Context) interface{} {
+	return func(name ...string) string {
+		b := make([]byte, 16)
+		newRandomStream(ctx, "uuidv4", strings.Join(name, " ")).Read(b)
+		b[6] = b[6]&0x0f | 0x40
+		b[8] = b[8]&0x3f | 0x80
+		return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+	}
+}
+
+func funcGenRandomString(ctx *Context) interface{} {
+	return func(length interface{}, args ...string) (string, error) {
+		n, _, isInt, err := number(length)
+		if err != nil || !isInt || n < 0 {
+			return "", fmt.Errorf("random_string: the length must be a positive integer")
+		}
+		if len(args) > 2 {
+			return "", fmt.Errorf("random_string takes a length, and optionally the characters to use and a name")
+		}
+		chars := defaultCharset
+		if len(args) > 0 && args[0] != "" {
+			chars = args[0]
+		}
+		runes := []rune(chars)
+
+		var name string
+		if len(args) == 2 {
+			name = args[1]
+		}
+
+		s := newRandomStream(ctx, "random_string", n, chars, name)
+		var b strings.Builder
+		for i := int64(0); i < n; i++ {
+			b.WriteRune(runes[s.uint64n(uint64(len(runes)))])
+		}
+		return b.String(), nil
+	}
+}
+
+func funcGenRandomInt(ctx *Context) interface{} {
+	return func(min, max interface{}, name ...string) (int64, error) {
+		lo, _, loInt, err := number(min)
+		if err != nil || !loInt {
+			return 0, fmt.Errorf("random_int: the minimum must be an integer")
+		}
+		hi, _, hiInt, err := number(max)
+		if err != nil || !hiInt {
+			return 0, fmt.Errorf("random_int: the maximum must be an integer")
+		}
+		if hi < lo {
+			return 0, fmt.Errorf("random_int: the maximum is smaller than the minimum")
+		}
+
+		s := newRandomStream(ctx, "random_int", lo, hi, strings.Join(name, " "))
+		return lo + int64(s.uint64n(uint64(hi-lo)+1)), nil
+	}
+}
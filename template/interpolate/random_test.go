@@ -0,0 +1,92 @@
+package interpolate
+
+import (
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func render(t *testing.T, ctx *Context, v string) string {
+	i := &I{Value: v}
+	result, err := i.Render(ctx)
+	if err != nil {
+		t.Fatalf("%s: err: %s", v, err)
+	}
+	return result
+}
+
+func TestFuncRandom_stable(t *testing.T) {
+	defer func(old []byte) { RandomSeed = old }(RandomSeed)
+
+	for _, v := range []string{
+		`{{uuidv4}}`,
+		`{{random_string 16}}`,
+		`{{random_int 1 1000000}}`,
+	} {
+		RandomSeed = []byte("seed")
+		first := render(t, &Context{BuildName: "a"}, v)
+		if again := render(t, &Context{BuildName: "a"}, v); again != first {
+			t.Fatalf("%s: not stable: %s != %s", v, first, again)
+		}
+		if other := render(t, &Context{BuildName: "b"}, v); other == first {
+			t.Fatalf("%s: the same in another build: %s", v, first)
+		}
+
+		RandomSeed = []byte("another seed")
+		if other := render(t, &Context{BuildName: "a"}, v); other == first {
+			t.Fatalf("%s: the same with another seed: %s", v, first)
+		}
+	}
+
+	// The seed of the context wins over the one of the package
+	RandomSeed = []byte("seed")
+	first := render(t, &Context{RandomSeed: []byte("another seed")}, `{{uuidv4}}`)
+	RandomSeed = []byte("another seed")
+	if again := render(t, &Context{}, `{{uuidv4}}`); again != first {
+		t.Fatalf("the seed of the context should be used: %s != %s", first, again)
+	}
+
+	ctx := &Context{}
+	if render(t, ctx, `{{random_string 16 "" "db"}}`) == render(t, ctx, `{{random_string 16}}`) {
+		t.Fatal("a name should give another value")
+	}
+}
+
+func TestFuncRandom(t *testing.T) {
+	ctx := &Context{}
+
+	uuid := render(t, ctx, `{{uuidv4}}`)
+	if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(uuid) {
+		t.Fatalf("bad: %s", uuid)
+	}
+
+	if s := render(t, ctx, `{{random_string 24}}`); !regexp.MustCompile(`^[a-zA-Z0-9]{24}$`).MatchString(s) {
+		t.Fatalf("bad: %s", s)
+	}
+	if s := render(t, ctx, `{{random_string "12" "xyzé"}}`); !regexp.MustCompile(`^[xyzé]{12}$`).MatchString(s) {
+		t.Fatalf("bad: %s", s)
+	}
+
+	for i := 0; i < 50; i++ {
+		v := render(t, ctx, `{{random_int 8000 8010 "`+strconv.Itoa(i)+`"}}`)
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 8000 || n > 8010 {
+			t.Fatalf("bad: %s", v)
+		}
+	}
+	if v := render(t, ctx, `{{random_int 7 7}}`); v != "7" {
+		t.Fatalf("bad: %s", v)
+	}
+
+	for _, v := range []string{
+		`{{random_string -1}}`,
+		`{{random_string "a"}}`,
+		`{{random_int 2 1}}`,
+		`{{random_int 1.5 2}}`,
+	} {
+		i := &I{Value: v}
+		if _, err := i.Render(ctx); err == nil {
+			t.Fatalf("%s: should error", v)
+		}
+	}
+}
@@ -36,7 +36,7 @@ func RenderMap(v interface{}, ctx *Context, f *RenderFilter) (map[string]interfa
 	for k, raw := range m {
 		// Always validate every field
 		if err := ValidateInterface(raw, ctx); err != nil {
-			return nil, fmt.Errorf("invalid '%s': %s", k, err)
+			return nil, fmt.Errorf("invalid '%s': %s", errorKey(k, err), err)
 		}
 
 		if !f.include(k) {
@@ -45,7 +45,7 @@ func RenderMap(v interface{}, ctx *Context, f *RenderFilter) (map[string]interfa
 
 		raw, err := RenderInterface(raw, ctx)
 		if err != nil {
-			return nil, fmt.Errorf("render '%s': %s", k, err)
+			return nil, fmt.Errorf("render '%s': %s", errorKey(k, err), err)
 		}
 
 		m[k] = raw
@@ -93,6 +93,30 @@ func ValidateInterface(v interface{}, ctx *Context) error {
 	return nil
 }
 
+// walkError is the error of an interpolation, with where it is in the
+// walked value, like "[0]" or "a.b".
+type walkError struct {
+	Path string
+	Err  error
+}
+
+func (e *walkError) Error() string {
+	return e.Err.Error()
+}
+
+// errorKey is the key of the map with the path in its value of where the
+// error is, like "inline[0]".
+func errorKey(k string, err error) string {
+	werr, ok := err.(*walkError)
+	if !ok || werr.Path == "" {
+		return k
+	}
+	if werr.Path[0] == '[' {
+		return k + werr.Path
+	}
+	return k + "." + werr.Path
+}
+
 // Include checks whether a key should be included.
 func (f *RenderFilter) include(k string) bool {
 	if f == nil {
@@ -243,9 +267,10 @@ func (w *renderWalker) Primitive(v reflect.Value) error {
 
 	replaceVal, err := w.F(strV)
 	if err != nil {
-		return fmt.Errorf(
-			"%s in:\n\n%s",
-			err, v.String())
+		return &walkError{
+			Path: w.path(),
+			Err:  fmt.Errorf("%s in:\n\n%s", err, v.String()),
+		}
 	}
 
 	if w.Replace {
@@ -280,3 +305,20 @@ func (w *renderWalker) Primitive(v reflect.Value) error {
 
 	return nil
 }
+
+// path is where the walk is, by the keys of the maps and the indexes of the
+// slices.
+func (w *renderWalker) path() string {
+	var path string
+	for _, k := range w.csKey {
+		if k.Kind() == reflect.Int {
+			path += fmt.Sprintf("[%d]", k.Int())
+			continue
+		}
+		if path != "" {
+			path += "."
+		}
+		path += fmt.Sprint(k.Interface())
+	}
+	return path
+}
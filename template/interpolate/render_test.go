@@ -2,6 +2,7 @@ package interpolate
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -149,3 +150,30 @@ func TestRenderMap(t *testing.T) {
 		}
 	}
 }
+
+func TestRenderMap_errorKey(t *testing.T) {
+	cases := []struct {
+		Input interface{}
+		Key   string
+	}{
+		{map[string]interface{}{"foo": "{{nope}}"}, "invalid 'foo'"},
+		{map[string]interface{}{"inline": []interface{}{"ok", "{{nope}}"}}, "invalid 'inline[1]'"},
+		{
+			map[string]interface{}{
+				"a": map[string]interface{}{"b": []interface{}{map[string]interface{}{"c": "{{nope}}"}}},
+			},
+			"invalid 'a.b[0].c'",
+		},
+	}
+
+	ctx := &Context{}
+	for _, tc := range cases {
+		_, err := RenderMap(tc.Input, ctx, nil)
+		if err == nil {
+			t.Fatalf("%s: should error", tc.Key)
+		}
+		if !strings.HasPrefix(err.Error(), tc.Key+": ") {
+			t.Fatalf("%s: bad: %s", tc.Key, err)
+		}
+	}
+}
@@ -25,12 +25,27 @@ type rawTemplate struct {
 
 	Builders           []map[string]interface{}
 	Push               map[string]interface{}
-	PostProcessors     []interface{} `mapstructure:"post-processors"`
+	ImageMetadata      map[string]interface{} `mapstructure:"image_metadata"`
+	PostProcessors     []interface{}          `mapstructure:"post-processors"`
 	Provisioners       []map[string]interface{}
+	Tests              []map[string]interface{}
 	Variables          map[string]interface{}
 	SensitiveVariables []string `mapstructure:"sensitive-variables"`
 
+	rawGroups `mapstructure:",squash"`
+	Include   []string
+
 	RawContents []byte
+
+	// dir is the directory of the template, the included files are
+	// relative to it.
+	dir string
+
+	// source is the template file, and groupSources the files the groups
+	// it includes are from, by kind and name of group, such as
+	// "provisioner-groups.base".
+	source       source
+	groupSources map[string]source
 }
 
 // Template returns the actual Template object built from this raw
@@ -111,85 +126,69 @@ func (r *rawTemplate) Template() (*Template, error) {
 		}
 
 		// Append the builders
+		b.Pos = r.source.pos(fmt.Sprintf("builders[%d]", i))
 		result.Builders[b.Name] = &b
 	}
 
+	// Gather the groups, the ones of the included files too
+	if err := r.readIncludes(); err != nil {
+		errs = multierror.Append(errs, err)
+	}
+	provisionerGroups, err := r.parseProvisionerGroups()
+	if err != nil {
+		errs = multierror.Append(errs, err)
+	}
+	postProcessorGroups, err := r.parsePostProcessorGroups()
+	if err != nil {
+		errs = multierror.Append(errs, err)
+	}
+
 	// Gather all the post-processors
 	if len(r.PostProcessors) > 0 {
 		result.PostProcessors = make([][]*PostProcessor, 0, len(r.PostProcessors))
 	}
-	for i, v := range r.PostProcessors {
-		// Parse the configurations. We need to do this because post-processors
-		// can take three different formats.
-		configs, err := r.parsePostProcessor(i, v)
-		if err != nil {
-			errs = multierror.Append(errs, err)
-			continue
-		}
-
-		// Parse the PostProcessors out of the configs
-		pps := make([]*PostProcessor, 0, len(configs))
-		for j, c := range configs {
-			var pp PostProcessor
-			if err := r.decoder(&pp, nil).Decode(c); err != nil {
-				errs = multierror.Append(errs, fmt.Errorf(
-					"post-processor %d.%d: %s", i+1, j+1, err))
-				continue
-			}
-
-			// Type is required
-			if pp.Type == "" {
-				errs = multierror.Append(errs, fmt.Errorf(
-					"post-processor %d.%d: type is required", i+1, j+1))
-				continue
-			}
-
-			// Set the configuration
-			delete(c, "except")
-			delete(c, "only")
-			delete(c, "keep_input_artifact")
-			delete(c, "type")
-			if len(c) > 0 {
-				pp.Config = c
-			}
-
-			pps = append(pps, &pp)
-		}
-
-		result.PostProcessors = append(result.PostProcessors, pps)
+	pps, err := r.expandPostProcessors(r.PostProcessors, postProcessorGroups)
+	if err != nil {
+		errs = multierror.Append(errs, err)
 	}
+	result.PostProcessors = append(result.PostProcessors, pps...)
 
 	// Gather all the provisioners
 	if len(r.Provisioners) > 0 {
 		result.Provisioners = make([]*Provisioner, 0, len(r.Provisioners))
 	}
-	for i, v := range r.Provisioners {
-		var p Provisioner
-		if err := r.decoder(&p, nil).Decode(v); err != nil {
-			errs = multierror.Append(errs, fmt.Errorf(
-				"provisioner %d: %s", i+1, err))
-			continue
-		}
+	ps, err := r.expandProvisioners("provisioners", r.Provisioners, provisionerGroups)
+	if err != nil {
+		errs = multierror.Append(errs, err)
+	}
+	result.Provisioners = append(result.Provisioners, ps...)
 
-		// Type is required before any richer validation
-		if p.Type == "" {
-			errs = multierror.Append(errs, fmt.Errorf(
-				"provisioner %d: missing 'type'", i+1))
+	// Gather the tests
+	if len(r.Tests) > 0 {
+		result.Tests = make([]*Test, 0, len(r.Tests))
+	}
+	for i, v := range r.Tests {
+		t, err := r.parseTest(i, v, provisionerGroups)
+		if err != nil {
+			for _, e := range multierror.Append(err).Errors {
+				errs = multierror.Append(errs, fmt.Errorf(
+					"test %d: %s", i+1, e))
+			}
 			continue
 		}
 
-		// Copy the configuration
-		delete(v, "except")
-		delete(v, "only")
-		delete(v, "override")
-		delete(v, "pause_before")
-		delete(v, "type")
-		if len(v) > 0 {
-			p.Config = v
-		}
+		result.Tests = append(result.Tests, t)
+	}
 
-		// TODO: stuff
-		result.Provisioners = append(result.Provisioners, &p)
+	// Image metadata
+	if r.ImageMetadata != nil {
+		m, err := r.parseImageMetadata(r.ImageMetadata)
+		if err != nil {
+			for _, e := range multierror.Append(err).Errors {
+				errs = multierror.Append(errs, fmt.Errorf("image_metadata: %s", e))
+			}
+		}
+		result.ImageMetadata = m
 	}
 
 	// Push
@@ -228,6 +227,141 @@ func (r *rawTemplate) decoder(
 	return d
 }
 
+func (r *rawTemplate) parseProvisioner(v map[string]interface{}) (*Provisioner, error) {
+	var p Provisioner
+	if err := r.decoder(&p, nil).Decode(v); err != nil {
+		return nil, err
+	}
+
+	// Type is required before any richer validation
+	if p.Type == "" {
+		return nil, fmt.Errorf("missing 'type'")
+	}
+
+	// Copy the configuration
+	delete(v, "except")
+	delete(v, "exec_env")
+	delete(v, "exec_user")
+	delete(v, "exec_workdir")
+	delete(v, "only")
+	delete(v, "override")
+	delete(v, "pause_before")
+	delete(v, "type")
+	if len(v) > 0 {
+		p.Config = v
+	}
+
+	return &p, nil
+}
+
+func (r *rawTemplate) parseImageMetadata(v map[string]interface{}) (*ImageMetadata, error) {
+	var m ImageMetadata
+	var md mapstructure.Metadata
+	if err := r.decoder(&m, &md).Decode(v); err != nil {
+		return nil, err
+	}
+
+	var errs error
+	for _, unused := range md.Unused {
+		errs = multierror.Append(errs, fmt.Errorf("unknown key '%s'", unused))
+	}
+	if m.Path == "" {
+		m.Path = DefaultImageMetadataPath
+	}
+	m.Pos = r.source.pos("image_metadata")
+	return &m, errs
+}
+
+func (r *rawTemplate) parseTest(
+	i int, v map[string]interface{}, groups map[string][]*Provisioner) (*Test, error) {
+	var raw struct {
+		Name          string
+		Builds        []string
+		Provisioners  []map[string]interface{}
+		KeepArtifacts bool `mapstructure:"keep_artifacts"`
+	}
+	var md mapstructure.Metadata
+	if err := r.decoder(&raw, &md).Decode(v); err != nil {
+		return nil, err
+	}
+
+	var errs error
+	for _, unused := range md.Unused {
+		errs = multierror.Append(errs, fmt.Errorf("unknown key '%s'", unused))
+	}
+	if raw.Name == "" {
+		errs = multierror.Append(errs, fmt.Errorf("missing 'name'"))
+	}
+
+	t := &Test{
+		Name:          raw.Name,
+		Builds:        raw.Builds,
+		KeepArtifacts: raw.KeepArtifacts,
+	}
+	ps, err := r.expandProvisioners(fmt.Sprintf("tests[%d].provisioners", i), raw.Provisioners, groups)
+	if err != nil {
+		errs = multierror.Append(errs, err)
+	}
+	t.Provisioners = ps
+
+	if errs != nil {
+		return nil, errs
+	}
+	return t, nil
+}
+
+// parsePostProcessorChain parses the i-th post-processor, which can be a
+// sequence of post-processors, of the list at path in src.
+func (r *rawTemplate) parsePostProcessorChain(
+	src source, path string, i int, v interface{}) ([]*PostProcessor, error) {
+	// Parse the configurations. We need to do this because post-processors
+	// can take three different formats.
+	configs, err := r.parsePostProcessor(i, v)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse the PostProcessors out of the configs
+	var errs error
+	pps := make([]*PostProcessor, 0, len(configs))
+	for j, c := range configs {
+		var pp PostProcessor
+		if err := r.decoder(&pp, nil).Decode(c); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf(
+				"post-processor %d.%d: %s", i+1, j+1, err))
+			continue
+		}
+
+		// Type is required
+		if pp.Type == "" {
+			errs = multierror.Append(errs, fmt.Errorf(
+				"post-processor %d.%d: type is required", i+1, j+1))
+			continue
+		}
+
+		pp.Pos = src.pos(fmt.Sprintf("%s[%d]", path, i))
+		if _, ok := v.([]interface{}); ok {
+			pp.Pos = src.pos(fmt.Sprintf("%s[%d][%d]", path, i, j))
+		}
+
+		// Set the configuration
+		delete(c, "except")
+		delete(c, "only")
+		delete(c, "keep_input_artifact")
+		delete(c, "build_override")
+		delete(c, "type")
+		if len(c) > 0 {
+			pp.Config = c
+		}
+
+		pps = append(pps, &pp)
+	}
+	if errs != nil {
+		return nil, errs
+	}
+	return pps, nil
+}
+
 func (r *rawTemplate) parsePostProcessor(
 	i int, raw interface{}) ([]map[string]interface{}, error) {
 	switch v := raw.(type) {
@@ -268,7 +402,15 @@ func (r *rawTemplate) parsePostProcessor(
 }
 
 // Parse takes the given io.Reader and parses a Template object out of it.
+// The files it includes are relative to the working directory.
 func Parse(r io.Reader) (*Template, error) {
+	return parse(r, "", true)
+}
+
+// parse parses the template of the file at path, which is empty for
+// templates that aren't files. The positions of the components are only
+// known when the contents are the ones of the file.
+func parse(r io.Reader, path string, positions bool) (*Template, error) {
 	// Create a buffer to copy what we read
 	var buf bytes.Buffer
 	if _, err := buf.ReadFrom(r); err != nil {
@@ -287,6 +429,13 @@ func Parse(r io.Reader) (*Template, error) {
 	var md mapstructure.Metadata
 	var rawTpl rawTemplate
 	rawTpl.RawContents = buf.Bytes()
+	rawTpl.source.filename = path
+	if path != "" {
+		rawTpl.dir = filepath.Dir(path)
+	}
+	if positions {
+		rawTpl.source.positions = NewPositions(rawTpl.RawContents)
+	}
 	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
 		Metadata: &md,
 		Result:   &rawTpl,
@@ -322,7 +471,7 @@ func Parse(r io.Reader) (*Template, error) {
 }
 
 // ParseFile is the same as Parse but is a helper to automatically open
-// a file for parsing.
+// a file for parsing. Files ending in .yaml or .yml are parsed as YAML.
 func ParseFile(path string) (*Template, error) {
 	var f *os.File
 	var err error
@@ -343,7 +492,18 @@ func ParseFile(path string) (*Template, error) {
 		}
 		defer f.Close()
 	}
-	tpl, err := Parse(f)
+	var filename string
+	if path != "-" {
+		if filename, err = filepath.Abs(path); err != nil {
+			return nil, err
+		}
+	}
+	var tpl *Template
+	if IsYAML(path) {
+		tpl, err = parseYAML(f, filename)
+	} else {
+		tpl, err = parse(f, filename, true)
+	}
 	if err != nil {
 		syntaxErr, ok := err.(*json.SyntaxError)
 		if !ok {
@@ -353,8 +513,12 @@ func ParseFile(path string) (*Template, error) {
 		f.Seek(0, os.SEEK_SET)
 		// Grab the error location, and return a string to point to offending syntax error
 		line, col, highlight := highlightPosition(f, syntaxErr.Offset)
-		err = fmt.Errorf("Error parsing JSON: %s\nAt line %d, column %d (offset %d):\n%s", err, line, col, syntaxErr.Offset, highlight)
-		return nil, err
+		return nil, &SyntaxError{
+			Err:       syntaxErr,
+			Line:      line,
+			Column:    col,
+			Highlight: highlight,
+		}
 	}
 
 	if !filepath.IsAbs(path) {
@@ -368,6 +532,20 @@ func ParseFile(path string) (*Template, error) {
 	return tpl, nil
 }
 
+// SyntaxError is returned by ParseFile when the template isn't valid
+// JSON. It carries the position of the error so callers can point to it.
+type SyntaxError struct {
+	Err       *json.SyntaxError
+	Line      int
+	Column    int
+	Highlight string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("Error parsing JSON: %s\nAt line %d, column %d (offset %d):\n%s",
+		e.Err, e.Line, e.Column, e.Err.Offset, e.Highlight)
+}
+
 // Takes a file and the location in bytes of a parse error
 // from json.SyntaxError.Offset and returns the line, column,
 // and pretty-printed context around the error with an arrow indicating the exact
@@ -70,6 +70,21 @@ func TestParse(t *testing.T) {
 			false,
 		},
 
+		{
+			"parse-provisioner-exec.json",
+			&Template{
+				Provisioners: []*Provisioner{
+					{
+						Type:        "something",
+						ExecUser:    "app",
+						ExecWorkdir: "/srv",
+						ExecEnv:     map[string]string{"FOO": "bar"},
+					},
+				},
+			},
+			false,
+		},
+
 		{
 			"parse-provisioner-only.json",
 			&Template{
@@ -281,6 +296,57 @@ func TestParse(t *testing.T) {
 			true,
 		},
 
+		{
+			"parse-pp-override.json",
+			&Template{
+				PostProcessors: [][]*PostProcessor{
+					{
+						{
+							Type: "foo",
+							Override: map[string]interface{}{
+								"bar": map[string]interface{}{},
+							},
+						},
+					},
+				},
+			},
+			false,
+		},
+
+		{
+			"parse-test.json",
+			&Template{
+				Tests: []*Test{
+					{
+						Name:          "smoke",
+						Builds:        []string{"foo"},
+						KeepArtifacts: true,
+						Provisioners: []*Provisioner{
+							{
+								Type: "shell",
+								Config: map[string]interface{}{
+									"inline": []interface{}{"true"},
+								},
+							},
+						},
+					},
+				},
+			},
+			false,
+		},
+
+		{
+			"parse-test-no-name.json",
+			nil,
+			true,
+		},
+
+		{
+			"parse-test-unknown-key.json",
+			nil,
+			true,
+		},
+
 		{
 			"parse-description.json",
 			&Template{
@@ -307,6 +373,92 @@ func TestParse(t *testing.T) {
 			false,
 		},
 
+		{
+			"parse-image-metadata.json",
+			&Template{
+				Builders: map[string]*Builder{
+					"something": {
+						Name: "something",
+						Type: "something",
+					},
+				},
+				ImageMetadata: &ImageMetadata{
+					OnlyExcept: OnlyExcept{Only: []string{"something"}},
+					Path:       DefaultImageMetadataPath,
+					Version:    "1.2.0",
+				},
+			},
+			false,
+		},
+
+		{
+			"parse-image-metadata-bad-key.json",
+			nil,
+			true,
+		},
+
+		{
+			"parse-groups.json",
+			&Template{
+				Provisioners: []*Provisioner{
+					{Type: "shell-local"},
+					{
+						OnlyExcept: OnlyExcept{Except: []string{"foo"}},
+						Type:       "shell",
+						Config: map[string]interface{}{
+							"inline": []interface{}{"lock"},
+						},
+					},
+					{
+						Type: "shell",
+						Config: map[string]interface{}{
+							"inline": []interface{}{"lock"},
+						},
+					},
+					{
+						OnlyExcept: OnlyExcept{Only: []string{"foo"}},
+						Type:       "file",
+						Config: map[string]interface{}{
+							"source": "a",
+						},
+					},
+				},
+				PostProcessors: [][]*PostProcessor{
+					{
+						{
+							OnlyExcept: OnlyExcept{Only: []string{"bar"}},
+							Type:       "compress",
+						},
+					},
+					{
+						{
+							OnlyExcept: OnlyExcept{Only: []string{"bar"}},
+							Type:       "checksum",
+						},
+					},
+				},
+			},
+			false,
+		},
+
+		{
+			"parse-groups-missing.json",
+			nil,
+			true,
+		},
+
+		{
+			"parse-groups-nested.json",
+			nil,
+			true,
+		},
+
+		{
+			"parse-groups-bad-include.json",
+			nil,
+			true,
+		},
+
 		{
 			"parse-comment.json",
 			&Template{
@@ -333,6 +485,7 @@ func TestParse(t *testing.T) {
 		}
 		if tpl != nil {
 			tpl.RawContents = nil
+			clearPositions(tpl)
 		}
 		if !reflect.DeepEqual(tpl, tc.Result) {
 			t.Fatalf("bad: %s\n\n%#v\n\n%#v", tc.File, tpl, tc.Result)
@@ -373,3 +526,14 @@ func TestParse_bad(t *testing.T) {
 		}
 	}
 }
+
+func TestParse_badSyntaxErrorPosition(t *testing.T) {
+	_, err := ParseFile(fixtureDir("error-middle.json"))
+	syntaxErr, ok := err.(*SyntaxError)
+	if !ok {
+		t.Fatalf("expected *SyntaxError, got %#v", err)
+	}
+	if syntaxErr.Line != 5 || syntaxErr.Column != 6 {
+		t.Fatalf("bad position: %d:%d", syntaxErr.Line, syntaxErr.Column)
+	}
+}
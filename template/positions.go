@@ -0,0 +1,144 @@
+package template
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Pos is where a builder, provisioner, or post-processor is defined, so the
+// errors about it can point to it.
+type Pos struct {
+	// Filename is the template, or the file the template includes the
+	// component from. It is empty for templates that aren't files.
+	Filename string
+
+	// Path is where the component is in the file, like "builders[1]" or
+	// "provisioner-groups.base[0]".
+	Path string
+
+	// Line and Column are zero when they aren't known, like for YAML
+	// files, which are read from the JSON they convert to.
+	Line   int
+	Column int
+}
+
+// String returns the position in the file:line:column format, leaving out
+// what isn't known.
+func (p Pos) String() string {
+	var parts []string
+	if p.Filename != "" {
+		parts = append(parts, filepath.Base(p.Filename))
+	}
+	if p.Line > 0 {
+		parts = append(parts, fmt.Sprint(p.Line), fmt.Sprint(p.Column))
+	}
+	return strings.Join(parts, ":")
+}
+
+// source is a file components are defined in.
+type source struct {
+	filename  string
+	positions *Positions
+}
+
+func (s source) pos(path string) Pos {
+	p := Pos{Filename: s.filename, Path: path}
+	if s.positions != nil {
+		p.Line, p.Column = s.positions.Position(path)
+	}
+	return p
+}
+
+// Positions finds where things are defined in a JSON template, by their
+// path such as "variables.foo" or "builders[1]".
+type Positions struct {
+	raw     []byte
+	offsets map[string]int64
+}
+
+// NewPositions returns the positions of the JSON document raw.
+func NewPositions(raw []byte) *Positions {
+	p := &Positions{
+		raw:     raw,
+		offsets: make(map[string]int64),
+	}
+
+	// This is best effort, the template has been parsed already so this
+	// only fails for templates that didn't come from a file.
+	p.walk(json.NewDecoder(bytes.NewReader(raw)), "")
+	return p
+}
+
+func (p *Positions) walk(dec *json.Decoder, path string) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return nil
+	}
+
+	switch delim {
+	case '{':
+		for dec.More() {
+			start := p.skip(dec.InputOffset())
+			tok, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			child := tok.(string)
+			if path != "" {
+				child = path + "." + child
+			}
+			p.offsets[child] = start
+			if err := p.walk(dec, child); err != nil {
+				return err
+			}
+		}
+	case '[':
+		for i := 0; dec.More(); i++ {
+			child := fmt.Sprintf("%s[%d]", path, i)
+			p.offsets[child] = p.skip(dec.InputOffset())
+			if err := p.walk(dec, child); err != nil {
+				return err
+			}
+		}
+	}
+
+	// The closing delimiter
+	_, err = dec.Token()
+	return err
+}
+
+// skip moves offset past the separators the decoder hasn't consumed yet,
+// to the start of the next token.
+func (p *Positions) skip(offset int64) int64 {
+	for offset < int64(len(p.raw)) && strings.IndexByte(" \t\r\n,:", p.raw[offset]) >= 0 {
+		offset++
+	}
+	return offset
+}
+
+// Position returns the line and column of path, or zeros if it isn't in
+// the template.
+func (p *Positions) Position(path string) (line, col int) {
+	offset, ok := p.offsets[path]
+	if !ok {
+		return 0, 0
+	}
+
+	line, col = 1, 1
+	for _, b := range p.raw[:offset] {
+		if b == '\n' {
+			line++
+			col = 1
+		} else {
+			col++
+		}
+	}
+	return line, col
+}
@@ -0,0 +1,115 @@
+package template
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+// clearPositions removes the positions of the components of tpl, for the
+// tests that compare templates.
+func clearPositions(tpl *Template) {
+	for _, b := range tpl.Builders {
+		b.Pos = Pos{}
+	}
+	for _, p := range tpl.Provisioners {
+		p.Pos = Pos{}
+	}
+	for _, chain := range tpl.PostProcessors {
+		for _, pp := range chain {
+			pp.Pos = Pos{}
+		}
+	}
+	for _, test := range tpl.Tests {
+		for _, p := range test.Provisioners {
+			p.Pos = Pos{}
+		}
+	}
+	if tpl.ImageMetadata != nil {
+		tpl.ImageMetadata.Pos = Pos{}
+	}
+}
+
+func TestPos_String(t *testing.T) {
+	cases := []struct {
+		Pos      Pos
+		Expected string
+	}{
+		{Pos{}, ""},
+		{Pos{Filename: "/tmp/foo.json", Path: "builders[0]"}, "foo.json"},
+		{Pos{Filename: "/tmp/foo.json", Line: 3, Column: 5}, "foo.json:3:5"},
+		{Pos{Line: 3, Column: 5}, "3:5"},
+	}
+	for _, tc := range cases {
+		if actual := tc.Pos.String(); actual != tc.Expected {
+			t.Fatalf("%#v: bad: %q", tc.Pos, actual)
+		}
+	}
+}
+
+func TestPositions(t *testing.T) {
+	raw, err := ioutil.ReadFile(fixtureDir("parse-positions.json"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	positions := NewPositions(raw)
+
+	cases := []struct {
+		path string
+		line int
+		col  int
+	}{
+		{"variables", 2, 3},
+		{"variables.unused", 4, 5},
+		{"builders[0]", 7, 5},
+		{"builders[0].content", 9, 7},
+		{"post-processors[1][1]", 23, 28},
+		{"push", 0, 0},
+	}
+	for _, tc := range cases {
+		line, col := positions.Position(tc.path)
+		if line != tc.line || col != tc.col {
+			t.Errorf("%s: expected %d:%d, got %d:%d", tc.path, tc.line, tc.col, line, col)
+		}
+	}
+}
+
+func TestParseFile_positions(t *testing.T) {
+	tpl, err := ParseFile(fixtureDir("parse-positions.json"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	cases := []struct {
+		Pos      Pos
+		Path     string
+		Expected string
+	}{
+		{tpl.Builders["file"].Pos, "builders[0]", "parse-positions.json:7:5"},
+		{tpl.Provisioners[0].Pos, "provisioners[0]", "parse-positions.json:18:5"},
+		{tpl.Provisioners[1].Pos, "provisioner-groups.base[0]", "parse-positions.json:14:7"},
+		{tpl.PostProcessors[0][0].Pos, "post-processors[0]", "parse-positions.json:22:5"},
+		{tpl.PostProcessors[1][1].Pos, "post-processors[1][1]", "parse-positions.json:23:28"},
+	}
+	for _, tc := range cases {
+		if tc.Pos.Path != tc.Path || tc.Pos.String() != tc.Expected {
+			t.Errorf("%s: bad: %s %s", tc.Path, tc.Pos.Path, tc.Pos)
+		}
+	}
+}
+
+func TestParseFile_positionsInclude(t *testing.T) {
+	tpl, err := ParseFile(fixtureDir("parse-groups.json"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The YAML include has no lines, only the file and the path.
+	pos := tpl.PostProcessors[0][0].Pos
+	if pos.Path != "post-processor-groups.publish[0]" || pos.String() != "parse-groups-include.yaml" {
+		t.Fatalf("bad: %#v", pos)
+	}
+	pos = tpl.Provisioners[1].Pos
+	if pos.Path != "provisioner-groups.hardening[0]" || pos.String() != "parse-groups.json:5:13" {
+		t.Fatalf("bad: %#v", pos)
+	}
+}
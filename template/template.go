@@ -3,6 +3,8 @@ package template
 import (
 	"errors"
 	"fmt"
+	"path"
+	"regexp"
 	"time"
 
 	"github.com/hashicorp/go-multierror"
@@ -23,6 +25,8 @@ type Template struct {
 	Builders           map[string]*Builder
 	Provisioners       []*Provisioner
 	PostProcessors     [][]*PostProcessor
+	Tests              []*Test
+	ImageMetadata      *ImageMetadata
 	Push               Push
 
 	// RawContents is just the raw data for this template
@@ -34,6 +38,8 @@ type Builder struct {
 	Name   string
 	Type   string
 	Config map[string]interface{}
+
+	Pos Pos `mapstructure:"-"`
 }
 
 // PostProcessor represents a post-processor within the template.
@@ -43,6 +49,9 @@ type PostProcessor struct {
 	Type              string
 	KeepInputArtifact bool `mapstructure:"keep_input_artifact"`
 	Config            map[string]interface{}
+	Override          map[string]interface{} `mapstructure:"build_override"`
+
+	Pos Pos `mapstructure:"-"`
 }
 
 // Provisioner represents a provisioner within the template.
@@ -53,6 +62,66 @@ type Provisioner struct {
 	Config      map[string]interface{}
 	Override    map[string]interface{}
 	PauseBefore time.Duration `mapstructure:"pause_before"`
+
+	// ExecUser, ExecWorkdir, and ExecEnv are the user, the working
+	// directory, and the extra environment variables of the commands the
+	// provisioner runs, for the communicators that support them.
+	ExecUser    string            `mapstructure:"exec_user"`
+	ExecWorkdir string            `mapstructure:"exec_workdir"`
+	ExecEnv     map[string]string `mapstructure:"exec_env"`
+
+	Pos Pos `mapstructure:"-"`
+}
+
+var envNameRe = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// validateExec validates the exec_* settings of the provisioner.
+func (p *Provisioner) validateExec() error {
+	var err error
+	if p.ExecWorkdir != "" && !path.IsAbs(p.ExecWorkdir) {
+		err = multierror.Append(err, errors.New("exec_workdir must be an absolute path"))
+	}
+	for k := range p.ExecEnv {
+		if !envNameRe.MatchString(k) {
+			err = multierror.Append(err, fmt.Errorf(
+				"invalid environment variable name in exec_env: '%s'", k))
+		}
+	}
+	return err
+}
+
+// Test represents a test within the template, run by `packer test`. It
+// builds the given builds, runs its provisioners after the ones of the
+// template to check the machine, and destroys the artifacts.
+type Test struct {
+	Name          string
+	Builds        []string
+	Provisioners  []*Provisioner
+	KeepArtifacts bool
+}
+
+// DefaultImageMetadataPath is where the image metadata file is written if
+// the template doesn't say.
+const DefaultImageMetadataPath = "/etc/packer-image.json"
+
+// ImageMetadata represents the metadata file written into the images of
+// the builds, after their provisioners, so the machines made from them can
+// tell where they come from.
+type ImageMetadata struct {
+	OnlyExcept `mapstructure:",squash"`
+
+	// Path is where the file is written on the machine.
+	Path string
+
+	// Name is the name of the template, the name of its file by default.
+	Name    string
+	Version string
+
+	// MoveCommand moves the file from a temporary path to Path, for users
+	// that can't write to it, like "sudo mv {{.Source}} {{.Destination}}".
+	MoveCommand string `mapstructure:"move_command"`
+
+	Pos Pos `mapstructure:"-"`
 }
 
 // Push represents the configuration for pushing the template to Atlas.
@@ -115,6 +184,63 @@ func (t *Template) Validate() error {
 					i+1, name))
 			}
 		}
+
+		if verr := p.validateExec(); verr != nil {
+			for _, e := range multierror.Append(verr).Errors {
+				err = multierror.Append(err, fmt.Errorf(
+					"provisioner %d: %s", i+1, e))
+			}
+		}
+	}
+
+	// Verify tests
+	names := make(map[string]bool)
+	for i, test := range t.Tests {
+		if names[test.Name] {
+			err = multierror.Append(err, fmt.Errorf(
+				"test %d: test with name '%s' already exists", i+1, test.Name))
+		}
+		names[test.Name] = true
+
+		for _, n := range test.Builds {
+			if _, ok := t.Builders[n]; !ok {
+				err = multierror.Append(err, fmt.Errorf(
+					"test %d: build '%s' not found", i+1, n))
+			}
+		}
+
+		for j, p := range test.Provisioners {
+			if verr := p.OnlyExcept.Validate(t); verr != nil {
+				for _, e := range multierror.Append(verr).Errors {
+					err = multierror.Append(err, fmt.Errorf(
+						"test %d: provisioner %d: %s", i+1, j+1, e))
+				}
+			}
+
+			for name := range p.Override {
+				if _, ok := t.Builders[name]; !ok {
+					err = multierror.Append(err, fmt.Errorf(
+						"test %d: provisioner %d: override '%s' doesn't exist",
+						i+1, j+1, name))
+				}
+			}
+
+			if verr := p.validateExec(); verr != nil {
+				for _, e := range multierror.Append(verr).Errors {
+					err = multierror.Append(err, fmt.Errorf(
+						"test %d: provisioner %d: %s", i+1, j+1, e))
+				}
+			}
+		}
+	}
+
+	// Verify the image metadata
+	if m := t.ImageMetadata; m != nil {
+		if verr := m.OnlyExcept.Validate(t); verr != nil {
+			for _, e := range multierror.Append(verr).Errors {
+				err = multierror.Append(err, fmt.Errorf("image_metadata: %s", e))
+			}
+		}
 	}
 
 	// Verify post-processors
@@ -127,6 +253,15 @@ func (t *Template) Validate() error {
 						"post-processor %d.%d: %s", i+1, j+1, e))
 				}
 			}
+
+			// Validate overrides
+			for name := range p.Override {
+				if _, ok := t.Builders[name]; !ok {
+					err = multierror.Append(err, fmt.Errorf(
+						"post-processor %d.%d: override '%s' doesn't exist",
+						i+1, j+1, name))
+				}
+			}
 		}
 	}
 
@@ -158,6 +293,61 @@ func (o *OnlyExcept) Skip(n string) bool {
 	return false
 }
 
+// Within narrows the builds of o down to the ones of outer, for a member of
+// a group used with only or except. It returns false if no build is left.
+func (o OnlyExcept) Within(outer OnlyExcept) (OnlyExcept, bool) {
+	only := o.Only
+	except := append(append([]string{}, o.Except...), outer.Except...)
+	if len(outer.Only) > 0 {
+		if len(only) == 0 {
+			only = outer.Only
+		} else {
+			only = intersect(only, outer.Only)
+			if len(only) == 0 {
+				return OnlyExcept{}, false
+			}
+		}
+	}
+
+	// With only, except just removes from it.
+	if len(only) > 0 {
+		var result []string
+		for _, n := range only {
+			if !contains(except, n) {
+				result = append(result, n)
+			}
+		}
+		if len(result) == 0 {
+			return OnlyExcept{}, false
+		}
+		return OnlyExcept{Only: result}, true
+	}
+
+	if len(except) == 0 {
+		except = nil
+	}
+	return OnlyExcept{Except: except}, true
+}
+
+func intersect(a, b []string) []string {
+	var result []string
+	for _, n := range a {
+		if contains(b, n) {
+			result = append(result, n)
+		}
+	}
+	return result
+}
+
+func contains(names []string, n string) bool {
+	for _, v := range names {
+		if v == n {
+			return true
+		}
+	}
+	return false
+}
+
 // Validate validates that the OnlyExcept settings are correct for a thing.
 func (o *OnlyExcept) Validate(t *Template) error {
 	if len(o.Only) > 0 && len(o.Except) > 0 {
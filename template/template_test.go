@@ -3,6 +3,7 @@ package template
 import (
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 )
 
@@ -33,6 +34,16 @@ func TestTemplateValidate(t *testing.T) {
 			false,
 		},
 
+		{
+			"validate-bad-prov-exec-workdir.json",
+			true,
+		},
+
+		{
+			"validate-bad-prov-exec-env.json",
+			true,
+		},
+
 		{
 			"validate-bad-prov-only.json",
 			true,
@@ -63,6 +74,16 @@ func TestTemplateValidate(t *testing.T) {
 			false,
 		},
 
+		{
+			"validate-bad-pp-override.json",
+			true,
+		},
+
+		{
+			"validate-good-pp-override.json",
+			false,
+		},
+
 		{
 			"validate-bad-pp-except.json",
 			true,
@@ -72,6 +93,21 @@ func TestTemplateValidate(t *testing.T) {
 			"validate-good-pp-except.json",
 			false,
 		},
+
+		{
+			"validate-bad-test-build.json",
+			true,
+		},
+
+		{
+			"validate-bad-test-repeat.json",
+			true,
+		},
+
+		{
+			"validate-good-test.json",
+			false,
+		},
 	}
 
 	for _, tc := range cases {
@@ -135,3 +171,48 @@ func TestOnlyExceptSkip(t *testing.T) {
 		}
 	}
 }
+
+func TestOnlyExceptWithin(t *testing.T) {
+	cases := []struct {
+		Inner, Outer OnlyExcept
+		Result       OnlyExcept
+		Ok           bool
+	}{
+		{OnlyExcept{}, OnlyExcept{}, OnlyExcept{}, true},
+		{OnlyExcept{Only: []string{"a"}}, OnlyExcept{}, OnlyExcept{Only: []string{"a"}}, true},
+		{OnlyExcept{}, OnlyExcept{Except: []string{"a"}}, OnlyExcept{Except: []string{"a"}}, true},
+		{
+			OnlyExcept{Except: []string{"a"}},
+			OnlyExcept{Except: []string{"b"}},
+			OnlyExcept{Except: []string{"a", "b"}},
+			true,
+		},
+		{
+			OnlyExcept{Only: []string{"a", "b"}},
+			OnlyExcept{Only: []string{"b", "c"}},
+			OnlyExcept{Only: []string{"b"}},
+			true,
+		},
+		{
+			OnlyExcept{Only: []string{"a", "b"}},
+			OnlyExcept{Except: []string{"a"}},
+			OnlyExcept{Only: []string{"b"}},
+			true,
+		},
+		{
+			OnlyExcept{Except: []string{"a"}},
+			OnlyExcept{Only: []string{"a", "b"}},
+			OnlyExcept{Only: []string{"b"}},
+			true,
+		},
+		{OnlyExcept{Only: []string{"a"}}, OnlyExcept{Only: []string{"b"}}, OnlyExcept{}, false},
+		{OnlyExcept{Only: []string{"a"}}, OnlyExcept{Except: []string{"a"}}, OnlyExcept{}, false},
+	}
+
+	for _, tc := range cases {
+		result, ok := tc.Inner.Within(tc.Outer)
+		if ok != tc.Ok || !reflect.DeepEqual(result, tc.Result) {
+			t.Fatalf("bad: %#v within %#v: %#v %t", tc.Inner, tc.Outer, result, ok)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package template
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// IsYAML reports whether the template or variable file at path is written
+// in YAML rather than JSON, going by its extension.
+func IsYAML(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return true
+	default:
+		return false
+	}
+}
+
+// ParseYAML is the same as Parse for a template written in YAML. The YAML is
+// converted to JSON first, so both describe the same template, and the
+// RawContents of the result are the JSON.
+func ParseYAML(r io.Reader) (*Template, error) {
+	return parseYAML(r, "")
+}
+
+func parseYAML(r io.Reader, path string) (*Template, error) {
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(r); err != nil {
+		return nil, err
+	}
+
+	contents, err := YAMLToJSON(buf.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return parse(bytes.NewReader(contents), path, false)
+}
+
+// YAMLToJSON converts a YAML document to the JSON document of the same
+// structure. Keys that aren't strings in YAML, like numbers, become strings.
+func YAMLToJSON(contents []byte) ([]byte, error) {
+	var raw interface{}
+	if err := yaml.Unmarshal(contents, &raw); err != nil {
+		return nil, fmt.Errorf("Error parsing YAML: %s", err)
+	}
+
+	return json.Marshal(jsonValue(raw))
+}
+
+// jsonValue turns the maps YAML decodes to, keyed by anything, into maps
+// keyed by strings that can be encoded as JSON.
+func jsonValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for k, value := range v {
+			result[fmt.Sprint(k)] = jsonValue(value)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, value := range v {
+			result[i] = jsonValue(value)
+		}
+		return result
+	default:
+		return v
+	}
+}
@@ -0,0 +1,51 @@
+package template
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseFile_yaml(t *testing.T) {
+	tpl, err := ParseFile(fixtureDir("parse-yaml.yaml"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	expected, err := ParseFile(fixtureDir("parse-yaml.json"))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// The YAML parses to the same template as the JSON.
+	tpl.Path, expected.Path = "", ""
+	tpl.RawContents, expected.RawContents = nil, nil
+	clearPositions(tpl)
+	clearPositions(expected)
+	if !reflect.DeepEqual(tpl, expected) {
+		t.Fatalf("bad:\n\n%#v\n\nexpected:\n\n%#v", tpl, expected)
+	}
+}
+
+func TestYAMLToJSON(t *testing.T) {
+	cases := []struct {
+		Input  string
+		Output string
+	}{
+		{"a: 1", `{"a":1}`},
+		{"1: [x, {2: true}]", `{"1":["x",{"2":true}]}`},
+		{"a: ~", `{"a":null}`},
+	}
+
+	for _, tc := range cases {
+		result, err := YAMLToJSON([]byte(tc.Input))
+		if err != nil {
+			t.Fatalf("%s: err: %s", tc.Input, err)
+		}
+		if string(result) != tc.Output {
+			t.Fatalf("%s: bad: %s", tc.Input, result)
+		}
+	}
+
+	if _, err := YAMLToJSON([]byte("a: [")); err == nil {
+		t.Fatal("should error")
+	}
+}